@@ -3,23 +3,46 @@ module github.com/httprunner/httprunner
 go 1.16
 
 require (
+	github.com/DATA-DOG/go-sqlmock v1.5.0
+	github.com/PuerkitoBio/goquery v1.8.0
+	github.com/alicebob/miniredis/v2 v2.17.0
 	github.com/andybalholm/brotli v1.0.4
+	github.com/andybalholm/cascadia v1.3.1
+	github.com/brianvoe/gofakeit/v6 v6.10.0
 	github.com/denisbrodbeck/machineid v1.0.1
+	github.com/eclipse/paho.mqtt.golang v1.3.5
+	github.com/emersion/go-imap v1.2.0
+	github.com/fxamacker/cbor/v2 v2.4.0
 	github.com/getsentry/sentry-go v0.13.0
+	github.com/go-redis/redis/v8 v8.11.4
+	github.com/go-sql-driver/mysql v1.6.0
 	github.com/google/uuid v1.3.0
 	github.com/httprunner/funplugin v0.4.2
 	github.com/jinzhu/copier v0.3.2
+	github.com/jlaffaye/ftp v0.0.0-20211117213618-11820403398b
 	github.com/jmespath/go-jmespath v0.4.0
 	github.com/json-iterator/go v1.1.12
+	github.com/klauspost/compress v1.15.1
+	github.com/lib/pq v1.10.5
 	github.com/maja42/goval v1.2.1
 	github.com/mattn/go-runewidth v0.0.13 // indirect
 	github.com/olekukonko/tablewriter v0.0.5
 	github.com/pkg/errors v0.9.1
+	github.com/pkg/sftp v1.13.1
 	github.com/prometheus/client_golang v1.11.0
 	github.com/rs/zerolog v1.26.1
+	github.com/segmentio/kafka-go v0.4.31
 	github.com/spf13/cobra v1.2.1
 	github.com/stretchr/testify v1.7.0
+	github.com/vmihailenco/msgpack/v5 v5.3.5
+	github.com/xitongsys/parquet-go v1.6.2
+	github.com/xitongsys/parquet-go-source v0.0.0-20200817004010-026bad9b25d0
+	golang.org/x/crypto v0.0.0-20211215165025-cf75a172585e
+	golang.org/x/net v0.0.0-20220225172249-27dd8689420f
+	golang.org/x/text v0.3.7
+	google.golang.org/protobuf v1.27.1
 	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b
+	modernc.org/sqlite v1.17.3
 )
 
 // replace github.com/httprunner/funplugin => ../funplugin
@@ -1,7 +1,11 @@
 package hrp
 
 import (
+	"net/http"
+	"net/http/cookiejar"
 	"os"
+	"regexp"
+	"strings"
 	"sync"
 	"time"
 
@@ -10,6 +14,8 @@ import (
 
 	"github.com/httprunner/funplugin"
 	"github.com/httprunner/httprunner/hrp/internal/boomer"
+	"github.com/httprunner/httprunner/hrp/internal/builtin"
+	"github.com/httprunner/httprunner/hrp/internal/json"
 	"github.com/httprunner/httprunner/hrp/internal/sdk"
 )
 
@@ -25,6 +31,39 @@ type HRPBoomer struct {
 	*boomer.Boomer
 	plugins      []funplugin.IPlugin // each task has its own plugin process
 	pluginsMutex *sync.RWMutex       // avoid data race
+	thinkTime    *ThinkTimeConfig    // override think time config of testcases
+	// json response body larger than threshold bytes is streamed
+	jsonStreamThreshold int64
+	// performance gates evaluated after load testing
+	gates []*LoadGate
+	// each virtual user keeps its own cookie jar, extracted variables and parameter row if enabled
+	userSession bool
+	// running steps and queue depths are reported in runtime status of debug server if set
+	debugServer DebugServer
+}
+
+// SetThinkTime configures think time setting for all testcases, which overrides think time config of testcase.
+func (b *HRPBoomer) SetThinkTime(setting *ThinkTimeConfig) {
+	b.thinkTime = setting
+}
+
+// SetJSONStreamThreshold configures size threshold in bytes of streaming json response body,
+// which reduces peak memory when extracting a few fields from large payloads.
+func (b *HRPBoomer) SetJSONStreamThreshold(threshold int64) {
+	b.jsonStreamThreshold = threshold
+}
+
+// SetUserSession configures whether each virtual user keeps its own cookie jar, variables extracted
+// in previous iterations and parameter row across iterations, thus login-once-then-browse scenarios
+// behave like distinct user sessions, otherwise each iteration starts from a fresh session with next parameter row.
+func (b *HRPBoomer) SetUserSession(enabled bool) {
+	b.userSession = enabled
+}
+
+// SetDebugServer reports running steps of virtual users and depths of stats queues in runtime status of debug server.
+func (b *HRPBoomer) SetDebugServer(d DebugServer) {
+	b.debugServer = d
+	d.AddQueue(b.QueueDepths)
 }
 
 // Run starts to run load test for one or multiple testcases.
@@ -54,6 +93,10 @@ func (b *HRPBoomer) Run(testcases ...ITestCase) {
 			log.Error().Err(err).Msg("failed to init parameter iterator")
 			os.Exit(1)
 		}
+		if err := b.AddGates(cfg.Gates...); err != nil {
+			log.Error().Err(err).Msg("failed to parse performance gates")
+			os.Exit(1)
+		}
 		rendezvousList := initRendezvous(testcase, int64(b.GetSpawnCount()))
 		task := b.convertBoomerTask(testcase, rendezvousList)
 		taskSlice = append(taskSlice, task)
@@ -76,6 +119,15 @@ func (b *HRPBoomer) convertBoomerTask(testcase *TestCase, rendezvousList []*Rend
 	hrpRunner := NewRunner(nil)
 	// set client transport for high concurrency load testing
 	hrpRunner.SetClientTransport(b.GetSpawnCount(), b.GetDisableKeepAlive(), b.GetDisableCompression())
+	if b.thinkTime != nil {
+		hrpRunner.SetThinkTime(b.thinkTime)
+	}
+	if b.jsonStreamThreshold > 0 {
+		hrpRunner.SetJSONStreamThreshold(b.jsonStreamThreshold)
+	}
+	if b.debugServer != nil {
+		hrpRunner.SetDebugServer(b.debugServer)
+	}
 	config := testcase.Config
 
 	// each testcase has its own plugin process
@@ -94,89 +146,255 @@ func (b *HRPBoomer) convertBoomerTask(testcase *TestCase, rendezvousList []*Rend
 		}
 	}()
 
-	return &boomer.Task{
+	task := &boomer.Task{
 		Name:   config.Name,
 		Weight: config.Weight,
 		Fn: func() {
-			sessionTestCase := &TestCase{}
-			// copy testcase to avoid data racing
-			if err := copier.Copy(sessionTestCase, testcase); err != nil {
-				log.Error().Err(err).Msg("copy testcase data failed")
-				return
+			b.runIteration(hrpRunner, testcase, plugin, nil)
+		},
+	}
+	if b.userSession {
+		task.NewUser = func() func() {
+			user := newVirtualUser(testcase.Config)
+			return func() {
+				b.runIteration(hrpRunner, testcase, plugin, user)
 			}
-			sessionRunner := hrpRunner.NewSessionRunner(sessionTestCase)
-			sessionRunner.parser.plugin = plugin
-
-			testcaseSuccess := true       // flag whole testcase result
-			var transactionSuccess = true // flag current transaction result
-
-			cfg := sessionTestCase.Config
-			// iterate through all parameter iterators and update case variables
-			for _, it := range cfg.ParametersSetting.Iterators {
-				if it.HasNext() {
-					cfg.Variables = mergeVariables(it.Next(), cfg.Variables)
-				}
+		}
+	}
+	return task
+}
+
+// virtualUser keeps session state of virtual user across iterations of testcase in load testing.
+type virtualUser struct {
+	jar        http.CookieJar
+	parameters map[string]interface{} // parameter row assigned to virtual user
+	variables  map[string]interface{} // session variables extracted in previous iterations
+}
+
+func newVirtualUser(cfg *TConfig) *virtualUser {
+	jar, _ := cookiejar.New(nil)
+	user := &virtualUser{
+		jar:       jar,
+		variables: make(map[string]interface{}),
+	}
+	if cfg.ParametersSetting != nil {
+		for _, it := range cfg.ParametersSetting.Iterators {
+			if it.HasNext() {
+				user.parameters = mergeVariables(it.Next(), user.parameters)
 			}
+		}
+	}
+	return user
+}
+
+// saveVariables keeps session variables of finished iteration for next iterations.
+func (u *virtualUser) saveVariables(sessionRunner *SessionRunner) {
+	sessionRunner.mutex.RLock()
+	defer sessionRunner.mutex.RUnlock()
+	for k, v := range sessionRunner.sessionVariables {
+		u.variables[k] = v
+	}
+}
+
+// runIteration runs testcase once and records stats, session state is kept in user if not nil.
+func (b *HRPBoomer) runIteration(hrpRunner *HRPRunner, testcase *TestCase, plugin funplugin.IPlugin, user *virtualUser) {
+	sessionTestCase := &TestCase{}
+	// copy testcase to avoid data racing
+	if err := copier.Copy(sessionTestCase, testcase); err != nil {
+		log.Error().Err(err).Msg("copy testcase data failed")
+		return
+	}
+	sessionRunner := hrpRunner.NewSessionRunner(sessionTestCase)
+	sessionRunner.parser.plugin = plugin
+	defer sessionRunner.closeClients()
 
-			if err := sessionRunner.parseConfig(cfg); err != nil {
-				log.Error().Err(err).Msg("parse config failed")
-				return
+	testcaseSuccess := true       // flag whole testcase result
+	var transactionSuccess = true // flag current transaction result
+
+	cfg := sessionTestCase.Config
+	if user != nil {
+		sessionRunner.jar = user.jar
+		cfg.Variables = mergeVariables(user.parameters, cfg.Variables)
+	} else {
+		// iterate through all parameter iterators and update case variables
+		for _, it := range cfg.ParametersSetting.Iterators {
+			if it.HasNext() {
+				cfg.Variables = mergeVariables(it.Next(), cfg.Variables)
 			}
+		}
+	}
 
-			startTime := time.Now()
-			for _, step := range testcase.TestSteps {
-				stepResult, err := step.Run(sessionRunner)
-				if err != nil {
-					// step failed
-					var elapsed int64
-					if stepResult != nil {
-						elapsed = stepResult.Elapsed
-					}
-					b.RecordFailure(string(step.Type()), step.Name(), elapsed, err.Error())
-
-					// update flag
-					testcaseSuccess = false
-					transactionSuccess = false
-
-					if hrpRunner.failfast {
-						log.Error().Msg("abort running due to failfast setting")
-						break
-					}
-					log.Warn().Err(err).Msg("run step failed, continue next step")
-					continue
-				}
-
-				// step success
-				if stepResult.StepType == stepTypeTransaction {
-					// transaction
-					// FIXME: support nested transactions
-					if step.Struct().Transaction.Type == transactionEnd { // only record when transaction ends
-						b.RecordTransaction(stepResult.Name, transactionSuccess, stepResult.Elapsed, 0)
-						transactionSuccess = true // reset flag for next transaction
-					}
-				} else if stepResult.StepType == stepTypeRendezvous {
-					// rendezvous
-				} else if stepResult.StepType == stepTypeThinkTime {
-					// think time
-					// no record required
-				} else {
-					// request or testcase step
-					b.RecordSuccess(string(step.Type()), step.Name(), stepResult.Elapsed, stepResult.ContentSize)
-				}
+	if err := sessionRunner.parseConfig(cfg); err != nil {
+		log.Error().Err(err).Msg("parse config failed")
+		return
+	}
+	if user != nil {
+		sessionRunner.updateSessionVariables(user.variables)
+		defer user.saveVariables(sessionRunner)
+	}
+
+	startTime := time.Now()
+	for _, step := range testcase.TestSteps {
+		stepResult, err := sessionRunner.runStep(step)
+		if err != nil {
+			// step failed
+			var elapsed int64
+			if stepResult != nil {
+				elapsed = stepResult.Elapsed
 			}
-			endTime := time.Now()
-
-			// report duration for transaction without end
-			for name, transaction := range sessionRunner.transactions {
-				if len(transaction) == 1 {
-					// if transaction end time not exists, use testcase end time instead
-					duration := endTime.Sub(transaction[transactionStart])
-					b.RecordTransaction(name, transactionSuccess, duration.Milliseconds(), 0)
-				}
+			b.RecordFailure(string(step.Type()), step.Name(), elapsed, err.Error())
+			b.recordRequest(stepResult)
+			b.recordError(step.Name(), stepResult, err)
+			// step result is not kept in load testing, reuse session data
+			releaseSessionData(stepResult)
+
+			// update flag
+			testcaseSuccess = false
+			transactionSuccess = false
+
+			if hrpRunner.failfast {
+				log.Error().Msg("abort running due to failfast setting")
+				break
 			}
+			log.Warn().Err(err).Msg("run step failed, continue next step")
+			continue
+		}
 
-			// report testcase as a whole Action transaction, inspired by LoadRunner
-			b.RecordTransaction("Action", testcaseSuccess, endTime.Sub(startTime).Milliseconds(), 0)
-		},
+		// step success
+		sessionRunner.updateSessionVariables(stepResult.ExportVars)
+		if stepResult.StepType == stepTypeTransaction {
+			// transaction
+			// FIXME: support nested transactions
+			if step.Struct().Transaction.Type == transactionEnd { // only record when transaction ends
+				b.RecordTransaction(stepResult.Name, transactionSuccess, stepResult.Elapsed, 0)
+				transactionSuccess = true // reset flag for next transaction
+			}
+		} else if stepResult.StepType == stepTypeRendezvous {
+			// rendezvous
+		} else if stepResult.StepType == stepTypeThinkTime {
+			// think time
+			// no record required
+		} else {
+			// request or testcase step
+			b.RecordSuccess(string(step.Type()), step.Name(), stepResult.Elapsed, stepResult.ContentSize)
+			b.recordRequest(stepResult)
+		}
+		releaseSessionData(stepResult)
+	}
+	endTime := time.Now()
+
+	// report duration for transaction without end
+	for name, transaction := range sessionRunner.transactions {
+		if len(transaction) == 1 {
+			// if transaction end time not exists, use testcase end time instead
+			duration := endTime.Sub(transaction[transactionStart])
+			b.RecordTransaction(name, transactionSuccess, duration.Milliseconds(), 0)
+		}
+	}
+
+	// report testcase as a whole Action transaction, inspired by LoadRunner
+	b.RecordTransaction("Action", testcaseSuccess, endTime.Sub(startTime).Milliseconds(), 0)
+	// report stats per scenario of mixed testcases
+	b.RecordScenario(cfg.Name, testcaseSuccess, endTime.Sub(startTime).Milliseconds())
+}
+
+// recordRequest reports raw result and response time with method and normalized url pattern of request step.
+func (b *HRPBoomer) recordRequest(stepResult *StepResult) {
+	if stepResult == nil {
+		return
+	}
+	if stepResult.StepType == stepTypeRequest || stepResult.StepType == stepTypeAPI {
+		var status int
+		if stepResult.Response != nil {
+			status = stepResult.Response.StatusCode
+		}
+		b.RecordResult(stepResult.Name, status, stepResult.Elapsed, stepResult.ContentSize)
+	}
+	sessionData, ok := stepResult.Data.(*SessionData)
+	if !ok || sessionData == nil || sessionData.ReqResps == nil {
+		return
+	}
+	request, ok := sessionData.ReqResps.Request.(map[string]interface{})
+	if !ok {
+		return
+	}
+	rawURL, _ := request["url"].(string)
+	if rawURL == "" {
+		return
+	}
+	method, _ := request["method"].(string)
+	b.RecordURL(method+" "+normalizeURLPattern(rawURL), stepResult.Elapsed)
+}
+
+// recordError reports failure of step with status code, error class and request and response pair as sample.
+func (b *HRPBoomer) recordError(stepName string, stepResult *StepResult, err error) {
+	var status int
+	class := string(FailureUnknown)
+	var sample func() string
+	if stepResult != nil {
+		if stepResult.Response != nil {
+			status = stepResult.Response.StatusCode
+		}
+		if stepResult.FailureCode != "" {
+			class = string(stepResult.FailureCode)
+		}
+		if sessionData, ok := stepResult.Data.(*SessionData); ok && sessionData != nil && sessionData.ReqResps != nil {
+			sample = func() string {
+				return errorSample(sessionData.ReqResps)
+			}
+		}
+	}
+	b.RecordError(stepName, status, class, err.Error(), sample)
+}
+
+// errorSample converts request and response pair to sample in error report, secrets are masked.
+func errorSample(reqResps *ReqResps) string {
+	data, err := json.Marshal(reqResps)
+	if err != nil {
+		return ""
+	}
+	return string(builtin.MaskSecretsInJSON(data))
+}
+
+var (
+	regexNumericID = regexp.MustCompile(`^\d+$`)
+	regexUUID      = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	regexHexID     = regexp.MustCompile(`^[0-9a-fA-F]{16,}$`)
+)
+
+// normalizeURLPattern converts url into pattern for aggregating percentiles, scheme, host and query are removed,
+// path segments of ids and variables are replaced, e.g. $base_url/users/123?a=1 => /users/{id},
+// /orders/$order_id => /orders/{var}.
+func normalizeURLPattern(rawURL string) string {
+	path := rawURL
+	if i := strings.IndexAny(path, "?#"); i >= 0 {
+		path = path[:i]
+	}
+	if i := strings.Index(path, "://"); i >= 0 {
+		path = path[i+3:]
+		if j := strings.Index(path, "/"); j >= 0 {
+			path = path[j:]
+		} else {
+			path = "/"
+		}
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		switch {
+		case strings.Contains(segment, "$"):
+			if i == 0 {
+				// base url variable
+				segments[i] = ""
+			} else {
+				segments[i] = "{var}"
+			}
+		case regexNumericID.MatchString(segment), regexUUID.MatchString(segment), regexHexID.MatchString(segment):
+			segments[i] = "{id}"
+		}
+	}
+	path = strings.Join(segments, "/")
+	if path == "" {
+		return "/"
 	}
+	return path
 }
@@ -1,8 +1,18 @@
 package hrp
 
 import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/httprunner/httprunner/hrp/internal/builtin"
 )
 
 func TestBoomerStandaloneRun(t *testing.T) {
@@ -32,3 +42,100 @@ func TestBoomerStandaloneRun(t *testing.T) {
 	time.Sleep(5 * time.Second)
 	b.Quit()
 }
+
+func TestBoomerUserSession(t *testing.T) {
+	var mutex sync.Mutex
+	var whoami []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			user := r.URL.Query().Get("user")
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: user, Path: "/"})
+			fmt.Fprintf(w, `{"token": "t-%s"}`, user)
+		case "/whoami":
+			var sid string
+			if cookie, err := r.Cookie("sid"); err == nil {
+				sid = cookie.Value
+			}
+			mutex.Lock()
+			whoami = append(whoami, sid+"/"+r.Header.Get("X-Token"))
+			mutex.Unlock()
+			w.Write([]byte("{}"))
+		}
+	}))
+	defer ts.Close()
+
+	login := &TestCase{
+		Config: NewConfig("login").
+			SetBaseURL(ts.URL).
+			WithParameters(map[string]interface{}{"user": []interface{}{"a", "b"}}),
+		TestSteps: []IStep{
+			NewStep("login").
+				GET("/login").
+				WithParams(map[string]interface{}{"user": "$user"}).
+				Extract().
+				WithJmesPath("body.token", "token"),
+		},
+	}
+	require.Nil(t, initParameterIterator(login.Config, "boomer"))
+	browse := &TestCase{
+		Config: NewConfig("browse").SetBaseURL(ts.URL),
+		TestSteps: []IStep{
+			NewStep("whoami").
+				GET("/whoami").
+				WithHeaders(map[string]string{"X-Token": "$token"}),
+		},
+	}
+	require.Nil(t, initParameterIterator(browse.Config, "boomer"))
+
+	b := NewBoomer(2, 1)
+	hrpRunner := NewRunner(nil)
+	userA := newVirtualUser(login.Config)
+	userB := newVirtualUser(login.Config)
+	assert.Equal(t, "a", userA.parameters["user"])
+	assert.Equal(t, "b", userB.parameters["user"])
+
+	// cookies and extracted variables are kept by each virtual user across iterations
+	b.runIteration(hrpRunner, login, nil, userA)
+	b.runIteration(hrpRunner, login, nil, userB)
+	b.runIteration(hrpRunner, browse, nil, userA)
+	b.runIteration(hrpRunner, browse, nil, userB)
+	assert.Equal(t, "t-a", userA.variables["token"])
+	u, _ := url.Parse(ts.URL)
+	assert.Len(t, userA.jar.Cookies(u), 1)
+	assert.Equal(t, []string{"a/t-a", "b/t-b"}, whoami)
+
+	// each iteration starts from a fresh session without virtual user, thus token is not found
+	b.runIteration(hrpRunner, browse, nil, nil)
+	assert.Len(t, whoami, 2)
+}
+
+func TestNormalizeURLPattern(t *testing.T) {
+	testData := []struct {
+		url      string
+		expected string
+	}{
+		{"/users/123", "/users/{id}"},
+		{"/users/123/orders?page=2", "/users/{id}/orders"},
+		{"https://example.com/users/550e8400-e29b-41d4-a716-446655440000#top", "/users/{id}"},
+		{"https://example.com", "/"},
+		{"$base_url/orders/$order_id", "/orders/{var}"},
+		{"/files/0123456789abcdef0123", "/files/{id}"},
+		{"/v2/users", "/v2/users"},
+	}
+	for _, data := range testData {
+		if actual := normalizeURLPattern(data.url); actual != data.expected {
+			t.Errorf("url pattern of %s should be %s, got %s", data.url, data.expected, actual)
+		}
+	}
+}
+
+func TestErrorSampleMaskSecrets(t *testing.T) {
+	builtin.AddSecrets("sample-token-9527")
+	sample := errorSample(&ReqResps{
+		Request:  map[string]interface{}{"headers": map[string]interface{}{"Authorization": "Bearer sample-token-9527"}},
+		Response: map[string]interface{}{"body": "token: sample-token-9527"},
+	})
+	assert.NotContains(t, sample, "sample-token-9527")
+	assert.Contains(t, sample, "Bearer ******")
+}
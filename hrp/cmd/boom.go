@@ -1,50 +1,155 @@
 package cmd
 
 import (
+	"os"
 	"time"
 
+	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 
 	"github.com/httprunner/httprunner/hrp"
 	"github.com/httprunner/httprunner/hrp/internal/boomer"
+	"github.com/httprunner/httprunner/hrp/internal/debug"
 )
 
 // boomCmd represents the boom command
 var boomCmd = &cobra.Command{
-	Use:   "boom",
-	Short: "run load test with boomer",
-	Long:  `run yaml/json testcase files for load test`,
+	Use:     "boom",
+	Aliases: []string{"load"},
+	Short:   "run load test with boomer",
+	Long:    `run yaml/json testcase files for load test`,
 	Example: `  $ hrp boom demo.json	# run specified json testcase file
   $ hrp boom demo.yaml	# run specified yaml testcase file
-  $ hrp boom examples/	# run testcases in specified folder`,
-	Args: cobra.MinimumNArgs(1),
+  $ hrp boom examples/	# run testcases in specified folder
+  $ hrp boom --profile load.yml	# run mixed scenarios of load profile by weights
+  $ hrp boom demo.json --executor constant-arrival-rate --arrival-rate 100 --spawn-count 500	# start 100 iterations per second with at most 500 users`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if loadProfile != "" {
+			return nil
+		}
+		return cobra.MinimumNArgs(1)(cmd, args)
+	},
 	PreRun: func(cmd *cobra.Command, args []string) {
 		boomer.SetUlimit(10240) // ulimit -n 10240
 		setLogLevel("WARN")     // disable info logs for load testing
 	},
 	Run: func(cmd *cobra.Command, args []string) {
 		var paths []hrp.ITestCase
+		if loadProfile != "" {
+			scenarios, err := hrp.LoadProfile(loadProfile)
+			if err != nil {
+				log.Error().Err(err).Msg("load profile failed")
+				os.Exit(1)
+			}
+			paths = append(paths, scenarios...)
+		}
 		for _, arg := range args {
 			path := hrp.TestCasePath(arg)
 			paths = append(paths, &path)
 		}
+		setJSONEngine()
 		hrpBoomer := hrp.NewBoomer(spawnCount, spawnRate)
 		hrpBoomer.SetRateLimiter(maxRPS, requestIncreaseRate)
 		if loopCount > 0 {
 			hrpBoomer.SetLoopCount(loopCount)
 		}
+		switch executor {
+		case "", "constant-vus":
+		case "constant-arrival-rate":
+			if arrivalRate <= 0 {
+				log.Error().Msg("arrival rate should be positive for constant-arrival-rate executor")
+				os.Exit(1)
+			}
+			hrpBoomer.SetArrivalRate(&boomer.ArrivalRateConfig{Rate: arrivalRate})
+		case "ramping-arrival-rate":
+			stages, err := boomer.ParseArrivalStages(arrivalStages)
+			if err != nil {
+				log.Error().Err(err).Msg("parse arrival stages failed")
+				os.Exit(1)
+			}
+			hrpBoomer.SetArrivalRate(&boomer.ArrivalRateConfig{Rate: arrivalRate, Stages: stages})
+		default:
+			log.Error().Str("executor", executor).
+				Msg("unknown executor, should be constant-vus, constant-arrival-rate or ramping-arrival-rate")
+			os.Exit(1)
+		}
+		if warmUp > 0 {
+			hrpBoomer.SetWarmUp(warmUp)
+		}
+		if guard || guardAutoCap {
+			hrpBoomer.EnableGuard(boomer.GuardConfig{
+				MaxCPU:    guardMaxCPU,
+				MaxMemory: guardMaxMemory << 20,
+				AutoCap:   guardAutoCap,
+			})
+		}
 		if !disableConsoleOutput {
 			hrpBoomer.AddOutput(boomer.NewConsoleOutput())
 		}
 		if prometheusPushgatewayURL != "" {
 			hrpBoomer.AddOutput(boomer.NewPrometheusPusherOutput(prometheusPushgatewayURL, "hrp"))
 		}
+		if thinkTime != "" {
+			thinkTimeSetting, err := hrp.ParseThinkTimeConfig(thinkTime)
+			if err != nil {
+				log.Error().Err(err).Msg("parse think time setting failed")
+				os.Exit(1)
+			}
+			hrpBoomer.SetThinkTime(thinkTimeSetting)
+		}
+		if jsonStreamThreshold > 0 {
+			hrpBoomer.SetJSONStreamThreshold(jsonStreamThreshold)
+		}
+		hrpBoomer.SetUserSession(userSession)
 		hrpBoomer.SetDisableKeepAlive(disableKeepalive)
 		hrpBoomer.SetDisableCompression(disableCompression)
 		hrpBoomer.EnableCPUProfile(cpuProfile, cpuProfileDuration)
 		hrpBoomer.EnableMemoryProfile(memoryProfile, memoryProfileDuration)
+		if err := hrpBoomer.AddGates(gates...); err != nil {
+			log.Error().Err(err).Msg("parse performance gates failed")
+			os.Exit(1)
+		}
+		if resultsPath != "" {
+			err := hrpBoomer.EnableResultsExport(boomer.ResultsConfig{
+				Path:       resultsPath,
+				SampleRate: resultsSampleRate,
+				MaxRows:    resultsMaxRows,
+			})
+			if err != nil {
+				log.Error().Err(err).Msg("enable results export failed")
+				os.Exit(1)
+			}
+		}
+		if statsCSV != "" {
+			hrpBoomer.EnableStatsCSV(statsCSV)
+		}
+		if errorSamples > 0 {
+			hrpBoomer.SetErrorSamples(errorSamples)
+		}
+		if errorReport != "" {
+			hrpBoomer.EnableErrorReport(errorReport)
+		}
+		if ctlListenAddr != "" {
+			if err := hrpBoomer.EnableController(ctlListenAddr); err != nil {
+				log.Error().Err(err).Msg("enable load controller failed")
+				os.Exit(1)
+			}
+		}
+		if debugAddr != "" {
+			debugServer, err := debug.StartServer(debugAddr)
+			if err != nil {
+				log.Error().Err(err).Msg("start debug server failed")
+				os.Exit(1)
+			}
+			defer debugServer.Close()
+			hrpBoomer.SetDebugServer(debugServer)
+		}
 		hrpBoomer.EnableGracefulQuit()
 		hrpBoomer.Run(paths...)
+		if err := hrpBoomer.CheckGates(); err != nil {
+			log.Error().Err(err).Msg("performance gates breached")
+			os.Exit(1)
+		}
 	},
 }
 
@@ -62,6 +167,25 @@ var (
 	disableConsoleOutput     bool
 	disableCompression       bool
 	disableKeepalive         bool
+	jsonStreamThreshold      int64
+	gates                    []string
+	statsCSV                 string
+	resultsPath              string
+	resultsSampleRate        float64
+	resultsMaxRows           int
+	ctlListenAddr            string
+	userSession              bool
+	warmUp                   time.Duration
+	errorSamples             int
+	errorReport              string
+	executor                 string
+	arrivalRate              float64
+	arrivalStages            string
+	loadProfile              string
+	guard                    bool
+	guardMaxCPU              float64
+	guardMaxMemory           uint64
+	guardAutoCap             bool
 )
 
 func init() {
@@ -71,7 +195,16 @@ func init() {
 	boomCmd.Flags().StringVar(&requestIncreaseRate, "request-increase-rate", "-1", "Request increase rate, disabled by default.")
 	boomCmd.Flags().IntVar(&spawnCount, "spawn-count", 1, "The number of users to spawn for load testing")
 	boomCmd.Flags().Float64Var(&spawnRate, "spawn-rate", 1, "The rate for spawning users")
+	boomCmd.Flags().StringVar(&loadProfile, "profile", "", "Load profile of scenarios with weights, e.g. 70% browse, 20% search, 10% checkout")
+	boomCmd.Flags().StringVar(&executor, "executor", "constant-vus", "Load executor, constant-vus loops iterations by users, constant-arrival-rate and ramping-arrival-rate start iterations at target rate with spawn count as max users")
+	boomCmd.Flags().Float64Var(&arrivalRate, "arrival-rate", 0, "Iterations started per second for arrival rate executors, which is the start rate of ramping-arrival-rate")
+	boomCmd.Flags().StringVar(&arrivalStages, "stages", "", "Stages of ramping-arrival-rate executor, e.g. 30s:100,1m:500 ramps to 100/s in 30s then to 500/s in 1m")
 	boomCmd.Flags().Int64Var(&loopCount, "loop-count", -1, "The specify running cycles for load testing")
+	boomCmd.Flags().DurationVar(&warmUp, "warm-up", 0, "Warm-up duration, samples of warm-up are excluded from final summary and performance gates, e.g. 10s")
+	boomCmd.Flags().BoolVar(&guard, "guard", false, "Sample CPU, memory, GC pause and file descriptors of load generator, and warn when the generator instead of the target is the bottleneck")
+	boomCmd.Flags().Float64Var(&guardMaxCPU, "guard-max-cpu", 90, "Max CPU usage in percent of all cores before load generator is regarded as overloaded")
+	boomCmd.Flags().Uint64Var(&guardMaxMemory, "guard-max-memory", 0, "Max memory in MB before load generator is regarded as overloaded, disabled by default")
+	boomCmd.Flags().BoolVar(&guardAutoCap, "guard-auto-cap", false, "Cap users when load generator is overloaded, implies --guard")
 	boomCmd.Flags().StringVar(&memoryProfile, "mem-profile", "", "Enable memory profiling.")
 	boomCmd.Flags().DurationVar(&memoryProfileDuration, "mem-profile-duration", 30*time.Second, "Memory profile duration.")
 	boomCmd.Flags().StringVar(&cpuProfile, "cpu-profile", "", "Enable CPU profiling.")
@@ -80,4 +213,17 @@ func init() {
 	boomCmd.Flags().BoolVar(&disableConsoleOutput, "disable-console-output", false, "Disable console output.")
 	boomCmd.Flags().BoolVar(&disableCompression, "disable-compression", false, "Disable compression")
 	boomCmd.Flags().BoolVar(&disableKeepalive, "disable-keepalive", false, "Disable keepalive")
+	boomCmd.Flags().BoolVar(&userSession, "user-session", false, "Each virtual user keeps its own cookies, extracted variables and parameter row across iterations")
+	boomCmd.Flags().Int64Var(&jsonStreamThreshold, "json-stream-threshold", 0, "Stream json response body larger than threshold bytes and only decode referenced fields, disabled by default.")
+	boomCmd.Flags().StringVar(&jsonEngine, "json-engine", "", "Set json engine, jsoniter (default) or std")
+	boomCmd.Flags().StringVar(&statsCSV, "stats-csv", "", "Write percentiles of response time per step, transaction and url pattern into csv file after run.")
+	boomCmd.Flags().IntVar(&errorSamples, "error-samples", 3, "Max request and response pairs sampled for each error bucket of step, status code and error class.")
+	boomCmd.Flags().StringVar(&errorReport, "error-report", "", "Write failures aggregated by step, status code and error class with sampled request and response pairs into json file after run.")
+	boomCmd.Flags().StringVar(&resultsPath, "results", "", "Write raw results of sampled requests into rolling .csv or .parquet files, e.g. results/raw.csv")
+	boomCmd.Flags().Float64Var(&resultsSampleRate, "results-sample-rate", 1, "Ratio of requests written into results files.")
+	boomCmd.Flags().IntVar(&resultsMaxRows, "results-max-rows", 1000000, "Roll to next results file after max rows.")
+	boomCmd.Flags().StringVar(&ctlListenAddr, "ctl-addr", "", "Listen address of load controller to adjust load by hrp boom ctl, e.g. :8089, disabled by default.")
+	boomCmd.Flags().StringVar(&debugAddr, "debug-addr", "", "Expose /debug/pprof/ and runtime status /debug/hrp/status (goroutines, active steps, queue depths) on listen address, e.g. localhost:6060")
+	boomCmd.Flags().StringArrayVar(&gates, "gate", nil, "Performance gate evaluated at the end of load testing, exit with non-zero code if breached, e.g. 'p95 < 300ms', 'error_rate < 0.1%'")
+	boomCmd.Flags().StringVar(&thinkTime, "think-time", "", "Override think time of testcases, e.g. multiply:0.5, random_percentage:0.5-1.5, limit:2s")
 }
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/httprunner/httprunner/hrp/internal/boomer"
+	"github.com/httprunner/httprunner/hrp/internal/json"
+)
+
+// boomCtlCmd represents the boom ctl command
+var boomCtlCmd = &cobra.Command{
+	Use:   "ctl",
+	Short: "adjust load of running load test",
+	Long:  `adjust users, spawn rate and max RPS of load test started by hrp boom --ctl-addr without restarting`,
+	Example: `  $ hrp boom ctl	# show current load
+  $ hrp load ctl --users 500 --spawn-rate 50	# adjust users to 500 with spawn rate 50
+  $ hrp load ctl --max-rps 1000 --addr localhost:8089	# adjust max RPS to 1000, 0 to disable rate limit`,
+	Args: cobra.NoArgs,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		setLogLevel(logLevel)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var ctl *boomer.ControlRequest
+		flags := cmd.Flags()
+		if flags.Changed("users") || flags.Changed("spawn-rate") || flags.Changed("max-rps") {
+			ctl = &boomer.ControlRequest{}
+			if flags.Changed("users") {
+				ctl.Users = &ctlUsers
+			}
+			if flags.Changed("spawn-rate") {
+				ctl.SpawnRate = &ctlSpawnRate
+			}
+			if flags.Changed("max-rps") {
+				ctl.MaxRPS = &ctlMaxRPS
+			}
+		}
+		state, err := boomer.Control(ctlAddr, ctl)
+		if err != nil {
+			return err
+		}
+		data, _ := json.MarshalIndent(state, "", "    ")
+		fmt.Println(string(data))
+		return nil
+	},
+}
+
+var (
+	ctlAddr      string
+	ctlUsers     int
+	ctlSpawnRate float64
+	ctlMaxRPS    int64
+)
+
+func init() {
+	boomCmd.AddCommand(boomCtlCmd)
+
+	boomCtlCmd.Flags().StringVar(&ctlAddr, "addr", "localhost:8089", "Address of load controller started by hrp boom --ctl-addr")
+	boomCtlCmd.Flags().IntVar(&ctlUsers, "users", 0, "Adjust concurrent users to spawn")
+	boomCtlCmd.Flags().Float64Var(&ctlSpawnRate, "spawn-rate", 0, "Rate for spawning users, should be specified with --users")
+	boomCtlCmd.Flags().Int64Var(&ctlMaxRPS, "max-rps", 0, "Adjust max RPS, 0 to disable rate limit")
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/httprunner/httprunner/hrp"
+)
+
+// convertCmd represents the convert command
+var convertCmd = &cobra.Command{
+	Use:   "convert $path...",
+	Short: "convert json/yaml testcases to test code and back",
+	Long: `convert json/yaml testcase files to test code, e.g. go test files using fluent NewStep api,
+or pytest files of HttpRunner v3, and convert pytest files of HttpRunner v3 back to json/yaml testcases`,
+	Example: `  $ hrp convert --to-gotest demo.yaml	# convert to demo_test.go beside demo.yaml
+  $ hrp convert --to-gotest testcases/	# convert testcases in specified folder
+  $ hrp convert --to-pytest demo.yaml	# convert to demo_test.py beside demo.yaml
+  $ hrp convert --to-yaml demo_test.py	# convert pytest to demo.yaml beside demo_test.py
+  $ hrp convert --to-json testcases/	# convert pytest files (*_test.py) in specified folder to json`,
+	Args: cobra.MinimumNArgs(1),
+	PreRun: func(cmd *cobra.Command, args []string) {
+		setLogLevel(logLevel)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var targets int
+		for _, selected := range []bool{convertToGoTest, convertToPyTest, convertToYAML, convertToJSON} {
+			if selected {
+				targets++
+			}
+		}
+		if targets != 1 {
+			return errors.New("please select one convert target type")
+		}
+		var outputFiles []string
+		var failed int
+		for _, arg := range args {
+			err := filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if d.IsDir() {
+					if path != arg && strings.HasPrefix(d.Name(), ".") {
+						return filepath.SkipDir // skip hidden folders
+					}
+					return nil
+				}
+				var outputPath string
+				ext := filepath.Ext(path)
+				switch {
+				case convertToGoTest || convertToPyTest:
+					if ext != ".yml" && ext != ".yaml" && ext != ".json" {
+						return nil
+					}
+					if convertToGoTest {
+						outputPath, err = hrp.ConvertGoTest(path)
+					} else {
+						outputPath, err = hrp.ConvertPyTest(path)
+					}
+				default:
+					// pytest files in folder are named *_test.py, other python files like debugtalk.py are skipped
+					if ext != ".py" || path != arg && !strings.HasSuffix(path, "_test.py") {
+						return nil
+					}
+					dstFormat := "yaml"
+					if convertToJSON {
+						dstFormat = "json"
+					}
+					outputPath, err = hrp.ConvertFromPyTest(path, dstFormat)
+				}
+				if err != nil {
+					log.Error().Err(err).Str("path", path).Msg("convert testcase failed")
+					failed++
+					return nil
+				}
+				outputFiles = append(outputFiles, outputPath)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		log.Info().Strs("output", outputFiles).Msg("convert testcase success")
+		if failed > 0 {
+			return fmt.Errorf("%d testcase files failed to convert", failed)
+		}
+		return nil
+	},
+}
+
+var (
+	convertToGoTest bool
+	convertToPyTest bool
+	convertToYAML   bool
+	convertToJSON   bool
+)
+
+func init() {
+	rootCmd.AddCommand(convertCmd)
+	convertCmd.Flags().BoolVar(&convertToGoTest, "to-gotest", false, "convert to go test files using fluent NewStep api")
+	convertCmd.Flags().BoolVar(&convertToPyTest, "to-pytest", false, "convert to pytest files of HttpRunner v3")
+	convertCmd.Flags().BoolVarP(&convertToYAML, "to-yaml", "y", false, "convert pytest files of HttpRunner v3 to yaml testcases")
+	convertCmd.Flags().BoolVarP(&convertToJSON, "to-json", "j", false, "convert pytest files of HttpRunner v3 to json testcases")
+}
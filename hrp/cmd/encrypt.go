@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/httprunner/httprunner/hrp/internal/builtin"
+)
+
+// encryptCmd represents the encrypt command
+var encryptCmd = &cobra.Command{
+	Use:   "encrypt $value",
+	Short: "encrypt secret value used in testcases",
+	Long: `encrypt secret value with AES-256-GCM key of HRP_SECRET_KEY environment variable,
+the output can be used in testcases as ${decrypt_secret(encrypted)} and decrypted with the same key at run time`,
+	Example: `  $ hrp encrypt --gen-key	# generate random key for HRP_SECRET_KEY
+  $ HRP_SECRET_KEY=<key> hrp encrypt my-password	# encrypt secret value`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if genSecretKey {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
+	PreRun: func(cmd *cobra.Command, args []string) {
+		setLogLevel(logLevel)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var output string
+		var err error
+		if genSecretKey {
+			output, err = builtin.GenSecretKey()
+		} else {
+			output, err = builtin.EncryptSecret(args[0])
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Println(output)
+		return nil
+	},
+}
+
+var genSecretKey bool
+
+func init() {
+	rootCmd.AddCommand(encryptCmd)
+	encryptCmd.Flags().BoolVar(&genSecretKey, "gen-key", false, "generate random base64 encoded key for HRP_SECRET_KEY")
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/httprunner/httprunner/hrp"
+)
+
+// fmtCmd represents the fmt command
+var fmtCmd = &cobra.Command{
+	Use:   "fmt $path...",
+	Short: "format json/yaml testcase files",
+	Long:  `rewrite json/yaml testcase files into canonical field order and indentation, or convert between json and yaml`,
+	Example: `  $ hrp fmt testcases/	# format testcases in specified folder in place
+  $ hrp fmt --check testcases/	# report testcases not formatted, e.g. in CI
+  $ hrp fmt --to-json demo.yaml	# convert yaml testcase to demo.json`,
+	Args: cobra.MinimumNArgs(1),
+	PreRun: func(cmd *cobra.Command, args []string) {
+		setLogLevel(logLevel)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if fmtToJSON && fmtToYAML {
+			return errors.New("please select only one convert format type")
+		}
+		var dstFormat string
+		if fmtToJSON {
+			dstFormat = "json"
+		} else if fmtToYAML {
+			dstFormat = "yaml"
+		}
+
+		var unformatted []string
+		var failed int
+		for _, arg := range args {
+			err := filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if d.IsDir() {
+					if path != arg && strings.HasPrefix(d.Name(), ".") {
+						return filepath.SkipDir // skip hidden folders
+					}
+					return nil
+				}
+				ext := filepath.Ext(path)
+				if ext != ".yml" && ext != ".yaml" && ext != ".json" {
+					return nil
+				}
+				outputPath, changed, err := hrp.FormatFile(path, dstFormat, fmtCheck)
+				if err != nil {
+					log.Error().Err(err).Str("path", path).Msg("format testcase file failed")
+					failed++
+					return nil
+				}
+				if changed {
+					unformatted = append(unformatted, outputPath)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if fmtCheck && len(unformatted) > 0 {
+			for _, path := range unformatted {
+				fmt.Println(path)
+			}
+			return fmt.Errorf("%d testcase files not formatted", len(unformatted))
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d testcase files failed to format", failed)
+		}
+		log.Info().Strs("output", unformatted).Msg("format testcase files success")
+		return nil
+	},
+}
+
+var (
+	fmtCheck  bool
+	fmtToJSON bool
+	fmtToYAML bool
+)
+
+func init() {
+	rootCmd.AddCommand(fmtCmd)
+	fmtCmd.Flags().BoolVar(&fmtCheck, "check", false, "do not write files, exit with error if any testcase file is not formatted")
+	fmtCmd.Flags().BoolVarP(&fmtToJSON, "to-json", "j", false, "convert testcase files to json format beside the original files")
+	fmtCmd.Flags().BoolVarP(&fmtToYAML, "to-yaml", "y", false, "convert testcase files to yaml format beside the original files")
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/httprunner/httprunner/hrp"
+)
+
+// reportCmd represents the report command
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "compare run results in history store",
+	Long:  `compare per-step latency and outcome of runs recorded by hrp run --history`,
+	Example: `  $ hrp report --history reports/history.db --compare last	# compare the latest run with the previous run
+  $ hrp report --history reports/history.db --compare 1648282800000 --threshold 0.5	# compare the latest run with specified run`,
+	Args: cobra.NoArgs,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		setLogLevel(logLevel)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if historyPath == "" {
+			return errors.New("history store not specified, please set --history")
+		}
+		store, err := hrp.OpenHistoryStore("sqlite3", historyPath)
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		var comparison *hrp.RunComparison
+		if compareRun == "last" {
+			comparison, err = store.CompareLast(regressionThreshold)
+		} else {
+			baselineRun, parseErr := strconv.ParseInt(compareRun, 10, 64)
+			if parseErr != nil {
+				return fmt.Errorf("invalid run to compare %s, should be last or run id", compareRun)
+			}
+			var runIDs []int64
+			runIDs, err = store.LatestRuns(1)
+			if err == nil && len(runIDs) == 0 {
+				err = errors.New("no run found in history store")
+			}
+			if err == nil {
+				comparison, err = store.Compare(baselineRun, runIDs[0], regressionThreshold)
+			}
+		}
+		if err != nil {
+			return err
+		}
+		comparison.Print(os.Stdout)
+		if regressions := comparison.Regressions(); len(regressions) > 0 {
+			return fmt.Errorf("%d steps regressed in run %d compared with run %d",
+				len(regressions), comparison.CurrentRun, comparison.BaselineRun)
+		}
+		return nil
+	},
+}
+
+var (
+	historyPath         string
+	compareRun          string
+	regressionThreshold float64
+)
+
+func init() {
+	rootCmd.AddCommand(reportCmd)
+	reportCmd.Flags().StringVar(&historyPath, "history", "", "sqlite history store recorded by hrp run --history")
+	reportCmd.Flags().StringVar(&compareRun, "compare", "last", "baseline run to compare the latest run with, last for the previous run, or run id")
+	reportCmd.Flags().Float64Var(&regressionThreshold, "threshold", 0.2, "relative latency increase regarded as regression, e.g. 0.2 for 20%")
+}
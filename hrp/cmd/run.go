@@ -1,21 +1,34 @@
 package cmd
 
 import (
+	"context"
 	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 
 	"github.com/httprunner/httprunner/hrp"
+	"github.com/httprunner/httprunner/hrp/internal/debug"
 )
 
 // runCmd represents the run command
 var runCmd = &cobra.Command{
 	Use:   "run $path...",
 	Short: "run API test",
-	Long:  `run yaml/json testcase files for API test`,
+	Long:  `run yaml/json testcase files for API test, folders are discovered recursively in lexical order`,
 	Example: `  $ hrp run demo.json	# run specified json testcase file
   $ hrp run demo.yaml	# run specified yaml testcase file
-  $ hrp run examples/	# run testcases in specified folder`,
+  $ hrp run examples/	# run testcases in specified folder
+  $ hrp run "examples/**/*_test.yaml"	# run testcases matched by glob pattern, ** matches any folders
+  $ gen-cases | hrp run -	# run json/yaml testcase read from stdin
+  $ hrp run examples/ --rerun-failed 2 --quarantine-file reports/quarantine.txt	# rerun failed testcases and record flaky ones
+  $ hrp run demo.yaml --var base_url=http://localhost:8080 --var retry=3	# override variables of all scopes
+  $ hrp run demo.yaml --interval 60s --alert-webhook https://hooks.slack.com/services/xxx	# run every minute and alert on failures`,
 	Args: cobra.MinimumNArgs(1),
 	PreRun: func(cmd *cobra.Command, args []string) {
 		setLogLevel(logLevel)
@@ -26,6 +39,7 @@ var runCmd = &cobra.Command{
 			path := hrp.TestCasePath(arg)
 			paths = append(paths, &path)
 		}
+		setJSONEngine()
 		runner := hrp.NewRunner(nil).
 			SetFailfast(!continueOnFailure).
 			SetSaveTests(saveTests)
@@ -38,10 +52,101 @@ var runCmd = &cobra.Command{
 		if pluginLogOn {
 			runner.SetPluginLogOn()
 		}
-		if proxyUrl != "" {
-			runner.SetProxyUrl(proxyUrl)
+		setConsoleOutput(runner)
+		setProxy(runner)
+		if snapshotDir != "" {
+			runner.SetSnapshotDir(snapshotDir)
 		}
-		err := runner.Run(paths...)
+		if updateSnapshots {
+			runner.SetUpdateSnapshots(true)
+		}
+		if thinkTime != "" {
+			thinkTimeSetting, err := hrp.ParseThinkTimeConfig(thinkTime)
+			if err != nil {
+				log.Error().Err(err).Msg("parse think time setting failed")
+				os.Exit(1)
+			}
+			runner.SetThinkTime(thinkTimeSetting)
+		}
+		if cmd.Flags().Changed("faker-seed") {
+			runner.SetFakerSeed(fakerSeed)
+		}
+		if len(cliVariables) > 0 {
+			variables, err := hrp.ParseVariableArgs(cliVariables)
+			if err != nil {
+				log.Error().Err(err).Msg("parse variables failed")
+				os.Exit(1)
+			}
+			runner.SetCLIVariables(variables)
+		}
+		if len(replaySteps) > 0 {
+			runner.SetReplaySteps(replaySteps...)
+		}
+		if sessionFile != "" {
+			variables, err := hrp.LoadSessionVariables(sessionFile)
+			if err != nil {
+				log.Error().Err(err).Msg("load session file failed")
+				os.Exit(1)
+			}
+			runner.SetReplayVariables(variables)
+		}
+		if harPath != "" {
+			runner.SetHAROutput(harPath)
+		}
+		if httpFileDir != "" {
+			runner.SetHTTPFileOutput(httpFileDir)
+		}
+		if saveSession {
+			runner.SetSaveSession(true)
+		}
+		if resume {
+			runner.SetResume(true)
+		}
+		if historyPath != "" {
+			store, err := hrp.OpenHistoryStore("sqlite3", historyPath)
+			if err != nil {
+				log.Error().Err(err).Msg("open history store failed")
+				os.Exit(1)
+			}
+			defer store.Close()
+			runner.SetHistoryStore(store)
+		}
+		if debugAddr != "" {
+			debugServer, err := debug.StartServer(debugAddr)
+			if err != nil {
+				log.Error().Err(err).Msg("start debug server failed")
+				os.Exit(1)
+			}
+			defer debugServer.Close()
+			runner.SetDebugServer(debugServer)
+		}
+		if rerunFailed > 0 {
+			runner.SetRerunFailed(rerunFailed)
+		}
+		if quarantineFile != "" {
+			runner.SetQuarantineFile(quarantineFile)
+		}
+		for _, rawURL := range notifyWebhooks {
+			notifier, err := hrp.ParseNotifier(rawURL)
+			if err != nil {
+				log.Error().Err(err).Msg("parse notify webhook failed")
+				os.Exit(1)
+			}
+			runner.AddNotifiers(notifier)
+		}
+		if interval > 0 {
+			runForever(runner, paths)
+			return
+		}
+		// cancel run on interrupt, partial summary is still saved and reported,
+		// default behavior is restored thus the second interrupt exits immediately
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			stop()
+		}()
+		err := runner.RunWithContext(ctx, paths...)
 		if err != nil {
 			os.Exit(1)
 		}
@@ -53,8 +158,37 @@ var (
 	requestsLogOff    bool
 	pluginLogOn       bool
 	proxyUrl          string
+	proxyUser         string
+	noProxy           []string
+	proxyTimeout      float64
 	saveTests         bool
 	genHTMLReport     bool
+	snapshotDir       string
+	updateSnapshots   bool
+	thinkTime         string
+	replaySteps       []string
+	saveSession       bool
+	resume            bool
+	quiet             bool
+	verbose           bool
+	noColor           bool
+	logBodyLimit      int
+	logContentTypes   []string
+	logHexdump        int
+	harPath           string
+	httpFileDir       string
+	sessionFile       string
+	jsonEngine        string
+	interval          time.Duration
+	maxRounds         int
+	alertThreshold    int
+	alertWebhooks     []string
+	notifyWebhooks    []string
+	rerunFailed       int
+	quarantineFile    string
+	cliVariables      []string
+	fakerSeed         int64
+	debugAddr         string
 )
 
 func init() {
@@ -63,6 +197,107 @@ func init() {
 	runCmd.Flags().BoolVar(&requestsLogOff, "log-requests-off", false, "turn off request & response details logging")
 	runCmd.Flags().BoolVar(&pluginLogOn, "log-plugin", false, "turn on plugin logging")
 	runCmd.Flags().StringVarP(&proxyUrl, "proxy-url", "p", "", "set proxy url")
+	runCmd.Flags().StringVar(&proxyUser, "proxy-user", "", "set proxy authentication in format of username:password")
+	runCmd.Flags().StringSliceVar(&noProxy, "no-proxy", nil, "hosts bypassing proxy, e.g. localhost,.example.com,10.0.0.0/8")
+	runCmd.Flags().Float64Var(&proxyTimeout, "proxy-connect-timeout", 0, "timeout seconds of connecting to proxy")
 	runCmd.Flags().BoolVarP(&saveTests, "save-tests", "s", false, "save tests summary")
 	runCmd.Flags().BoolVarP(&genHTMLReport, "gen-html-report", "g", false, "generate html report")
+	runCmd.Flags().StringVar(&snapshotDir, "snapshot-dir", "", "set folder of snapshot golden files, default to snapshots beside testcase")
+	runCmd.Flags().BoolVar(&updateSnapshots, "update-snapshots", false, "overwrite snapshot golden files with current responses")
+	runCmd.Flags().StringArrayVar(&cliVariables, "var", nil, "set variable in format of NAME=VALUE overriding variables of all scopes, can be repeated")
+	runCmd.Flags().Int64Var(&fakerSeed, "faker-seed", 0, "seed random source of fake_* functions to generate reproducible fake data")
+	runCmd.Flags().StringSliceVar(&replaySteps, "step", nil, "only run specified steps by name or index (starting from 1) with their dependencies")
+	runCmd.Flags().StringVar(&sessionFile, "session-file", "", "seed variables from saved session file when running specified steps")
+	runCmd.Flags().BoolVar(&saveSession, "save-session", false, "save session variables, cookies and completed steps to .session.json on failure")
+	runCmd.Flags().BoolVar(&resume, "resume", false, "restore saved .session.json and continue from the failed step")
+	runCmd.Flags().BoolVar(&quiet, "quiet", false, "only print pass/fail line of each step")
+	runCmd.Flags().BoolVar(&verbose, "verbose", false, "print full request & response dumps")
+	runCmd.Flags().BoolVar(&noColor, "no-color", false, "disable colors in console output")
+	runCmd.Flags().IntVar(&logBodyLimit, "log-body-limit", 0, "truncate printed request & response bodies longer than limit bytes, unlimited if not set")
+	runCmd.Flags().StringSliceVar(&logContentTypes, "log-content-types", nil, "prefixes of content types whose bodies are printed as text, * for all, default to text/,application/json,application/xml,application/x-www-form-urlencoded")
+	runCmd.Flags().IntVar(&logHexdump, "log-hexdump", 0, "print first N bytes of binary request & response bodies as hexdump")
+	runCmd.Flags().StringVar(&harPath, "har", "", "write all executed requests & responses into specified HAR file")
+	runCmd.Flags().StringVar(&httpFileDir, "http-file-dir", "", "write each executed request step with resolved variables as .http file into specified folder")
+	runCmd.Flags().StringVar(&thinkTime, "think-time", "", "override think time of testcases, e.g. ignore, multiply:0.5, limit:2s")
+	runCmd.Flags().StringVar(&jsonEngine, "json-engine", "", "set json engine, jsoniter (default) or std")
+	runCmd.Flags().DurationVar(&interval, "interval", 0, "run testcases repeatedly on schedule for synthetic monitoring, e.g. 60s")
+	runCmd.Flags().IntVar(&maxRounds, "max-rounds", 0, "stop scheduled runs after max rounds, run forever if not set")
+	runCmd.Flags().IntVar(&alertThreshold, "alert-threshold", 3, "fire alert webhooks when consecutive failed rounds reach threshold")
+	runCmd.Flags().StringVar(&historyPath, "history", "", "record per-step latency and outcome into sqlite history store")
+	runCmd.Flags().StringSliceVar(&notifyWebhooks, "notify-webhook", nil, "post run summary to webhook url of slack, lark, dingtalk or generic http endpoint")
+	runCmd.Flags().IntVar(&rerunFailed, "rerun-failed", 0, "rerun failed testcases up to N times, testcases passed in rerun are marked as flaky")
+	runCmd.Flags().StringVar(&quarantineFile, "quarantine-file", "", "write names of flaky testcases into quarantine list file")
+	runCmd.Flags().StringVar(&debugAddr, "debug-addr", "", "expose /debug/pprof/ and runtime status /debug/hrp/status (goroutines, active steps) on listen address, e.g. localhost:6060")
+	runCmd.Flags().StringSliceVar(&alertWebhooks, "alert-webhook", nil, "alert webhook url of slack, lark, generic http endpoint, or pagerduty://<routing_key>")
+}
+
+// setProxy configures proxy specified by --proxy-url and related flags.
+func setProxy(runner *hrp.HRPRunner) {
+	if proxyUrl == "" {
+		return
+	}
+	// password may contain colon
+	credentials := strings.SplitN(proxyUser, ":", 2)
+	cfg := &hrp.ProxyConfig{
+		URL:            proxyUrl,
+		Username:       credentials[0],
+		NoProxy:        noProxy,
+		ConnectTimeout: proxyTimeout,
+	}
+	if len(credentials) == 2 {
+		cfg.Password = credentials[1]
+	}
+	runner.SetProxy(cfg)
+}
+
+// setJSONEngine selects json engine specified by --json-engine flag.
+func setJSONEngine() {
+	if jsonEngine == "" {
+		return
+	}
+	if err := hrp.SetJSONEngine(jsonEngine); err != nil {
+		log.Error().Err(err).Msg("set json engine failed")
+		os.Exit(1)
+	}
+}
+
+// setConsoleOutput configures console output level and colors of runner.
+func setConsoleOutput(runner *hrp.HRPRunner) {
+	if quiet {
+		runner.SetOutputLevel(hrp.OutputQuiet)
+	} else if verbose {
+		runner.SetOutputLevel(hrp.OutputVerbose)
+	}
+	runner.SetColorOutput(!noColor && runtime.GOOS != "windows")
+	if logBodyLimit > 0 {
+		runner.SetLogBodyLimit(logBodyLimit)
+	}
+	if len(logContentTypes) > 0 {
+		runner.SetPrintableContentTypes(logContentTypes...)
+	}
+	if logHexdump > 0 {
+		runner.SetLogHexdump(logHexdump)
+	}
+}
+
+// runForever runs testcases on schedule specified by --interval until interrupted.
+func runForever(runner *hrp.HRPRunner, paths []hrp.ITestCase) {
+	cfg := &hrp.MonitorConfig{
+		Interval:         interval,
+		MaxRounds:        maxRounds,
+		FailureThreshold: alertThreshold,
+	}
+	for _, rawURL := range alertWebhooks {
+		webhook, err := hrp.ParseAlertWebhook(rawURL)
+		if err != nil {
+			log.Error().Err(err).Msg("parse alert webhook failed")
+			os.Exit(1)
+		}
+		cfg.Webhooks = append(cfg.Webhooks, webhook)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	stats := runner.RunForever(ctx, cfg, paths...)
+	log.Info().Int("rounds", stats.Rounds).Int("failures", stats.Failures).
+		Float64("successRate", stats.SuccessRate).Msg("scheduled runs stopped")
 }
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/httprunner/httprunner/hrp"
+)
+
+// schemaCmd represents the schema command
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "generate json schema of testcase files",
+	Long:  `generate json schema of json/yaml testcase files, which enables autocompletion and validation in editors`,
+	Example: `  $ hrp schema	# print json schema of testcase files
+  $ hrp schema -o .vscode/hrp-testcase.schema.json	# save json schema to file`,
+	Args: cobra.NoArgs,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		setLogLevel(logLevel)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		content, err := hrp.GenTestCaseSchema()
+		if err != nil {
+			return err
+		}
+		if schemaOutput == "" {
+			_, err = os.Stdout.Write(content)
+			return err
+		}
+		if err := os.WriteFile(schemaOutput, content, 0o644); err != nil {
+			return err
+		}
+		log.Info().Str("path", schemaOutput).Msg("generate json schema of testcase files")
+		return nil
+	},
+}
+
+var schemaOutput string
+
+func init() {
+	rootCmd.AddCommand(schemaCmd)
+	schemaCmd.Flags().StringVarP(&schemaOutput, "output", "o", "", "output file path, print to stdout if not specified")
+}
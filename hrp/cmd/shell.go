@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/httprunner/httprunner/hrp"
+)
+
+// shellCmd represents the shell command
+var shellCmd = &cobra.Command{
+	Use:   "shell $path",
+	Short: "debug testcase interactively",
+	Long:  `load yaml/json testcase file and run steps one at a time, inspect responses and edit variables between steps`,
+	Example: `  $ hrp shell demo.json	# debug specified json testcase file
+  $ hrp shell demo.yaml	# debug specified yaml testcase file`,
+	Args: cobra.ExactArgs(1),
+	PreRun: func(cmd *cobra.Command, args []string) {
+		setLogLevel(logLevel)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		path := hrp.TestCasePath(args[0])
+		runner := hrp.NewRunner(nil).SetRequestsLogOn()
+		if pluginLogOn {
+			runner.SetPluginLogOn()
+		}
+		setConsoleOutput(runner)
+		setProxy(runner)
+		shell, err := runner.NewShell(&path, os.Stdin, os.Stdout)
+		if err != nil {
+			log.Error().Err(err).Msg("load testcase failed")
+			os.Exit(1)
+		}
+		if err := shell.Run(); err != nil {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(shellCmd)
+	shellCmd.Flags().BoolVar(&pluginLogOn, "log-plugin", false, "turn on plugin logging")
+	shellCmd.Flags().StringVarP(&proxyUrl, "proxy-url", "p", "", "set proxy url")
+	shellCmd.Flags().StringVar(&proxyUser, "proxy-user", "", "set proxy authentication in format of username:password")
+	shellCmd.Flags().StringSliceVar(&noProxy, "no-proxy", nil, "hosts bypassing proxy, e.g. localhost,.example.com,10.0.0.0/8")
+	shellCmd.Flags().Float64Var(&proxyTimeout, "proxy-connect-timeout", 0, "timeout seconds of connecting to proxy")
+	shellCmd.Flags().BoolVar(&quiet, "quiet", false, "only print pass/fail line of each step")
+	shellCmd.Flags().BoolVar(&verbose, "verbose", false, "print full request & response dumps")
+	shellCmd.Flags().BoolVar(&noColor, "no-color", false, "disable colors in console output")
+}
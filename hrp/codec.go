@@ -0,0 +1,120 @@
+package hrp
+
+import (
+	"bytes"
+	"io"
+	"fmt"
+	"mime"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/httprunner/httprunner/hrp/internal/json"
+)
+
+// bodyCodec encodes request body and decodes response body in binary formats other than json,
+// which is selected by Content-Type of request or response.
+type bodyCodec struct {
+	name      string
+	marshal   func(v interface{}) ([]byte, error)
+	unmarshal func(data []byte) (interface{}, error)
+}
+
+var (
+	msgpackCodec = &bodyCodec{
+		name: "msgpack",
+		marshal: func(v interface{}) ([]byte, error) {
+			var buf bytes.Buffer
+			enc := msgpack.NewEncoder(&buf)
+			enc.SetSortMapKeys(true)
+			if err := enc.Encode(v); err != nil {
+				return nil, err
+			}
+			return buf.Bytes(), nil
+		},
+		unmarshal: func(data []byte) (interface{}, error) {
+			dec := msgpack.NewDecoder(bytes.NewReader(data))
+			dec.UseLooseInterfaceDecoding(true)
+			// maps are decoded with keys of any type, not only strings
+			dec.SetMapDecoder(func(dec *msgpack.Decoder) (interface{}, error) {
+				return dec.DecodeUntypedMap()
+			})
+			return dec.DecodeInterface()
+		},
+	}
+	cborCodec = &bodyCodec{
+		name:    "cbor",
+		marshal: cbor.Marshal,
+		unmarshal: func(data []byte) (interface{}, error) {
+			// maps are decoded with keys of any type, e.g. integer keys of COSE structures
+			var v interface{}
+			err := cbor.Unmarshal(data, &v)
+			return v, err
+		},
+	}
+)
+
+// bodyCodecs maps media type to body codec
+var bodyCodecs = map[string]*bodyCodec{
+	"application/msgpack":   msgpackCodec,
+	"application/x-msgpack": msgpackCodec,
+	"application/cbor":      cborCodec,
+}
+
+// lookupBodyCodec returns body codec of content type, nil is returned for json and other text formats.
+func lookupBodyCodec(contentType string) *bodyCodec {
+	if contentType == "" {
+		return nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil
+	}
+	return bodyCodecs[mediaType]
+}
+
+// readCodecResponseBody reads response body and decodes it with body codec,
+// decoded body is normalized to the same types as json body, thus it can be extracted and validated alike.
+func readCodecResponseBody(r io.Reader, codec *bodyCodec) (interface{}, error) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if _, err := buf.ReadFrom(r); err != nil {
+		return nil, err
+	}
+	decoded, err := codec.unmarshal(buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	content, err := json.Marshal(stringifyMapKeys(decoded))
+	if err != nil {
+		return nil, err
+	}
+	var body interface{}
+	if err := json.Unmarshal(content, &body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// stringifyMapKeys converts keys of decoded maps to strings recursively, which can be marshaled to json.
+func stringifyMapKeys(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			m[fmt.Sprint(key)] = stringifyMapKeys(item)
+		}
+		return m
+	case map[string]interface{}:
+		for key, item := range value {
+			value[key] = stringifyMapKeys(item)
+		}
+		return value
+	case []interface{}:
+		for i, item := range value {
+			value[i] = stringifyMapKeys(item)
+		}
+		return value
+	}
+	return v
+}
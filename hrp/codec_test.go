@@ -0,0 +1,74 @@
+package hrp
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunRequestWithBodyCodecs(t *testing.T) {
+	// decode request body and echo it back in the same format
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		codec := lookupBodyCodec(r.Header.Get("Content-Type"))
+		content, _ := io.ReadAll(r.Body)
+		data, err := codec.unmarshal(content)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		reply, _ := codec.marshal(map[string]interface{}{"echo": data, "codec": codec.name})
+		w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+		_, _ = w.Write(reply)
+	}))
+	defer ts.Close()
+
+	var steps []IStep
+	for _, contentType := range []string{"application/msgpack", "application/x-msgpack", "application/cbor"} {
+		steps = append(steps, NewStep(contentType).
+			POST("/rpc").
+			WithHeaders(map[string]string{"Content-Type": contentType}).
+			WithBody(map[string]interface{}{"device": "$device", "temperature": 21.5, "tags": []interface{}{"a", "b"}}).
+			Validate().
+			AssertEqual("status_code", 200, "check status code").
+			AssertEqual("body.echo.device", "sensor-1", "check string field").
+			AssertEqual("body.echo.temperature", 21.5, "check float field").
+			AssertEqual("body.echo.tags", []interface{}{"a", "b"}, "check array field"))
+	}
+	testcase := &TestCase{
+		Config: NewConfig("body codecs").SetBaseURL(ts.URL).
+			WithVariables(map[string]interface{}{"device": "sensor-1"}),
+		TestSteps: steps,
+	}
+	assert.Nil(t, NewRunner(t).Run(testcase))
+}
+
+func TestLookupBodyCodec(t *testing.T) {
+	assert.Equal(t, msgpackCodec, lookupBodyCodec("application/msgpack; charset=binary"))
+	assert.Equal(t, cborCodec, lookupBodyCodec("application/cbor"))
+	assert.Nil(t, lookupBodyCodec("application/json"))
+	assert.Nil(t, lookupBodyCodec(""))
+}
+
+func TestReadCodecResponseBodyWithIntegerKeys(t *testing.T) {
+	// e.g. COSE key of cbor is map with integer keys
+	data := map[interface{}]interface{}{1: 2, -1: map[interface{}]interface{}{3: "x"}, "kid": []interface{}{map[interface{}]interface{}{4: true}}}
+	for _, codec := range []*bodyCodec{cborCodec, msgpackCodec} {
+		content, err := codec.marshal(data)
+		if !assert.Nil(t, err) {
+			t.Fatal()
+		}
+		body, err := readCodecResponseBody(bytes.NewReader(content), codec)
+		if !assert.Nil(t, err, codec.name) {
+			continue
+		}
+		assert.Equal(t, map[string]interface{}{
+			"1":   float64(2),
+			"-1":  map[string]interface{}{"3": "x"},
+			"kid": []interface{}{map[string]interface{}{"4": true}},
+		}, body, codec.name)
+	}
+}
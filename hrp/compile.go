@@ -0,0 +1,120 @@
+package hrp
+
+import (
+	builtinJSON "encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// compiledRequest stores literals of constant request fields, which are resolved once at testcase load time.
+// constant fields have no variable or function references, thus are not parsed in each execution.
+// literals are shared by all executions and must not be modified.
+type compiledRequest struct {
+	url          string
+	urlConstant  bool
+	params       map[string]interface{} // nil if params are not constant
+	headers      map[string]string      // nil if headers are not constant
+	cookies      map[string]string      // nil if cookies are not constant
+	body         interface{}
+	bodyConstant bool
+}
+
+// isConstant checks if raw value has no variable or function references, $$ is escaped $ and not a reference.
+func isConstant(raw interface{}) bool {
+	if _, ok := raw.(builtinJSON.Number); ok {
+		return true
+	}
+	rawValue := reflect.ValueOf(raw)
+	switch rawValue.Kind() {
+	case reflect.String:
+		return !strings.Contains(strings.ReplaceAll(rawValue.String(), "$$", ""), "$")
+	case reflect.Slice:
+		for i := 0; i < rawValue.Len(); i++ {
+			if !isConstant(rawValue.Index(i).Interface()) {
+				return false
+			}
+		}
+		return true
+	case reflect.Map:
+		for _, k := range rawValue.MapKeys() {
+			if !isConstant(k.Interface()) || !isConstant(rawValue.MapIndex(k).Interface()) {
+				return false
+			}
+		}
+		return true
+	default:
+		return true
+	}
+}
+
+// compileRequest resolves constant fields of request, non-constant fields are still parsed in each execution.
+func compileRequest(parser *Parser, request *Request) {
+	if request == nil {
+		return
+	}
+	compiled := &compiledRequest{}
+	if isConstant(request.URL) {
+		if url, err := parser.ParseString(request.URL, nil); err == nil {
+			compiled.url = convertString(url)
+			compiled.urlConstant = true
+		}
+	}
+	if len(request.Params) > 0 && isConstant(request.Params) {
+		if params, err := parser.Parse(request.Params, nil); err == nil {
+			compiled.params = params.(map[string]interface{})
+		}
+	}
+	if len(request.Headers) > 0 && isConstant(request.Headers) {
+		if headers, err := parser.ParseHeaders(request.Headers, nil); err == nil {
+			compiled.headers = headers
+		}
+	}
+	if len(request.Cookies) > 0 && isConstant(request.Cookies) {
+		cookies := make(map[string]string, len(request.Cookies))
+		for name, value := range request.Cookies {
+			parsedValue, err := parser.Parse(value, nil)
+			if err != nil {
+				cookies = nil
+				break
+			}
+			cookies[name] = fmt.Sprintf("%v", parsedValue)
+		}
+		compiled.cookies = cookies
+	}
+	if request.Body != nil && isConstant(request.Body) {
+		if body, err := parser.Parse(request.Body, nil); err == nil {
+			compiled.body = body
+			compiled.bodyConstant = true
+		}
+	}
+	request.compiled = compiled
+}
+
+// compileTestCase resolves constant request fields of all steps in testcase, including referenced apis and testcases.
+func compileTestCase(testcase *TestCase) {
+	compileTestCaseSteps(newParser(), testcase, make(map[*TestCase]bool))
+}
+
+// compileTestCaseSteps compiles steps of testcase, visited testcases are skipped in case of circular references.
+func compileTestCaseSteps(parser *Parser, testcase *TestCase, visited map[*TestCase]bool) {
+	if visited[testcase] {
+		return
+	}
+	visited[testcase] = true
+	for _, step := range testcase.TestSteps {
+		tStep := step.Struct()
+		if tStep == nil {
+			continue
+		}
+		if tStep.Request != nil && tStep.Request.compiled == nil {
+			compileRequest(parser, tStep.Request)
+		}
+		if api, ok := tStep.API.(*API); ok && api.Request != nil && api.Request.compiled == nil {
+			compileRequest(parser, api.Request)
+		}
+		if refTestCase, ok := tStep.TestCase.(*TestCase); ok {
+			compileTestCaseSteps(parser, refTestCase, visited)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package hrp
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsConstant(t *testing.T) {
+	testData := []struct {
+		raw      interface{}
+		constant bool
+	}{
+		{"/get", true},
+		{"price $$10", true},
+		{"/get/$id", false},
+		{"${gen_random_string(5)}", false},
+		{123, true},
+		{nil, true},
+		{map[string]interface{}{"a": 1, "b": []interface{}{"x", "y"}}, true},
+		{map[string]interface{}{"a": 1, "b": []interface{}{"x", "$y"}}, false},
+		{map[string]string{"$key": "value"}, false},
+	}
+	for _, data := range testData {
+		assert.Equal(t, data.constant, isConstant(data.raw), data.raw)
+	}
+}
+
+func TestCompileRequest(t *testing.T) {
+	request := &Request{
+		Method:  httpPOST,
+		URL:     "/post",
+		Params:  map[string]interface{}{"foo1": "$foo", "foo2": "bar2"},
+		Headers: map[string]string{"User-Agent": "HttpRunnerPlus"},
+		Cookies: map[string]string{"user": "debugtalk"},
+		Body:    map[string]interface{}{"a": 1, "b": " c "},
+	}
+	compileRequest(newParser(), request)
+	compiled := request.compiled
+	if !assert.NotNil(t, compiled) {
+		t.Fatal()
+	}
+	assert.True(t, compiled.urlConstant)
+	assert.Equal(t, "/post", compiled.url)
+	assert.Nil(t, compiled.params) // params reference variable
+	assert.Equal(t, map[string]string{"User-Agent": "HttpRunnerPlus"}, compiled.headers)
+	assert.Equal(t, map[string]string{"user": "debugtalk"}, compiled.cookies)
+	assert.True(t, compiled.bodyConstant)
+	assert.Equal(t, map[string]interface{}{"a": 1, "b": "c"}, compiled.body)
+
+	// build request with compiled literals and parsed params
+	config := NewConfig("compile").
+		SetBaseURL("https://postman-echo.com").
+		SetHeaders(map[string]string{"User-Agent": "config", "X-Trace": "$foo"})
+	rb, err := buildStepRequest(newParser(), config, request, map[string]interface{}{"foo": "bar"})
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	assert.Equal(t, "https://postman-echo.com/post?foo1=bar&foo2=bar2", rb.req.URL.String())
+	assert.Equal(t, "HttpRunnerPlus", rb.req.Header.Get("User-Agent")) // step headers override config headers
+	assert.Equal(t, "bar", rb.req.Header.Get("X-Trace"))
+	assert.Equal(t, "user=debugtalk", rb.req.Header.Get("Cookie"))
+	body, _ := io.ReadAll(rb.req.Body)
+	assert.Equal(t, `{"a":1,"b":"c"}`, string(body))
+}
+
+func TestCompileAPIOverride(t *testing.T) {
+	request := &Request{Method: httpGET, URL: "/get", Params: map[string]interface{}{"a": "1"}}
+	compileRequest(newParser(), request)
+	assert.NotNil(t, request.compiled)
+
+	overridden := overrideAPIRequest(request, &APIOverride{Params: map[string]interface{}{"a": "2"}})
+	assert.Nil(t, overridden.compiled)
+	assert.NotNil(t, request.compiled)
+}
+
+func TestLoadTestCasesCompileRequests(t *testing.T) {
+	testCases, err := loadTestCases(&TestCase{
+		Config:    NewConfig("compile"),
+		TestSteps: []IStep{stepGET, stepPOSTData},
+	})
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	for _, step := range testCases[0].TestSteps {
+		assert.NotNil(t, step.Struct().Request.compiled)
+	}
+}
@@ -0,0 +1,102 @@
+package hrp
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/httprunner/httprunner/hrp/internal/builtin"
+)
+
+var regexCondition = regexp.MustCompile(`^\s*(.+?)\s*(==|!=|>=|<=|>|<)\s*(.+?)\s*$`)
+
+// evalCondition evaluates condition expression with variables mapping.
+// condition can be a comparison, e.g. "$state != done", "${get_count()} >= 3",
+// or a single variable/function expression which is evaluated as boolean, e.g. "$is_running".
+func evalCondition(parser *Parser, condition string, variablesMapping map[string]interface{}) (bool, error) {
+	matches := regexCondition.FindStringSubmatch(condition)
+	if matches == nil {
+		value, err := parser.Parse(condition, variablesMapping)
+		if err != nil {
+			return false, err
+		}
+		return isTruthy(value), nil
+	}
+
+	left, err := parser.Parse(matches[1], variablesMapping)
+	if err != nil {
+		return false, err
+	}
+	right, err := parser.Parse(matches[3], variablesMapping)
+	if err != nil {
+		return false, err
+	}
+	return compareValues(left, matches[2], right)
+}
+
+// compareValues compares two values by value if both are numbers, otherwise by string.
+func compareValues(left interface{}, operator string, right interface{}) (bool, error) {
+	leftNumber, err1 := toFloat64(left)
+	rightNumber, err2 := toFloat64(right)
+	if err1 == nil && err2 == nil {
+		switch operator {
+		case "==":
+			return leftNumber == rightNumber, nil
+		case "!=":
+			return leftNumber != rightNumber, nil
+		case ">":
+			return leftNumber > rightNumber, nil
+		case "<":
+			return leftNumber < rightNumber, nil
+		case ">=":
+			return leftNumber >= rightNumber, nil
+		case "<=":
+			return leftNumber <= rightNumber, nil
+		}
+	}
+
+	leftString := strings.Trim(fmt.Sprint(left), `"'`)
+	rightString := strings.Trim(fmt.Sprint(right), `"'`)
+	switch operator {
+	case "==":
+		return leftString == rightString, nil
+	case "!=":
+		return leftString != rightString, nil
+	case ">":
+		return leftString > rightString, nil
+	case "<":
+		return leftString < rightString, nil
+	case ">=":
+		return leftString >= rightString, nil
+	case "<=":
+		return leftString <= rightString, nil
+	}
+	return false, fmt.Errorf("unsupported condition operator: %s", operator)
+}
+
+func toFloat64(value interface{}) (float64, error) {
+	if s, ok := value.(string); ok {
+		return strconv.ParseFloat(s, 64)
+	}
+	return builtin.Interface2Float64(value)
+}
+
+// isTruthy converts value to boolean, empty string, "false", zero number and nil are false.
+func isTruthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+		return v != ""
+	}
+	if f, err := builtin.Interface2Float64(value); err == nil {
+		return f != 0
+	}
+	return true
+}
@@ -0,0 +1,38 @@
+package hrp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvalCondition(t *testing.T) {
+	parser := newParser()
+	variables := map[string]interface{}{
+		"state":   "running",
+		"count":   3,
+		"running": true,
+		"empty":   "",
+	}
+	testData := []struct {
+		condition string
+		expected  bool
+	}{
+		{"$state != done", true},
+		{"$state == running", true},
+		{"$state == 'running'", true},
+		{"$count >= 3", true},
+		{"$count < 3", false},
+		{"${max(1, $count)} > 2", true},
+		{"$running", true},
+		{"$empty", false},
+		{"false", false},
+	}
+	for _, data := range testData {
+		result, err := evalCondition(parser, data.condition, variables)
+		if !assert.Nil(t, err, data.condition) {
+			t.Fatal()
+		}
+		assert.Equal(t, data.expected, result, data.condition)
+	}
+}
@@ -1,9 +1,13 @@
 package hrp
 
 import (
+	"fmt"
 	"math/rand"
 	"reflect"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/httprunner/httprunner/hrp/internal/builtin"
@@ -20,17 +24,31 @@ func NewConfig(name string) *TConfig {
 // TConfig represents config data structure for testcase.
 // Each testcase should contain one config part.
 type TConfig struct {
-	Name              string                 `json:"name" yaml:"name"` // required
-	Verify            bool                   `json:"verify,omitempty" yaml:"verify,omitempty"`
-	BaseURL           string                 `json:"base_url,omitempty" yaml:"base_url,omitempty"`
-	Headers           map[string]string      `json:"headers,omitempty" yaml:"headers,omitempty"`
-	Variables         map[string]interface{} `json:"variables,omitempty" yaml:"variables,omitempty"`
-	Parameters        map[string]interface{} `json:"parameters,omitempty" yaml:"parameters,omitempty"`
-	ParametersSetting *TParamsConfig         `json:"parameters_setting,omitempty" yaml:"parameters_setting,omitempty"`
-	ThinkTimeSetting  *ThinkTimeConfig       `json:"think_time,omitempty" yaml:"think_time,omitempty"`
-	Export            []string               `json:"export,omitempty" yaml:"export,omitempty"`
-	Weight            int                    `json:"weight,omitempty" yaml:"weight,omitempty"`
-	Path              string                 `json:"path,omitempty" yaml:"path,omitempty"` // testcase file path
+	Name              string                   `json:"name" yaml:"name"` // required
+	Verify            bool                     `json:"verify,omitempty" yaml:"verify,omitempty"`
+	BaseURL           string                   `json:"base_url,omitempty" yaml:"base_url,omitempty"`
+	Headers           map[string]string        `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Variables         map[string]interface{}   `json:"variables,omitempty" yaml:"variables,omitempty"`
+	Readonly          []string                 `json:"readonly,omitempty" yaml:"readonly,omitempty"`             // config variables which can not be overridden by extracted or step variables
+	LazyVariables     map[string]*LazyVariable `json:"lazy_variables,omitempty" yaml:"lazy_variables,omitempty"` // variables evaluated when referenced
+	Parameters        map[string]interface{}   `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	ParametersSetting *TParamsConfig           `json:"parameters_setting,omitempty" yaml:"parameters_setting,omitempty"`
+	ThinkTimeSetting  *ThinkTimeConfig         `json:"think_time,omitempty" yaml:"think_time,omitempty"`
+	Export            []string                 `json:"export,omitempty" yaml:"export,omitempty"`
+	Weight            int                      `json:"weight,omitempty" yaml:"weight,omitempty"`
+	MaxBodySize       int64                    `json:"max_body_size,omitempty" yaml:"max_body_size,omitempty"` // max bytes of response body to read, unlimited if <= 0
+	Deadline          float64                  `json:"deadline,omitempty" yaml:"deadline,omitempty"`           // seconds of time budget for the whole testcase, unlimited if <= 0
+	Gates             []string                 `json:"gates,omitempty" yaml:"gates,omitempty"`                 // performance gates of load testing, e.g. p95 < 300ms, error_rate < 0.1%
+	Transport         *TransportConfig         `json:"transport,omitempty" yaml:"transport,omitempty"`
+	UserAgent         *UserAgentConfig         `json:"user_agent,omitempty" yaml:"user_agent,omitempty"` // User-Agent profiles rotated for requests without explicit User-Agent header
+	Databases         map[string]*DBConfig     `json:"databases,omitempty" yaml:"databases,omitempty"`   // database name => connection, queried by database steps
+	Redis             map[string]*RedisConfig  `json:"redis,omitempty" yaml:"redis,omitempty"`           // redis instance name => connection, used by redis steps
+	IdempotencyKey    *IdempotencyKeyConfig    `json:"idempotency_key,omitempty" yaml:"idempotency_key,omitempty"`
+	CorrelationID     *CorrelationIDConfig     `json:"correlation_id,omitempty" yaml:"correlation_id,omitempty"`
+	JWT               *JWTConfig               `json:"jwt,omitempty" yaml:"jwt,omitempty"`                               // keys verifying jwt of responses
+	RequestTransform  *RequestTransform        `json:"request_transform,omitempty" yaml:"request_transform,omitempty"`   // transform of request body for request steps, e.g. encryption and signature
+	ResponseTransform *ResponseTransform       `json:"response_transform,omitempty" yaml:"response_transform,omitempty"` // transform of response body for request steps, e.g. decryption
+	Path              string                   `json:"path,omitempty" yaml:"path,omitempty"`                             // testcase file path
 }
 
 // WithVariables sets variables for current testcase.
@@ -69,6 +87,22 @@ func (c *TConfig) SetThinkTime(strategy thinkTimeStrategy, cfg interface{}, limi
 	return c
 }
 
+// SetReadonly marks config variables as readonly, which can not be overridden by extracted or step variables.
+func (c *TConfig) SetReadonly(names ...string) *TConfig {
+	c.Readonly = names
+	return c
+}
+
+// WithLazyVariable adds variable whose value expression is evaluated when referenced,
+// eval is once to reuse the first evaluated value in testcase, or always to evaluate on each reference.
+func (c *TConfig) WithLazyVariable(name string, value interface{}, eval lazyEvalMode) *TConfig {
+	if c.LazyVariables == nil {
+		c.LazyVariables = make(map[string]*LazyVariable)
+	}
+	c.LazyVariables[name] = &LazyVariable{Value: value, Eval: eval}
+	return c
+}
+
 // ExportVars specifies variable names to export for current testcase.
 func (c *TConfig) ExportVars(vars ...string) *TConfig {
 	c.Export = vars
@@ -81,6 +115,72 @@ func (c *TConfig) SetWeight(weight int) *TConfig {
 	return c
 }
 
+// SetMaxBodySize sets max bytes of response body to read for current testcase,
+// the rest of giant responses is discarded instead of being buffered.
+func (c *TConfig) SetMaxBodySize(size int64) *TConfig {
+	c.MaxBodySize = size
+	return c
+}
+
+// SetDeadline sets time budget in seconds for the whole testcase, timeout of each request is bounded by
+// remaining budget, and the testcase fails with timeout once budget is exhausted.
+func (c *TConfig) SetDeadline(seconds float64) *TConfig {
+	c.Deadline = seconds
+	return c
+}
+
+// SetGates sets performance gates evaluated at the end of load testing, e.g. p95 < 300ms, error_rate < 0.1%,
+// hrp boom exits with non-zero code if any gate is breached.
+func (c *TConfig) SetGates(gates ...string) *TConfig {
+	c.Gates = append(c.Gates, gates...)
+	return c
+}
+
+// SetTransport sets http transport tuning for current testcase, e.g. max idle connections per host.
+func (c *TConfig) SetTransport(transport *TransportConfig) *TConfig {
+	c.Transport = transport
+	return c
+}
+
+// SetUserAgents sets User-Agent profiles with rotation strategy for current testcase,
+// which are applied to requests without explicit User-Agent header.
+func (c *TConfig) SetUserAgents(strategy userAgentStrategy, profiles ...string) *TConfig {
+	c.UserAgent = &UserAgentConfig{Strategy: strategy, Profiles: profiles}
+	return c
+}
+
+type userAgentStrategy string
+
+const (
+	userAgentFixed      userAgentStrategy = "fixed"       // always use the first profile
+	userAgentRoundRobin userAgentStrategy = "round_robin" // use profiles in turn
+	userAgentRandom     userAgentStrategy = "random"      // use random profile
+)
+
+// UserAgentConfig rotates User-Agent profiles, which is useful for load realism and bot-detection testing.
+// rotation index is shared by all iterations of testcase in load testing.
+type UserAgentConfig struct {
+	index    uint64            // first field to keep 64-bit alignment for atomic operations
+	Strategy userAgentStrategy `json:"strategy,omitempty" yaml:"strategy,omitempty"` // fixed (default), round_robin, random
+	Profiles []string          `json:"profiles" yaml:"profiles"`
+}
+
+// next returns User-Agent profile according to strategy, empty if no profile is configured.
+func (c *UserAgentConfig) next() string {
+	if c == nil || len(c.Profiles) == 0 {
+		return ""
+	}
+	switch c.Strategy {
+	case userAgentRoundRobin:
+		i := atomic.AddUint64(&c.index, 1) - 1
+		return c.Profiles[i%uint64(len(c.Profiles))]
+	case userAgentRandom:
+		return c.Profiles[rand.Intn(len(c.Profiles))]
+	default:
+		return c.Profiles[0]
+	}
+}
+
 type ThinkTimeConfig struct {
 	Strategy thinkTimeStrategy `json:"strategy,omitempty" yaml:"strategy,omitempty"` // default、random、limit、multiply、ignore
 	Setting  interface{}       `json:"setting,omitempty" yaml:"setting,omitempty"`   // random(map): {"min_percentage": 0.5, "max_percentage": 1.5}; 10、multiply(float64): 1.5
@@ -142,6 +242,61 @@ func (ttc *ThinkTimeConfig) checkThinkTime() {
 	}
 }
 
+// ParseThinkTimeConfig parses think time setting in command line format, multiple settings are separated by comma.
+// e.g. ignore, multiply:0.5, random_percentage:0.5-1.5, limit:2s, multiply:0.5,limit:2
+func ParseThinkTimeConfig(setting string) (*ThinkTimeConfig, error) {
+	ttc := &ThinkTimeConfig{Strategy: thinkTimeDefault}
+	for _, item := range strings.Split(setting, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name := item
+		var value string
+		if i := strings.Index(item, ":"); i != -1 {
+			name, value = item[:i], item[i+1:]
+		}
+
+		switch thinkTimeStrategy(name) {
+		case thinkTimeDefault, thinkTimeIgnore:
+			ttc.Strategy = thinkTimeStrategy(name)
+		case thinkTimeMultiply:
+			multiply, err := strconv.ParseFloat(value, 64)
+			if err != nil || multiply <= 0 {
+				return nil, fmt.Errorf("invalid think time multiply: %s", item)
+			}
+			ttc.Strategy = thinkTimeMultiply
+			ttc.Setting = multiply
+		case thinkTimeRandomPercentage:
+			percentages := strings.Split(value, "-")
+			if len(percentages) != 2 {
+				return nil, fmt.Errorf("invalid think time random percentage: %s", item)
+			}
+			left, err1 := strconv.ParseFloat(percentages[0], 64)
+			right, err2 := strconv.ParseFloat(percentages[1], 64)
+			if err1 != nil || err2 != nil || left < 0 || left > right {
+				return nil, fmt.Errorf("invalid think time random percentage: %s", item)
+			}
+			ttc.Strategy = thinkTimeRandomPercentage
+			ttc.Setting = map[string]float64{"min_percentage": left, "max_percentage": right}
+		case "limit":
+			// limit in seconds, e.g. 2 or 2s or 500ms
+			limit, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				duration, err := time.ParseDuration(value)
+				if err != nil {
+					return nil, fmt.Errorf("invalid think time limit: %s", item)
+				}
+				limit = duration.Seconds()
+			}
+			ttc.Limit = limit
+		default:
+			return nil, fmt.Errorf("unsupported think time setting: %s", item)
+		}
+	}
+	return ttc, nil
+}
+
 type thinkTimeStrategy string
 
 const (
@@ -0,0 +1,79 @@
+package hrp
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// connMeta represents metadata of connection which response is received from, which can be extracted and
+// validated with jmespath, e.g. conn.remote_ip, conn.tls.version, conn.tls.cert_days_left.
+type connMeta struct {
+	RemoteIP   string   `json:"remote_ip,omitempty"`   // resolved server ip, or ip of proxy if request is sent via proxy
+	RemotePort string   `json:"remote_port,omitempty"` // server port
+	IPFamily   string   `json:"ip_family,omitempty"`   // ipv4 or ipv6
+	Protocol   string   `json:"protocol,omitempty"`    // HTTP/1.1, HTTP/2.0
+	TLS        *tlsMeta `json:"tls,omitempty"`         // not set for plain http
+}
+
+type tlsMeta struct {
+	Version            string `json:"version"`                       // TLS 1.0, TLS 1.1, TLS 1.2, TLS 1.3
+	CipherSuite        string `json:"cipher_suite"`                  // e.g. TLS_AES_128_GCM_SHA256
+	ServerName         string `json:"server_name,omitempty"`         // SNI sent by client
+	NegotiatedProtocol string `json:"negotiated_protocol,omitempty"` // ALPN, e.g. h2
+	CertSubject        string `json:"cert_subject,omitempty"`        // common name of server certificate
+	CertIssuer         string `json:"cert_issuer,omitempty"`         // common name of server certificate issuer
+	CertNotBefore      string `json:"cert_not_before,omitempty"`     // in RFC3339 format
+	CertNotAfter       string `json:"cert_not_after,omitempty"`      // in RFC3339 format
+	CertDaysLeft       int    `json:"cert_days_left"`                // days until server certificate expires, negative if expired
+}
+
+// newConnMeta creates connection metadata from response and traced address, nil is returned if both are unknown.
+func newConnMeta(resp *http.Response, address *Address) *connMeta {
+	if address == nil && resp.TLS == nil {
+		return nil
+	}
+	meta := &connMeta{Protocol: resp.Proto}
+	if address != nil {
+		meta.RemoteIP = address.ServerIP
+		meta.RemotePort = address.ServerPort
+		meta.IPFamily = address.IPFamily
+	}
+	if resp.TLS != nil {
+		meta.TLS = newTLSMeta(resp.TLS)
+	}
+	return meta
+}
+
+func newTLSMeta(state *tls.ConnectionState) *tlsMeta {
+	meta := &tlsMeta{
+		Version:            tlsVersionName(state.Version),
+		CipherSuite:        tls.CipherSuiteName(state.CipherSuite),
+		ServerName:         state.ServerName,
+		NegotiatedProtocol: state.NegotiatedProtocol,
+	}
+	if len(state.PeerCertificates) > 0 {
+		cert := state.PeerCertificates[0]
+		meta.CertSubject = cert.Subject.CommonName
+		meta.CertIssuer = cert.Issuer.CommonName
+		meta.CertNotBefore = cert.NotBefore.UTC().Format(time.RFC3339)
+		meta.CertNotAfter = cert.NotAfter.UTC().Format(time.RFC3339)
+		meta.CertDaysLeft = int(time.Until(cert.NotAfter).Hours() / 24)
+	}
+	return meta
+}
+
+func tlsVersionName(version uint16) string {
+	switch version {
+	case tls.VersionTLS10:
+		return "TLS 1.0"
+	case tls.VersionTLS11:
+		return "TLS 1.1"
+	case tls.VersionTLS12:
+		return "TLS 1.2"
+	case tls.VersionTLS13:
+		return "TLS 1.3"
+	}
+	return fmt.Sprintf("0x%04X", version)
+}
@@ -0,0 +1,58 @@
+package hrp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunRequestWithConnMeta(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})
+	ts := httptest.NewTLSServer(handler)
+	defer ts.Close()
+	plain := httptest.NewServer(handler)
+	defer plain.Close()
+
+	testcase := &TestCase{
+		Config: NewConfig("connection metadata").
+			WithVariables(map[string]interface{}{"plain": plain.URL}),
+		TestSteps: []IStep{
+			NewStep("https").GET(ts.URL).
+				Extract().
+				WithJmesPath("conn.tls.cert_not_after", "not_after").
+				Validate().
+				AssertEqual("conn.remote_ip", "127.0.0.1", "check remote ip").
+				AssertEqual("conn.ip_family", "ipv4", "check ip family").
+				AssertEqual("conn.protocol", "HTTP/1.1", "check protocol").
+				AssertStartsWith("conn.tls.version", "TLS 1.", "check tls version").
+				AssertExists("conn.tls.cipher_suite", "check cipher suite").
+				AssertCertValidFor(30, "check cert expires more than 30 days from now").
+				AssertGreater("conn.tls.cert_days_left", 30.0, "check numbers of different types").
+				AssertRFC3339("conn.tls.cert_not_after", "check cert expiry time"),
+			NewStep("http").GET("$plain").
+				Validate().
+				AssertEqual("conn.remote_ip", "127.0.0.1", "check remote ip").
+				AssertNotExists("conn.tls", "check no tls for plain http"),
+		},
+	}
+	sessionRunner := NewRunner(t).NewSessionRunner(testcase)
+	if !assert.Nil(t, sessionRunner.Start()) {
+		t.Fatal()
+	}
+	assert.Equal(t, ts.Certificate().NotAfter.UTC().Format("2006-01-02T15:04:05Z"),
+		sessionRunner.sessionVariables["not_after"])
+
+	testcase = &TestCase{
+		Config: NewConfig("certificate expiring"),
+		TestSteps: []IStep{
+			NewStep("https").GET(ts.URL).
+				Validate().
+				AssertCertValidFor(100000, "check cert expires more than 100000 days from now"),
+		},
+	}
+	assert.NotNil(t, NewRunner(nil).Run(testcase))
+}
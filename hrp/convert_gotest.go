@@ -0,0 +1,525 @@
+package hrp
+
+import (
+	"bytes"
+	builtinJSON "encoding/json"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// aliases of assert, which are converted into canonical names by code converters
+var assertAliases = map[string]string{
+	"eq":           "equals",
+	"equal":        "equals",
+	"lt":           "less_than",
+	"le":           "less_or_equals",
+	"gt":           "greater_than",
+	"ge":           "greater_or_equals",
+	"ne":           "not_equal",
+	"len_eq":       "length_equals",
+	"length_equal": "length_equals",
+	"len_lt":       "length_less_than",
+	"count_lt":     "length_less_than",
+	"len_le":       "length_less_or_equals",
+	"count_le":     "length_less_or_equals",
+	"len_gt":       "length_greater_than",
+	"count_gt":     "length_greater_than",
+	"len_ge":       "length_greater_or_equals",
+	"count_ge":     "length_greater_or_equals",
+	"str_eq":       "string_equals",
+	"set_eq":       "set_equals",
+}
+
+// canonicalAssert returns canonical name of assert alias.
+func canonicalAssert(name string) string {
+	if canonical, ok := assertAliases[name]; ok {
+		return canonical
+	}
+	return name
+}
+
+// builder methods of validators with (jmesPath, expected, msg) arguments
+var goTestExpectAsserts = map[string]string{
+	"equals":                   "AssertEqual",
+	"less_than":                "AssertLess",
+	"less_or_equals":           "AssertLessOrEqual",
+	"greater_than":             "AssertGreater",
+	"greater_or_equals":        "AssertGreaterOrEqual",
+	"not_equal":                "AssertNotEqual",
+	"contains":                 "AssertContains",
+	"type_match":               "AssertTypeMatch",
+	"startswith":               "AssertStartsWith",
+	"endswith":                 "AssertEndsWith",
+	"length_equals":            "AssertLengthEqual",
+	"length_less_than":         "AssertLengthLessThan",
+	"length_less_or_equals":    "AssertLengthLessOrEquals",
+	"length_greater_than":      "AssertLengthGreaterThan",
+	"length_greater_or_equals": "AssertLengthGreaterOrEquals",
+	"contained_by":             "AssertContainedBy",
+	"string_equals":            "AssertStringEqual",
+	"regex_match":              "AssertRegexp",
+	"equal_fold":               "AssertEqualFold",
+	"contains_fold":            "AssertContainsFold",
+	"set_equals":               "AssertSetEquals",
+}
+
+// builder methods of validators with (jmesPath, msg) arguments
+var goTestCheckAsserts = map[string]string{
+	"is_sorted_asc":     "AssertSortedAsc",
+	"is_sorted_desc":    "AssertSortedDesc",
+	"unique_items":      "AssertUniqueItems",
+	"is_rfc3339":        "AssertRFC3339",
+	"is_unix_timestamp": "AssertUnixTimestamp",
+	"exists":            "AssertExists",
+	"not_exists":        "AssertNotExists",
+}
+
+// ConvertGoTest converts json/yaml testcase file into go test file beside it, which builds testcase with
+// fluent NewStep api, e.g. demo.yaml is converted into demo_test.go.
+// Error is returned if steps or fields of testcase are not supported by fluent api, thus they could be kept in yaml.
+func ConvertGoTest(path string) (outputPath string, err error) {
+	tc, err := loadTCase(path)
+	if err != nil {
+		return "", errors.Wrap(err, "load testcase failed")
+	}
+	if tc.Config == nil {
+		return "", errors.New("config not found in testcase")
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	projectRootDir, err := getProjectRootDirPath(absPath)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get project root dir")
+	}
+
+	outputPath = strings.TrimSuffix(path, filepath.Ext(path)) + "_test.go"
+	g := &goTestGenerator{
+		projectRootDir: projectRootDir,
+		outputDir:      filepath.Dir(absPath),
+	}
+	if _, err := locatePlugin(absPath); err == nil {
+		// plugin is located by testcase path
+		g.casePath = filepath.Base(path)
+	}
+	code, err := g.generate(tc, filepath.Base(path))
+	if err != nil {
+		return "", errors.Wrapf(err, "convert %s to go test failed", path)
+	}
+	if err := os.WriteFile(outputPath, code, 0o644); err != nil {
+		return "", errors.Wrap(err, "write go test file failed")
+	}
+	log.Info().Str("path", outputPath).Msg("convert testcase to go test")
+	return outputPath, nil
+}
+
+type goTestGenerator struct {
+	projectRootDir string // referenced api and testcase paths are relative to project root dir
+	outputDir      string // referenced paths in go test are relative to output dir
+	casePath       string // testcase path set in config to locate plugin
+	buf            bytes.Buffer
+	refs           []string // declarations of referenced api and testcase paths
+}
+
+func (g *goTestGenerator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+func (g *goTestGenerator) generate(tc *TCase, fileName string) ([]byte, error) {
+	var steps bytes.Buffer
+	g.buf = bytes.Buffer{}
+	for _, step := range tc.TestSteps {
+		if err := g.genStep(step); err != nil {
+			return nil, errors.Wrapf(err, "step %q", step.Name)
+		}
+	}
+	steps.Write(g.buf.Bytes())
+
+	g.buf = bytes.Buffer{}
+	g.printf("// Converted by hrp convert --to-gotest from %s.\n\n", fileName)
+	g.printf("package %s\n\n", goPackageName(filepath.Base(g.outputDir)))
+	g.printf("import (\n\"testing\"\n\n\"github.com/httprunner/httprunner/hrp\"\n)\n\n")
+	g.printf("func %s(t *testing.T) {\n", goTestFuncName(fileName))
+	for _, ref := range g.refs {
+		g.printf("%s\n", ref)
+	}
+	if err := g.genConfig(tc.Config); err != nil {
+		return nil, errors.Wrap(err, "config")
+	}
+	g.printf("testcase := &hrp.TestCase{\nConfig: config,\nTestSteps: []hrp.IStep{\n")
+	g.buf.Write(steps.Bytes())
+	g.printf("},\n}\n\n")
+	g.printf("err := hrp.NewRunner(t).Run(testcase)\n")
+	g.printf("if err != nil {\nt.Fatalf(\"run testcase error: %%v\", err)\n}\n}\n")
+	return format.Source(g.buf.Bytes())
+}
+
+func (g *goTestGenerator) genConfig(config *TConfig) error {
+	if fields := unsupportedFields(config, "name", "verify", "base_url", "headers", "variables",
+		"readonly", "lazy_variables", "parameters", "export", "weight", "max_body_size", "path"); len(fields) > 0 {
+		return fmt.Errorf("fields %s not supported", strings.Join(fields, ", "))
+	}
+	g.printf("config := hrp.NewConfig(%s)", strconv.Quote(config.Name))
+	if config.BaseURL != "" {
+		g.printf(".\nSetBaseURL(%s)", strconv.Quote(config.BaseURL))
+	}
+	if config.Verify {
+		g.printf(".\nSetVerifySSL(true)")
+	}
+	if len(config.Headers) > 0 {
+		g.printf(".\nSetHeaders(%s)", goLiteral(config.Headers))
+	}
+	if len(config.Variables) > 0 {
+		g.printf(".\nWithVariables(%s)", goLiteral(config.Variables))
+	}
+	for _, name := range sortedKeys(config.LazyVariables) {
+		variable := config.LazyVariables[name]
+		g.printf(".\nWithLazyVariable(%s, %s, %s)", strconv.Quote(name), goLiteral(variable.Value), strconv.Quote(string(variable.Eval)))
+	}
+	if len(config.Readonly) > 0 {
+		g.printf(".\nSetReadonly(%s)", goStringArgs(config.Readonly))
+	}
+	if len(config.Parameters) > 0 {
+		g.printf(".\nWithParameters(%s)", goLiteral(config.Parameters))
+	}
+	if len(config.Export) > 0 {
+		g.printf(".\nExportVars(%s)", goStringArgs(config.Export))
+	}
+	if config.Weight != 0 {
+		g.printf(".\nSetWeight(%d)", config.Weight)
+	}
+	if config.MaxBodySize != 0 {
+		g.printf(".\nSetMaxBodySize(%d)", config.MaxBodySize)
+	}
+	g.printf("\n")
+	if g.casePath != "" {
+		g.printf("config.Path = %s // locate plugin by testcase path\n", strconv.Quote(g.casePath))
+	}
+	return nil
+}
+
+func (g *goTestGenerator) genStep(step *TStep) error {
+	if step.Request != nil {
+		return g.genRequestStep(step)
+	} else if step.API != nil {
+		return g.genRefStep(step, "api", step.API, "APIPath", "CallRefAPI")
+	} else if step.TestCase != nil {
+		return g.genRefStep(step, "testcase", step.TestCase, "TestCasePath", "CallRefCase")
+	} else if step.ThinkTime != nil {
+		if fields := unsupportedFields(step, "name", "think_time"); len(fields) > 0 {
+			return fmt.Errorf("fields %s not supported", strings.Join(fields, ", "))
+		}
+		g.printf("hrp.NewStep(%s).\nSetThinkTime(%s),\n",
+			strconv.Quote(step.Name), goLiteral(step.ThinkTime.Time))
+		return nil
+	} else if step.Transaction != nil {
+		if fields := unsupportedFields(step, "name", "transaction"); len(fields) > 0 {
+			return fmt.Errorf("fields %s not supported", strings.Join(fields, ", "))
+		}
+		method := "StartTransaction"
+		if step.Transaction.Type == transactionEnd {
+			method = "EndTransaction"
+		}
+		g.printf("hrp.NewStep(%s).\n%s(%s),\n",
+			strconv.Quote(step.Name), method, strconv.Quote(step.Transaction.Name))
+		return nil
+	}
+	return errors.New("step type not supported")
+}
+
+// genStepPrefix generates step with variables and setup hooks, which are set before request or reference.
+func (g *goTestGenerator) genStepPrefix(step *TStep) {
+	g.printf("hrp.NewStep(%s)", strconv.Quote(step.Name))
+	if len(step.Variables) > 0 {
+		g.printf(".\nWithVariables(%s)", goLiteral(step.Variables))
+	}
+	for _, hook := range step.SetupHooks {
+		g.printf(".\nSetupHook(%s)", strconv.Quote(hook))
+	}
+}
+
+func (g *goTestGenerator) genRequestStep(step *TStep) error {
+	if fields := unsupportedFields(step, "name", "request", "variables", "setup_hooks",
+		"teardown_hooks", "extract", "validate"); len(fields) > 0 {
+		return fmt.Errorf("fields %s not supported", strings.Join(fields, ", "))
+	}
+	request := step.Request
+	if fields := unsupportedFields(request, "method", "url", "params", "params_order", "params_encoded",
+		"raw_url", "headers", "cookies", "body", "body_file", "json", "data", "timeout",
+		"allow_redirects", "verify"); len(fields) > 0 {
+		return fmt.Errorf("request fields %s not supported", strings.Join(fields, ", "))
+	}
+
+	g.genStepPrefix(step)
+	switch request.Method {
+	case httpGET, httpHEAD, httpPOST, httpPUT, httpDELETE, httpOPTIONS, httpPATCH:
+		g.printf(".\n%s(%s)", request.Method, strconv.Quote(request.URL))
+	default:
+		g.printf(".\nCustomMethod(%s, %s)", strconv.Quote(string(request.Method)), strconv.Quote(request.URL))
+	}
+	if request.Verify {
+		g.printf(".\nSetVerify(true)")
+	}
+	if request.Timeout != 0 {
+		g.printf(".\nSetTimeout(%s)", strconv.FormatFloat(float64(request.Timeout), 'f', -1, 32))
+	}
+	if request.AllowRedirects {
+		g.printf(".\nSetAllowRedirects(true)")
+	}
+	if len(request.Params) > 0 {
+		g.printf(".\nWithParams(%s)", goLiteral(request.Params))
+	}
+	if len(request.ParamsOrder) > 0 {
+		g.printf(".\nWithParamsOrder(%s)", goStringArgs(request.ParamsOrder))
+	}
+	if request.ParamsEncoded {
+		g.printf(".\nWithEncodedParams()")
+	}
+	if request.RawURL {
+		g.printf(".\nWithRawURL()")
+	}
+
+	// json body is sent with json content type, the same as loading testcase
+	body := request.Body
+	headers := request.Headers
+	if body == nil && request.Json != nil {
+		body = request.Json
+		headers = make(map[string]string, len(request.Headers)+1)
+		for k, v := range request.Headers {
+			headers[k] = v
+		}
+		headers["Content-Type"] = "application/json; charset=utf-8"
+	} else if body == nil {
+		body = request.Data
+	}
+	if len(headers) > 0 {
+		g.printf(".\nWithHeaders(%s)", goLiteral(headers))
+	}
+	if len(request.Cookies) > 0 {
+		g.printf(".\nWithCookies(%s)", goLiteral(request.Cookies))
+	}
+	if body != nil {
+		g.printf(".\nWithBody(%s)", goLiteral(body))
+	} else if request.BodyFile != "" {
+		g.printf(".\nWithBodyFile(%s)", strconv.Quote(request.BodyFile))
+	}
+	for _, hook := range step.TeardownHooks {
+		g.printf(".\nTeardownHook(%s)", strconv.Quote(hook))
+	}
+	if err := g.genExtractAndValidate(step); err != nil {
+		return err
+	}
+	g.printf(",\n")
+	return nil
+}
+
+// genRefStep generates step calling referenced api or testcase, path is declared as variable
+// since reference requires pointer of path.
+func (g *goTestGenerator) genRefStep(step *TStep, field string, ref interface{}, pathType, method string) error {
+	if fields := unsupportedFields(step, "name", field, "variables", "setup_hooks",
+		"teardown_hooks", "export"); len(fields) > 0 {
+		return fmt.Errorf("fields %s not supported", strings.Join(fields, ", "))
+	}
+	refPath, ok := ref.(string)
+	if !ok {
+		return fmt.Errorf("referenced %s path should be string", field)
+	}
+	path, err := filepath.Rel(g.outputDir, filepath.Join(g.projectRootDir, refPath))
+	if err != nil {
+		return errors.Wrapf(err, "resolve referenced %s path failed", field)
+	}
+	name := fmt.Sprintf("ref%s%d", pathType, len(g.refs)+1)
+	g.refs = append(g.refs, fmt.Sprintf("%s := hrp.%s(%s)", name, pathType, strconv.Quote(filepath.ToSlash(path))))
+
+	g.genStepPrefix(step)
+	g.printf(".\n%s(&%s)", method, name)
+	for _, hook := range step.TeardownHooks {
+		g.printf(".\nTeardownHook(%s)", strconv.Quote(hook))
+	}
+	if len(step.Export) > 0 {
+		g.printf(".\nExport(%s)", goStringArgs(step.Export))
+	}
+	g.printf(",\n")
+	return nil
+}
+
+func (g *goTestGenerator) genExtractAndValidate(step *TStep) error {
+	if len(step.Extract) > 0 {
+		g.printf(".\nExtract()")
+		for _, varName := range sortedKeys(step.Extract) {
+			g.printf(".\nWithJmesPath(%s, %s)", strconv.Quote(step.Extract[varName]), strconv.Quote(varName))
+		}
+	}
+	if len(step.Validators) == 0 {
+		return nil
+	}
+	if err := convertCompatValidator(step.Validators); err != nil {
+		return err
+	}
+	g.printf(".\nValidate()")
+	for _, iValidator := range step.Validators {
+		validator := iValidator.(Validator)
+		if validator.Not != nil || len(validator.AnyOf) > 0 || validator.ExpectPath != "" ||
+			len(validator.Ignore) > 0 || validator.Epsilon != 0 {
+			return fmt.Errorf("validator %s of %s not supported", validator.Assert, validator.Check)
+		}
+		assertName := canonicalAssert(validator.Assert)
+		if method, ok := goTestExpectAsserts[assertName]; ok {
+			g.printf(".\n%s(%s, %s, %s)", method, strconv.Quote(validator.Check),
+				goLiteral(validator.Expect), strconv.Quote(validator.Message))
+			continue
+		}
+		method, ok := goTestCheckAsserts[assertName]
+		if ok && (validator.Expect == nil || validator.Expect == true) {
+			g.printf(".\n%s(%s, %s)", method, strconv.Quote(validator.Check), strconv.Quote(validator.Message))
+			continue
+		}
+		return fmt.Errorf("validator %s of %s not supported", validator.Assert, validator.Check)
+	}
+	return nil
+}
+
+// unsupportedFields returns json names of non-zero fields of struct pointer except supported fields.
+func unsupportedFields(ptr interface{}, supported ...string) []string {
+	supportedFields := make(map[string]bool, len(supported))
+	for _, name := range supported {
+		supportedFields[name] = true
+	}
+	var fields []string
+	value := reflect.ValueOf(ptr).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		name := strings.Split(value.Type().Field(i).Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" || supportedFields[name] {
+			continue
+		}
+		if !value.Field(i).IsZero() {
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
+// goLiteral returns go literal of value decoded from json/yaml testcase.
+func goLiteral(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "nil"
+	case string:
+		return strconv.Quote(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float64:
+		s := strconv.FormatFloat(v, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".eEn") { // keep float type, e.g. 1.0
+			s += ".0"
+		}
+		return s
+	case builtinJSON.Number:
+		if _, err := v.Int64(); err == nil {
+			return v.String()
+		}
+		f, _ := v.Float64()
+		return goLiteral(f)
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = goLiteral(item)
+		}
+		return "[]interface{}{" + strings.Join(items, ", ") + "}"
+	case map[string]interface{}:
+		var b strings.Builder
+		b.WriteString("map[string]interface{}{")
+		for _, key := range sortedKeys(v) {
+			fmt.Fprintf(&b, "\n%s: %s,", strconv.Quote(key), goLiteral(v[key]))
+		}
+		if len(v) > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString("}")
+		return b.String()
+	case map[string]string:
+		var b strings.Builder
+		b.WriteString("map[string]string{")
+		for _, key := range sortedKeys(v) {
+			fmt.Fprintf(&b, "\n%s: %s,", strconv.Quote(key), strconv.Quote(v[key]))
+		}
+		if len(v) > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString("}")
+		return b.String()
+	}
+	return fmt.Sprintf("%#v", value)
+}
+
+// sortedKeys returns sorted keys of map[string]interface{} or map[string]string.
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	for _, key := range reflect.ValueOf(m).MapKeys() {
+		keys = append(keys, key.String())
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func goStringArgs(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = strconv.Quote(value)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// goTestFuncName converts file name into test function name, e.g. demo_requests.yml => TestCaseDemoRequests.
+func goTestFuncName(fileName string) string {
+	name := strings.TrimSuffix(fileName, filepath.Ext(fileName))
+	var b strings.Builder
+	b.WriteString("TestCase")
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// goPackageName converts folder name into package name, e.g. request-methods => request_methods.
+func goPackageName(dirName string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(dirName) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+	name := b.String()
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		name = "testcases_" + name
+	}
+	return strings.TrimRight(name, "_")
+}
@@ -0,0 +1,123 @@
+package hrp
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const goTestConvertYAML = `config:
+    name: login demo
+    base_url: https://example.com
+    variables:
+        user: admin
+        retry: 3
+teststeps:
+    - name: start
+      transaction:
+          name: login
+          type: start
+    - name: login
+      setup_hooks:
+          - ${sleep(1)}
+      request:
+          method: POST
+          url: /login
+          json:
+              user: $user
+              ratio: 1.0
+      extract:
+          token: body.token
+      validate:
+          - eq: ["status_code", 200]
+          - check: body.roles
+            assert: length_greater_than
+            expect: 0
+            msg: check roles
+          - exists: ["body.token"]
+    - name: wait
+      think_time:
+          time: 1.5
+    - name: profile
+      testcase: profile.yml
+      export:
+          - nickname
+`
+
+func TestConvertGoTest(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "login-cases")
+	assert.Nil(t, os.MkdirAll(dir, 0o755))
+	path := filepath.Join(dir, "login_demo.yml")
+	assert.Nil(t, os.WriteFile(path, []byte(goTestConvertYAML), 0o644))
+
+	outputPath, err := ConvertGoTest(path)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	assert.Equal(t, filepath.Join(dir, "login_demo_test.go"), outputPath)
+	content, err := os.ReadFile(outputPath)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	code := string(content)
+	assert.Contains(t, code, "package login_cases\n")
+	assert.Contains(t, code, "func TestCaseLoginDemo(t *testing.T) {\n")
+	assert.Contains(t, code, `hrp.NewStep("start").
+				StartTransaction("login"),`)
+	assert.Contains(t, code, `hrp.NewStep("login").
+				SetupHook("${sleep(1)}").
+				POST("/login").
+				WithHeaders(map[string]string{
+					"Content-Type": "application/json; charset=utf-8",
+				}).
+				WithBody(map[string]interface{}{
+					"ratio": 1.0,
+					"user":  "$user",
+				}).
+				Extract().
+				WithJmesPath("body.token", "token").
+				Validate().
+				AssertEqual("status_code", 200, "").
+				AssertLengthGreaterThan("body.roles", 0, "check roles").
+				AssertExists("body.token", ""),`)
+	assert.Contains(t, code, `hrp.NewStep("wait").
+				SetThinkTime(1.5),`)
+	assert.Contains(t, code, "CallRefCase(&refTestCasePath1).\n\t\t\t\tExport(\"nickname\"),")
+	assert.Contains(t, code, "err := hrp.NewRunner(t).Run(testcase)")
+
+	// steps not supported by fluent api are reported
+	path = filepath.Join(dir, "loop.yml")
+	assert.Nil(t, os.WriteFile(path, []byte(`config:
+    name: loop
+teststeps:
+    - name: poll
+      loop:
+          condition: ${is_pending($status)}
+          steps: []
+`), 0o644))
+	_, err = ConvertGoTest(path)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), `step "poll": step type not supported`)
+	}
+}
+
+func TestConvertGoTestCompiles(t *testing.T) {
+	// generated test is put in module, thus hrp package is resolved
+	dir, err := os.MkdirTemp(".", "gotest_")
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "login_demo.yml")
+	assert.Nil(t, os.WriteFile(path, []byte(goTestConvertYAML), 0o644))
+	if _, err := ConvertGoTest(path); !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	os.Remove(path)
+
+	output, err := exec.Command("go", "vet", "./"+filepath.Base(dir)).CombinedOutput()
+	assert.Nil(t, err, string(output))
+}
@@ -0,0 +1,710 @@
+package hrp
+
+import (
+	"bytes"
+	builtinJSON "encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+
+	"github.com/httprunner/httprunner/hrp/internal/builtin"
+)
+
+// assert methods of HttpRunner v3 python with (jmes_path, expected_value, message) arguments
+var pyTestAsserts = map[string]string{
+	"equals":                   "assert_equal",
+	"not_equal":                "assert_not_equal",
+	"greater_than":             "assert_greater_than",
+	"less_than":                "assert_less_than",
+	"greater_or_equals":        "assert_greater_or_equals",
+	"less_or_equals":           "assert_less_or_equals",
+	"length_equals":            "assert_length_equal",
+	"length_greater_than":      "assert_length_greater_than",
+	"length_less_than":         "assert_length_less_than",
+	"length_greater_or_equals": "assert_length_greater_or_equals",
+	"length_less_or_equals":    "assert_length_less_or_equals",
+	"string_equals":            "assert_string_equals",
+	"startswith":               "assert_startswith",
+	"endswith":                 "assert_endswith",
+	"regex_match":              "assert_regex_match",
+	"contains":                 "assert_contains",
+	"contained_by":             "assert_contained_by",
+	"type_match":               "assert_type_match",
+}
+
+var pyTestMethods = map[string]HTTPMethod{
+	"get":     httpGET,
+	"post":    httpPOST,
+	"put":     httpPUT,
+	"head":    httpHEAD,
+	"delete":  httpDELETE,
+	"options": httpOPTIONS,
+	"patch":   httpPATCH,
+}
+
+// ConvertPyTest converts json/yaml testcase file into pytest file of HttpRunner v3 beside it,
+// e.g. demo.yaml is converted into demo_test.py, referenced testcases are imported as converted modules.
+// Error is returned if steps or fields of testcase are not supported by HttpRunner v3.
+func ConvertPyTest(path string) (outputPath string, err error) {
+	tc, err := loadTCase(path)
+	if err != nil {
+		return "", errors.Wrap(err, "load testcase failed")
+	}
+	if tc.Config == nil {
+		return "", errors.New("config not found in testcase")
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	projectRootDir, err := getProjectRootDirPath(absPath)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get project root dir")
+	}
+
+	outputPath = filepath.Join(filepath.Dir(path), pyModuleName(filepath.Base(path))+".py")
+	g := &pyTestGenerator{projectRootDir: projectRootDir}
+	code, err := g.generate(tc, filepath.Base(path))
+	if err != nil {
+		return "", errors.Wrapf(err, "convert %s to pytest failed", path)
+	}
+	if err := os.WriteFile(outputPath, code, 0o644); err != nil {
+		return "", errors.Wrap(err, "write pytest file failed")
+	}
+	log.Info().Str("path", outputPath).Msg("convert testcase to pytest")
+	return outputPath, nil
+}
+
+type pyTestGenerator struct {
+	projectRootDir string // referenced testcase paths are relative to project root dir
+	buf            bytes.Buffer
+	imports        []string // import statements of referenced testcases
+}
+
+func (g *pyTestGenerator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+func (g *pyTestGenerator) generate(tc *TCase, fileName string) ([]byte, error) {
+	g.buf = bytes.Buffer{}
+	if err := g.genConfig(tc.Config); err != nil {
+		return nil, errors.Wrap(err, "config")
+	}
+	g.printf("    teststeps = [\n")
+	for _, step := range tc.TestSteps {
+		if err := g.genStep(step); err != nil {
+			return nil, errors.Wrapf(err, "step %q", step.Name)
+		}
+	}
+	g.printf("    ]\n")
+	body := g.buf.Bytes()
+
+	className := goTestFuncName(fileName)
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# Converted by hrp convert --to-pytest from %s.\n\n", fileName)
+	b.WriteString("from httprunner import HttpRunner, Config, Step, RunRequest, RunTestCase\n")
+	for _, statement := range g.imports {
+		fmt.Fprintf(&b, "%s\n", statement)
+	}
+	fmt.Fprintf(&b, "\n\nclass %s(HttpRunner):\n\n", className)
+	b.Write(body)
+	fmt.Fprintf(&b, "\n\nif __name__ == \"__main__\":\n    %s().test_start()\n", className)
+	return b.Bytes(), nil
+}
+
+func (g *pyTestGenerator) genConfig(config *TConfig) error {
+	if fields := unsupportedFields(config, "name", "verify", "base_url", "variables",
+		"export", "weight", "path"); len(fields) > 0 {
+		return fmt.Errorf("fields %s not supported", strings.Join(fields, ", "))
+	}
+	g.printf("    config = (\n        Config(%s)\n", strconv.Quote(config.Name))
+	if len(config.Variables) > 0 {
+		g.printf("        .variables(**%s)\n", pyLiteral(config.Variables))
+	}
+	if config.BaseURL != "" {
+		g.printf("        .base_url(%s)\n", strconv.Quote(config.BaseURL))
+	}
+	g.printf("        .verify(%s)\n", pyLiteral(config.Verify))
+	if len(config.Export) > 0 {
+		g.printf("        .export(*%s)\n", pyLiteral(config.Export))
+	}
+	if config.Weight != 0 {
+		g.printf("        .locust_weight(%d)\n", config.Weight)
+	}
+	g.printf("    )\n\n")
+	return nil
+}
+
+func (g *pyTestGenerator) genStep(step *TStep) error {
+	if step.Request != nil {
+		return g.genRequestStep(step)
+	} else if step.TestCase != nil {
+		return g.genRefStep(step)
+	}
+	return errors.New("step type not supported")
+}
+
+// genStepPrefix generates step with variables and setup hooks, which are set before request or reference.
+func (g *pyTestGenerator) genStepPrefix(step *TStep, stepType string) {
+	g.printf("        Step(\n            %s(%s)\n", stepType, strconv.Quote(step.Name))
+	if len(step.Variables) > 0 {
+		g.printf("            .with_variables(**%s)\n", pyLiteral(step.Variables))
+	}
+	for _, hook := range step.SetupHooks {
+		g.printf("            .setup_hook(%s)\n", strconv.Quote(hook))
+	}
+}
+
+func (g *pyTestGenerator) genRequestStep(step *TStep) error {
+	if fields := unsupportedFields(step, "name", "request", "variables", "setup_hooks",
+		"teardown_hooks", "extract", "validate"); len(fields) > 0 {
+		return fmt.Errorf("fields %s not supported", strings.Join(fields, ", "))
+	}
+	request := step.Request
+	if fields := unsupportedFields(request, "method", "url", "params", "headers", "cookies",
+		"body", "json", "data", "timeout", "allow_redirects", "verify"); len(fields) > 0 {
+		return fmt.Errorf("request fields %s not supported", strings.Join(fields, ", "))
+	}
+	var method string
+	for name, m := range pyTestMethods {
+		if m == request.Method {
+			method = name
+		}
+	}
+	if method == "" {
+		return fmt.Errorf("request method %s not supported", request.Method)
+	}
+
+	g.genStepPrefix(step, "RunRequest")
+	g.printf("            .%s(%s)\n", method, strconv.Quote(request.URL))
+	if len(request.Params) > 0 {
+		g.printf("            .with_params(**%s)\n", pyLiteral(request.Params))
+	}
+	if len(request.Headers) > 0 {
+		g.printf("            .with_headers(**%s)\n", pyLiteral(request.Headers))
+	}
+	if len(request.Cookies) > 0 {
+		g.printf("            .with_cookies(**%s)\n", pyLiteral(request.Cookies))
+	}
+	// body of string is sent as is, other body is encoded as json
+	if _, ok := request.Body.(string); ok {
+		g.printf("            .with_data(%s)\n", pyLiteral(request.Body))
+	} else if request.Body != nil {
+		g.printf("            .with_json(%s)\n", pyLiteral(request.Body))
+	}
+	if request.Json != nil {
+		g.printf("            .with_json(%s)\n", pyLiteral(request.Json))
+	}
+	if request.Data != nil {
+		g.printf("            .with_data(%s)\n", pyLiteral(request.Data))
+	}
+	if request.Timeout != 0 {
+		g.printf("            .set_timeout(%s)\n", strconv.FormatFloat(float64(request.Timeout), 'f', -1, 32))
+	}
+	if request.Verify {
+		g.printf("            .set_verify(True)\n")
+	}
+	if request.AllowRedirects {
+		g.printf("            .set_allow_redirects(True)\n")
+	}
+	for _, hook := range step.TeardownHooks {
+		g.printf("            .teardown_hook(%s)\n", strconv.Quote(hook))
+	}
+	if len(step.Extract) > 0 {
+		g.printf("            .extract()\n")
+		for _, varName := range sortedKeys(step.Extract) {
+			g.printf("            .with_jmespath(%s, %s)\n", strconv.Quote(step.Extract[varName]), strconv.Quote(varName))
+		}
+	}
+	if len(step.Validators) > 0 {
+		if err := convertCompatValidator(step.Validators); err != nil {
+			return err
+		}
+		g.printf("            .validate()\n")
+		for _, iValidator := range step.Validators {
+			validator := iValidator.(Validator)
+			method, ok := pyTestAsserts[canonicalAssert(validator.Assert)]
+			if !ok || validator.Not != nil || len(validator.AnyOf) > 0 || validator.ExpectPath != "" ||
+				len(validator.Ignore) > 0 || validator.Epsilon != 0 {
+				return fmt.Errorf("validator %s of %s not supported", validator.Assert, validator.Check)
+			}
+			g.printf("            .%s(%s, %s", method, strconv.Quote(validator.Check), pyLiteral(validator.Expect))
+			if validator.Message != "" {
+				g.printf(", %s", strconv.Quote(validator.Message))
+			}
+			g.printf(")\n")
+		}
+	}
+	g.printf("        ),\n")
+	return nil
+}
+
+// genRefStep generates step calling referenced testcase, which is imported from converted pytest module.
+func (g *pyTestGenerator) genRefStep(step *TStep) error {
+	if fields := unsupportedFields(step, "name", "testcase", "variables", "setup_hooks",
+		"teardown_hooks", "export"); len(fields) > 0 {
+		return fmt.Errorf("fields %s not supported", strings.Join(fields, ", "))
+	}
+	refPath, ok := step.TestCase.(string)
+	if !ok {
+		return errors.New("referenced testcase path should be string")
+	}
+	var modules []string
+	for _, dir := range strings.Split(filepath.ToSlash(filepath.Dir(refPath)), "/") {
+		if dir != "." && dir != "" {
+			modules = append(modules, pyIdentifier(dir))
+		}
+	}
+	modules = append(modules, pyModuleName(filepath.Base(refPath)))
+	className := goTestFuncName(filepath.Base(refPath))
+	statement := fmt.Sprintf("from %s import %s", strings.Join(modules, "."), className)
+	if !builtin.Contains(g.imports, statement) {
+		g.imports = append(g.imports, statement)
+	}
+
+	g.genStepPrefix(step, "RunTestCase")
+	g.printf("            .call(%s)\n", className)
+	for _, hook := range step.TeardownHooks {
+		g.printf("            .teardown_hook(%s)\n", strconv.Quote(hook))
+	}
+	if len(step.Export) > 0 {
+		g.printf("            .export(*%s)\n", pyLiteral(step.Export))
+	}
+	g.printf("        ),\n")
+	return nil
+}
+
+// pyLiteral returns python literal of value decoded from json/yaml testcase.
+func pyLiteral(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "None"
+	case bool:
+		if v {
+			return "True"
+		}
+		return "False"
+	case float64:
+		s := strconv.FormatFloat(v, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".eEn") { // keep float type, e.g. 1.0
+			s += ".0"
+		}
+		return s
+	case builtinJSON.Number:
+		if _, err := v.Int64(); err == nil {
+			return v.String()
+		}
+		f, _ := v.Float64()
+		return pyLiteral(f)
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = pyLiteral(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case []string:
+		return "[" + goStringArgs(v) + "]"
+	case map[string]interface{}:
+		items := make([]string, 0, len(v))
+		for _, key := range sortedKeys(v) {
+			items = append(items, fmt.Sprintf("%s: %s", strconv.Quote(key), pyLiteral(v[key])))
+		}
+		return "{" + strings.Join(items, ", ") + "}"
+	case map[string]string:
+		items := make([]string, 0, len(v))
+		for _, key := range sortedKeys(v) {
+			items = append(items, fmt.Sprintf("%s: %s", strconv.Quote(key), strconv.Quote(v[key])))
+		}
+		return "{" + strings.Join(items, ", ") + "}"
+	}
+	return goLiteral(value)
+}
+
+// pyIdentifier converts name into valid python identifier, e.g. request-methods => request_methods.
+func pyIdentifier(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+	identifier := b.String()
+	if identifier == "" || unicode.IsDigit(rune(identifier[0])) {
+		identifier = "T" + identifier
+	}
+	return identifier
+}
+
+// pyModuleName converts testcase file name into pytest module name, e.g. demo-login.yml => demo_login_test.
+func pyModuleName(fileName string) string {
+	return pyIdentifier(strings.TrimSuffix(fileName, filepath.Ext(fileName))) + "_test"
+}
+
+// ConvertFromPyTest converts pytest file of HttpRunner v3 into json/yaml testcase file beside it,
+// e.g. demo_test.py is converted into demo.yaml, dstFormat is json or yaml.
+// Referenced testcases are resolved from imported modules relative to project root dir.
+func ConvertFromPyTest(path, dstFormat string) (outputPath string, err error) {
+	if dstFormat != formatJSON && dstFormat != formatYAML {
+		return "", fmt.Errorf("unsupported target format %s", dstFormat)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", errors.Wrap(err, "read pytest file failed")
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	projectRootDir, err := getProjectRootDirPath(absPath)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get project root dir")
+	}
+
+	l := &pyTestLoader{projectRootDir: projectRootDir, ext: "." + dstFormat}
+	tc, err := l.load(content)
+	if err != nil {
+		return "", errors.Wrapf(err, "convert %s from pytest failed", path)
+	}
+	data, err := yaml.Marshal(tc)
+	if err != nil {
+		return "", errors.Wrap(err, "marshal testcase failed")
+	}
+	formatted, err := FormatTestCase(data, formatYAML, dstFormat)
+	if err != nil {
+		return "", err
+	}
+
+	name := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), "_test")
+	outputPath = filepath.Join(filepath.Dir(path), name+"."+dstFormat)
+	if err := os.WriteFile(outputPath, formatted, 0o644); err != nil {
+		return "", errors.Wrap(err, "write testcase file failed")
+	}
+	log.Info().Str("path", outputPath).Msg("convert pytest to testcase")
+	return outputPath, nil
+}
+
+type pyTestLoader struct {
+	projectRootDir string // referenced testcase paths are relative to project root dir
+	ext            string // extension of referenced testcase file if not found
+	imports        map[string]pyImport
+}
+
+func (l *pyTestLoader) load(content []byte) (*TCase, error) {
+	tokens, err := tokenizePython(string(content))
+	if err != nil {
+		return nil, err
+	}
+	p := &pyParser{tokens: tokens}
+	assigns, imports, err := p.parseModule()
+	if err != nil {
+		return nil, err
+	}
+	l.imports = imports
+
+	configChain, ok := assigns["config"].(*pyChain)
+	if !ok {
+		return nil, errors.New("config not found in pytest")
+	}
+	config, err := l.loadConfig(configChain)
+	if err != nil {
+		return nil, errors.Wrap(err, "config")
+	}
+	steps, ok := assigns["teststeps"].([]interface{})
+	if !ok {
+		return nil, errors.New("teststeps not found in pytest")
+	}
+	tc := &TCase{Config: config}
+	for i, item := range steps {
+		step, err := l.loadStep(item)
+		if err != nil {
+			return nil, errors.Wrapf(err, "step %d", i+1)
+		}
+		tc.TestSteps = append(tc.TestSteps, step)
+	}
+	return tc, nil
+}
+
+func (l *pyTestLoader) loadConfig(chain *pyChain) (*TConfig, error) {
+	if chain.calls[0].name != "Config" {
+		return nil, fmt.Errorf("expect Config, got %s", chain.calls[0].name)
+	}
+	config := &TConfig{}
+	var err error
+	if config.Name, err = pyStringArg(chain.calls[0], 0, "name"); err != nil {
+		return nil, err
+	}
+	for _, call := range chain.calls[1:] {
+		switch call.name {
+		case "variables":
+			config.Variables = call.kwargs
+		case "base_url":
+			config.BaseURL, err = pyStringArg(call, 0, "base_url")
+		case "verify":
+			config.Verify, err = pyBoolArg(call, 0, "verify")
+		case "export":
+			config.Export, err = pyStringArgs(call)
+		case "locust_weight":
+			var weight interface{}
+			if weight, err = pyArg(call, 0, "weight"); err == nil {
+				w, ok := weight.(int)
+				if !ok {
+					return nil, errors.New("locust_weight should be int")
+				}
+				config.Weight = w
+			}
+		default:
+			return nil, fmt.Errorf("config method %s not supported", call.name)
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, call.name)
+		}
+	}
+	return config, nil
+}
+
+func (l *pyTestLoader) loadStep(item interface{}) (*TStep, error) {
+	chain, ok := item.(*pyChain)
+	if !ok || len(chain.calls) != 1 || chain.calls[0].name != "Step" || len(chain.calls[0].args) != 1 {
+		return nil, errors.New("step should be Step(RunRequest(...)) or Step(RunTestCase(...))")
+	}
+	chain, ok = chain.calls[0].args[0].(*pyChain)
+	if !ok {
+		return nil, errors.New("step should be Step(RunRequest(...)) or Step(RunTestCase(...))")
+	}
+	step := &TStep{}
+	var err error
+	if step.Name, err = pyStringArg(chain.calls[0], 0, "name"); err != nil {
+		return nil, err
+	}
+	isRequest := chain.calls[0].name == "RunRequest"
+	if !isRequest && chain.calls[0].name != "RunTestCase" {
+		return nil, fmt.Errorf("step type %s not supported", chain.calls[0].name)
+	}
+	if isRequest {
+		step.Request = &Request{}
+	}
+
+	for _, call := range chain.calls[1:] {
+		if err := l.loadStepCall(step, call, isRequest); err != nil {
+			return nil, errors.Wrapf(err, "step %q: %s", step.Name, call.name)
+		}
+	}
+	if isRequest && step.Request.Method == "" {
+		return nil, fmt.Errorf("step %q: request method not found", step.Name)
+	} else if !isRequest && step.TestCase == nil {
+		return nil, fmt.Errorf("step %q: referenced testcase not found", step.Name)
+	}
+	return step, nil
+}
+
+func (l *pyTestLoader) loadStepCall(step *TStep, call *pyCall, isRequest bool) (err error) {
+	// methods shared by request and testcase steps
+	switch call.name {
+	case "with_variables":
+		step.Variables = call.kwargs
+		return nil
+	case "setup_hook", "teardown_hook":
+		if len(call.args)+len(call.kwargs) > 1 {
+			return errors.New("hook with assigned variable not supported")
+		}
+		hook, err := pyStringArg(call, 0, "hook")
+		if err != nil {
+			return err
+		}
+		if call.name == "setup_hook" {
+			step.SetupHooks = append(step.SetupHooks, hook)
+		} else {
+			step.TeardownHooks = append(step.TeardownHooks, hook)
+		}
+		return nil
+	}
+
+	if !isRequest {
+		switch call.name {
+		case "call":
+			arg, err := pyArg(call, 0, "testcase")
+			if err != nil {
+				return err
+			}
+			className, ok := arg.(pyName)
+			if !ok {
+				return errors.New("referenced testcase should be class name")
+			}
+			step.TestCase, err = l.refTestCasePath(string(className))
+			return err
+		case "export":
+			step.Export, err = pyStringArgs(call)
+			return err
+		}
+		return errors.New("method not supported")
+	}
+
+	request := step.Request
+	if method, ok := pyTestMethods[call.name]; ok {
+		request.Method = method
+		request.URL, err = pyStringArg(call, 0, "url")
+		return err
+	}
+	switch call.name {
+	case "with_params":
+		request.Params = call.kwargs
+	case "with_headers":
+		request.Headers, err = pyStringMap(call.kwargs)
+	case "with_cookies":
+		request.Cookies, err = pyStringMap(call.kwargs)
+	case "with_data":
+		request.Data, err = pyArg(call, 0, "data")
+	case "with_json":
+		request.Json, err = pyArg(call, 0, "req_json")
+	case "set_timeout":
+		var timeout interface{}
+		if timeout, err = pyArg(call, 0, "timeout"); err == nil {
+			switch t := timeout.(type) {
+			case int:
+				request.Timeout = float32(t)
+			case float64:
+				request.Timeout = float32(t)
+			default:
+				err = errors.New("timeout should be number")
+			}
+		}
+	case "set_verify":
+		request.Verify, err = pyBoolArg(call, 0, "verify")
+	case "set_allow_redirects":
+		request.AllowRedirects, err = pyBoolArg(call, 0, "allow_redirects")
+	case "extract", "validate":
+	case "with_jmespath":
+		var jmesPath, varName string
+		if jmesPath, err = pyStringArg(call, 0, "jmes_path"); err != nil {
+			return err
+		}
+		if varName, err = pyStringArg(call, 1, "var_name"); err != nil {
+			return err
+		}
+		if step.Extract == nil {
+			step.Extract = make(map[string]string)
+		}
+		step.Extract[varName] = jmesPath
+	default:
+		return l.loadValidator(step, call)
+	}
+	return err
+}
+
+func (l *pyTestLoader) loadValidator(step *TStep, call *pyCall) error {
+	var assertName string
+	for name, method := range pyTestAsserts {
+		if method == call.name {
+			assertName = name
+		}
+	}
+	if assertName == "" {
+		return errors.New("method not supported")
+	}
+	check, err := pyStringArg(call, 0, "jmes_path")
+	if err != nil {
+		return err
+	}
+	expect, err := pyArg(call, 1, "expected_value")
+	if err != nil {
+		return err
+	}
+	validator := Validator{Check: check, Assert: assertName, Expect: expect}
+	if len(call.args) > 2 || call.kwargs["message"] != nil {
+		if validator.Message, err = pyStringArg(call, 2, "message"); err != nil {
+			return err
+		}
+	}
+	step.Validators = append(step.Validators, validator)
+	return nil
+}
+
+// refTestCasePath returns testcase path of imported class, e.g. TestCaseDemo imported from testcases.demo_test
+// is testcases/demo.yaml, existing testcase file in json or yaml is preferred.
+func (l *pyTestLoader) refTestCasePath(className string) (string, error) {
+	imported, ok := l.imports[className]
+	if !ok {
+		return "", fmt.Errorf("referenced testcase %s not imported", className)
+	}
+	module := strings.TrimSuffix(imported.module, "_test")
+	if module == "" || strings.HasPrefix(module, ".") {
+		return "", fmt.Errorf("relative import of %s not supported", className)
+	}
+	path := strings.ReplaceAll(module, ".", "/")
+	for _, ext := range []string{".yml", ".yaml", ".json"} {
+		if builtin.IsFilePathExists(filepath.Join(l.projectRootDir, path+ext)) {
+			return path + ext, nil
+		}
+	}
+	return path + l.ext, nil
+}
+
+func pyArg(call *pyCall, index int, name string) (interface{}, error) {
+	if index < len(call.args) {
+		return call.args[index], nil
+	}
+	if value, ok := call.kwargs[name]; ok {
+		return value, nil
+	}
+	return nil, fmt.Errorf("argument %s not found", name)
+}
+
+func pyStringArg(call *pyCall, index int, name string) (string, error) {
+	value, err := pyArg(call, index, name)
+	if err != nil {
+		return "", err
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("argument %s should be string", name)
+	}
+	return s, nil
+}
+
+func pyBoolArg(call *pyCall, index int, name string) (bool, error) {
+	value, err := pyArg(call, index, name)
+	if err != nil {
+		return false, err
+	}
+	b, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("argument %s should be bool", name)
+	}
+	return b, nil
+}
+
+func pyStringArgs(call *pyCall) ([]string, error) {
+	values := make([]string, len(call.args))
+	for i, arg := range call.args {
+		s, ok := arg.(string)
+		if !ok {
+			return nil, errors.New("arguments should be string")
+		}
+		values[i] = s
+	}
+	return values, nil
+}
+
+func pyStringMap(kwargs map[string]interface{}) (map[string]string, error) {
+	m := make(map[string]string, len(kwargs))
+	for k, v := range kwargs {
+		switch value := v.(type) {
+		case string:
+			m[k] = value
+		case int, float64, bool:
+			m[k] = fmt.Sprint(value)
+		default:
+			return nil, fmt.Errorf("value of %s should be string", k)
+		}
+	}
+	return m, nil
+}
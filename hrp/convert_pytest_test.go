@@ -0,0 +1,206 @@
+package hrp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/httprunner/httprunner/hrp/internal/builtin"
+)
+
+const pyTestConvertYAML = `config:
+    name: login demo
+    base_url: https://example.com
+    variables:
+        user: admin
+        retry: 3
+    export: ["token"]
+teststeps:
+    - name: login
+      setup_hooks:
+          - ${sleep(1)}
+      request:
+          method: POST
+          url: /login
+          headers:
+              X-Source: hrp
+          json:
+              user: $user
+              ratio: 1.0
+              remember: true
+      extract:
+          token: body.token
+      validate:
+          - eq: ["status_code", 200]
+          - check: body.roles
+            assert: length_greater_than
+            expect: 0
+            msg: check roles
+    - name: profile
+      variables:
+          token: $token
+      testcase: testcases/profile-info.yml
+      export:
+          - nickname
+`
+
+const pyTestV3 = `# NOTE: Generated By HttpRunner v3
+from httprunner import HttpRunner, Config, Step, RunRequest, RunTestCase
+from testcases.profile_info_test import TestCaseProfileInfo as Profile
+
+
+class TestCaseLogin(HttpRunner):
+
+    config = (
+        Config("login demo")
+        .variables(**{"user": 'admin', "ids": [1, -2, 3.5]})
+        .base_url("https://example.com")
+        .verify(False)
+        .locust_weight(2)
+    )
+
+    teststeps = [
+        Step(
+            RunRequest("login")
+            .post("/login")
+            .with_headers(**{"X-Source": "hrp"})
+            .with_data("a=1&b=2")  # form body
+            .set_timeout(1.5)
+            .extract()
+            .with_jmespath("body.token", "token")
+            .validate()
+            .assert_equal("status_code", 200)
+            .assert_contains("body.msg", "it's \"ok\"\n", message="check msg")
+        ),
+        Step(
+            RunTestCase("profile")
+            .call(Profile)
+            .export(*["nickname"])
+        ),
+    ]
+
+
+if __name__ == "__main__":
+    TestCaseLogin().test_start()
+`
+
+func TestConvertPyTest(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "testcases")
+	assert.Nil(t, os.MkdirAll(dir, 0o755))
+	path := filepath.Join(dir, "login-demo.yml")
+	assert.Nil(t, os.WriteFile(path, []byte(pyTestConvertYAML), 0o644))
+
+	outputPath, err := ConvertPyTest(path)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	assert.Equal(t, filepath.Join(dir, "login_demo_test.py"), outputPath)
+	content, err := os.ReadFile(outputPath)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	code := string(content)
+	assert.Contains(t, code, "from testcases.profile_info_test import TestCaseProfileInfo\n")
+	assert.Contains(t, code, "class TestCaseLoginDemo(HttpRunner):\n")
+	assert.Contains(t, code, `        Config("login demo")
+        .variables(**{"retry": 3, "user": "admin"})
+        .base_url("https://example.com")
+        .verify(False)
+        .export(*["token"])
+`)
+	assert.Contains(t, code, `            RunRequest("login")
+            .setup_hook("${sleep(1)}")
+            .post("/login")
+            .with_headers(**{"X-Source": "hrp"})
+            .with_json({"ratio": 1.0, "remember": True, "user": "$user"})
+            .extract()
+            .with_jmespath("body.token", "token")
+            .validate()
+            .assert_equal("status_code", 200)
+            .assert_length_greater_than("body.roles", 0, "check roles")
+        ),`)
+	assert.Contains(t, code, `            RunTestCase("profile")
+            .with_variables(**{"token": "$token"})
+            .call(TestCaseProfileInfo)
+            .export(*["nickname"])
+        ),`)
+	assert.Contains(t, code, "    TestCaseLoginDemo().test_start()\n")
+
+	// converted pytest is loaded back as the same testcase
+	assert.Nil(t, os.Remove(path))
+	outputPath, err = ConvertFromPyTest(outputPath, formatYAML)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	assert.Equal(t, filepath.Join(dir, "login_demo.yaml"), outputPath)
+	tc := &TCase{}
+	if !assert.Nil(t, builtin.LoadFile(outputPath, tc)) {
+		t.Fatal()
+	}
+	assert.Equal(t, "login demo", tc.Config.Name)
+	assert.Equal(t, []string{"token"}, tc.Config.Export)
+	assert.Equal(t, "$user", tc.TestSteps[0].Request.Json.(map[string]interface{})["user"])
+	assert.Equal(t, []string{"${sleep(1)}"}, tc.TestSteps[0].SetupHooks)
+	assert.Equal(t, "body.token", tc.TestSteps[0].Extract["token"])
+	assert.Len(t, tc.TestSteps[0].Validators, 2)
+	assert.Equal(t, "testcases/profile_info.yaml", tc.TestSteps[1].TestCase)
+
+	// steps not supported by HttpRunner v3 are reported
+	path = filepath.Join(dir, "wait.yml")
+	assert.Nil(t, os.WriteFile(path, []byte(`config:
+    name: wait
+teststeps:
+    - name: wait
+      think_time:
+          time: 1.5
+`), 0o644))
+	_, err = ConvertPyTest(path)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), `step "wait": step type not supported`)
+	}
+}
+
+func TestConvertFromPyTest(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "testcases")
+	assert.Nil(t, os.MkdirAll(dir, 0o755))
+	path := filepath.Join(dir, "login_test.py")
+	assert.Nil(t, os.WriteFile(path, []byte(pyTestV3), 0o644))
+
+	outputPath, err := ConvertFromPyTest(path, formatJSON)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	assert.Equal(t, filepath.Join(dir, "login.json"), outputPath)
+	tc := &TCase{}
+	if !assert.Nil(t, builtin.LoadFile(outputPath, tc)) {
+		t.Fatal()
+	}
+	assert.Equal(t, 2, tc.Config.Weight)
+	assert.Equal(t, "admin", tc.Config.Variables["user"])
+	assert.Len(t, tc.Config.Variables["ids"], 3)
+
+	request := tc.TestSteps[0].Request
+	assert.Equal(t, httpPOST, request.Method)
+	assert.Equal(t, "a=1&b=2", request.Data)
+	assert.Equal(t, float32(1.5), request.Timeout)
+	assert.Equal(t, map[string]string{"X-Source": "hrp"}, request.Headers)
+	if assert.Nil(t, convertCompatValidator(tc.TestSteps[0].Validators)) {
+		assert.Equal(t, Validator{
+			Check:   "body.msg",
+			Assert:  "contains",
+			Expect:  "it's \"ok\"\n",
+			Message: "check msg",
+		}, tc.TestSteps[0].Validators[1])
+	}
+	assert.Equal(t, "testcases/profile_info.json", tc.TestSteps[1].TestCase)
+	assert.Equal(t, []string{"nickname"}, tc.TestSteps[1].Export)
+
+	// unsupported python is reported
+	assert.Nil(t, os.WriteFile(path, []byte(`config = Config(f"demo {name}")`), 0o644))
+	_, err = ConvertFromPyTest(path, formatJSON)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "f-string not supported")
+	}
+}
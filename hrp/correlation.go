@@ -0,0 +1,63 @@
+package hrp
+
+import (
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+type correlationIDScope string
+
+const (
+	correlationIDPerTestCase correlationIDScope = "testcase" // one id shared by all requests of testcase
+	correlationIDPerStep     correlationIDScope = "step"     // new id for each request step
+)
+
+const (
+	defaultCorrelationIDHeader   = "X-Request-ID"
+	defaultCorrelationIDVariable = "request_id"
+)
+
+// CorrelationIDConfig generates correlation id attached to every request without explicit id header,
+// the id is recorded in logs and reports and exposed as variable, which eases correlating with backend logs.
+type CorrelationIDConfig struct {
+	Header   string             `json:"header,omitempty" yaml:"header,omitempty"`     // default to X-Request-ID
+	Template string             `json:"template,omitempty" yaml:"template,omitempty"` // default to random uuid, e.g. hrp-${gen_random_string(16)}
+	Scope    correlationIDScope `json:"scope,omitempty" yaml:"scope,omitempty"`       // testcase (default), step
+	Variable string             `json:"variable,omitempty" yaml:"variable,omitempty"` // variable name of generated id, default to request_id
+}
+
+// SetCorrelationID sets correlation id generation for requests of current testcase.
+func (c *TConfig) SetCorrelationID(cfg *CorrelationIDConfig) *TConfig {
+	c.CorrelationID = cfg
+	return c
+}
+
+func (c *CorrelationIDConfig) header() string {
+	if c.Header == "" {
+		return defaultCorrelationIDHeader
+	}
+	return c.Header
+}
+
+func (c *CorrelationIDConfig) variable() string {
+	if c.Variable == "" {
+		return defaultCorrelationIDVariable
+	}
+	return c.Variable
+}
+
+func (c *CorrelationIDConfig) perStep() bool {
+	return c.Scope == correlationIDPerStep
+}
+
+// generate renders id template with variables, random uuid is generated if template is not set.
+func (c *CorrelationIDConfig) generate(parser *Parser, variablesMapping map[string]interface{}) (string, error) {
+	if c.Template == "" {
+		return uuid.NewString(), nil
+	}
+	id, err := parser.Parse(c.Template, variablesMapping)
+	if err != nil {
+		return "", errors.Wrap(err, "generate correlation id failed")
+	}
+	return convertString(id), nil
+}
@@ -0,0 +1,78 @@
+package hrp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunCaseWithCorrelationID(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"request_id": "` + r.Header.Get("X-Request-ID") +
+			`", "trace_id": "` + r.Header.Get("X-Trace-ID") + `"}`))
+	}))
+	defer ts.Close()
+
+	testcase := &TestCase{
+		Config: NewConfig("correlation id per testcase").
+			SetBaseURL(ts.URL).
+			SetCorrelationID(&CorrelationIDConfig{}),
+		TestSteps: []IStep{
+			NewStep("get").GET("/get").
+				Validate().
+				AssertEqual("body.request_id", "$request_id", "check id attached"),
+			NewStep("post").POST("/post").
+				Validate().
+				AssertEqual("body.request_id", "$request_id", "check the same id attached"),
+			NewStep("explicit id").GET("/get").
+				WithHeaders(map[string]string{"X-Request-ID": "explicit"}).
+				Validate().
+				AssertEqual("body.request_id", "explicit", "check explicit id header"),
+		},
+	}
+	sessionRunner := NewRunner(t).NewSessionRunner(testcase)
+	if !assert.Nil(t, sessionRunner.Start()) {
+		t.Fatal()
+	}
+	summary := sessionRunner.GetSummary()
+	requestID := sessionRunner.sessionVariables["request_id"]
+	assert.Len(t, requestID, 36) // uuid
+	assert.Equal(t, requestID, summary.CorrelationID)
+	assert.Equal(t, requestID, summary.Records[0].CorrelationID)
+	assert.Equal(t, requestID, summary.Records[1].CorrelationID)
+	assert.Empty(t, summary.Records[2].CorrelationID)
+
+	testcase = &TestCase{
+		Config: NewConfig("correlation id per step").
+			SetBaseURL(ts.URL).
+			SetCorrelationID(&CorrelationIDConfig{
+				Header:   "X-Trace-ID",
+				Template: "hrp-${gen_random_string(8)}",
+				Scope:    correlationIDPerStep,
+				Variable: "trace_id",
+			}),
+		TestSteps: []IStep{
+			NewStep("step 1").GET("/get").
+				Extract().
+				WithJmesPath("body.trace_id", "trace_id_1").
+				Validate().
+				AssertEqual("body.trace_id", "$trace_id", "check id attached").
+				AssertEqual("body.request_id", "", "check default header not attached"),
+			NewStep("step 2").GET("/get").
+				Validate().
+				AssertEqual("body.trace_id", "$trace_id", "check id attached").
+				AssertNotEqual("body.trace_id", "$trace_id_1", "check new id generated"),
+		},
+	}
+	sessionRunner = NewRunner(t).NewSessionRunner(testcase)
+	if !assert.Nil(t, sessionRunner.Start()) {
+		t.Fatal()
+	}
+	summary = sessionRunner.GetSummary()
+	assert.Empty(t, summary.CorrelationID)
+	assert.Regexp(t, `^hrp-\w{8}$`, summary.Records[0].CorrelationID)
+	assert.Equal(t, sessionRunner.sessionVariables["trace_id_1"], summary.Records[0].CorrelationID)
+	assert.NotEqual(t, summary.Records[0].CorrelationID, summary.Records[1].CorrelationID)
+}
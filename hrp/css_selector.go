@@ -0,0 +1,100 @@
+package hrp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"github.com/pkg/errors"
+	"golang.org/x/net/html"
+)
+
+// cssExtractorPrefix is prefix of extractor locating elements of html body by css selector,
+// e.g. css:form input[name=csrf]|attr:value
+const cssExtractorPrefix = "css:"
+
+// cssExtractor extracts text, inner html, attribute or count of elements matched by css selector.
+type cssExtractor struct {
+	selector cascadia.Selector
+	target   string // text, html, attr or count
+	attr     string // attribute name of attr target
+	all      bool   // extract all matched elements into list
+}
+
+// newCSSExtractor compiles extractor in format of css:<selector>|<target>, target is one of
+// text (default), html, attr:<name> and count, prefixed with all: to extract all matched elements.
+func newCSSExtractor(expr string) (*cssExtractor, error) {
+	expr = strings.TrimPrefix(expr, cssExtractorPrefix)
+	selector, target := expr, "text"
+	// selector may contain | in attribute selector, e.g. [lang|=en]
+	if i := strings.LastIndex(expr, "|"); i >= 0 && isCSSTarget(strings.TrimSpace(expr[i+1:])) {
+		selector, target = expr[:i], strings.TrimSpace(expr[i+1:])
+	}
+	compiled, err := cascadia.Compile(strings.TrimSpace(selector))
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid css selector %s", selector)
+	}
+	extractor := &cssExtractor{selector: compiled}
+	if strings.HasPrefix(target, "all:") {
+		extractor.all = true
+		target = strings.TrimPrefix(target, "all:")
+	}
+	switch {
+	case target == "text", target == "html":
+		extractor.target = target
+	case target == "count" && !extractor.all:
+		extractor.target = target
+	case strings.HasPrefix(target, "attr:") && len(target) > len("attr:"):
+		extractor.target, extractor.attr = "attr", strings.TrimPrefix(target, "attr:")
+	default:
+		return nil, fmt.Errorf("invalid css extractor target %s, expect text, html, attr:<name> or count", target)
+	}
+	return extractor, nil
+}
+
+func isCSSTarget(target string) bool {
+	target = strings.TrimPrefix(target, "all:")
+	return target == "text" || target == "html" || target == "count" || strings.HasPrefix(target, "attr:")
+}
+
+// extract returns value of the first matched element, or values of all matched elements if all is set,
+// nil is returned if no element matched.
+func (e *cssExtractor) extract(doc *html.Node) interface{} {
+	selection := goquery.NewDocumentFromNode(doc).FindMatcher(e.selector)
+	if e.target == "count" {
+		return selection.Length()
+	}
+	if !e.all {
+		if selection.Length() == 0 {
+			return nil
+		}
+		value, _ := e.value(selection.First())
+		return value
+	}
+	values := []interface{}{}
+	selection.Each(func(_ int, s *goquery.Selection) {
+		if value, ok := e.value(s); ok {
+			values = append(values, value)
+		}
+	})
+	return values
+}
+
+func (e *cssExtractor) value(s *goquery.Selection) (interface{}, bool) {
+	switch e.target {
+	case "html":
+		content, err := s.Html()
+		if err != nil {
+			return nil, false
+		}
+		return content, true
+	case "attr":
+		value, ok := s.Attr(e.attr)
+		if !ok {
+			return nil, false
+		}
+		return value, true
+	}
+	return strings.TrimSpace(s.Text()), true
+}
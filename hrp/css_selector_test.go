@@ -0,0 +1,123 @@
+package hrp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+)
+
+const testLoginPage = `<html><body>
+<p lang="en-US">Welcome</p>
+<h1 class="title main">Sign in</h1>
+<form id="login" action="/sso/login" method="post">
+  <input type="hidden" name="csrf" value="token-123">
+  <input type="text" name="username" class="input">
+  <input type="password" name="password" class="input large">
+  <select name="lang"><option value="en">English</option><option value="zh" selected>中文</option></select>
+  <button type="submit">Login</button>
+</form>
+<ul id="links">
+  <li><a href="/help">Help</a></li>
+  <li class="active"><a href="/signup">Sign <b>up</b></a></li>
+  <li><a href="https://example.com/terms">Terms</a></li>
+</ul>
+</body></html>`
+
+func TestCSSExtractor(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(testLoginPage))
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	testData := []struct {
+		expr     string
+		expected interface{}
+	}{
+		{"css:form input[name=csrf]|attr:value", "token-123"},
+		{"css:#login > input[type='password']|attr:name", "password"},
+		{"css:input.input.large|attr:name", "password"},
+		{"css:h1.title", "Sign in"},
+		{"css:li.active a", "Sign up"},
+		{"css:li.active a|html", "Sign <b>up</b>"},
+		{"css:option:checked|attr:value", "zh"},
+		{"css:input|count", 3},
+		{"css:form input, button|count", 4},
+		{"css:a[href^=http]|attr:href", "https://example.com/terms"},
+		{"css:a[href$=up]|attr:href", "/signup"},
+		{"css:a[href*=el]", "Help"},
+		{"css:h1[class~=main]", "Sign in"},
+		{"css:li:first-child a", "Help"},
+		{"css:li:last-child a", "Terms"},
+		{"css:li:nth-child(2) > a|attr:href", "/signup"},
+		{"css:li:nth-child(odd) a|all:text", []interface{}{"Help", "Terms"}},
+		{"css:li:not(.active) a|all:attr:href", []interface{}{"/help", "https://example.com/terms"}},
+		{"css:li.active + li a", "Terms"},
+		{"css:h1 ~ ul li:contains('Help')", "Help"},
+		{"css:input|all:attr:class", []interface{}{"input", "input large"}},
+		{"css:[lang|=en]", "Welcome"},
+		{"css:table td", nil},
+		{"css:table td|all:text", []interface{}{}},
+	}
+	for _, data := range testData {
+		extractor, err := newCSSExtractor(data.expr)
+		if !assert.Nil(t, err, data.expr) {
+			continue
+		}
+		assert.Equal(t, data.expected, extractor.extract(doc), data.expr)
+	}
+
+	for _, expr := range []string{
+		"css:", "css:div[", "css:div >", "css:a:unknown", "css:li:nth-child(x)", "css:a|attr:", "css:a|all:count", "css:a|value",
+	} {
+		_, err := newCSSExtractor(expr)
+		assert.NotNil(t, err, expr)
+	}
+}
+
+func TestCSSSelectorExtraction(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(testLoginPage))
+		case "/sso/login":
+			r.ParseForm()
+			w.Header().Set("Content-Type", "application/json")
+			if r.PostForm.Get("csrf") != "token-123" {
+				w.WriteHeader(http.StatusForbidden)
+			}
+			w.Write([]byte(`{"user": "` + r.PostForm.Get("username") + `"}`))
+		}
+	}))
+	defer ts.Close()
+
+	testcase := &TestCase{
+		Config: NewConfig("css selector extraction").SetBaseURL(ts.URL),
+		TestSteps: []IStep{
+			NewStep("login page").
+				GET("/login").
+				Extract().
+				WithCSSSelector("form input[name=csrf]", "attr:value", "token").
+				WithCSSSelector("form#login", "attr:action", "action").
+				WithCSSSelector("h1", "", "title").
+				Validate().
+				AssertEqual("css:form input|count", 3, "check inputs count").
+				AssertEqual("css:button[type=submit]", "Login", "check submit button"),
+			NewStep("submit form").
+				POST("$action").
+				WithHeaders(map[string]string{"Content-Type": "application/x-www-form-urlencoded"}).
+				WithBody(map[string]interface{}{"csrf": "$token", "username": "alice"}).
+				Validate().
+				AssertEqual("status_code", 200, "check csrf token").
+				AssertEqual("body.user", "alice", "check user"),
+		},
+	}
+	sessionRunner := NewRunner(t).NewSessionRunner(testcase)
+	if assert.Nil(t, sessionRunner.Start()) {
+		assert.Equal(t, "token-123", sessionRunner.sessionVariables["token"])
+		assert.Equal(t, "Sign in", sessionRunner.sessionVariables["title"])
+	}
+}
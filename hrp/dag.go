@@ -0,0 +1,201 @@
+package hrp
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// DependsOn declares names of steps that current step depends on.
+// If any step in testcase declares dependencies, steps will be run as a DAG,
+// i.e. independent steps run concurrently while dependent steps run after their dependencies.
+func (s *StepRequest) DependsOn(stepNames ...string) *StepRequest {
+	s.step.DependsOn = append(s.step.DependsOn, stepNames...)
+	return s
+}
+
+// hasStepDependencies checks if any step declares depends_on.
+func hasStepDependencies(steps []IStep) bool {
+	for _, step := range steps {
+		if len(step.Struct().DependsOn) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// buildStepDAG returns dependents index list and in-degree of each step,
+// error is returned if dependency is unknown, ambiguous or cyclic.
+func buildStepDAG(steps []IStep) (dependents [][]int, inDegree []int, err error) {
+	stepIndex := make(map[string]int)
+	duplicated := make(map[string]bool)
+	for i, step := range steps {
+		name := step.Struct().Name
+		if _, ok := stepIndex[name]; ok {
+			duplicated[name] = true
+		}
+		stepIndex[name] = i
+	}
+
+	dependents = make([][]int, len(steps))
+	inDegree = make([]int, len(steps))
+	for i, step := range steps {
+		for _, dependency := range step.Struct().DependsOn {
+			j, ok := stepIndex[dependency]
+			if !ok {
+				return nil, nil, fmt.Errorf("step %s depends on unknown step %s", step.Name(), dependency)
+			}
+			if duplicated[dependency] {
+				return nil, nil, fmt.Errorf("step %s depends on ambiguous step name %s", step.Name(), dependency)
+			}
+			if i == j {
+				return nil, nil, fmt.Errorf("step %s depends on itself", step.Name())
+			}
+			dependents[j] = append(dependents[j], i)
+			inDegree[i]++
+		}
+	}
+
+	// detect cycles with topological sorting
+	degree := make([]int, len(inDegree))
+	copy(degree, inDegree)
+	var queue []int
+	for i, d := range degree {
+		if d == 0 {
+			queue = append(queue, i)
+		}
+	}
+	visited := 0
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		visited++
+		for _, j := range dependents[i] {
+			degree[j]--
+			if degree[j] == 0 {
+				queue = append(queue, j)
+			}
+		}
+	}
+	if visited != len(steps) {
+		var cyclic []string
+		for i, d := range degree {
+			if d > 0 {
+				cyclic = append(cyclic, steps[i].Name())
+			}
+		}
+		return nil, nil, fmt.Errorf("cyclic step dependencies found in steps %v", cyclic)
+	}
+	return dependents, inDegree, nil
+}
+
+type dagStepResult struct {
+	index      int
+	stepResult *StepResult
+	err        error
+	skipped    bool
+}
+
+// runStepsInDAG runs steps in goroutines once all their dependencies finished,
+// exported variables are merged into session variables before dependent steps start,
+// indexes are positions of steps in testcase.
+func (r *SessionRunner) runStepsInDAG(steps []IStep, indexes []int) error {
+	dependents, inDegree, err := buildStepDAG(steps)
+	if err != nil {
+		return errors.Wrap(err, "build step DAG failed")
+	}
+
+	results := make(chan *dagStepResult)
+	running := 0
+	launch := func(index int) {
+		step := steps[index]
+		running++
+		if r.completedSteps[indexes[index]] {
+			log.Info().Str("step", step.Name()).Msg("skip step completed in previous run")
+			go func() {
+				results <- &dagStepResult{index: index, skipped: true}
+			}()
+			return
+		}
+		log.Info().Str("step", step.Name()).
+			Str("type", string(step.Type())).Msg("run step start")
+		go func() {
+			stepResult, err := r.runStep(step)
+			results <- &dagStepResult{index: index, stepResult: stepResult, err: err}
+		}()
+	}
+	for i, degree := range inDegree {
+		if degree == 0 {
+			launch(i)
+		}
+	}
+
+	var abortErr error
+	for running > 0 {
+		result := <-results
+		running--
+
+		step := steps[result.index]
+		if result.skipped {
+			for _, j := range dependents[result.index] {
+				inDegree[j]--
+				if inDegree[j] == 0 && abortErr == nil {
+					launch(j)
+				}
+			}
+			continue
+		}
+		stepResult := result.stepResult
+		if stepResult == nil {
+			stepResult = &StepResult{
+				Name:     step.Name(),
+				StepType: step.Type(),
+				Success:  false,
+			}
+		}
+		r.hrpRunner.reporter.printStepResult(stepResult, result.err)
+		if abortErr == nil {
+			// stop launching new steps and wait for running steps if run is canceled or deadline is exceeded
+			abortErr = r.interrupted()
+		}
+		if result.err != nil && r.hrpRunner.failfast && r.interrupted() == nil {
+			log.Error().
+				Str("step", stepResult.Name).
+				Str("type", string(stepResult.StepType)).
+				Bool("success", false).
+				Msg("run step end")
+			if abortErr == nil {
+				// stop launching new steps and wait for running steps
+				abortErr = errors.Wrap(result.err, "abort running due to failfast setting")
+			}
+			continue
+		}
+
+		// update extracted variables
+		r.updateSessionVariables(stepResult.ExportVars)
+		// update testcase summary
+		r.updateSummary(stepResult)
+		if stepResult.Success {
+			r.completedSteps[indexes[result.index]] = true
+		}
+
+		log.Info().
+			Str("step", stepResult.Name).
+			Str("type", string(stepResult.StepType)).
+			Bool("success", stepResult.Success).
+			Interface("exportVars", stepResult.ExportVars).
+			Msg("run step end")
+
+		if abortErr != nil {
+			continue
+		}
+		for _, j := range dependents[result.index] {
+			inDegree[j]--
+			if inDegree[j] == 0 {
+				launch(j)
+			}
+		}
+	}
+	return abortErr
+}
@@ -0,0 +1,157 @@
+package hrp
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunCaseWithDAG(t *testing.T) {
+	// get user and get order wait for each other, which returns only if they run concurrently
+	var mutex sync.Mutex
+	var arrived, completed int
+	bothArrived := make(chan struct{})
+	var completedBeforeCheckout int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/user", "/order":
+			mutex.Lock()
+			arrived++
+			if arrived == 2 {
+				close(bothArrived)
+			}
+			mutex.Unlock()
+			select {
+			case <-bothArrived:
+			case <-time.After(5 * time.Second):
+				w.WriteHeader(http.StatusRequestTimeout)
+				return
+			}
+			mutex.Lock()
+			completed++
+			mutex.Unlock()
+		case "/checkout":
+			mutex.Lock()
+			completedBeforeCheckout = completed
+			mutex.Unlock()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"path": "%s", "args": "%s"}`, r.URL.Path, r.URL.RawQuery)
+	}))
+	defer ts.Close()
+
+	testcase := &TestCase{
+		Config: NewConfig("dag").SetBaseURL(ts.URL),
+		TestSteps: []IStep{
+			NewStep("get user").
+				GET("/user").
+				Extract().
+				WithJmesPath("body.path", "user").
+				Validate().
+				AssertEqual("status_code", 200, "check run concurrently"),
+			NewStep("get order").
+				GET("/order").
+				Extract().
+				WithJmesPath("body.path", "order").
+				Validate().
+				AssertEqual("status_code", 200, "check run concurrently"),
+			NewStep("checkout").
+				DependsOn("get user", "get order").
+				GET("/checkout").
+				WithParams(map[string]interface{}{"user": "$user", "order": "$order"}).
+				Validate().
+				AssertEqual("body.args", "order=%2Forder&user=%2Fuser", "check exported variables"),
+		},
+	}
+
+	if !assert.Nil(t, NewRunner(t).Run(testcase)) {
+		t.Fatal()
+	}
+	// independent steps run concurrently, checkout runs after them
+	assert.Equal(t, 2, completedBeforeCheckout)
+}
+
+func TestBuildStepDAG(t *testing.T) {
+	steps := []IStep{
+		NewStep("a").DependsOn("c").GET("/a"),
+		NewStep("b").DependsOn("a").GET("/b"),
+		NewStep("c").DependsOn("b").GET("/c"),
+		NewStep("d").GET("/d"),
+	}
+	_, _, err := buildStepDAG(steps)
+	if !assert.NotNil(t, err) {
+		t.Fatal()
+	}
+	assert.Contains(t, err.Error(), "cyclic step dependencies found in steps [a b c]")
+
+	_, _, err = buildStepDAG([]IStep{NewStep("a").DependsOn("x").GET("/a")})
+	assert.NotNil(t, err)
+
+	dependents, inDegree, err := buildStepDAG([]IStep{
+		NewStep("a").DependsOn("c").GET("/a"),
+		NewStep("b").DependsOn("a", "c").GET("/b"),
+		NewStep("c").GET("/c"),
+	})
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	assert.Equal(t, [][]int{{1}, nil, {0, 1}}, dependents)
+	assert.Equal(t, []int{1, 2, 0}, inDegree)
+}
+
+func TestRunCaseWithDAGRefCases(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"path": "%s"}`, r.URL.Path)
+	}))
+	defer ts.Close()
+
+	newRefCase := func(name string) *TestCase {
+		return &TestCase{
+			Config: NewConfig(name).SetBaseURL(ts.URL).ExportVars(name),
+			TestSteps: []IStep{
+				NewStep("get "+name).
+					GET("/"+name).
+					Extract().
+					WithJmesPath("body.path", name),
+				NewStep("get " + name + " again").
+					GET("/" + name),
+			},
+		}
+	}
+
+	// referenced testcases run concurrently, and merge their summaries and exported variables
+	var steps []IStep
+	var dependencies []string
+	for i := 0; i < 5; i++ {
+		name := fmt.Sprintf("ref%d", i)
+		steps = append(steps, NewStep("call "+name).CallRefCase(newRefCase(name)))
+		dependencies = append(dependencies, "call "+name)
+	}
+	steps = append(steps, NewStep("check").
+		DependsOn(dependencies...).
+		GET("/check").
+		WithParams(map[string]interface{}{"ref": "$ref4"}).
+		Validate().
+		AssertEqual("status_code", 200, "check status code"))
+	testcase := &TestCase{
+		Config:    NewConfig("dag with referenced testcases").SetBaseURL(ts.URL),
+		TestSteps: steps,
+	}
+
+	sessionRunner := NewRunner(t).NewSessionRunner(testcase)
+	if !assert.Nil(t, sessionRunner.Start()) {
+		t.Fatal()
+	}
+	summary := sessionRunner.GetSummary()
+	assert.True(t, summary.Success)
+	// 5 referenced testcases with 2 steps, plus step results of referenced testcases and check step
+	assert.Equal(t, 5*2+5+1, summary.Stat.Total)
+	assert.Len(t, summary.Records, 5*2+5+1)
+	assert.Equal(t, "/ref4", sessionRunner.sessionVariables["ref4"])
+}
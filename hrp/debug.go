@@ -0,0 +1,33 @@
+package hrp
+
+import (
+	"github.com/rs/zerolog/log"
+)
+
+// DebugServer reports runtime status of hrp during long runs, e.g. debug server started by `--debug-addr`
+// of hrp commands, which also exposes net/http/pprof endpoints.
+type DebugServer interface {
+	// StepStarted records step of testcase as active, and returns function to be called when step ends.
+	StepStarted(testcase, step, stepType string) func()
+	// AddQueue adds provider of queue depths reported in runtime status.
+	AddQueue(depths func() map[string]int)
+}
+
+// SetDebugServer reports steps running by runner in runtime status of debug server.
+func (r *HRPRunner) SetDebugServer(d DebugServer) *HRPRunner {
+	log.Info().Msg("[init] SetDebugServer")
+	r.debugServer = d
+	return r
+}
+
+// debugStepStarted records step as active in debug server, and returns function to be called when step ends.
+func (r *HRPRunner) debugStepStarted(testcase *TestCase, step IStep) func() {
+	if r.debugServer == nil {
+		return func() {}
+	}
+	var name string
+	if testcase != nil && testcase.Config != nil {
+		name = testcase.Config.Name
+	}
+	return r.debugServer.StepStarted(name, step.Name(), string(step.Type()))
+}
@@ -0,0 +1,83 @@
+package hrp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testDebugServer records steps reported by runner.
+type testDebugServer struct {
+	mutex  sync.Mutex
+	active map[string]string // step name => testcase name
+	types  map[string]string // step name => step type
+}
+
+func (d *testDebugServer) StepStarted(testcase, step, stepType string) func() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.active[step] = testcase
+	d.types[step] = stepType
+	return func() {
+		d.mutex.Lock()
+		defer d.mutex.Unlock()
+		delete(d.active, step)
+	}
+}
+
+func (d *testDebugServer) AddQueue(depths func() map[string]int) {}
+
+func (d *testDebugServer) activeSteps() map[string]string {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	steps := make(map[string]string)
+	for step, testcase := range d.active {
+		steps[step] = testcase
+	}
+	return steps
+}
+
+func TestRunCaseWithDebugServer(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+	}))
+	defer ts.Close()
+
+	testcase := &TestCase{
+		Config: NewConfig("slow case").SetBaseURL(ts.URL),
+		TestSteps: []IStep{
+			NewStep("slow step").GET("/slow"),
+		},
+	}
+	d := &testDebugServer{active: make(map[string]string), types: make(map[string]string)}
+	done := make(chan error)
+	go func() {
+		done <- NewRunner(t).SetDebugServer(d).Run(testcase)
+	}()
+
+	<-entered
+	assert.Equal(t, map[string]string{"slow step": "slow case"}, d.activeSteps())
+	close(release)
+	select {
+	case err := <-done:
+		assert.Nil(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("run not finished")
+	}
+	assert.Empty(t, d.activeSteps())
+	assert.Equal(t, "request-GET", d.types["slow step"])
+}
+
+func TestPprofNotRegistered(t *testing.T) {
+	// importing hrp does not register net/http/pprof handlers on default mux
+	req, _ := http.NewRequest(http.MethodGet, "/debug/pprof/", nil)
+	_, pattern := http.DefaultServeMux.Handler(req)
+	assert.Empty(t, pattern)
+}
@@ -0,0 +1,142 @@
+package hrp
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// startSocks5Server starts SOCKS5 server without authentication, which resolves host names in hosts only,
+// requested host names are sent to channel.
+func startSocks5Server(t *testing.T, hosts map[string]string, requested chan<- string) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSocks5(conn, hosts, requested)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func serveSocks5(conn net.Conn, hosts map[string]string, requested chan<- string) {
+	defer conn.Close()
+	// greeting: version, count of methods, methods
+	buf := make([]byte, 262)
+	if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+		return
+	}
+	if _, err := io.ReadFull(conn, buf[:buf[1]]); err != nil {
+		return
+	}
+	conn.Write([]byte{5, 0}) // no authentication
+	// request: version, connect, reserved, address type, address, port
+	if _, err := io.ReadFull(conn, buf[:4]); err != nil {
+		return
+	}
+	var host string
+	switch buf[3] {
+	case 1: // ipv4
+		io.ReadFull(conn, buf[:4])
+		host = net.IP(buf[:4]).String()
+	case 3: // domain name
+		io.ReadFull(conn, buf[:1])
+		n := int(buf[0])
+		io.ReadFull(conn, buf[:n])
+		host = string(buf[:n])
+		requested <- host
+	default:
+		return
+	}
+	io.ReadFull(conn, buf[:2])
+	port := binary.BigEndian.Uint16(buf[:2])
+	if addr, ok := hosts[host]; ok {
+		host = addr
+	}
+	target, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(port))))
+	if err != nil {
+		conn.Write([]byte{5, 4, 0, 1, 0, 0, 0, 0, 0, 0}) // host unreachable
+		return
+	}
+	defer target.Close()
+	conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+	go io.Copy(target, conn)
+	io.Copy(conn, target)
+}
+
+func TestRunRequestWithSocks5(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Host))
+	}))
+	defer ts.Close()
+	_, port, _ := net.SplitHostPort(ts.Listener.Addr().String())
+
+	requested := make(chan string, 1)
+	socks5Addr := startSocks5Server(t, map[string]string{"internal.test": "127.0.0.1"}, requested)
+	testcase := &TestCase{
+		Config: NewConfig("socks5 tunnel").
+			WithVariables(map[string]interface{}{"socks5": "socks5h://" + socks5Addr}),
+		TestSteps: []IStep{
+			NewStep("via socks5").
+				GET("http://internal.test:"+port+"/get").
+				ViaSocks5("$socks5").
+				Validate().
+				AssertEqual("status_code", 200, "check status code").
+				AssertEqual("body", "internal.test:"+port, "check host"),
+		},
+	}
+	assert.Nil(t, NewRunner(t).Run(testcase))
+	assert.Equal(t, "internal.test", <-requested) // host name is resolved by proxy
+}
+
+func TestRunRequestWithLocalAddr(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("only linux routes the whole 127.0.0.0/8 to loopback")
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, _ := net.SplitHostPort(r.RemoteAddr)
+		_, _ = w.Write([]byte(host))
+	}))
+	defer ts.Close()
+
+	testcase := &TestCase{
+		Config: NewConfig("bind local addr").SetBaseURL(ts.URL),
+		TestSteps: []IStep{
+			NewStep("from 127.0.0.2").GET("/").WithLocalAddr("127.0.0.2").
+				Validate().
+				AssertEqual("body", "127.0.0.2", "check source ip"),
+			NewStep("from 127.0.0.3").GET("/").WithLocalAddr("127.0.0.3").
+				Validate().
+				AssertEqual("body", "127.0.0.3", "check source ip"),
+			NewStep("default").GET("/").
+				Validate().
+				AssertEqual("body", "127.0.0.1", "check source ip"),
+		},
+	}
+	runner := NewRunner(t)
+	assert.Nil(t, runner.Run(testcase))
+	assert.Len(t, runner.egressClients, 2)
+
+	ip, err := egressLocalIP(Egress{Interface: "lo"}, "ipv4")
+	if assert.Nil(t, err) {
+		assert.Equal(t, "127.0.0.1", ip.String())
+	}
+	_, err = egressLocalIP(Egress{Interface: "not-exist0"}, "")
+	assert.NotNil(t, err)
+	_, err = egressLocalIP(Egress{LocalAddr: "localhost"}, "")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,59 @@
+package hrp
+
+import (
+	"regexp"
+	"sync"
+
+	"github.com/jmespath/go-jmespath"
+)
+
+// expressionCache caches compiled jmespath, regexp and css extractor expressions,
+// which is shared by session runners of the same testcase to avoid recompiling for each iteration.
+type expressionCache struct {
+	jmespaths sync.Map // expression string -> *jmespath.JMESPath
+	regexps   sync.Map // expression string -> *regexp.Regexp
+	css       sync.Map // expression string -> *cssExtractor
+}
+
+func newExpressionCache() *expressionCache {
+	return &expressionCache{}
+}
+
+// compileJmespath returns compiled jmespath expression from cache, compile errors are not cached.
+func (p *Parser) compileJmespath(expr string) (*jmespath.JMESPath, error) {
+	if compiled, ok := p.exprCache.jmespaths.Load(expr); ok {
+		return compiled.(*jmespath.JMESPath), nil
+	}
+	compiled, err := jmespath.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	p.exprCache.jmespaths.Store(expr, compiled)
+	return compiled, nil
+}
+
+// compileRegexp returns compiled regexp expression from cache, compile errors are not cached.
+func (p *Parser) compileRegexp(expr string) (*regexp.Regexp, error) {
+	if compiled, ok := p.exprCache.regexps.Load(expr); ok {
+		return compiled.(*regexp.Regexp), nil
+	}
+	compiled, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	p.exprCache.regexps.Store(expr, compiled)
+	return compiled, nil
+}
+
+// compileCSSExtractor returns compiled css extractor from cache, compile errors are not cached.
+func (p *Parser) compileCSSExtractor(expr string) (*cssExtractor, error) {
+	if compiled, ok := p.exprCache.css.Load(expr); ok {
+		return compiled.(*cssExtractor), nil
+	}
+	compiled, err := newCSSExtractor(expr)
+	if err != nil {
+		return nil, err
+	}
+	p.exprCache.css.Store(expr, compiled)
+	return compiled, nil
+}
@@ -0,0 +1,46 @@
+package hrp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpressionCache(t *testing.T) {
+	runner := NewRunner(t)
+	parser1 := runner.NewSessionRunner(&TestCase{Config: NewConfig("case1")}).parser
+	parser2 := runner.NewSessionRunner(&TestCase{Config: NewConfig("case2")}).parser
+
+	compiled1, err := parser1.compileJmespath("body.data[0].id")
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	compiled2, err := parser2.compileJmespath("body.data[0].id")
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	assert.Same(t, compiled1, compiled2)
+
+	_, err = parser1.compileJmespath("body.data[")
+	assert.NotNil(t, err)
+
+	regexp1, err := parser1.compileRegexp(`"id": (\d+)`)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	regexp2, _ := parser2.compileRegexp(`"id": (\d+)`)
+	assert.Same(t, regexp1, regexp2)
+}
+
+func BenchmarkSearchJmespath(b *testing.B) {
+	respObj := &responseObject{
+		parser: newParser(),
+		respObjMeta: map[string]interface{}{
+			"body": map[string]interface{}{"data": []interface{}{map[string]interface{}{"id": 1}}},
+		},
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		respObj.searchJmespath("body.data[0].id")
+	}
+}
@@ -0,0 +1,74 @@
+package hrp
+
+import (
+	"context"
+	"net"
+
+	"github.com/pkg/errors"
+)
+
+// FailureCode is stable machine-readable category of step failure, which is recorded in step result,
+// e.g. CI gates could retry steps failed with transport error while failing fast on validation failure.
+type FailureCode string
+
+const (
+	FailureTransport  FailureCode = "transport_error"    // connection refused, dns resolution or tls handshake failed, etc.
+	FailureTimeout    FailureCode = "timeout"            // request timed out, or wait until condition not matched before timeout
+	FailureValidation FailureCode = "validation_failure" // response assertion or snapshot not matched
+	FailureExtraction FailureCode = "extraction_failure" // extractor expression is invalid
+	FailureHook       FailureCode = "hook_error"         // setup or teardown hook failed
+	FailureUnknown    FailureCode = "unknown_error"      // other failures, e.g. parse variables failed
+)
+
+// StepError is step failure with failure code.
+type StepError struct {
+	Code FailureCode
+	Err  error
+}
+
+func (e *StepError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
+
+func newStepError(code FailureCode, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &StepError{Code: code, Err: err}
+}
+
+// classifyFailure returns failure code of step error, explicit code of StepError takes precedence,
+// otherwise validation errors and network errors are recognized by type.
+func classifyFailure(err error) FailureCode {
+	var stepErr *StepError
+	if errors.As(err, &stepErr) {
+		return stepErr.Code
+	}
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		return FailureValidation
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return FailureTimeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return FailureTimeout
+		}
+		return FailureTransport
+	}
+	return FailureUnknown
+}
+
+// setFailureCode records failure code of step error into step result.
+func setFailureCode(stepResult *StepResult, err error) {
+	if stepResult == nil || err == nil || stepResult.Success {
+		return
+	}
+	stepResult.FailureCode = classifyFailure(err)
+}
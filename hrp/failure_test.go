@@ -0,0 +1,54 @@
+package hrp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunCaseWithFailureCode(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(500 * time.Millisecond)
+		}
+		_, _ = w.Write([]byte(`{"status": "ok"}`))
+	}))
+	defer ts.Close()
+	closed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closed.Close()
+
+	testcase := &TestCase{
+		Config: NewConfig("failure code").SetBaseURL(ts.URL),
+		TestSteps: []IStep{
+			NewStep("pass").GET("/get").
+				Validate().
+				AssertEqual("body.status", "ok", "check status"),
+			NewStep("validation").GET("/get").
+				Validate().
+				AssertEqual("body.status", "failed", "check status"),
+			NewStep("timeout").GET("/slow"),
+			NewStep("transport").GET(closed.URL + "/get"),
+			NewStep("extraction").GET("/get").
+				Extract().
+				WithJmesPath("body.[", "status"),
+			NewStep("hook").SetupHook("${not_found_func()}").GET("/get"),
+		},
+	}
+	runner := NewRunner(nil).SetFailfast(false)
+	runner.client.Timeout = 100 * time.Millisecond
+	sessionRunner := runner.NewSessionRunner(testcase)
+	assert.Nil(t, sessionRunner.Start())
+	records := sessionRunner.GetSummary().Records
+	if !assert.Len(t, records, 6) {
+		t.Fatal()
+	}
+	expected := []FailureCode{
+		"", FailureValidation, FailureTimeout, FailureTransport, FailureExtraction, FailureHook,
+	}
+	for i, code := range expected {
+		assert.Equal(t, code, records[i].FailureCode, records[i].Name)
+	}
+}
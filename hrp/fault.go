@@ -0,0 +1,153 @@
+package hrp
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ErrFaultConnectionDropped is returned when reading response body after connection dropped by fault injection.
+var ErrFaultConnectionDropped = errors.New("fault injection: connection dropped")
+
+// Fault represents fault injection options for request step,
+// which is used to exercise resilience behaviors like client retries and circuit breakers.
+type Fault struct {
+	Delay          int64    `json:"delay,omitempty" yaml:"delay,omitempty"`                     // inject latency in milliseconds before sending request
+	DropBody       bool     `json:"drop_body,omitempty" yaml:"drop_body,omitempty"`             // drop connection when reading response body
+	DropBodyAfter  int64    `json:"drop_body_after,omitempty" yaml:"drop_body_after,omitempty"` // drop connection after reading n bytes of response body
+	CorruptHeaders []string `json:"corrupt_headers,omitempty" yaml:"corrupt_headers,omitempty"` // request headers to replace with garbage values
+	Probability    float64  `json:"probability,omitempty" yaml:"probability,omitempty"`         // probability to inject faults, default to 1(100%)
+}
+
+// faultTransport implements http.RoundTripper, it injects faults before delegating to next RoundTripper.
+type faultTransport struct {
+	next  http.RoundTripper
+	fault *Fault
+}
+
+func newFaultTransport(next http.RoundTripper, fault *Fault) *faultTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &faultTransport{
+		next:  next,
+		fault: fault,
+	}
+}
+
+func (t *faultTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	fault := t.fault
+	if fault.Probability > 0 && rand.Float64() >= fault.Probability {
+		return t.next.RoundTrip(req)
+	}
+
+	if fault.Delay > 0 {
+		log.Warn().Int64("delay(ms)", fault.Delay).Msg("fault injection: delay request")
+		select {
+		case <-time.After(time.Duration(fault.Delay) * time.Millisecond):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+
+	if len(fault.CorruptHeaders) > 0 {
+		// RoundTripper should not modify request
+		req = req.Clone(req.Context())
+		for _, name := range fault.CorruptHeaders {
+			value := req.Header.Get(name)
+			log.Warn().Str("header", name).Msg("fault injection: corrupt request header")
+			req.Header.Set(name, corruptValue(value))
+		}
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	if fault.DropBody {
+		log.Warn().Int64("after(bytes)", fault.DropBodyAfter).Msg("fault injection: drop connection mid-body")
+		resp.Body = &droppedBody{
+			body:      resp.Body,
+			remaining: fault.DropBodyAfter,
+		}
+	}
+	return resp, nil
+}
+
+const corruptLetters = "!#%&*+-.^_|~0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// corruptValue returns printable garbage in the same length of value, at least one byte.
+func corruptValue(value string) string {
+	n := len(value)
+	if n == 0 {
+		n = 1
+	}
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = corruptLetters[rand.Intn(len(corruptLetters))]
+	}
+	return string(b)
+}
+
+// droppedBody reads at most remaining bytes from body, then closes body and returns ErrFaultConnectionDropped.
+type droppedBody struct {
+	body      io.ReadCloser
+	remaining int64
+}
+
+func (b *droppedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		b.body.Close()
+		return 0, ErrFaultConnectionDropped
+	}
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	// io.EOF is returned as is if response body ended before dropping point
+	n, err := b.body.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
+
+func (b *droppedBody) Close() error {
+	return b.body.Close()
+}
+
+// InjectDelay injects latency in milliseconds before sending current HTTP request.
+func (s *StepRequestWithOptionalArgs) InjectDelay(delay int64) *StepRequestWithOptionalArgs {
+	s.ensureFault().Delay = delay
+	return s
+}
+
+// DropConnection drops connection after reading afterBytes of response body for current HTTP request.
+func (s *StepRequestWithOptionalArgs) DropConnection(afterBytes int64) *StepRequestWithOptionalArgs {
+	fault := s.ensureFault()
+	fault.DropBody = true
+	fault.DropBodyAfter = afterBytes
+	return s
+}
+
+// CorruptHeader replaces value of the specified request header with garbage for current HTTP request.
+func (s *StepRequestWithOptionalArgs) CorruptHeader(name string) *StepRequestWithOptionalArgs {
+	fault := s.ensureFault()
+	fault.CorruptHeaders = append(fault.CorruptHeaders, name)
+	return s
+}
+
+// WithFaultProbability sets probability to inject faults for current HTTP request.
+func (s *StepRequestWithOptionalArgs) WithFaultProbability(probability float64) *StepRequestWithOptionalArgs {
+	s.ensureFault().Probability = probability
+	return s
+}
+
+func (s *StepRequestWithOptionalArgs) ensureFault() *Fault {
+	if s.step.Fault == nil {
+		s.step.Fault = &Fault{}
+	}
+	return s.step.Fault
+}
@@ -0,0 +1,66 @@
+package hrp
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunRequestWithFault(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"token": "%s", "data": "%s"}`, r.Header.Get("X-Token"), strings.Repeat("a", 1024))
+	}))
+	defer ts.Close()
+
+	newRunner := func() *SessionRunner {
+		return NewRunner(t).NewSessionRunner(&TestCase{Config: NewConfig("fault")})
+	}
+
+	// inject delay
+	stepResult, err := NewStep("delay").
+		GET(ts.URL).
+		InjectDelay(200).
+		Validate().
+		AssertEqual("status_code", 200, "check status code").
+		Run(newRunner())
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	assert.GreaterOrEqual(t, stepResult.Elapsed, int64(200))
+
+	// corrupt header
+	stepResult, err = NewStep("corrupt header").
+		GET(ts.URL).
+		WithHeaders(map[string]string{"X-Token": "abcdef"}).
+		CorruptHeader("X-Token").
+		Validate().
+		AssertLengthEqual("body.token", 6, "corrupted header keeps length").
+		AssertNotEqual("body.token", "abcdef", "check header corrupted").
+		Run(newRunner())
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+
+	// drop connection mid-body
+	_, err = NewStep("drop connection").
+		GET(ts.URL).
+		DropConnection(10).
+		Run(newRunner())
+	if !assert.NotNil(t, err) {
+		t.Fatal()
+	}
+	assert.Contains(t, err.Error(), ErrFaultConnectionDropped.Error())
+
+	// never inject faults
+	_, err = NewStep("probability").
+		GET(ts.URL).
+		DropConnection(10).
+		WithFaultProbability(1e-9).
+		Run(newRunner())
+	assert.Nil(t, err)
+}
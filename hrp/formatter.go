@@ -0,0 +1,448 @@
+package hrp
+
+import (
+	"bytes"
+	builtinJSON "encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+
+	"github.com/httprunner/httprunner/hrp/internal/builtin"
+)
+
+const (
+	formatJSON = "json"
+	formatYAML = "yaml"
+)
+
+// validators of step are declared as interface{}, but formatted in field order of Validator
+var formatFieldTypes = map[string]reflect.Type{
+	"validate": reflect.TypeOf([]*Validator{}),
+}
+
+// fileFormat returns format of testcase file by file extension.
+func fileFormat(path string) (string, error) {
+	switch filepath.Ext(path) {
+	case ".json":
+		return formatJSON, nil
+	case ".yaml", ".yml":
+		return formatYAML, nil
+	}
+	return "", builtin.ErrUnsupportedFileExt
+}
+
+// FormatTestCase rewrites testcase or api content into canonical field order and indentation,
+// content is converted if dstFormat differs from srcFormat, formats are json or yaml.
+// Fields are ordered as declared in TCase and API, unknown fields are kept in original order after known fields,
+// and comments of yaml content are preserved.
+func FormatTestCase(content []byte, srcFormat, dstFormat string) ([]byte, error) {
+	var docs []*yaml.Node
+	switch srcFormat {
+	case formatJSON:
+		node, err := parseJSONNode(content)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse json content failed")
+		}
+		docs = append(docs, node)
+	case formatYAML:
+		// yaml content may contain multiple documents separated by ---, which are formatted one by one
+		decoder := yaml.NewDecoder(bytes.NewReader(content))
+		for {
+			doc := &yaml.Node{}
+			err := decoder.Decode(doc)
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				if strings.Contains(err.Error(), "unknown anchor") && strings.Contains(string(content), yamlIncludesKey+":") {
+					// aliases of anchors in included files are resolved only when loading testcase
+					return nil, errors.Wrap(err, "yaml with aliases of included anchors can not be formatted")
+				}
+				return nil, errors.Wrap(err, "parse yaml content failed")
+			}
+			docs = append(docs, doc)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported source format %s", srcFormat)
+	}
+	if len(docs) == 0 {
+		return nil, errors.New("testcase content is empty")
+	}
+
+	bodies := make([]*yaml.Node, len(docs))
+	for i, root := range docs {
+		body := root
+		if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+			body = root.Content[0]
+		}
+		if body.Kind != yaml.MappingNode {
+			return nil, errors.New("testcase content should be mapping")
+		}
+		reorderNode(body, testCaseType(body))
+		bodies[i] = body
+	}
+
+	var buf bytes.Buffer
+	switch dstFormat {
+	case formatYAML:
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(4)
+		for _, root := range docs {
+			if err := encoder.Encode(root); err != nil {
+				return nil, errors.Wrap(err, "encode yaml failed")
+			}
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, errors.Wrap(err, "encode yaml failed")
+		}
+		return buf.Bytes(), nil
+	case formatJSON:
+		if len(bodies) > 1 {
+			return nil, fmt.Errorf("%d yaml documents found, only single document can be converted to json", len(bodies))
+		}
+		var compact bytes.Buffer
+		if err := writeJSONNode(&compact, bodies[0]); err != nil {
+			return nil, errors.Wrap(err, "encode json failed")
+		}
+		if err := builtinJSON.Indent(&buf, compact.Bytes(), "", "    "); err != nil {
+			return nil, errors.Wrap(err, "indent json failed")
+		}
+		buf.WriteByte('\n')
+		return buf.Bytes(), nil
+	}
+	return nil, fmt.Errorf("unsupported target format %s", dstFormat)
+}
+
+// FormatFile formats testcase file in place, or converts it into file of dstFormat beside the original file,
+// e.g. demo.yaml is converted into demo.json, dstFormat is empty to keep the original format.
+// If check is set, file is not written and only whether it is changed is reported.
+func FormatFile(path, dstFormat string, check bool) (outputPath string, changed bool, err error) {
+	srcFormat, err := fileFormat(path)
+	if err != nil {
+		return "", false, err
+	}
+	if dstFormat == "" {
+		dstFormat = srcFormat
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, errors.Wrap(err, "read testcase file failed")
+	}
+	formatted, err := FormatTestCase(content, srcFormat, dstFormat)
+	if err != nil {
+		return "", false, errors.Wrapf(err, "format %s failed", path)
+	}
+
+	outputPath = path
+	if dstFormat != srcFormat {
+		outputPath = strings.TrimSuffix(path, filepath.Ext(path)) + "." + dstFormat
+		content, err = os.ReadFile(outputPath)
+		if err != nil && !os.IsNotExist(err) {
+			return "", false, errors.Wrap(err, "read converted file failed")
+		}
+	}
+	changed = !bytes.Equal(content, formatted)
+	if !changed || check {
+		return outputPath, changed, nil
+	}
+	if err := os.WriteFile(outputPath, formatted, 0o644); err != nil {
+		return "", false, errors.Wrap(err, "write formatted file failed")
+	}
+	log.Info().Str("path", outputPath).Msg("format testcase file")
+	return outputPath, true, nil
+}
+
+// testCaseType returns API for api content with request at top level, otherwise TCase.
+func testCaseType(node *yaml.Node) reflect.Type {
+	hasRequest := false
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		switch node.Content[i].Value {
+		case "config", "teststeps":
+			return reflect.TypeOf(TCase{})
+		case "request":
+			hasRequest = true
+		}
+	}
+	if hasRequest {
+		return reflect.TypeOf(API{})
+	}
+	return reflect.TypeOf(TCase{})
+}
+
+type formatField struct {
+	index int
+	typ   reflect.Type
+}
+
+// formatFields returns fields of struct by json name, fields without json name are ignored.
+func formatFields(typ reflect.Type) map[string]formatField {
+	fields := make(map[string]formatField)
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		fieldType := field.Type
+		if override, ok := formatFieldTypes[name]; ok {
+			fieldType = override
+		}
+		fields[name] = formatField{index: i, typ: fieldType}
+	}
+	return fields
+}
+
+// reorderNode orders keys of mapping node as fields of struct type recursively,
+// merge keys are kept at the beginning and unknown keys are kept in original order at the end.
+// keys of mapping are kept in original order if reordering moves alias before its anchor.
+func reorderNode(node *yaml.Node, typ reflect.Type) {
+	for typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == nil {
+		return
+	}
+	switch node.Kind {
+	case yaml.MappingNode:
+		switch typ.Kind() {
+		case reflect.Struct:
+			fields := formatFields(typ)
+			type pair struct {
+				key, value *yaml.Node
+				order      int
+			}
+			original := append([]*yaml.Node{}, node.Content...)
+			var pairs []pair
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				key, value := node.Content[i], node.Content[i+1]
+				order := len(fields) + typ.NumField()
+				if key.Value == "<<" {
+					order = -1
+				} else if field, ok := fields[key.Value]; ok {
+					order = field.index
+					reorderNode(value, field.typ)
+				}
+				pairs = append(pairs, pair{key: key, value: value, order: order})
+			}
+			sort.SliceStable(pairs, func(i, j int) bool {
+				return pairs[i].order < pairs[j].order
+			})
+			node.Content = node.Content[:0]
+			for _, p := range pairs {
+				node.Content = append(node.Content, p.key, p.value)
+			}
+			if !anchorsBeforeAliases(node.Content) {
+				node.Content = original
+			}
+		case reflect.Map:
+			for i := 1; i < len(node.Content); i += 2 {
+				reorderNode(node.Content[i], typ.Elem())
+			}
+		}
+	case yaml.SequenceNode:
+		if typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array {
+			for _, item := range node.Content {
+				reorderNode(item, typ.Elem())
+			}
+		}
+	}
+}
+
+// anchorsBeforeAliases checks that anchors defined in nodes are ahead of aliases referring to them.
+func anchorsBeforeAliases(nodes []*yaml.Node) bool {
+	anchors := make(map[string]int) // index of node defining anchor
+	aliases := make(map[string]int) // index of the first node referring to anchor
+	for i, node := range nodes {
+		walkNode(node, func(n *yaml.Node) {
+			if n.Anchor != "" {
+				if _, ok := anchors[n.Anchor]; !ok {
+					anchors[n.Anchor] = i
+				}
+			}
+			if n.Kind == yaml.AliasNode {
+				if _, ok := aliases[n.Value]; !ok {
+					aliases[n.Value] = i
+				}
+			}
+		})
+	}
+	for name, index := range aliases {
+		if anchorIndex, ok := anchors[name]; ok && anchorIndex > index {
+			return false
+		}
+	}
+	return true
+}
+
+// walkNode calls fn with node and its descendants in document order, aliased nodes are not walked again.
+func walkNode(node *yaml.Node, fn func(n *yaml.Node)) {
+	fn(node)
+	for _, child := range node.Content {
+		walkNode(child, fn)
+	}
+}
+
+// parseJSONNode parses json content into yaml node, order of object keys is preserved.
+func parseJSONNode(content []byte) (*yaml.Node, error) {
+	decoder := builtinJSON.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	return decodeJSONNode(decoder)
+}
+
+func decodeJSONNode(decoder *builtinJSON.Decoder) (*yaml.Node, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch v := token.(type) {
+	case builtinJSON.Delim:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		if v == '{' {
+			node = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		}
+		for decoder.More() {
+			if node.Kind == yaml.MappingNode {
+				key, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+				node.Content = append(node.Content, &yaml.Node{
+					Kind: yaml.ScalarNode, Tag: "!!str", Value: key.(string),
+				})
+			}
+			item, err := decodeJSONNode(decoder)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, item)
+		}
+		// consume closing delimiter
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+		return node, nil
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v}, nil
+	case builtinJSON.Number:
+		tag := "!!int"
+		if strings.ContainsAny(v.String(), ".eE") {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: v.String()}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(v)}, nil
+	case nil:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	}
+	return nil, fmt.Errorf("unexpected json token %v", token)
+}
+
+// writeJSONNode writes yaml node as compact json, aliases and merge keys are resolved.
+func writeJSONNode(buf *bytes.Buffer, node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			buf.WriteString("null")
+			return nil
+		}
+		return writeJSONNode(buf, node.Content[0])
+	case yaml.AliasNode:
+		return writeJSONNode(buf, node.Alias)
+	case yaml.MappingNode:
+		buf.WriteByte('{')
+		for i, pair := range mappingPairs(node) {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSONValue(buf, pair[0].Value); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := writeJSONNode(buf, pair[1]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case yaml.SequenceNode:
+		buf.WriteByte('[')
+		for i, item := range node.Content {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSONNode(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case yaml.ScalarNode:
+		switch node.ShortTag() {
+		case "!!str", "!!binary":
+			return writeJSONValue(buf, node.Value)
+		case "!!null":
+			buf.WriteString("null")
+		default:
+			var value interface{}
+			if err := node.Decode(&value); err != nil {
+				return err
+			}
+			return writeJSONValue(buf, value)
+		}
+	}
+	return nil
+}
+
+// mappingPairs returns key value pairs of mapping node, pairs of merged mappings are appended
+// if keys are not declared explicitly.
+func mappingPairs(node *yaml.Node) [][2]*yaml.Node {
+	var pairs [][2]*yaml.Node
+	var merged []*yaml.Node
+	declared := make(map[string]bool)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if key.ShortTag() == "!!merge" {
+			if value.Kind == yaml.SequenceNode {
+				merged = append(merged, value.Content...)
+			} else {
+				merged = append(merged, value)
+			}
+			continue
+		}
+		declared[key.Value] = true
+		pairs = append(pairs, [2]*yaml.Node{key, value})
+	}
+	for _, source := range merged {
+		if source.Kind == yaml.AliasNode {
+			source = source.Alias
+		}
+		if source.Kind != yaml.MappingNode {
+			continue
+		}
+		for _, pair := range mappingPairs(source) {
+			if !declared[pair[0].Value] {
+				declared[pair[0].Value] = true
+				pairs = append(pairs, pair)
+			}
+		}
+	}
+	return pairs
+}
+
+// writeJSONValue writes value as json without escaping html characters, e.g. & in url.
+func writeJSONValue(buf *bytes.Buffer, value interface{}) error {
+	var b bytes.Buffer
+	encoder := builtinJSON.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return err
+	}
+	buf.Write(bytes.TrimRight(b.Bytes(), "\n"))
+	return nil
+}
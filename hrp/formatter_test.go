@@ -0,0 +1,197 @@
+package hrp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+const unformattedYAML = `teststeps:
+  # login first
+  - validate:
+      - expect: 200
+        assert: equals
+        check: status_code
+    request:
+      url: /login?a=1&b=2
+      method: POST
+      json: {user: $user}
+    name: login
+config:
+  variables:
+    user: admin
+  name: demo
+  custom: kept
+`
+
+const formattedYAML = `config:
+    name: demo
+    variables:
+        user: admin
+    custom: kept
+teststeps:
+    # login first
+    - name: login
+      request:
+        method: POST
+        url: /login?a=1&b=2
+        json: {user: $user}
+      validate:
+        - check: status_code
+          assert: equals
+          expect: 200
+`
+
+const formattedJSON = `{
+    "config": {
+        "name": "demo",
+        "variables": {
+            "user": "admin"
+        },
+        "custom": "kept"
+    },
+    "teststeps": [
+        {
+            "name": "login",
+            "request": {
+                "method": "POST",
+                "url": "/login?a=1&b=2",
+                "json": {
+                    "user": "$user"
+                }
+            },
+            "validate": [
+                {
+                    "check": "status_code",
+                    "assert": "equals",
+                    "expect": 200
+                }
+            ]
+        }
+    ]
+}
+`
+
+func TestFormatTestCase(t *testing.T) {
+	formatted, err := FormatTestCase([]byte(unformattedYAML), formatYAML, formatYAML)
+	if assert.Nil(t, err) {
+		assert.Equal(t, formattedYAML, string(formatted))
+	}
+	formatted, err = FormatTestCase([]byte(unformattedYAML), formatYAML, formatJSON)
+	if assert.Nil(t, err) {
+		assert.Equal(t, formattedJSON, string(formatted))
+	}
+
+	// convert json back to yaml
+	formatted, err = FormatTestCase([]byte(formattedJSON), formatJSON, formatYAML)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	tc := &TCase{}
+	if assert.Nil(t, yaml.Unmarshal(formatted, tc)) {
+		assert.Equal(t, "demo", tc.Config.Name)
+		assert.Equal(t, "/login?a=1&b=2", tc.TestSteps[0].Request.URL)
+	}
+	// formatting is idempotent
+	again, err := FormatTestCase(formatted, formatYAML, formatYAML)
+	if assert.Nil(t, err) {
+		assert.Equal(t, string(formatted), string(again))
+	}
+
+	_, err = FormatTestCase([]byte("- name: demo"), formatYAML, formatYAML)
+	assert.NotNil(t, err)
+
+	// documents of yaml are formatted one by one
+	formatted, err = FormatTestCase([]byte(unformattedYAML+"---\n"+unformattedYAML), formatYAML, formatYAML)
+	if assert.Nil(t, err) {
+		assert.Equal(t, formattedYAML+"---\n"+formattedYAML, string(formatted))
+	}
+	_, err = FormatTestCase([]byte(unformattedYAML+"---\n"+unformattedYAML), formatYAML, formatJSON)
+	assert.NotNil(t, err)
+}
+
+func TestFormatTestCaseWithAnchors(t *testing.T) {
+	content := `teststeps:
+  - variables: &v
+      a: 1
+    request:
+      params: *v
+      url: /get
+      method: GET
+    name: step with alias
+  - request:
+      url: /post
+      method: POST
+      json: *v
+    name: step without anchor
+config:
+  name: anchors
+`
+	formatted, err := FormatTestCase([]byte(content), formatYAML, formatYAML)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	// keys of step are kept in original order, otherwise alias is moved before its anchor
+	assert.Equal(t, `config:
+    name: anchors
+teststeps:
+    - variables: &v
+        a: 1
+      request:
+        method: GET
+        url: /get
+        params: *v
+      name: step with alias
+    - name: step without anchor
+      request:
+        method: POST
+        url: /post
+        json: *v
+`, string(formatted))
+
+	tc := &TCase{}
+	if assert.Nil(t, yaml.Unmarshal(formatted, tc)) {
+		assert.Equal(t, map[string]interface{}{"a": 1}, tc.TestSteps[0].Request.Params)
+	}
+	again, err := FormatTestCase(formatted, formatYAML, formatYAML)
+	if assert.Nil(t, err) {
+		assert.Equal(t, string(formatted), string(again))
+	}
+}
+
+func TestFormatFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "demo.yml")
+	if !assert.Nil(t, os.WriteFile(path, []byte(unformattedYAML), 0o644)) {
+		t.Fatal()
+	}
+
+	// check only
+	outputPath, changed, err := FormatFile(path, "", true)
+	assert.Nil(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, path, outputPath)
+	content, _ := os.ReadFile(path)
+	assert.Equal(t, unformattedYAML, string(content))
+
+	// format in place
+	_, changed, err = FormatFile(path, "", false)
+	assert.Nil(t, err)
+	assert.True(t, changed)
+	content, _ = os.ReadFile(path)
+	assert.Equal(t, formattedYAML, string(content))
+	_, changed, err = FormatFile(path, "", true)
+	assert.Nil(t, err)
+	assert.False(t, changed)
+
+	// convert to json beside yaml file
+	outputPath, changed, err = FormatFile(path, formatJSON, false)
+	assert.Nil(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, filepath.Join(dir, "demo.json"), outputPath)
+	content, _ = os.ReadFile(outputPath)
+	assert.Equal(t, formattedJSON, string(content))
+}
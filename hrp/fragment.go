@@ -0,0 +1,160 @@
+package hrp
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/httprunner/httprunner/hrp/internal/builtin"
+)
+
+// TFragment represents a reusable sequence of steps stored in file, e.g. login flow,
+// which can be inlined into testcases with include step.
+// Fragment variables are merged into each inlined step, so they will not pollute testcase config.
+type TFragment struct {
+	Variables map[string]interface{} `json:"variables,omitempty" yaml:"variables,omitempty"`
+	TestSteps []*TStep               `json:"teststeps" yaml:"teststeps"`
+}
+
+// loadFragment loads fragment file referenced by include step and converts its steps,
+// include step variables override fragment variables.
+func loadFragment(step *TStep, projectRootDir string, refChain []string) ([]IStep, error) {
+	path := filepath.Join(projectRootDir, step.Include)
+	if !builtin.IsFilePathExists(path) {
+		return nil, errors.New("included fragment file not found: " + path)
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, refPath := range refChain {
+		if refPath == absPath {
+			return nil, fmt.Errorf("cyclic fragment include found: %s",
+				strings.Join(append(refChain, absPath), " -> "))
+		}
+	}
+	refChain = append(refChain[:len(refChain):len(refChain)], absPath)
+
+	fragment := &TFragment{}
+	if err := builtin.LoadFile(path, fragment); err != nil {
+		return nil, err
+	}
+	for _, fragmentStep := range fragment.TestSteps {
+		if err := makeCompatStep(fragmentStep); err != nil {
+			return nil, err
+		}
+	}
+
+	steps, err := convertTSteps(fragment.TestSteps, projectRootDir, refChain)
+	if err != nil {
+		return nil, err
+	}
+	return spliceFragment(steps, mergeVariables(step.Variables, fragment.Variables)), nil
+}
+
+// spliceFragment merges fragment variables into copies of steps, step variables have higher priority.
+// Steps are copied thus fragment can be reused with different variables.
+func spliceFragment(steps []IStep, variables map[string]interface{}) []IStep {
+	if len(variables) == 0 {
+		return steps
+	}
+	splicedSteps := make([]IStep, 0, len(steps))
+	for _, step := range steps {
+		tStep := *step.Struct()
+		tStep.Variables = make(map[string]interface{})
+		for k, v := range mergeVariables(step.Struct().Variables, variables) {
+			tStep.Variables[k] = v
+		}
+		splicedSteps = append(splicedSteps, copyStep(step, &tStep))
+	}
+	return splicedSteps
+}
+
+// copyStep returns step of the same type as step with tStep.
+func copyStep(step IStep, tStep *TStep) IStep {
+	switch s := step.(type) {
+	case *StepRequestWithOptionalArgs:
+		return &StepRequestWithOptionalArgs{step: tStep}
+	case *StepRequestExtraction:
+		return &StepRequestExtraction{step: tStep}
+	case *StepRequestValidation:
+		return &StepRequestValidation{step: tStep}
+	case *StepAPIWithOptionalArgs:
+		return &StepAPIWithOptionalArgs{step: tStep}
+	case *StepTestCaseWithOptionalArgs:
+		return &StepTestCaseWithOptionalArgs{step: tStep}
+	case *StepTransaction:
+		return &StepTransaction{step: tStep}
+	case *StepRendezvous:
+		return &StepRendezvous{step: tStep}
+	case *StepThinkTime:
+		return &StepThinkTime{step: tStep}
+	case *StepBranch:
+		return &StepBranch{step: tStep, thenSteps: s.thenSteps, elseSteps: s.elseSteps}
+	case *StepLoop:
+		return &StepLoop{step: tStep, steps: s.steps}
+	case *StepDB:
+		return &StepDB{step: tStep}
+	case *StepRedis:
+		return &StepRedis{step: tStep}
+	case *StepKafka:
+		return &StepKafka{step: tStep}
+	case *StepMQTT:
+		return &StepMQTT{step: tStep}
+	case *StepEmail:
+		return &StepEmail{step: tStep}
+	case *StepFile:
+		return &StepFile{step: tStep}
+	case *StepShell:
+		return &StepShell{step: tStep}
+	case *StepFuzz:
+		return &StepFuzz{step: tStep}
+	default:
+		// steps implemented outside hrp can not be copied, variables are merged in place
+		step.Struct().Variables = tStep.Variables
+		return step
+	}
+}
+
+// StepFragment represents a reusable sequence of steps with its own variables.
+type StepFragment struct {
+	variables map[string]interface{}
+	steps     []IStep
+}
+
+// NewFragment creates a reusable sequence of steps, which can be spliced into testcases with Steps.
+func NewFragment(steps ...IStep) *StepFragment {
+	return &StepFragment{
+		steps: steps,
+	}
+}
+
+// WithVariables returns fragment with variables, which are merged into each step of fragment,
+// the original fragment is not changed thus it can be reused with different variables.
+func (f *StepFragment) WithVariables(variables map[string]interface{}) *StepFragment {
+	return &StepFragment{
+		variables: variables,
+		steps:     f.steps,
+	}
+}
+
+// Steps splices steps and fragments into a flat step list, each item should be IStep,
+// []IStep or *StepFragment, e.g. Steps(loginFragment, NewStep("get user").GET("/user")).
+func Steps(items ...interface{}) []IStep {
+	var steps []IStep
+	for _, item := range items {
+		switch v := item.(type) {
+		case IStep:
+			steps = append(steps, v)
+		case []IStep:
+			steps = append(steps, v...)
+		case *StepFragment:
+			steps = append(steps, spliceFragment(v.steps, v.variables)...)
+		default:
+			panic(fmt.Sprintf("unexpected step type %T", item))
+		}
+	}
+	return steps
+}
@@ -0,0 +1,156 @@
+package hrp
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newEchoServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"path": "%s", "args": "%s"}`, r.URL.Path, r.URL.RawQuery)
+	}))
+}
+
+func TestLoadCaseWithInclude(t *testing.T) {
+	ts := newEchoServer()
+	defer ts.Close()
+
+	cwd, _ := os.Getwd()
+	dir := t.TempDir()
+	fragmentPath := filepath.Join(dir, "login.yaml")
+	relFragmentPath, _ := filepath.Rel(cwd, fragmentPath)
+	fragment := `
+variables:
+  username: admin
+  password: "123456"
+teststeps:
+  - name: login
+    request:
+      method: GET
+      url: /login
+      params:
+        username: $username
+        password: $password
+    extract:
+      login_args: body.args
+`
+	if err := os.WriteFile(fragmentPath, []byte(fragment), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	content := fmt.Sprintf(`
+config:
+  name: include
+  base_url: %s
+teststeps:
+  - name: login flow
+    include: %s
+    variables:
+      username: debugtalk
+  - name: get user
+    request:
+      method: GET
+      url: /user
+    validate:
+      - eq: ["body.path", "/user"]
+      - eq: ["$login_args", "password=123456&username=debugtalk"]
+`, ts.URL, relFragmentPath)
+	casePath := filepath.Join(dir, "include.yaml")
+	if err := os.WriteFile(casePath, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tc := TestCasePath(casePath)
+	testcase, err := tc.ToTestCase()
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	if !assert.Len(t, testcase.TestSteps, 2) {
+		t.Fatal()
+	}
+	assert.Equal(t, "login", testcase.TestSteps[0].Name())
+	// fragment variables do not pollute testcase config
+	assert.Empty(t, testcase.Config.Variables["password"])
+	assert.Nil(t, NewRunner(t).Run(testcase))
+}
+
+func TestRunCaseWithFragment(t *testing.T) {
+	ts := newEchoServer()
+	defer ts.Close()
+
+	newLoginFragment := func() *StepFragment {
+		return NewFragment(
+			NewStep("login").
+				GET("/login").
+				WithParams(map[string]interface{}{"username": "$username"}).
+				Extract().
+				WithJmesPath("body.args", "login_args"),
+		).WithVariables(map[string]interface{}{"username": "admin"})
+	}
+
+	testcase := &TestCase{
+		Config: NewConfig("fragment").SetBaseURL(ts.URL),
+		TestSteps: Steps(
+			newLoginFragment(),
+			NewStep("get user").
+				GET("/user").
+				Validate().
+				AssertEqual("$login_args", "username=admin", "check login args"),
+		),
+	}
+	if !assert.Len(t, testcase.TestSteps, 2) {
+		t.Fatal()
+	}
+	assert.Nil(t, NewRunner(t).Run(testcase))
+}
+
+func TestReuseFragmentWithVariables(t *testing.T) {
+	ts := newEchoServer()
+	defer ts.Close()
+
+	loginFragment := NewFragment(
+		NewStep("login").
+			WithVariables(map[string]interface{}{"password": "123456"}).
+			GET("/login").
+			WithParams(map[string]interface{}{"username": "$username"}).
+			Extract().
+			WithJmesPath("body.args", "login_args"),
+	)
+	newTestCase := func(username string) *TestCase {
+		return &TestCase{
+			Config: NewConfig("fragment " + username).SetBaseURL(ts.URL),
+			TestSteps: Steps(
+				loginFragment.WithVariables(map[string]interface{}{"username": username}),
+				NewStep("check").
+					GET("/user").
+					Validate().
+					AssertEqual("$login_args", "username="+username, "check login args"),
+			),
+		}
+	}
+	// reuse fragment in different testcases
+	admin := newTestCase("admin")
+	guest := newTestCase("guest")
+	assert.Nil(t, NewRunner(t).Run(admin, guest))
+	assert.Equal(t, "admin", admin.TestSteps[0].Struct().Variables["username"])
+	assert.Equal(t, "guest", guest.TestSteps[0].Struct().Variables["username"])
+	assert.Equal(t, "123456", guest.TestSteps[0].Struct().Variables["password"])
+
+	// reuse fragment twice in the same testcase
+	steps := Steps(
+		loginFragment.WithVariables(map[string]interface{}{"username": "admin"}),
+		loginFragment.WithVariables(map[string]interface{}{"username": "guest"}),
+	)
+	if assert.Len(t, steps, 2) {
+		assert.Equal(t, "admin", steps[0].Struct().Variables["username"])
+		assert.Equal(t, "guest", steps[1].Struct().Variables["username"])
+	}
+	// fragment steps are not changed
+	assert.Equal(t, map[string]interface{}{"password": "123456"}, loginFragment.steps[0].Struct().Variables)
+}
@@ -0,0 +1,178 @@
+package hrp
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/httprunner/httprunner/hrp/internal/boomer"
+)
+
+// regexLoadGate matches gate expression, e.g. p95 < 300ms, error_rate < 0.1%, rps >= 100
+var regexLoadGate = regexp.MustCompile(`^\s*([a-z_]+|p\d+(?:\.\d+)?)\s*(<=|>=|<|>|==)\s*(\d+(?:\.\d+)?)\s*(ms|s|%)?\s*$`)
+
+// LoadGate represents performance gate evaluated against stats of all requests at the end of load testing.
+// Supported metrics:
+// avg, min, max, median and pNN (e.g. p95, p99.9) of response time in ms (default) or s,
+// error_rate in ratio (e.g. 0.001) or percent (e.g. 0.1%), rps, requests and failures.
+type LoadGate struct {
+	Expr      string
+	Metric    string
+	Operator  string
+	Threshold float64 // milliseconds for response time, ratio for error rate
+}
+
+// ParseLoadGate parses gate expression, e.g. p95 < 300ms, error_rate < 0.1%.
+func ParseLoadGate(expr string) (*LoadGate, error) {
+	matches := regexLoadGate.FindStringSubmatch(strings.ToLower(expr))
+	if matches == nil {
+		return nil, fmt.Errorf("invalid gate %q, should be like p95 < 300ms or error_rate < 0.1%%", expr)
+	}
+	metric, operator, unit := matches[1], matches[2], matches[4]
+	threshold, _ := strconv.ParseFloat(matches[3], 64)
+
+	switch {
+	case isLatencyMetric(metric):
+		if metric != "avg" && metric != "min" && metric != "max" && metric != "median" {
+			if percent, _ := strconv.ParseFloat(metric[1:], 64); percent <= 0 || percent > 100 {
+				return nil, fmt.Errorf("invalid gate %q, percentile should be in (0, 100]", expr)
+			}
+		}
+		switch unit {
+		case "", "ms":
+		case "s":
+			threshold *= 1000
+		default:
+			return nil, fmt.Errorf("invalid gate %q, unit of response time should be ms or s", expr)
+		}
+	case metric == "error_rate":
+		switch unit {
+		case "":
+		case "%":
+			threshold /= 100
+		default:
+			return nil, fmt.Errorf("invalid gate %q, unit of error rate should be %%", expr)
+		}
+	case metric == "rps" || metric == "requests" || metric == "failures":
+		if unit != "" {
+			return nil, fmt.Errorf("invalid gate %q, unexpected unit %s of %s", expr, unit, metric)
+		}
+	default:
+		return nil, fmt.Errorf("invalid gate %q, unknown metric %s", expr, metric)
+	}
+
+	return &LoadGate{
+		Expr:      strings.TrimSpace(expr),
+		Metric:    metric,
+		Operator:  operator,
+		Threshold: threshold,
+	}, nil
+}
+
+func isLatencyMetric(metric string) bool {
+	switch metric {
+	case "avg", "min", "max", "median":
+		return true
+	}
+	if !strings.HasPrefix(metric, "p") {
+		return false
+	}
+	_, err := strconv.ParseFloat(metric[1:], 64)
+	return err == nil
+}
+
+// value returns actual value of gate metric from stats.
+func (g *LoadGate) value(stats *boomer.TotalStats) float64 {
+	switch g.Metric {
+	case "avg":
+		return stats.AvgResponseTime()
+	case "min":
+		return float64(stats.MinResponseTime)
+	case "max":
+		return float64(stats.MaxResponseTime)
+	case "median":
+		return float64(stats.PercentileResponseTime(50))
+	case "error_rate":
+		return stats.FailRatio()
+	case "rps":
+		return stats.RPS()
+	case "requests":
+		return float64(stats.NumRequests)
+	case "failures":
+		return float64(stats.NumFailures)
+	}
+	percent, _ := strconv.ParseFloat(g.Metric[1:], 64)
+	return float64(stats.PercentileResponseTime(percent))
+}
+
+// check returns error if gate is breached.
+func (g *LoadGate) check(stats *boomer.TotalStats) error {
+	actual := g.value(stats)
+	var passed bool
+	switch g.Operator {
+	case "<":
+		passed = actual < g.Threshold
+	case "<=":
+		passed = actual <= g.Threshold
+	case ">":
+		passed = actual > g.Threshold
+	case ">=":
+		passed = actual >= g.Threshold
+	case "==":
+		passed = actual == g.Threshold
+	}
+	if passed {
+		return nil
+	}
+	return fmt.Errorf("gate %q breached, actual %s: %s", g.Expr, g.Metric, g.format(actual))
+}
+
+func (g *LoadGate) format(value float64) string {
+	switch {
+	case isLatencyMetric(g.Metric):
+		return strconv.FormatFloat(value, 'f', -1, 64) + "ms"
+	case g.Metric == "error_rate":
+		return strconv.FormatFloat(value*100, 'f', -1, 64) + "%"
+	case g.Metric == "rps":
+		return strconv.FormatFloat(value, 'f', 2, 64)
+	}
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// AddGates adds performance gates evaluated by CheckGates after load testing, e.g. p95 < 300ms.
+func (b *HRPBoomer) AddGates(exprs ...string) error {
+	for _, expr := range exprs {
+		gate, err := ParseLoadGate(expr)
+		if err != nil {
+			return err
+		}
+		b.gates = append(b.gates, gate)
+	}
+	return nil
+}
+
+// CheckGates evaluates performance gates of boomer and testcases against stats of all requests,
+// which should be called after Run returns, error is returned if any gate is breached.
+func (b *HRPBoomer) CheckGates() error {
+	if len(b.gates) == 0 {
+		return nil
+	}
+	stats := b.GetTotalStats()
+	var breached []string
+	for _, gate := range b.gates {
+		if err := gate.check(stats); err != nil {
+			log.Error().Err(err).Msg("performance gate breached")
+			breached = append(breached, err.Error())
+			continue
+		}
+		log.Warn().Str("gate", gate.Expr).Msg("performance gate passed")
+	}
+	if len(breached) > 0 {
+		return errors.New(strings.Join(breached, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package hrp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLoadGate(t *testing.T) {
+	testData := []struct {
+		expr      string
+		metric    string
+		operator  string
+		threshold float64
+	}{
+		{"p95 < 300ms", "p95", "<", 300},
+		{"P99.9<=1.5s", "p99.9", "<=", 1500},
+		{"avg < 200", "avg", "<", 200},
+		{"median<100ms", "median", "<", 100},
+		{"error_rate < 0.1%", "error_rate", "<", 0.001},
+		{"error_rate <= 0.01", "error_rate", "<=", 0.01},
+		{"rps >= 100", "rps", ">=", 100},
+		{"failures == 0", "failures", "==", 0},
+	}
+	for _, data := range testData {
+		gate, err := ParseLoadGate(data.expr)
+		if !assert.Nil(t, err, data.expr) {
+			continue
+		}
+		assert.Equal(t, data.metric, gate.Metric, data.expr)
+		assert.Equal(t, data.operator, gate.Operator, data.expr)
+		assert.InDelta(t, data.threshold, gate.Threshold, 1e-9, data.expr)
+	}
+
+	for _, expr := range []string{"p95", "p95 ~ 300ms", "p0 < 1", "p101 < 1", "latency < 1", "error_rate < 1ms", "rps > 1%", "avg < 1%"} {
+		_, err := ParseLoadGate(expr)
+		assert.NotNil(t, err, expr)
+	}
+}
+
+func TestCheckGates(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer ts.Close()
+
+	testcase := &TestCase{
+		Config: NewConfig("gates").SetBaseURL(ts.URL).
+			SetGates("p95 < 10s", "requests == 4"),
+		TestSteps: []IStep{
+			NewStep("ok").GET("/ok"),
+			NewStep("fail").GET("/fail").
+				Validate().
+				AssertEqual("status_code", 200, "check status code"),
+		},
+	}
+	b := NewBoomer(1, 10)
+	b.SetLoopCount(2)
+	if !assert.Nil(t, b.AddGates("error_rate < 10%", "failures <= 2")) {
+		t.Fatal()
+	}
+	b.Run(testcase)
+
+	err := b.CheckGates()
+	if !assert.NotNil(t, err) {
+		t.Fatal()
+	}
+	assert.Equal(t, `gate "error_rate < 10%" breached, actual error_rate: 50%`, err.Error())
+}
@@ -0,0 +1,365 @@
+package hrp
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/httprunner/httprunner/hrp/internal/version"
+)
+
+/*
+HTTP Archive (HAR) 1.2 format
+http://www.softwareishard.com/blog/har-12-spec/
+only fields recorded by hrp are defined, the exported file can be loaded into browser devtools.
+*/
+
+type harFile struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string      `json:"version"`
+	Creator harCreator  `json:"creator"`
+	Pages   []*harPage  `json:"pages"`
+	Entries []*harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harPage struct {
+	StartedDateTime string         `json:"startedDateTime"`
+	ID              string         `json:"id"`
+	Title           string         `json:"title"`
+	PageTimings     harPageTimings `json:"pageTimings"`
+}
+
+type harPageTimings struct {
+	OnContentLoad float64 `json:"onContentLoad"`
+	OnLoad        float64 `json:"onLoad"`
+}
+
+type harEntry struct {
+	Pageref         string      `json:"pageref,omitempty"`
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"` // total elapsed milliseconds
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+	Comment         string      `json:"comment,omitempty"`
+}
+
+type harRequest struct {
+	Method      string       `json:"method"`
+	URL         string       `json:"url"`
+	HTTPVersion string       `json:"httpVersion"`
+	Cookies     []harNVP     `json:"cookies"`
+	Headers     []harNVP     `json:"headers"`
+	QueryString []harNVP     `json:"queryString"`
+	PostData    *harPostData `json:"postData,omitempty"`
+	HeadersSize int          `json:"headersSize"`
+	BodySize    int          `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int        `json:"status"`
+	StatusText  string     `json:"statusText"`
+	HTTPVersion string     `json:"httpVersion"`
+	Cookies     []harNVP   `json:"cookies"`
+	Headers     []harNVP   `json:"headers"`
+	Content     harContent `json:"content"`
+	RedirectURL string     `json:"redirectURL"`
+	HeadersSize int        `json:"headersSize"`
+	BodySize    int        `json:"bodySize"`
+}
+
+type harNVP struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// harTimings records timing phases in milliseconds, -1 if the phase does not apply.
+type harTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+// harRecorder records all executed requests and responses of a run, which are written to HAR file.
+type harRecorder struct {
+	mutex   sync.Mutex
+	pages   []*harPage
+	entries []*harEntry
+}
+
+func newHARRecorder() *harRecorder {
+	return &harRecorder{}
+}
+
+// addEntry appends entry to page of testcase, page is created at the first entry of testcase.
+func (h *harRecorder) addEntry(pageTitle string, entry *harEntry) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	var page *harPage
+	for _, p := range h.pages {
+		if p.Title == pageTitle {
+			page = p
+			break
+		}
+	}
+	if page == nil {
+		page = &harPage{
+			StartedDateTime: entry.StartedDateTime,
+			ID:              "page_" + strconv.Itoa(len(h.pages)+1),
+			Title:           pageTitle,
+		}
+		h.pages = append(h.pages, page)
+	}
+	entry.Pageref = page.ID
+	h.entries = append(h.entries, entry)
+}
+
+// dump writes recorded entries to HAR file.
+func (h *harRecorder) dump(path string) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	har := &harFile{
+		Log: harLog{
+			Version: "1.2",
+			Creator: harCreator{Name: "hrp", Version: version.VERSION},
+			Pages:   h.pages,
+			Entries: h.entries,
+		},
+	}
+	if har.Log.Pages == nil {
+		har.Log.Pages = []*harPage{}
+	}
+	if har.Log.Entries == nil {
+		har.Log.Entries = []*harEntry{}
+	}
+	return dumpMaskedJSON(har, path)
+}
+
+// harCapture captures request, response and timing phases of one request.
+type harCapture struct {
+	entry *harEntry
+	start time.Time
+
+	// mutex protects timing phases and server address written by trace callbacks,
+	// which may be called in dialing goroutines of transport even after request is canceled
+	mutex                     sync.Mutex
+	serverIPAddress           string
+	dnsStart, dnsDone         time.Time
+	connectStart, connectDone time.Time
+	tlsStart, tlsDone         time.Time
+	gotConn, wroteRequest     time.Time
+	firstByte                 time.Time
+}
+
+// newHARCapture records request content and traces timing phases of request,
+// request body is read and reset thus it can still be sent.
+func newHARCapture(req *http.Request) (*harCapture, *http.Request, error) {
+	c := &harCapture{
+		entry: &harEntry{
+			Request: harRequest{
+				Method:      req.Method,
+				URL:         displayURL(req.URL),
+				HTTPVersion: req.Proto,
+				Cookies:     []harNVP{},
+				Headers:     harHeaders(req.Header),
+				QueryString: []harNVP{},
+				HeadersSize: -1,
+			},
+		},
+	}
+	if c.entry.Request.HTTPVersion == "" {
+		c.entry.Request.HTTPVersion = "HTTP/1.1"
+	}
+	for _, cookie := range req.Cookies() {
+		c.entry.Request.Cookies = append(c.entry.Request.Cookies, harNVP{Name: cookie.Name, Value: cookie.Value})
+	}
+	for name, values := range req.URL.Query() {
+		for _, value := range values {
+			c.entry.Request.QueryString = append(c.entry.Request.QueryString, harNVP{Name: name, Value: value})
+		}
+	}
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, req, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		c.entry.Request.BodySize = len(body)
+		c.entry.Request.PostData = &harPostData{
+			MimeType: req.Header.Get("Content-Type"),
+			Text:     string(body),
+		}
+	}
+
+	trace := &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { c.record(&c.dnsStart) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { c.record(&c.dnsDone) },
+		ConnectStart:         func(string, string) { c.record(&c.connectStart) },
+		ConnectDone:          func(string, string, error) { c.record(&c.connectDone) },
+		TLSHandshakeStart:    func() { c.record(&c.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { c.record(&c.tlsDone) },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { c.record(&c.wroteRequest) },
+		GotFirstResponseByte: func() { c.record(&c.firstByte) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			c.record(&c.gotConn)
+			if addr, ok := info.Conn.RemoteAddr().(*net.TCPAddr); ok {
+				c.mutex.Lock()
+				c.serverIPAddress = addr.IP.String()
+				c.mutex.Unlock()
+			}
+		},
+	}
+	c.start = time.Now()
+	c.entry.StartedDateTime = c.start.Format(time.RFC3339Nano)
+	return c, req.WithContext(httptrace.WithClientTrace(req.Context(), trace)), nil
+}
+
+// record records time of timing phase.
+func (c *harCapture) record(t *time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	*t = time.Now()
+}
+
+// finish records response content and timing phases, response body is read and reset.
+// resp is nil if request failed, and err is recorded as entry comment.
+func (c *harCapture) finish(resp *http.Response, err error) (*harEntry, error) {
+	entry := c.entry
+	entry.Response = harResponse{
+		Cookies:     []harNVP{},
+		Headers:     []harNVP{},
+		HeadersSize: -1,
+		BodySize:    -1,
+	}
+	if err != nil {
+		entry.Comment = err.Error()
+	}
+	if resp != nil {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+
+		entry.Response.Status = resp.StatusCode
+		entry.Response.StatusText = http.StatusText(resp.StatusCode)
+		entry.Response.HTTPVersion = resp.Proto
+		entry.Response.Headers = harHeaders(resp.Header)
+		entry.Response.RedirectURL = resp.Header.Get("Location")
+		entry.Response.BodySize = len(body)
+		for _, cookie := range resp.Cookies() {
+			entry.Response.Cookies = append(entry.Response.Cookies, harNVP{Name: cookie.Name, Value: cookie.Value})
+		}
+		entry.Response.Content = harContent{
+			Size:     len(body),
+			MimeType: resp.Header.Get("Content-Type"),
+		}
+		if utf8.Valid(body) {
+			entry.Response.Content.Text = string(body)
+		} else {
+			entry.Response.Content.Text = base64.StdEncoding.EncodeToString(body)
+			entry.Response.Content.Encoding = "base64"
+		}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	entry.ServerIPAddress = c.serverIPAddress
+	end := time.Now()
+	entry.Time = milliseconds(end.Sub(c.start))
+	entry.Timings = harTimings{
+		Blocked: -1,
+		DNS:     phase(c.dnsStart, c.dnsDone),
+		Connect: phase(c.connectStart, c.connectDone),
+		SSL:     phase(c.tlsStart, c.tlsDone),
+		Send:    phase(c.gotConn, c.wroteRequest),
+		Wait:    phase(c.wroteRequest, c.firstByte),
+		Receive: phase(c.firstByte, end),
+	}
+	if !c.gotConn.IsZero() {
+		entry.Timings.Blocked = milliseconds(c.gotConn.Sub(c.start))
+		// blocked time excludes dns and connect phases
+		for _, t := range []float64{entry.Timings.DNS, entry.Timings.Connect} {
+			if t > 0 {
+				entry.Timings.Blocked -= t
+			}
+		}
+		if entry.Timings.Blocked < 0 {
+			entry.Timings.Blocked = 0
+		}
+	}
+	// connect time includes ssl time in HAR
+	if entry.Timings.SSL > 0 && entry.Timings.Connect >= 0 {
+		entry.Timings.Connect += entry.Timings.SSL
+	}
+	return entry, nil
+}
+
+func harHeaders(header http.Header) []harNVP {
+	headers := []harNVP{}
+	for name, values := range header {
+		for _, value := range values {
+			headers = append(headers, harNVP{Name: name, Value: value})
+		}
+	}
+	return headers
+}
+
+// phase returns milliseconds between start and end, -1 if phase is not traced.
+func phase(start, end time.Time) float64 {
+	if start.IsZero() || end.IsZero() {
+		return -1
+	}
+	return milliseconds(end.Sub(start))
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// recordHAR records entry of request into HAR recorder of runner.
+func (r *SessionRunner) recordHAR(entry *harEntry) {
+	if entry == nil {
+		return
+	}
+	r.hrpRunner.harRecorder.addEntry(r.testCase.Config.Name, entry)
+	log.Debug().Str("url", entry.Request.URL).Msg("request recorded to HAR")
+}
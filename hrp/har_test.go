@@ -0,0 +1,100 @@
+package hrp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/httprunner/httprunner/hrp/internal/builtin"
+)
+
+func TestRunCaseWithHAROutput(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/error" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		_, _ = w.Write([]byte(`{"path": "` + r.URL.Path + `"}`))
+	}))
+	defer ts.Close()
+
+	testcase := &TestCase{
+		Config: NewConfig("har").SetBaseURL(ts.URL),
+		TestSteps: []IStep{
+			NewStep("create user").
+				POST("/users").
+				WithParams(map[string]interface{}{"dry_run": true}).
+				WithBody(map[string]interface{}{"name": "hrp"}),
+			NewStep("failed request").
+				GET("/error").
+				Validate().
+				AssertEqual("status_code", 200, "check status code"),
+		},
+	}
+
+	harPath := filepath.Join(t.TempDir(), "run.har")
+	err := NewRunner(nil).SetHAROutput(harPath).Run(testcase)
+	if !assert.NotNil(t, err) {
+		t.Fatal()
+	}
+
+	har := &harFile{}
+	if !assert.Nil(t, builtin.LoadFile(harPath, har)) {
+		t.Fatal()
+	}
+	assert.Equal(t, "1.2", har.Log.Version)
+	if !assert.Len(t, har.Log.Entries, 2) {
+		t.Fatal()
+	}
+	assert.Equal(t, []*harPage{{
+		StartedDateTime: har.Log.Entries[0].StartedDateTime,
+		ID:              "page_1",
+		Title:           "har",
+	}}, har.Log.Pages)
+
+	entry := har.Log.Entries[0]
+	assert.Equal(t, "page_1", entry.Pageref)
+	assert.Equal(t, "POST", entry.Request.Method)
+	assert.Equal(t, []harNVP{{Name: "dry_run", Value: "true"}}, entry.Request.QueryString)
+	assert.Equal(t, `{"name":"hrp"}`, entry.Request.PostData.Text)
+	assert.Equal(t, 200, entry.Response.Status)
+	assert.Equal(t, `{"path": "/users"}`, entry.Response.Content.Text)
+	assert.Equal(t, "127.0.0.1", entry.ServerIPAddress)
+	assert.Greater(t, entry.Time, 0.0)
+	assert.GreaterOrEqual(t, entry.Timings.Wait, 0.0)
+
+	assert.Equal(t, 500, har.Log.Entries[1].Response.Status)
+	assert.Equal(t, "Internal Server Error", har.Log.Entries[1].Response.StatusText)
+}
+
+func TestHARCaptureTraceRace(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1/", nil)
+	c, req, err := newHARCapture(req)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	trace := httptrace.ContextClientTrace(req.Context())
+	if !assert.NotNil(t, trace) {
+		t.Fatal()
+	}
+
+	// trace callbacks may be called by dialing goroutines of transport after request failed
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		trace.DNSStart(httptrace.DNSStartInfo{})
+		trace.DNSDone(httptrace.DNSDoneInfo{})
+		trace.ConnectStart("tcp", "127.0.0.1:80")
+		trace.ConnectDone("tcp", "127.0.0.1:80", nil)
+	}()
+	entry, err := c.finish(nil, context.DeadlineExceeded)
+	<-done
+	if assert.Nil(t, err) {
+		assert.Equal(t, context.DeadlineExceeded.Error(), entry.Comment)
+	}
+}
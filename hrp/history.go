@@ -0,0 +1,266 @@
+package hrp
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"math/rand"
+	"strconv"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+const historyTableSQL = `CREATE TABLE IF NOT EXISTS hrp_results (
+	run_id BIGINT NOT NULL,
+	testcase VARCHAR(255) NOT NULL,
+	step_index INTEGER NOT NULL,
+	step VARCHAR(255) NOT NULL,
+	step_type VARCHAR(32) NOT NULL,
+	success BOOLEAN NOT NULL,
+	elapsed_ms BIGINT NOT NULL
+)`
+
+// HistoryStore records per-step latency and outcome of each run into database, which is used to compare
+// results with previous runs. Runs are identified by start time in unix milliseconds followed by 3 random digits,
+// so that runs started in the same millisecond are still told apart.
+// The default driver is sqlite3, which is built in with pure go sqlite driver.
+type HistoryStore struct {
+	db *sql.DB
+}
+
+// OpenHistoryStore opens history store with database driver and dsn, e.g. ("sqlite3", "reports/history.db"),
+// result table is created if not exists.
+func OpenHistoryStore(driver, dsn string) (*HistoryStore, error) {
+	db, err := sql.Open(sqlDriverName(driver), dsn)
+	if err != nil {
+		return nil, errors.Wrap(err, "open history store failed")
+	}
+	if _, err := db.Exec(historyTableSQL); err != nil {
+		db.Close()
+		return nil, errors.Wrap(err, "create history table failed")
+	}
+	return &HistoryStore{db: db}, nil
+}
+
+// Close closes database of history store.
+func (s *HistoryStore) Close() error {
+	return s.db.Close()
+}
+
+// SetHistoryStore configures to record results of each run into history store.
+func (r *HRPRunner) SetHistoryStore(store *HistoryStore) *HRPRunner {
+	log.Info().Msg("[init] SetHistoryStore")
+	r.historyStore = store
+	return r
+}
+
+// stepRecord represents latency and outcome of step in history store.
+type stepRecord struct {
+	TestCase  string
+	StepIndex int
+	Step      string
+	StepType  StepType
+	Success   bool
+	Elapsed   int64
+}
+
+// key identifies the same step among runs, steps of the same name in testcase are told by index.
+func (s *stepRecord) key() string {
+	return fmt.Sprintf("%s\x00%d\x00%s", s.TestCase, s.StepIndex, s.Step)
+}
+
+// Save records step results of summary as a run.
+func (s *HistoryStore) Save(summary *Summary) error {
+	runID := summary.Time.StartAt.UnixNano()/int64(1e6)*1000 + int64(rand.Intn(1000))
+	tx, err := s.db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "begin history transaction failed")
+	}
+	stmt, err := tx.Prepare("INSERT INTO hrp_results (run_id, testcase, step_index, step, step_type, success, elapsed_ms) " +
+		"VALUES (?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "prepare history statement failed")
+	}
+	defer stmt.Close()
+	for _, caseSummary := range summary.Details {
+		for index, record := range caseSummary.Records {
+			if record == nil {
+				continue
+			}
+			_, err := stmt.Exec(runID, caseSummary.Name, index, record.Name, string(record.StepType), record.Success, record.Elapsed)
+			if err != nil {
+				tx.Rollback()
+				return errors.Wrap(err, "insert history record failed")
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "commit history transaction failed")
+	}
+	log.Info().Int64("runID", runID).Msg("save run results into history store")
+	return nil
+}
+
+// LatestRuns returns ids of latest n runs, in descending order of start time.
+func (s *HistoryStore) LatestRuns(n int) ([]int64, error) {
+	rows, err := s.db.Query("SELECT DISTINCT run_id FROM hrp_results ORDER BY run_id DESC LIMIT ?", n)
+	if err != nil {
+		return nil, errors.Wrap(err, "query latest runs failed")
+	}
+	defer rows.Close()
+	var runIDs []int64
+	for rows.Next() {
+		var runID int64
+		if err := rows.Scan(&runID); err != nil {
+			return nil, errors.Wrap(err, "scan run id failed")
+		}
+		runIDs = append(runIDs, runID)
+	}
+	return runIDs, rows.Err()
+}
+
+func (s *HistoryStore) loadRun(runID int64) ([]*stepRecord, error) {
+	rows, err := s.db.Query("SELECT testcase, step_index, step, step_type, success, elapsed_ms FROM hrp_results "+
+		"WHERE run_id = ? ORDER BY testcase, step_index", runID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "query results of run %d failed", runID)
+	}
+	defer rows.Close()
+	var records []*stepRecord
+	for rows.Next() {
+		record := &stepRecord{}
+		var stepType string
+		err := rows.Scan(&record.TestCase, &record.StepIndex, &record.Step, &stepType, &record.Success, &record.Elapsed)
+		if err != nil {
+			return nil, errors.Wrap(err, "scan history record failed")
+		}
+		record.StepType = StepType(stepType)
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("run %d not found in history store", runID)
+	}
+	return records, nil
+}
+
+// StepComparison represents latency and outcome of step in baseline run and current run.
+type StepComparison struct {
+	TestCase        string  `json:"testcase"`
+	Step            string  `json:"step"`
+	BaselineElapsed int64   `json:"baseline_elapsed_ms"`
+	CurrentElapsed  int64   `json:"current_elapsed_ms"`
+	Change          float64 `json:"change"` // relative change of latency, e.g. 0.5 means 50% slower
+	BaselineSuccess bool    `json:"baseline_success"`
+	CurrentSuccess  bool    `json:"current_success"`
+	Regressed       bool    `json:"regressed"` // latency regressed beyond threshold, or step turned failed
+}
+
+// RunComparison represents comparison of steps in current run against baseline run.
+type RunComparison struct {
+	BaselineRun int64             `json:"baseline_run"`
+	CurrentRun  int64             `json:"current_run"`
+	Threshold   float64           `json:"threshold"`
+	Steps       []*StepComparison `json:"steps"`
+}
+
+// Regressions returns steps regressed in current run.
+func (c *RunComparison) Regressions() []*StepComparison {
+	var steps []*StepComparison
+	for _, step := range c.Steps {
+		if step.Regressed {
+			steps = append(steps, step)
+		}
+	}
+	return steps
+}
+
+// Print prints comparison of steps as table, regressed steps are marked.
+func (c *RunComparison) Print(w io.Writer) {
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"Testcase", "Step", "Baseline(ms)", "Current(ms)", "Change", "Status", "Regressed"})
+	for _, step := range c.Steps {
+		status := "pass"
+		if !step.CurrentSuccess {
+			status = "fail"
+		}
+		regressed := ""
+		if step.Regressed {
+			regressed = "yes"
+		}
+		table.Append([]string{
+			step.TestCase,
+			step.Step,
+			strconv.FormatInt(step.BaselineElapsed, 10),
+			strconv.FormatInt(step.CurrentElapsed, 10),
+			fmt.Sprintf("%+.1f%%", step.Change*100),
+			status,
+			regressed,
+		})
+	}
+	table.Render()
+}
+
+// Compare compares steps of current run against baseline run, steps not executed in baseline run are skipped.
+// Step is regressed if latency increases beyond threshold, e.g. 0.2 for 20%, or it passed in baseline run but fails now.
+func (s *HistoryStore) Compare(baselineRun, currentRun int64, threshold float64) (*RunComparison, error) {
+	baseline, err := s.loadRun(baselineRun)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.loadRun(currentRun)
+	if err != nil {
+		return nil, err
+	}
+	return &RunComparison{
+		BaselineRun: baselineRun,
+		CurrentRun:  currentRun,
+		Threshold:   threshold,
+		Steps:       compareStepRecords(baseline, current, threshold),
+	}, nil
+}
+
+// CompareLast compares the latest run against the previous run.
+func (s *HistoryStore) CompareLast(threshold float64) (*RunComparison, error) {
+	runIDs, err := s.LatestRuns(2)
+	if err != nil {
+		return nil, err
+	}
+	if len(runIDs) < 2 {
+		return nil, errors.New("at least two runs are required in history store to compare")
+	}
+	return s.Compare(runIDs[1], runIDs[0], threshold)
+}
+
+func compareStepRecords(baseline, current []*stepRecord, threshold float64) []*StepComparison {
+	baselineRecords := make(map[string]*stepRecord, len(baseline))
+	for _, record := range baseline {
+		baselineRecords[record.key()] = record
+	}
+	var steps []*StepComparison
+	for _, record := range current {
+		base, ok := baselineRecords[record.key()]
+		if !ok {
+			continue
+		}
+		step := &StepComparison{
+			TestCase:        record.TestCase,
+			Step:            record.Step,
+			BaselineElapsed: base.Elapsed,
+			CurrentElapsed:  record.Elapsed,
+			BaselineSuccess: base.Success,
+			CurrentSuccess:  record.Success,
+		}
+		if base.Elapsed > 0 {
+			step.Change = float64(record.Elapsed-base.Elapsed) / float64(base.Elapsed)
+		}
+		step.Regressed = step.Change > threshold || (base.Success && !record.Success)
+		steps = append(steps, step)
+	}
+	return steps
+}
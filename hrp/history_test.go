@@ -0,0 +1,153 @@
+package hrp
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareStepRecords(t *testing.T) {
+	baseline := []*stepRecord{
+		{TestCase: "tc", StepIndex: 0, Step: "login", Success: true, Elapsed: 100},
+		{TestCase: "tc", StepIndex: 1, Step: "query", Success: true, Elapsed: 100},
+		{TestCase: "tc", StepIndex: 2, Step: "query", Success: true, Elapsed: 100},
+		{TestCase: "tc", StepIndex: 3, Step: "logout", Success: false, Elapsed: 10},
+	}
+	current := []*stepRecord{
+		{TestCase: "tc", StepIndex: 0, Step: "login", Success: true, Elapsed: 110},
+		{TestCase: "tc", StepIndex: 1, Step: "query", Success: true, Elapsed: 130},
+		{TestCase: "tc", StepIndex: 2, Step: "query", Success: false, Elapsed: 50},
+		{TestCase: "tc", StepIndex: 3, Step: "logout", Success: false, Elapsed: 10},
+		{TestCase: "tc", StepIndex: 4, Step: "new step", Success: true, Elapsed: 10},
+	}
+	steps := compareStepRecords(baseline, current, 0.2)
+	if !assert.Len(t, steps, 4) {
+		t.Fatal()
+	}
+	assert.False(t, steps[0].Regressed) // +10%
+	assert.True(t, steps[1].Regressed)  // +30%
+	assert.InDelta(t, 0.3, steps[1].Change, 1e-9)
+	assert.True(t, steps[2].Regressed)  // turned failed
+	assert.False(t, steps[3].Regressed) // failed in baseline run
+}
+
+func TestRunWithHistoryStore(t *testing.T) {
+	db, mock, err := sqlmock.NewWithDSN("hrp_history_db", sqlmock.QueryMatcherOption(sqlmock.QueryMatcherRegexp))
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	defer db.Close()
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS hrp_results").WillReturnResult(sqlmock.NewResult(0, 0))
+	store, err := OpenHistoryStore("sqlmock", "hrp_history_db")
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+	testcase := &TestCase{
+		Config: NewConfig("history").SetBaseURL(ts.URL),
+		TestSteps: []IStep{
+			NewStep("get").GET("/get"),
+			NewStep("post").POST("/post"),
+		},
+	}
+	mock.ExpectBegin()
+	insert := mock.ExpectPrepare(regexp.QuoteMeta("INSERT INTO hrp_results"))
+	insert.ExpectExec().
+		WithArgs(sqlmock.AnyArg(), "history", 0, "get", "request", true, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	insert.ExpectExec().
+		WithArgs(sqlmock.AnyArg(), "history", 1, "post", "request", true, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectCommit()
+	assert.Nil(t, NewRunner(t).SetHistoryStore(store).Run(testcase))
+
+	// compare the latest run with the previous run
+	columns := []string{"testcase", "step_index", "step", "step_type", "success", "elapsed_ms"}
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT DISTINCT run_id FROM hrp_results ORDER BY run_id DESC LIMIT ?")).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"run_id"}).AddRow(2000).AddRow(1000))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT testcase, step_index, step, step_type, success, elapsed_ms FROM hrp_results")).
+		WithArgs(1000).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("history", 0, "get", "request", true, 100).
+			AddRow("history", 1, "post", "request", true, 100))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT testcase, step_index, step, step_type, success, elapsed_ms FROM hrp_results")).
+		WithArgs(2000).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("history", 0, "get", "request", true, 150).
+			AddRow("history", 1, "post", "request", true, 90))
+	comparison, err := store.CompareLast(0.2)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	assert.Equal(t, int64(1000), comparison.BaselineRun)
+	assert.Equal(t, int64(2000), comparison.CurrentRun)
+	regressions := comparison.Regressions()
+	if assert.Len(t, regressions, 1) {
+		assert.Equal(t, "get", regressions[0].Step)
+	}
+	var buf bytes.Buffer
+	comparison.Print(&buf)
+	assert.Contains(t, buf.String(), "+50.0%")
+
+	// not enough runs to compare
+	mock.ExpectQuery("SELECT DISTINCT run_id").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"run_id"}).AddRow(2000))
+	_, err = store.CompareLast(0.2)
+	assert.NotNil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestHistoryStoreWithSQLite(t *testing.T) {
+	store, err := OpenHistoryStore("sqlite3", filepath.Join(t.TempDir(), "history.db"))
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	defer store.Close()
+
+	var delay int64 = 10 // milliseconds
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Duration(atomic.LoadInt64(&delay)) * time.Millisecond)
+	}))
+	defer ts.Close()
+	testcase := &TestCase{
+		Config: NewConfig("history").SetBaseURL(ts.URL),
+		TestSteps: []IStep{
+			NewStep("get").GET("/get"),
+		},
+	}
+	runner := NewRunner(t).SetHistoryStore(store)
+	assert.Nil(t, runner.Run(testcase))
+	atomic.StoreInt64(&delay, 60)
+	assert.Nil(t, runner.Run(testcase))
+
+	runIDs, err := store.LatestRuns(5)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	assert.Len(t, runIDs, 2)
+	comparison, err := store.CompareLast(0.2)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	assert.Equal(t, runIDs[1], comparison.BaselineRun)
+	if assert.Len(t, comparison.Steps, 1) {
+		step := comparison.Steps[0]
+		assert.Equal(t, "get", step.Step)
+		assert.True(t, step.BaselineSuccess)
+		assert.True(t, step.CurrentSuccess)
+		assert.GreaterOrEqual(t, step.CurrentElapsed, int64(60))
+		assert.True(t, step.Regressed)
+	}
+}
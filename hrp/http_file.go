@@ -0,0 +1,96 @@
+package hrp
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/httprunner/httprunner/hrp/internal/builtin"
+)
+
+// SetHTTPFileOutput configures to write each executed request step with resolved variables
+// into .http file under dir, which can be re-sent from JetBrains HTTP Client or VS Code REST Client.
+// Files are grouped by testcase and named by execution order, e.g. dir/testcase/001_step.http.
+func (r *HRPRunner) SetHTTPFileOutput(dir string) *HRPRunner {
+	log.Info().Str("dir", dir).Msg("[init] SetHTTPFileOutput")
+	r.httpFileDir = dir
+	return r
+}
+
+// httpFilePath returns .http file path of request step, returns empty string if not enabled.
+func (r *SessionRunner) httpFilePath(stepName string) string {
+	dir := r.hrpRunner.httpFileDir
+	if dir == "" {
+		return ""
+	}
+	seq := atomic.AddInt64(&r.hrpRunner.httpFileSeq, 1)
+	caseDir := regexSnapshotFileName.ReplaceAllString(strings.TrimSpace(r.testCase.Config.Name), "_")
+	if caseDir == "" {
+		caseDir = "testcase"
+	}
+	fileName := regexSnapshotFileName.ReplaceAllString(strings.TrimSpace(stepName), "_")
+	return filepath.Join(dir, caseDir, fmt.Sprintf("%03d_%s.http", seq, fileName))
+}
+
+// dumpHTTPFile writes request into .http file with registered secrets masked,
+// request body is read and reset thus it can still be sent.
+func dumpHTTPFile(path, stepName string, client *http.Client, req *http.Request) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "### %s\n", stepName)
+	fmt.Fprintf(&b, "%s %s\n", req.Method, displayURL(req.URL))
+	if req.Host != "" && req.Host != req.URL.Host {
+		fmt.Fprintf(&b, "Host: %s\n", req.Host)
+	}
+	header := req.Header.Clone()
+	// cookies are added by cookie jar when request is sent
+	if header.Get("Cookie") == "" && client != nil && client.Jar != nil {
+		var cookies []string
+		for _, cookie := range client.Jar.Cookies(req.URL) {
+			cookies = append(cookies, cookie.Name+"="+cookie.Value)
+		}
+		if len(cookies) > 0 {
+			header.Set("Cookie", strings.Join(cookies, "; "))
+		}
+	}
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range header[name] {
+			fmt.Fprintf(&b, "%s: %s\n", name, value)
+		}
+	}
+
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return errors.Wrap(err, "read request body failed")
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		b.WriteString("\n")
+		if utf8.Valid(body) {
+			b.Write(body)
+			b.WriteString("\n")
+		} else {
+			fmt.Fprintf(&b, "# binary body of %d bytes omitted\n", len(body))
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(builtin.MaskSecrets(b.String())), 0o644)
+}
@@ -0,0 +1,65 @@
+package hrp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunCaseWithHTTPFileOutput(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc123"})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"token": "secret-token"}`))
+	}))
+	defer ts.Close()
+
+	RegisterSecrets("secret-token")
+	testcase := &TestCase{
+		Config: NewConfig("http file").
+			SetBaseURL(ts.URL).
+			WithVariables(map[string]interface{}{"name": "hrp"}),
+		TestSteps: []IStep{
+			NewStep("login").
+				POST("/login").
+				Extract().
+				WithJmesPath("body.token", "token"),
+			NewStep("create user").
+				POST("/users").
+				WithParams(map[string]interface{}{"dry_run": true}).
+				WithHeaders(map[string]string{"Authorization": "Bearer $token"}).
+				WithBody(map[string]interface{}{"name": "$name"}),
+		},
+	}
+
+	dir := t.TempDir()
+	// cookies of cookie jar enabled by session state are written
+	err := NewRunner(t).SetSaveSession(true).SetHTTPFileOutput(dir).Run(testcase)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "http_file", "*.http"))
+	assert.Equal(t, []string{
+		filepath.Join(dir, "http_file", "001_login.http"),
+		filepath.Join(dir, "http_file", "002_create_user.http"),
+	}, files)
+
+	content, err := os.ReadFile(filepath.Join(dir, "http_file", "002_create_user.http"))
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	assert.Equal(t, "### create user\n"+
+		"POST "+ts.URL+"/users?dry_run=true\n"+
+		"Authorization: Bearer ******\n"+
+		"Content-Type: application/json; charset=utf-8\n"+
+		"Cookie: session=abc123\n"+
+		"\n"+
+		`{"name":"hrp"}`+"\n", string(content))
+}
@@ -0,0 +1,111 @@
+package hrp
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+type idempotencyKeyScope string
+
+const (
+	idempotencyKeyPerStep    idempotencyKeyScope = "step"    // one key for each step, re-issued requests of step share the same key
+	idempotencyKeyPerRequest idempotencyKeyScope = "request" // new key for each request, including re-issued requests
+)
+
+const (
+	defaultIdempotencyKeyHeader   = "Idempotency-Key"
+	defaultIdempotencyKeyVariable = "idempotency_key"
+)
+
+// IdempotencyKeyConfig generates idempotency key for requests of unsafe methods without explicit key header,
+// the generated key is exposed as session variable, e.g. to assert it is echoed by server in later steps.
+type IdempotencyKeyConfig struct {
+	Header   string              `json:"header,omitempty" yaml:"header,omitempty"`     // default to Idempotency-Key
+	Template string              `json:"template,omitempty" yaml:"template,omitempty"` // default to random uuid, e.g. order-${gen_random_string(16)}
+	Scope    idempotencyKeyScope `json:"scope,omitempty" yaml:"scope,omitempty"`       // step (default), request
+	Variable string              `json:"variable,omitempty" yaml:"variable,omitempty"` // variable name of generated key, default to idempotency_key
+	Methods  []string            `json:"methods,omitempty" yaml:"methods,omitempty"`   // methods to inject key, default to POST, PUT, PATCH and DELETE
+}
+
+// SetIdempotencyKey sets idempotency key generation for requests of current testcase.
+func (c *TConfig) SetIdempotencyKey(cfg *IdempotencyKeyConfig) *TConfig {
+	c.IdempotencyKey = cfg
+	return c
+}
+
+func (c *IdempotencyKeyConfig) header() string {
+	if c.Header == "" {
+		return defaultIdempotencyKeyHeader
+	}
+	return c.Header
+}
+
+func (c *IdempotencyKeyConfig) variable() string {
+	if c.Variable == "" {
+		return defaultIdempotencyKeyVariable
+	}
+	return c.Variable
+}
+
+// applies checks if idempotency key is injected for request, which has unsafe method by default
+// and has no explicit key header.
+func (c *IdempotencyKeyConfig) applies(config *TConfig, request *Request) bool {
+	return c.appliesMethod(request.Method) && !hasExplicitHeader(config, request, c.header())
+}
+
+// hasExplicitHeader checks if header is specified in request headers, header list or config headers.
+func hasExplicitHeader(config *TConfig, request *Request, header string) bool {
+	for _, headers := range []map[string]string{request.Headers, config.Headers} {
+		for name := range headers {
+			if strings.EqualFold(name, header) {
+				return true
+			}
+		}
+	}
+	for _, field := range request.HeaderList {
+		if strings.EqualFold(field.Name, header) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *IdempotencyKeyConfig) appliesMethod(method HTTPMethod) bool {
+	if len(c.Methods) == 0 {
+		switch method {
+		case httpPOST, httpPUT, httpPATCH, httpDELETE:
+			return true
+		}
+		return false
+	}
+	for _, m := range c.Methods {
+		if strings.EqualFold(m, string(method)) {
+			return true
+		}
+	}
+	return false
+}
+
+// generate renders key template with step variables, random uuid is generated if template is not set.
+func (c *IdempotencyKeyConfig) generate(parser *Parser, stepVariables map[string]interface{}) (string, error) {
+	if c.Template == "" {
+		return uuid.NewString(), nil
+	}
+	key, err := parser.Parse(c.Template, stepVariables)
+	if err != nil {
+		return "", errors.Wrap(err, "generate idempotency key failed")
+	}
+	return convertString(key), nil
+}
+
+// setIdempotencyKey generates idempotency key into step variables.
+func setIdempotencyKey(cfg *IdempotencyKeyConfig, parser *Parser, stepVariables map[string]interface{}) error {
+	key, err := cfg.generate(parser, stepVariables)
+	if err != nil {
+		return err
+	}
+	stepVariables[cfg.variable()] = key
+	return nil
+}
@@ -0,0 +1,88 @@
+package hrp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunCaseWithIdempotencyKey(t *testing.T) {
+	var mutex sync.Mutex
+	var keys, orderKeys []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		key := r.Header.Get("Idempotency-Key")
+		keys = append(keys, key)
+		if r.URL.Path == "/retry" {
+			orderKeys = append(orderKeys, r.Header.Get("X-Idempotency-Key"))
+		}
+		if len(orderKeys) == 1 {
+			_, _ = w.Write([]byte(`{"status": "pending", "key": "` + key + `"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"status": "done", "key": "` + key + `"}`))
+	}))
+	defer ts.Close()
+
+	testcase := &TestCase{
+		Config: NewConfig("idempotency key").
+			SetBaseURL(ts.URL).
+			SetIdempotencyKey(&IdempotencyKeyConfig{}),
+		TestSteps: []IStep{
+			NewStep("create order").POST("/orders").
+				Validate().
+				AssertEqual("body.key", "$idempotency_key", "check key injected"),
+			NewStep("query order").GET("/orders").
+				Validate().
+				AssertEqual("body.key", "", "check key not injected for GET"),
+			NewStep("retry with the same key").POST("/orders").
+				WithHeaders(map[string]string{"Idempotency-Key": "$idempotency_key"}).
+				Validate().
+				AssertEqual("body.key", "$idempotency_key", "check explicit key header"),
+		},
+	}
+	sessionRunner := NewRunner(t).NewSessionRunner(testcase)
+	if !assert.Nil(t, sessionRunner.Start()) {
+		t.Fatal()
+	}
+	assert.Len(t, keys, 3)
+	assert.Len(t, keys[0], 36) // uuid
+	assert.Equal(t, keys[0], keys[2])
+	assert.Equal(t, keys[0], sessionRunner.sessionVariables["idempotency_key"])
+
+	// new key for each re-issued request
+	keys = nil
+	testcase = &TestCase{
+		Config: NewConfig("idempotency key per request").
+			SetBaseURL(ts.URL).
+			SetIdempotencyKey(&IdempotencyKeyConfig{
+				Header:   "X-Idempotency-Key",
+				Template: "order-${gen_random_string(8)}",
+				Scope:    idempotencyKeyPerRequest,
+				Variable: "order_key",
+				Methods:  []string{"get"},
+			}),
+		TestSteps: []IStep{
+			NewStep("poll order").GET("/retry").
+				WaitUntil("body.status", "done", 0.01, 1).
+				Validate().
+				AssertEqual("body.key", "", "check default header not injected"),
+		},
+	}
+	sessionRunner = NewRunner(t).NewSessionRunner(testcase)
+	if !assert.Nil(t, sessionRunner.Start()) {
+		t.Fatal()
+	}
+	request := sessionRunner.GetSummary().Records[0].Data.(*SessionData).ReqResps.Request.(map[string]interface{})
+	orderKey := sessionRunner.sessionVariables["order_key"].(string)
+	assert.Regexp(t, `^order-\w{8}$`, orderKey)
+	assert.Equal(t, orderKey, request["headers"].(map[string]string)["X-Idempotency-Key"])
+	if assert.Len(t, orderKeys, 2) {
+		assert.NotEqual(t, orderKeys[0], orderKeys[1])
+		assert.Equal(t, orderKey, orderKeys[1])
+	}
+}
@@ -0,0 +1,126 @@
+package boomer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ArrivalRateConfig configures open model load generation, which starts iterations at target rate
+// independent of response time, instead of closed model that each user starts next iteration after previous one finished.
+// Iterations are dropped if all virtual users are busy, max virtual users are specified by spawn count.
+type ArrivalRateConfig struct {
+	Rate   float64        // iterations per second, which is the start rate of ramping arrival rate
+	Stages []ArrivalStage // rate is ramped linearly to target of each stage, and kept at the last target after stages
+}
+
+// ArrivalStage ramps arrival rate linearly from previous rate to target in duration.
+type ArrivalStage struct {
+	Duration time.Duration
+	Target   float64 // iterations per second
+}
+
+// ParseArrivalStages parses stages of ramping arrival rate, e.g. 30s:100,1m:500
+// ramps arrival rate to 100/s in 30s, then to 500/s in 1m.
+func ParseArrivalStages(stages string) ([]ArrivalStage, error) {
+	var result []ArrivalStage
+	for _, stage := range strings.Split(stages, ",") {
+		stage = strings.TrimSpace(stage)
+		if stage == "" {
+			continue
+		}
+		parts := strings.Split(stage, ":")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid arrival stage %q, should be like 30s:100", stage)
+		}
+		duration, err := time.ParseDuration(strings.TrimSpace(parts[0]))
+		if err != nil || duration <= 0 {
+			return nil, fmt.Errorf("invalid duration of arrival stage %q", stage)
+		}
+		target, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil || target < 0 {
+			return nil, fmt.Errorf("invalid target rate of arrival stage %q", stage)
+		}
+		result = append(result, ArrivalStage{Duration: duration, Target: target})
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no arrival stage found in %q", stages)
+	}
+	return result, nil
+}
+
+// rateAt returns arrival rate at elapsed time since run started.
+func (c *ArrivalRateConfig) rateAt(elapsed time.Duration) float64 {
+	rate := c.Rate
+	for _, stage := range c.Stages {
+		if elapsed < stage.Duration {
+			return rate + (stage.Target-rate)*float64(elapsed)/float64(stage.Duration)
+		}
+		elapsed -= stage.Duration
+		rate = stage.Target
+	}
+	return rate
+}
+
+// idleArrivalInterval is the interval to check arrival rate again when the rate is zero.
+const idleArrivalInterval = 10 * time.Millisecond
+
+// scheduleArrivals starts iterations at arrival rate until quit, each iteration is run by an idle virtual user,
+// and it is dropped if all of maxUsers virtual users are busy.
+func (r *localRunner) scheduleArrivals(maxUsers int, quit chan bool) {
+	log.Info().
+		Float64("rate", r.arrivalRate.Rate).
+		Int("maxUsers", maxUsers).
+		Msg("Scheduling arrivals")
+
+	// idle virtual users, each keeps functions of virtual user created by tasks
+	users := make(chan map[*Task]func(), maxUsers)
+	for i := 0; i < maxUsers; i++ {
+		users <- make(map[*Task]func())
+	}
+	atomic.StoreInt32(&r.state, stateRunning)
+	r.spawnDoneOnce.Do(func() {
+		close(r.spawnDone)
+	})
+
+	start := time.Now()
+	next := start
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	defer timer.Stop()
+	for {
+		rate := r.arrivalRate.rateAt(time.Since(start))
+		if rate > 0 {
+			next = next.Add(time.Duration(float64(time.Second) / rate))
+		} else {
+			next = time.Now().Add(idleArrivalInterval)
+		}
+		timer.Reset(time.Until(next))
+		select {
+		case <-quit:
+			return
+		case <-timer.C:
+		}
+		if rate <= 0 {
+			continue
+		}
+
+		select {
+		case userFns := <-users:
+			atomic.AddInt32(&r.currentClientsNum, 1)
+			go func() {
+				defer func() {
+					atomic.AddInt32(&r.currentClientsNum, -1)
+					users <- userFns
+				}()
+				r.runTask(userFns)
+			}()
+		default:
+			atomic.AddInt64(&r.droppedIterations, 1)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package boomer
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseArrivalStages(t *testing.T) {
+	stages, err := ParseArrivalStages("30s:100, 1m:500")
+	require.Nil(t, err)
+	assert.Equal(t, []ArrivalStage{
+		{Duration: 30 * time.Second, Target: 100},
+		{Duration: time.Minute, Target: 500},
+	}, stages)
+
+	for _, invalid := range []string{"", "30s", "abc:100", "30s:-1", "0s:10"} {
+		_, err := ParseArrivalStages(invalid)
+		assert.NotNil(t, err, invalid)
+	}
+}
+
+func TestArrivalRateAt(t *testing.T) {
+	cfg := &ArrivalRateConfig{
+		Rate: 10,
+		Stages: []ArrivalStage{
+			{Duration: 10 * time.Second, Target: 110},
+			{Duration: 10 * time.Second, Target: 10},
+		},
+	}
+	assert.Equal(t, 10.0, cfg.rateAt(0))
+	assert.Equal(t, 60.0, cfg.rateAt(5*time.Second))
+	assert.Equal(t, 110.0, cfg.rateAt(10*time.Second))
+	assert.Equal(t, 60.0, cfg.rateAt(15*time.Second))
+	assert.Equal(t, 10.0, cfg.rateAt(time.Minute))
+
+	constant := &ArrivalRateConfig{Rate: 50}
+	assert.Equal(t, 50.0, constant.rateAt(time.Hour))
+}
+
+func TestScheduleArrivals(t *testing.T) {
+	var iterations int64
+	task := &Task{
+		Name: "slow",
+		Fn: func() {
+			atomic.AddInt64(&iterations, 1)
+			// iterations are started at arrival rate regardless of response time
+			time.Sleep(100 * time.Millisecond)
+		},
+	}
+	runner := newLocalRunner(20, 1)
+	runner.arrivalRate = &ArrivalRateConfig{Rate: 50}
+	runner.setTasks([]*Task{task})
+
+	quit := make(chan bool)
+	go runner.scheduleArrivals(runner.spawnCount, quit)
+	time.Sleep(time.Second)
+	close(quit)
+
+	count := atomic.LoadInt64(&iterations)
+	assert.True(t, count >= 40 && count <= 55, "unexpected iterations %d", count)
+	assert.Equal(t, int64(0), atomic.LoadInt64(&runner.droppedIterations))
+}
+
+func TestScheduleArrivalsDropped(t *testing.T) {
+	task := &Task{
+		Name: "slow",
+		Fn: func() {
+			time.Sleep(200 * time.Millisecond)
+		},
+	}
+	runner := newLocalRunner(1, 1)
+	runner.arrivalRate = &ArrivalRateConfig{Rate: 50}
+	runner.setTasks([]*Task{task})
+
+	quit := make(chan bool)
+	go runner.scheduleArrivals(runner.spawnCount, quit)
+	time.Sleep(500 * time.Millisecond)
+	close(quit)
+
+	// the only virtual user is busy
+	assert.True(t, atomic.LoadInt64(&runner.droppedIterations) > 10)
+}
@@ -2,8 +2,10 @@ package boomer
 
 import (
 	"math"
+	"net/http"
 	"os"
 	"os/signal"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -22,6 +24,9 @@ type Boomer struct {
 
 	disableKeepalive   bool
 	disableCompression bool
+
+	maxRPS     int64        // max RPS of rate limiter, 0 if rate limit is disabled
+	controller *http.Server // adjusts load of running load testing if enabled
 }
 
 // NewStandaloneBoomer returns a new Boomer, which can run without master.
@@ -53,9 +58,28 @@ func (b *Boomer) SetRateLimiter(maxRPS int64, requestIncreaseRate string) {
 	}
 
 	if rateLimiter != nil {
-		b.localRunner.rateLimitEnabled = true
-		b.localRunner.rateLimiter = rateLimiter
+		b.localRunner.setRateLimiter(rateLimiter)
+		atomic.StoreInt64(&b.maxRPS, maxRPS)
+	}
+}
+
+// SetSpawnCount adjusts users and spawn rate of running load testing without restarting,
+// spawn rate is not changed if it is not positive.
+func (b *Boomer) SetSpawnCount(spawnCount int, spawnRate float64) error {
+	return b.localRunner.setSpawnCount(spawnCount, spawnRate)
+}
+
+// SetMaxRPS replaces rate limiter with stable rate limiter of max RPS, which takes effect immediately
+// if load testing is running, rate limiter is disabled if maxRPS is not positive.
+func (b *Boomer) SetMaxRPS(maxRPS int64) {
+	log.Warn().Int64("maxRPS", maxRPS).Msg("adjust max RPS")
+	if maxRPS <= 0 {
+		b.localRunner.setRateLimiter(nil)
+		atomic.StoreInt64(&b.maxRPS, 0)
+		return
 	}
+	atomic.StoreInt64(&b.maxRPS, maxRPS)
+	b.localRunner.setRateLimiter(NewStableRateLimiter(maxRPS, time.Second))
 }
 
 // SetDisableKeepAlive disable keep-alive for tcp
@@ -82,6 +106,29 @@ func (b *Boomer) SetLoopCount(loopCount int64) {
 	b.localRunner.loop = &Loop{loopCount: loopCount * int64(b.localRunner.spawnCount)}
 }
 
+// SetArrivalRate starts iterations at arrival rate independent of response time instead of looping users,
+// spawn count is the max number of concurrent virtual users, and rate limiter is not applied.
+func (b *Boomer) SetArrivalRate(cfg *ArrivalRateConfig) {
+	b.localRunner.arrivalRate = cfg
+}
+
+// GetDroppedIterations returns number of iterations dropped since all virtual users are busy in arrival rate mode.
+func (b *Boomer) GetDroppedIterations() int64 {
+	return atomic.LoadInt64(&b.localRunner.droppedIterations)
+}
+
+// SetWarmUp sets duration of warm-up phase from start of run, samples of warm-up phase are reported
+// in outputs but excluded from final summary and performance gates.
+func (b *Boomer) SetWarmUp(duration time.Duration) {
+	b.localRunner.warmUpDuration = duration
+}
+
+// EnableGuard samples CPU, memory, GC pause and file descriptors of load generator during run,
+// warns when the generator is overloaded, and caps users if auto cap is enabled.
+func (b *Boomer) EnableGuard(cfg GuardConfig) {
+	b.localRunner.guard = newLoadGuard(cfg)
+}
+
 // AddOutput accepts outputs which implements the boomer.Output interface.
 func (b *Boomer) AddOutput(o Output) {
 	b.localRunner.addOutput(o)
@@ -126,6 +173,7 @@ func (b *Boomer) Run(tasks ...*Task) {
 
 	b.localRunner.setTasks(tasks)
 	b.localRunner.start()
+	b.stopController()
 }
 
 // RecordTransaction reports a transaction stat.
@@ -138,6 +186,15 @@ func (b *Boomer) RecordTransaction(name string, success bool, elapsedTime int64,
 	}
 }
 
+// RecordScenario reports an iteration of scenario, which is aggregated per scenario.
+func (b *Boomer) RecordScenario(name string, success bool, elapsedTime int64) {
+	b.localRunner.stats.scenarioChan <- &scenario{
+		name:        name,
+		success:     success,
+		elapsedTime: elapsedTime,
+	}
+}
+
 // RecordSuccess reports a success.
 func (b *Boomer) RecordSuccess(requestType, name string, responseTime int64, responseLength int64) {
 	b.localRunner.stats.requestSuccessChan <- &requestSuccess{
@@ -158,15 +215,103 @@ func (b *Boomer) RecordFailure(requestType, name string, responseTime int64, exc
 	}
 }
 
+// RecordURL reports response time of request with normalized url pattern, e.g. GET /users/{id},
+// which is aggregated into percentiles per url pattern.
+func (b *Boomer) RecordURL(pattern string, responseTime int64) {
+	b.localRunner.stats.requestURLChan <- &requestURL{
+		pattern:      pattern,
+		responseTime: responseTime,
+	}
+}
+
+// RecordError reports failure of step, which is aggregated by step, status code and error class
+// for top errors report, sample is called to get request and response pair if the bucket needs more samples.
+func (b *Boomer) RecordError(step string, status int, class string, message string, sample func() string) {
+	e := &requestError{
+		step:    step,
+		status:  status,
+		class:   class,
+		message: message,
+	}
+	if sample != nil && b.localRunner.stats.errorSampler.acquire(errorBucketKey(step, status, class)) {
+		e.sample = sample()
+	}
+	b.localRunner.stats.requestErrorChan <- e
+}
+
+// SetErrorSamples sets max number of request and response pairs sampled for each error bucket, default to 3.
+func (b *Boomer) SetErrorSamples(samples int) {
+	b.localRunner.stats.errorSampler = newErrorSampler(samples)
+}
+
+// EnableErrorReport will write failures aggregated by step, status code and error class
+// with sampled request and response pairs into json file after run.
+func (b *Boomer) EnableErrorReport(path string) {
+	b.localRunner.errorReportPath = path
+}
+
+// EnableResultsExport will write raw results of sampled requests into rolling csv or parquet files during run.
+func (b *Boomer) EnableResultsExport(cfg ResultsConfig) error {
+	w, err := newResultsWriter(cfg)
+	if err != nil {
+		return err
+	}
+	b.localRunner.results = w
+	return nil
+}
+
+// RecordResult reports raw result of request, which is exported if results export is enabled.
+func (b *Boomer) RecordResult(name string, status int, responseTime int64, responseLength int64) {
+	if b.localRunner.results == nil {
+		return
+	}
+	b.localRunner.results.record(&resultSample{
+		timestamp: time.Now().UnixNano() / int64(time.Millisecond),
+		name:      name,
+		status:    status,
+		latency:   responseTime,
+		bytes:     responseLength,
+	})
+}
+
+// QueueDepths returns number of samples buffered in stats channels and results buffer,
+// which keep growing if stats collector can not keep up with load generation.
+func (b *Boomer) QueueDepths() map[string]int {
+	stats := b.localRunner.stats
+	depths := map[string]int{
+		"transaction":     len(stats.transactionChan),
+		"request_success": len(stats.requestSuccessChan),
+		"request_failure": len(stats.requestFailureChan),
+		"request_url":     len(stats.requestURLChan),
+		"request_error":   len(stats.requestErrorChan),
+		"scenario":        len(stats.scenarioChan),
+	}
+	if results := b.localRunner.results; results != nil {
+		depths["results"] = len(results.samples)
+	}
+	return depths
+}
+
+// EnableStatsCSV will write percentiles of response time per step, transaction and url pattern
+// into csv file after run.
+func (b *Boomer) EnableStatsCSV(path string) {
+	b.localRunner.statsCSVPath = path
+}
+
 // Quit will send a quit message to the master.
 func (b *Boomer) Quit() {
 	b.localRunner.stop()
 }
 
+// GetTotalStats returns accumulated stats of all requests, which should be called after Run returns.
+func (b *Boomer) GetTotalStats() *TotalStats {
+	return newTotalStats(b.localRunner.stats.total)
+}
+
 func (b *Boomer) GetSpawnDoneChan() chan struct{} {
 	return b.localRunner.spawnDone
 }
 
 func (b *Boomer) GetSpawnCount() int {
-	return b.localRunner.spawnCount
+	return b.localRunner.getSpawnCount()
 }
@@ -144,3 +144,52 @@ func TestCreateRatelimiter(t *testing.T) {
 		}
 	}
 }
+
+func TestWarmUp(t *testing.T) {
+	b := NewStandaloneBoomer(1, 100)
+	b.SetWarmUp(300 * time.Millisecond)
+
+	start := time.Now()
+	task := &Task{
+		Name: "warmUp",
+		Fn: func() {
+			// slow requests during warm-up are excluded from final stats
+			var responseTime int64 = 10
+			if time.Since(start) < 200*time.Millisecond {
+				responseTime = 1000
+			}
+			b.RecordSuccess("http", "warmUp", responseTime, 0)
+			time.Sleep(10 * time.Millisecond)
+		},
+	}
+	done := make(chan struct{})
+	go func() {
+		b.Run(task)
+		close(done)
+	}()
+	time.Sleep(time.Second)
+	b.Quit()
+	<-done
+
+	stats := b.GetTotalStats()
+	if stats.NumRequests == 0 {
+		t.Fatal("requests after warm-up should be recorded")
+	}
+	if stats.MaxResponseTime != 10 {
+		t.Errorf("requests of warm-up should be excluded, got max response time %d", stats.MaxResponseTime)
+	}
+}
+
+func TestQueueDepths(t *testing.T) {
+	b := NewStandaloneBoomer(1, 1)
+	b.RecordSuccess("http", "get", 10, 100)
+	b.RecordSuccess("http", "get", 10, 100)
+
+	depths := b.QueueDepths()
+	if depths["request_success"] != 2 {
+		t.Error("request_success queue depth should be 2")
+	}
+	if _, ok := depths["results"]; ok {
+		t.Error("results queue should not be reported if results export is disabled")
+	}
+}
@@ -0,0 +1,156 @@
+package boomer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/httprunner/httprunner/hrp/internal/json"
+)
+
+const controlPath = "/ctl"
+
+// ControlRequest adjusts load of running load testing, nil fields are not changed.
+type ControlRequest struct {
+	Users     *int     `json:"users,omitempty"`
+	SpawnRate *float64 `json:"spawn_rate,omitempty"`
+	MaxRPS    *int64   `json:"max_rps,omitempty"` // rate limit is disabled if not positive
+}
+
+// ControlState is current load of load testing returned by controller.
+type ControlState struct {
+	State      string  `json:"state"`
+	Users      int32   `json:"users"`       // current users
+	SpawnCount int     `json:"spawn_count"` // target users
+	SpawnRate  float64 `json:"spawn_rate"`
+	MaxRPS     int64   `json:"max_rps"`
+}
+
+// EnableController starts http server listening on addr, which adjusts users and max RPS of
+// running load testing by POST /ctl, and returns current load by GET /ctl.
+// The server is shut down after load testing stopped.
+func (b *Boomer) EnableController(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return errors.Wrap(err, "listen controller failed")
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(controlPath, b.handleControl)
+	b.controller = &http.Server{Handler: mux}
+	log.Warn().Str("addr", listener.Addr().String()).Msg("start load controller")
+	go func() {
+		if err := b.controller.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Error().Err(err).Msg("load controller stopped")
+		}
+	}()
+	return nil
+}
+
+func (b *Boomer) stopController() {
+	if b.controller == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := b.controller.Shutdown(ctx); err != nil {
+		log.Error().Err(err).Msg("shutdown load controller failed")
+	}
+}
+
+func (b *Boomer) handleControl(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var ctl ControlRequest
+		if err := json.NewDecoder(req.Body).Decode(&ctl); err != nil {
+			http.Error(w, fmt.Sprintf("invalid control request: %v", err), http.StatusBadRequest)
+			return
+		}
+		if err := b.control(&ctl); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(b.controlState())
+}
+
+func (b *Boomer) control(ctl *ControlRequest) error {
+	if ctl.Users != nil {
+		var spawnRate float64
+		if ctl.SpawnRate != nil {
+			spawnRate = *ctl.SpawnRate
+		}
+		if err := b.SetSpawnCount(*ctl.Users, spawnRate); err != nil {
+			return err
+		}
+	} else if ctl.SpawnRate != nil {
+		return errors.New("spawn rate should be specified with users")
+	}
+	if ctl.MaxRPS != nil {
+		b.SetMaxRPS(*ctl.MaxRPS)
+	}
+	return nil
+}
+
+func (b *Boomer) controlState() *ControlState {
+	r := b.localRunner
+	return &ControlState{
+		State:      stateName(atomic.LoadInt32(&r.state)),
+		Users:      atomic.LoadInt32(&r.currentClientsNum),
+		SpawnCount: r.getSpawnCount(),
+		SpawnRate:  r.getSpawnRate(),
+		MaxRPS:     atomic.LoadInt64(&b.maxRPS),
+	}
+}
+
+// Control sends control request to controller of running load testing, e.g. localhost:8089,
+// and returns current load after adjusted, load is not changed if ctl is nil.
+func Control(addr string, ctl *ControlRequest) (*ControlState, error) {
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	url := strings.TrimSuffix(addr, "/") + controlPath
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	var resp *http.Response
+	var err error
+	if ctl == nil {
+		resp, err = client.Get(url)
+	} else {
+		data, marshalErr := json.Marshal(ctl)
+		if marshalErr != nil {
+			return nil, marshalErr
+		}
+		resp, err = client.Post(url, "application/json", bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "request load controller failed")
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("control load failed: %s", strings.TrimSpace(string(body)))
+	}
+	state := &ControlState{}
+	if err := json.Unmarshal(body, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
@@ -0,0 +1,104 @@
+package boomer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestControl(t *testing.T) {
+	b := NewStandaloneBoomer(2, 100)
+	ts := httptest.NewServer(http.HandlerFunc(b.handleControl))
+	defer ts.Close()
+
+	users := 5
+	_, err := Control(ts.URL, &ControlRequest{Users: &users})
+	assert.EqualError(t, err, "control load failed: load testing is not running")
+
+	done := make(chan struct{})
+	go func() {
+		b.Run(&Task{
+			Name: "sleep",
+			Fn: func() {
+				time.Sleep(10 * time.Millisecond)
+			},
+		})
+		close(done)
+	}()
+	waitUsers := func(expected int32) {
+		require.Eventually(t, func() bool {
+			return atomic.LoadInt32(&b.localRunner.currentClientsNum) == expected
+		}, 3*time.Second, 10*time.Millisecond)
+	}
+	waitUsers(2)
+
+	// increase users
+	spawnRate := 1000.0
+	state, err := Control(ts.URL, &ControlRequest{Users: &users, SpawnRate: &spawnRate})
+	require.Nil(t, err)
+	assert.Equal(t, 5, state.SpawnCount)
+	assert.Equal(t, 1000.0, state.SpawnRate)
+	waitUsers(5)
+
+	// decrease users and limit RPS
+	users = 1
+	maxRPS := int64(10)
+	state, err = Control(ts.URL, &ControlRequest{Users: &users, MaxRPS: &maxRPS})
+	require.Nil(t, err)
+	assert.Equal(t, int32(1), state.Users)
+	assert.Equal(t, int64(10), state.MaxRPS)
+	assert.NotNil(t, b.localRunner.getRateLimiter())
+
+	// disable rate limit
+	maxRPS = 0
+	_, err = Control(ts.URL, &ControlRequest{MaxRPS: &maxRPS})
+	require.Nil(t, err)
+	assert.Nil(t, b.localRunner.getRateLimiter())
+
+	state, err = Control(ts.URL, nil)
+	require.Nil(t, err)
+	assert.Equal(t, "running", state.State)
+	assert.Equal(t, 1, state.SpawnCount)
+
+	_, err = Control(ts.URL, &ControlRequest{SpawnRate: &spawnRate})
+	assert.NotNil(t, err)
+
+	b.Quit()
+	<-done
+}
+
+func TestSetSpawnCountWithLoop(t *testing.T) {
+	runner := newLocalRunner(2, 2)
+	runner.loop = &Loop{loopCount: 4}
+	assert.NotNil(t, runner.setSpawnCount(3, 1))
+}
+
+func TestSetSpawnCountDuringSpawning(t *testing.T) {
+	// spawn slowly, thus users are decreased before initial spawning completed
+	b := NewStandaloneBoomer(10, 10)
+	go b.Run(&Task{
+		Name: "sleep",
+		Fn: func() {
+			time.Sleep(10 * time.Millisecond)
+		},
+	})
+	defer b.Quit()
+
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&b.localRunner.currentClientsNum) >= 2
+	}, 3*time.Second, 10*time.Millisecond)
+	require.Nil(t, b.SetSpawnCount(1, 0))
+
+	select {
+	case <-b.GetSpawnDoneChan():
+	case <-time.After(3 * time.Second):
+		t.Fatal("spawn done should be closed after spawning canceled")
+	}
+	assert.Equal(t, int32(stateRunning), atomic.LoadInt32(&b.localRunner.state))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&b.localRunner.currentClientsNum))
+}
@@ -0,0 +1,158 @@
+package boomer
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/olekukonko/tablewriter"
+
+	"github.com/httprunner/httprunner/hrp/internal/json"
+)
+
+const (
+	defaultErrorSamples = 3
+	topErrorsCount      = 10
+	maxErrorMessageLen  = 80
+)
+
+type requestError struct {
+	step    string
+	status  int
+	class   string
+	message string
+	sample  string
+}
+
+// errorBucket aggregates failures of load testing by step, status code and error class,
+// which keeps sampled request and response pairs for troubleshooting.
+type errorBucket struct {
+	Step    string   `json:"step"`
+	Status  int      `json:"status"` // status code of response, 0 if no response is received
+	Class   string   `json:"class"`  // error class, e.g. transport_error, validation_failure
+	Count   int64    `json:"count"`
+	Message string   `json:"message"` // error message of the first failure
+	Samples []string `json:"samples,omitempty"`
+}
+
+func errorBucketKey(step string, status int, class string) string {
+	return step + "|" + strconv.Itoa(status) + "|" + class
+}
+
+// errorSampler limits sampled failures of each bucket, which is called by workers before sampling
+// since serializing request and response pairs is expensive.
+type errorSampler struct {
+	mutex   sync.Mutex
+	limit   int
+	sampled map[string]int
+}
+
+func newErrorSampler(limit int) *errorSampler {
+	return &errorSampler{
+		limit:   limit,
+		sampled: make(map[string]int),
+	}
+}
+
+// acquire returns true if failure of bucket should be sampled.
+func (s *errorSampler) acquire(key string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.sampled[key] >= s.limit {
+		return false
+	}
+	s.sampled[key]++
+	return true
+}
+
+func (s *errorSampler) reset() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.sampled = make(map[string]int)
+}
+
+func (s *requestStats) logRequestError(e *requestError) {
+	key := errorBucketKey(e.step, e.status, e.class)
+	bucket, ok := s.errorBuckets[key]
+	if !ok {
+		bucket = &errorBucket{
+			Step:    e.step,
+			Status:  e.status,
+			Class:   e.class,
+			Message: e.message,
+		}
+		s.errorBuckets[key] = bucket
+	}
+	bucket.Count++
+	if e.sample != "" {
+		bucket.Samples = append(bucket.Samples, e.sample)
+	}
+}
+
+// sortedErrorBuckets returns error buckets sorted by count in descending order.
+func (s *requestStats) sortedErrorBuckets() []*errorBucket {
+	buckets := make([]*errorBucket, 0, len(s.errorBuckets))
+	for _, bucket := range s.errorBuckets {
+		buckets = append(buckets, bucket)
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		if buckets[i].Count != buckets[j].Count {
+			return buckets[i].Count > buckets[j].Count
+		}
+		return errorBucketKey(buckets[i].Step, buckets[i].Status, buckets[i].Class) <
+			errorBucketKey(buckets[j].Step, buckets[j].Status, buckets[j].Class)
+	})
+	return buckets
+}
+
+var topErrorsHeader = []string{"Step", "Status", "Error class", "# occurrences", "Error"}
+
+func topErrorsRows(buckets []*errorBucket) [][]string {
+	if len(buckets) > topErrorsCount {
+		buckets = buckets[:topErrorsCount]
+	}
+	rows := make([][]string, 0, len(buckets))
+	for _, bucket := range buckets {
+		status := "-"
+		if bucket.Status > 0 {
+			status = strconv.Itoa(bucket.Status)
+		}
+		// truncate by characters, in case multi-byte characters are cut in half
+		message := []rune(strings.Join(strings.Fields(bucket.Message), " "))
+		if len(message) > maxErrorMessageLen {
+			message = append(message[:maxErrorMessageLen], []rune("...")...)
+		}
+		rows = append(rows, []string{
+			bucket.Step,
+			status,
+			bucket.Class,
+			strconv.FormatInt(bucket.Count, 10),
+			string(message),
+		})
+	}
+	return rows
+}
+
+// writeErrorReport writes all error buckets with sampled request and response pairs into json file.
+func writeErrorReport(path string, buckets []*errorBucket) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "    ")
+	if err := encoder.Encode(buckets); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+func printTopErrors(buckets []*errorBucket) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(topErrorsHeader)
+	table.AppendBulk(topErrorsRows(buckets))
+	table.Render()
+}
@@ -0,0 +1,62 @@
+package boomer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/httprunner/httprunner/hrp/internal/json"
+)
+
+func TestRecordError(t *testing.T) {
+	b := NewStandaloneBoomer(1, 1)
+	b.SetErrorSamples(2)
+	stats := b.localRunner.stats
+
+	var sampled int
+	sample := func() string {
+		sampled++
+		return `{"request": {}, "response": {}}`
+	}
+	for i := 0; i < 5; i++ {
+		b.RecordError("get user", 500, "validation_failure", "assert status_code failed", sample)
+		stats.logRequestError(<-stats.requestErrorChan)
+	}
+	b.RecordError("get user", 0, "timeout", "request timed out", sample)
+	stats.logRequestError(<-stats.requestErrorChan)
+	b.RecordError("list users", 404, "validation_failure", strings.Repeat("x", 100), nil)
+	stats.logRequestError(<-stats.requestErrorChan)
+
+	// only a few failures of each bucket are sampled
+	assert.Equal(t, 3, sampled)
+	buckets := stats.sortedErrorBuckets()
+	require.Len(t, buckets, 3)
+	assert.Equal(t, int64(5), buckets[0].Count)
+	assert.Len(t, buckets[0].Samples, 2)
+
+	rows := topErrorsRows(buckets)
+	assert.Equal(t, []string{"get user", "500", "validation_failure", "5", "assert status_code failed"}, rows[0])
+	assert.Equal(t, []string{"get user", "-", "timeout", "1", "request timed out"}, rows[1])
+	assert.Equal(t, strings.Repeat("x", maxErrorMessageLen)+"...", rows[2][4])
+
+	// message of multi-byte characters is truncated by characters
+	rows = topErrorsRows([]*errorBucket{{Step: "get user", Message: strings.Repeat("错误", 50)}})
+	assert.Equal(t, strings.Repeat("错误", maxErrorMessageLen/2)+"...", rows[0][4])
+
+	path := filepath.Join(t.TempDir(), "errors.json")
+	require.Nil(t, writeErrorReport(path, buckets))
+	data, err := os.ReadFile(path)
+	require.Nil(t, err)
+	var report []*errorBucket
+	require.Nil(t, json.Unmarshal(data, &report))
+	assert.Equal(t, buckets[0].Samples, report[0].Samples)
+
+	// samples are reset with stats
+	stats.clearAll()
+	assert.Empty(t, stats.sortedErrorBuckets())
+	assert.True(t, stats.errorSampler.acquire(errorBucketKey("get user", 500, "validation_failure")))
+}
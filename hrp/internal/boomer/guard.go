@@ -0,0 +1,258 @@
+package boomer
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultGuardInterval   = 5 * time.Second
+	defaultGuardMaxCPU     = 90
+	defaultGuardMaxGCPause = 100 * time.Millisecond
+	defaultGuardMaxFDUsage = 0.9
+	// generator is regarded as overloaded after consecutive overloaded samples
+	guardOverloadSamples = 3
+	// users are decreased by ratio when generator is overloaded after spawning completed
+	guardCapRatio = 0.9
+)
+
+// GuardConfig configures guard of load generator, which samples CPU, memory, GC pause and file descriptors
+// of the generator itself, and warns when the generator instead of the target is the bottleneck.
+type GuardConfig struct {
+	Interval   time.Duration // sampling interval, default to 5s
+	MaxCPU     float64       // max CPU usage in percent of all cores, default to 90
+	MaxMemory  uint64        // max memory obtained from OS in bytes, disabled if 0
+	MaxGCPause time.Duration // max total GC pause in one interval, default to 100ms
+	MaxFDUsage float64       // max ratio of open file descriptors to limit, default to 0.9
+	AutoCap    bool          // cap users when generator is overloaded
+}
+
+// guardSample is resource usage of load generator in one interval.
+type guardSample struct {
+	cpu        float64 // CPU usage in percent of all cores, -1 if not supported
+	memory     uint64  // memory obtained from OS in bytes
+	gcPause    time.Duration
+	fds        int // open file descriptors, -1 if not supported
+	fdLimit    int
+	goroutines int
+}
+
+// loadGuard samples resource usage of load generator periodically.
+type loadGuard struct {
+	cfg GuardConfig
+
+	lastCPUTime time.Duration
+	lastGCPause uint64
+	lastTime    time.Time
+	overloaded  int // consecutive overloaded samples
+
+	mutex     sync.Mutex
+	peak      guardSample
+	overloads int // times that generator is regarded as overloaded
+	cappedTo  int // users capped by guard, 0 if not capped
+}
+
+func newLoadGuard(cfg GuardConfig) *loadGuard {
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultGuardInterval
+	}
+	if cfg.MaxCPU <= 0 {
+		cfg.MaxCPU = defaultGuardMaxCPU
+	}
+	if cfg.MaxGCPause <= 0 {
+		cfg.MaxGCPause = defaultGuardMaxGCPause
+	}
+	if cfg.MaxFDUsage <= 0 || cfg.MaxFDUsage > 1 {
+		cfg.MaxFDUsage = defaultGuardMaxFDUsage
+	}
+	return &loadGuard{cfg: cfg}
+}
+
+// reset records baseline of cumulative CPU time and GC pause before sampling.
+func (g *loadGuard) reset() {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	g.lastCPUTime = processCPUTime()
+	g.lastGCPause = memStats.PauseTotalNs
+	g.lastTime = time.Now()
+	g.overloaded = 0
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.peak = guardSample{cpu: -1, fds: -1}
+	g.overloads = 0
+	g.cappedTo = 0
+}
+
+// sample returns resource usage since last sample.
+func (g *loadGuard) sample() *guardSample {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	now := time.Now()
+	cpuTime := processCPUTime()
+
+	s := &guardSample{
+		cpu:        -1,
+		memory:     memStats.Sys,
+		gcPause:    time.Duration(memStats.PauseTotalNs - g.lastGCPause),
+		goroutines: runtime.NumGoroutine(),
+	}
+	if elapsed := now.Sub(g.lastTime); cpuTime >= 0 && elapsed > 0 {
+		s.cpu = float64(cpuTime-g.lastCPUTime) / float64(elapsed) / float64(runtime.NumCPU()) * 100
+	}
+	s.fds, s.fdLimit = openFileDescriptors()
+
+	g.lastCPUTime = cpuTime
+	g.lastGCPause = memStats.PauseTotalNs
+	g.lastTime = now
+	g.updatePeak(s)
+	return s
+}
+
+func (g *loadGuard) updatePeak(s *guardSample) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if s.cpu > g.peak.cpu {
+		g.peak.cpu = s.cpu
+	}
+	if s.memory > g.peak.memory {
+		g.peak.memory = s.memory
+	}
+	if s.gcPause > g.peak.gcPause {
+		g.peak.gcPause = s.gcPause
+	}
+	if s.fds > g.peak.fds {
+		g.peak.fds = s.fds
+		g.peak.fdLimit = s.fdLimit
+	}
+	if s.goroutines > g.peak.goroutines {
+		g.peak.goroutines = s.goroutines
+	}
+}
+
+// check returns reasons why load generator is overloaded, empty if any resource is not exhausted.
+func (g *loadGuard) check(s *guardSample) []string {
+	var reasons []string
+	if s.cpu >= g.cfg.MaxCPU {
+		reasons = append(reasons, fmt.Sprintf("cpu %.1f%% >= %.1f%%", s.cpu, g.cfg.MaxCPU))
+	}
+	if g.cfg.MaxMemory > 0 && s.memory >= g.cfg.MaxMemory {
+		reasons = append(reasons, fmt.Sprintf("memory %s >= %s", formatBytes(s.memory), formatBytes(g.cfg.MaxMemory)))
+	}
+	if s.gcPause >= g.cfg.MaxGCPause {
+		reasons = append(reasons, fmt.Sprintf("gc pause %v >= %v", s.gcPause, g.cfg.MaxGCPause))
+	}
+	if s.fds >= 0 && s.fdLimit > 0 && float64(s.fds) >= float64(s.fdLimit)*g.cfg.MaxFDUsage {
+		reasons = append(reasons, fmt.Sprintf("file descriptors %d/%d", s.fds, s.fdLimit))
+	}
+	return reasons
+}
+
+// cappedUsers returns users decreased by ratio for overloaded generator, thus the generator recovers from overloading.
+func cappedUsers(current int) int {
+	capped := int(float64(current) * guardCapRatio)
+	if capped >= current {
+		capped = current - 1
+	}
+	if capped < 1 {
+		capped = 1
+	}
+	return capped
+}
+
+// runGuard samples resource usage of load generator until quit,
+// warns and caps users if the generator is overloaded in consecutive samples.
+func (r *localRunner) runGuard(quit chan bool) {
+	g := r.guard
+	ticker := time.NewTicker(g.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-quit:
+			return
+		case <-ticker.C:
+		}
+
+		s := g.sample()
+		reasons := g.check(s)
+		if len(reasons) == 0 {
+			g.overloaded = 0
+			continue
+		}
+		g.overloaded++
+		if g.overloaded < guardOverloadSamples {
+			continue
+		}
+		g.overloaded = 0
+
+		g.mutex.Lock()
+		g.overloads++
+		g.mutex.Unlock()
+		log.Warn().Strs("reasons", reasons).Int("goroutines", s.goroutines).
+			Msg("load generator is overloaded, results may be limited by the generator instead of the target")
+
+		if !g.cfg.AutoCap || r.arrivalRate != nil || r.loop != nil {
+			continue
+		}
+		// users are not capped during spawning, otherwise spawning is canceled before target users reached
+		if atomic.LoadInt32(&r.state) != stateRunning {
+			continue
+		}
+		current := int(atomic.LoadInt32(&r.currentClientsNum))
+		target := r.getSpawnCount()
+		capped := cappedUsers(current)
+		if capped >= target {
+			continue
+		}
+		log.Warn().Int("users", capped).Int("target", target).Msg("cap users of overloaded load generator")
+		if err := r.setSpawnCount(capped, 0); err != nil {
+			log.Error().Err(err).Msg("cap users failed")
+			continue
+		}
+		g.mutex.Lock()
+		g.cappedTo = capped
+		g.mutex.Unlock()
+	}
+}
+
+// summary returns peak resource usage of load generator for final summary.
+func (g *loadGuard) summary() string {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	items := []string{}
+	if g.peak.cpu >= 0 {
+		items = append(items, fmt.Sprintf("CPU %.1f%%", g.peak.cpu))
+	}
+	items = append(items,
+		fmt.Sprintf("Memory %s", formatBytes(g.peak.memory)),
+		fmt.Sprintf("GC pause %v/interval", g.peak.gcPause),
+		fmt.Sprintf("Goroutines %d", g.peak.goroutines),
+	)
+	if g.peak.fds >= 0 {
+		items = append(items, fmt.Sprintf("File descriptors %d/%d", g.peak.fds, g.peak.fdLimit))
+	}
+	line := fmt.Sprintf("Generator peak usage: %s, Overloaded: %d times", strings.Join(items, ", "), g.overloads)
+	if g.cappedTo > 0 {
+		line += fmt.Sprintf(", Users capped to %d", g.cappedTo)
+	}
+	return line
+}
+
+func formatBytes(b uint64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%dB", b)
+	}
+	div, exp := uint64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
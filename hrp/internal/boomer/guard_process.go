@@ -0,0 +1,33 @@
+// +build !windows
+
+package boomer
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// processCPUTime returns cumulative user and system CPU time of current process.
+func processCPUTime() time.Duration {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		return -1
+	}
+	return time.Duration(usage.Utime.Nano() + usage.Stime.Nano())
+}
+
+// openFileDescriptors returns open file descriptors and limit of current process.
+func openFileDescriptors() (int, int) {
+	var rLimit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rLimit); err != nil {
+		return -1, 0
+	}
+	for _, dir := range []string{"/proc/self/fd", "/dev/fd"} {
+		entries, err := os.ReadDir(dir)
+		if err == nil {
+			return len(entries), int(rLimit.Cur)
+		}
+	}
+	return -1, int(rLimit.Cur)
+}
@@ -0,0 +1,15 @@
+// +build windows
+
+package boomer
+
+import "time"
+
+// processCPUTime is not supported on windows.
+func processCPUTime() time.Duration {
+	return -1
+}
+
+// openFileDescriptors is not supported on windows.
+func openFileDescriptors() (int, int) {
+	return -1, 0
+}
@@ -0,0 +1,89 @@
+package boomer
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadGuardCheck(t *testing.T) {
+	g := newLoadGuard(GuardConfig{MaxMemory: 1 << 30})
+	assert.Equal(t, defaultGuardInterval, g.cfg.Interval)
+
+	reasons := g.check(&guardSample{cpu: 50, memory: 1 << 20, gcPause: time.Millisecond, fds: 10, fdLimit: 1024})
+	assert.Empty(t, reasons)
+
+	reasons = g.check(&guardSample{cpu: 95, memory: 2 << 30, gcPause: 200 * time.Millisecond, fds: 1000, fdLimit: 1024})
+	assert.Equal(t, []string{
+		"cpu 95.0% >= 90.0%",
+		"memory 2.0GiB >= 1.0GiB",
+		"gc pause 200ms >= 100ms",
+		"file descriptors 1000/1024",
+	}, reasons)
+
+	// unsupported resources are ignored
+	reasons = g.check(&guardSample{cpu: -1, fds: -1})
+	assert.Empty(t, reasons)
+}
+
+func TestLoadGuardSample(t *testing.T) {
+	g := newLoadGuard(GuardConfig{})
+	g.reset()
+	time.Sleep(10 * time.Millisecond)
+	s := g.sample()
+	assert.Greater(t, s.memory, uint64(0))
+	assert.Greater(t, s.goroutines, 0)
+	if runtime.GOOS == "linux" {
+		assert.GreaterOrEqual(t, s.cpu, float64(0))
+		assert.Greater(t, s.fds, 0)
+		assert.Greater(t, s.fdLimit, 0)
+	}
+	assert.Contains(t, g.summary(), "Overloaded: 0 times")
+}
+
+func TestCappedUsers(t *testing.T) {
+	assert.Equal(t, 90, cappedUsers(100))
+	assert.Equal(t, 4, cappedUsers(5))
+	assert.Equal(t, 1, cappedUsers(1))
+	assert.Equal(t, 1, cappedUsers(0))
+}
+
+func TestLoadGuardAutoCap(t *testing.T) {
+	b := NewStandaloneBoomer(10, 100)
+	// regard generator as overloaded by any cpu usage
+	b.EnableGuard(GuardConfig{Interval: 20 * time.Millisecond, MaxCPU: 0.000001, AutoCap: true})
+	go b.Run(&Task{
+		Name: "busy",
+		Fn: func() {
+			time.Sleep(time.Millisecond)
+		},
+	})
+	defer b.Quit()
+
+	assert.Eventually(t, func() bool {
+		return b.GetSpawnCount() < 10
+	}, 3*time.Second, 10*time.Millisecond)
+}
+
+func TestLoadGuardAutoCapDuringSpawning(t *testing.T) {
+	// generator is overloaded several times before spawning completed
+	b := NewStandaloneBoomer(5, 10)
+	b.EnableGuard(GuardConfig{Interval: 20 * time.Millisecond, MaxCPU: 0.000001, AutoCap: true})
+	go b.Run(&Task{
+		Name: "busy",
+		Fn: func() {
+			time.Sleep(time.Millisecond)
+		},
+	})
+	defer b.Quit()
+
+	select {
+	case <-b.GetSpawnDoneChan():
+	case <-time.After(5 * time.Second):
+		t.Fatal("spawn done should be closed when guard is triggered during spawning")
+	}
+	// users are not capped before spawning completed
+	assert.Equal(t, 5, b.GetSpawnCount())
+}
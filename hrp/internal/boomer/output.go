@@ -2,6 +2,7 @@ package boomer
 
 import (
 	"fmt"
+	"math"
 	"os"
 	"sort"
 	"strconv"
@@ -66,6 +67,33 @@ func getMedianResponseTime(numRequests int64, responseTimes map[int64]int64) int
 	return medianResponseTime
 }
 
+// getPercentileResponseTime returns response time at percent (0-100) of sorted response times,
+// which is the smallest response time that covers at least percent of requests.
+func getPercentileResponseTime(numRequests int64, responseTimes map[int64]int64, percent float64) int64 {
+	if len(responseTimes) == 0 || numRequests == 0 {
+		return 0
+	}
+	var sortedKeys []int64
+	for k := range responseTimes {
+		sortedKeys = append(sortedKeys, k)
+	}
+	sort.Slice(sortedKeys, func(i, j int) bool {
+		return sortedKeys[i] < sortedKeys[j]
+	})
+	rank := int64(math.Ceil(float64(numRequests) * percent / 100))
+	if rank < 1 {
+		rank = 1
+	}
+	var count int64
+	for _, k := range sortedKeys {
+		count += responseTimes[k]
+		if count >= rank {
+			return k
+		}
+	}
+	return sortedKeys[len(sortedKeys)-1]
+}
+
 func getAvgResponseTime(numRequests int64, totalResponseTime int64) (avgResponseTime float64) {
 	avgResponseTime = float64(0)
 	if numRequests != 0 {
@@ -117,19 +145,7 @@ func (o *ConsoleOutput) OnEvent(data map[string]interface{}) {
 		return
 	}
 
-	var state string
-	switch output.State {
-	case stateInit:
-		state = "initializing"
-	case stateSpawning:
-		state = "spawning"
-	case stateRunning:
-		state = "running"
-	case stateQuitting:
-		state = "quitting"
-	case stateStopped:
-		state = "stopped"
-	}
+	state := stateName(output.State)
 
 	currentTime := time.Now()
 	println(fmt.Sprintf("Current time: %s, Users: %d, State: %s, Total RPS: %.1f, Total Average Response Time: %.1fms, Total Fail Ratio: %.1f%%",
@@ -102,3 +102,35 @@ func TestConsoleOutput(t *testing.T) {
 
 	o.OnStop()
 }
+
+func TestGetPercentileResponseTime(t *testing.T) {
+	numRequests := int64(100)
+	responseTimes := map[int64]int64{
+		10:  50,
+		20:  40,
+		150: 9,
+		900: 1,
+	}
+	testData := []struct {
+		percent  float64
+		expected int64
+	}{
+		{0, 10},
+		{50, 10},
+		{51, 20},
+		{90, 20},
+		{95, 150},
+		{99, 150},
+		{99.9, 900},
+		{100, 900},
+	}
+	for _, data := range testData {
+		if actual := getPercentileResponseTime(numRequests, responseTimes, data.percent); actual != data.expected {
+			t.Errorf("p%v response time should be %d, got %d", data.percent, data.expected, actual)
+		}
+	}
+
+	if getPercentileResponseTime(0, map[int64]int64{}, 95) != 0 {
+		t.Error("percentile response time should be 0 without requests")
+	}
+}
@@ -0,0 +1,66 @@
+package boomer
+
+import (
+	"github.com/pkg/errors"
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/source"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// parquetRowGroupSize is max bytes of row group buffered in memory, samples of flushes are written into
+// the same row group until it is full, rather than one row group for each flush.
+const parquetRowGroupSize = 16 * 1024 * 1024
+
+// parquetResult is schema of raw results in parquet file.
+type parquetResult struct {
+	Timestamp int64  `parquet:"name=timestamp, type=INT64, convertedtype=TIMESTAMP_MILLIS"`
+	Name      string `parquet:"name=name, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Status    int32  `parquet:"name=status, type=INT32"`
+	Latency   int64  `parquet:"name=latency_ms, type=INT64"`
+	Bytes     int64  `parquet:"name=bytes, type=INT64"`
+}
+
+// parquetResultsWriter writes samples into parquet file, compressed with snappy.
+type parquetResultsWriter struct {
+	file   source.ParquetFile
+	writer *writer.ParquetWriter
+}
+
+func newParquetWriter(path string) (*parquetResultsWriter, error) {
+	file, err := local.NewLocalFileWriter(path)
+	if err != nil {
+		return nil, err
+	}
+	pw, err := writer.NewParquetWriter(file, new(parquetResult), 1)
+	if err != nil {
+		file.Close()
+		return nil, errors.Wrap(err, "create parquet writer failed")
+	}
+	pw.RowGroupSize = parquetRowGroupSize
+	return &parquetResultsWriter{file: file, writer: pw}, nil
+}
+
+func (w *parquetResultsWriter) write(samples []*resultSample) error {
+	for _, s := range samples {
+		err := w.writer.Write(parquetResult{
+			Timestamp: s.timestamp,
+			Name:      s.name,
+			Status:    int32(s.status),
+			Latency:   s.latency,
+			Bytes:     s.bytes,
+		})
+		if err != nil {
+			return errors.Wrap(err, "write parquet row failed")
+		}
+	}
+	return nil
+}
+
+// close flushes buffered row group and writes file metadata as footer.
+func (w *parquetResultsWriter) close() error {
+	if err := w.writer.WriteStop(); err != nil {
+		w.file.Close()
+		return errors.Wrap(err, "write parquet footer failed")
+	}
+	return w.file.Close()
+}
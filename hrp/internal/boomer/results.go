@@ -0,0 +1,253 @@
+package boomer
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultResultsMaxRows    = 1000000
+	defaultResultsBufferSize = 10000
+	resultsFlushRows         = 10000
+	resultsFlushInterval     = time.Second
+)
+
+// ResultsConfig configures export of raw results of sampled requests in load testing,
+// which are written into rolling csv or parquet files for offline analysis, e.g. in pandas or ClickHouse.
+type ResultsConfig struct {
+	// Path of results files, format is detected by extension .csv or .parquet,
+	// and files are numbered when rolling, e.g. results.csv => results-0001.csv, results-0002.csv
+	Path       string
+	SampleRate float64 // ratio of requests to export in (0, 1], default to 1
+	MaxRows    int     // roll to next file after max rows, default to 1000000
+	BufferSize int     // max samples buffered in memory, samples are dropped if buffer is full, default to 10000
+}
+
+type resultSample struct {
+	timestamp int64 // unix milliseconds when request completed
+	name      string
+	status    int // status code of response, 0 if no response is received
+	latency   int64
+	bytes     int64
+}
+
+// resultsFileWriter writes samples into one results file.
+type resultsFileWriter interface {
+	write(samples []*resultSample) error
+	close() error
+}
+
+// resultsWriter buffers samples with bounded channel and flushes them asynchronously,
+// thus load generation is never blocked by writing files.
+type resultsWriter struct {
+	cfg     ResultsConfig
+	prefix  string
+	ext     string
+	samples chan *resultSample
+	dropped int64
+
+	mutex  sync.RWMutex
+	closed bool
+	done   chan struct{}
+	err    error
+
+	file     resultsFileWriter
+	fileSeq  int
+	fileRows int
+}
+
+func newResultsWriter(cfg ResultsConfig) (*resultsWriter, error) {
+	ext := strings.ToLower(filepath.Ext(cfg.Path))
+	if ext != ".csv" && ext != ".parquet" {
+		return nil, fmt.Errorf("unexpected results file extension %s, should be .csv or .parquet", ext)
+	}
+	if cfg.SampleRate <= 0 || cfg.SampleRate > 1 {
+		cfg.SampleRate = 1
+	}
+	if cfg.MaxRows <= 0 {
+		cfg.MaxRows = defaultResultsMaxRows
+	}
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = defaultResultsBufferSize
+	}
+	if dir := filepath.Dir(cfg.Path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, errors.Wrap(err, "create results folder failed")
+		}
+	}
+	w := &resultsWriter{
+		cfg:     cfg,
+		prefix:  strings.TrimSuffix(cfg.Path, filepath.Ext(cfg.Path)),
+		ext:     ext,
+		samples: make(chan *resultSample, cfg.BufferSize),
+		done:    make(chan struct{}),
+	}
+	go w.run()
+	return w, nil
+}
+
+// record buffers sample without blocking, sample is dropped if it is not sampled or buffer is full.
+func (w *resultsWriter) record(sample *resultSample) {
+	if w.cfg.SampleRate < 1 && rand.Float64() >= w.cfg.SampleRate {
+		return
+	}
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	if w.closed {
+		return
+	}
+	select {
+	case w.samples <- sample:
+	default:
+		atomic.AddInt64(&w.dropped, 1)
+	}
+}
+
+// close flushes buffered samples and closes the current file.
+func (w *resultsWriter) close() error {
+	w.mutex.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.samples)
+	}
+	w.mutex.Unlock()
+	<-w.done
+	if dropped := atomic.LoadInt64(&w.dropped); dropped > 0 {
+		log.Warn().Int64("dropped", dropped).Msg("results samples dropped since buffer is full")
+	}
+	return w.err
+}
+
+func (w *resultsWriter) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(resultsFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*resultSample, 0, resultsFlushRows)
+	for {
+		select {
+		case sample, ok := <-w.samples:
+			if !ok {
+				w.flush(batch)
+				if w.file != nil {
+					w.setErr(w.file.close())
+				}
+				return
+			}
+			batch = append(batch, sample)
+			if len(batch) >= resultsFlushRows {
+				w.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			w.flush(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+// flush writes samples into the current file, and rolls to next file after max rows.
+func (w *resultsWriter) flush(samples []*resultSample) {
+	for len(samples) > 0 {
+		if w.file == nil {
+			if err := w.openFile(); err != nil {
+				w.setErr(err)
+				return
+			}
+		}
+		n := w.cfg.MaxRows - w.fileRows
+		if n > len(samples) {
+			n = len(samples)
+		}
+		if err := w.file.write(samples[:n]); err != nil {
+			w.setErr(err)
+			return
+		}
+		samples = samples[n:]
+		w.fileRows += n
+		if w.fileRows >= w.cfg.MaxRows {
+			w.setErr(w.file.close())
+			w.file = nil
+		}
+	}
+}
+
+func (w *resultsWriter) openFile() (err error) {
+	w.fileSeq++
+	w.fileRows = 0
+	path := fmt.Sprintf("%s-%04d%s", w.prefix, w.fileSeq, w.ext)
+	log.Info().Str("path", path).Msg("write results file")
+	if w.ext == ".parquet" {
+		w.file, err = newParquetWriter(path)
+	} else {
+		w.file, err = newCSVResultsWriter(path)
+	}
+	return err
+}
+
+func (w *resultsWriter) setErr(err error) {
+	if err == nil {
+		return
+	}
+	log.Error().Err(err).Msg("write results file failed")
+	if w.err == nil {
+		w.err = err
+	}
+}
+
+var resultsCSVHeader = []string{"timestamp", "name", "status", "latency_ms", "bytes"}
+
+type csvResultsWriter struct {
+	file   *os.File
+	writer *csv.Writer
+}
+
+func newCSVResultsWriter(path string) (*csvResultsWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	writer := csv.NewWriter(file)
+	if err := writer.Write(resultsCSVHeader); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &csvResultsWriter{file: file, writer: writer}, nil
+}
+
+func (w *csvResultsWriter) write(samples []*resultSample) error {
+	for _, s := range samples {
+		err := w.writer.Write([]string{
+			time.Unix(0, s.timestamp*int64(time.Millisecond)).UTC().Format("2006-01-02T15:04:05.000Z"),
+			s.name,
+			strconv.Itoa(s.status),
+			strconv.FormatInt(s.latency, 10),
+			strconv.FormatInt(s.bytes, 10),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+func (w *csvResultsWriter) close() error {
+	w.writer.Flush()
+	if err := w.writer.Error(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
@@ -0,0 +1,120 @@
+package boomer
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/reader"
+)
+
+func TestResultsWriterCSV(t *testing.T) {
+	dir := t.TempDir()
+	w, err := newResultsWriter(ResultsConfig{Path: filepath.Join(dir, "raw.csv"), MaxRows: 2})
+	require.Nil(t, err)
+
+	for i := 0; i < 3; i++ {
+		w.record(&resultSample{timestamp: 1648252800000 + int64(i), name: "get", status: 200, latency: int64(i), bytes: 10})
+	}
+	require.Nil(t, w.close())
+
+	file, err := os.Open(filepath.Join(dir, "raw-0001.csv"))
+	require.Nil(t, err)
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	require.Nil(t, err)
+	assert.Equal(t, [][]string{
+		resultsCSVHeader,
+		{"2022-03-26T00:00:00.000Z", "get", "200", "0", "10"},
+		{"2022-03-26T00:00:00.001Z", "get", "200", "1", "10"},
+	}, records)
+
+	file2, err := os.Open(filepath.Join(dir, "raw-0002.csv"))
+	require.Nil(t, err)
+	defer file2.Close()
+	records, err = csv.NewReader(file2).ReadAll()
+	require.Nil(t, err)
+	assert.Len(t, records, 2)
+}
+
+func TestResultsWriterParquet(t *testing.T) {
+	dir := t.TempDir()
+	w, err := newResultsWriter(ResultsConfig{Path: filepath.Join(dir, "raw.parquet")})
+	require.Nil(t, err)
+	w.record(&resultSample{timestamp: 1648252800000, name: "get", status: 200, latency: 12, bytes: 10})
+	// samples of flushes are written into the same row group
+	time.Sleep(resultsFlushInterval + 200*time.Millisecond)
+	w.record(&resultSample{timestamp: 1648252800001, name: "post", status: 0, latency: 34, bytes: 0})
+	require.Nil(t, w.close())
+
+	data, err := os.ReadFile(filepath.Join(dir, "raw-0001.parquet"))
+	require.Nil(t, err)
+	assert.Equal(t, "PAR1", string(data[:4]))
+	assert.Equal(t, "PAR1", string(data[len(data)-4:]))
+
+	// decode file with parquet reader
+	file, err := local.NewLocalFileReader(filepath.Join(dir, "raw-0001.parquet"))
+	require.Nil(t, err)
+	defer file.Close()
+	r, err := reader.NewParquetColumnReader(file, 1)
+	require.Nil(t, err)
+	defer r.ReadStop()
+
+	assert.Equal(t, int64(2), r.GetNumRows())
+	assert.Len(t, r.Footer.RowGroups, 1)
+	columnNames := []string{"timestamp", "name", "status", "latency_ms", "bytes"}
+	schema := r.Footer.Schema
+	if assert.Len(t, schema, len(columnNames)+1) {
+		for i, name := range columnNames {
+			assert.Equal(t, name, r.SchemaHandler.Infos[i+1].ExName)
+		}
+		assert.Equal(t, parquet.ConvertedType_TIMESTAMP_MILLIS, schema[1].GetConvertedType())
+		assert.Equal(t, parquet.ConvertedType_UTF8, schema[2].GetConvertedType())
+		assert.Equal(t, parquet.Type_INT32, schema[3].GetType())
+	}
+
+	columns := map[string][]interface{}{}
+	for i, name := range columnNames {
+		values, _, _, err := r.ReadColumnByIndex(int64(i), 2)
+		require.Nil(t, err, name)
+		columns[name] = values
+	}
+	assert.Equal(t, map[string][]interface{}{
+		"timestamp":  {int64(1648252800000), int64(1648252800001)},
+		"name":       {"get", "post"},
+		"status":     {int32(200), int32(0)},
+		"latency_ms": {int64(12), int64(34)},
+		"bytes":      {int64(10), int64(0)},
+	}, columns)
+}
+
+func TestResultsWriterDrop(t *testing.T) {
+	// writer without flushing goroutine, thus samples are never consumed from buffer
+	w := &resultsWriter{
+		cfg:     ResultsConfig{SampleRate: 1},
+		samples: make(chan *resultSample, 2),
+		done:    make(chan struct{}),
+	}
+	for i := 0; i < 5; i++ {
+		w.record(&resultSample{})
+	}
+	assert.Equal(t, 2, len(w.samples))
+	assert.Equal(t, int64(3), w.dropped)
+
+	close(w.done)
+	require.Nil(t, w.close())
+	// samples are ignored after closed
+	w.record(&resultSample{})
+	assert.Equal(t, int64(3), w.dropped)
+}
+
+func TestResultsWriterInvalidPath(t *testing.T) {
+	_, err := newResultsWriter(ResultsConfig{Path: "raw.json"})
+	assert.NotNil(t, err)
+}
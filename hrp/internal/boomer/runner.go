@@ -1,6 +1,8 @@
 package boomer
 
 import (
+	"encoding/csv"
+	"errors"
 	"fmt"
 	"math/rand"
 	"os"
@@ -23,6 +25,22 @@ const (
 	stateStopped             // stopped
 )
 
+func stateName(state int32) string {
+	switch state {
+	case stateInit:
+		return "initializing"
+	case stateSpawning:
+		return "spawning"
+	case stateRunning:
+		return "running"
+	case stateQuitting:
+		return "quitting"
+	case stateStopped:
+		return "stopped"
+	}
+	return ""
+}
+
 const (
 	reportStatsInterval = 3 * time.Second
 )
@@ -58,34 +76,61 @@ type runner struct {
 	tasks           []*Task
 	totalTaskWeight int
 
-	rateLimiter      RateLimiter
-	rateLimitEnabled bool
-	stats            *requestStats
+	rateLimiter        RateLimiter
+	rateLimitEnabled   bool
+	rateLimiterStarted bool
+	rateLimiterMutex   sync.RWMutex
+	stats              *requestStats
 
 	currentClientsNum int32 // current clients count
 	spawnCount        int   // target clients to spawn
 	spawnRate         float64
 	loop              *Loop // specify loop count for testcase, count = loopCount * spawnCount
 	spawnDone         chan struct{}
+	spawnDoneOnce     sync.Once
+
+	// quit channel of each spawned worker, which is closed to stop the worker when users are decreased
+	workers      []chan bool
+	workersMutex sync.Mutex
+	quitChan     chan bool // closed to stop all workers
+	spawnCancel  chan bool // closed to cancel spawning in progress
 
 	outputs []Output
+
+	// iterations are started at arrival rate instead of by looping users if set
+	arrivalRate       *ArrivalRateConfig
+	droppedIterations int64 // iterations dropped since all users are busy in arrival rate mode
+
+	// stats of warm-up phase are reset when it elapses, thus excluded from final summary and gates
+	warmUpDuration time.Duration
+
+	// resource usage of load generator is sampled and users are capped when overloaded if set
+	guard *loadGuard
+
+	// percentiles of final summary are written into csv file if set
+	statsCSVPath string
+	// error buckets with sampled request and response pairs are written into json file if set
+	errorReportPath string
+	// raw results of sampled requests are written into files if set
+	results *resultsWriter
 }
 
 // safeRun runs fn and recovers from unexpected panics.
-// it prevents panics from Task.Fn crashing boomer.
-func (r *runner) safeRun(fn func()) {
+// it prevents panics from Task.Fn crashing boomer, and returns the recovered panic as error.
+func (r *runner) safeRun(fn func()) (err error) {
 	defer func() {
 		// don't panic
-		err := recover()
-		if err != nil {
+		if e := recover(); e != nil {
 			stackTrace := debug.Stack()
-			errMsg := fmt.Sprintf("%v", err)
+			errMsg := fmt.Sprintf("%v", e)
 			os.Stderr.Write([]byte(errMsg))
 			os.Stderr.Write([]byte("\n"))
 			os.Stderr.Write(stackTrace)
+			err = errors.New(errMsg)
 		}
 	}()
 	fn()
+	return nil
 }
 
 func (r *runner) addOutput(o Output) {
@@ -174,19 +219,93 @@ func (r *runner) reportTestResult() {
 	row[9] = strconv.FormatFloat(entryTotalOutput.currentFailPerSec, 'f', 2, 64)
 	table.Append(row)
 	table.Render()
+	if r.arrivalRate != nil {
+		println(fmt.Sprintf("Dropped iterations: %d", atomic.LoadInt64(&r.droppedIterations)))
+	}
+	if r.guard != nil {
+		println(r.guard.summary())
+	}
 	println()
+
+	// percentiles of response time per step, transaction and url pattern
+	percentiles := r.stats.sortedPercentiles()
+	if len(percentiles) > 0 {
+		println(fmt.Sprint("=========================================== Response Time Percentiles (ms) =============================="))
+		table = tablewriter.NewWriter(os.Stdout)
+		table.SetHeader(percentileHeader)
+		table.AppendBulk(percentileRows(percentiles))
+		table.Render()
+		println()
+
+		if r.statsCSVPath != "" {
+			if err := writeStatsCSV(r.statsCSVPath, percentiles); err != nil {
+				log.Error().Err(err).Str("path", r.statsCSVPath).Msg("write stats csv failed")
+			}
+		}
+	}
+
+	// failures aggregated by step, status code and error class
+	errorBuckets := r.stats.sortedErrorBuckets()
+	if len(errorBuckets) > 0 {
+		println(fmt.Sprint("=========================================== Top Errors =================================================="))
+		printTopErrors(errorBuckets)
+		println()
+
+		if r.errorReportPath != "" {
+			if err := writeErrorReport(r.errorReportPath, errorBuckets); err != nil {
+				log.Error().Err(err).Str("path", r.errorReportPath).Msg("write error report failed")
+			}
+		}
+	}
 }
 
-func (r *localRunner) spawnWorkers(spawnCount int, spawnRate float64, quit chan bool, spawnCompleteFunc func()) {
+var percentileHeader = []string{"Type", "Name", "# samples", "P50", "P90", "P95", "P99"}
+
+func percentileRows(percentiles []*percentileEntry) [][]string {
+	rows := make([][]string, 0, len(percentiles))
+	for _, entry := range percentiles {
+		rows = append(rows, []string{
+			entry.typ,
+			entry.name,
+			strconv.FormatInt(entry.samples, 10),
+			strconv.FormatFloat(entry.percentile(50), 'f', 2, 64),
+			strconv.FormatFloat(entry.percentile(90), 'f', 2, 64),
+			strconv.FormatFloat(entry.percentile(95), 'f', 2, 64),
+			strconv.FormatFloat(entry.percentile(99), 'f', 2, 64),
+		})
+	}
+	return rows
+}
+
+// writeStatsCSV writes percentiles of final summary into csv file.
+func writeStatsCSV(path string, percentiles []*percentileEntry) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer := csv.NewWriter(file)
+	if err := writer.Write(percentileHeader); err != nil {
+		return err
+	}
+	if err := writer.WriteAll(percentileRows(percentiles)); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+func (r *localRunner) spawnWorkers(spawnCount int, spawnRate float64, quit, cancel chan bool, spawnCompleteFunc func()) {
 	log.Info().
 		Int("spawnCount", spawnCount).
 		Float64("spawnRate", spawnRate).
 		Msg("Spawning workers")
 
-	atomic.StoreInt32(&r.state, stateSpawning)
+	if !atomic.CompareAndSwapInt32(&r.state, stateInit, stateSpawning) {
+		atomic.CompareAndSwapInt32(&r.state, stateRunning, stateSpawning)
+	}
 	for i := 1; i <= spawnCount; i++ {
 		// spawn workers with rate limit
-		sleepTime := time.Duration(1000000/r.spawnRate) * time.Microsecond
+		sleepTime := time.Duration(1000000/spawnRate) * time.Microsecond
 		time.Sleep(sleepTime)
 
 		// loop count per worker
@@ -201,46 +320,207 @@ func (r *localRunner) spawnWorkers(spawnCount int, spawnRate float64, quit chan
 			log.Info().Msg("Quitting spawning workers")
 			return
 		default:
-			atomic.AddInt32(&r.currentClientsNum, 1)
-			go func() {
-				for {
-					select {
-					case <-quit:
-						return
-					default:
-						if workerLoop != nil && !workerLoop.acquire() {
-							return
-						}
-						if r.rateLimitEnabled {
-							blocked := r.rateLimiter.Acquire()
-							if !blocked {
-								task := r.getTask()
-								r.safeRun(task.Fn)
-							}
-						} else {
-							task := r.getTask()
-							r.safeRun(task.Fn)
-						}
-						if workerLoop != nil {
-							// finished count of total
-							r.loop.increaseFinishedCount()
-							// finished count of single worker
-							workerLoop.increaseFinishedCount()
-							if r.loop.isFinished() {
-								r.stop()
-							}
-						}
-					}
-				}
-			}()
+			if !r.spawnWorker(quit, cancel, workerLoop) {
+				log.Info().Msg("Spawning workers canceled")
+				return
+			}
 		}
 	}
 
-	close(r.spawnDone)
+	r.spawnComplete()
 	if spawnCompleteFunc != nil {
 		spawnCompleteFunc()
 	}
-	atomic.StoreInt32(&r.state, stateRunning)
+}
+
+// spawnComplete closes spawnDone and marks state as running when spawning completed.
+func (r *localRunner) spawnComplete() {
+	r.spawnDoneOnce.Do(func() {
+		close(r.spawnDone)
+	})
+	atomic.CompareAndSwapInt32(&r.state, stateSpawning, stateRunning)
+}
+
+// spawnWorker starts a worker running tasks until quit or stopped by decreasing users,
+// it returns false if spawning is canceled.
+func (r *localRunner) spawnWorker(quit, cancel chan bool, workerLoop *Loop) bool {
+	r.workersMutex.Lock()
+	defer r.workersMutex.Unlock()
+	select {
+	case <-cancel:
+		return false
+	default:
+	}
+	workerQuit := make(chan bool)
+	r.workers = append(r.workers, workerQuit)
+	atomic.AddInt32(&r.currentClientsNum, 1)
+
+	go func() {
+		// functions of virtual users created by tasks, which are kept by the worker
+		userFns := make(map[*Task]func())
+		for {
+			select {
+			case <-quit:
+				return
+			case <-workerQuit:
+				return
+			default:
+				if workerLoop != nil && !workerLoop.acquire() {
+					return
+				}
+				if rateLimiter := r.getRateLimiter(); rateLimiter != nil {
+					blocked := rateLimiter.Acquire()
+					if !blocked {
+						r.runTask(userFns)
+					}
+				} else {
+					r.runTask(userFns)
+				}
+				if workerLoop != nil {
+					// finished count of total
+					r.loop.increaseFinishedCount()
+					// finished count of single worker
+					workerLoop.increaseFinishedCount()
+					if r.loop.isFinished() {
+						r.stop()
+					}
+				}
+			}
+		}
+	}()
+	return true
+}
+
+// runTask runs a random task, function of virtual user is created by task once and kept in userFns.
+// if creating virtual user panics, the run is recorded as failure and the user is created again in next run.
+func (r *runner) runTask(userFns map[*Task]func()) {
+	task := r.getTask()
+	if task.NewUser == nil {
+		r.safeRun(task.Fn)
+		return
+	}
+	fn, ok := userFns[task]
+	if !ok {
+		err := r.safeRun(func() {
+			fn = task.NewUser()
+		})
+		if err != nil {
+			r.stats.requestFailureChan <- &requestFailure{
+				requestType: "user",
+				name:        task.Name,
+				errMsg:      "create virtual user failed: " + err.Error(),
+			}
+			return
+		}
+		userFns[task] = fn
+	}
+	if fn != nil {
+		r.safeRun(fn)
+	}
+}
+
+// setSpawnCount adjusts users of running load testing, workers are spawned with spawn rate if users are increased,
+// or stopped after their running tasks finished if users are decreased.
+func (r *localRunner) setSpawnCount(spawnCount int, spawnRate float64) error {
+	if spawnCount < 0 {
+		return fmt.Errorf("invalid users %d", spawnCount)
+	}
+	if r.loop != nil {
+		return errors.New("users can not be adjusted when loop count is specified")
+	}
+	if r.arrivalRate != nil {
+		return errors.New("users can not be adjusted in arrival rate mode")
+	}
+	if state := atomic.LoadInt32(&r.state); state != stateSpawning && state != stateRunning {
+		return errors.New("load testing is not running")
+	}
+
+	r.workersMutex.Lock()
+	defer r.workersMutex.Unlock()
+	if spawnRate <= 0 {
+		spawnRate = r.spawnRate
+	}
+	log.Warn().Int("users", spawnCount).Float64("spawnRate", spawnRate).Msg("adjust users")
+	r.spawnCount = spawnCount
+	r.spawnRate = spawnRate
+
+	// cancel spawning in progress
+	close(r.spawnCancel)
+	r.spawnCancel = make(chan bool)
+
+	delta := spawnCount - len(r.workers)
+	if delta > 0 {
+		go r.spawnWorkers(delta, spawnRate, r.quitChan, r.spawnCancel, nil)
+	} else if delta < 0 {
+		for _, workerQuit := range r.workers[spawnCount:] {
+			close(workerQuit)
+		}
+		r.workers = r.workers[:spawnCount]
+		atomic.AddInt32(&r.currentClientsNum, int32(delta))
+	}
+	if delta <= 0 {
+		// spawning in progress is canceled without replacement
+		r.spawnComplete()
+	}
+	return nil
+}
+
+func (r *runner) getSpawnCount() int {
+	r.workersMutex.Lock()
+	defer r.workersMutex.Unlock()
+	return r.spawnCount
+}
+
+func (r *runner) getSpawnRate() float64 {
+	r.workersMutex.Lock()
+	defer r.workersMutex.Unlock()
+	return r.spawnRate
+}
+
+func (r *runner) getRateLimiter() RateLimiter {
+	r.rateLimiterMutex.RLock()
+	defer r.rateLimiterMutex.RUnlock()
+	if !r.rateLimitEnabled {
+		return nil
+	}
+	return r.rateLimiter
+}
+
+// setRateLimiter replaces rate limiter, which takes effect immediately if load testing is running,
+// rate limiter is disabled if nil.
+func (r *runner) setRateLimiter(rateLimiter RateLimiter) {
+	r.rateLimiterMutex.Lock()
+	defer r.rateLimiterMutex.Unlock()
+	previous := r.rateLimiter
+	if r.rateLimiterStarted {
+		if rateLimiter != nil {
+			rateLimiter.Start()
+		}
+		if r.rateLimitEnabled {
+			// workers blocked by previous rate limiter are released after its refill period
+			defer previous.Stop()
+		}
+	}
+	r.rateLimiter = rateLimiter
+	r.rateLimitEnabled = rateLimiter != nil
+}
+
+func (r *runner) startRateLimiter() {
+	r.rateLimiterMutex.Lock()
+	defer r.rateLimiterMutex.Unlock()
+	if r.rateLimitEnabled {
+		r.rateLimiter.Start()
+	}
+	r.rateLimiterStarted = true
+}
+
+func (r *runner) stopRateLimiter() {
+	r.rateLimiterMutex.Lock()
+	defer r.rateLimiterMutex.Unlock()
+	if r.rateLimitEnabled {
+		r.rateLimiter.Stop()
+	}
+	r.rateLimiterStarted = false
 }
 
 // setTasks will set the runner's task list AND the total task weight
@@ -308,19 +588,33 @@ func (r *localRunner) start() {
 	// init state
 	atomic.StoreInt32(&r.state, stateInit)
 	atomic.StoreInt32(&r.currentClientsNum, 0)
+	atomic.StoreInt64(&r.droppedIterations, 0)
 	r.stats.clearAll()
 
 	// start rate limiter
-	if r.rateLimitEnabled {
-		r.rateLimiter.Start()
-	}
+	r.startRateLimiter()
 
 	// all running workers(goroutines) will select on this channel.
 	// close this channel will stop all running workers.
 	quitChan := make(chan bool)
 	// when this channel is closed, all statistics are reported successfully
 	reportedChan := make(chan bool)
-	go r.spawnWorkers(r.spawnCount, r.spawnRate, quitChan, nil)
+	r.workersMutex.Lock()
+	r.workers = nil
+	r.quitChan = quitChan
+	r.spawnCancel = make(chan bool)
+	if r.arrivalRate != nil {
+		go r.scheduleArrivals(r.spawnCount, quitChan)
+	} else {
+		go r.spawnWorkers(r.spawnCount, r.spawnRate, quitChan, r.spawnCancel, nil)
+	}
+	r.workersMutex.Unlock()
+
+	// start guard of load generator
+	if r.guard != nil {
+		r.guard.reset()
+		go r.runGuard(quitChan)
+	}
 
 	// output setup
 	r.outputOnStart()
@@ -328,6 +622,12 @@ func (r *localRunner) start() {
 	// start running
 	go func() {
 		var ticker = time.NewTicker(reportStatsInterval)
+		var warmUpChan <-chan time.Time
+		if r.warmUpDuration > 0 {
+			warmUpTimer := time.NewTimer(r.warmUpDuration)
+			defer warmUpTimer.Stop()
+			warmUpChan = warmUpTimer.C
+		}
 		for {
 			select {
 			// record stats
@@ -338,11 +638,26 @@ func (r *localRunner) start() {
 			case n := <-r.stats.requestFailureChan:
 				r.stats.logRequest(n.requestType, n.name, n.responseTime, 0)
 				r.stats.logError(n.requestType, n.name, n.errMsg)
+			case u := <-r.stats.requestURLChan:
+				r.stats.logURL(u.pattern, u.responseTime)
+			case e := <-r.stats.requestErrorChan:
+				r.stats.logRequestError(e)
+			case s := <-r.stats.scenarioChan:
+				r.stats.logScenario(s.name, s.success, s.elapsedTime)
+			// reset stats after warm-up
+			case <-warmUpChan:
+				warmUpChan = nil
+				r.reportStats()
+				r.stats.clearAll()
+				log.Warn().Dur("warmUp", r.warmUpDuration).Msg("warm-up finished, stats are reset")
 			// report stats
 			case <-ticker.C:
 				r.reportStats()
 				// close reportedChan and return if the last stats is reported successfully
 				if atomic.LoadInt32(&r.state) == stateQuitting {
+					if warmUpChan != nil {
+						log.Warn().Msg("load testing stopped during warm-up, stats include warm-up samples")
+					}
 					close(reportedChan)
 					return
 				}
@@ -362,8 +677,13 @@ func (r *localRunner) start() {
 	<-reportedChan
 
 	// stop rate limiter
-	if r.rateLimitEnabled {
-		r.rateLimiter.Stop()
+	r.stopRateLimiter()
+
+	// flush raw results
+	if r.results != nil {
+		if err := r.results.close(); err != nil {
+			log.Error().Err(err).Msg("export results failed")
+		}
 	}
 
 	// report test result
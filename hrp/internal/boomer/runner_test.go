@@ -112,3 +112,54 @@ func TestLoopCount(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestTaskNewUser(t *testing.T) {
+	var users, iterations int64
+	task := &Task{
+		Name: "TaskA",
+		NewUser: func() func() {
+			atomic.AddInt64(&users, 1)
+			return func() {
+				atomic.AddInt64(&iterations, 1)
+				time.Sleep(time.Millisecond)
+			}
+		},
+	}
+	runner := newLocalRunner(2, 100)
+	runner.loop = &Loop{loopCount: 10}
+	runner.setTasks([]*Task{task})
+	go runner.start()
+	<-runner.stopChan
+	assert.Equal(t, int64(2), atomic.LoadInt64(&users))
+	assert.Equal(t, int64(10), atomic.LoadInt64(&iterations))
+}
+
+func TestTaskNewUserPanic(t *testing.T) {
+	var users, iterations int
+	task := &Task{
+		Name: "TaskA",
+		NewUser: func() func() {
+			users++
+			if users <= 2 {
+				panic("create user failed")
+			}
+			return func() {
+				iterations++
+			}
+		},
+	}
+	runner := &runner{stats: newRequestStats()}
+	runner.setTasks([]*Task{task})
+	userFns := make(map[*Task]func())
+	for i := 0; i < 5; i++ {
+		runner.runTask(userFns)
+	}
+	// user is created again after failures, and created once after success
+	assert.Equal(t, 3, users)
+	assert.Equal(t, 3, iterations)
+	if assert.Len(t, runner.stats.requestFailureChan, 2) {
+		failure := <-runner.stats.requestFailureChan
+		assert.Equal(t, "TaskA", failure.name)
+		assert.Equal(t, "create virtual user failed: create user failed", failure.errMsg)
+	}
+}
@@ -1,6 +1,7 @@
 package boomer
 
 import (
+	"sort"
 	"time"
 
 	"github.com/httprunner/httprunner/hrp/internal/json"
@@ -27,6 +28,47 @@ type requestFailure struct {
 	errMsg       string
 }
 
+type scenario struct {
+	name        string
+	success     bool
+	elapsedTime int64
+}
+
+type requestURL struct {
+	pattern      string
+	responseTime int64
+}
+
+// types of percentile entries, which are also sorting order in final summary
+const (
+	percentileTypeTotal       = "total"
+	percentileTypeScenario    = "scenario"
+	percentileTypeStep        = "step"
+	percentileTypeTransaction = "transaction"
+	percentileTypeURL         = "url"
+)
+
+var percentileTypeOrder = map[string]int{
+	percentileTypeTotal:       0,
+	percentileTypeScenario:    1,
+	percentileTypeStep:        2,
+	percentileTypeTransaction: 3,
+	percentileTypeURL:         4,
+}
+
+// percentileEntry records response time digest of total, step name, transaction or url pattern,
+// which is accumulated during the whole run for percentiles in final summary.
+type percentileEntry struct {
+	typ     string
+	name    string
+	samples int64
+	digest  *tdigest
+}
+
+func (e *percentileEntry) percentile(percent float64) float64 {
+	return e.digest.quantile(percent / 100)
+}
+
 type requestStats struct {
 	entries   map[string]*statsEntry
 	errors    map[string]*statsError
@@ -39,6 +81,15 @@ type requestStats struct {
 
 	requestSuccessChan chan *requestSuccess
 	requestFailureChan chan *requestFailure
+	requestURLChan     chan *requestURL
+	requestErrorChan   chan *requestError
+	scenarioChan       chan *scenario
+
+	percentiles map[string]*percentileEntry
+
+	// failures aggregated by step, status code and error class, with sampled request and response pairs
+	errorBuckets map[string]*errorBucket
+	errorSampler *errorSampler
 }
 
 func newRequestStats() (stats *requestStats) {
@@ -52,6 +103,12 @@ func newRequestStats() (stats *requestStats) {
 	stats.transactionChan = make(chan *transaction, 100)
 	stats.requestSuccessChan = make(chan *requestSuccess, 100)
 	stats.requestFailureChan = make(chan *requestFailure, 100)
+	stats.requestURLChan = make(chan *requestURL, 100)
+	stats.requestErrorChan = make(chan *requestError, 100)
+	stats.scenarioChan = make(chan *scenario, 100)
+	stats.percentiles = make(map[string]*percentileEntry)
+	stats.errorBuckets = make(map[string]*errorBucket)
+	stats.errorSampler = newErrorSampler(defaultErrorSamples)
 
 	stats.total = &statsEntry{
 		Name:   "Total",
@@ -70,11 +127,54 @@ func (s *requestStats) logTransaction(name string, success bool, responseTime in
 		s.get(name, "transaction").logFailures()
 	}
 	s.get(name, "transaction").log(responseTime, contentLength)
+	s.logPercentile(percentileTypeTransaction, name, responseTime)
+}
+
+// logScenario logs iteration of scenario, which is aggregated separately from requests and transactions.
+func (s *requestStats) logScenario(name string, success bool, elapsedTime int64) {
+	entry := s.get(name, "scenario")
+	if !success {
+		entry.logFailures()
+	}
+	entry.log(elapsedTime, 0)
+	s.logPercentile(percentileTypeScenario, name, elapsedTime)
 }
 
 func (s *requestStats) logRequest(method, name string, responseTime int64, contentLength int64) {
 	s.total.log(responseTime, contentLength)
 	s.get(name, method).log(responseTime, contentLength)
+	s.logPercentile(percentileTypeTotal, "Total", responseTime)
+	s.logPercentile(percentileTypeStep, name, responseTime)
+}
+
+func (s *requestStats) logURL(pattern string, responseTime int64) {
+	s.logPercentile(percentileTypeURL, pattern, responseTime)
+}
+
+func (s *requestStats) logPercentile(typ, name string, responseTime int64) {
+	key := typ + "|" + name
+	entry, ok := s.percentiles[key]
+	if !ok {
+		entry = &percentileEntry{typ: typ, name: name, digest: newTDigest(defaultCompression)}
+		s.percentiles[key] = entry
+	}
+	entry.samples++
+	entry.digest.add(float64(responseTime))
+}
+
+// sortedPercentiles returns percentile entries sorted by type and name.
+func (s *requestStats) sortedPercentiles() []*percentileEntry {
+	entries := make([]*percentileEntry, 0, len(s.percentiles))
+	for _, entry := range s.percentiles {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].typ != entries[j].typ {
+			return percentileTypeOrder[entries[i].typ] < percentileTypeOrder[entries[j].typ]
+		}
+		return entries[i].name < entries[j].name
+	})
+	return entries
 }
 
 func (s *requestStats) logError(method, name, err string) {
@@ -121,6 +221,9 @@ func (s *requestStats) clearAll() {
 	s.transactionFailed = 0
 	s.entries = make(map[string]*statsEntry)
 	s.errors = make(map[string]*statsError)
+	s.percentiles = make(map[string]*percentileEntry)
+	s.errorBuckets = make(map[string]*errorBucket)
+	s.errorSampler.reset()
 	s.startTime = time.Now().Unix()
 }
 
@@ -155,6 +258,59 @@ func (s *requestStats) collectReportData() map[string]interface{} {
 	return data
 }
 
+// TotalStats represents accumulated stats of all requests in load testing,
+// which is used to evaluate performance gates after run.
+type TotalStats struct {
+	NumRequests       int64
+	NumFailures       int64
+	TotalResponseTime int64
+	MinResponseTime   int64
+	MaxResponseTime   int64
+	Duration          time.Duration // from the first request to the last request, at least 1s
+	responseTimes     map[int64]int64
+}
+
+func newTotalStats(entry *statsEntry) *TotalStats {
+	duration := time.Duration(entry.LastRequestTimestamp-entry.StartTime) * time.Second
+	if duration < time.Second {
+		duration = time.Second
+	}
+	responseTimes := make(map[int64]int64, len(entry.ResponseTimes))
+	for k, v := range entry.ResponseTimes {
+		responseTimes[k] = v
+	}
+	return &TotalStats{
+		NumRequests:       entry.NumRequests,
+		NumFailures:       entry.NumFailures,
+		TotalResponseTime: entry.TotalResponseTime,
+		MinResponseTime:   entry.MinResponseTime,
+		MaxResponseTime:   entry.MaxResponseTime,
+		Duration:          duration,
+		responseTimes:     responseTimes,
+	}
+}
+
+// AvgResponseTime returns average response time in milliseconds.
+func (s *TotalStats) AvgResponseTime() float64 {
+	return getAvgResponseTime(s.NumRequests, s.TotalResponseTime)
+}
+
+// PercentileResponseTime returns response time in milliseconds at percent (0-100),
+// response times are rounded to 2 significant digits when recorded, e.g. 147 becomes 150.
+func (s *TotalStats) PercentileResponseTime(percent float64) int64 {
+	return getPercentileResponseTime(s.NumRequests, s.responseTimes, percent)
+}
+
+// FailRatio returns ratio of failed requests.
+func (s *TotalStats) FailRatio() float64 {
+	return getTotalFailRatio(s.NumRequests, s.NumFailures)
+}
+
+// RPS returns average requests per second.
+func (s *TotalStats) RPS() float64 {
+	return getCurrentRps(s.NumRequests, s.Duration.Seconds())
+}
+
 // statsEntry represents a single stats entry (name and method)
 type statsEntry struct {
 	// Name (URL) of this stats entry
@@ -1,6 +1,9 @@
 package boomer
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -214,3 +217,103 @@ func TestCollectReportData(t *testing.T) {
 		t.Error("Key stats not found")
 	}
 }
+
+func TestTotalStats(t *testing.T) {
+	newStats := newRequestStats()
+	newStats.logRequest("http", "success", 10, 30)
+	newStats.logRequest("http", "success", 20, 40)
+	newStats.logRequest("http", "success", 30, 40)
+	newStats.logRequest("http", "failure", 140, 0)
+	newStats.logError("http", "failure", "timeout")
+
+	stats := newTotalStats(newStats.total)
+	if stats.NumRequests != 4 || stats.NumFailures != 1 {
+		t.Error("numRequests and numFailures are wrong, expected: 4 and 1, got:", stats.NumRequests, stats.NumFailures)
+	}
+	if stats.AvgResponseTime() != 50 {
+		t.Error("avgResponseTime is wrong, expected: 50, got:", stats.AvgResponseTime())
+	}
+	if stats.PercentileResponseTime(75) != 30 {
+		t.Error("p75 response time is wrong, expected: 30, got:", stats.PercentileResponseTime(75))
+	}
+	if stats.PercentileResponseTime(95) != 140 {
+		t.Error("p95 response time is wrong, expected: 140, got:", stats.PercentileResponseTime(95))
+	}
+	if stats.FailRatio() != 0.25 {
+		t.Error("failRatio is wrong, expected: 0.25, got:", stats.FailRatio())
+	}
+	if stats.RPS() != 4 {
+		t.Error("rps is wrong, expected: 4, got:", stats.RPS())
+	}
+}
+
+func TestLogPercentiles(t *testing.T) {
+	newStats := newRequestStats()
+	for i := int64(1); i <= 100; i++ {
+		newStats.logRequest("request", "get user", i, 0)
+		newStats.logURL("GET /users/{id}", i)
+	}
+	newStats.logTransaction("checkout", true, 500, 0)
+
+	percentiles := newStats.sortedPercentiles()
+	var names []string
+	for _, entry := range percentiles {
+		names = append(names, entry.typ+": "+entry.name)
+	}
+	expected := []string{"total: Total", "step: get user", "transaction: checkout", "url: GET /users/{id}"}
+	if strings.Join(names, ", ") != strings.Join(expected, ", ") {
+		t.Error("percentile entries are wrong, expected:", expected, "got:", names)
+	}
+	step := percentiles[1]
+	if step.samples != 100 {
+		t.Error("samples of step is wrong, expected: 100, got:", step.samples)
+	}
+	if p := step.percentile(50); p < 49 || p > 51 {
+		t.Error("p50 of step is wrong, expected: about 50, got:", p)
+	}
+	if p := step.percentile(99); p < 98 || p > 100 {
+		t.Error("p99 of step is wrong, expected: about 99, got:", p)
+	}
+
+	path := filepath.Join(t.TempDir(), "stats.csv")
+	if err := writeStatsCSV(path, percentiles); err != nil {
+		t.Fatal(err)
+	}
+	content, _ := os.ReadFile(path)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 5 || lines[0] != "Type,Name,# samples,P50,P90,P95,P99" {
+		t.Error("stats csv is wrong, got:", string(content))
+	}
+	if lines[3] != "transaction,checkout,1,500.00,500.00,500.00,500.00" {
+		t.Error("transaction row of stats csv is wrong, got:", lines[3])
+	}
+
+	newStats.clearAll()
+	if len(newStats.sortedPercentiles()) != 0 {
+		t.Error("percentiles should be cleared")
+	}
+}
+
+func TestLogScenario(t *testing.T) {
+	newStats := newRequestStats()
+	newStats.logScenario("browse", true, 10)
+	newStats.logScenario("browse", false, 30)
+	newStats.logScenario("checkout", true, 20)
+
+	entry := newStats.get("browse", "scenario")
+	if entry.NumRequests != 2 {
+		t.Error("iterations of scenario is wrong, expected: 2, got:", entry.NumRequests)
+	}
+	if entry.NumFailures != 1 {
+		t.Error("failures of scenario is wrong, expected: 1, got:", entry.NumFailures)
+	}
+	// scenarios are not counted as requests
+	if newStats.total.NumRequests != 0 {
+		t.Error("scenarios should not be counted as requests, got:", newStats.total.NumRequests)
+	}
+
+	percentiles := newStats.sortedPercentiles()
+	if len(percentiles) != 2 || percentiles[0].typ != percentileTypeScenario || percentiles[0].name != "browse" {
+		t.Error("percentile entries of scenarios are wrong")
+	}
+}
@@ -8,6 +8,9 @@ type Task struct {
 	// The weight is used to distribute goroutines over multiple tasks.
 	Weight int
 	// Fn is called by the goroutines allocated to this task, in a loop.
-	Fn   func()
-	Name string
+	Fn func()
+	// NewUser is called once by each goroutine allocated to this task if set, and the returned function
+	// is called in a loop instead of Fn, thus state of virtual user is kept across iterations.
+	NewUser func() func()
+	Name    string
 }
@@ -0,0 +1,121 @@
+package boomer
+
+import (
+	"math"
+	"sort"
+)
+
+const defaultCompression = 100
+
+type centroid struct {
+	mean   float64
+	weight float64
+}
+
+// tdigest is merging t-digest which estimates quantiles of stream with bounded memory,
+// quantiles near the tails are more accurate than those near the median.
+// see https://github.com/tdunning/t-digest/blob/main/docs/t-digest-paper/histo.pdf
+type tdigest struct {
+	compression float64
+	centroids   []centroid // sorted by mean
+	buffer      []centroid // unmerged samples
+	count       float64
+	min         float64
+	max         float64
+}
+
+func newTDigest(compression float64) *tdigest {
+	return &tdigest{
+		compression: compression,
+		min:         math.Inf(1),
+		max:         math.Inf(-1),
+	}
+}
+
+// add adds sample to digest, samples are buffered and merged in batch.
+func (t *tdigest) add(value float64) {
+	t.buffer = append(t.buffer, centroid{mean: value, weight: 1})
+	t.count++
+	if value < t.min {
+		t.min = value
+	}
+	if value > t.max {
+		t.max = value
+	}
+	if len(t.buffer) >= int(5*t.compression) {
+		t.compress()
+	}
+}
+
+// compress merges buffered samples into centroids, each centroid is limited by size
+// proportional to q*(1-q), thus centroids near the tails are small.
+func (t *tdigest) compress() {
+	if len(t.buffer) == 0 {
+		return
+	}
+	all := append(t.centroids, t.buffer...)
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].mean < all[j].mean
+	})
+
+	merged := make([]centroid, 0, len(t.centroids)+1)
+	current := all[0]
+	var cumulative float64 // weight before current centroid
+	for _, c := range all[1:] {
+		q := (cumulative + (current.weight+c.weight)/2) / t.count
+		limit := 4 * t.count * q * (1 - q) / t.compression
+		if current.weight+c.weight <= math.Max(limit, 1) {
+			current.mean += (c.mean - current.mean) * c.weight / (current.weight + c.weight)
+			current.weight += c.weight
+			continue
+		}
+		cumulative += current.weight
+		merged = append(merged, current)
+		current = c
+	}
+	merged = append(merged, current)
+
+	t.centroids = merged
+	t.buffer = t.buffer[:0]
+}
+
+// quantile returns estimated value at quantile q (0-1), values are interpolated between centroids.
+func (t *tdigest) quantile(q float64) float64 {
+	t.compress()
+	if len(t.centroids) == 0 {
+		return 0
+	}
+	if q <= 0 {
+		return t.min
+	}
+	if q >= 1 {
+		return t.max
+	}
+	if len(t.centroids) == 1 {
+		return t.centroids[0].mean
+	}
+
+	target := q * t.count
+	// interpolate from min to center of the first centroid
+	first := t.centroids[0]
+	if target < first.weight/2 {
+		return t.min + (first.mean-t.min)*target/(first.weight/2)
+	}
+	var cumulative float64
+	for i := 0; i < len(t.centroids)-1; i++ {
+		c, next := t.centroids[i], t.centroids[i+1]
+		center := cumulative + c.weight/2
+		nextCenter := cumulative + c.weight + next.weight/2
+		if target < nextCenter {
+			return c.mean + (next.mean-c.mean)*(target-center)/(nextCenter-center)
+		}
+		cumulative += c.weight
+	}
+	// interpolate from center of the last centroid to max
+	last := t.centroids[len(t.centroids)-1]
+	center := t.count - last.weight/2
+	if last.weight == 1 || target <= center {
+		return last.mean
+	}
+	return last.mean + (t.max-last.mean)*(target-center)/(last.weight/2)
+}
@@ -0,0 +1,52 @@
+package boomer
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestTDigestQuantile(t *testing.T) {
+	digest := newTDigest(defaultCompression)
+	if digest.quantile(0.5) != 0 {
+		t.Error("quantile of empty digest should be 0")
+	}
+
+	// shuffled 1..100000
+	values := rand.New(rand.NewSource(1)).Perm(100000)
+	for _, v := range values {
+		digest.add(float64(v + 1))
+	}
+	if len(digest.centroids) > 10*defaultCompression {
+		t.Error("centroids of digest should be bounded, got:", len(digest.centroids))
+	}
+	testData := []struct {
+		q         float64
+		expected  float64
+		tolerance float64
+	}{
+		{0, 1, 0},
+		{0.5, 50000, 1000},
+		{0.9, 90000, 500},
+		{0.95, 95000, 300},
+		{0.99, 99000, 100},
+		{0.999, 99900, 20},
+		{1, 100000, 0},
+	}
+	for _, data := range testData {
+		actual := digest.quantile(data.q)
+		if math.Abs(actual-data.expected) > data.tolerance {
+			t.Errorf("quantile %v should be close to %v, got: %v", data.q, data.expected, actual)
+		}
+	}
+}
+
+func TestTDigestSingleValue(t *testing.T) {
+	digest := newTDigest(defaultCompression)
+	digest.add(42)
+	for _, q := range []float64{0, 0.5, 0.99, 1} {
+		if digest.quantile(q) != 42 {
+			t.Errorf("quantile %v should be 42, got: %v", q, digest.quantile(q))
+		}
+	}
+}
@@ -3,11 +3,22 @@ package builtin
 import (
 	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
 
+// IsOrderingAssertion checks if assertion compares order of values, which requires values of the same type.
+func IsOrderingAssertion(assertMethod string) bool {
+	switch assertMethod {
+	case "lt", "less_than", "le", "less_or_equals", "gt", "greater_than", "ge", "greater_or_equals":
+		return true
+	}
+	return false
+}
+
 var Assertions = map[string]func(t assert.TestingT, actual interface{}, expected interface{}, msgAndArgs ...interface{}) bool{
 	"eq":                assert.EqualValues,
 	"equals":            assert.EqualValues,
@@ -46,8 +57,28 @@ var Assertions = map[string]func(t assert.TestingT, actual interface{}, expected
 	"str_eq":                   StringEqual,
 	"string_equals":            StringEqual,
 	"regex_match":              RegexMatch,
+	"json_equals":              JSONEquals,
+	"json_eq":                  JSONEquals,
+	"equal_fold":               EqualFold,
+	"contains_fold":            ContainsFold,
+	"approx_equal":             ApproxEqual,
+	"approx_eq":                ApproxEqual,
+	"set_equals":               SetEquals,
+	"set_eq":                   SetEquals,
+	"is_sorted_asc":            IsSortedAsc,
+	"is_sorted_desc":           IsSortedDesc,
+	"unique_items":             UniqueItems,
+	"is_rfc3339":               IsRFC3339,
+	"is_unix_timestamp":        IsUnixTimestamp,
+	"time_within":              TimeWithin,
+	"exists":                   Exists,
+	"not_exists":               NotExists,
+	"status_in":                StatusIn,
 }
 
+// DefaultEpsilon is the max allowed difference of approx_equal assertion if epsilon is not specified
+const DefaultEpsilon = 1e-6
+
 // StartsWith check if string starts with substring
 func StartsWith(t assert.TestingT, actual, expected interface{}, msgAndArgs ...interface{}) bool {
 	if !assert.IsType(t, "string", actual, fmt.Sprintf("actual is %v", actual)) {
@@ -160,10 +191,399 @@ func StringEqual(t assert.TestingT, actual, expected interface{}, msgAndArgs ...
 	return assert.True(t, strings.EqualFold(actualString, expectedString), msgAndArgs)
 }
 
+// EqualFold check if strings are equal under Unicode case-folding, e.g. header values and enum strings
+func EqualFold(t assert.TestingT, actual, expected interface{}, msgAndArgs ...interface{}) bool {
+	if !assert.IsType(t, "string", actual, fmt.Sprintf("actual is %v", actual)) {
+		return false
+	}
+	if !assert.IsType(t, "string", expected, fmt.Sprintf("expected is %v", expected)) {
+		return false
+	}
+	if strings.EqualFold(actual.(string), expected.(string)) {
+		return true
+	}
+	return assert.Fail(t, fmt.Sprintf("%q does not equal %q ignoring case", actual, expected), msgAndArgs...)
+}
+
+// ContainsFold check if string contains substring, or list contains string element, ignoring case
+func ContainsFold(t assert.TestingT, actual, expected interface{}, msgAndArgs ...interface{}) bool {
+	if !assert.IsType(t, "string", expected, fmt.Sprintf("expected is %v", expected)) {
+		return false
+	}
+	expectedString := expected.(string)
+	switch v := actual.(type) {
+	case string:
+		if strings.Contains(strings.ToLower(v), strings.ToLower(expectedString)) {
+			return true
+		}
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok && strings.EqualFold(s, expectedString) {
+				return true
+			}
+		}
+	case []string:
+		for _, s := range v {
+			if strings.EqualFold(s, expectedString) {
+				return true
+			}
+		}
+	default:
+		return assert.Fail(t, fmt.Sprintf("%#v is neither string nor list of strings", actual), msgAndArgs...)
+	}
+	return assert.Fail(t, fmt.Sprintf("%#v does not contain %q ignoring case", actual, expected), msgAndArgs...)
+}
+
+// ApproxEqual check if numbers differ by no more than DefaultEpsilon, e.g. prices, coordinates and scores
+func ApproxEqual(t assert.TestingT, actual, expected interface{}, msgAndArgs ...interface{}) bool {
+	return ApproxEqualWithEpsilon(t, actual, expected, DefaultEpsilon, msgAndArgs...)
+}
+
+// ApproxEqualWithEpsilon check if numbers differ by no more than epsilon, numeric strings are also accepted
+func ApproxEqualWithEpsilon(t assert.TestingT, actual, expected interface{}, epsilon float64, msgAndArgs ...interface{}) bool {
+	actualFloat, err := convertFloat(actual)
+	if err != nil {
+		return assert.Fail(t, fmt.Sprintf("actual is not number: %v", err), msgAndArgs...)
+	}
+	expectedFloat, err := convertFloat(expected)
+	if err != nil {
+		return assert.Fail(t, fmt.Sprintf("expected is not number: %v", err), msgAndArgs...)
+	}
+	return assert.InDelta(t, expectedFloat, actualFloat, epsilon, msgAndArgs...)
+}
+
+// SetEquals check if lists contain the same items regardless of order, items are compared as json values
+func SetEquals(t assert.TestingT, actual, expected interface{}, msgAndArgs ...interface{}) bool {
+	actualItems, err := normalizeList(actual)
+	if err != nil {
+		return assert.Fail(t, fmt.Sprintf("actual is not list: %v", err), msgAndArgs...)
+	}
+	expectedItems, err := normalizeList(expected)
+	if err != nil {
+		return assert.Fail(t, fmt.Sprintf("expected is not list: %v", err), msgAndArgs...)
+	}
+	if len(actualItems) != len(expectedItems) {
+		return assert.Fail(t, fmt.Sprintf("%v should have %d item(s), but has %d",
+			actual, len(expectedItems), len(actualItems)), msgAndArgs...)
+	}
+	matched := make([]bool, len(actualItems))
+	for _, expectedItem := range expectedItems {
+		found := false
+		for i, actualItem := range actualItems {
+			if !matched[i] && len(DiffJSON(expectedItem, actualItem)) == 0 {
+				matched[i], found = true, true
+				break
+			}
+		}
+		if !found {
+			return assert.Fail(t, fmt.Sprintf("%v does not contain %v", actual, expectedItem), msgAndArgs...)
+		}
+	}
+	return true
+}
+
+// IsSortedAsc check if list of numbers or strings is sorted in ascending order,
+// expected is true, or false to assert list is not sorted
+func IsSortedAsc(t assert.TestingT, actual, expected interface{}, msgAndArgs ...interface{}) bool {
+	return assertListProperty(t, actual, expected, "sorted in ascending order", func(items []interface{}) (bool, error) {
+		return isSorted(items, false)
+	}, msgAndArgs...)
+}
+
+// IsSortedDesc check if list of numbers or strings is sorted in descending order,
+// expected is true, or false to assert list is not sorted
+func IsSortedDesc(t assert.TestingT, actual, expected interface{}, msgAndArgs ...interface{}) bool {
+	return assertListProperty(t, actual, expected, "sorted in descending order", func(items []interface{}) (bool, error) {
+		return isSorted(items, true)
+	}, msgAndArgs...)
+}
+
+// UniqueItems check if items of list are unique, items are compared as json values,
+// expected is true, or false to assert list has duplicate items
+func UniqueItems(t assert.TestingT, actual, expected interface{}, msgAndArgs ...interface{}) bool {
+	return assertListProperty(t, actual, expected, "unique", func(items []interface{}) (bool, error) {
+		for i := range items {
+			for j := i + 1; j < len(items); j++ {
+				if len(DiffJSON(items[i], items[j])) == 0 {
+					return false, nil
+				}
+			}
+		}
+		return true, nil
+	}, msgAndArgs...)
+}
+
+func assertListProperty(t assert.TestingT, actual, expected interface{}, property string,
+	check func(items []interface{}) (bool, error), msgAndArgs ...interface{}) bool {
+
+	want, err := expectBool(expected)
+	if err != nil {
+		return assert.Fail(t, err.Error(), msgAndArgs...)
+	}
+	items, err := normalizeList(actual)
+	if err != nil {
+		return assert.Fail(t, fmt.Sprintf("actual is not list: %v", err), msgAndArgs...)
+	}
+	got, err := check(items)
+	if err != nil {
+		return assert.Fail(t, err.Error(), msgAndArgs...)
+	}
+	if got == want {
+		return true
+	}
+	if want {
+		return assert.Fail(t, fmt.Sprintf("%v is not %s", actual, property), msgAndArgs...)
+	}
+	return assert.Fail(t, fmt.Sprintf("%v should not be %s", actual, property), msgAndArgs...)
+}
+
+// expectBool converts expected of property assertions, nil is treated as true.
+func expectBool(expected interface{}) (bool, error) {
+	if expected == nil {
+		return true, nil
+	}
+	b, ok := expected.(bool)
+	if !ok {
+		return false, fmt.Errorf("expected should be true or false, got %#v", expected)
+	}
+	return b, nil
+}
+
+// normalizeList converts list or json string of list to generic json items.
+func normalizeList(value interface{}) ([]interface{}, error) {
+	normalized, err := NormalizeJSON(value)
+	if err != nil {
+		return nil, err
+	}
+	items, ok := normalized.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%#v is not list", value)
+	}
+	return items, nil
+}
+
+// isSorted checks if items are all numbers or all strings and sorted, equal neighbours are allowed.
+func isSorted(items []interface{}, desc bool) (bool, error) {
+	for i := 1; i < len(items); i++ {
+		var less, greater bool
+		if prev, ok := toNumber(items[i-1]); ok {
+			cur, ok := toNumber(items[i])
+			if !ok {
+				return false, fmt.Errorf("items should be all numbers or all strings, got %v", items[i])
+			}
+			less, greater = cur < prev, cur > prev
+		} else if prev, ok := items[i-1].(string); ok {
+			cur, ok := items[i].(string)
+			if !ok {
+				return false, fmt.Errorf("items should be all numbers or all strings, got %v", items[i])
+			}
+			less, greater = cur < prev, cur > prev
+		} else {
+			return false, fmt.Errorf("items should be all numbers or all strings, got %v", items[i-1])
+		}
+		if (!desc && less) || (desc && greater) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// unix timestamps in seconds or milliseconds between 1970 and 2100 are accepted
+const maxUnixTimestamp = 4102444800
+
+// IsRFC3339 check if value is RFC 3339 datetime string, e.g. 2022-04-01T08:00:00Z,
+// expected is true, or false to assert value is not RFC 3339 datetime
+func IsRFC3339(t assert.TestingT, actual, expected interface{}, msgAndArgs ...interface{}) bool {
+	want, err := expectBool(expected)
+	if err != nil {
+		return assert.Fail(t, err.Error(), msgAndArgs...)
+	}
+	s, ok := actual.(string)
+	got := ok && isRFC3339(s)
+	if got == want {
+		return true
+	}
+	if want {
+		return assert.Fail(t, fmt.Sprintf("%#v is not RFC 3339 datetime", actual), msgAndArgs...)
+	}
+	return assert.Fail(t, fmt.Sprintf("%#v should not be RFC 3339 datetime", actual), msgAndArgs...)
+}
+
+// IsUnixTimestamp check if value is unix timestamp in seconds or milliseconds, numeric string is also accepted,
+// expected is true, or false to assert value is not unix timestamp
+func IsUnixTimestamp(t assert.TestingT, actual, expected interface{}, msgAndArgs ...interface{}) bool {
+	want, err := expectBool(expected)
+	if err != nil {
+		return assert.Fail(t, err.Error(), msgAndArgs...)
+	}
+	_, err = parseUnixTimestamp(actual)
+	got := err == nil
+	if got == want {
+		return true
+	}
+	if want {
+		return assert.Fail(t, fmt.Sprintf("%#v is not unix timestamp: %v", actual, err), msgAndArgs...)
+	}
+	return assert.Fail(t, fmt.Sprintf("%#v should not be unix timestamp", actual), msgAndArgs...)
+}
+
+// TimeWithin check if RFC 3339 datetime or unix timestamp is within duration from now, in the past or future,
+// expected is duration string, e.g. 30s, 5m, 1h
+func TimeWithin(t assert.TestingT, actual, expected interface{}, msgAndArgs ...interface{}) bool {
+	durationString, ok := expected.(string)
+	if !ok {
+		return assert.Fail(t, fmt.Sprintf("expected should be duration string, e.g. 5m, got %#v", expected), msgAndArgs...)
+	}
+	duration, err := time.ParseDuration(durationString)
+	if err != nil {
+		return assert.Fail(t, fmt.Sprintf("expected is not valid duration: %v", err), msgAndArgs...)
+	}
+	var actualTime time.Time
+	if s, ok := actual.(string); ok && isRFC3339(s) {
+		actualTime, _ = time.Parse(time.RFC3339Nano, s)
+	} else {
+		actualTime, err = parseUnixTimestamp(actual)
+		if err != nil {
+			return assert.Fail(t, fmt.Sprintf("%#v is neither RFC 3339 datetime nor unix timestamp", actual), msgAndArgs...)
+		}
+	}
+	return assert.WithinDuration(t, time.Now(), actualTime, duration, msgAndArgs...)
+}
+
+func isRFC3339(s string) bool {
+	_, err := time.Parse(time.RFC3339Nano, s)
+	return err == nil
+}
+
+// parseUnixTimestamp parses unix timestamp in seconds or milliseconds, which is distinguished by magnitude.
+func parseUnixTimestamp(value interface{}) (time.Time, error) {
+	if _, ok := value.(bool); ok {
+		return time.Time{}, fmt.Errorf("bool is not number")
+	}
+	f, err := convertFloat(value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if f < 0 {
+		return time.Time{}, fmt.Errorf("negative timestamp")
+	}
+	if f >= maxUnixTimestamp {
+		f /= 1000 // milliseconds
+	}
+	if f >= maxUnixTimestamp {
+		return time.Time{}, fmt.Errorf("timestamp out of range")
+	}
+	sec := int64(f)
+	return time.Unix(sec, int64((f-float64(sec))*1e9)), nil
+}
+
+// Field is field located by check expression, Exists tells missing field from field with null value.
+type Field struct {
+	Path   string
+	Value  interface{}
+	Exists bool
+}
+
+// Exists check if field exists, even if its value is null, expected is true or false.
+// actual should be Field, other values are regarded as existing if not nil.
+func Exists(t assert.TestingT, actual, expected interface{}, msgAndArgs ...interface{}) bool {
+	want, err := expectBool(expected)
+	if err != nil {
+		return assert.Fail(t, err.Error(), msgAndArgs...)
+	}
+	field, ok := actual.(Field)
+	if !ok {
+		field = Field{Path: "field", Value: actual, Exists: actual != nil}
+	}
+	if field.Exists == want {
+		return true
+	}
+	if want {
+		return assert.Fail(t, fmt.Sprintf("%s is missing", field.Path), msgAndArgs...)
+	}
+	return assert.Fail(t, fmt.Sprintf("%s should be missing, got %#v", field.Path, field.Value), msgAndArgs...)
+}
+
+// NotExists check if field is missing, field with null value exists, expected is true or false.
+func NotExists(t assert.TestingT, actual, expected interface{}, msgAndArgs ...interface{}) bool {
+	want, err := expectBool(expected)
+	if err != nil {
+		return assert.Fail(t, err.Error(), msgAndArgs...)
+	}
+	return Exists(t, actual, !want, msgAndArgs...)
+}
+
+// StatusIn check if status code is one of expected codes or classes,
+// expected is list or single item of code and class, e.g. [200, 204], "2xx", ["2xx", 304]
+func StatusIn(t assert.TestingT, actual, expected interface{}, msgAndArgs ...interface{}) bool {
+	code, err := convertStatusCode(actual)
+	if err != nil {
+		return assert.Fail(t, fmt.Sprintf("actual is not status code: %v", err), msgAndArgs...)
+	}
+	patterns, ok := expected.([]interface{})
+	if !ok {
+		patterns = []interface{}{expected}
+	}
+	for _, pattern := range patterns {
+		if class, ok := pattern.(string); ok && isStatusClass(class) {
+			if code/100 == int(class[0]-'0') {
+				return true
+			}
+			continue
+		}
+		expectedCode, err := convertStatusCode(pattern)
+		if err != nil {
+			return assert.Fail(t, fmt.Sprintf("expected should be status code or class like 2xx, got %#v", pattern), msgAndArgs...)
+		}
+		if code == expectedCode {
+			return true
+		}
+	}
+	return assert.Fail(t, fmt.Sprintf("status code %d is not in %v", code, patterns), msgAndArgs...)
+}
+
+// isStatusClass checks if s is status class from 1xx to 5xx, case-insensitive
+func isStatusClass(s string) bool {
+	return len(s) == 3 && s[0] >= '1' && s[0] <= '5' && strings.EqualFold(s[1:], "xx")
+}
+
+func convertStatusCode(value interface{}) (int, error) {
+	if s, ok := value.(string); ok {
+		return strconv.Atoi(s)
+	}
+	if number, ok := value.(interface{ Int64() (int64, error) }); ok { // json.Number
+		code, err := number.Int64()
+		return int(code), err
+	}
+	return convertInt(value)
+}
+
 func RegexMatch(t assert.TestingT, actual, expected interface{}, msgAndArgs ...interface{}) bool {
 	return assert.Regexp(t, expected, actual, msgAndArgs)
 }
 
+// JSONEquals deep compares json structures, numbers are compared by value
+// and json strings are decoded before comparing.
+func JSONEquals(t assert.TestingT, actual, expected interface{}, msgAndArgs ...interface{}) bool {
+	actualJSON, err := NormalizeJSON(actual)
+	if err != nil {
+		return assert.Fail(t, fmt.Sprintf("actual is not valid json: %v", err), msgAndArgs...)
+	}
+	expectedJSON, err := NormalizeJSON(expected)
+	if err != nil {
+		return assert.Fail(t, fmt.Sprintf("expected is not valid json: %v", err), msgAndArgs...)
+	}
+	diffs := DiffJSON(expectedJSON, actualJSON)
+	if len(diffs) == 0 {
+		return true
+	}
+	var diffLines []string
+	for _, diff := range diffs {
+		diffLines = append(diffLines, diff.String())
+	}
+	return assert.Fail(t, fmt.Sprintf("json not equal:\n%s", strings.Join(diffLines, "\n")), msgAndArgs...)
+}
+
 func convertInt(value interface{}) (int, error) {
 	switch v := value.(type) {
 	case int:
@@ -195,6 +615,25 @@ func convertInt(value interface{}) (int, error) {
 	}
 }
 
+func convertFloat(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(v), 64)
+	case interface{ Float64() (float64, error) }: // json.Number
+		return v.Float64()
+	default:
+		i, err := convertInt(value)
+		if err != nil {
+			return 0, fmt.Errorf("unsupported float convertion for %v(%T)", v, v)
+		}
+		return float64(i), nil
+	}
+}
+
 // getLen try to get length of object.
 // return (false, 0) if impossible.
 func getLen(x interface{}) (ok bool, length int) {
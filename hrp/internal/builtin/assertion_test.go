@@ -3,6 +3,7 @@ package builtin
 import (
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -174,6 +175,121 @@ func TestStringEqual(t *testing.T) {
 	}
 }
 
+func TestEqualFold(t *testing.T) {
+	assert.True(t, EqualFold(t, "application/JSON", "Application/json"))
+	assert.True(t, EqualFold(t, "PAID", "paid"))
+	mockT := new(testing.T)
+	assert.False(t, EqualFold(mockT, "paid", "unpaid"))
+	assert.False(t, EqualFold(mockT, 1, "1"))
+}
+
+func TestContainsFold(t *testing.T) {
+	assert.True(t, ContainsFold(t, "text/HTML; charset=UTF-8", "charset=utf-8"))
+	assert.True(t, ContainsFold(t, []interface{}{"PAID", "Shipped"}, "shipped"))
+	assert.True(t, ContainsFold(t, []string{"gzip", "BR"}, "br"))
+	mockT := new(testing.T)
+	assert.False(t, ContainsFold(mockT, "no-cache", "no-store"))
+	assert.False(t, ContainsFold(mockT, []interface{}{"paid"}, "pai"))
+	assert.False(t, ContainsFold(mockT, 123, "1"))
+}
+
+func TestApproxEqual(t *testing.T) {
+	assert.True(t, ApproxEqual(t, 0.1+0.2, 0.3))
+	assert.True(t, ApproxEqual(t, "19.99", 19.99))
+	assert.True(t, ApproxEqual(t, 3, 3.0000001))
+	assert.True(t, ApproxEqualWithEpsilon(t, 39.9042, 39.9, 0.01))
+	mockT := new(testing.T)
+	assert.False(t, ApproxEqual(mockT, 0.31, 0.3))
+	assert.False(t, ApproxEqualWithEpsilon(mockT, 39.9142, 39.9, 0.01))
+	assert.False(t, ApproxEqual(mockT, "abc", 0.3))
+}
+
+func TestSetEquals(t *testing.T) {
+	assert.True(t, SetEquals(t, []interface{}{"b", "a", "c"}, []interface{}{"a", "b", "c"}))
+	assert.True(t, SetEquals(t, []interface{}{1.0, map[string]interface{}{"id": 2}}, `[{"id": 2}, 1]`))
+	assert.True(t, SetEquals(t, []int{1, 1, 2}, []interface{}{1, 2, 1}))
+	mockT := new(testing.T)
+	assert.False(t, SetEquals(mockT, []interface{}{1, 1, 2}, []interface{}{1, 2, 2}))
+	assert.False(t, SetEquals(mockT, []interface{}{1, 2}, []interface{}{1, 2, 3}))
+	assert.False(t, SetEquals(mockT, "abc", []interface{}{"abc"}))
+}
+
+func TestIsSorted(t *testing.T) {
+	assert.True(t, IsSortedAsc(t, []interface{}{1, 2, 2, 3.5}, true))
+	assert.True(t, IsSortedAsc(t, []interface{}{"apple", "banana"}, nil))
+	assert.True(t, IsSortedDesc(t, []interface{}{"2022-04-02", "2022-04-01"}, true))
+	assert.True(t, IsSortedDesc(t, []interface{}{1, 3}, false))
+	assert.True(t, IsSortedAsc(t, []interface{}{}, true))
+	mockT := new(testing.T)
+	assert.False(t, IsSortedAsc(mockT, []interface{}{3, 1}, true))
+	assert.False(t, IsSortedDesc(mockT, []interface{}{1, 3}, true))
+	assert.False(t, IsSortedAsc(mockT, []interface{}{1, "a"}, true))
+	assert.False(t, IsSortedAsc(mockT, []interface{}{1, 2}, "yes"))
+}
+
+func TestUniqueItems(t *testing.T) {
+	assert.True(t, UniqueItems(t, []interface{}{1, 2, "1"}, true))
+	assert.True(t, UniqueItems(t, []interface{}{map[string]interface{}{"id": 1}, map[string]interface{}{"id": 2}}, nil))
+	assert.True(t, UniqueItems(t, []interface{}{1, 1.0}, false))
+	mockT := new(testing.T)
+	assert.False(t, UniqueItems(mockT, []interface{}{map[string]interface{}{"id": 1}, map[string]interface{}{"id": 1}}, true))
+}
+
+func TestIsRFC3339(t *testing.T) {
+	assert.True(t, IsRFC3339(t, "2022-04-01T08:00:00Z", true))
+	assert.True(t, IsRFC3339(t, "2022-04-01T16:00:00.123+08:00", nil))
+	assert.True(t, IsRFC3339(t, "2022-04-01 08:00:00", false))
+	mockT := new(testing.T)
+	assert.False(t, IsRFC3339(mockT, "2022-04-01", true))
+	assert.False(t, IsRFC3339(mockT, 1648800000, true))
+}
+
+func TestIsUnixTimestamp(t *testing.T) {
+	assert.True(t, IsUnixTimestamp(t, 1648800000, true))
+	assert.True(t, IsUnixTimestamp(t, 1648800000123.0, true))
+	assert.True(t, IsUnixTimestamp(t, "1648800000", nil))
+	assert.True(t, IsUnixTimestamp(t, "2022-04-01", false))
+	mockT := new(testing.T)
+	assert.False(t, IsUnixTimestamp(mockT, -1, true))
+	assert.False(t, IsUnixTimestamp(mockT, true, true))
+	assert.False(t, IsUnixTimestamp(mockT, 1e20, true))
+}
+
+func TestExists(t *testing.T) {
+	assert.True(t, Exists(t, Field{Path: "body.token", Value: nil, Exists: true}, nil))
+	assert.True(t, Exists(t, Field{Path: "body.token"}, false))
+	assert.True(t, Exists(t, "abc", true))
+	assert.True(t, NotExists(t, Field{Path: "body.token"}, nil))
+	assert.True(t, NotExists(t, Field{Path: "body.token", Value: "", Exists: true}, false))
+	mockT := new(testing.T)
+	assert.False(t, Exists(mockT, Field{Path: "body.token"}, true))
+	assert.False(t, NotExists(mockT, Field{Path: "body.token", Value: nil, Exists: true}, true))
+	assert.False(t, Exists(mockT, Field{Path: "body.token"}, "yes"))
+}
+
+func TestStatusIn(t *testing.T) {
+	assert.True(t, StatusIn(t, 204, []interface{}{200, 204}))
+	assert.True(t, StatusIn(t, int64(201), "2xx"))
+	assert.True(t, StatusIn(t, 304, []interface{}{"2XX", "3xx"}))
+	assert.True(t, StatusIn(t, 404, []interface{}{"2xx", 404.0}))
+	assert.True(t, StatusIn(t, 500, "500"))
+	mockT := new(testing.T)
+	assert.False(t, StatusIn(mockT, 500, []interface{}{"2xx", 404}))
+	assert.False(t, StatusIn(mockT, 200, "ok"))
+	assert.False(t, StatusIn(mockT, "abc", "2xx"))
+}
+
+func TestTimeWithin(t *testing.T) {
+	now := time.Now()
+	assert.True(t, TimeWithin(t, now.Add(-10*time.Second).Format(time.RFC3339), "1m"))
+	assert.True(t, TimeWithin(t, now.Unix(), "5s"))
+	assert.True(t, TimeWithin(t, now.UnixNano()/int64(time.Millisecond), "5s"))
+	mockT := new(testing.T)
+	assert.False(t, TimeWithin(mockT, now.Add(-time.Hour).Format(time.RFC3339), "5m"))
+	assert.False(t, TimeWithin(mockT, now.Unix(), "five minutes"))
+	assert.False(t, TimeWithin(mockT, "yesterday", "24h"))
+}
+
 func TestRegexMatch(t *testing.T) {
 	testData := []struct {
 		raw      interface{}
@@ -189,3 +305,16 @@ func TestRegexMatch(t *testing.T) {
 		}
 	}
 }
+
+func TestJSONEquals(t *testing.T) {
+	actual := map[string]interface{}{"id": 1, "items": []interface{}{"a", "b"}}
+	if !assert.True(t, JSONEquals(t, actual, `{"items": ["a", "b"], "id": 1.0}`)) {
+		t.Fail()
+	}
+	if !assert.False(t, JSONEquals(&testing.T{}, actual, map[string]interface{}{"id": 2})) {
+		t.Fail()
+	}
+	if !assert.False(t, JSONEquals(&testing.T{}, actual, `{"id": `)) {
+		t.Fail()
+	}
+}
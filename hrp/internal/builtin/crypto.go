@@ -0,0 +1,337 @@
+package builtin
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// decodeBase64 decodes standard or url-safe base64, with or without padding.
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if strings.ContainsAny(s, "-_") {
+		return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	}
+	return base64.RawStdEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// base64Decode decodes standard or url-safe base64 into string, e.g. ${base64_decode($body)}
+func base64Decode(s string) (string, error) {
+	data, err := decodeBase64(s)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid base64")
+	}
+	return string(data), nil
+}
+
+// base64Encode encodes string in standard base64, e.g. ${base64_encode($hrp_request_body)}
+func base64Encode(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+// sha256Hex returns hex encoded SHA-256 digest, e.g. ${sha256($hrp_request_body)}
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// hmacSHA256 returns hex encoded HMAC-SHA256 of data, e.g. ${hmac_sha256($hrp_request_body, $secret)}
+func hmacSHA256(data, key string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(data))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// symmetricKey returns AES key of 16, 24 or 32 bytes, key is raw string of valid size,
+// otherwise base64 encoded, and prefix base64: forces decoding, e.g. base64:MDEyMzQ1Njc4OWFiY2RlZg==
+func symmetricKey(key string) ([]byte, error) {
+	if !strings.HasPrefix(key, "base64:") && isAESKeySize(len(key)) && !strings.HasSuffix(key, "=") {
+		return []byte(key), nil
+	}
+	if data, err := decodeBase64(strings.TrimPrefix(key, "base64:")); err == nil && isAESKeySize(len(data)) {
+		return data, nil
+	}
+	return nil, errors.New("AES key should be 16, 24 or 32 bytes, raw or base64 encoded")
+}
+
+func isAESKeySize(n int) bool {
+	return n == 16 || n == 24 || n == 32
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func gcmOpen(key, sealed []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize()+gcm.Overhead() {
+		return nil, errors.New("AES-GCM ciphertext is too short")
+	}
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "AES-GCM decrypt failed")
+	}
+	return plaintext, nil
+}
+
+func gcmSeal(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// aesGCMEncrypt encrypts plaintext with AES-GCM key and random nonce, returns base64 encoded nonce, ciphertext and tag,
+// e.g. ${aes_gcm_encrypt($hrp_request_body, $key)}
+func aesGCMEncrypt(plaintext, key string) (string, error) {
+	k, err := symmetricKey(key)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := gcmSeal(k, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// aesGCMDecrypt decrypts base64 encoded nonce, ciphertext and tag with AES-GCM key,
+// e.g. ${aes_gcm_decrypt($data, $key)}
+func aesGCMDecrypt(ciphertext, key string) (string, error) {
+	k, err := symmetricKey(key)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := decodeBase64(ciphertext)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid base64 ciphertext")
+	}
+	plaintext, err := gcmOpen(k, sealed)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// readPEM returns PEM content of key, key is PEM content or path of PEM file.
+func readPEM(key string) ([]byte, error) {
+	if strings.Contains(key, "-----BEGIN") {
+		return []byte(key), nil
+	}
+	data, err := os.ReadFile(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "read key file failed")
+	}
+	return data, nil
+}
+
+// loadRSAPublicKey loads RSA public key, certificate or private key in PEM, key is PEM content or path of PEM file.
+func loadRSAPublicKey(key string) (*rsa.PublicKey, error) {
+	data, err := readPEM(key)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := parsePEMKeys(data)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, ok := keys[0].Key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key of type %T is not RSA key", keys[0].Key)
+	}
+	return publicKey, nil
+}
+
+// loadRSAPrivateKey loads RSA private key in PEM, key is PEM content or path of PEM file.
+func loadRSAPrivateKey(key string) (*rsa.PrivateKey, error) {
+	data, err := readPEM(key)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found in private key")
+	}
+	if k, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return k, nil
+	}
+	k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse private key failed")
+	}
+	rsaKey, ok := k.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key of type %T is not RSA key", k)
+	}
+	return rsaKey, nil
+}
+
+// rsaDecryptBytes decrypts with RSA private key, padding is oaep (default, with SHA-256) or pkcs1.
+func rsaDecryptBytes(ciphertext []byte, key string, padding []string) ([]byte, error) {
+	privateKey, err := loadRSAPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	var plaintext []byte
+	switch p := rsaPadding(padding); p {
+	case "oaep":
+		plaintext, err = rsa.DecryptOAEP(sha256.New(), rand.Reader, privateKey, ciphertext, nil)
+	case "pkcs1":
+		plaintext, err = rsa.DecryptPKCS1v15(rand.Reader, privateKey, ciphertext)
+	default:
+		return nil, fmt.Errorf("RSA padding %s is not supported, expect oaep or pkcs1", p)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "RSA decrypt failed")
+	}
+	return plaintext, nil
+}
+
+func rsaPadding(padding []string) string {
+	if len(padding) == 0 || padding[0] == "" {
+		return "oaep"
+	}
+	return strings.ToLower(padding[0])
+}
+
+// rsaDecrypt decrypts base64 encoded ciphertext with RSA private key, e.g. ${rsa_decrypt($data, $private_key)},
+// padding is oaep (default) or pkcs1.
+func rsaDecrypt(ciphertext, key string, padding ...string) (string, error) {
+	data, err := decodeBase64(ciphertext)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid base64 ciphertext")
+	}
+	plaintext, err := rsaDecryptBytes(data, key, padding)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// rsaEnvelopeDecrypt decrypts AES key encrypted by RSA private key, and decrypts ciphertext with AES-GCM key,
+// both are base64 encoded, e.g. ${rsa_envelope_decrypt($encrypted_key, $data, $private_key)}
+func rsaEnvelopeDecrypt(encryptedKey, ciphertext, key string, padding ...string) (string, error) {
+	data, err := decodeBase64(encryptedKey)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid base64 encrypted key")
+	}
+	aesKey, err := rsaDecryptBytes(data, key, padding)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := decodeBase64(ciphertext)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid base64 ciphertext")
+	}
+	plaintext, err := gcmOpen(aesKey, sealed)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// rsaEncryptBytes encrypts with RSA public key, padding is oaep (default, with SHA-256) or pkcs1.
+func rsaEncryptBytes(plaintext []byte, key string, padding []string) ([]byte, error) {
+	publicKey, err := loadRSAPublicKey(key)
+	if err != nil {
+		return nil, err
+	}
+	var ciphertext []byte
+	switch p := rsaPadding(padding); p {
+	case "oaep":
+		ciphertext, err = rsa.EncryptOAEP(sha256.New(), rand.Reader, publicKey, plaintext, nil)
+	case "pkcs1":
+		ciphertext, err = rsa.EncryptPKCS1v15(rand.Reader, publicKey, plaintext)
+	default:
+		return nil, fmt.Errorf("RSA padding %s is not supported, expect oaep or pkcs1", p)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "RSA encrypt failed")
+	}
+	return ciphertext, nil
+}
+
+// rsaEncrypt encrypts plaintext with RSA public key and returns base64 encoded ciphertext,
+// e.g. ${rsa_encrypt($password, $public_key)}, padding is oaep (default) or pkcs1.
+func rsaEncrypt(plaintext, key string, padding ...string) (string, error) {
+	ciphertext, err := rsaEncryptBytes([]byte(plaintext), key, padding)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// rsaEnvelopeEncrypt encrypts plaintext with random AES-256-GCM key, and encrypts the key with RSA public key,
+// returns base64 encoded encrypted key and ciphertext, e.g. {"key": "...", "data": "..."}
+func rsaEnvelopeEncrypt(plaintext, key string, padding ...string) (map[string]interface{}, error) {
+	aesKey := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, aesKey); err != nil {
+		return nil, err
+	}
+	encryptedKey, err := rsaEncryptBytes(aesKey, key, padding)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := gcmSeal(aesKey, []byte(plaintext))
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"key":  base64.StdEncoding.EncodeToString(encryptedKey),
+		"data": base64.StdEncoding.EncodeToString(sealed),
+	}, nil
+}
+
+// rsaSign signs data with RSA private key in PKCS #1 v1.5 and returns base64 encoded signature,
+// e.g. ${rsa_sign($hrp_request_body, $private_key)}, hash is sha256 (default), sha384 or sha512.
+func rsaSign(data, key string, hash ...string) (string, error) {
+	privateKey, err := loadRSAPrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+	h := crypto.SHA256
+	if len(hash) > 0 && hash[0] != "" {
+		switch strings.ToLower(hash[0]) {
+		case "sha256":
+		case "sha384":
+			h = crypto.SHA384
+		case "sha512":
+			h = crypto.SHA512
+		default:
+			return "", fmt.Errorf("hash %s is not supported, expect sha256, sha384 or sha512", hash[0])
+		}
+	}
+	digest := h.New()
+	digest.Write([]byte(data))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, privateKey, h, digest.Sum(nil))
+	if err != nil {
+		return "", errors.Wrap(err, "RSA sign failed")
+	}
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
@@ -0,0 +1,140 @@
+package builtin
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAESGCMDecrypt(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	gcm, _ := newGCM(key)
+	nonce := make([]byte, gcm.NonceSize())
+	rand.Read(nonce)
+	sealed := gcm.Seal(nonce, nonce, []byte(`{"user":"alice"}`), nil)
+
+	for _, k := range []string{string(key), base64.StdEncoding.EncodeToString(key), "base64:" + base64.RawStdEncoding.EncodeToString(key)} {
+		for _, ciphertext := range []string{base64.StdEncoding.EncodeToString(sealed), base64.RawURLEncoding.EncodeToString(sealed)} {
+			plaintext, err := aesGCMDecrypt(ciphertext, k)
+			if assert.Nil(t, err) {
+				assert.Equal(t, `{"user":"alice"}`, plaintext)
+			}
+		}
+	}
+
+	_, err := aesGCMDecrypt(base64.StdEncoding.EncodeToString(sealed), "short")
+	assert.NotNil(t, err)
+	_, err = aesGCMDecrypt(base64.StdEncoding.EncodeToString(sealed), "fedcba9876543210")
+	assert.NotNil(t, err)
+	short := []byte("0123456789abcdef")
+	_, err = symmetricKey(base64.StdEncoding.EncodeToString(short))
+	assert.Nil(t, err, "base64 of 16 bytes key is 24 bytes with padding")
+	_, err = aesGCMDecrypt("AAAA", string(key))
+	assert.NotNil(t, err)
+}
+
+func TestRSADecrypt(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	keyPEM := string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}))
+	pkcs8, _ := x509.MarshalPKCS8PrivateKey(rsaKey)
+	keyPath := filepath.Join(t.TempDir(), "private.pem")
+	os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}), 0o600)
+
+	oaep, _ := rsa.EncryptOAEP(sha256.New(), rand.Reader, &rsaKey.PublicKey, []byte("hello"), nil)
+	plaintext, err := rsaDecrypt(base64.StdEncoding.EncodeToString(oaep), keyPEM)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "hello", plaintext)
+	}
+	pkcs1, _ := rsa.EncryptPKCS1v15(rand.Reader, &rsaKey.PublicKey, []byte("hello"))
+	plaintext, err = rsaDecrypt(base64.StdEncoding.EncodeToString(pkcs1), keyPath, "pkcs1")
+	if assert.Nil(t, err) {
+		assert.Equal(t, "hello", plaintext)
+	}
+	_, err = rsaDecrypt(base64.StdEncoding.EncodeToString(pkcs1), keyPEM, "oaep")
+	assert.NotNil(t, err)
+	_, err = rsaDecrypt(base64.StdEncoding.EncodeToString(pkcs1), keyPEM, "none")
+	assert.NotNil(t, err)
+
+	// envelope of AES key encrypted by RSA public key
+	aesKey := make([]byte, 32)
+	rand.Read(aesKey)
+	encryptedKey, _ := rsa.EncryptOAEP(sha256.New(), rand.Reader, &rsaKey.PublicKey, aesKey, nil)
+	gcm, _ := newGCM(aesKey)
+	nonce := make([]byte, gcm.NonceSize())
+	sealed := gcm.Seal(nonce, nonce, []byte("secret payload"), nil)
+	plaintext, err = rsaEnvelopeDecrypt(base64.StdEncoding.EncodeToString(encryptedKey),
+		base64.StdEncoding.EncodeToString(sealed), keyPEM)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "secret payload", plaintext)
+	}
+}
+
+func TestBase64Decode(t *testing.T) {
+	for _, s := range []string{"aGk/Pz4=", "aGk/Pz4", "aGk_Pz4"} {
+		decoded, err := base64Decode(s)
+		if assert.Nil(t, err, s) {
+			assert.Equal(t, "hi??>", decoded)
+		}
+	}
+	_, err := base64Decode("!!")
+	assert.NotNil(t, err)
+}
+
+func TestEncryptAndSign(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString([]byte("0123456789abcdef"))
+	ciphertext, err := aesGCMEncrypt(`{"user":"alice"}`, key)
+	if assert.Nil(t, err) {
+		plaintext, err := aesGCMDecrypt(ciphertext, key)
+		assert.Nil(t, err)
+		assert.Equal(t, `{"user":"alice"}`, plaintext)
+	}
+
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	privatePEM := string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}))
+	pub, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	publicPEM := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub}))
+
+	for _, padding := range []string{"oaep", "pkcs1"} {
+		ciphertext, err = rsaEncrypt("password", publicPEM, padding)
+		if assert.Nil(t, err, padding) {
+			plaintext, err := rsaDecrypt(ciphertext, privatePEM, padding)
+			assert.Nil(t, err, padding)
+			assert.Equal(t, "password", plaintext)
+		}
+	}
+
+	envelope, err := rsaEnvelopeEncrypt("secret payload", publicPEM)
+	if assert.Nil(t, err) {
+		plaintext, err := rsaEnvelopeDecrypt(envelope["key"].(string), envelope["data"].(string), privatePEM)
+		assert.Nil(t, err)
+		assert.Equal(t, "secret payload", plaintext)
+	}
+
+	for hash, h := range map[string]crypto.Hash{"": crypto.SHA256, "sha512": crypto.SHA512} {
+		signature, err := rsaSign("payload", privatePEM, hash)
+		if !assert.Nil(t, err) {
+			continue
+		}
+		sig, _ := base64.StdEncoding.DecodeString(signature)
+		digest := h.New()
+		digest.Write([]byte("payload"))
+		assert.Nil(t, rsa.VerifyPKCS1v15(&rsaKey.PublicKey, h, digest.Sum(nil), sig))
+	}
+	_, err = rsaSign("payload", privatePEM, "md5")
+	assert.NotNil(t, err)
+
+	assert.Equal(t, "cGF5bG9hZA==", base64Encode("payload"))
+	assert.Equal(t, "239f59ed55e737c77147cf55ad0c1b030b6d7ee748a7426952f9b852d5a935e5", sha256Hex("payload"))
+	// RFC 4231 test case 2
+	assert.Equal(t, "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843",
+		hmacSHA256("what do ya want for nothing?", "Jefe"))
+}
@@ -0,0 +1,319 @@
+package builtin
+
+import (
+	"bytes"
+	builtinJSON "encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/httprunner/httprunner/hrp/internal/json"
+)
+
+// JSONDiff represents one mismatched field between two json structures.
+type JSONDiff struct {
+	Path     string      `json:"path" yaml:"path"`
+	Expected interface{} `json:"expected" yaml:"expected"`
+	Actual   interface{} `json:"actual" yaml:"actual"`
+}
+
+func (d JSONDiff) String() string {
+	return fmt.Sprintf("%s: expected %s, got %s", d.Path, formatDiffValue(d.Expected), formatDiffValue(d.Actual))
+}
+
+type missingValue struct{}
+
+func (missingValue) MarshalJSON() ([]byte, error) {
+	return []byte(`"<missing>"`), nil
+}
+
+// Missing is used as JSONDiff value when the field does not exist on one side.
+var Missing = missingValue{}
+
+func formatDiffValue(v interface{}) string {
+	if v == Missing {
+		return "<missing>"
+	}
+	b, err := builtinJSON.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(b)
+}
+
+// DiffJSON deep compares two json structures and returns all mismatched fields,
+// numbers are compared by value regardless of their go types.
+func DiffJSON(expected, actual interface{}) []JSONDiff {
+	return diffJSON("", expected, actual)
+}
+
+func diffJSON(path string, expected, actual interface{}) (diffs []JSONDiff) {
+	expectedMap, ok1 := toStringMap(expected)
+	actualMap, ok2 := toStringMap(actual)
+	if ok1 && ok2 {
+		keys := make(map[string]struct{})
+		for k := range expectedMap {
+			keys[k] = struct{}{}
+		}
+		for k := range actualMap {
+			keys[k] = struct{}{}
+		}
+		sortedKeys := make([]string, 0, len(keys))
+		for k := range keys {
+			sortedKeys = append(sortedKeys, k)
+		}
+		sort.Strings(sortedKeys)
+
+		for _, k := range sortedKeys {
+			subPath := joinJSONPath(path, k)
+			e, eOK := expectedMap[k]
+			a, aOK := actualMap[k]
+			switch {
+			case !eOK:
+				diffs = append(diffs, JSONDiff{Path: subPath, Expected: Missing, Actual: a})
+			case !aOK:
+				diffs = append(diffs, JSONDiff{Path: subPath, Expected: e, Actual: Missing})
+			default:
+				diffs = append(diffs, diffJSON(subPath, e, a)...)
+			}
+		}
+		return diffs
+	}
+
+	expectedSlice, ok1 := toSlice(expected)
+	actualSlice, ok2 := toSlice(actual)
+	if ok1 && ok2 {
+		length := len(expectedSlice)
+		if len(actualSlice) > length {
+			length = len(actualSlice)
+		}
+		for i := 0; i < length; i++ {
+			subPath := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(expectedSlice):
+				diffs = append(diffs, JSONDiff{Path: subPath, Expected: Missing, Actual: actualSlice[i]})
+			case i >= len(actualSlice):
+				diffs = append(diffs, JSONDiff{Path: subPath, Expected: expectedSlice[i], Actual: Missing})
+			default:
+				diffs = append(diffs, diffJSON(subPath, expectedSlice[i], actualSlice[i])...)
+			}
+		}
+		return diffs
+	}
+
+	if !equalJSONValue(expected, actual) {
+		if path == "" {
+			path = "@"
+		}
+		diffs = append(diffs, JSONDiff{Path: path, Expected: expected, Actual: actual})
+	}
+	return diffs
+}
+
+func equalJSONValue(expected, actual interface{}) bool {
+	if e, ok := toNumber(expected); ok {
+		if a, ok := toNumber(actual); ok {
+			return e == a
+		}
+		return false
+	}
+	return reflect.DeepEqual(expected, actual)
+}
+
+func toNumber(v interface{}) (float64, bool) {
+	if n, ok := v.(builtinJSON.Number); ok {
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+func toStringMap(v interface{}) (map[string]interface{}, bool) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Map {
+		return nil, false
+	}
+	m := make(map[string]interface{}, rv.Len())
+	for _, k := range rv.MapKeys() {
+		m[fmt.Sprintf("%v", k.Interface())] = rv.MapIndex(k).Interface()
+	}
+	return m, true
+}
+
+func toSlice(v interface{}) ([]interface{}, bool) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, false
+	}
+	s := make([]interface{}, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		s[i] = rv.Index(i).Interface()
+	}
+	return s, true
+}
+
+func joinJSONPath(path, key string) string {
+	if strings.ContainsAny(key, ".-[] \"") {
+		key = strconv.Quote(key)
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// NormalizeJSON returns a deep copy of value in generic json structure,
+// numbers are decoded as json.Number. If value is a string of json object
+// or array, it will be decoded.
+func NormalizeJSON(value interface{}) (interface{}, error) {
+	var data []byte
+	if s, ok := value.(string); ok {
+		trimmed := strings.TrimSpace(s)
+		if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+			return s, nil
+		}
+		data = []byte(trimmed)
+	} else {
+		var err error
+		data, err = json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var result interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type jsonPathToken struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// parseJSONPath parses simple jmespath field expressions,
+// e.g. body.data.id, body.items[0].name, body.items[*].ts, headers."Content-Type"
+func parseJSONPath(path string) ([]jsonPathToken, error) {
+	var tokens []jsonPathToken
+	i := 0
+	for i < len(path) {
+		switch c := path[i]; {
+		case c == '.':
+			i++
+		case c == '"':
+			end := strings.IndexByte(path[i+1:], '"')
+			if end == -1 {
+				return nil, fmt.Errorf("unclosed quote in path: %s", path)
+			}
+			tokens = append(tokens, jsonPathToken{key: path[i+1 : i+1+end]})
+			i += end + 2
+		case c == '[':
+			end := strings.IndexByte(path[i:], ']')
+			if end == -1 {
+				return nil, fmt.Errorf("unclosed bracket in path: %s", path)
+			}
+			content := path[i+1 : i+end]
+			if content == "*" {
+				tokens = append(tokens, jsonPathToken{isIndex: true, wildcard: true})
+			} else {
+				index, err := strconv.Atoi(content)
+				if err != nil {
+					return nil, fmt.Errorf("invalid index %s in path: %s", content, path)
+				}
+				tokens = append(tokens, jsonPathToken{isIndex: true, index: index})
+			}
+			i += end + 1
+		default:
+			end := strings.IndexAny(path[i:], ".[")
+			if end == -1 {
+				end = len(path) - i
+			}
+			key := path[i : i+end]
+			if key == "*" {
+				tokens = append(tokens, jsonPathToken{wildcard: true})
+			} else {
+				tokens = append(tokens, jsonPathToken{key: key})
+			}
+			i += end
+		}
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty path")
+	}
+	return tokens, nil
+}
+
+// RemoveJSONPaths deletes fields located by paths from data in place.
+// Only simple jmespath field expressions are supported, see parseJSONPath.
+func RemoveJSONPaths(data interface{}, paths ...string) error {
+	for _, path := range paths {
+		tokens, err := parseJSONPath(path)
+		if err != nil {
+			return err
+		}
+		removeJSONPath(data, tokens)
+	}
+	return nil
+}
+
+func removeJSONPath(data interface{}, tokens []jsonPathToken) {
+	token := tokens[0]
+	last := len(tokens) == 1
+	switch v := data.(type) {
+	case map[string]interface{}:
+		if token.isIndex {
+			return
+		}
+		if token.wildcard {
+			for k, value := range v {
+				if last {
+					delete(v, k)
+				} else {
+					removeJSONPath(value, tokens[1:])
+				}
+			}
+			return
+		}
+		value, ok := v[token.key]
+		if !ok {
+			return
+		}
+		if last {
+			delete(v, token.key)
+		} else {
+			removeJSONPath(value, tokens[1:])
+		}
+	case []interface{}:
+		if !token.isIndex {
+			return
+		}
+		for i, value := range v {
+			if !token.wildcard && i != token.index {
+				continue
+			}
+			if last {
+				// slice length is kept, removed elements are replaced with nil
+				v[i] = nil
+			} else {
+				removeJSONPath(value, tokens[1:])
+			}
+		}
+	}
+}
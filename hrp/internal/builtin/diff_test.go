@@ -0,0 +1,61 @@
+package builtin
+
+import (
+	builtinJSON "encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffJSON(t *testing.T) {
+	expected := map[string]interface{}{
+		"id":    1,
+		"name":  "hrp",
+		"tags":  []interface{}{"a", "b"},
+		"extra": true,
+	}
+	actual := map[string]interface{}{
+		"id":   builtinJSON.Number("1"),
+		"name": "httprunner",
+		"tags": []interface{}{"a"},
+	}
+	diffs := DiffJSON(expected, actual)
+	if !assert.Len(t, diffs, 3) {
+		t.Fatal()
+	}
+	assert.Equal(t, "extra", diffs[0].Path)
+	assert.Equal(t, Missing, diffs[0].Actual)
+	assert.Equal(t, `name: expected "hrp", got "httprunner"`, diffs[1].String())
+	assert.Equal(t, "tags[1]", diffs[2].Path)
+
+	assert.Empty(t, DiffJSON([]interface{}{1.0, "a"}, []interface{}{1, "a"}))
+}
+
+func TestRemoveJSONPaths(t *testing.T) {
+	data := map[string]interface{}{
+		"body": map[string]interface{}{
+			"id": 1,
+			"items": []interface{}{
+				map[string]interface{}{"ts": 1, "name": "a"},
+				map[string]interface{}{"ts": 2, "name": "b"},
+			},
+		},
+		"headers": map[string]interface{}{"Content-Type": "application/json", "Date": "today"},
+	}
+	err := RemoveJSONPaths(data, "body.id", "body.items[*].ts", `headers."Date"`, "body.not_exist")
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	expected := map[string]interface{}{
+		"body": map[string]interface{}{
+			"items": []interface{}{
+				map[string]interface{}{"name": "a"},
+				map[string]interface{}{"name": "b"},
+			},
+		},
+		"headers": map[string]interface{}{"Content-Type": "application/json"},
+	}
+	assert.Equal(t, expected, data)
+
+	assert.NotNil(t, RemoveJSONPaths(data, "body.items[x]"))
+}
@@ -0,0 +1,166 @@
+package builtin
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+
+	"github.com/brianvoe/gofakeit/v6"
+)
+
+// faker generates realistic test data with gofakeit, which is seedable,
+// data generated in the same order with the same seed are reproducible.
+// gofakeit provides data of US only, data of CN are picked from built-in lists with the same random source.
+var faker = &fakerSource{faker: gofakeit.NewUnlocked(0)}
+
+type fakerSource struct {
+	mutex sync.Mutex
+	faker *gofakeit.Faker
+	seq   int // sequence number making generated emails and usernames unique
+}
+
+// SetFakerSeed resets random source of fake_* functions with seed, thus generated data are reproducible.
+func SetFakerSeed(seed int64) {
+	faker.mutex.Lock()
+	defer faker.mutex.Unlock()
+	// gofakeit.New regards seed 0 as random seed, thus random source is created directly
+	faker.faker = &gofakeit.Faker{Rand: rand.New(rand.NewSource(seed))}
+	faker.seq = 0
+}
+
+// generate calls fn with faker exclusively, thus data generated by fn are reproducible with the same seed.
+func (f *fakerSource) generate(fn func(faker *gofakeit.Faker) string) string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return fn(f.faker)
+}
+
+func (f *fakerSource) next() int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.seq++
+	return f.seq
+}
+
+var (
+	fakeEmailDomains = []string{"example.com", "example.net", "example.org"}
+
+	fakeCNLastNames = []string{
+		"王", "李", "张", "刘", "陈", "杨", "黄", "赵", "吴", "周", "徐", "孙", "马", "朱", "胡", "郭", "何", "林", "罗", "高",
+	}
+	fakeCNFirstNames = []string{
+		"伟", "芳", "娜", "敏", "静", "磊", "洋", "艳", "勇", "军", "杰", "娟", "涛", "明", "超", "秀英", "丽", "强",
+		"浩然", "子涵", "欣怡", "梓轩", "雨桐", "宇航", "思远", "佳怡", "俊杰", "晓明",
+	}
+	fakeCNCities = []string{
+		"北京市|朝阳区", "上海市|浦东新区", "广东省深圳市|南山区", "广东省广州市|天河区", "浙江省杭州市|西湖区",
+		"江苏省南京市|鼓楼区", "四川省成都市|武侯区", "湖北省武汉市|洪山区", "陕西省西安市|雁塔区", "福建省厦门市|思明区",
+	}
+	fakeCNStreets = []string{
+		"建国路", "人民路", "中山路", "解放路", "长安街", "科技园路", "文化路", "和平路", "滨江大道", "南京路",
+	}
+	fakeCNMobilePrefixes = []string{
+		"130", "131", "132", "133", "135", "136", "137", "138", "139", "150",
+		"151", "152", "155", "157", "158", "159", "177", "180", "186", "188", "189", "199",
+	}
+)
+
+// fakeLocale returns locale in upper case, US by default, only US and CN are supported.
+func fakeLocale(locale []string) (string, error) {
+	if len(locale) == 0 || locale[0] == "" {
+		return "US", nil
+	}
+	switch l := strings.ToUpper(locale[0]); l {
+	case "US", "CN":
+		return l, nil
+	default:
+		return "", fmt.Errorf("locale %s not supported, expect US or CN", locale[0])
+	}
+}
+
+func fakeFirstName(locale ...string) (string, error) {
+	l, err := fakeLocale(locale)
+	if err != nil {
+		return "", err
+	}
+	return faker.generate(func(f *gofakeit.Faker) string {
+		if l == "CN" {
+			return f.RandomString(fakeCNFirstNames)
+		}
+		return f.FirstName()
+	}), nil
+}
+
+func fakeLastName(locale ...string) (string, error) {
+	l, err := fakeLocale(locale)
+	if err != nil {
+		return "", err
+	}
+	return faker.generate(func(f *gofakeit.Faker) string {
+		if l == "CN" {
+			return f.RandomString(fakeCNLastNames)
+		}
+		return f.LastName()
+	}), nil
+}
+
+func fakeName(locale ...string) (string, error) {
+	l, err := fakeLocale(locale)
+	if err != nil {
+		return "", err
+	}
+	return faker.generate(func(f *gofakeit.Faker) string {
+		if l == "CN" {
+			return f.RandomString(fakeCNLastNames) + f.RandomString(fakeCNFirstNames)
+		}
+		return f.Name()
+	}), nil
+}
+
+// fakeUsername returns unique username, e.g. james.smith42
+func fakeUsername() string {
+	name := faker.generate(func(f *gofakeit.Faker) string {
+		return strings.ToLower(f.FirstName() + "." + f.LastName())
+	})
+	return fmt.Sprintf("%s%d", name, faker.next())
+}
+
+// fakeEmail returns unique email address of reserved example domains, e.g. james.smith42@example.com
+func fakeEmail() string {
+	domain := faker.generate(func(f *gofakeit.Faker) string {
+		return f.RandomString(fakeEmailDomains)
+	})
+	return fakeUsername() + "@" + domain
+}
+
+// fakePhone returns phone number, e.g. +1-415-555-0123 for US, 13812345678 for CN mobile.
+func fakePhone(locale ...string) (string, error) {
+	l, err := fakeLocale(locale)
+	if err != nil {
+		return "", err
+	}
+	return faker.generate(func(f *gofakeit.Faker) string {
+		if l == "CN" {
+			return f.RandomString(fakeCNMobilePrefixes) + f.Numerify("########")
+		}
+		// area code and exchange code should not start with 0 or 1
+		return fmt.Sprintf("+1-%d%s-%d%s-%s", f.Number(2, 9), f.Numerify("##"),
+			f.Number(2, 9), f.Numerify("##"), f.Numerify("####"))
+	}), nil
+}
+
+// fakeAddress returns street address, e.g. 1234 Maple Street, Austin, TX 73301
+func fakeAddress(locale ...string) (string, error) {
+	l, err := fakeLocale(locale)
+	if err != nil {
+		return "", err
+	}
+	return faker.generate(func(f *gofakeit.Faker) string {
+		if l == "CN" {
+			city := strings.Split(f.RandomString(fakeCNCities), "|")
+			return fmt.Sprintf("%s%s%s%d号", city[0], city[1], f.RandomString(fakeCNStreets), f.Number(1, 999))
+		}
+		return fmt.Sprintf("%s, %s, %s %s", f.Street(), f.City(), f.StateAbr(), f.Zip())
+	}), nil
+}
@@ -0,0 +1,68 @@
+package builtin
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFakerFunctions(t *testing.T) {
+	name, err := fakeName()
+	if assert.Nil(t, err) {
+		assert.Regexp(t, `^[A-Z][A-Za-z]+ [A-Z][A-Za-z]+$`, name)
+	}
+	name, err = fakeName("cn")
+	if assert.Nil(t, err) {
+		assert.GreaterOrEqual(t, len([]rune(name)), 2)
+	}
+	_, err = fakeName("JP")
+	assert.NotNil(t, err)
+
+	phone, err := fakePhone()
+	if assert.Nil(t, err) {
+		assert.Regexp(t, `^\+1-[2-9]\d{2}-[2-9]\d{2}-\d{4}$`, phone)
+	}
+	phone, err = fakePhone("CN")
+	if assert.Nil(t, err) {
+		assert.Regexp(t, `^1[3-9]\d{9}$`, phone)
+	}
+	address, err := fakeAddress()
+	if assert.Nil(t, err) {
+		assert.Regexp(t, `^\d+ [\w ]+, [\w ]+, [A-Z]{2} \d{5}$`, address)
+	}
+	address, err = fakeAddress("CN")
+	if assert.Nil(t, err) {
+		assert.Regexp(t, `号$`, address)
+	}
+
+	// emails are unique
+	emailRegex := regexp.MustCompile(`^[a-z]+\.[a-z]+\d+@example\.(com|net|org)$`)
+	emails := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		email := fakeEmail()
+		assert.Regexp(t, emailRegex, email)
+		emails[email] = true
+	}
+	assert.Len(t, emails, 100)
+}
+
+func TestSetFakerSeed(t *testing.T) {
+	generate := func() []string {
+		name, _ := fakeName()
+		phone, _ := fakePhone("CN")
+		address, _ := fakeAddress()
+		return []string{name, fakeEmail(), phone, address, fakeUsername()}
+	}
+	SetFakerSeed(42)
+	data := generate()
+	SetFakerSeed(42)
+	assert.Equal(t, data, generate())
+	SetFakerSeed(43)
+	assert.NotEqual(t, data, generate())
+	// seed 0 is also reproducible
+	SetFakerSeed(0)
+	data = generate()
+	SetFakerSeed(0)
+	assert.Equal(t, data, generate())
+}
@@ -9,13 +9,36 @@ import (
 )
 
 var Functions = map[string]interface{}{
-	"get_timestamp":     getTimestamp,    // call without arguments
-	"sleep":             sleep,           // call with one argument
-	"gen_random_string": genRandomString, // call with one argument
-	"max":               math.Max,        // call with two arguments
-	"md5":               MD5,             // call with one argument
-	"parameterize":      loadFromCSV,
-	"P":                 loadFromCSV,
+	"get_timestamp":        getTimestamp,    // call without arguments
+	"sleep":                sleep,           // call with one argument
+	"gen_random_string":    genRandomString, // call with one argument
+	"max":                  math.Max,        // call with two arguments
+	"md5":                  MD5,             // call with one argument
+	"parameterize":         loadFromCSV,
+	"P":                    loadFromCSV,
+	"fake_name":            fakeName,           // call with optional locale, US (default) or CN
+	"fake_first_name":      fakeFirstName,      // call with optional locale
+	"fake_last_name":       fakeLastName,       // call with optional locale
+	"fake_username":        fakeUsername,       // call without arguments
+	"fake_email":           fakeEmail,          // call without arguments
+	"fake_phone":           fakePhone,          // call with optional locale
+	"fake_address":         fakeAddress,        // call with optional locale
+	"vault":                vault,              // call with secret path and key
+	"aws_sm":               awsSecretsManager,  // call with secret name and optional key of json secret
+	"decrypt_secret":       decryptSecret,      // call with secret encrypted by hrp encrypt
+	"jwt_decode":           jwtDecode,          // call with jwt, returns header, claims and signature
+	"jwt_verify":           jwtVerify,          // call with jwt and key, which is HMAC secret, PEM, JWK(S) or url of JWKS
+	"base64_encode":        base64Encode,       // call with string
+	"base64_decode":        base64Decode,       // call with standard or url-safe base64
+	"sha256":               sha256Hex,          // call with string, returns hex digest
+	"hmac_sha256":          hmacSHA256,         // call with data and key, returns hex digest
+	"aes_gcm_encrypt":      aesGCMEncrypt,      // call with plaintext and AES key
+	"aes_gcm_decrypt":      aesGCMDecrypt,      // call with base64 ciphertext and AES key
+	"rsa_encrypt":          rsaEncrypt,         // call with plaintext, public key and optional padding
+	"rsa_decrypt":          rsaDecrypt,         // call with base64 ciphertext, private key and optional padding
+	"rsa_envelope_encrypt": rsaEnvelopeEncrypt, // call with plaintext, public key and optional padding, returns key and data
+	"rsa_envelope_decrypt": rsaEnvelopeDecrypt, // call with base64 encrypted AES key, ciphertext, private key and optional padding
+	"rsa_sign":             rsaSign,            // call with data, private key and optional hash, returns base64 signature
 }
 
 func init() {
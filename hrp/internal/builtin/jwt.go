@@ -0,0 +1,446 @@
+package builtin
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/httprunner/httprunner/hrp/internal/json"
+)
+
+// JWT is decoded json web token in compact serialization, e.g. eyJhbGciOi...
+type JWT struct {
+	Header       map[string]interface{}
+	Claims       map[string]interface{}
+	Signature    []byte
+	signingInput string
+}
+
+// ParseJWT decodes header and claims of json web token without verification,
+// token can be prefixed with Bearer as value of Authorization header.
+func ParseJWT(token string) (*JWT, error) {
+	token = strings.TrimSpace(token)
+	if len(token) > 7 && strings.EqualFold(token[:7], "bearer ") {
+		token = strings.TrimSpace(token[7:])
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("invalid jwt: expect 3 parts separated by dot")
+	}
+	jwt := &JWT{signingInput: parts[0] + "." + parts[1]}
+	if err := decodeJWTPart(parts[0], &jwt.Header); err != nil {
+		return nil, errors.Wrap(err, "invalid jwt header")
+	}
+	if err := decodeJWTPart(parts[1], &jwt.Claims); err != nil {
+		return nil, errors.Wrap(err, "invalid jwt claims")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[2], "="))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid jwt signature")
+	}
+	jwt.Signature = signature
+	return jwt, nil
+}
+
+func decodeJWTPart(part string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(part, "="))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Algorithm returns alg of jwt header, e.g. HS256, RS256, ES256.
+func (t *JWT) Algorithm() string {
+	alg, _ := t.Header["alg"].(string)
+	return alg
+}
+
+// Map returns header, claims and base64url encoded signature of jwt, which are located by jmespath.
+func (t *JWT) Map() map[string]interface{} {
+	return map[string]interface{}{
+		"header":    t.Header,
+		"claims":    t.Claims,
+		"signature": base64.RawURLEncoding.EncodeToString(t.Signature),
+	}
+}
+
+// TimeClaim returns numeric date claim of jwt, e.g. exp, nbf and iat.
+func (t *JWT) TimeClaim(name string) (time.Time, bool) {
+	seconds, ok := t.Claims[name].(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(0, int64(seconds*float64(time.Second))), true
+}
+
+// Verify verifies signature of jwt with one of keys, keys are selected by kid of jwt header if set.
+func (t *JWT) Verify(keys []*JWTKey) error {
+	alg := t.Algorithm()
+	if alg == "" || strings.EqualFold(alg, "none") {
+		return errors.New("unsecured jwt is not allowed")
+	}
+	kid, _ := t.Header["kid"].(string)
+	var lastErr error
+	for _, key := range keys {
+		if kid != "" && key.ID != "" && key.ID != kid {
+			continue
+		}
+		if key.Algorithm != "" && key.Algorithm != alg {
+			continue
+		}
+		if lastErr = verifyJWTSignature(alg, key.Key, t.signingInput, t.Signature); lastErr == nil {
+			return nil
+		}
+	}
+	if lastErr == nil {
+		return fmt.Errorf("no key found for jwt with alg %s and kid %s", alg, kid)
+	}
+	return lastErr
+}
+
+func verifyJWTSignature(alg string, key interface{}, signingInput string, signature []byte) error {
+	// alg of signature is EdDSA or family with hash size, e.g. HS256, which is taken from token header
+	if alg != "EdDSA" && len(alg) != 5 {
+		return fmt.Errorf("jwt alg %s is not supported", alg)
+	}
+	var hash crypto.Hash
+	switch alg[len(alg)-3:] {
+	case "256":
+		hash = crypto.SHA256
+	case "384":
+		hash = crypto.SHA384
+	case "512":
+		hash = crypto.SHA512
+	}
+	digest := func() []byte {
+		h := hash.New()
+		h.Write([]byte(signingInput))
+		return h.Sum(nil)
+	}
+
+	errInvalid := errors.New("jwt signature is invalid")
+	errKeyType := fmt.Errorf("key of type %T can not verify jwt with alg %s", key, alg)
+	switch {
+	case alg == "EdDSA":
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return errKeyType
+		}
+		if !ed25519.Verify(pub, []byte(signingInput), signature) {
+			return errInvalid
+		}
+		return nil
+	case hash == 0:
+		return fmt.Errorf("jwt alg %s is not supported", alg)
+	}
+	switch alg[:2] {
+	case "HS":
+		secret, ok := key.([]byte)
+		if !ok {
+			return errKeyType
+		}
+		mac := hmac.New(hash.New, secret)
+		mac.Write([]byte(signingInput))
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return errInvalid
+		}
+		return nil
+	case "RS", "PS":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errKeyType
+		}
+		var err error
+		if alg[:2] == "RS" {
+			err = rsa.VerifyPKCS1v15(pub, hash, digest(), signature)
+		} else {
+			err = rsa.VerifyPSS(pub, hash, digest(), signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto})
+		}
+		if err != nil {
+			return errInvalid
+		}
+		return nil
+	case "ES":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return errKeyType
+		}
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return errInvalid
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(pub, digest(), r, s) {
+			return errInvalid
+		}
+		return nil
+	}
+	return fmt.Errorf("jwt alg %s is not supported", alg)
+}
+
+// JWTKey is key verifying jwt signature, Key is []byte of HMAC secret,
+// *rsa.PublicKey, *ecdsa.PublicKey or ed25519.PublicKey.
+type JWTKey struct {
+	ID        string
+	Algorithm string
+	Key       interface{}
+}
+
+// LoadJWTKeys loads keys verifying jwt, key can be url of JWKS, JWK or JWKS in json,
+// public key, certificate or private key in PEM, otherwise key is used as HMAC secret.
+func LoadJWTKeys(key string) ([]*JWTKey, error) {
+	trimmed := strings.TrimSpace(key)
+	switch {
+	case strings.HasPrefix(trimmed, "http://") || strings.HasPrefix(trimmed, "https://"):
+		return FetchJWKS(trimmed)
+	case strings.HasPrefix(trimmed, "{"):
+		return parseJWKS([]byte(trimmed))
+	case strings.HasPrefix(trimmed, "-----BEGIN"):
+		return parsePEMKeys([]byte(trimmed))
+	}
+	return []*JWTKey{{Key: []byte(key)}}, nil
+}
+
+func parsePEMKeys(data []byte) ([]*JWTKey, error) {
+	var keys []*JWTKey
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		key, err := parsePEMKey(block)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, &JWTKey{Key: key})
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no key found in PEM")
+	}
+	return keys, nil
+}
+
+func parsePEMKey(block *pem.Block) (interface{}, error) {
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		if cert, err = x509.ParseCertificate(block.Bytes); err == nil {
+			key = cert.PublicKey
+		}
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse PEM block %s failed", block.Type)
+	}
+	// public key is used to verify signature
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return &k.PublicKey, nil
+	case *ecdsa.PrivateKey:
+		return &k.PublicKey, nil
+	case ed25519.PrivateKey:
+		return k.Public(), nil
+	}
+	return key, nil
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+// parseJWKS parses JWK or JWKS in json, keys not for signature or of unknown types are ignored.
+func parseJWKS(data []byte) ([]*JWTKey, error) {
+	var set struct {
+		Keys []*jwk `json:"keys"`
+		jwk
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, errors.Wrap(err, "invalid JWKS")
+	}
+	if set.Keys == nil {
+		set.Keys = []*jwk{&set.jwk}
+	}
+	var keys []*JWTKey
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid JWK %s", k.Kid)
+		}
+		if key != nil {
+			keys = append(keys, &JWTKey{ID: k.Kid, Algorithm: k.Alg, Key: key})
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no signature key found in JWKS")
+	}
+	return keys, nil
+}
+
+func (k *jwk) publicKey() (interface{}, error) {
+	decode := func(s string) []byte {
+		b, _ := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+		return b
+	}
+	switch k.Kty {
+	case "RSA":
+		n, e := decode(k.N), decode(k.E)
+		if len(n) == 0 || len(e) == 0 {
+			return nil, errors.New("n and e are required for RSA key")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("curve %s is not supported", k.Crv)
+		}
+		x, y := new(big.Int).SetBytes(decode(k.X)), new(big.Int).SetBytes(decode(k.Y))
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		x := decode(k.X)
+		if k.Crv != "Ed25519" || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("curve %s is not supported", k.Crv)
+		}
+		return ed25519.PublicKey(x), nil
+	case "oct":
+		return decode(k.K), nil
+	}
+	return nil, nil
+}
+
+var (
+	jwksCache      sync.Map // url -> []*JWTKey
+	jwksHTTPClient = &http.Client{Timeout: 30 * time.Second}
+)
+
+// FetchJWKS fetches JWKS from url, keys are cached until refreshed by RefreshJWKS.
+func FetchJWKS(url string) ([]*JWTKey, error) {
+	if keys, ok := jwksCache.Load(url); ok {
+		return keys.([]*JWTKey), nil
+	}
+	return RefreshJWKS(url)
+}
+
+// RefreshJWKS fetches JWKS from url and updates cache, which is called when kid of jwt is not found after key rotation.
+func RefreshJWKS(url string) ([]*JWTKey, error) {
+	resp, err := jwksHTTPClient.Get(url)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch JWKS failed")
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "read JWKS failed")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch JWKS failed, status code %d: %s", resp.StatusCode, data)
+	}
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return nil, err
+	}
+	jwksCache.Store(url, keys)
+	return keys, nil
+}
+
+// VerifyJWT verifies signature of jwt with key loaded by LoadJWTKeys,
+// JWKS of url is refreshed once if no key matches kid of jwt.
+func VerifyJWT(jwt *JWT, key string) error {
+	keys, err := LoadJWTKeys(key)
+	if err != nil {
+		return err
+	}
+	err = jwt.Verify(keys)
+	if err == nil {
+		return nil
+	}
+	trimmed := strings.TrimSpace(key)
+	kid, _ := jwt.Header["kid"].(string)
+	if kid == "" || !(strings.HasPrefix(trimmed, "http://") || strings.HasPrefix(trimmed, "https://")) || hasJWTKey(keys, kid) {
+		return err
+	}
+	if keys, err = RefreshJWKS(trimmed); err != nil {
+		return err
+	}
+	return jwt.Verify(keys)
+}
+
+func hasJWTKey(keys []*JWTKey, kid string) bool {
+	for _, key := range keys {
+		if key.ID == kid {
+			return true
+		}
+	}
+	return false
+}
+
+// jwtDecode decodes header and claims of jwt without verification, e.g. ${jwt_decode($token)}
+func jwtDecode(token string) (map[string]interface{}, error) {
+	jwt, err := ParseJWT(token)
+	if err != nil {
+		return nil, err
+	}
+	return jwt.Map(), nil
+}
+
+// jwtVerify checks if signature of jwt is valid, e.g. ${jwt_verify($token, $secret)},
+// key is HMAC secret, PEM, JWK, JWKS or url of JWKS.
+func jwtVerify(token, key string) (bool, error) {
+	jwt, err := ParseJWT(token)
+	if err != nil {
+		return false, err
+	}
+	return VerifyJWT(jwt, key) == nil, nil
+}
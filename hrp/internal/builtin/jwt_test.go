@@ -0,0 +1,180 @@
+package builtin
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/httprunner/httprunner/hrp/internal/json"
+)
+
+// signTestJWT signs jwt with key, which is []byte of HMAC secret or crypto.Signer.
+func signTestJWT(t *testing.T, alg, kid string, key interface{}, claims map[string]interface{}) string {
+	header := map[string]interface{}{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	encode := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signingInput := encode(header) + "." + encode(claims)
+
+	hash := map[string]crypto.Hash{"256": crypto.SHA256, "384": crypto.SHA384, "512": crypto.SHA512}[alg[len(alg)-3:]]
+	var digest []byte
+	if hash != 0 {
+		h := hash.New()
+		h.Write([]byte(signingInput))
+		digest = h.Sum(nil)
+	}
+	var signature []byte
+	var err error
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(hash.New, k)
+		mac.Write([]byte(signingInput))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		if strings.HasPrefix(alg, "PS") {
+			signature, err = rsa.SignPSS(rand.Reader, k, hash, digest, nil)
+		} else {
+			signature, err = rsa.SignPKCS1v15(rand.Reader, k, hash, digest)
+		}
+	case *ecdsa.PrivateKey:
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, k, digest)
+		size := (k.Curve.Params().BitSize + 7) / 8
+		signature = make([]byte, 2*size)
+		r.FillBytes(signature[:size])
+		s.FillBytes(signature[size:])
+	case ed25519.PrivateKey:
+		signature = ed25519.Sign(k, []byte(signingInput))
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestVerifyJWT(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	rsaPub, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	rsaPEM := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: rsaPub}))
+	ecPriv, _ := x509.MarshalECPrivateKey(ecKey)
+	ecPEM := string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: ecPriv}))
+	edPub, _ := x509.MarshalPKIXPublicKey(edKey.Public())
+	edPEM := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: edPub}))
+	b64 := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	rsaJWK := fmt.Sprintf(`{"kty":"RSA","kid":"rsa1","n":"%s","e":"AQAB"}`, b64(rsaKey.N.Bytes()))
+	ecJWK := fmt.Sprintf(`{"kty":"EC","kid":"ec1","crv":"P-256","x":"%s","y":"%s"}`,
+		b64(ecKey.X.FillBytes(make([]byte, 32))), b64(ecKey.Y.FillBytes(make([]byte, 32))))
+
+	claims := map[string]interface{}{"sub": "alice", "aud": []string{"api"}, "exp": 4102444800}
+	testData := []struct {
+		alg string
+		kid string
+		key interface{}
+		pub string
+	}{
+		{"HS256", "", []byte("secret"), "secret"},
+		{"HS512", "", []byte("secret"), "secret"},
+		{"RS256", "", rsaKey, rsaPEM},
+		{"PS384", "", rsaKey, rsaPEM},
+		{"RS512", "rsa1", rsaKey, `{"keys":[` + ecJWK + `,` + rsaJWK + `]}`},
+		{"ES256", "", ecKey, ecPEM},
+		{"ES256", "ec1", ecKey, ecJWK},
+		{"EdDSA", "", edKey, edPEM},
+	}
+	for _, data := range testData {
+		token := signTestJWT(t, data.alg, data.kid, data.key, claims)
+		jwt, err := ParseJWT("Bearer " + token)
+		if !assert.Nil(t, err, data.alg) {
+			continue
+		}
+		assert.Equal(t, "alice", jwt.Claims["sub"])
+		assert.Nil(t, VerifyJWT(jwt, data.pub), data.alg)
+		verified, err := jwtVerify(token, data.pub)
+		assert.Nil(t, err)
+		assert.True(t, verified, data.alg)
+
+		// tampered claims
+		parts := strings.Split(token, ".")
+		parts[1] = b64([]byte(`{"sub":"bob"}`))
+		tampered, _ := ParseJWT(strings.Join(parts, "."))
+		assert.NotNil(t, VerifyJWT(tampered, data.pub), data.alg)
+	}
+
+	// wrong key
+	jwt, _ := ParseJWT(signTestJWT(t, "HS256", "", []byte("secret"), claims))
+	assert.NotNil(t, VerifyJWT(jwt, "other"))
+	assert.NotNil(t, VerifyJWT(jwt, rsaPEM))
+	// unsecured jwt
+	jwt, _ = ParseJWT(b64([]byte(`{"alg":"none"}`)) + "." + b64([]byte(`{"sub":"alice"}`)) + ".")
+	assert.NotNil(t, VerifyJWT(jwt, "secret"))
+	// kid not found
+	jwt, _ = ParseJWT(signTestJWT(t, "RS256", "rsa2", rsaKey, claims))
+	assert.NotNil(t, VerifyJWT(jwt, rsaJWK))
+	// short or unknown alg in token header
+	for _, alg := range []string{"H", "HS", "HS25", "XX256"} {
+		jwt, _ = ParseJWT(b64([]byte(`{"alg":"`+alg+`"}`)) + "." + b64([]byte(`{"sub":"alice"}`)) + ".c2ln")
+		err := VerifyJWT(jwt, "secret")
+		if assert.NotNil(t, err, alg) {
+			assert.Contains(t, err.Error(), "not supported", alg)
+		}
+	}
+
+	for _, token := range []string{"", "a.b", "!.e30.", "e30.!.", "e30.e30.!"} {
+		_, err := ParseJWT(token)
+		assert.NotNil(t, err, token)
+	}
+}
+
+func TestFetchJWKS(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	b64 := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	var fetches int32
+	kid := "old"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		fmt.Fprintf(w, `{"keys":[{"kty":"RSA","kid":"%s","use":"sig","n":"%s","e":"AQAB"},{"kty":"RSA","kid":"enc","use":"enc","n":"%s","e":"AQAB"}]}`,
+			kid, b64(rsaKey.N.Bytes()), b64(rsaKey.N.Bytes()))
+	}))
+	defer ts.Close()
+
+	jwt, _ := ParseJWT(signTestJWT(t, "RS256", "old", rsaKey, map[string]interface{}{"sub": "alice"}))
+	assert.Nil(t, VerifyJWT(jwt, ts.URL))
+	assert.Nil(t, VerifyJWT(jwt, ts.URL))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetches), "JWKS should be cached")
+
+	// JWKS is refreshed when kid is not found after key rotation
+	kid = "new"
+	jwt, _ = ParseJWT(signTestJWT(t, "RS256", "new", rsaKey, map[string]interface{}{"sub": "alice"}))
+	assert.Nil(t, VerifyJWT(jwt, ts.URL))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&fetches))
+
+	keys, err := FetchJWKS(ts.URL)
+	if assert.Nil(t, err) {
+		assert.Len(t, keys, 1, "encryption key should be ignored")
+	}
+}
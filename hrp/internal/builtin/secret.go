@@ -0,0 +1,427 @@
+package builtin
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/httprunner/httprunner/hrp/internal/json"
+)
+
+// SecretKeyEnv is environment variable of base64 encoded AES-256 key, which decrypts values
+// encrypted by hrp encrypt with decrypt_secret function.
+const SecretKeyEnv = "HRP_SECRET_KEY"
+
+// secrets with fewer characters are not masked, which would mask unrelated output
+const minMaskedSecretLength = 4
+
+const secretMask = "******"
+
+var jsonHTMLUnescaper = strings.NewReplacer(`\u003c`, "<", `\u003e`, ">", `\u0026`, "&")
+
+var secrets = &secretRegistry{values: make(map[string]bool)}
+
+// secretRegistry records resolved secret values, which are masked in outputs.
+type secretRegistry struct {
+	mutex    sync.RWMutex
+	values   map[string]bool
+	replacer *strings.Replacer
+}
+
+// AddSecrets registers secret values to be masked by MaskSecrets,
+// escaped forms of values in json and html are also masked.
+func AddSecrets(values ...string) {
+	secrets.mutex.Lock()
+	defer secrets.mutex.Unlock()
+	for _, value := range values {
+		if len(value) < minMaskedSecretLength {
+			continue
+		}
+		secrets.values[value] = true
+		if escaped, err := json.Marshal(value); err == nil {
+			escapedValue := strings.Trim(string(escaped), `"`)
+			secrets.values[escapedValue] = true
+			// json encoded without escaping html characters
+			secrets.values[jsonHTMLUnescaper.Replace(escapedValue)] = true
+		}
+		secrets.values[html.EscapeString(value)] = true
+	}
+	// replace longer values first, in case secret contains another secret
+	values = make([]string, 0, len(secrets.values))
+	for value := range secrets.values {
+		values = append(values, value)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if len(values[i]) != len(values[j]) {
+			return len(values[i]) > len(values[j])
+		}
+		return values[i] < values[j]
+	})
+	oldnew := make([]string, 0, 2*len(values))
+	for _, value := range values {
+		oldnew = append(oldnew, value, secretMask)
+	}
+	secrets.replacer = strings.NewReplacer(oldnew...)
+}
+
+// MaskSecrets replaces registered secret values in text with ******.
+func MaskSecrets(text string) string {
+	secrets.mutex.RLock()
+	replacer := secrets.replacer
+	secrets.mutex.RUnlock()
+	if replacer == nil {
+		return text
+	}
+	return replacer.Replace(text)
+}
+
+// MaskSecretsInJSON replaces registered secret values in json with ******, only string literals are masked
+// in place and numbers equal to secret values are replaced by masked string, thus masked json is still valid.
+func MaskSecretsInJSON(data []byte) []byte {
+	secrets.mutex.RLock()
+	defer secrets.mutex.RUnlock()
+	if secrets.replacer == nil {
+		return data
+	}
+	var buf bytes.Buffer
+	buf.Grow(len(data))
+	for i := 0; i < len(data); {
+		switch c := data[i]; {
+		case c == '"':
+			end := i + 1
+			for end < len(data) && data[end] != '"' {
+				if data[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(data) {
+				// unterminated string literal, json is invalid
+				buf.WriteString(secrets.replacer.Replace(string(data[i:])))
+				return buf.Bytes()
+			}
+			buf.WriteByte('"')
+			buf.WriteString(secrets.replacer.Replace(string(data[i+1 : end])))
+			buf.WriteByte('"')
+			i = end + 1
+		case c == '-' || (c >= '0' && c <= '9'):
+			end := i + 1
+			for end < len(data) && strings.IndexByte("+-.eE0123456789", data[end]) >= 0 {
+				end++
+			}
+			if secrets.values[string(data[i:end])] {
+				buf.WriteString(`"` + secretMask + `"`)
+			} else {
+				buf.Write(data[i:end])
+			}
+			i = end
+		default:
+			buf.WriteByte(c)
+			i++
+		}
+	}
+	return buf.Bytes()
+}
+
+// addSecretValue registers secret value fetched from backends, booleans and nulls are not masked,
+// which would mask every true, false or null in outputs.
+func addSecretValue(value interface{}) {
+	switch v := value.(type) {
+	case string:
+		AddSecrets(v)
+	case bool, nil:
+	default:
+		AddSecrets(fmt.Sprint(v))
+	}
+}
+
+// secretCache caches secrets fetched from backends, thus backends are requested once in process.
+// Secrets of different keys are fetched concurrently, while each key is fetched by one caller at a time.
+var secretCache = struct {
+	sync.Mutex
+	entries map[string]*secretCacheEntry
+}{entries: make(map[string]*secretCacheEntry)}
+
+type secretCacheEntry struct {
+	sync.Mutex
+	value   interface{}
+	fetched bool
+}
+
+func cachedSecret(key string, fetch func() (interface{}, error)) (interface{}, error) {
+	secretCache.Lock()
+	entry, ok := secretCache.entries[key]
+	if !ok {
+		entry = &secretCacheEntry{}
+		secretCache.entries[key] = entry
+	}
+	secretCache.Unlock()
+
+	entry.Lock()
+	defer entry.Unlock()
+	if entry.fetched {
+		return entry.value, nil
+	}
+	value, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	addSecretValue(value)
+	entry.value = value
+	entry.fetched = true
+	return value, nil
+}
+
+var secretHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// vault reads key of secret in path from HashiCorp Vault, e.g. ${vault(secret/data/app, password)},
+// both kv v1 and v2 secrets engines are supported. Vault address and token are read from
+// environment variables VAULT_ADDR and VAULT_TOKEN, and VAULT_NAMESPACE if set.
+func vault(path, key string) (interface{}, error) {
+	return cachedSecret("vault:"+path+":"+key, func() (interface{}, error) {
+		addr := os.Getenv("VAULT_ADDR")
+		token := os.Getenv("VAULT_TOKEN")
+		if addr == "" || token == "" {
+			return nil, errors.New("VAULT_ADDR and VAULT_TOKEN should be set to read secrets from vault")
+		}
+		req, err := http.NewRequest(http.MethodGet,
+			strings.TrimSuffix(addr, "/")+"/v1/"+strings.TrimPrefix(path, "/"), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("X-Vault-Token", token)
+		if namespace := os.Getenv("VAULT_NAMESPACE"); namespace != "" {
+			req.Header.Set("X-Vault-Namespace", namespace)
+		}
+		var body struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		if err := doSecretRequest(req, &body); err != nil {
+			return nil, errors.Wrapf(err, "read vault secret %s failed", path)
+		}
+		data := body.Data
+		// kv v2 secrets engine wraps secret with metadata
+		if nested, ok := data["data"].(map[string]interface{}); ok {
+			if _, ok := data["metadata"]; ok {
+				data = nested
+			}
+		}
+		value, ok := data[key]
+		if !ok {
+			return nil, fmt.Errorf("key %s not found in vault secret %s", key, path)
+		}
+		return value, nil
+	})
+}
+
+// awsSecretsManager reads secret string from AWS Secrets Manager, e.g. ${aws_sm(prod/db)},
+// key of json secret string can be specified, e.g. ${aws_sm(prod/db, password)}.
+// Region and credentials are read from environment variables AWS_REGION (or AWS_DEFAULT_REGION),
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN if set,
+// endpoint can be overridden by AWS_ENDPOINT_URL_SECRETS_MANAGER or AWS_ENDPOINT_URL.
+func awsSecretsManager(name string, key ...string) (interface{}, error) {
+	secretString, err := cachedSecret("aws_sm:"+name, func() (interface{}, error) {
+		return getAWSSecretString(name)
+	})
+	if err != nil || len(key) == 0 {
+		return secretString, err
+	}
+	var secret map[string]interface{}
+	if err := json.Unmarshal([]byte(secretString.(string)), &secret); err != nil {
+		return nil, errors.Wrapf(err, "secret string of %s is not json object", name)
+	}
+	value, ok := secret[key[0]]
+	if !ok {
+		return nil, fmt.Errorf("key %s not found in aws secret %s", key[0], name)
+	}
+	addSecretValue(value)
+	return value, nil
+}
+
+func getAWSSecretString(name string) (interface{}, error) {
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	accessKey := os.Getenv("AWS_ACCESS_KEY_ID")
+	secretKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if region == "" || accessKey == "" || secretKey == "" {
+		return nil, errors.New("AWS_REGION, AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY should be set to read secrets from aws")
+	}
+	endpoint := os.Getenv("AWS_ENDPOINT_URL_SECRETS_MANAGER")
+	if endpoint == "" {
+		endpoint = os.Getenv("AWS_ENDPOINT_URL")
+	}
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://secretsmanager.%s.amazonaws.com", region)
+	}
+
+	payload, _ := json.Marshal(map[string]string{"SecretId": name})
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(endpoint, "/")+"/", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	if token := os.Getenv("AWS_SESSION_TOKEN"); token != "" {
+		req.Header.Set("X-Amz-Security-Token", token)
+	}
+	signAWSRequest(req, payload, region, "secretsmanager", accessKey, secretKey, time.Now().UTC())
+
+	var body struct {
+		SecretString *string `json:"SecretString"`
+	}
+	if err := doSecretRequest(req, &body); err != nil {
+		return nil, errors.Wrapf(err, "read aws secret %s failed", name)
+	}
+	if body.SecretString == nil {
+		return nil, fmt.Errorf("aws secret %s has no secret string", name)
+	}
+	return *body.SecretString, nil
+}
+
+// signAWSRequest signs request with AWS Signature Version 4, all headers set are signed.
+func signAWSRequest(req *http.Request, payload []byte, region, service, accessKey, secretKey string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(req.Header.Get(name))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	payloadHash := sha256.Sum256(payload)
+	canonicalRequest := strings.Join([]string{
+		req.Method, path, req.URL.Query().Encode(),
+		canonicalHeaders.String(), signedHeaders, hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	scope := strings.Join([]string{date, region, service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	hmacSHA256 := func(key []byte, data string) []byte {
+		h := hmac.New(sha256.New, key)
+		h.Write([]byte(data))
+		return h.Sum(nil)
+	}
+	signingKey := hmacSHA256([]byte("AWS4"+secretKey), date)
+	for _, item := range []string{region, service, "aws4_request"} {
+		signingKey = hmacSHA256(signingKey, item)
+	}
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+}
+
+func doSecretRequest(req *http.Request, v interface{}) error {
+	resp, err := secretHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.Unmarshal(body, v)
+}
+
+// GenSecretKey generates base64 encoded random AES-256 key for SecretKeyEnv.
+func GenSecretKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+func secretCipher() (cipher.AEAD, error) {
+	encodedKey := os.Getenv(SecretKeyEnv)
+	if encodedKey == "" {
+		return nil, fmt.Errorf("%s should be set to encrypt or decrypt secrets", SecretKeyEnv)
+	}
+	key, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s is not base64 encoded", SecretKeyEnv)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("%s should be 32 bytes AES-256 key, got %d bytes", SecretKeyEnv, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptSecret encrypts value with AES-256-GCM key of SecretKeyEnv,
+// returns url-safe base64 encoded nonce and ciphertext, which can be decrypted by decrypt_secret.
+func EncryptSecret(value string) (string, error) {
+	gcm, err := secretCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(value), nil)
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// decryptSecret decrypts value encrypted by EncryptSecret, e.g. ${decrypt_secret(3q2-7w...)}.
+func decryptSecret(encrypted string) (string, error) {
+	gcm, err := secretCipher()
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", errors.Wrap(err, "encrypted secret is not url-safe base64 encoded")
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("encrypted secret is too short")
+	}
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", errors.Wrap(err, "decrypt secret failed, check if secret is encrypted with the same key")
+	}
+	AddSecrets(string(plaintext))
+	return string(plaintext), nil
+}
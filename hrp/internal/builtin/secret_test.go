@@ -0,0 +1,192 @@
+package builtin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setEnv(t *testing.T, envs map[string]string) {
+	for key, value := range envs {
+		origin, ok := os.LookupEnv(key)
+		os.Setenv(key, value)
+		key := key
+		t.Cleanup(func() {
+			if ok {
+				os.Setenv(key, origin)
+			} else {
+				os.Unsetenv(key)
+			}
+		})
+	}
+}
+
+func TestMaskSecrets(t *testing.T) {
+	AddSecrets("s3cr3t-p@ss", `quo"ted<secret>`, "abc")
+	assert.Equal(t, "password: ******", MaskSecrets("password: s3cr3t-p@ss"))
+	assert.Equal(t, `{"a": "******", "b": "******"}`, MaskSecrets(`{"a": "quo\"ted<secret>", "b": "quo\"ted\u003csecret\u003e"}`))
+	assert.Equal(t, "<td>******</td>", MaskSecrets("<td>quo&#34;ted&lt;secret&gt;</td>"))
+	// short secrets are not masked
+	assert.Equal(t, "abc", MaskSecrets("abc"))
+}
+
+func TestMaskSecretsInJSON(t *testing.T) {
+	AddSecrets("json-secret", "20220401")
+	data := []byte(`{"pin": 20220401, "id": 202204010, "token": "bearer json-secret", "ok": true, "quote": "\"json-secret\""}`)
+	masked := MaskSecretsInJSON(data)
+	assert.True(t, json.Valid(masked))
+	assert.Equal(t, `{"pin": "******", "id": 202204010, "token": "bearer ******", "ok": true, "quote": "\"******\""}`,
+		string(masked))
+	// text masking corrupts json with numeric secrets
+	assert.False(t, json.Valid([]byte(MaskSecrets(string(data)))))
+
+	// booleans fetched from secret backends are not masked
+	addSecretValue(true)
+	assert.Equal(t, `{"ok": true}`, string(MaskSecretsInJSON([]byte(`{"ok": true}`))))
+}
+
+func TestCachedSecretConcurrently(t *testing.T) {
+	// fetching slow secret does not block secrets of other keys
+	fetching := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan interface{})
+	go func() {
+		value, _ := cachedSecret("test:slow", func() (interface{}, error) {
+			close(fetching)
+			<-release
+			return "slow-secret", nil
+		})
+		done <- value
+	}()
+	<-fetching
+	value, err := cachedSecret("test:fast", func() (interface{}, error) {
+		return "fast-secret", nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "fast-secret", value)
+	close(release)
+	assert.Equal(t, "slow-secret", <-done)
+
+	// the same key is fetched once
+	value, err = cachedSecret("test:slow", func() (interface{}, error) {
+		return nil, errors.New("fetched again")
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "slow-secret", value)
+}
+
+func TestEncryptSecret(t *testing.T) {
+	key, err := GenSecretKey()
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	setEnv(t, map[string]string{SecretKeyEnv: key})
+	encrypted, err := EncryptSecret("my-password")
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	assert.Regexp(t, `^[\w\-]+$`, encrypted)
+	decrypted, err := decryptSecret(encrypted)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "my-password", decrypted)
+	}
+	assert.Equal(t, "******", MaskSecrets("my-password"))
+
+	// decrypt with another key
+	key, _ = GenSecretKey()
+	setEnv(t, map[string]string{SecretKeyEnv: key})
+	_, err = decryptSecret(encrypted)
+	assert.NotNil(t, err)
+	setEnv(t, map[string]string{SecretKeyEnv: "c2hvcnQ="})
+	_, err = decryptSecret(encrypted)
+	assert.NotNil(t, err)
+}
+
+func TestVault(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/app":
+			w.Write([]byte(`{"data": {"data": {"password": "kv2-password"}, "metadata": {"version": 1}}}`))
+		case "/v1/kv/app":
+			w.Write([]byte(`{"data": {"password": "kv1-password", "port": 5432}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+	setEnv(t, map[string]string{"VAULT_ADDR": ts.URL, "VAULT_TOKEN": "root-token"})
+
+	value, err := vault("secret/data/app", "password")
+	if assert.Nil(t, err) {
+		assert.Equal(t, "kv2-password", value)
+	}
+	value, err = vault("kv/app", "port")
+	if assert.Nil(t, err) {
+		assert.Equal(t, float64(5432), value)
+	}
+	_, err = vault("kv/app", "username")
+	assert.NotNil(t, err)
+	_, err = vault("kv/not-found", "password")
+	assert.NotNil(t, err)
+	assert.Equal(t, "password is ******", MaskSecrets("password is kv2-password"))
+}
+
+func TestAWSSecretsManager(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if r.Header.Get("X-Amz-Target") != "secretsmanager.GetSecretValue" ||
+			!strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") ||
+			r.Header.Get("X-Amz-Security-Token") != "session-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"Name":         body["SecretId"],
+			"SecretString": `{"username": "admin", "password": "aws-password"}`,
+		})
+	}))
+	defer ts.Close()
+	setEnv(t, map[string]string{
+		"AWS_REGION": "us-east-1", "AWS_ACCESS_KEY_ID": "AKID", "AWS_SECRET_ACCESS_KEY": "SECRET",
+		"AWS_SESSION_TOKEN": "session-token", "AWS_ENDPOINT_URL": ts.URL,
+	})
+
+	value, err := awsSecretsManager("arn:aws:secretsmanager:us-east-1:123456789012:secret:prod/db", "password")
+	if assert.Nil(t, err) {
+		assert.Equal(t, "aws-password", value)
+	}
+	value, err = awsSecretsManager("arn:aws:secretsmanager:us-east-1:123456789012:secret:prod/db")
+	if assert.Nil(t, err) {
+		assert.Contains(t, value, `"username": "admin"`)
+	}
+	_, err = awsSecretsManager("arn:aws:secretsmanager:us-east-1:123456789012:secret:prod/db", "token")
+	assert.NotNil(t, err)
+	// secret is cached
+	assert.Equal(t, 1, requests)
+	assert.Equal(t, "******", MaskSecrets("aws-password"))
+}
+
+func TestSignAWSRequest(t *testing.T) {
+	// get-vanilla of aws signature version 4 test suite
+	req, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	now, _ := time.Parse("20060102T150405Z", "20150830T123600Z")
+	signAWSRequest(req, nil, "us-east-1", "service",
+		"AKIDEXAMPLE", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", now)
+	assert.Equal(t, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "+
+		"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		req.Header.Get("Authorization"))
+}
@@ -0,0 +1,185 @@
+// Package debug serves net/http/pprof endpoints and runtime status of hrp during long runs.
+// net/http/pprof registers its handlers on http.DefaultServeMux when imported,
+// thus the package is only imported by hrp commands instead of package hrp.
+package debug
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/httprunner/httprunner/hrp/internal/json"
+)
+
+const (
+	debugStatusPath         = "/debug/hrp/status"
+	defaultActiveStepsLimit = 100
+)
+
+// Server exposes net/http/pprof endpoints under /debug/pprof/ and runtime status of hrp
+// under /debug/hrp/status during long runs, thus hangs and leaks in big suites can be diagnosed live.
+type Server struct {
+	server  *http.Server
+	addr    string
+	startAt time.Time
+
+	stepSeq     int64
+	stepsMutex  sync.Mutex
+	activeSteps map[int64]*ActiveStep
+
+	queuesMutex sync.RWMutex
+	queues      []func() map[string]int
+}
+
+// RuntimeStatus is runtime status of hrp returned by debug server.
+type RuntimeStatus struct {
+	StartAt          time.Time      `json:"start_at"`
+	Uptime           string         `json:"uptime"`
+	Goroutines       int            `json:"goroutines"`
+	HeapAlloc        uint64         `json:"heap_alloc"` // bytes of allocated heap objects
+	NumGC            uint32         `json:"num_gc"`
+	ActiveStepsTotal int            `json:"active_steps_total"`
+	ActiveSteps      []*ActiveStep  `json:"active_steps"`     // the longest running steps first
+	Queues           map[string]int `json:"queues,omitempty"` // depths of internal queues, e.g. stats channels of load testing
+}
+
+// ActiveStep is running step reported by debug server.
+type ActiveStep struct {
+	TestCase string    `json:"testcase"`
+	Step     string    `json:"step"`
+	Type     string    `json:"type"`
+	StartAt  time.Time `json:"start_at"`
+	Elapsed  string    `json:"elapsed,omitempty"`
+}
+
+// StartServer starts debug server listening on addr, e.g. localhost:6060.
+func StartServer(addr string) (*Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, errors.Wrap(err, "listen debug server failed")
+	}
+	d := &Server{
+		addr:        listener.Addr().String(),
+		startAt:     time.Now(),
+		activeSteps: make(map[int64]*ActiveStep),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc(debugStatusPath, d.handleStatus)
+	d.server = &http.Server{Handler: mux}
+	log.Warn().Str("addr", d.addr).Msg("start debug server")
+	go func() {
+		if err := d.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Error().Err(err).Msg("debug server stopped")
+		}
+	}()
+	return d, nil
+}
+
+// Addr returns listening address of debug server.
+func (d *Server) Addr() string {
+	return d.addr
+}
+
+// Close shuts down debug server.
+func (d *Server) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return d.server.Shutdown(ctx)
+}
+
+// AddQueue adds provider of queue depths reported in runtime status.
+func (d *Server) AddQueue(depths func() map[string]int) {
+	d.queuesMutex.Lock()
+	defer d.queuesMutex.Unlock()
+	d.queues = append(d.queues, depths)
+}
+
+// StepStarted records step of testcase as active, and returns function to be called when step ends.
+func (d *Server) StepStarted(testcase, step, stepType string) func() {
+	id := atomic.AddInt64(&d.stepSeq, 1)
+	active := &ActiveStep{
+		TestCase: testcase,
+		Step:     step,
+		Type:     stepType,
+		StartAt:  time.Now(),
+	}
+	d.stepsMutex.Lock()
+	d.activeSteps[id] = active
+	d.stepsMutex.Unlock()
+	return func() {
+		d.stepsMutex.Lock()
+		delete(d.activeSteps, id)
+		d.stepsMutex.Unlock()
+	}
+}
+
+// Status returns current runtime status, at most limit active steps are returned.
+func (d *Server) Status(limit int) *RuntimeStatus {
+	now := time.Now()
+	d.stepsMutex.Lock()
+	steps := make([]*ActiveStep, 0, len(d.activeSteps))
+	for _, step := range d.activeSteps {
+		s := *step
+		s.Elapsed = now.Sub(s.StartAt).String()
+		steps = append(steps, &s)
+	}
+	d.stepsMutex.Unlock()
+	sort.Slice(steps, func(i, j int) bool {
+		return steps[i].StartAt.Before(steps[j].StartAt)
+	})
+
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	status := &RuntimeStatus{
+		StartAt:          d.startAt,
+		Uptime:           now.Sub(d.startAt).Round(time.Second).String(),
+		Goroutines:       runtime.NumGoroutine(),
+		HeapAlloc:        memStats.HeapAlloc,
+		NumGC:            memStats.NumGC,
+		ActiveStepsTotal: len(steps),
+		ActiveSteps:      steps,
+	}
+	if limit > 0 && len(steps) > limit {
+		status.ActiveSteps = steps[:limit]
+	}
+
+	d.queuesMutex.RLock()
+	defer d.queuesMutex.RUnlock()
+	for _, depths := range d.queues {
+		for name, depth := range depths() {
+			if status.Queues == nil {
+				status.Queues = make(map[string]int)
+			}
+			status.Queues[name] = depth
+		}
+	}
+	return status
+}
+
+func (d *Server) handleStatus(w http.ResponseWriter, req *http.Request) {
+	limit := defaultActiveStepsLimit
+	if value := req.URL.Query().Get("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil {
+			http.Error(w, "invalid limit: "+value, http.StatusBadRequest)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(d.Status(limit))
+}
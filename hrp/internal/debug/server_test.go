@@ -0,0 +1,76 @@
+package debug
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/httprunner/httprunner/hrp/internal/json"
+)
+
+func getRuntimeStatus(t *testing.T, d *Server) *RuntimeStatus {
+	resp, err := http.Get("http://" + d.Addr() + debugStatusPath)
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	status := &RuntimeStatus{}
+	require.Nil(t, json.NewDecoder(resp.Body).Decode(status))
+	return status
+}
+
+func TestServer(t *testing.T) {
+	d, err := StartServer("127.0.0.1:0")
+	require.Nil(t, err)
+	defer d.Close()
+
+	resp, err := http.Get("http://" + d.Addr() + "/debug/pprof/goroutine?debug=1")
+	require.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = http.Get("http://" + d.Addr() + debugStatusPath + "?limit=x")
+	require.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	d.AddQueue(func() map[string]int {
+		return map[string]int{"pending": 3}
+	})
+	end := d.StepStarted("slow case", "slow step", "request-GET")
+	status := getRuntimeStatus(t, d)
+	assert.Greater(t, status.Goroutines, 0)
+	assert.Equal(t, 1, status.ActiveStepsTotal)
+	if assert.Len(t, status.ActiveSteps, 1) {
+		assert.Equal(t, "slow case", status.ActiveSteps[0].TestCase)
+		assert.Equal(t, "slow step", status.ActiveSteps[0].Step)
+		assert.Equal(t, "request-GET", status.ActiveSteps[0].Type)
+	}
+	assert.Equal(t, map[string]int{"pending": 3}, status.Queues)
+
+	end()
+	status = getRuntimeStatus(t, d)
+	assert.Equal(t, 0, status.ActiveStepsTotal)
+}
+
+func TestServerActiveStepsLimit(t *testing.T) {
+	d := &Server{startAt: time.Now(), activeSteps: make(map[int64]*ActiveStep)}
+	var ends []func()
+	for _, name := range []string{"first", "second", "third"} {
+		ends = append(ends, d.StepStarted("limit", name, "request-GET"))
+		time.Sleep(time.Millisecond)
+	}
+	status := d.Status(2)
+	assert.Equal(t, 3, status.ActiveStepsTotal)
+	if assert.Len(t, status.ActiveSteps, 2) {
+		// the longest running steps first
+		assert.Equal(t, "first", status.ActiveSteps[0].Step)
+		assert.Equal(t, "second", status.ActiveSteps[1].Step)
+	}
+	for _, end := range ends {
+		end()
+	}
+	assert.Equal(t, 0, d.Status(0).ActiveStepsTotal)
+}
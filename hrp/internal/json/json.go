@@ -1,16 +1,125 @@
 package json
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+
 	jsoniter "github.com/json-iterator/go"
 )
 
-// replace with third-party json library to improve performance
-var json = jsoniter.ConfigCompatibleWithStandardLibrary
+// json engines, JSON encode/decode dominates CPU in high-RPS load testing,
+// thus the engine can be selected for different architectures.
+const (
+	EngineJSONIter = "jsoniter" // default engine
+	EngineStdlib   = "std"      // encoding/json in standard library
+)
+
+// Encoder is implemented by json encoders of all engines.
+type Encoder interface {
+	Encode(v interface{}) error
+	SetIndent(prefix, indent string)
+	SetEscapeHTML(on bool)
+}
+
+// Decoder is implemented by json decoders of all engines.
+type Decoder interface {
+	Decode(v interface{}) error
+	UseNumber()
+	DisallowUnknownFields()
+	More() bool
+	Buffered() io.Reader
+}
+
+// Engine is a json implementation compatible with encoding/json.
+type Engine struct {
+	Name          string
+	Marshal       func(v interface{}) ([]byte, error)
+	MarshalIndent func(v interface{}, prefix, indent string) ([]byte, error)
+	Unmarshal     func(data []byte, v interface{}) error
+	NewEncoder    func(w io.Writer) Encoder
+	NewDecoder    func(r io.Reader) Decoder
+}
 
 var (
-	Marshal       = json.Marshal
-	MarshalIndent = json.MarshalIndent
-	Unmarshal     = json.Unmarshal
-	NewDecoder    = json.NewDecoder
-	Get           = json.Get
+	enginesMutex sync.RWMutex
+	engines      = make(map[string]*Engine)
+	current      atomic.Value // *Engine
 )
+
+func init() {
+	jsoniterAPI := jsoniter.ConfigCompatibleWithStandardLibrary
+	Register(&Engine{
+		Name:          EngineJSONIter,
+		Marshal:       jsoniterAPI.Marshal,
+		MarshalIndent: jsoniterAPI.MarshalIndent,
+		Unmarshal:     jsoniterAPI.Unmarshal,
+		NewEncoder:    func(w io.Writer) Encoder { return jsoniterAPI.NewEncoder(w) },
+		NewDecoder:    func(r io.Reader) Decoder { return jsoniterAPI.NewDecoder(r) },
+	})
+	Register(&Engine{
+		Name:          EngineStdlib,
+		Marshal:       json.Marshal,
+		MarshalIndent: json.MarshalIndent,
+		Unmarshal:     json.Unmarshal,
+		NewEncoder:    func(w io.Writer) Encoder { return json.NewEncoder(w) },
+		NewDecoder:    func(r io.Reader) Decoder { return json.NewDecoder(r) },
+	})
+	current.Store(engines[EngineJSONIter])
+}
+
+// Register registers json engine, which can be selected by SetEngine.
+func Register(engine *Engine) {
+	enginesMutex.Lock()
+	defer enginesMutex.Unlock()
+	engines[engine.Name] = engine
+}
+
+// SetEngine selects json engine by name, it should be called before running testcases.
+func SetEngine(name string) error {
+	enginesMutex.RLock()
+	defer enginesMutex.RUnlock()
+	engine, ok := engines[name]
+	if !ok {
+		return fmt.Errorf("unknown json engine %s, available engines: %v", name, engineNames())
+	}
+	current.Store(engine)
+	return nil
+}
+
+// EngineName returns name of current json engine.
+func EngineName() string {
+	return current.Load().(*Engine).Name
+}
+
+func engineNames() []string {
+	names := make([]string, 0, len(engines))
+	for name := range engines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func Marshal(v interface{}) ([]byte, error) {
+	return current.Load().(*Engine).Marshal(v)
+}
+
+func MarshalIndent(v interface{}, prefix, indent string) ([]byte, error) {
+	return current.Load().(*Engine).MarshalIndent(v, prefix, indent)
+}
+
+func Unmarshal(data []byte, v interface{}) error {
+	return current.Load().(*Engine).Unmarshal(data, v)
+}
+
+func NewEncoder(w io.Writer) Encoder {
+	return current.Load().(*Engine).NewEncoder(w)
+}
+
+func NewDecoder(r io.Reader) Decoder {
+	return current.Load().(*Engine).NewDecoder(r)
+}
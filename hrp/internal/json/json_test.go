@@ -0,0 +1,35 @@
+package json
+
+import (
+	"bytes"
+	builtinJSON "encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetEngine(t *testing.T) {
+	defer SetEngine(EngineJSONIter)
+
+	for _, engine := range []string{EngineStdlib, EngineJSONIter} {
+		if !assert.Nil(t, SetEngine(engine)) {
+			t.Fatal()
+		}
+		assert.Equal(t, engine, EngineName())
+
+		data, err := Marshal(map[string]interface{}{"a": 1, "b": "c"})
+		assert.Nil(t, err)
+		assert.Equal(t, `{"a":1,"b":"c"}`, string(data), engine)
+
+		var value map[string]interface{}
+		decoder := NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		assert.Nil(t, decoder.Decode(&value))
+		assert.Equal(t, builtinJSON.Number("1"), value["a"], engine)
+	}
+}
+
+func TestSetEngineUnavailable(t *testing.T) {
+	assert.NotNil(t, SetEngine("unknown"))
+	assert.Equal(t, EngineJSONIter, EngineName())
+}
@@ -0,0 +1,20 @@
+package hrp
+
+import (
+	"github.com/rs/zerolog/log"
+
+	"github.com/httprunner/httprunner/hrp/internal/json"
+)
+
+// json engines for encoding requests and decoding responses
+const (
+	JSONEngineJSONIter = json.EngineJSONIter // default engine
+	JSONEngineStdlib   = json.EngineStdlib
+)
+
+// SetJSONEngine selects json engine of the whole process, it should be called before running testcases.
+// JSON encode/decode dominates CPU in high-RPS load testing, and the fastest engine varies with architectures.
+func SetJSONEngine(engine string) error {
+	log.Info().Str("engine", engine).Msg("[init] SetJSONEngine")
+	return json.SetEngine(engine)
+}
@@ -0,0 +1,292 @@
+package hrp
+
+import (
+	"bytes"
+	builtinJSON "encoding/json"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// jsonPathNode is a trie node of simple jmespath expressions on response body, e.g. body.data.items[0].id
+// only the fields on the paths are decoded when streaming large response body.
+type jsonPathNode struct {
+	terminal bool // decode the whole value at this node
+	fields   map[string]*jsonPathNode
+	indexes  map[int]*jsonPathNode
+	maxIndex int
+}
+
+func newJSONPathNode() *jsonPathNode {
+	return &jsonPathNode{
+		fields:   make(map[string]*jsonPathNode),
+		indexes:  make(map[int]*jsonPathNode),
+		maxIndex: -1,
+	}
+}
+
+// add adds path segments to trie, segment is field name (string) or list index (int).
+func (n *jsonPathNode) add(segments []interface{}) {
+	node := n
+	for _, segment := range segments {
+		if node.terminal {
+			return
+		}
+		var child *jsonPathNode
+		switch s := segment.(type) {
+		case string:
+			if child = node.fields[s]; child == nil {
+				child = newJSONPathNode()
+				node.fields[s] = child
+			}
+		case int:
+			if child = node.indexes[s]; child == nil {
+				child = newJSONPathNode()
+				node.indexes[s] = child
+			}
+			if s > node.maxIndex {
+				node.maxIndex = s
+			}
+		}
+		node = child
+	}
+	node.terminal = true
+}
+
+// parseSimpleJmesPath parses jmespath consisting of fields and non-negative indexes,
+// e.g. body.data."user-list"[0].id => ["body", "data", "user-list", 0, "id"]
+// ok is false if expression contains functions, filters, projections, etc.
+func parseSimpleJmesPath(expr string) (segments []interface{}, ok bool) {
+	expr = strings.TrimSpace(expr)
+	for i := 0; i < len(expr); {
+		switch c := expr[i]; {
+		case c == '.' && len(segments) > 0 && i+1 < len(expr) && expr[i+1] != '.' && expr[i+1] != '[':
+			i++
+			continue
+		case c == '"':
+			end := strings.IndexByte(expr[i+1:], '"')
+			if end < 0 {
+				return nil, false
+			}
+			segments = append(segments, expr[i+1:i+1+end])
+			i += end + 2
+		case c == '[':
+			end := strings.IndexByte(expr[i:], ']')
+			if end < 0 {
+				return nil, false
+			}
+			index, err := strconv.Atoi(expr[i+1 : i+end])
+			if err != nil || index < 0 {
+				return nil, false
+			}
+			segments = append(segments, index)
+			i += end + 1
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			j := i + 1
+			for j < len(expr) && (expr[j] == '_' || expr[j] >= 'a' && expr[j] <= 'z' ||
+				expr[j] >= 'A' && expr[j] <= 'Z' || expr[j] >= '0' && expr[j] <= '9') {
+				j++
+			}
+			segments = append(segments, expr[i:j])
+			i = j
+		default:
+			return nil, false
+		}
+		// segment must be followed by end, dot or index
+		if i < len(expr) && expr[i] != '.' && expr[i] != '[' {
+			return nil, false
+		}
+	}
+	if len(segments) == 0 {
+		return nil, false
+	}
+	if _, ok := segments[0].(string); !ok {
+		return nil, false
+	}
+	return segments, true
+}
+
+// appendValidatorExprs appends jmespath expressions referenced by validator, including nested validators of groups.
+func appendValidatorExprs(exprs []string, validator Validator) []string {
+	if validator.Not != nil {
+		return appendValidatorExprs(exprs, *validator.Not)
+	}
+	for _, nested := range validator.AnyOf {
+		exprs = appendValidatorExprs(exprs, nested)
+	}
+	if len(validator.AnyOf) > 0 {
+		return exprs
+	}
+	if validator.ExpectPath != "" {
+		exprs = append(exprs, validator.ExpectPath)
+	}
+	if strings.Contains(validator.Check, "$") {
+		// reference variable, not related to response
+		return exprs
+	}
+	return append(exprs, validator.Check)
+}
+
+// bodyPathTrie builds trie of response body paths referenced by step extractors, validators and wait until,
+// ok is false if the whole body is needed, e.g. regexp extractor, snapshot or complex jmespath expressions.
+func bodyPathTrie(step *TStep) (trie *jsonPathNode, ok bool) {
+	if step.Snapshot != nil {
+		return nil, false
+	}
+	for _, hook := range step.TeardownHooks {
+		if strings.Contains(hook, "hrp_step_response") {
+			return nil, false
+		}
+	}
+
+	var exprs []string
+	for _, expr := range step.Extract {
+		exprs = append(exprs, expr)
+	}
+	for _, iValidator := range step.Validators {
+		validator, ok := iValidator.(Validator)
+		if !ok {
+			return nil, false
+		}
+		exprs = appendValidatorExprs(exprs, validator)
+	}
+	if step.WaitUntil != nil {
+		exprs = append(exprs, step.WaitUntil.JmesPath)
+	}
+
+	trie = newJSONPathNode()
+	for _, expr := range exprs {
+		if strings.Contains(expr, textExtractorSubRegexp) {
+			return nil, false
+		}
+		segments, ok := parseSimpleJmesPath(expr)
+		if !ok {
+			return nil, false
+		}
+		if segments[0] != "body" {
+			// status_code, headers or cookies
+			continue
+		}
+		if len(segments) == 1 {
+			return nil, false
+		}
+		trie.add(segments[1:])
+	}
+	return trie, true
+}
+
+// streamJSONBody decodes only the values located by trie from json stream,
+// unrelated fields are skipped without being buffered, list elements not referenced are decoded as nil.
+func streamJSONBody(r io.Reader, trie *jsonPathNode) (interface{}, error) {
+	decoder := builtinJSON.NewDecoder(r)
+	decoder.UseNumber()
+	return streamJSONValue(decoder, trie)
+}
+
+func streamJSONValue(decoder *builtinJSON.Decoder, node *jsonPathNode) (interface{}, error) {
+	if node.terminal {
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		return value, nil
+	}
+
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch token {
+	case builtinJSON.Delim('{'):
+		object := make(map[string]interface{})
+		for decoder.More() {
+			keyToken, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, _ := keyToken.(string)
+			if child, ok := node.fields[key]; ok {
+				value, err := streamJSONValue(decoder, child)
+				if err != nil {
+					return nil, err
+				}
+				object[key] = value
+			} else if err := skipJSONValue(decoder); err != nil {
+				return nil, err
+			}
+		}
+		_, err = decoder.Token() // consume }
+		return object, err
+	case builtinJSON.Delim('['):
+		var list []interface{}
+		for index := 0; decoder.More(); index++ {
+			child, ok := node.indexes[index]
+			if index > node.maxIndex || !ok {
+				if err := skipJSONValue(decoder); err != nil {
+					return nil, err
+				}
+				if index <= node.maxIndex {
+					list = append(list, nil)
+				}
+				continue
+			}
+			value, err := streamJSONValue(decoder, child)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		_, err = decoder.Token() // consume ]
+		return list, err
+	default:
+		// scalar value
+		return token, nil
+	}
+}
+
+// skipJSONValue skips the next json value in stream.
+func skipJSONValue(decoder *builtinJSON.Decoder) error {
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch token {
+		case builtinJSON.Delim('{'), builtinJSON.Delim('['):
+			depth++
+		case builtinJSON.Delim('}'), builtinJSON.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+// readResponseBody reads response body, large json body above threshold is streamed
+// and only fields located by trie are decoded to reduce peak memory.
+func readResponseBody(body io.Reader, threshold int64, trie *jsonPathNode) (interface{}, error) {
+	if threshold <= 0 || trie == nil {
+		return readFullResponseBody(body)
+	}
+	prefix, err := io.ReadAll(io.LimitReader(body, threshold+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(prefix)) <= threshold {
+		return readFullResponseBody(bytes.NewReader(prefix))
+	}
+	trimmed := bytes.TrimLeft(prefix, " \t\r\n")
+	if len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[') {
+		// not json, use raw body
+		return readFullResponseBody(io.MultiReader(bytes.NewReader(prefix), body))
+	}
+	value, err := streamJSONBody(io.MultiReader(bytes.NewReader(prefix), body), trie)
+	if err != nil {
+		return nil, errors.Wrap(err, "stream json response body failed")
+	}
+	return value, nil
+}
@@ -0,0 +1,100 @@
+package hrp
+
+import (
+	builtinJSON "encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSimpleJmesPath(t *testing.T) {
+	testData := []struct {
+		expr     string
+		segments []interface{}
+		ok       bool
+	}{
+		{"body.data.items[1].id", []interface{}{"body", "data", "items", 1, "id"}, true},
+		{`body."user-list"[0]`, []interface{}{"body", "user-list", 0}, true},
+		{"status_code", []interface{}{"status_code"}, true},
+		{"body.items[-1]", nil, false},
+		{"body.items[*].id", nil, false},
+		{"length(body.items)", nil, false},
+		{"body.items[?id > `1`]", nil, false},
+		{"body..id", nil, false},
+		{"body.[a, b]", nil, false},
+	}
+	for _, data := range testData {
+		segments, ok := parseSimpleJmesPath(data.expr)
+		assert.Equal(t, data.ok, ok, data.expr)
+		assert.Equal(t, data.segments, segments, data.expr)
+	}
+}
+
+func TestStreamJSONBody(t *testing.T) {
+	trie := newJSONPathNode()
+	trie.add([]interface{}{"data", "items", 1, "id"})
+	trie.add([]interface{}{"total"})
+	trie.add([]interface{}{"meta"})
+
+	body := `{"ignored": {"a": [1, 2, {"b": "c"}]}, "total": 3, "meta": {"page": 1},
+		"data": {"items": [{"id": 1}, {"id": 2, "name": "x"}, {"id": 3}]}}`
+	value, err := streamJSONBody(strings.NewReader(body), trie)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	assert.Equal(t, map[string]interface{}{
+		"total": builtinJSON.Number("3"),
+		"meta":  map[string]interface{}{"page": builtinJSON.Number("1")},
+		"data": map[string]interface{}{
+			"items": []interface{}{nil, map[string]interface{}{"id": builtinJSON.Number("2")}},
+		},
+	}, value)
+}
+
+func TestRunRequestWithJSONStream(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		items := make([]string, 1000)
+		for i := range items {
+			items[i] = fmt.Sprintf(`{"id": %d, "payload": "%s"}`, i, strings.Repeat("x", 100))
+		}
+		fmt.Fprintf(w, `{"code": 0, "data": {"items": [%s]}}`, strings.Join(items, ","))
+	}))
+	defer ts.Close()
+
+	newTestCase := func() *TestCase {
+		return &TestCase{
+			Config: NewConfig("json stream").SetBaseURL(ts.URL),
+			TestSteps: []IStep{
+				NewStep("large response").
+					GET("/items").
+					Extract().
+					WithJmesPath("body.data.items[2].id", "item_id").
+					Validate().
+					AssertEqual("status_code", 200, "check status code").
+					AssertEqual("body.code", 0, "check code").
+					AssertEqual("body.data.items[2].id", 2, "check item id").
+					AssertField("body.data.items[1].id", "less_than", "body.data.items[5].id", "check cross field").
+					AssertAnyOf(
+						Validator{Check: "body.data.items[3].id", Assert: "equals", Expect: 0},
+						Validator{Check: "body.data.items[4].id", Assert: "equals", Expect: 4},
+					),
+				NewStep("complex expression").
+					GET("/items").
+					Validate().
+					AssertLengthEqual("body.data.items", 1000, "check items count"),
+			},
+		}
+	}
+
+	sessionRunner := NewRunner(t).SetJSONStreamThreshold(1024).NewSessionRunner(newTestCase())
+	if !assert.Nil(t, sessionRunner.Start()) {
+		t.Fatal()
+	}
+	summary := sessionRunner.GetSummary()
+	assert.EqualValues(t, 2, summary.Records[0].ExportVars["item_id"])
+}
@@ -0,0 +1,150 @@
+package hrp
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/httprunner/httprunner/hrp/internal/builtin"
+)
+
+// jwtExtractorPrefix is prefix of extractor decoding jwt located by jmespath,
+// e.g. jwt:body.access_token|claims.sub, jwt:headers.Authorization|valid
+const jwtExtractorPrefix = "jwt:"
+
+// JWTConfig configures keys verifying signature of jwt decoded by jwt extractors,
+// the first of Key and JWKSURL which verifies signature is used.
+type JWTConfig struct {
+	Key     string  `json:"key,omitempty" yaml:"key,omitempty"`           // HMAC secret, PEM public key or certificate, JWK or JWKS in json
+	JWKSURL string  `json:"jwks_url,omitempty" yaml:"jwks_url,omitempty"` // url of JWKS, e.g. https://auth.example.com/.well-known/jwks.json
+	Leeway  float64 `json:"leeway,omitempty" yaml:"leeway,omitempty"`     // seconds of clock skew allowed when checking exp and nbf
+}
+
+// SetJWT sets keys verifying jwt of responses for current testcase.
+func (c *TConfig) SetJWT(cfg *JWTConfig) *TConfig {
+	c.JWT = cfg
+	return c
+}
+
+// jwtVerifier verifies jwt with keys of JWTConfig, which are parsed with config variables.
+type jwtVerifier struct {
+	keys   []string
+	leeway time.Duration
+}
+
+// newJWTVerifier parses keys of jwt config with config variables.
+func newJWTVerifier(cfg *JWTConfig, parser *Parser, variables map[string]interface{}) (*jwtVerifier, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	verifier := &jwtVerifier{leeway: time.Duration(cfg.Leeway * float64(time.Second))}
+	for _, key := range []string{cfg.Key, cfg.JWKSURL} {
+		if key == "" {
+			continue
+		}
+		parsed, err := parser.ParseString(key, variables)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse jwt key failed")
+		}
+		verifier.keys = append(verifier.keys, convertString(parsed))
+	}
+	return verifier, nil
+}
+
+func (v *jwtVerifier) verify(jwt *builtin.JWT) error {
+	if v == nil || len(v.keys) == 0 {
+		return errors.New("no jwt key configured")
+	}
+	var err error
+	for _, key := range v.keys {
+		if err = builtin.VerifyJWT(jwt, key); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// jwtMeta returns decoded jwt with verification results:
+// verified and verify_error of signature, expired and expires_in of exp claim,
+// and valid if signature is verified and jwt is within exp and nbf.
+func (v *jwtVerifier) jwtMeta(jwt *builtin.JWT) map[string]interface{} {
+	meta := jwt.Map()
+	var leeway time.Duration
+	if v != nil {
+		leeway = v.leeway
+	}
+	err := v.verify(jwt)
+	meta["verified"] = err == nil
+	if err != nil {
+		meta["verify_error"] = err.Error()
+	}
+
+	now := time.Now()
+	expired, premature := false, false
+	if exp, ok := jwt.TimeClaim("exp"); ok {
+		expired = now.After(exp.Add(leeway))
+		meta["expires_in"] = int64(exp.Sub(now).Seconds())
+	}
+	meta["expired"] = expired
+	if nbf, ok := jwt.TimeClaim("nbf"); ok {
+		premature = now.Add(leeway).Before(nbf)
+	}
+	meta["valid"] = err == nil && !expired && !premature
+	return meta
+}
+
+// splitJWTExtractor splits jwt extractor into jmespath of token and jmespath of decoded jwt.
+func splitJWTExtractor(expr string) (tokenPath, path string) {
+	expr = strings.TrimPrefix(expr, jwtExtractorPrefix)
+	if i := strings.Index(expr, "|"); i >= 0 {
+		return strings.TrimSpace(expr[:i]), strings.TrimSpace(expr[i+1:])
+	}
+	return strings.TrimSpace(expr), ""
+}
+
+// compileJWTExtractor checks whether jmespath of token and decoded jwt are valid.
+func (p *Parser) compileJWTExtractor(expr string) error {
+	tokenPath, path := splitJWTExtractor(expr)
+	if _, err := p.compileJmespath(tokenPath); err != nil {
+		return err
+	}
+	if path != "" {
+		if _, err := p.compileJmespath(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// searchJWT decodes jwt located by jmespath and searches decoded jwt,
+// token can be prefixed with Bearer, nil is returned if token is not found or invalid.
+func (v *responseObject) searchJWT(expr string) interface{} {
+	tokenPath, path := splitJWTExtractor(expr)
+	token, ok := v.searchJmespath(tokenPath).(string)
+	if !ok {
+		log.Error().Str("expr", expr).Msg("jwt not found")
+		return nil
+	}
+	jwt, err := builtin.ParseJWT(token)
+	if err != nil {
+		log.Error().Str("expr", expr).Err(err).Msg("decode jwt failed")
+		return nil
+	}
+	meta := v.jwtVerifier.jwtMeta(jwt)
+	if path == "" {
+		return meta
+	}
+	compiled, err := v.parser.compileJmespath(path)
+	if err != nil {
+		log.Error().Str("expr", expr).Err(err).Msg("compile jmespath failed")
+		return nil
+	}
+	value, err := compiled.Search(meta)
+	if err != nil {
+		log.Error().Str("expr", expr).Err(err).Msg("search jmespath failed")
+		return nil
+	}
+	return value
+}
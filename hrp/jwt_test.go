@@ -0,0 +1,81 @@
+package hrp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func signHS256(secret, claims string) string {
+	encode := base64.RawURLEncoding.EncodeToString
+	signingInput := encode([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." + encode([]byte(claims))
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signingInput))
+	return signingInput + "." + encode(mac.Sum(nil))
+}
+
+func TestJWTExtraction(t *testing.T) {
+	exp := time.Now().Add(time.Hour).Unix()
+	token := signHS256("s3cret", fmt.Sprintf(
+		`{"sub":"alice","aud":["api","web"],"exp":%d,"https://example.com/roles":["admin"]}`, exp))
+	expired := signHS256("s3cret", fmt.Sprintf(`{"sub":"alice","exp":%d}`, time.Now().Add(-time.Minute).Unix()))
+	forged := signHS256("other", `{"sub":"mallory"}`)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Authorization", "Bearer "+token)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token": "%s", "expired_token": "%s", "forged_token": "%s"}`, token, expired, forged)
+	}))
+	defer ts.Close()
+
+	testcase := &TestCase{
+		Config: NewConfig("jwt extraction").
+			SetBaseURL(ts.URL).
+			WithVariables(map[string]interface{}{"secret": "s3cret"}).
+			SetJWT(&JWTConfig{Key: "$secret"}),
+		TestSteps: []IStep{
+			NewStep("login").
+				POST("/login").
+				Extract().
+				WithJWTClaim("body.access_token", "sub", "user").
+				WithJmesPath("jwt:body.access_token|claims.aud", "audience").
+				Validate().
+				AssertJWTValid("body.access_token", "check token").
+				AssertJWTValid("headers.Authorization", "check bearer token").
+				AssertJWTClaim("body.access_token", "https://example.com/roles", []interface{}{"admin"}, "check roles").
+				AssertContains("jwt:body.access_token|claims.aud", "api", "check audience").
+				AssertGreater("jwt:body.access_token|expires_in", 3000, "check expires in").
+				AssertEqual("jwt:body.access_token|header.alg", "HS256", "check alg").
+				AssertEqual("jwt:body.expired_token|verified", true, "check expired signature").
+				AssertEqual("jwt:body.expired_token|expired", true, "check expired").
+				AssertEqual("jwt:body.expired_token|valid", false, "check expired invalid").
+				AssertEqual("jwt:body.forged_token|verified", false, "check forged").
+				AssertEqual("jwt:body.forged_token|verify_error", "jwt signature is invalid", "check forged error").
+				AssertEqual("jwt:body.missing_token", nil, "check missing token"),
+		},
+	}
+	sessionRunner := NewRunner(t).NewSessionRunner(testcase)
+	if assert.Nil(t, sessionRunner.Start()) {
+		assert.Equal(t, "alice", sessionRunner.sessionVariables["user"])
+		assert.Equal(t, []interface{}{"api", "web"}, sessionRunner.sessionVariables["audience"])
+	}
+
+	// jwt is not verified without key
+	testcase = &TestCase{
+		Config: NewConfig("jwt without key").SetBaseURL(ts.URL),
+		TestSteps: []IStep{
+			NewStep("login").
+				POST("/login").
+				Validate().
+				AssertEqual("jwt:body.access_token|claims.sub", "alice", "check decoded claims").
+				AssertEqual("jwt:body.access_token|verify_error", "no jwt key configured", "check not verified"),
+		},
+	}
+	assert.Nil(t, NewRunner(t).NewSessionRunner(testcase).Start())
+}
@@ -0,0 +1,56 @@
+package hrp
+
+import (
+	"github.com/rs/zerolog/log"
+)
+
+// RunListener subscribes events of testcases and steps, e.g. IDE plugins, progress bars and custom reporters.
+// Callbacks are called synchronously, and may be called concurrently when steps run as DAG.
+// Referenced testcases and nested steps of loop or branch also emit events.
+type RunListener interface {
+	OnCaseStart(testcase *TestCase)
+	OnStepStart(testcase *TestCase, step IStep)
+	// stepResult may be nil if step fails before running, e.g. parsing variables failed
+	OnStepEnd(testcase *TestCase, stepResult *StepResult, err error)
+	OnCaseEnd(testcase *TestCase, summary *TestCaseSummary, err error)
+}
+
+// BaseRunListener implements RunListener with no-op callbacks,
+// which can be embedded by listeners only interested in some events.
+type BaseRunListener struct{}
+
+func (BaseRunListener) OnCaseStart(testcase *TestCase)                                    {}
+func (BaseRunListener) OnStepStart(testcase *TestCase, step IStep)                        {}
+func (BaseRunListener) OnStepEnd(testcase *TestCase, stepResult *StepResult, err error)   {}
+func (BaseRunListener) OnCaseEnd(testcase *TestCase, summary *TestCaseSummary, err error) {}
+
+// AddListener adds listener to receive events of testcases and steps.
+func (r *HRPRunner) AddListener(l RunListener) *HRPRunner {
+	log.Info().Msg("[init] AddListener")
+	r.listeners = append(r.listeners, l)
+	return r
+}
+
+func (r *HRPRunner) onCaseStart(testcase *TestCase) {
+	for _, l := range r.listeners {
+		l.OnCaseStart(testcase)
+	}
+}
+
+func (r *HRPRunner) onStepStart(testcase *TestCase, step IStep) {
+	for _, l := range r.listeners {
+		l.OnStepStart(testcase, step)
+	}
+}
+
+func (r *HRPRunner) onStepEnd(testcase *TestCase, stepResult *StepResult, err error) {
+	for _, l := range r.listeners {
+		l.OnStepEnd(testcase, stepResult, err)
+	}
+}
+
+func (r *HRPRunner) onCaseEnd(testcase *TestCase, summary *TestCaseSummary, err error) {
+	for _, l := range r.listeners {
+		l.OnCaseEnd(testcase, summary, err)
+	}
+}
@@ -0,0 +1,86 @@
+package hrp
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordListener struct {
+	events []string
+}
+
+func (l *recordListener) OnCaseStart(testcase *TestCase) {
+	l.events = append(l.events, "case start: "+testcase.Config.Name)
+}
+
+func (l *recordListener) OnStepStart(testcase *TestCase, step IStep) {
+	l.events = append(l.events, "step start: "+step.Name())
+}
+
+func (l *recordListener) OnStepEnd(testcase *TestCase, stepResult *StepResult, err error) {
+	l.events = append(l.events, fmt.Sprintf("step end: %s, success: %v", stepResult.Name, stepResult.Success))
+}
+
+func (l *recordListener) OnCaseEnd(testcase *TestCase, summary *TestCaseSummary, err error) {
+	l.events = append(l.events, fmt.Sprintf("case end: %s, steps: %d, error: %v",
+		testcase.Config.Name, summary.Stat.Total, err != nil))
+}
+
+type countListener struct {
+	BaseRunListener
+	steps int
+}
+
+func (l *countListener) OnStepEnd(testcase *TestCase, stepResult *StepResult, err error) {
+	l.steps++
+}
+
+func TestRunCaseWithListener(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer ts.Close()
+
+	refCase := &TestCase{
+		Config: NewConfig("ref").SetBaseURL(ts.URL),
+		TestSteps: []IStep{
+			NewStep("get ref").GET("/ref"),
+		},
+	}
+	testcase := &TestCase{
+		Config: NewConfig("listener").SetBaseURL(ts.URL),
+		TestSteps: []IStep{
+			NewStep("get user").GET("/user"),
+			NewStep("call ref").CallRefCase(refCase),
+			NewStep("fail").GET("/fail").
+				Validate().
+				AssertEqual("status_code", 200, "check status code"),
+		},
+	}
+
+	listener := &recordListener{}
+	counter := &countListener{}
+	err := NewRunner(nil).AddListener(listener).AddListener(counter).Run(testcase)
+	assert.NotNil(t, err)
+	assert.Equal(t, []string{
+		"case start: listener",
+		"step start: get user",
+		"step end: get user, success: true",
+		"step start: call ref",
+		"case start: call ref",
+		"step start: get ref",
+		"step end: get ref, success: true",
+		"case end: call ref, steps: 1, error: false",
+		"step end: call ref, success: true",
+		"step start: fail",
+		"step end: fail, success: false",
+		"case end: listener, steps: 3, error: true",
+	}, listener.events)
+	assert.Equal(t, 4, counter.steps)
+}
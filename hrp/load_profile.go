@@ -0,0 +1,72 @@
+package hrp
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/httprunner/httprunner/hrp/internal/builtin"
+)
+
+// TLoadProfile represents mixed scenarios of load testing, e.g. 70% browse, 20% search and 10% checkout,
+// each iteration picks a scenario by weight, and stats are reported per scenario.
+type TLoadProfile struct {
+	Scenarios []*TLoadScenario `json:"scenarios" yaml:"scenarios"`
+}
+
+// TLoadScenario references testcase with weight in load profile.
+type TLoadScenario struct {
+	Name     string `json:"name,omitempty" yaml:"name,omitempty"` // scenario name in stats, default to testcase name
+	TestCase string `json:"testcase" yaml:"testcase"`             // testcase path relative to load profile
+	Weight   int    `json:"weight" yaml:"weight"`
+}
+
+// LoadProfile loads load profile file, and returns testcases of scenarios
+// with config name and weight overridden by scenarios.
+func LoadProfile(path string) ([]ITestCase, error) {
+	profile := &TLoadProfile{}
+	if err := builtin.LoadFile(path, profile); err != nil {
+		return nil, errors.Wrap(err, "load profile failed")
+	}
+	if len(profile.Scenarios) == 0 {
+		return nil, fmt.Errorf("no scenario found in load profile %s", path)
+	}
+
+	var totalWeight int
+	names := make(map[string]bool)
+	testCases := make([]ITestCase, 0, len(profile.Scenarios))
+	for _, scenario := range profile.Scenarios {
+		if scenario.Weight < 0 {
+			return nil, fmt.Errorf("invalid weight %d of scenario %s", scenario.Weight, scenario.TestCase)
+		}
+		totalWeight += scenario.Weight
+
+		casePath := TestCasePath(filepath.Join(filepath.Dir(path), scenario.TestCase))
+		testCase, err := casePath.ToTestCase()
+		if err != nil {
+			return nil, errors.Wrapf(err, "load testcase of scenario %s failed", scenario.TestCase)
+		}
+		if scenario.Name != "" {
+			testCase.Config.Name = scenario.Name
+		}
+		if names[testCase.Config.Name] {
+			return nil, fmt.Errorf("duplicate scenario name %s, stats are reported per scenario", testCase.Config.Name)
+		}
+		names[testCase.Config.Name] = true
+		testCase.Config.Weight = scenario.Weight
+		testCases = append(testCases, testCase)
+	}
+	if totalWeight == 0 {
+		return nil, fmt.Errorf("weights of scenarios in load profile %s are all zero", path)
+	}
+
+	for _, tc := range testCases {
+		config := tc.(*TestCase).Config
+		log.Info().Str("scenario", config.Name).
+			Str("ratio", fmt.Sprintf("%.1f%%", float64(config.Weight)*100/float64(totalWeight))).
+			Msg("load scenario")
+	}
+	return testCases, nil
+}
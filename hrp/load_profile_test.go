@@ -0,0 +1,56 @@
+package hrp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeLoadProfileFiles(t *testing.T, profile string) string {
+	dir := t.TempDir()
+	require.Nil(t, os.MkdirAll(filepath.Join(dir, "testcases"), 0o755))
+	for _, name := range []string{"browse", "search"} {
+		content := "config:\n  name: " + name + "\nteststeps:\n- name: get\n  request:\n    method: GET\n    url: http://127.0.0.1/" + name + "\n"
+		require.Nil(t, os.WriteFile(filepath.Join(dir, "testcases", name+".yml"), []byte(content), 0o644))
+	}
+	path := filepath.Join(dir, "load.yml")
+	require.Nil(t, os.WriteFile(path, []byte(profile), 0o644))
+	return path
+}
+
+func TestLoadProfile(t *testing.T) {
+	path := writeLoadProfileFiles(t, `
+scenarios:
+- testcase: testcases/browse.yml
+  weight: 70
+- name: search products
+  testcase: testcases/search.yml
+  weight: 30
+`)
+	testCases, err := LoadProfile(path)
+	require.Nil(t, err)
+	require.Len(t, testCases, 2)
+	browse := testCases[0].(*TestCase)
+	assert.Equal(t, "browse", browse.Config.Name)
+	assert.Equal(t, 70, browse.Config.Weight)
+	search := testCases[1].(*TestCase)
+	assert.Equal(t, "search products", search.Config.Name)
+	assert.Equal(t, 30, search.Config.Weight)
+}
+
+func TestLoadProfileInvalid(t *testing.T) {
+	profiles := []string{
+		"scenarios: []\n",
+		"scenarios:\n- testcase: testcases/browse.yml\n  weight: 0\n",
+		"scenarios:\n- testcase: testcases/browse.yml\n  weight: -1\n",
+		"scenarios:\n- testcase: testcases/browse.yml\n  weight: 1\n- testcase: testcases/browse.yml\n  weight: 1\n",
+		"scenarios:\n- testcase: testcases/checkout.yml\n  weight: 1\n",
+	}
+	for _, profile := range profiles {
+		_, err := LoadProfile(writeLoadProfileFiles(t, profile))
+		assert.NotNil(t, err, profile)
+	}
+}
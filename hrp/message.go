@@ -0,0 +1,122 @@
+package hrp
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/httprunner/httprunner/hrp/internal/builtin"
+	"github.com/httprunner/httprunner/hrp/internal/json"
+)
+
+// messages of broker steps, e.g. MQTT and Kafka, are received in background and buffered in session,
+// thus message emitted by a former request step can still be awaited by the following receive step.
+
+const defaultMessageTimeout = 10.0 // seconds
+
+// MessageFilter selects the awaited message, value extracted by jmespath from message should equal to expected,
+// payload of message is decoded as json if possible, e.g. payload.order_id of MQTT message, value.order_id of Kafka message
+type MessageFilter struct {
+	JmesPath string      `json:"jmespath" yaml:"jmespath"`
+	Expect   interface{} `json:"expect" yaml:"expect"` // variables and functions are supported
+}
+
+// messageBuffer stores received messages until they are consumed by receive steps.
+type messageBuffer struct {
+	mutex    sync.Mutex
+	messages []map[string]interface{}
+	notify   chan struct{}
+}
+
+func newMessageBuffer() *messageBuffer {
+	return &messageBuffer{
+		notify: make(chan struct{}, 1),
+	}
+}
+
+func (b *messageBuffer) push(message map[string]interface{}) {
+	b.mutex.Lock()
+	b.messages = append(b.messages, message)
+	b.mutex.Unlock()
+	select {
+	case b.notify <- struct{}{}:
+	default:
+	}
+}
+
+// pop removes and returns the first buffered message accepted by match, nil is returned if not found.
+func (b *messageBuffer) pop(match func(message map[string]interface{}) bool) map[string]interface{} {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for i, message := range b.messages {
+		if match(message) {
+			b.messages = append(b.messages[:i], b.messages[i+1:]...)
+			return message
+		}
+	}
+	return nil
+}
+
+// wait blocks until a message accepted by match is received, or ctx is done when step times out or run is canceled.
+func (b *messageBuffer) wait(ctx context.Context, match func(message map[string]interface{}) bool) (
+	map[string]interface{}, error) {
+
+	for {
+		if message := b.pop(match); message != nil {
+			return message, nil
+		}
+		select {
+		case <-b.notify:
+		case <-ctx.Done():
+			return nil, errors.Wrap(ctx.Err(), "no matched message received")
+		}
+	}
+}
+
+// decodeMessagePayload decodes json payload, other payload is kept as string.
+func decodeMessagePayload(payload []byte) interface{} {
+	var data interface{}
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return string(payload)
+	}
+	return data
+}
+
+// encodeMessagePayload encodes map and slice payload as json, string and bytes are published as is.
+func encodeMessagePayload(payload interface{}) ([]byte, error) {
+	switch v := payload.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return v, nil
+	default:
+		return json.Marshal(v)
+	}
+}
+
+// messageMatcher returns function to check if message matches parsed filter, all messages match nil filter.
+func (r *SessionRunner) messageMatcher(filter *MessageFilter, stepVariables map[string]interface{}) (
+	func(message map[string]interface{}) bool, error) {
+
+	if filter == nil || filter.JmesPath == "" {
+		return func(map[string]interface{}) bool { return true }, nil
+	}
+	expected, err := r.parser.Parse(filter.Expect, stepVariables)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse message filter failed")
+	}
+	compiled, err := r.parser.compileJmespath(filter.JmesPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "compile message filter failed")
+	}
+	return func(message map[string]interface{}) bool {
+		actual, err := compiled.Search(message)
+		if err != nil {
+			return false
+		}
+		return len(builtin.DiffJSON(expected, actual)) == 0
+	}, nil
+}
@@ -0,0 +1,219 @@
+package hrp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultMonitorInterval         = 60 * time.Second
+	defaultMonitorWindow           = 100
+	defaultMonitorFailureThreshold = 3
+	pagerDutyEventsURL             = "https://events.pagerduty.com/v2/enqueue"
+)
+
+// MonitorConfig configures scheduled runs of testcases for synthetic monitoring.
+type MonitorConfig struct {
+	Interval         time.Duration   // interval between starts of two rounds, default to 60s
+	MaxRounds        int             // stop after max rounds, run forever if <= 0
+	Window           int             // number of latest rounds to calculate rolling success rate, default to 100
+	FailureThreshold int             // alert when consecutive failed rounds reach threshold, default to 3
+	Webhooks         []*AlertWebhook // webhooks to notify alert and recovery
+}
+
+// MonitorStats represents rolling stats of scheduled runs.
+type MonitorStats struct {
+	Rounds              int       `json:"rounds"`
+	Failures            int       `json:"failures"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	SuccessRate         float64   `json:"success_rate"` // success rate of latest rounds in window
+	LastError           string    `json:"last_error,omitempty"`
+	LastRoundAt         time.Time `json:"last_round_at"`
+}
+
+// AlertWebhook represents webhook fired on consecutive failures and recovery of scheduled runs.
+type AlertWebhook struct {
+	Type       webhookType `json:"type" yaml:"type"`                                   // generic (default), slack, lark, dingtalk, pagerduty
+	URL        string      `json:"url" yaml:"url"`                                     // incoming webhook url, default to PagerDuty Events API v2 for pagerduty
+	RoutingKey string      `json:"routing_key,omitempty" yaml:"routing_key,omitempty"` // integration key of PagerDuty service
+}
+
+// ParseAlertWebhook parses alert webhook from url, type is detected by host of url,
+// PagerDuty integration key is specified as pagerduty://<routing_key>.
+func ParseAlertWebhook(rawURL string) (*AlertWebhook, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse alert webhook url failed")
+	}
+	switch u.Scheme {
+	case "pagerduty":
+		return &AlertWebhook{Type: webhookPagerDuty, URL: pagerDutyEventsURL, RoutingKey: u.Host}, nil
+	case "http", "https":
+		return &AlertWebhook{Type: detectWebhookType(u), URL: rawURL}, nil
+	}
+	return nil, fmt.Errorf("unexpected alert webhook scheme %s, should be http, https or pagerduty", u.Scheme)
+}
+
+// payload renders message of alert webhook in format of webhook type.
+func (w *AlertWebhook) payload(event string, message string, stats MonitorStats) interface{} {
+	if w.Type == webhookPagerDuty {
+		action := "trigger"
+		if event == "recovery" {
+			action = "resolve"
+		}
+		return map[string]interface{}{
+			"routing_key":  w.RoutingKey,
+			"event_action": action,
+			"dedup_key":    "hrp-monitor",
+			"payload": map[string]interface{}{
+				"summary":  message,
+				"source":   "hrp",
+				"severity": "critical",
+			},
+		}
+	}
+	if payload := textPayload(w.Type, message); payload != nil {
+		return payload
+	}
+	return map[string]interface{}{
+		"event":   event,
+		"message": message,
+		"stats":   stats,
+	}
+}
+
+// post sends message to webhook, failures are logged without interrupting scheduled runs.
+func (w *AlertWebhook) post(client *http.Client, event string, message string, stats MonitorStats) {
+	targetURL := w.URL
+	if targetURL == "" && w.Type == webhookPagerDuty {
+		targetURL = pagerDutyEventsURL
+	}
+	if err := postWebhook(client, targetURL, w.payload(event, message, stats)); err != nil {
+		log.Error().Err(err).Str("type", string(w.Type)).Msg("post alert webhook failed")
+	}
+}
+
+// monitor maintains rolling stats of scheduled runs and fires alerts.
+type monitor struct {
+	cfg     *MonitorConfig
+	client  *http.Client
+	stats   MonitorStats
+	results []bool // results of latest rounds in window, used as ring buffer
+	alerted bool   // alert has been fired and not recovered yet
+}
+
+func newMonitor(cfg *MonitorConfig) *monitor {
+	c := *cfg
+	if c.Interval <= 0 {
+		c.Interval = defaultMonitorInterval
+	}
+	if c.Window <= 0 {
+		c.Window = defaultMonitorWindow
+	}
+	if c.FailureThreshold <= 0 {
+		c.FailureThreshold = defaultMonitorFailureThreshold
+	}
+	return &monitor{
+		cfg:    &c,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// record updates stats with result of round, alert is fired when consecutive failures reach threshold,
+// and recovery is notified when round succeeds after alert.
+func (m *monitor) record(summary *Summary, err error) {
+	success := err == nil && (summary == nil || summary.Success)
+	m.stats.Rounds++
+	m.stats.LastRoundAt = time.Now()
+	if len(m.results) < m.cfg.Window {
+		m.results = append(m.results, success)
+	} else {
+		m.results[(m.stats.Rounds-1)%m.cfg.Window] = success
+	}
+	var passed int
+	for _, result := range m.results {
+		if result {
+			passed++
+		}
+	}
+	m.stats.SuccessRate = float64(passed) / float64(len(m.results))
+
+	if success {
+		m.stats.ConsecutiveFailures = 0
+		if m.alerted {
+			m.alerted = false
+			m.notify("recovery", fmt.Sprintf("[hrp monitor] testcases recovered, success rate %.1f%% of latest %d rounds",
+				m.stats.SuccessRate*100, len(m.results)))
+		}
+		return
+	}
+
+	m.stats.Failures++
+	m.stats.ConsecutiveFailures++
+	if err != nil {
+		m.stats.LastError = err.Error()
+	} else {
+		m.stats.LastError = strings.Join(failedTestCases(summary), ", ") + " failed"
+	}
+	if !m.alerted && m.stats.ConsecutiveFailures >= m.cfg.FailureThreshold {
+		m.alerted = true
+		m.notify("alert", fmt.Sprintf("[hrp monitor] testcases failed %d consecutive rounds, "+
+			"success rate %.1f%% of latest %d rounds, last error: %s",
+			m.stats.ConsecutiveFailures, m.stats.SuccessRate*100, len(m.results), m.stats.LastError))
+	}
+}
+
+func (m *monitor) notify(event string, message string) {
+	log.Warn().Str("event", event).Msg(message)
+	for _, webhook := range m.cfg.Webhooks {
+		webhook.post(m.client, event, message, m.stats)
+	}
+}
+
+// failedTestCases returns names of failed testcases in summary.
+func failedTestCases(summary *Summary) []string {
+	var names []string
+	for _, caseSummary := range summary.Details {
+		if !caseSummary.Success {
+			names = append(names, caseSummary.Name)
+		}
+	}
+	return names
+}
+
+// RunForever runs testcases repeatedly on schedule for synthetic monitoring, until context is done
+// or max rounds are reached, testcase files are reloaded in each round.
+// Rolling success rate is maintained and alert webhooks are fired on consecutive failures.
+func (r *HRPRunner) RunForever(ctx context.Context, cfg *MonitorConfig, testcases ...ITestCase) *MonitorStats {
+	m := newMonitor(cfg)
+	log.Info().Dur("interval", m.cfg.Interval).Int("maxRounds", m.cfg.MaxRounds).Msg("run testcases on schedule")
+	for {
+		start := time.Now()
+		summary, err := r.runAndNotify(ctx, testcases...)
+		m.record(summary, err)
+		log.Info().Int("round", m.stats.Rounds).Bool("success", m.stats.ConsecutiveFailures == 0).
+			Float64("successRate", m.stats.SuccessRate).Msg("run round end")
+		if m.cfg.MaxRounds > 0 && m.stats.Rounds >= m.cfg.MaxRounds {
+			break
+		}
+
+		// wait until next round, starts immediately if round takes longer than interval
+		timer := time.NewTimer(time.Until(start.Add(m.cfg.Interval)))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			stats := m.stats
+			return &stats
+		case <-timer.C:
+		}
+	}
+	stats := m.stats
+	return &stats
+}
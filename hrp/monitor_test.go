@@ -0,0 +1,111 @@
+package hrp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAlertWebhook(t *testing.T) {
+	testData := []struct {
+		rawURL  string
+		webhook *AlertWebhook
+	}{
+		{"https://hooks.slack.com/services/T0/B0/xx", &AlertWebhook{Type: webhookSlack, URL: "https://hooks.slack.com/services/T0/B0/xx"}},
+		{"https://open.feishu.cn/open-apis/bot/v2/hook/xx", &AlertWebhook{Type: webhookLark, URL: "https://open.feishu.cn/open-apis/bot/v2/hook/xx"}},
+		{"pagerduty://R0UT1NGK3Y", &AlertWebhook{Type: webhookPagerDuty, URL: pagerDutyEventsURL, RoutingKey: "R0UT1NGK3Y"}},
+		{"http://alert.internal/hrp", &AlertWebhook{Type: webhookGeneric, URL: "http://alert.internal/hrp"}},
+	}
+	for _, data := range testData {
+		webhook, err := ParseAlertWebhook(data.rawURL)
+		if assert.Nil(t, err) {
+			assert.Equal(t, data.webhook, webhook)
+		}
+	}
+	_, err := ParseAlertWebhook("ftp://alert.internal")
+	assert.NotNil(t, err)
+}
+
+func TestRunForever(t *testing.T) {
+	// rounds 2, 3 and 4 fail
+	var mutex sync.Mutex
+	var rounds int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		rounds++
+		if rounds >= 2 && rounds <= 4 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+	var events []string
+	webhookServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mutex.Lock()
+		defer mutex.Unlock()
+		events = append(events, string(body))
+	}))
+	defer webhookServer.Close()
+
+	testcase := &TestCase{
+		Config: NewConfig("monitor").SetBaseURL(ts.URL),
+		TestSteps: []IStep{
+			NewStep("health").GET("/health").
+				Validate().
+				AssertStatusOK("check status code"),
+		},
+	}
+	cfg := &MonitorConfig{
+		Interval:         time.Millisecond,
+		MaxRounds:        6,
+		Window:           4,
+		FailureThreshold: 2,
+		Webhooks: []*AlertWebhook{
+			{Type: webhookSlack, URL: webhookServer.URL},
+			{Type: webhookGeneric, URL: webhookServer.URL},
+		},
+	}
+	stats := NewRunner(nil).RunForever(context.Background(), cfg, testcase)
+	assert.Equal(t, 6, stats.Rounds)
+	assert.Equal(t, 3, stats.Failures)
+	assert.Equal(t, 0, stats.ConsecutiveFailures)
+	assert.Equal(t, 0.5, stats.SuccessRate) // rounds 3 ~ 6
+	assert.Contains(t, stats.LastError, "503")
+
+	// alert fired once on the 2nd consecutive failure, recovery notified once
+	if assert.Len(t, events, 4) {
+		assert.Contains(t, events[0], `"text":"[hrp monitor] testcases failed 2 consecutive rounds`)
+		assert.Contains(t, events[1], `"event":"alert"`)
+		assert.Contains(t, events[2], `"text":"[hrp monitor] testcases recovered`)
+		assert.Contains(t, events[3], `"event":"recovery"`)
+	}
+
+	// summary of each round is sent to notifiers
+	notifier := &testCountNotifier{}
+	NewRunner(nil).AddNotifiers(notifier).RunForever(context.Background(), &MonitorConfig{
+		Interval: time.Millisecond, MaxRounds: 2,
+	}, testcase)
+	assert.Equal(t, 2, notifier.count)
+
+	// stop when context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	stats = NewRunner(nil).RunForever(ctx, &MonitorConfig{Interval: time.Hour}, testcase)
+	assert.Equal(t, 1, stats.Rounds)
+}
+
+type testCountNotifier struct {
+	count int
+}
+
+func (n *testCountNotifier) Notify(summary *Summary, runErr error) error {
+	n.count++
+	return nil
+}
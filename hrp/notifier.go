@@ -0,0 +1,239 @@
+package hrp
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/httprunner/httprunner/hrp/internal/builtin"
+	"github.com/httprunner/httprunner/hrp/internal/json"
+)
+
+// Notifier sends summary of run after HRPRunner.Run completes, e.g. to chat groups,
+// runErr is the error aborting run if any, then summary only contains testcases finished before.
+type Notifier interface {
+	Notify(summary *Summary, runErr error) error
+}
+
+// AddNotifiers adds notifiers to send summary after run completes.
+func (r *HRPRunner) AddNotifiers(notifiers ...Notifier) *HRPRunner {
+	log.Info().Int("count", len(notifiers)).Msg("[init] AddNotifiers")
+	r.notifiers = append(r.notifiers, notifiers...)
+	return r
+}
+
+// notify sends summary to all notifiers, failures are logged without changing result of run.
+func (r *HRPRunner) notify(summary *Summary, runErr error) {
+	for _, notifier := range r.notifiers {
+		if err := notifier.Notify(summary, runErr); err != nil {
+			log.Error().Err(err).Msg("send run summary notification failed")
+		}
+	}
+}
+
+// webhookType represents message format of webhook.
+type webhookType string
+
+const (
+	webhookGeneric   webhookType = "generic"
+	webhookSlack     webhookType = "slack"
+	webhookLark      webhookType = "lark"
+	webhookDingTalk  webhookType = "dingtalk"
+	webhookPagerDuty webhookType = "pagerduty"
+)
+
+// detectWebhookType detects message format of webhook by host of url.
+func detectWebhookType(u *url.URL) webhookType {
+	switch u.Host {
+	case "hooks.slack.com":
+		return webhookSlack
+	case "open.feishu.cn", "open.larksuite.com":
+		return webhookLark
+	case "oapi.dingtalk.com":
+		return webhookDingTalk
+	}
+	return webhookGeneric
+}
+
+// textPayload renders text message in format of chat webhook, nil is returned for other webhooks.
+func textPayload(typ webhookType, text string) interface{} {
+	switch typ {
+	case webhookSlack:
+		return map[string]interface{}{"text": text}
+	case webhookLark:
+		return map[string]interface{}{
+			"msg_type": "text",
+			"content":  map[string]interface{}{"text": text},
+		}
+	case webhookDingTalk:
+		return map[string]interface{}{
+			"msgtype": "text",
+			"text":    map[string]interface{}{"content": text},
+		}
+	}
+	return nil
+}
+
+// postWebhook posts payload to webhook in json.
+func postWebhook(client *http.Client, webhookURL string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return errors.Wrap(err, "marshal webhook payload failed")
+	}
+	data = builtin.MaskSecretsInJSON(data)
+	resp, err := client.Post(webhookURL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return errors.Wrap(err, "post webhook failed")
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("post webhook failed, unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// WebhookNotifier posts run summary to incoming webhook of Slack, Lark/Feishu, DingTalk,
+// or generic http endpoint which receives summary in json.
+type WebhookNotifier struct {
+	typ    webhookType
+	url    string
+	client *http.Client
+}
+
+func newWebhookNotifier(typ webhookType, webhookURL string) *WebhookNotifier {
+	return &WebhookNotifier{
+		typ:    typ,
+		url:    webhookURL,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// NewSlackNotifier creates notifier posting run summary to Slack incoming webhook.
+func NewSlackNotifier(webhookURL string) *WebhookNotifier {
+	return newWebhookNotifier(webhookSlack, webhookURL)
+}
+
+// NewLarkNotifier creates notifier posting run summary to Lark/Feishu bot webhook.
+func NewLarkNotifier(webhookURL string) *WebhookNotifier {
+	return newWebhookNotifier(webhookLark, webhookURL)
+}
+
+// NewDingTalkNotifier creates notifier posting run summary to DingTalk robot webhook.
+func NewDingTalkNotifier(webhookURL string) *WebhookNotifier {
+	return newWebhookNotifier(webhookDingTalk, webhookURL)
+}
+
+// NewWebhookNotifier creates notifier posting run summary in json to generic http endpoint.
+func NewWebhookNotifier(webhookURL string) *WebhookNotifier {
+	return newWebhookNotifier(webhookGeneric, webhookURL)
+}
+
+// ParseNotifier creates notifier from webhook url, type is detected by host of url,
+// e.g. hooks.slack.com for Slack, open.feishu.cn for Lark, oapi.dingtalk.com for DingTalk.
+func ParseNotifier(rawURL string) (*WebhookNotifier, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse notifier webhook url failed")
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unexpected notifier webhook scheme %s, should be http or https", u.Scheme)
+	}
+	return newWebhookNotifier(detectWebhookType(u), rawURL), nil
+}
+
+// Notify posts run summary to webhook.
+func (n *WebhookNotifier) Notify(summary *Summary, runErr error) error {
+	payload := textPayload(n.typ, formatSummaryText(summary, runErr))
+	if payload == nil {
+		payload = newNotification(summary, runErr)
+	}
+	return postWebhook(n.client, n.url, payload)
+}
+
+// notification represents run summary posted to generic webhook.
+type notification struct {
+	Success     bool          `json:"success"`
+	Stat        *Stat         `json:"stat"`
+	Duration    float64       `json:"duration"` // seconds
+	FailedSteps []*failedStep `json:"failed_steps,omitempty"`
+	Error       string        `json:"error,omitempty"` // error aborting run
+	Platform    *Platform     `json:"platform,omitempty"`
+	StartAt     time.Time     `json:"start_at"`
+}
+
+type failedStep struct {
+	TestCase string `json:"testcase"`
+	Step     string `json:"step"`
+	Message  string `json:"message,omitempty"`
+}
+
+func newNotification(summary *Summary, runErr error) *notification {
+	n := &notification{
+		Success:     summary.Success && runErr == nil,
+		Stat:        summary.Stat,
+		Duration:    summaryDuration(summary),
+		FailedSteps: collectFailedSteps(summary),
+		Platform:    summary.Platform,
+		StartAt:     summary.Time.StartAt,
+	}
+	if runErr != nil {
+		n.Error = runErr.Error()
+	}
+	return n
+}
+
+// summaryDuration returns duration of run in seconds, which is not recorded in summary if run is aborted.
+func summaryDuration(summary *Summary) float64 {
+	if summary.Time.Duration > 0 {
+		return summary.Time.Duration
+	}
+	return time.Since(summary.Time.StartAt).Seconds()
+}
+
+func collectFailedSteps(summary *Summary) []*failedStep {
+	var steps []*failedStep
+	for _, caseSummary := range summary.Details {
+		for _, record := range caseSummary.Records {
+			if record.Success {
+				continue
+			}
+			steps = append(steps, &failedStep{
+				TestCase: caseSummary.Name,
+				Step:     record.Name,
+				Message:  record.Attachment,
+			})
+		}
+	}
+	return steps
+}
+
+// formatSummaryText formats run summary as text message of chat webhooks.
+func formatSummaryText(summary *Summary, runErr error) string {
+	result := "passed"
+	if !summary.Success || runErr != nil {
+		result = "failed"
+	}
+	var b strings.Builder
+	stat := summary.Stat
+	fmt.Fprintf(&b, "[hrp] run %s in %.2fs\n", result, summaryDuration(summary))
+	fmt.Fprintf(&b, "testcases: %d total, %d passed, %d failed\n",
+		stat.TestCases.Total, stat.TestCases.Success, stat.TestCases.Fail)
+	fmt.Fprintf(&b, "teststeps: %d total, %d passed, %d failed",
+		stat.TestSteps.Total, stat.TestSteps.Successes, stat.TestSteps.Failures)
+	if steps := collectFailedSteps(summary); len(steps) > 0 {
+		b.WriteString("\nfailed steps:")
+		for _, step := range steps {
+			fmt.Fprintf(&b, "\n- %s / %s: %s", step.TestCase, step.Step, step.Message)
+		}
+	}
+	if runErr != nil {
+		fmt.Fprintf(&b, "\naborted: %s", runErr.Error())
+	}
+	return b.String()
+}
@@ -0,0 +1,81 @@
+package hrp
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/httprunner/httprunner/hrp/internal/json"
+)
+
+func TestRunWithNotifiers(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer ts.Close()
+	var mutex sync.Mutex
+	payloads := make(map[string]string)
+	webhookServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mutex.Lock()
+		defer mutex.Unlock()
+		payloads[r.URL.Path] = string(body)
+	}))
+	defer webhookServer.Close()
+
+	newTestCase := func() *TestCase {
+		return &TestCase{
+			Config: NewConfig("notify").SetBaseURL(ts.URL),
+			TestSteps: []IStep{
+				NewStep("pass").GET("/pass").
+					Validate().
+					AssertStatusOK("check status code"),
+				NewStep("fail").GET("/fail").
+					Validate().
+					AssertStatusOK("check status code"),
+			},
+		}
+	}
+	runner := NewRunner(nil).SetFailfast(false).AddNotifiers(
+		NewSlackNotifier(webhookServer.URL+"/slack"),
+		NewDingTalkNotifier(webhookServer.URL+"/dingtalk"),
+		NewWebhookNotifier(webhookServer.URL+"/generic"),
+	)
+	assert.Nil(t, runner.Run(newTestCase()))
+
+	var slack map[string]string
+	if assert.Nil(t, json.Unmarshal([]byte(payloads["/slack"]), &slack)) {
+		assert.Regexp(t, `^\[hrp\] run failed in [\d.]+s\n`, slack["text"])
+		assert.Contains(t, slack["text"], "testcases: 1 total, 0 passed, 1 failed\n")
+		assert.Contains(t, slack["text"], "teststeps: 2 total, 1 passed, 1 failed\n")
+		assert.Contains(t, slack["text"], "- notify / fail: step validation failed: status_code equals 200, got 500")
+	}
+	assert.Contains(t, payloads["/dingtalk"], `"msgtype":"text"`)
+
+	var generic notification
+	if assert.Nil(t, json.Unmarshal([]byte(payloads["/generic"]), &generic)) {
+		assert.False(t, generic.Success)
+		assert.Equal(t, 2, generic.Stat.TestSteps.Total)
+		if assert.Len(t, generic.FailedSteps, 1) {
+			assert.Equal(t, "fail", generic.FailedSteps[0].Step)
+		}
+		assert.Empty(t, generic.Error)
+	}
+
+	// notify when run is aborted
+	notifier, err := ParseNotifier(webhookServer.URL + "/aborted")
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	assert.NotNil(t, NewRunner(nil).AddNotifiers(notifier).Run(newTestCase()))
+	assert.Contains(t, payloads["/aborted"], `"error":"abort running due to failfast setting`)
+
+	_, err = ParseNotifier("ftp://notify.internal")
+	assert.NotNil(t, err)
+}
@@ -19,11 +19,14 @@ import (
 )
 
 func newParser() *Parser {
-	return &Parser{}
+	return &Parser{
+		exprCache: newExpressionCache(),
+	}
 }
 
 type Parser struct {
-	plugin funplugin.IPlugin // plugin is used to call functions
+	plugin    funplugin.IPlugin // plugin is used to call functions
+	exprCache *expressionCache  // compiled jmespath and regexp expressions
 }
 
 func buildURL(baseURL, stepURL string) string {
@@ -138,9 +141,9 @@ const (
 )
 
 var (
-	regexCompileVariable = regexp.MustCompile(fmt.Sprintf(`\$\{(%s)\}|\$(%s)`, regexVariable, regexVariable))     // parse ${var} or $var
-	regexCompileFunction = regexp.MustCompile(fmt.Sprintf(`\$\{(%s)\(([\$\w\.\-/\s=,]*)\)\}`, regexFunctionName)) // parse ${func1($a, $b)}
-	regexCompileNumber   = regexp.MustCompile(regexNumber)                                                        // parse number
+	regexCompileVariable = regexp.MustCompile(fmt.Sprintf(`\$\{(%s)\}|\$(%s)`, regexVariable, regexVariable))        // parse ${var} or $var
+	regexCompileFunction = regexp.MustCompile(fmt.Sprintf(`\$\{(%s)\(([\$\w\.\-/:\s=,"']*)\)\}`, regexFunctionName)) // parse ${func1($a, $b, "c")}
+	regexCompileNumber   = regexp.MustCompile(regexNumber)                                                           // parse number
 )
 
 // ParseString parse string with variables
@@ -175,8 +178,9 @@ func (p *Parser) ParseString(raw string, variablesMapping map[string]interface{}
 		}
 
 		// search function like ${func($a, $b)}
+		// function should be matched at current $, instead of the following ones
 		funcMatched := regexCompileFunction.FindStringSubmatch(remainedString)
-		if len(funcMatched) == 3 {
+		if len(funcMatched) == 3 && strings.HasPrefix(remainedString, funcMatched[0]) {
 			funcName := funcMatched[1]
 			argsStr := funcMatched[2]
 			arguments, err := parseFunctionArguments(argsStr)
@@ -226,6 +230,13 @@ func (p *Parser) ParseString(raw string, variablesMapping map[string]interface{}
 			if !ok {
 				return raw, fmt.Errorf("variable %s not found", varName)
 			}
+			// evaluate lazy variable when referenced
+			if lazy, ok := varValue.(*lazyValue); ok {
+				var err error
+				if varValue, err = lazy.evaluate(p, variablesMapping); err != nil {
+					return raw, err
+				}
+			}
 
 			if fmt.Sprintf("${%s}", varName) == raw || fmt.Sprintf("$%s", varName) == raw {
 				// raw string is a variable, $var or ${var}, return its value directly
@@ -325,7 +336,9 @@ func mergeValidators(validators, overriddenValidators []interface{}) []interface
 	for _, validator := range validators {
 		flag := true
 		for _, mergedValidator := range mergedValidators {
-			if validator.(Validator).Check == mergedValidator.(Validator).Check {
+			// validator groups have no check and are never overridden
+			check := validator.(Validator).Check
+			if check != "" && check == mergedValidator.(Validator).Check {
 				flag = false
 				break
 			}
@@ -380,8 +393,8 @@ func parseFunctionArguments(argsStr string) ([]interface{}, error) {
 		return []interface{}{}, nil
 	}
 
-	// split arguments by comma
-	args := strings.Split(argsStr, ",")
+	// split arguments by comma outside quotes
+	args := splitFunctionArguments(argsStr)
 	arguments := make([]interface{}, len(args))
 	for index, arg := range args {
 		arg = strings.TrimSpace(arg)
@@ -389,6 +402,12 @@ func parseFunctionArguments(argsStr string) ([]interface{}, error) {
 			continue
 		}
 
+		// quoted argument is kept as string, e.g. "CN", '010'
+		if len(arg) >= 2 && (arg[0] == '"' || arg[0] == '\'') && arg[len(arg)-1] == arg[0] {
+			arguments[index] = arg[1 : len(arg)-1]
+			continue
+		}
+
 		// parse argument to number if possible
 		arg, err := literalEval(arg)
 		if err != nil {
@@ -400,6 +419,27 @@ func parseFunctionArguments(argsStr string) ([]interface{}, error) {
 	return arguments, nil
 }
 
+// splitFunctionArguments splits arguments by comma, commas in quoted arguments are kept.
+func splitFunctionArguments(argsStr string) []string {
+	var args []string
+	var quote rune
+	start := 0
+	for i, c := range argsStr {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ',':
+			args = append(args, argsStr[start:i])
+			start = i + 1
+		}
+	}
+	return append(args, argsStr[start:])
+}
+
 func (p *Parser) ParseVariables(variables map[string]interface{}) (map[string]interface{}, error) {
 	parsedVariables := make(map[string]interface{})
 	var traverseRounds int
@@ -649,8 +689,10 @@ func initParameterIterator(cfg *TConfig, mode string) (err error) {
 	}
 	// parse config parameters setting
 	if cfg.ParametersSetting == nil {
-		cfg.ParametersSetting = &TParamsConfig{Iterators: []*Iterator{}}
+		cfg.ParametersSetting = &TParamsConfig{}
 	}
+	// reset iterators exhausted by previous run of the same testcase
+	cfg.ParametersSetting.Iterators = []*Iterator{}
 	// boomer模式下不限制迭代次数
 	if mode == "boomer" {
 		cfg.ParametersSetting.Iteration = -1
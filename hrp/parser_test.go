@@ -95,6 +95,7 @@ func TestRegexCompileFunction(t *testing.T) {
 		"${func1($a, 123)}",
 		"${func1(123, $b)}",
 		"abc${func1(123, $b)}123",
+		`${func1("CN", 'a')}`,
 	}
 
 	for _, expr := range testData {
@@ -543,6 +544,8 @@ func TestParseFunctionArguments(t *testing.T) {
 		{"1, -2.3", []interface{}{1, -2.3}},
 		{"1,,2", []interface{}{1, nil, 2}},
 		{" $var1 , 2 ", []interface{}{"$var1", 2}},
+		{`"CN"`, []interface{}{"CN"}},
+		{`'010', "a,b", ""`, []interface{}{"010", "a,b", ""}},
 	}
 
 	for _, data := range testData {
@@ -591,6 +594,7 @@ func TestParseDataStringWithFunctions(t *testing.T) {
 		{"${max($a, $b)}", 12.3},
 		{"abc${max($a, $b)}123", "abc12.3123"},
 		{"abc${max($a, 3.45)}123", "abc12.3123"},
+		{"$a-${max($a, $b)}", "12.3-12.3"}, // variable followed by function
 	}
 
 	for _, data := range testData2 {
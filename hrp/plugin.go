@@ -59,6 +59,10 @@ func initPlugin(path string, logOn bool) (plugin funplugin.IPlugin, err error) {
 }
 
 func locatePlugin(path string) (pluginPath string, err error) {
+	// testcase read from stdin locates plugin from current working directory
+	if path == stdinTestCasePath {
+		path = "."
+	}
 	// priority: hashicorp plugin (debugtalk.bin > debugtalk.py) > go plugin (debugtalk.so)
 
 	pluginPath, err = locateFile(path, hashicorpGoPluginFile)
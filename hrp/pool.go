@@ -0,0 +1,176 @@
+package hrp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// objects reused across requests to reduce GC pressure in sustained load testing
+
+// buffers larger than maxPooledBufferSize are dropped instead of being pooled,
+// to avoid holding memory of occasional giant bodies.
+const maxPooledBufferSize = 1 << 20
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+func getBuffer() *bytes.Buffer {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf == nil || buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	bufferPool.Put(buf)
+}
+
+// pooledBody is request body backed by pooled buffer, buffer is put back when body is closed.
+// transport may close body in a separate goroutine, thus read and close are guarded by mutex.
+type pooledBody struct {
+	mutex  sync.Mutex
+	buf    *bytes.Buffer
+	reader *bytes.Reader
+}
+
+func newPooledBody(buf *bytes.Buffer) *pooledBody {
+	return &pooledBody{
+		buf:    buf,
+		reader: bytes.NewReader(buf.Bytes()),
+	}
+}
+
+func (b *pooledBody) Read(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.reader == nil {
+		return 0, io.EOF
+	}
+	return b.reader.Read(p)
+}
+
+func (b *pooledBody) Close() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.buf != nil {
+		putBuffer(b.buf)
+		b.buf, b.reader = nil, nil
+	}
+	return nil
+}
+
+var (
+	gzipReaderPool   sync.Pool
+	zlibReaderPool   sync.Pool
+	brotliReaderPool sync.Pool
+	zstdReaderPool   sync.Pool
+)
+
+// pooledReader is decompression reader of response body, reader is put back to pool when closed.
+type pooledReader struct {
+	io.Reader
+	pool *sync.Pool
+}
+
+func (r *pooledReader) Close() error {
+	if r.Reader == nil {
+		return nil
+	}
+	switch reader := r.Reader.(type) {
+	case io.Closer:
+		reader.Close()
+	case *zstd.Decoder:
+		// release reference to response body, decoder can still be reset
+		reader.Reset(nil)
+	}
+	r.pool.Put(r.Reader)
+	r.Reader = nil
+	return nil
+}
+
+func newGzipReader(body io.Reader) (io.ReadCloser, error) {
+	if reader, ok := gzipReaderPool.Get().(*gzip.Reader); ok {
+		if err := reader.Reset(body); err != nil {
+			return nil, err
+		}
+		return &pooledReader{Reader: reader, pool: &gzipReaderPool}, nil
+	}
+	reader, err := gzip.NewReader(body)
+	if err != nil {
+		return nil, err
+	}
+	return &pooledReader{Reader: reader, pool: &gzipReaderPool}, nil
+}
+
+func newZlibReader(body io.Reader) (io.ReadCloser, error) {
+	if reader, ok := zlibReaderPool.Get().(io.ReadCloser); ok {
+		if err := reader.(zlib.Resetter).Reset(body, nil); err != nil {
+			return nil, err
+		}
+		return &pooledReader{Reader: reader, pool: &zlibReaderPool}, nil
+	}
+	reader, err := zlib.NewReader(body)
+	if err != nil {
+		return nil, err
+	}
+	return &pooledReader{Reader: reader, pool: &zlibReaderPool}, nil
+}
+
+func newBrotliReader(body io.Reader) (io.ReadCloser, error) {
+	if reader, ok := brotliReaderPool.Get().(*brotli.Reader); ok {
+		if err := reader.Reset(body); err != nil {
+			return nil, err
+		}
+		return &pooledReader{Reader: reader, pool: &brotliReaderPool}, nil
+	}
+	return &pooledReader{Reader: brotli.NewReader(body), pool: &brotliReaderPool}, nil
+}
+
+// newZstdReader decodes zstd stream synchronously with concurrency 1, thus no goroutine is leaked by pooled decoders.
+func newZstdReader(body io.Reader) (io.ReadCloser, error) {
+	if reader, ok := zstdReaderPool.Get().(*zstd.Decoder); ok {
+		if err := reader.Reset(body); err != nil {
+			return nil, err
+		}
+		return &pooledReader{Reader: reader, pool: &zstdReaderPool}, nil
+	}
+	reader, err := zstd.NewReader(body, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	return &pooledReader{Reader: reader, pool: &zstdReaderPool}, nil
+}
+
+var sessionDataPool = sync.Pool{
+	New: func() interface{} {
+		return &SessionData{ReqResps: &ReqResps{}}
+	},
+}
+
+// releaseSessionData puts session data of request step back to pool,
+// it should only be called when step result is discarded, e.g. in load testing.
+func releaseSessionData(stepResult *StepResult) {
+	if stepResult == nil {
+		return
+	}
+	sessionData, ok := stepResult.Data.(*SessionData)
+	if !ok || sessionData == nil {
+		return
+	}
+	stepResult.Data = nil
+	reqResps := sessionData.ReqResps
+	*reqResps = ReqResps{}
+	*sessionData = SessionData{ReqResps: reqResps}
+	sessionDataPool.Put(sessionData)
+}
@@ -0,0 +1,210 @@
+package hrp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/httprunner/httprunner/hrp/internal/json"
+)
+
+var poolTestBody = []byte(`{"code": 0, "data": {"name": "debugtalk", "tags": ["a", "b", "c"]}, "message": "` +
+	strings.Repeat("x", 1024) + `"}`)
+
+func compressBody(t testing.TB, encoding string, body []byte) []byte {
+	var buf bytes.Buffer
+	var writer io.WriteCloser
+	switch encoding {
+	case "gzip":
+		writer = gzip.NewWriter(&buf)
+	case "deflate":
+		writer = zlib.NewWriter(&buf)
+	case "br":
+		writer = brotli.NewWriter(&buf)
+	case "zstd":
+		writer, _ = zstd.NewWriter(&buf)
+	}
+	if _, err := writer.Write(body); err != nil {
+		t.Fatal(err)
+	}
+	writer.Close()
+	return buf.Bytes()
+}
+
+func TestDecodeResponseBodyWithPooledReaders(t *testing.T) {
+	for _, encoding := range []string{"gzip", "deflate", "br", "zstd"} {
+		compressed := compressBody(t, encoding, poolTestBody)
+		// decode twice to reuse pooled reader
+		for i := 0; i < 2; i++ {
+			resp := &http.Response{
+				Header: http.Header{"Content-Encoding": []string{encoding}},
+				Body:   io.NopCloser(bytes.NewReader(compressed)),
+			}
+			if !assert.Nil(t, decodeResponseBody(resp)) {
+				t.Fatal()
+			}
+			body, err := io.ReadAll(resp.Body)
+			assert.Nil(t, err)
+			assert.Nil(t, resp.Body.Close())
+			assert.Equal(t, poolTestBody, body, encoding)
+		}
+	}
+}
+
+func TestReadFullResponseBodyReuseBuffer(t *testing.T) {
+	body, err := readFullResponseBody(bytes.NewReader(poolTestBody))
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	// overwrite pooled buffer, parsed body should not be affected
+	buf := getBuffer()
+	buf.Write(bytes.Repeat([]byte("z"), len(poolTestBody)))
+	putBuffer(buf)
+
+	data := body.(map[string]interface{})["data"].(map[string]interface{})
+	assert.Equal(t, "debugtalk", data["name"])
+
+	text, err := readFullResponseBody(strings.NewReader("plain text"))
+	assert.Nil(t, err)
+	assert.Equal(t, "plain text", text)
+}
+
+func TestPooledBody(t *testing.T) {
+	rb := newRequestBuilder(newParser(), &TConfig{}, &Request{})
+	if !assert.Nil(t, rb.setJSONBody(map[string]interface{}{"a": "<b>"})) {
+		t.Fatal()
+	}
+	expected, _ := json.Marshal(map[string]interface{}{"a": "<b>"})
+	assert.Equal(t, int64(len(expected)), rb.req.ContentLength)
+	content, err := io.ReadAll(rb.req.Body)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, content)
+
+	// closing body twice is safe, and closed body reads nothing
+	assert.Nil(t, rb.req.Body.Close())
+	assert.Nil(t, rb.req.Body.Close())
+	n, err := rb.req.Body.Read(make([]byte, 8))
+	assert.Equal(t, 0, n)
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestReleaseSessionData(t *testing.T) {
+	sessionData := newSessionData()
+	sessionData.Success = true
+	sessionData.ReqResps.Request = map[string]interface{}{"url": "/get"}
+	sessionData.Truncated = true
+	stepResult := &StepResult{Data: sessionData}
+
+	releaseSessionData(stepResult)
+	assert.Nil(t, stepResult.Data)
+	assert.False(t, sessionData.Success)
+	assert.False(t, sessionData.Truncated)
+	assert.Nil(t, sessionData.ReqResps.Request)
+
+	// step result without session data is ignored
+	releaseSessionData(&StepResult{Data: "other"})
+	releaseSessionData(nil)
+}
+
+func BenchmarkReadResponseBody(b *testing.B) {
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := readFullResponseBody(bytes.NewReader(poolTestBody)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("unpooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			content, err := io.ReadAll(bytes.NewReader(poolTestBody))
+			if err != nil {
+				b.Fatal(err)
+			}
+			var body interface{}
+			if err := json.Unmarshal(content, &body); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkDecodeGzipResponseBody(b *testing.B) {
+	compressed := compressBody(b, "gzip", poolTestBody)
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			resp := &http.Response{
+				Header: http.Header{"Content-Encoding": []string{"gzip"}},
+				Body:   io.NopCloser(bytes.NewReader(compressed)),
+			}
+			if err := decodeResponseBody(resp); err != nil {
+				b.Fatal(err)
+			}
+			if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+				b.Fatal(err)
+			}
+			resp.Body.Close()
+		}
+	})
+	b.Run("unpooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			reader, err := gzip.NewReader(bytes.NewReader(compressed))
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, err := io.Copy(io.Discard, reader); err != nil {
+				b.Fatal(err)
+			}
+			reader.Close()
+		}
+	})
+}
+
+func BenchmarkRunStepRequest(b *testing.B) {
+	compressed := compressBody(b, "gzip", poolTestBody)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "gzip")
+		_, _ = w.Write(compressed)
+	}))
+	defer ts.Close()
+
+	testcase := &TestCase{
+		Config: NewConfig("benchmark").SetBaseURL(ts.URL),
+	}
+	step := NewStep("post json").
+		POST("/post").
+		WithBody(map[string]interface{}{"foo": "bar", "list": []int{1, 2, 3}}).
+		Validate().
+		AssertEqual("status_code", 200, "check status code").
+		AssertEqual("body.data.name", "debugtalk", "check name").step
+	runner := NewRunner(nil).SetOutputLevel(OutputQuiet)
+	sessionRunner := runner.NewSessionRunner(testcase)
+	if err := sessionRunner.parseConfig(testcase.Config); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		stepResult, err := runStepRequest(sessionRunner, step)
+		if err != nil {
+			b.Fatal(err)
+		}
+		// session data is released in load testing
+		releaseSessionData(stepResult)
+	}
+}
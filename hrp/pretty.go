@@ -0,0 +1,273 @@
+package hrp
+
+import (
+	"bytes"
+	builtinJSON "encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+	"unicode/utf8"
+)
+
+// countingReader counts bytes of response body read on wire, before it is decompressed.
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+type bodyFormat int
+
+const (
+	bodyFormatRaw bodyFormat = iota
+	bodyFormatJSON
+	bodyFormatXML
+)
+
+func detectBodyFormat(contentType string) bodyFormat {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return bodyFormatRaw
+	}
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return bodyFormatJSON
+	case mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml"):
+		return bodyFormatXML
+	}
+	return bodyFormatRaw
+}
+
+// formatBody pretty prints json and xml body and highlights syntax if color is enabled,
+// body is truncated if it is longer than limit, which is unlimited if limit <= 0.
+func (c *consoleReporter) formatBody(contentType string, body []byte) string {
+	format := detectBodyFormat(contentType)
+	var text string
+	switch format {
+	case bodyFormatJSON:
+		text = prettyJSON(body)
+	case bodyFormatXML:
+		text = prettyXML(body)
+	default:
+		text = string(body)
+	}
+
+	var note string
+	if c.bodyLimit > 0 && len(text) > c.bodyLimit {
+		total := len(text)
+		text = truncateUTF8(text, c.bodyLimit)
+		note = fmt.Sprintf("\n... (body truncated, %d of %d bytes printed)", len(text), total)
+	}
+	if c.color {
+		switch format {
+		case bodyFormatJSON:
+			text = highlightJSON(text)
+		case bodyFormatXML:
+			text = highlightXML(text)
+		}
+	}
+	return text + c.colorize(note, colorYellow)
+}
+
+// truncateUTF8 truncates string to at most n bytes without splitting multi-byte characters.
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// prettyJSON indents json body, invalid json is kept as is.
+func prettyJSON(body []byte) string {
+	var buf bytes.Buffer
+	if err := builtinJSON.Indent(&buf, bytes.TrimSpace(body), "", "  "); err != nil {
+		return string(body)
+	}
+	return buf.String()
+}
+
+// prettyXML indents xml body, elements with only text are kept in one line,
+// invalid xml is kept as is.
+func prettyXML(body []byte) string {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.Strict = false
+	var tokens []xml.Token
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return string(body)
+		}
+		if data, ok := token.(xml.CharData); ok {
+			if len(bytes.TrimSpace(data)) == 0 {
+				continue
+			}
+			token = xml.CharData(bytes.TrimSpace(data))
+		}
+		tokens = append(tokens, xml.CopyToken(token))
+	}
+
+	var b strings.Builder
+	depth := 0
+	newline := func() {
+		if b.Len() > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(strings.Repeat("  ", depth))
+	}
+	for i := 0; i < len(tokens); i++ {
+		switch t := tokens[i].(type) {
+		case xml.StartElement:
+			newline()
+			writeXMLStartElement(&b, t)
+			// keep element with only text or without content in one line
+			if i+2 < len(tokens) {
+				if data, ok := tokens[i+1].(xml.CharData); ok {
+					if _, ok := tokens[i+2].(xml.EndElement); ok {
+						xml.EscapeText(&b, data)
+						fmt.Fprintf(&b, "</%s>", xmlName(t.Name))
+						i += 2
+						continue
+					}
+				}
+			}
+			if i+1 < len(tokens) {
+				if _, ok := tokens[i+1].(xml.EndElement); ok {
+					fmt.Fprintf(&b, "</%s>", xmlName(t.Name))
+					i++
+					continue
+				}
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+			if depth < 0 {
+				return string(body)
+			}
+			newline()
+			fmt.Fprintf(&b, "</%s>", xmlName(t.Name))
+		case xml.CharData:
+			newline()
+			xml.EscapeText(&b, t)
+		case xml.Comment:
+			newline()
+			fmt.Fprintf(&b, "<!--%s-->", t)
+		case xml.ProcInst:
+			newline()
+			fmt.Fprintf(&b, "<?%s %s?>", t.Target, t.Inst)
+		case xml.Directive:
+			newline()
+			fmt.Fprintf(&b, "<!%s>", t)
+		}
+	}
+	return b.String()
+}
+
+func xmlName(name xml.Name) string {
+	if name.Space != "" {
+		return name.Space + ":" + name.Local
+	}
+	return name.Local
+}
+
+func writeXMLStartElement(b *strings.Builder, element xml.StartElement) {
+	b.WriteString("<" + xmlName(element.Name))
+	for _, attr := range element.Attr {
+		fmt.Fprintf(b, ` %s="`, xmlName(attr.Name))
+		xml.EscapeText(b, []byte(attr.Value))
+		b.WriteByte('"')
+	}
+	b.WriteByte('>')
+}
+
+// highlightJSON highlights keys, strings and literals of indented json, which may be truncated.
+func highlightJSON(text string) string {
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		ch := text[i]
+		switch {
+		case ch == '"':
+			end := i + 1
+			for end < len(text) && text[end] != '"' {
+				if text[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end < len(text) {
+				end++ // closing quote
+			} else {
+				end = len(text)
+			}
+			color := colorGreen
+			if rest := strings.TrimLeft(text[end:], " \t\r\n"); strings.HasPrefix(rest, ":") {
+				color = colorCyan // object key
+			}
+			b.WriteString(color + text[i:end] + colorReset)
+			i = end
+		case ch == '-' || ch >= '0' && ch <= '9' || ch == 't' || ch == 'f' || ch == 'n':
+			end := i
+			for end < len(text) && strings.IndexByte(" \t\r\n,]}:", text[end]) < 0 {
+				end++
+			}
+			b.WriteString(colorYellow + text[i:end] + colorReset)
+			i = end
+		default:
+			b.WriteByte(ch)
+			i++
+		}
+	}
+	return b.String()
+}
+
+// highlightXML highlights tags and quoted attribute values of xml, which may be truncated.
+func highlightXML(text string) string {
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		if text[i] != '<' {
+			end := strings.IndexByte(text[i:], '<')
+			if end < 0 {
+				end = len(text) - i
+			}
+			b.WriteString(text[i : i+end])
+			i += end
+			continue
+		}
+		end := strings.IndexByte(text[i:], '>')
+		if end < 0 {
+			end = len(text) - i
+		} else {
+			end++
+		}
+		tag := text[i : i+end]
+		// highlight quoted attribute values in tag
+		for {
+			start := strings.IndexByte(tag, '"')
+			if start < 0 {
+				break
+			}
+			stop := strings.IndexByte(tag[start+1:], '"')
+			if stop < 0 {
+				break
+			}
+			stop += start + 2
+			b.WriteString(colorCyan + tag[:start] + colorReset + colorGreen + tag[start:stop] + colorReset)
+			tag = tag[stop:]
+		}
+		b.WriteString(colorCyan + tag + colorReset)
+		i += end
+	}
+	return b.String()
+}
@@ -0,0 +1,141 @@
+package hrp
+
+import (
+	"io"
+	"mime"
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/httprunner/httprunner/hrp/internal/json"
+)
+
+const contentTypeProtobuf = "application/x-protobuf"
+
+// ProtobufBody represents request body encoded in protobuf binary format,
+// descriptor is a FileDescriptorSet file generated by protoc with --include_imports --descriptor_set_out.
+type ProtobufBody struct {
+	Message    string      `json:"message" yaml:"message"`       // required, full name of request message, e.g. pkg.CreateOrder
+	Descriptor string      `json:"descriptor" yaml:"descriptor"` // required, relative path is resolved against project root dir
+	Data       interface{} `json:"data,omitempty" yaml:"data,omitempty"`
+	Response   string      `json:"response,omitempty" yaml:"response,omitempty"` // full name of response message, response is not decoded if empty
+
+	descriptorPath string // descriptor path resolved against project root dir
+}
+
+func (b *ProtobufBody) path() string {
+	if b.descriptorPath != "" {
+		return b.descriptorPath
+	}
+	return b.Descriptor
+}
+
+// protoFilesCache caches loaded descriptor files, descriptor file path => *protoregistry.Files
+var protoFilesCache sync.Map
+
+func loadProtoFiles(path string) (*protoregistry.Files, error) {
+	if files, ok := protoFilesCache.Load(path); ok {
+		return files.(*protoregistry.Files), nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "read protobuf descriptor failed")
+	}
+	fileSet := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(content, fileSet); err != nil {
+		return nil, errors.Wrapf(err, "unmarshal protobuf descriptor %s failed", path)
+	}
+	files, err := protodesc.NewFiles(fileSet)
+	if err != nil {
+		return nil, errors.Wrapf(err, "load protobuf descriptor %s failed", path)
+	}
+	protoFilesCache.Store(path, files)
+	return files, nil
+}
+
+func findProtoMessage(descriptorPath, message string) (protoreflect.MessageDescriptor, error) {
+	files, err := loadProtoFiles(descriptorPath)
+	if err != nil {
+		return nil, err
+	}
+	desc, err := files.FindDescriptorByName(protoreflect.FullName(message))
+	if err != nil {
+		return nil, errors.Wrapf(err, "protobuf message %s not found in %s", message, descriptorPath)
+	}
+	messageDesc, ok := desc.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, errors.Errorf("%s is not a protobuf message", message)
+	}
+	return messageDesc, nil
+}
+
+// encodeProtobuf encodes data map into protobuf binary of message, field names are the same as protojson.
+func encodeProtobuf(descriptorPath, message string, data interface{}) ([]byte, error) {
+	messageDesc, err := findProtoMessage(descriptorPath, message)
+	if err != nil {
+		return nil, err
+	}
+	msg := dynamicpb.NewMessage(messageDesc)
+	if data != nil {
+		content, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		if err := protojson.Unmarshal(content, msg); err != nil {
+			return nil, errors.Wrapf(err, "convert data to protobuf message %s failed", message)
+		}
+	}
+	return proto.Marshal(msg)
+}
+
+// decodeProtobuf decodes protobuf binary of message into map, which can be extracted and validated as json body.
+func decodeProtobuf(descriptorPath, message string, content []byte) (interface{}, error) {
+	messageDesc, err := findProtoMessage(descriptorPath, message)
+	if err != nil {
+		return nil, err
+	}
+	msg := dynamicpb.NewMessage(messageDesc)
+	if err := proto.Unmarshal(content, msg); err != nil {
+		return nil, errors.Wrapf(err, "unmarshal protobuf message %s failed", message)
+	}
+	jsonContent, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	var body interface{}
+	if err := json.Unmarshal(jsonContent, &body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// readProtobufResponseBody reads response body and decodes it as protobuf message.
+func readProtobufResponseBody(r io.Reader, descriptorPath, message string) (interface{}, error) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if _, err := buf.ReadFrom(r); err != nil {
+		return nil, err
+	}
+	return decodeProtobuf(descriptorPath, message, buf.Bytes())
+}
+
+// isProtobufContentType checks if content type is protobuf, e.g. application/x-protobuf, application/protobuf
+func isProtobufContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch mediaType {
+	case contentTypeProtobuf, "application/protobuf", "application/vnd.google.protobuf", "application/octet-stream":
+		return true
+	}
+	return false
+}
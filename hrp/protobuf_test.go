@@ -0,0 +1,126 @@
+package hrp
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// writeOrderDescriptor writes descriptor set of demo.CreateOrder and demo.OrderReply
+func writeOrderDescriptor(t *testing.T) string {
+	field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type,
+		label descriptorpb.FieldDescriptorProto_Label) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name:   proto.String(name),
+			Number: proto.Int32(number),
+			Type:   typ.Enum(),
+			Label:  label.Enum(),
+		}
+	}
+	optional := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+	fileSet := &descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{{
+			Name:    proto.String("demo.proto"),
+			Package: proto.String("demo"),
+			Syntax:  proto.String("proto3"),
+			MessageType: []*descriptorpb.DescriptorProto{
+				{
+					Name: proto.String("CreateOrder"),
+					Field: []*descriptorpb.FieldDescriptorProto{
+						field("user_id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, optional),
+						field("amount", 2, descriptorpb.FieldDescriptorProto_TYPE_INT32, optional),
+						field("items", 3, descriptorpb.FieldDescriptorProto_TYPE_STRING,
+							descriptorpb.FieldDescriptorProto_LABEL_REPEATED),
+					},
+				},
+				{
+					Name: proto.String("OrderReply"),
+					Field: []*descriptorpb.FieldDescriptorProto{
+						field("order_id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, optional),
+						field("amount", 2, descriptorpb.FieldDescriptorProto_TYPE_INT32, optional),
+					},
+				},
+			},
+		}},
+	}
+	content, err := proto.Marshal(fileSet)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	path := filepath.Join(t.TempDir(), "demo.pb")
+	if !assert.Nil(t, os.WriteFile(path, content, 0o644)) {
+		t.Fatal()
+	}
+	return path
+}
+
+func TestRunRequestWithProtobufBody(t *testing.T) {
+	descriptor := writeOrderDescriptor(t)
+	requestDesc, err := findProtoMessage(descriptor, "demo.CreateOrder")
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	replyDesc, _ := findProtoMessage(descriptor, "demo.OrderReply")
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, _ := io.ReadAll(r.Body)
+		order := dynamicpb.NewMessage(requestDesc)
+		if err := proto.Unmarshal(content, order); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		reply := dynamicpb.NewMessage(replyDesc)
+		reply.Set(replyDesc.Fields().ByName("order_id"),
+			order.Get(requestDesc.Fields().ByName("user_id")))
+		reply.Set(replyDesc.Fields().ByName("amount"),
+			order.Get(requestDesc.Fields().ByName("amount")))
+		replyContent, _ := proto.Marshal(reply)
+		w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+		_, _ = w.Write(replyContent)
+	}))
+	defer ts.Close()
+
+	testcase := &TestCase{
+		Config: NewConfig("protobuf body").SetBaseURL(ts.URL).
+			WithVariables(map[string]interface{}{"user": "debugtalk"}),
+		TestSteps: []IStep{
+			NewStep("create order").
+				POST("/orders").
+				WithProtobufBody(&ProtobufBody{
+					Message:    "demo.CreateOrder",
+					Descriptor: descriptor,
+					Response:   "demo.OrderReply",
+					Data: map[string]interface{}{
+						"user_id": "$user", "amount": 3, "items": []interface{}{"a", "b"},
+					},
+				}).
+				Validate().
+				AssertEqual("headers.\"Content-Type\"", contentTypeProtobuf, "check content type").
+				AssertEqual("body.order_id", "debugtalk", "check decoded field").
+				AssertEqual("body.amount", 3, "check decoded field"),
+		},
+	}
+	assert.Nil(t, NewRunner(t).Run(testcase))
+
+	// unknown message
+	_, err = encodeProtobuf(descriptor, "demo.Unknown", nil)
+	assert.NotNil(t, err)
+	// data field not defined in message
+	_, err = encodeProtobuf(descriptor, "demo.CreateOrder", map[string]interface{}{"unknown": 1})
+	assert.NotNil(t, err)
+}
+
+func TestIsProtobufContentType(t *testing.T) {
+	assert.True(t, isProtobufContentType("application/x-protobuf"))
+	assert.True(t, isProtobufContentType("application/protobuf; proto=demo.OrderReply"))
+	assert.False(t, isProtobufContentType("application/json"))
+	assert.False(t, isProtobufContentType(""))
+}
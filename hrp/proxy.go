@@ -0,0 +1,119 @@
+package hrp
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// ProxyConfig represents proxy settings of runner, requests to hosts in NoProxy are sent directly.
+type ProxyConfig struct {
+	URL            string   `json:"url" yaml:"url"`                                             // required, e.g. http://127.0.0.1:8888
+	Username       string   `json:"username,omitempty" yaml:"username,omitempty"`               // optional, overrides user info in url
+	Password       string   `json:"password,omitempty" yaml:"password,omitempty"`               // optional
+	NoProxy        []string `json:"no_proxy,omitempty" yaml:"no_proxy,omitempty"`               // hosts bypassing proxy, e.g. localhost, .example.com, 10.0.0.0/8, example.com:8080, *
+	ConnectTimeout float64  `json:"connect_timeout,omitempty" yaml:"connect_timeout,omitempty"` // timeout seconds of connecting to proxy, no timeout if <= 0
+}
+
+// SetProxy configures proxy with authentication and bypass rules, e.g. enterprise proxy rejecting unauthenticated CONNECT.
+func (r *HRPRunner) SetProxy(cfg *ProxyConfig) *HRPRunner {
+	log.Info().Str("proxyUrl", cfg.URL).Str("username", cfg.Username).
+		Strs("noProxy", cfg.NoProxy).Float64("connectTimeout", cfg.ConnectTimeout).Msg("[init] SetProxy")
+	proxy, err := cfg.proxyFunc()
+	if err != nil {
+		log.Error().Err(err).Str("proxyUrl", cfg.URL).Msg("[init] invalid proxy")
+		return r
+	}
+	dialer := &net.Dialer{}
+	if cfg.ConnectTimeout > 0 {
+		dialer.Timeout = time.Duration(cfg.ConnectTimeout * float64(time.Second))
+	}
+	r.dialer = dialer
+	r.client.Transport = &http.Transport{
+		Proxy:           proxy,
+		DialContext:     dialer.DialContext,
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}
+	r.resetClients() // tuned clients are rebuilt based on new transport
+	return r
+}
+
+// proxyFunc returns proxy function of http transport, proxy credentials are sent in Proxy-Authorization header
+// of both plain http requests and CONNECT requests for https.
+func (cfg *ProxyConfig) proxyFunc() (func(*http.Request) (*url.URL, error), error) {
+	proxyURL, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	if proxyURL.Scheme == "" || proxyURL.Host == "" {
+		return nil, errors.Errorf("proxy url should be in format of scheme://host:port, got %s", cfg.URL)
+	}
+	if cfg.Username != "" {
+		proxyURL.User = url.UserPassword(cfg.Username, cfg.Password)
+	}
+	return func(req *http.Request) (*url.URL, error) {
+		if bypassProxy(cfg.NoProxy, req.URL) {
+			return nil, nil
+		}
+		return proxyURL, nil
+	}, nil
+}
+
+// bypassProxy checks if request url matches any of no_proxy rules, e.g.
+//   - "*" matches all hosts
+//   - "10.0.0.0/8" matches ip in cidr
+//   - "example.com" and ".example.com" match example.com and its subdomains, "*.example.com" matches subdomains only
+//   - "example.com:8080" matches host with the specified port only
+func bypassProxy(noProxy []string, u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if port == "" {
+		port = map[string]string{"http": "80", "https": "443"}[u.Scheme]
+	}
+	ip := net.ParseIP(host)
+	for _, rule := range noProxy {
+		rule = strings.ToLower(strings.TrimSpace(rule))
+		if rule == "" {
+			continue
+		}
+		if rule == "*" {
+			return true
+		}
+		if _, cidr, err := net.ParseCIDR(rule); err == nil {
+			if ip != nil && cidr.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if ruleHost, rulePort, err := net.SplitHostPort(rule); err == nil {
+			if rulePort != port {
+				continue
+			}
+			rule = ruleHost
+		}
+		rule = strings.Trim(rule, "[]") // ipv6 without port
+		if ruleIP := net.ParseIP(rule); ruleIP != nil {
+			if ip != nil && ruleIP.Equal(ip) {
+				return true
+			}
+			continue
+		}
+		if strings.HasPrefix(rule, "*.") {
+			if strings.HasSuffix(host, rule[1:]) {
+				return true
+			}
+			continue
+		}
+		rule = strings.TrimPrefix(rule, ".")
+		if host == rule || strings.HasSuffix(host, "."+rule) {
+			return true
+		}
+	}
+	return false
+}
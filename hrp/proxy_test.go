@@ -0,0 +1,85 @@
+package hrp
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBypassProxy(t *testing.T) {
+	noProxy := []string{"localhost", ".internal.com", "*.example.com", "10.0.0.0/8", "api.test.com:8080", "::1"}
+	testData := []struct {
+		rawURL string
+		bypass bool
+	}{
+		{"http://localhost:3000/get", true},
+		{"http://internal.com", true},
+		{"https://api.internal.com", true},
+		{"https://example.com", false},
+		{"https://www.example.com", true},
+		{"http://10.1.2.3:8000", true},
+		{"http://11.1.2.3:8000", false},
+		{"http://api.test.com:8080", true},
+		{"http://api.test.com", false},
+		{"http://[::1]:8080", true},
+		{"http://httpbin.org", false},
+	}
+	for _, data := range testData {
+		u, _ := url.Parse(data.rawURL)
+		assert.Equal(t, data.bypass, bypassProxy(noProxy, u), data.rawURL)
+	}
+	u, _ := url.Parse("http://httpbin.org")
+	assert.True(t, bypassProxy([]string{"*"}, u))
+}
+
+func TestRunRequestWithProxyAuth(t *testing.T) {
+	expectedAuth := "Basic " + base64.StdEncoding.EncodeToString([]byte("debugtalk:p@ss:word"))
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Proxy-Authorization") != expectedAuth {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		_, _ = w.Write([]byte("proxied " + r.URL.Host))
+	}))
+	defer proxy.Close()
+	direct := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("direct"))
+	}))
+	defer direct.Close()
+
+	testcase := &TestCase{
+		Config: NewConfig("proxy auth").
+			WithVariables(map[string]interface{}{"direct": direct.URL}),
+		TestSteps: []IStep{
+			NewStep("via proxy").GET("http://httpbin.test/get").
+				Validate().
+				AssertEqual("status_code", 200, "check status code").
+				AssertEqual("body", "proxied httpbin.test", "check proxied"),
+			NewStep("bypass proxy").GET("$direct/get").
+				Validate().
+				AssertEqual("body", "direct", "check not proxied"),
+		},
+	}
+	runner := NewRunner(t).SetProxy(&ProxyConfig{
+		URL:            proxy.URL,
+		Username:       "debugtalk",
+		Password:       "p@ss:word",
+		NoProxy:        []string{"127.0.0.1"},
+		ConnectTimeout: 1,
+	})
+	assert.Nil(t, runner.Run(testcase))
+
+	testcase = &TestCase{
+		Config: NewConfig("proxy auth required"),
+		TestSteps: []IStep{
+			NewStep("via proxy").GET("http://httpbin.test/get").
+				Validate().
+				AssertEqual("status_code", 200, "check status code"),
+		},
+	}
+	assert.NotNil(t, NewRunner(nil).SetProxyUrl(proxy.URL).Run(testcase))
+}
@@ -0,0 +1,517 @@
+package hrp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// pytest_parser parses testcases written in HttpRunner v3 python style, only literals, names,
+// chained calls and import statements are supported, which are enough for generated pytest files.
+
+type pyTokenKind int
+
+const (
+	pyTokenName pyTokenKind = iota
+	pyTokenString
+	pyTokenNumber
+	pyTokenOp
+	pyTokenEOF
+)
+
+type pyToken struct {
+	kind  pyTokenKind
+	value string // name, op, number literal, or decoded string
+	line  int
+}
+
+// pyName is identifier in python expression, e.g. class name of referenced testcase.
+type pyName string
+
+// pyCall is call of function or method with positional and keyword arguments.
+type pyCall struct {
+	name   string
+	args   []interface{}
+	kwargs map[string]interface{}
+}
+
+// pyChain is chained method calls, e.g. RunRequest("get").get("/get").validate().
+type pyChain struct {
+	calls []*pyCall
+}
+
+// pyImport is imported name of from import statement.
+type pyImport struct {
+	module string
+	name   string
+}
+
+func tokenizePython(src string) ([]pyToken, error) {
+	var tokens []pyToken
+	line := 1
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case c == '\\' && i+1 < len(src) && src[i+1] == '\n':
+			// explicit line joining
+			line++
+			i += 2
+		case c == '_' || c < utf8.RuneSelf && unicode.IsLetter(rune(c)):
+			start := i
+			for i < len(src) && (src[i] == '_' || src[i] < utf8.RuneSelf &&
+				(unicode.IsLetter(rune(src[i])) || unicode.IsDigit(rune(src[i])))) {
+				i++
+			}
+			name := src[start:i]
+			if i < len(src) && (src[i] == '"' || src[i] == '\'') && isPyStringPrefix(name) {
+				value, n, lines, err := readPyString(src[i:], strings.ToLower(name))
+				if err != nil {
+					return nil, errors.Wrapf(err, "line %d", line)
+				}
+				tokens = append(tokens, pyToken{kind: pyTokenString, value: value, line: line})
+				line += lines
+				i += n
+				continue
+			}
+			tokens = append(tokens, pyToken{kind: pyTokenName, value: name, line: line})
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(src) && src[i+1] >= '0' && src[i+1] <= '9':
+			start := i
+			for i < len(src) {
+				ch := src[i]
+				if ch >= '0' && ch <= '9' || ch == '.' || ch == '_' || ch == 'x' || ch == 'X' || ch == 'o' || ch == 'O' ||
+					ch >= 'a' && ch <= 'f' || ch >= 'A' && ch <= 'F' {
+					i++
+				} else if (ch == '+' || ch == '-') && (src[i-1] == 'e' || src[i-1] == 'E') &&
+					!strings.HasPrefix(strings.ToLower(src[start:i]), "0x") {
+					i++
+				} else {
+					break
+				}
+			}
+			tokens = append(tokens, pyToken{kind: pyTokenNumber, value: src[start:i], line: line})
+		case c == '"' || c == '\'':
+			value, n, lines, err := readPyString(src[i:], "")
+			if err != nil {
+				return nil, errors.Wrapf(err, "line %d", line)
+			}
+			tokens = append(tokens, pyToken{kind: pyTokenString, value: value, line: line})
+			line += lines
+			i += n
+		case c == '*' && i+1 < len(src) && src[i+1] == '*':
+			tokens = append(tokens, pyToken{kind: pyTokenOp, value: "**", line: line})
+			i += 2
+		case c == '=' && i+1 < len(src) && src[i+1] == '=':
+			tokens = append(tokens, pyToken{kind: pyTokenOp, value: "==", line: line})
+			i += 2
+		case strings.IndexByte("()[]{},:.=*-+@", c) >= 0:
+			tokens = append(tokens, pyToken{kind: pyTokenOp, value: string(c), line: line})
+			i++
+		default:
+			return nil, fmt.Errorf("line %d: unexpected character %q", line, c)
+		}
+	}
+	tokens = append(tokens, pyToken{kind: pyTokenEOF, line: line})
+	return tokens, nil
+}
+
+func isPyStringPrefix(name string) bool {
+	switch strings.ToLower(name) {
+	case "r", "u", "b", "br", "rb", "f", "fr", "rf":
+		return true
+	}
+	return false
+}
+
+// readPyString reads python string literal with prefix, returns decoded string, length of literal
+// and count of line breaks in literal.
+func readPyString(src, prefix string) (value string, n int, lines int, err error) {
+	if strings.Contains(prefix, "f") {
+		return "", 0, 0, errors.New("f-string not supported")
+	}
+	raw := strings.Contains(prefix, "r")
+	quote := src[:1]
+	if strings.HasPrefix(src, strings.Repeat(quote, 3)) {
+		quote = strings.Repeat(quote, 3)
+	}
+	var b strings.Builder
+	for i := len(quote); i < len(src); {
+		if strings.HasPrefix(src[i:], quote) {
+			return b.String(), len(prefix) + i + len(quote), lines, nil
+		}
+		c := src[i]
+		if c == '\n' {
+			if len(quote) == 1 {
+				return "", 0, 0, errors.New("unterminated string literal")
+			}
+			lines++
+		}
+		if c != '\\' || i+1 >= len(src) {
+			b.WriteByte(c)
+			i++
+			continue
+		}
+		next := src[i+1]
+		if raw {
+			b.WriteByte(c)
+			b.WriteByte(next)
+			i += 2
+			continue
+		}
+		i += 2
+		switch next {
+		case '\n':
+			lines++ // line continuation in string
+		case '\\', '\'', '"':
+			b.WriteByte(next)
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case 'a':
+			b.WriteByte('\a')
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'v':
+			b.WriteByte('\v')
+		case 'x', 'u', 'U':
+			size := map[byte]int{'x': 2, 'u': 4, 'U': 8}[next]
+			if i+size > len(src) {
+				return "", 0, 0, errors.New("invalid escape in string literal")
+			}
+			code, err := strconv.ParseUint(src[i:i+size], 16, 32)
+			if err != nil {
+				return "", 0, 0, errors.New("invalid escape in string literal")
+			}
+			b.WriteRune(rune(code))
+			i += size
+		default:
+			if next >= '0' && next <= '7' {
+				// octal escape of up to three digits
+				end := i
+				for end < len(src) && end < i+2 && src[end] >= '0' && src[end] <= '7' {
+					end++
+				}
+				code, _ := strconv.ParseUint(src[i-1:end], 8, 32)
+				b.WriteRune(rune(code))
+				i = end
+			} else {
+				// unknown escape is kept as is
+				b.WriteByte('\\')
+				b.WriteByte(next)
+			}
+		}
+	}
+	return "", 0, 0, errors.New("unterminated string literal")
+}
+
+type pyParser struct {
+	tokens []pyToken
+	pos    int
+}
+
+func (p *pyParser) peek() pyToken {
+	return p.tokens[p.pos]
+}
+
+func (p *pyParser) next() pyToken {
+	token := p.tokens[p.pos]
+	if token.kind != pyTokenEOF {
+		p.pos++
+	}
+	return token
+}
+
+func (p *pyParser) isOp(op string) bool {
+	token := p.peek()
+	return token.kind == pyTokenOp && token.value == op
+}
+
+func (p *pyParser) expectOp(op string) error {
+	token := p.next()
+	if token.kind != pyTokenOp || token.value != op {
+		return fmt.Errorf("line %d: expected %q, got %q", token.line, op, token.value)
+	}
+	return nil
+}
+
+// parseModule parses assignments of config and teststeps, and from import statements at top level,
+// which are usually class attributes of HttpRunner testcase.
+func (p *pyParser) parseModule() (assigns map[string]interface{}, imports map[string]pyImport, err error) {
+	assigns = make(map[string]interface{})
+	imports = make(map[string]pyImport)
+	depth := 0
+	for p.peek().kind != pyTokenEOF {
+		token := p.next()
+		if token.kind == pyTokenOp {
+			switch token.value {
+			case "(", "[", "{":
+				depth++
+			case ")", "]", "}":
+				depth--
+			}
+			continue
+		}
+		if token.kind != pyTokenName || depth != 0 {
+			continue
+		}
+		switch {
+		case token.value == "from":
+			if err := p.parseImport(imports); err != nil {
+				return nil, nil, err
+			}
+		case (token.value == "config" || token.value == "teststeps") && p.isOp("="):
+			p.next()
+			value, err := p.parseExpr()
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "parse %s failed", token.value)
+			}
+			assigns[token.value] = value
+		}
+	}
+	return assigns, imports, nil
+}
+
+// parseImport parses from import statement after from keyword, e.g. from testcases.demo_test import TestCaseDemo as Demo
+func (p *pyParser) parseImport(imports map[string]pyImport) error {
+	var module strings.Builder
+	for p.peek().kind == pyTokenName && p.peek().value != "import" || p.isOp(".") {
+		module.WriteString(p.next().value)
+	}
+	if token := p.next(); token.kind != pyTokenName || token.value != "import" {
+		return fmt.Errorf("line %d: invalid import statement", token.line)
+	}
+	parenthesized := p.isOp("(")
+	if parenthesized {
+		p.next()
+	}
+	for {
+		token := p.next()
+		if token.kind != pyTokenName {
+			return fmt.Errorf("line %d: invalid import statement", token.line)
+		}
+		alias := token.value
+		if p.peek().kind == pyTokenName && p.peek().value == "as" {
+			p.next()
+			alias = p.next().value
+		}
+		imports[alias] = pyImport{module: module.String(), name: token.value}
+		if !p.isOp(",") {
+			break
+		}
+		p.next()
+		if parenthesized && p.isOp(")") {
+			break
+		}
+	}
+	if parenthesized {
+		return p.expectOp(")")
+	}
+	return nil
+}
+
+func (p *pyParser) parseExpr() (interface{}, error) {
+	value, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	// trailers of attribute and call
+	for {
+		if p.isOp(".") {
+			p.next()
+			token := p.next()
+			if token.kind != pyTokenName {
+				return nil, fmt.Errorf("line %d: expected attribute name", token.line)
+			}
+			switch v := value.(type) {
+			case pyName:
+				value = pyName(string(v) + "." + token.value)
+			case *pyChain:
+				if !p.isOp("(") {
+					return nil, fmt.Errorf("line %d: attribute %s of call not supported", token.line, token.value)
+				}
+				v.calls = append(v.calls, &pyCall{name: token.value})
+			default:
+				return nil, fmt.Errorf("line %d: attribute %s of literal not supported", token.line, token.value)
+			}
+		} else if p.isOp("(") {
+			p.next()
+			switch v := value.(type) {
+			case pyName:
+				call := &pyCall{name: string(v)}
+				if err := p.parseArgs(call); err != nil {
+					return nil, err
+				}
+				value = &pyChain{calls: []*pyCall{call}}
+			case *pyChain:
+				call := v.calls[len(v.calls)-1]
+				if call.args != nil || call.kwargs != nil {
+					return nil, fmt.Errorf("line %d: call of call result not supported", p.peek().line)
+				}
+				if err := p.parseArgs(call); err != nil {
+					return nil, err
+				}
+			default:
+				return nil, fmt.Errorf("line %d: call of literal not supported", p.peek().line)
+			}
+		} else {
+			return value, nil
+		}
+	}
+}
+
+func (p *pyParser) parsePrimary() (interface{}, error) {
+	token := p.next()
+	switch token.kind {
+	case pyTokenString:
+		value := token.value
+		// adjacent strings are concatenated
+		for p.peek().kind == pyTokenString {
+			value += p.next().value
+		}
+		return value, nil
+	case pyTokenNumber:
+		return parsePyNumber(token)
+	case pyTokenName:
+		switch token.value {
+		case "True":
+			return true, nil
+		case "False":
+			return false, nil
+		case "None":
+			return nil, nil
+		}
+		return pyName(token.value), nil
+	case pyTokenOp:
+		switch token.value {
+		case "-", "+":
+			number := p.next()
+			if number.kind != pyTokenNumber {
+				return nil, fmt.Errorf("line %d: unary %s only supported for numbers", token.line, token.value)
+			}
+			return parsePyNumber(pyToken{kind: number.kind, value: token.value + number.value, line: number.line})
+		case "(":
+			value, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			return value, p.expectOp(")")
+		case "[":
+			list := make([]interface{}, 0)
+			for !p.isOp("]") {
+				item, err := p.parseExpr()
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, item)
+				if !p.isOp(",") {
+					break
+				}
+				p.next()
+			}
+			return list, p.expectOp("]")
+		case "{":
+			dict := make(map[string]interface{})
+			for !p.isOp("}") {
+				key, err := p.parseExpr()
+				if err != nil {
+					return nil, err
+				}
+				if err := p.expectOp(":"); err != nil {
+					return nil, err
+				}
+				value, err := p.parseExpr()
+				if err != nil {
+					return nil, err
+				}
+				dict[fmt.Sprint(key)] = value
+				if !p.isOp(",") {
+					break
+				}
+				p.next()
+			}
+			return dict, p.expectOp("}")
+		}
+	}
+	return nil, fmt.Errorf("line %d: unexpected token %q", token.line, token.value)
+}
+
+// parseArgs parses arguments of call after opening parenthesis, **dict and *list are unpacked.
+func (p *pyParser) parseArgs(call *pyCall) error {
+	call.args = make([]interface{}, 0)
+	call.kwargs = make(map[string]interface{})
+	for !p.isOp(")") {
+		switch {
+		case p.isOp("**"):
+			p.next()
+			value, err := p.parseExpr()
+			if err != nil {
+				return err
+			}
+			dict, ok := value.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("line %d: ** argument should be dict", p.peek().line)
+			}
+			for k, v := range dict {
+				call.kwargs[k] = v
+			}
+		case p.isOp("*"):
+			p.next()
+			value, err := p.parseExpr()
+			if err != nil {
+				return err
+			}
+			list, ok := value.([]interface{})
+			if !ok {
+				return fmt.Errorf("line %d: * argument should be list", p.peek().line)
+			}
+			call.args = append(call.args, list...)
+		case p.peek().kind == pyTokenName && p.tokens[p.pos+1].kind == pyTokenOp && p.tokens[p.pos+1].value == "=":
+			name := p.next().value
+			p.next()
+			value, err := p.parseExpr()
+			if err != nil {
+				return err
+			}
+			call.kwargs[name] = value
+		default:
+			value, err := p.parseExpr()
+			if err != nil {
+				return err
+			}
+			call.args = append(call.args, value)
+		}
+		if !p.isOp(",") {
+			break
+		}
+		p.next()
+	}
+	return p.expectOp(")")
+}
+
+func parsePyNumber(token pyToken) (interface{}, error) {
+	literal := strings.ReplaceAll(token.value, "_", "")
+	if i, err := strconv.ParseInt(literal, 0, 64); err == nil {
+		return int(i), nil
+	}
+	if f, err := strconv.ParseFloat(literal, 64); err == nil {
+		return f, nil
+	}
+	return nil, fmt.Errorf("line %d: invalid number %s", token.line, token.value)
+}
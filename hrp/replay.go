@@ -0,0 +1,91 @@
+package hrp
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/httprunner/httprunner/hrp/internal/builtin"
+)
+
+// selectReplaySteps selects steps by name or index (starting from 1) with their declared
+// dependencies recursively, selected steps are returned in original order.
+func selectReplaySteps(steps []IStep, selectors []string) ([]IStep, []int, error) {
+	stepIndex := make(map[string]int)   // step display name -> index
+	dependIndex := make(map[string]int) // step struct name -> index, used by depends_on
+	for i, step := range steps {
+		if _, ok := stepIndex[step.Name()]; !ok {
+			stepIndex[step.Name()] = i
+		}
+		if _, ok := dependIndex[step.Struct().Name]; !ok {
+			dependIndex[step.Struct().Name] = i
+		}
+	}
+
+	selected := make([]bool, len(steps))
+	var selectStep func(i int)
+	selectStep = func(i int) {
+		if selected[i] {
+			return
+		}
+		selected[i] = true
+		for _, dependency := range steps[i].Struct().DependsOn {
+			if j, ok := dependIndex[dependency]; ok {
+				selectStep(j)
+			}
+		}
+	}
+
+	for _, selector := range selectors {
+		if i, ok := stepIndex[selector]; ok {
+			selectStep(i)
+			continue
+		}
+		index, err := strconv.Atoi(selector)
+		if err != nil || index < 1 || index > len(steps) {
+			return nil, nil, fmt.Errorf("replay step not found: %s", selector)
+		}
+		selectStep(index - 1)
+	}
+
+	var replaySteps []IStep
+	var indexes []int
+	for i, step := range steps {
+		if selected[i] {
+			replaySteps = append(replaySteps, step)
+			indexes = append(indexes, i)
+		}
+	}
+	return replaySteps, indexes, nil
+}
+
+// LoadSessionVariables loads variables from saved session file to seed replaying steps.
+// The file can be summary saved by hrp run --save-tests, in which exported variables
+// of all steps are collected in order, or a plain json/yaml file of variables mapping.
+func LoadSessionVariables(path string) (map[string]interface{}, error) {
+	var content map[string]interface{}
+	if err := builtin.LoadFile(path, &content); err != nil {
+		return nil, err
+	}
+	if _, ok := content["details"]; !ok {
+		return content, nil
+	}
+
+	summary := &Summary{}
+	if err := builtin.LoadFile(path, summary); err != nil {
+		return nil, err
+	}
+	variables := make(map[string]interface{})
+	for _, caseSummary := range summary.Details {
+		if caseSummary.InOut != nil {
+			for k, v := range caseSummary.InOut.ConfigVars {
+				variables[k] = v
+			}
+		}
+		for _, record := range caseSummary.Records {
+			for k, v := range record.ExportVars {
+				variables[k] = v
+			}
+		}
+	}
+	return variables, nil
+}
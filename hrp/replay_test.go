@@ -0,0 +1,107 @@
+package hrp
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/httprunner/httprunner/hrp/internal/builtin"
+)
+
+func TestRunCaseWithReplaySteps(t *testing.T) {
+	var mutex sync.Mutex
+	var paths []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		paths = append(paths, r.URL.Path)
+		mutex.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"path": "%s", "token": "%s"}`, r.URL.Path, r.URL.Query().Get("token"))
+	}))
+	defer ts.Close()
+
+	newTestCase := func() *TestCase {
+		return &TestCase{
+			Config: NewConfig("replay").SetBaseURL(ts.URL),
+			TestSteps: []IStep{
+				NewStep("login").
+					GET("/login").
+					WithParams(map[string]interface{}{"token": "new"}).
+					Extract().
+					WithJmesPath("body.token", "token"),
+				NewStep("get user").
+					GET("/user").
+					WithParams(map[string]interface{}{"token": "$token"}).
+					Validate().
+					AssertEqual("body.token", "$expected_token", "check token"),
+				NewStep("logout").
+					DependsOn("login").
+					GET("/logout"),
+			},
+		}
+	}
+
+	// replay by name with seeded variables
+	paths = nil
+	err := NewRunner(t).
+		SetReplaySteps("get user").
+		SetReplayVariables(map[string]interface{}{"token": "saved", "expected_token": "saved"}).
+		Run(newTestCase())
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	assert.Equal(t, []string{"/user"}, paths)
+
+	// replay by index with declared dependencies
+	paths = nil
+	err = NewRunner(t).SetReplaySteps("3").Run(newTestCase())
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	assert.Equal(t, []string{"/login", "/logout"}, paths)
+
+	err = NewRunner(t).SetReplaySteps("4").Run(newTestCase())
+	assert.NotNil(t, err)
+}
+
+func TestLoadSessionVariables(t *testing.T) {
+	dir := t.TempDir()
+	summary := newOutSummary()
+	summary.appendCaseSummary(&TestCaseSummary{
+		Name: "demo",
+		Stat: &TestStepStat{},
+		InOut: &TestCaseInOut{
+			ConfigVars: map[string]interface{}{"base_url": "http://127.0.0.1"},
+		},
+		Records: []*StepResult{
+			{Name: "login", ExportVars: map[string]interface{}{"token": "abc"}},
+			{Name: "refresh", ExportVars: map[string]interface{}{"token": "def", "uid": "1"}},
+		},
+	})
+	summaryPath := filepath.Join(dir, "summary.json")
+	if err := builtin.Dump2JSON(summary, summaryPath); err != nil {
+		t.Fatal(err)
+	}
+	variables, err := LoadSessionVariables(summaryPath)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	assert.Equal(t, map[string]interface{}{
+		"base_url": "http://127.0.0.1", "token": "def", "uid": "1",
+	}, variables)
+
+	variablesPath := filepath.Join(dir, "variables.json")
+	if err := builtin.Dump2JSON(map[string]interface{}{"token": "abc"}, variablesPath); err != nil {
+		t.Fatal(err)
+	}
+	variables, err = LoadSessionVariables(variablesPath)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	assert.Equal(t, map[string]interface{}{"token": "abc"}, variables)
+}
@@ -0,0 +1,242 @@
+package hrp
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+)
+
+// OutputLevel controls how much detail is printed to console.
+type OutputLevel int
+
+const (
+	OutputQuiet   OutputLevel = iota // only print pass/fail line of each step
+	OutputNormal                     // print step results, validation failures, and request/response dumps if requests log is on
+	OutputVerbose                    // print full request/response dumps of all steps
+)
+
+const (
+	colorReset  = "\033[0m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+	colorCyan   = "\033[36m"
+)
+
+// DefaultPrintableContentTypes are prefixes of content types whose bodies are printed as text.
+var DefaultPrintableContentTypes = []string{
+	"text/",
+	"application/json",
+	"application/xml",
+	"application/x-www-form-urlencoded",
+}
+
+// consoleReporter prints requests, responses and step results to writer, default to stdout.
+type consoleReporter struct {
+	out       io.Writer
+	level     OutputLevel
+	color     bool
+	bodyLimit int        // max bytes of request/response body to print, unlimited if <= 0
+	mutex     sync.Mutex // steps may print concurrently when running in DAG
+
+	printableContentTypes []string // prefixes of printable content types
+	hexdumpLimit          int      // max bytes of binary body to print as hexdump, omitted if <= 0
+}
+
+func newConsoleReporter() *consoleReporter {
+	return &consoleReporter{
+		out:                   NewSecretMaskWriter(os.Stdout),
+		level:                 OutputNormal,
+		printableContentTypes: DefaultPrintableContentTypes,
+	}
+}
+
+// showDumps checks if request & response dumps should be printed.
+func (c *consoleReporter) showDumps(requestsLogOn bool) bool {
+	switch c.level {
+	case OutputQuiet:
+		return false
+	case OutputVerbose:
+		return true
+	default:
+		return requestsLogOn
+	}
+}
+
+// shouldPrintBody returns true if the content type matches prefix of printable content types.
+func (c *consoleReporter) shouldPrintBody(contentType string) bool {
+	contentType = strings.ToLower(strings.TrimSpace(contentType))
+	for _, prefix := range c.printableContentTypes {
+		if prefix == "*" || strings.HasPrefix(contentType, strings.ToLower(prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// dumpBody formats printable body, or previews first bytes of binary body as hexdump.
+func (c *consoleReporter) dumpBody(contentType string, body []byte) string {
+	if c.shouldPrintBody(contentType) {
+		return c.formatBody(contentType, body)
+	}
+	preview := body
+	if len(preview) > c.hexdumpLimit {
+		preview = preview[:c.hexdumpLimit]
+	}
+	return hex.Dump(preview) + c.colorize(fmt.Sprintf("(binary body of %d bytes for Content-Type: %v, first %d bytes shown)",
+		len(body), contentType, len(preview)), colorYellow)
+}
+
+func (c *consoleReporter) colorize(text, color string) string {
+	if !c.color {
+		return text
+	}
+	return color + text + colorReset
+}
+
+func (c *consoleReporter) printRequest(req *http.Request) error {
+	reqContentType := req.Header.Get("Content-Type")
+	reqDump, err := httputil.DumpRequest(req, false)
+	if err != nil {
+		return errors.Wrap(err, "dump request failed")
+	}
+	reqContent := string(reqDump)
+	if req.Body != nil && req.Body != http.NoBody {
+		if c.shouldPrintBody(reqContentType) || c.hexdumpLimit > 0 {
+			body, err := io.ReadAll(req.Body)
+			if err != nil {
+				return errors.Wrap(err, "dump request failed")
+			}
+			req.Body = io.NopCloser(bytes.NewReader(body))
+			req.GetBody = func() (io.ReadCloser, error) {
+				return io.NopCloser(bytes.NewReader(body)), nil
+			}
+			reqContent += c.dumpBody(reqContentType, body)
+		} else {
+			reqContent += fmt.Sprintf("(request body omitted for Content-Type: %v)", reqContentType)
+		}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	fmt.Fprintln(c.out, c.colorize("-------------------- request --------------------", colorCyan))
+	fmt.Fprintln(c.out, reqContent)
+	return nil
+}
+
+// printResponse prints response with pretty printed body, wire counts bytes of body
+// before decoding and is used to show compression ratio if it is not nil.
+func (c *consoleReporter) printResponse(resp *http.Response, wire *countingReader) error {
+	respContentType := resp.Header.Get("Content-Type")
+	respDump, err := httputil.DumpResponse(resp, false)
+	if err != nil {
+		return errors.Wrap(err, "dump response failed")
+	}
+	respContent := string(respDump)
+	if c.shouldPrintBody(respContentType) || c.hexdumpLimit > 0 {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return errors.Wrap(err, "dump response failed")
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		respContent += c.dumpBody(respContentType, body)
+		if encoding := resp.Header.Get("Content-Encoding"); encoding != "" && wire != nil {
+			respContent += c.colorize(fmt.Sprintf("\n(%d bytes decoded from %d bytes of %s encoding on wire)",
+				len(body), wire.n, encoding), colorYellow)
+		} else if resp.Uncompressed {
+			// transport has decoded gzip body transparently and removed Content-Encoding header,
+			// size on wire is not known then
+			respContent += c.colorize(fmt.Sprintf("\n(%d bytes decoded from gzip encoding by transport)",
+				len(body)), colorYellow)
+		}
+	} else {
+		respContent += fmt.Sprintf("(response body omitted for Content-Type: %v)", respContentType)
+	}
+	// highlight status line
+	if i := strings.Index(respContent, "\r\n"); i >= 0 {
+		respContent = c.colorize(respContent[:i], statusColor(resp.StatusCode)) + respContent[i:]
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	fmt.Fprintln(c.out, c.colorize("==================== response ===================", colorCyan))
+	fmt.Fprintln(c.out, respContent)
+	fmt.Fprintln(c.out, c.colorize("--------------------------------------------------", colorCyan))
+	return nil
+}
+
+func statusColor(statusCode int) string {
+	switch {
+	case statusCode >= 500:
+		return colorRed
+	case statusCode >= 400:
+		return colorYellow
+	default:
+		return colorGreen
+	}
+}
+
+// printValidationFailures prints failed validation results in aligned table,
+// rendered diffs are printed below the table.
+func (c *consoleReporter) printValidationFailures(results []*ValidationResult) {
+	if c.level == OutputQuiet {
+		return
+	}
+	var failures []*ValidationResult
+	for _, result := range results {
+		if result.CheckResult == "fail" {
+			failures = append(failures, result)
+		}
+	}
+	if len(failures) == 0 {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	fmt.Fprintln(c.out, c.colorize("==================== validation failures ===================", colorRed))
+	tw := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHECK\tASSERT\tEXPECT\tACTUAL\tMESSAGE")
+	for _, result := range failures {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+			result.Check, result.Assert,
+			truncateValue(formatValidationValue(result.Expect)),
+			truncateValue(formatValidationValue(result.CheckValue)),
+			result.Message)
+	}
+	tw.Flush()
+	for _, result := range failures {
+		if result.Diff != "" {
+			fmt.Fprintf(c.out, "diff of %s:\n%s\n", result.Check, c.colorize(result.Diff, colorRed))
+		}
+	}
+	fmt.Fprintln(c.out, c.colorize("--------------------------------------------------", colorRed))
+}
+
+// printStepResult prints one line of step result, e.g. PASS  get user  (12ms)
+func (c *consoleReporter) printStepResult(stepResult *StepResult, err error) {
+	if stepResult == nil {
+		return
+	}
+	status := c.colorize("PASS", colorGreen)
+	if !stepResult.Success {
+		status = c.colorize("FAIL", colorRed)
+	}
+	line := fmt.Sprintf("%s  %s  (%dms)", status, stepResult.Name, stepResult.Elapsed)
+	if err != nil {
+		line += "  " + err.Error()
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	fmt.Fprintln(c.out, line)
+}
@@ -0,0 +1,179 @@
+package hrp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunCaseWithOutputLevel(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"name": "hrp"}`))
+	}))
+	defer ts.Close()
+
+	newTestCase := func() *TestCase {
+		return &TestCase{
+			Config: NewConfig("output").SetBaseURL(ts.URL),
+			TestSteps: []IStep{
+				NewStep("get user").
+					GET("/user").
+					Validate().
+					AssertEqual("body.name", "hrp", "check name"),
+				NewStep("get user again").
+					GET("/user").
+					Validate().
+					AssertEqual("body.name", "httprunner", "check name"),
+			},
+		}
+	}
+
+	// quiet mode only prints pass/fail line of each step
+	out := &bytes.Buffer{}
+	err := NewRunner(nil).SetRequestsLogOn().SetOutput(out).SetOutputLevel(OutputQuiet).Run(newTestCase())
+	if !assert.NotNil(t, err) {
+		t.Fatal()
+	}
+	assert.Contains(t, out.String(), "PASS  get user  (")
+	assert.Contains(t, out.String(), "FAIL  get user again  (")
+	assert.NotContains(t, out.String(), "request ---")
+	assert.NotContains(t, out.String(), "validation failures")
+
+	// verbose mode prints dumps even if requests log is off, with colors
+	out.Reset()
+	err = NewRunner(nil).SetOutput(out).SetOutputLevel(OutputVerbose).SetColorOutput(true).Run(newTestCase())
+	if !assert.NotNil(t, err) {
+		t.Fatal()
+	}
+	assert.Contains(t, out.String(), colorGreen+"HTTP/1.1 200 OK"+colorReset)
+	assert.Contains(t, out.String(), colorGreen+"PASS"+colorReset+"  get user  (")
+	assert.Contains(t, out.String(), colorRed+"FAIL"+colorReset+"  get user again  (")
+	assert.Contains(t, out.String(), "validation failures")
+}
+
+func TestFormatBody(t *testing.T) {
+	c := &consoleReporter{}
+	assert.Equal(t, "{\n  \"name\": \"hrp\",\n  \"tags\": [\n    1,\n    true\n  ]\n}",
+		c.formatBody("application/json; charset=utf-8", []byte(`{"name":"hrp","tags":[1,true]}`)))
+	assert.Equal(t, "<?xml version=\"1.0\"?>\n<user id=\"1\">\n  <name>hrp</name>\n  <tags></tags>\n</user>",
+		c.formatBody("application/xml", []byte(`<?xml version="1.0"?><user id="1"><name>hrp</name><tags/></user>`)))
+	// invalid body is kept as is
+	assert.Equal(t, `{"name":`, c.formatBody("application/json", []byte(`{"name":`)))
+	assert.Equal(t, "a=1&b=2", c.formatBody("application/x-www-form-urlencoded", []byte("a=1&b=2")))
+
+	// truncate without splitting multi-byte characters
+	c.bodyLimit = 4
+	assert.Equal(t, "中\n... (body truncated, 3 of 6 bytes printed)", c.formatBody("text/plain", []byte("中文")))
+
+	c = &consoleReporter{color: true}
+	assert.Equal(t, "{"+colorCyan+`"ok"`+colorReset+":"+colorYellow+"true"+colorReset+"}",
+		highlightJSON(`{"ok":true}`))
+	assert.Equal(t, colorCyan+"<a href="+colorReset+colorGreen+`"x"`+colorReset+colorCyan+">"+colorReset+"text"+
+		colorCyan+"</a>"+colorReset, highlightXML(`<a href="x">text</a>`))
+}
+
+func TestPrintResponseWithEncoding(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "gzip")
+		gw := gzip.NewWriter(w)
+		_, _ = gw.Write([]byte(`{"data": "` + strings.Repeat("a", 1000) + `"}`))
+		_ = gw.Close()
+	}))
+	defer ts.Close()
+
+	testcase := &TestCase{
+		Config: NewConfig("encoding").SetBaseURL(ts.URL),
+		TestSteps: []IStep{
+			NewStep("get data").
+				GET("/data").
+				WithHeaders(map[string]string{"Accept-Encoding": "gzip"}).
+				Validate().
+				AssertLengthEqual("body.data", 1000, "check decoded body"),
+		},
+	}
+	out := &bytes.Buffer{}
+	err := NewRunner(t).SetRequestsLogOn().SetOutput(out).SetLogBodyLimit(20).Run(testcase)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	assert.Contains(t, out.String(), "{\n  \"data\": \"aaaaaaa\n... (body truncated, 20 of 1016 bytes printed)")
+	assert.Regexp(t, `\(1012 bytes decoded from \d+ bytes of gzip encoding on wire\)`, out.String())
+}
+
+func TestPrintResponseWithTransparentDecoding(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "gzip")
+		gw := gzip.NewWriter(w)
+		_, _ = gw.Write([]byte(`{"data": "` + strings.Repeat("中", 100) + `"}`))
+		_ = gw.Close()
+	}))
+	defer ts.Close()
+
+	testcase := &TestCase{
+		Config: NewConfig("transparent decoding").SetBaseURL(ts.URL),
+		TestSteps: []IStep{
+			NewStep("get data").
+				GET("/data").
+				Validate().
+				AssertEqual("status_code", 200, "check status code"),
+		},
+	}
+	out := &bytes.Buffer{}
+	err := NewRunner(t).SetRequestsLogOn().SetOutput(out).SetLogBodyLimit(20).Run(testcase)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	// body is truncated at character boundary, printed bytes are counted after truncation
+	assert.Contains(t, out.String(), "... (body truncated, 19 of 316 bytes printed)")
+	assert.Contains(t, out.String(), "(312 bytes decoded from gzip encoding by transport)")
+}
+
+func TestPrintBinaryBody(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/proto":
+			w.Header().Set("Content-Type", "application/x-protobuf")
+			_, _ = w.Write([]byte{0x0a, 0x03, 'h', 'r', 'p', 0x10, 0x01, 0x18, 0x02})
+		case "/text":
+			w.Header().Set("Content-Type", "application/vnd.hrp.v1+text")
+			_, _ = w.Write([]byte("hello"))
+		}
+	}))
+	defer ts.Close()
+
+	testcase := &TestCase{
+		Config: NewConfig("binary").SetBaseURL(ts.URL),
+		TestSteps: []IStep{
+			NewStep("get proto").GET("/proto"),
+			NewStep("get text").GET("/text"),
+		},
+	}
+
+	// binary body is omitted by default
+	out := &bytes.Buffer{}
+	err := NewRunner(t).SetRequestsLogOn().SetOutput(out).Run(testcase)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	assert.Contains(t, out.String(), "(response body omitted for Content-Type: application/x-protobuf)")
+	assert.Contains(t, out.String(), "(response body omitted for Content-Type: application/vnd.hrp.v1+text)")
+
+	// preview binary body as hexdump, and print custom content type as text
+	out.Reset()
+	err = NewRunner(t).SetRequestsLogOn().SetOutput(out).SetLogHexdump(4).
+		SetPrintableContentTypes(append(DefaultPrintableContentTypes, "application/vnd.hrp.")...).Run(testcase)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	assert.Contains(t, out.String(), "00000000  0a 03 68 72                                       |..hr|\n"+
+		"(binary body of 9 bytes for Content-Type: application/x-protobuf, first 4 bytes shown)")
+	assert.Contains(t, out.String(), "\r\n\r\nhello\n")
+}
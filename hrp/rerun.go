@@ -0,0 +1,87 @@
+package hrp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/httprunner/httprunner/hrp/internal/builtin"
+)
+
+// SetRerunFailed configures to rerun failed testcase up to n times, testcase passed in rerun is marked as flaky,
+// and testcase failed in all reruns is regarded as consistently failing.
+func (r *HRPRunner) SetRerunFailed(n int) *HRPRunner {
+	log.Info().Int("reruns", n).Msg("[init] SetRerunFailed")
+	r.rerunFailed = n
+	return r
+}
+
+// SetQuarantineFile configures to write names of flaky testcases into quarantine list file after run,
+// one testcase name per line.
+func (r *HRPRunner) SetQuarantineFile(path string) *HRPRunner {
+	log.Info().Str("path", path).Msg("[init] SetQuarantineFile")
+	r.quarantinePath = path
+	return r
+}
+
+// runTestCase runs testcase with current parameters, failed testcase is rerun if rerun failed is set.
+// testcase is canceled when ctx of run is done.
+func (r *HRPRunner) runTestCase(ctx context.Context, testcase *TestCase) (*TestCaseSummary, error) {
+	sessionRunner := r.newSessionRunner(ctx, testcase)
+	err := sessionRunner.Start()
+	caseSummary := sessionRunner.GetSummary()
+	for rerun := 1; rerun <= r.rerunFailed && (err != nil || !caseSummary.Success) && canceled(ctx) == nil; rerun++ {
+		log.Warn().Err(err).Str("testcase", testcase.Config.Name).
+			Int("rerun", rerun).Msg("rerun failed testcase")
+		sessionRunner = r.newSessionRunner(ctx, testcase)
+		err = sessionRunner.Start()
+		caseSummary = sessionRunner.GetSummary()
+		caseSummary.Reruns = rerun
+		caseSummary.Flaky = err == nil && caseSummary.Success
+	}
+	if caseSummary.Flaky {
+		log.Warn().Str("testcase", testcase.Config.Name).
+			Int("reruns", caseSummary.Reruns).Msg("testcase is flaky, passed in rerun")
+	}
+	return caseSummary, err
+}
+
+// flakyTestCases returns sorted names of flaky testcases in summary.
+func (s *Summary) flakyTestCases() []string {
+	names := make(map[string]bool)
+	for _, caseSummary := range s.Details {
+		if caseSummary.Flaky {
+			names[caseSummary.Name] = true
+		}
+	}
+	var flaky []string
+	for name := range names {
+		flaky = append(flaky, name)
+	}
+	sort.Strings(flaky)
+	return flaky
+}
+
+// dumpQuarantine writes names of flaky testcases into quarantine list file, parent folders are created if not exist.
+func (s *Summary) dumpQuarantine(path string) error {
+	dir, _ := filepath.Split(path)
+	if dir != "" {
+		if err := builtin.EnsureFolderExists(dir); err != nil {
+			return err
+		}
+	}
+	var content string
+	if flaky := s.flakyTestCases(); len(flaky) > 0 {
+		content = strings.Join(flaky, "\n") + "\n"
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return errors.Wrap(err, "write quarantine file failed")
+	}
+	log.Info().Str("path", path).Msg("write quarantine list of flaky testcases")
+	return nil
+}
@@ -0,0 +1,71 @@
+package hrp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunWithRerunFailed(t *testing.T) {
+	var flakyCount int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/flaky":
+			// fail for the first two requests
+			if atomic.AddInt32(&flakyCount, 1) <= 2 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		case "/broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer ts.Close()
+
+	newTestCase := func(name, path string) *TestCase {
+		return &TestCase{
+			Config: NewConfig(name).SetBaseURL(ts.URL),
+			TestSteps: []IStep{
+				NewStep("get").GET(path).
+					Validate().
+					AssertStatusOK("check status code"),
+			},
+		}
+	}
+	quarantinePath := filepath.Join(t.TempDir(), "reports", "quarantine.txt")
+	runner := NewRunner(nil).SetFailfast(false).SetRerunFailed(2).SetQuarantineFile(quarantinePath)
+	err := runner.Run(
+		newTestCase("stable", "/stable"),
+		newTestCase("flaky", "/flaky"),
+		newTestCase("broken", "/broken"),
+	)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	summary := runner.summary
+	if !assert.Len(t, summary.Details, 3) {
+		t.Fatal()
+	}
+	stable, flaky, broken := summary.Details[0], summary.Details[1], summary.Details[2]
+	assert.True(t, stable.Success)
+	assert.Equal(t, 0, stable.Reruns)
+	assert.True(t, flaky.Success)
+	assert.True(t, flaky.Flaky)
+	assert.Equal(t, 2, flaky.Reruns)
+	assert.False(t, broken.Success)
+	assert.False(t, broken.Flaky)
+	assert.Equal(t, 2, broken.Reruns)
+	assert.Equal(t, TestCaseStat{Total: 3, Success: 2, Fail: 1, Flaky: 1}, summary.Stat.TestCases)
+
+	content, err := os.ReadFile(quarantinePath)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "flaky\n", string(content))
+	}
+
+	// consistently failing testcase aborts run with failfast
+	assert.NotNil(t, NewRunner(nil).SetRerunFailed(1).Run(newTestCase("broken", "/broken")))
+}
@@ -6,19 +6,51 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"regexp"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
-	"github.com/jmespath/go-jmespath"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
 
 	"github.com/httprunner/httprunner/hrp/internal/builtin"
 	"github.com/httprunner/httprunner/hrp/internal/json"
 )
 
 func newResponseObject(t *testing.T, parser *Parser, resp *http.Response) (*responseObject, error) {
+	// read response body
+	body, err := readFullResponseBody(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return newResponseObjectWithBody(t, parser, resp, body, nil)
+}
+
+// readFullResponseBody reads the whole response body, json body is parsed and others are kept as raw string.
+func readFullResponseBody(r io.Reader) (interface{}, error) {
+	// read into pooled buffer, parsed body does not reference buffer bytes
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if _, err := buf.ReadFrom(r); err != nil {
+		return nil, err
+	}
+	respBodyBytes := buf.Bytes()
+
+	// parse response body
+	var body interface{}
+	if err := json.Unmarshal(respBodyBytes, &body); err != nil {
+		// response body is not json, use raw body
+		body = string(respBodyBytes)
+	}
+	return body, nil
+}
+
+// newResponseObjectWithBody creates response object with response body which has been read,
+// address traced from connection is exposed with tls state of response as conn metadata.
+func newResponseObjectWithBody(t *testing.T, parser *Parser, resp *http.Response, body interface{}, address *Address) (*responseObject, error) {
 	// prepare response headers
 	headers := make(map[string]string)
 	for k, v := range resp.Header {
@@ -33,26 +65,20 @@ func newResponseObject(t *testing.T, parser *Parser, resp *http.Response) (*resp
 		cookies[cookie.Name] = cookie.Value
 	}
 
-	// read response body
-	respBodyBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	// parse response body
-	var body interface{}
-	if err := json.Unmarshal(respBodyBytes, &body); err != nil {
-		// response body is not json, use raw body
-		body = string(respBodyBytes)
-	}
-
 	respObjMeta := respObjMeta{
 		StatusCode: resp.StatusCode,
 		Headers:    headers,
 		Cookies:    cookies,
 		Body:       body,
+		Conn:       newConnMeta(resp, address),
 	}
+	respObjMeta.setTableMeta(resp.Header.Get("Content-Type"), body)
+	return newResponseObjectWithMeta(t, parser, respObjMeta)
+}
 
+// newResponseObjectWithMeta creates response object with meta data, which is extracted and validated with jmespath,
+// meta data can be received message of non-HTTP steps, e.g. {"topic": "xx", "payload": {...}}.
+func newResponseObjectWithMeta(t *testing.T, parser *Parser, respObjMeta interface{}) (*responseObject, error) {
 	// convert respObjMeta to interface{}
 	respObjMetaBytes, _ := json.Marshal(respObjMeta)
 	var data interface{}
@@ -78,20 +104,65 @@ type respObjMeta struct {
 	Headers    map[string]string `json:"headers"`
 	Cookies    map[string]string `json:"cookies"`
 	Body       interface{}       `json:"body"`
+	Conn       *connMeta         `json:"conn,omitempty"`
+	CSV        *tableMeta        `json:"csv,omitempty"`   // parsed body of csv content type
+	Excel      *excelMeta        `json:"excel,omitempty"` // parsed body of excel content type
+}
+
+// Response is typed response of request step, which is exposed to Go embedders
+// to write custom assertions in plain Go after step runs, see StepResult.Response and SessionRunner.LastResponse.
+type Response struct {
+	StatusCode int
+	Proto      string
+	Header     http.Header
+	Cookies    []*http.Cookie
+	Body       interface{} // decoded body, e.g. json object or array, otherwise raw string
+	Truncated  bool        // body exceeds max body size and is truncated
+	Timings    ResponseTimings
+}
+
+// ResponseTimings records durations of request phases,
+// connection phases are zero if connection is reused from pool.
+type ResponseTimings struct {
+	DNSLookup        time.Duration
+	TCPConnection    time.Duration
+	TLSHandshake     time.Duration
+	ServerProcessing time.Duration // from request written to first response byte
+	Total            time.Duration // from request sent to response headers received
+}
+
+// Cookie returns the named cookie set by response, nil is returned if not found.
+func (r *Response) Cookie(name string) *http.Cookie {
+	for _, cookie := range r.Cookies {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	return nil
 }
 
 type responseObject struct {
 	t                 *testing.T
 	parser            *Parser
 	respObjMeta       interface{}
+	response          *Response // typed response exposed to Go embedders
 	validationResults []*ValidationResult
+	truncated         bool // response body is truncated by max body size
+	connReused        bool // connection is reused from pool
+	address           *Address
+	htmlDoc           *html.Node   // parsed html body, which is shared by css extractors
+	jwtVerifier       *jwtVerifier // verifies jwt decoded by jwt extractors
 }
 
 const textExtractorSubRegexp string = `(.*)`
 
 func (v *responseObject) extractField(value string) interface{} {
 	var result interface{}
-	if strings.Contains(value, textExtractorSubRegexp) {
+	if strings.HasPrefix(value, cssExtractorPrefix) {
+		result = v.searchCSS(value)
+	} else if strings.HasPrefix(value, jwtExtractorPrefix) {
+		result = v.searchJWT(value)
+	} else if strings.Contains(value, textExtractorSubRegexp) {
 		result = v.searchRegexp(value)
 	} else {
 		result = v.searchJmespath(value)
@@ -99,20 +170,42 @@ func (v *responseObject) extractField(value string) interface{} {
 	return result
 }
 
-func (v *responseObject) Extract(extractors map[string]string) map[string]interface{} {
+// Extract extracts variables from response, invalid extractor expression results in extraction failure.
+func (v *responseObject) Extract(extractors map[string]string) (map[string]interface{}, error) {
 	if extractors == nil {
-		return nil
+		return nil, nil
 	}
 
 	extractMapping := make(map[string]interface{})
 	for key, value := range extractors {
+		if err := v.compileExtractor(value); err != nil {
+			return nil, newStepError(FailureExtraction,
+				errors.Wrapf(err, "invalid extractor %s for variable %s", value, key))
+		}
 		extractedValue := v.extractField(value)
 		log.Info().Str("from", value).Interface("value", extractedValue).Msg("extract value")
 		log.Info().Str("variable", key).Interface("value", extractedValue).Msg("set variable")
 		extractMapping[key] = extractedValue
 	}
 
-	return extractMapping
+	return extractMapping, nil
+}
+
+// compileExtractor checks whether extractor expression is valid css extractor, jwt extractor, regexp or jmespath.
+func (v *responseObject) compileExtractor(value string) error {
+	if strings.HasPrefix(value, cssExtractorPrefix) {
+		_, err := v.parser.compileCSSExtractor(value)
+		return err
+	}
+	if strings.HasPrefix(value, jwtExtractorPrefix) {
+		return v.parser.compileJWTExtractor(value)
+	}
+	if strings.Contains(value, textExtractorSubRegexp) {
+		_, err := v.parser.compileRegexp(value)
+		return err
+	}
+	_, err := v.parser.compileJmespath(value)
+	return err
 }
 
 func (v *responseObject) Validate(iValidators []interface{}, variablesMapping map[string]interface{}) (err error) {
@@ -121,72 +214,273 @@ func (v *responseObject) Validate(iValidators []interface{}, variablesMapping ma
 		if !ok {
 			return errors.New("validator type error")
 		}
-		// parse check value
-		checkItem := validator.Check
-		var checkValue interface{}
-		if strings.Contains(checkItem, "$") {
-			// reference variable
-			checkValue, err = v.parser.Parse(checkItem, variablesMapping)
-			if err != nil {
-				return err
-			}
-		} else {
-			// regExp or jmesPath
-			checkValue = v.extractField(checkItem)
+		validResult, err := v.validate(validator, variablesMapping, v.t)
+		if err != nil {
+			return err
+		}
+		v.validationResults = append(v.validationResults, validResult)
+		if validResult.CheckResult != "pass" {
+			v.t.Fail()
+			log.Error().
+				Str("checkExpr", validResult.Check).
+				Str("assertMethod", validResult.Assert).
+				Interface("checkValue", validResult.CheckValue).
+				Interface("expectValue", validResult.Expect).
+				Msg("assert failed")
+			return &ValidationError{Results: []*ValidationResult{validResult}}
 		}
+	}
+	return nil
+}
 
-		// get assert method
-		assertMethod := validator.Assert
-		assertFunc, ok := builtin.Assertions[assertMethod]
-		if !ok {
-			return errors.New(fmt.Sprintf("unexpected assertMethod: %v", assertMethod))
+// silentTestingT discards assertion failure messages of validators nested in not and any_of groups,
+// since failure of nested validator does not mean failure of the group.
+type silentTestingT struct{}
+
+func (silentTestingT) Errorf(format string, args ...interface{}) {}
+
+// validate evaluates single validator or validator group, assertion failure messages are reported to t.
+func (v *responseObject) validate(validator Validator, variablesMapping map[string]interface{}, t assert.TestingT) (*ValidationResult, error) {
+	if validator.Not != nil {
+		return v.validateNot(validator, variablesMapping, t)
+	}
+	if len(validator.AnyOf) > 0 {
+		return v.validateAnyOf(validator, variablesMapping, t)
+	}
+
+	// parse check value
+	checkItem := validator.Check
+	var checkValue interface{}
+	var err error
+	if strings.Contains(checkItem, "$") {
+		// reference variable
+		checkValue, err = v.parser.Parse(checkItem, variablesMapping)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// regExp or jmesPath
+		checkValue = v.extractField(checkItem)
+	}
+
+	// get assert method
+	assertMethod := validator.Assert
+	assertFunc, ok := builtin.Assertions[assertMethod]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("unexpected assertMethod: %v", assertMethod))
+	}
+	if validator.Epsilon > 0 && (assertMethod == "approx_equal" || assertMethod == "approx_eq") {
+		epsilon := validator.Epsilon
+		assertFunc = func(t assert.TestingT, actual, expected interface{}, msgAndArgs ...interface{}) bool {
+			return builtin.ApproxEqualWithEpsilon(t, actual, expected, epsilon, msgAndArgs...)
 		}
+	}
 
-		// parse expected value
-		expectValue, err := v.parser.Parse(validator.Expect, variablesMapping)
+	// parse expected value, or extract it from the same response for cross-field validation
+	var expectValue interface{}
+	if validator.ExpectPath != "" {
+		expectValue = v.extractField(validator.ExpectPath)
+		checkValue, expectValue = alignNumbers(checkValue, expectValue)
+	} else {
+		expectValue, err = v.parser.Parse(validator.Expect, variablesMapping)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		validResult := &ValidationResult{
-			Validator: Validator{
-				Check:   validator.Check,
-				Expect:  expectValue,
-				Assert:  assertMethod,
-				Message: validator.Message,
-			},
-			CheckValue:  checkValue,
-			CheckResult: "fail",
+	}
+	// remove ignored fields before comparing
+	if len(validator.Ignore) > 0 {
+		checkValue, err = removeIgnoredFields(checkValue, validator.Ignore)
+		if err != nil {
+			return nil, err
 		}
+		expectValue, err = removeIgnoredFields(expectValue, validator.Ignore)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-		// do assertion
-		result := assertFunc(v.t, checkValue, expectValue)
-		if result {
+	validResult := &ValidationResult{
+		Validator: Validator{
+			Check:      validator.Check,
+			Expect:     expectValue,
+			Assert:     assertMethod,
+			Message:    validator.Message,
+			Ignore:     validator.Ignore,
+			Epsilon:    validator.Epsilon,
+			ExpectPath: validator.ExpectPath,
+		},
+		CheckValue:  checkValue,
+		CheckResult: "fail",
+	}
+
+	// do assertion
+	actual := checkValue
+	if assertMethod == "exists" || assertMethod == "not_exists" {
+		// tell missing field from field with null value
+		actual = builtin.Field{Path: checkItem, Value: checkValue, Exists: v.fieldExists(checkItem, checkValue)}
+	}
+	expected := expectValue
+	if builtin.IsOrderingAssertion(assertMethod) {
+		// numbers in testcase file may be of different type from numbers in response, e.g. float64 and int64
+		actual, expected = alignNumbers(actual, expected)
+	}
+	result := assertFunc(t, actual, expected)
+	if result {
+		validResult.CheckResult = "pass"
+	} else {
+		diffValidationResult(validResult)
+	}
+	log.Info().
+		Str("checkExpr", validator.Check).
+		Str("assertMethod", assertMethod).
+		Interface("expectValue", expectValue).
+		Interface("checkValue", checkValue).
+		Bool("result", result).
+		Msgf("validate %s", checkItem)
+	return validResult, nil
+}
+
+// fieldExists checks if field located by check expression exists, field with null value exists while missing field does not,
+// existence of complex jmespath expressions, e.g. functions and projections, is decided by non-null result.
+func (v *responseObject) fieldExists(checkItem string, checkValue interface{}) bool {
+	if strings.Contains(checkItem, textExtractorSubRegexp) {
+		return checkValue != checkItem // expression is returned if regexp not matched
+	}
+	if strings.Contains(checkItem, "$") {
+		return checkValue != nil
+	}
+	segments, ok := parseSimpleJmesPath(checkItem)
+	if !ok {
+		return checkValue != nil
+	}
+	current := reflect.ValueOf(v.respObjMeta)
+	for _, segment := range segments {
+		for current.Kind() == reflect.Interface || current.Kind() == reflect.Ptr {
+			current = current.Elem()
+		}
+		switch key := segment.(type) {
+		case string:
+			if current.Kind() != reflect.Map || current.Type().Key().Kind() != reflect.String {
+				return false
+			}
+			current = current.MapIndex(reflect.ValueOf(key).Convert(current.Type().Key()))
+			if !current.IsValid() {
+				return false
+			}
+		case int:
+			if current.Kind() != reflect.Slice && current.Kind() != reflect.Array || key >= current.Len() {
+				return false
+			}
+			current = current.Index(key)
+		}
+	}
+	return true
+}
+
+// validateNot passes if the nested validator fails, e.g. status code is not 500.
+func (v *responseObject) validateNot(validator Validator, variablesMapping map[string]interface{}, t assert.TestingT) (*ValidationResult, error) {
+	nested, err := v.validate(*validator.Not, variablesMapping, silentTestingT{})
+	if err != nil {
+		return nil, err
+	}
+	validResult := &ValidationResult{
+		Validator: Validator{
+			Check:   nested.Check,
+			Assert:  "not " + nested.Assert,
+			Expect:  nested.Expect,
+			Message: validator.Message,
+		},
+		CheckValue:  nested.CheckValue,
+		CheckResult: "fail",
+	}
+	if nested.CheckResult != "pass" {
+		validResult.CheckResult = "pass"
+	} else {
+		t.Errorf("expected %s %v not %s %v", nested.Check, nested.CheckValue, nested.Assert, nested.Expect)
+	}
+	return validResult, nil
+}
+
+// validateAnyOf passes if at least one of the nested validators passes, e.g. status code is 200 or 204.
+func (v *responseObject) validateAnyOf(validator Validator, variablesMapping map[string]interface{}, t assert.TestingT) (*ValidationResult, error) {
+	var checks, asserts []string
+	var checkValues, expectValues []interface{}
+	validResult := &ValidationResult{
+		Validator: Validator{
+			Assert:  "any_of",
+			Message: validator.Message,
+		},
+		CheckResult: "fail",
+	}
+	for _, nestedValidator := range validator.AnyOf {
+		nested, err := v.validate(nestedValidator, variablesMapping, silentTestingT{})
+		if err != nil {
+			return nil, err
+		}
+		if nested.CheckResult == "pass" {
 			validResult.CheckResult = "pass"
 		}
-		v.validationResults = append(v.validationResults, validResult)
-		log.Info().
-			Str("checkExpr", validator.Check).
-			Str("assertMethod", assertMethod).
-			Interface("expectValue", expectValue).
-			Interface("checkValue", checkValue).
-			Bool("result", result).
-			Msgf("validate %s", checkItem)
-		if !result {
-			v.t.Fail()
-			log.Error().
-				Str("checkExpr", validator.Check).
-				Str("assertMethod", assertMethod).
-				Interface("checkValue", checkValue).
-				Interface("expectValue", expectValue).
-				Msg("assert failed")
-			return errors.New("step validation failed")
+		checks = append(checks, nested.Check)
+		asserts = append(asserts, nested.Assert)
+		checkValues = append(checkValues, nested.CheckValue)
+		expectValues = append(expectValues, nested.Expect)
+	}
+	validResult.Expect = expectValues
+	if sameStrings(checks) {
+		// e.g. status_code any_of [200, 204]
+		validResult.Check = checks[0]
+		validResult.CheckValue = checkValues[0]
+	} else {
+		validResult.Check = strings.Join(checks, " | ")
+		validResult.CheckValue = checkValues
+	}
+	if validResult.CheckResult != "pass" {
+		t.Errorf("expected any of %s %v, got %v", strings.Join(asserts, " | "), expectValues, validResult.CheckValue)
+	}
+	return validResult, nil
+}
+
+func sameStrings(values []string) bool {
+	for _, value := range values {
+		if value != values[0] {
+			return false
 		}
 	}
-	return nil
+	return len(values) > 0
+}
+
+// removeIgnoredFields returns a normalized copy of value with fields located by paths removed.
+func removeIgnoredFields(value interface{}, paths []string) (interface{}, error) {
+	normalized, err := builtin.NormalizeJSON(value)
+	if err != nil {
+		return value, errors.Wrap(err, "normalize json failed")
+	}
+	if err := builtin.RemoveJSONPaths(normalized, paths...); err != nil {
+		return value, err
+	}
+	return normalized, nil
+}
+
+// diffJSONValues returns path-by-path diff of expected and actual json values.
+func diffJSONValues(expected, actual interface{}) []builtin.JSONDiff {
+	expectedJSON, err := builtin.NormalizeJSON(expected)
+	if err != nil {
+		return nil
+	}
+	actualJSON, err := builtin.NormalizeJSON(actual)
+	if err != nil {
+		return nil
+	}
+	return builtin.DiffJSON(expectedJSON, actualJSON)
 }
 
 func (v *responseObject) searchJmespath(expr string) interface{} {
-	checkValue, err := jmespath.Search(expr, v.respObjMeta)
+	compiled, err := v.parser.compileJmespath(expr)
+	if err != nil {
+		log.Error().Str("expr", expr).Err(err).Msg("compile jmespath failed")
+		return expr // invalid jmespath, return the expression
+	}
+	checkValue, err := compiled.Search(v.respObjMeta)
 	if err != nil {
 		log.Error().Str("expr", expr).Err(err).Msg("search jmespath failed")
 		return expr // jmespath not found, return the expression
@@ -212,7 +506,7 @@ func (v *responseObject) searchRegexp(expr string) interface{} {
 		log.Error().Interface("resp", respMap).Msg("convert body to string failed")
 		return expr
 	}
-	regexpCompile, err := regexp.Compile(expr)
+	regexpCompile, err := v.parser.compileRegexp(expr)
 	if err != nil {
 		log.Error().Str("expr", expr).Err(err).Msg("compile expr failed")
 		return expr
@@ -224,3 +518,55 @@ func (v *responseObject) searchRegexp(expr string) interface{} {
 	log.Error().Str("expr", expr).Msg("search regexp failed")
 	return expr
 }
+
+// searchCSS extracts value of html body by css extractor, nil is returned if body is not html or no element matched.
+func (v *responseObject) searchCSS(expr string) interface{} {
+	extractor, err := v.parser.compileCSSExtractor(expr)
+	if err != nil {
+		log.Error().Str("expr", expr).Err(err).Msg("compile expr failed")
+		return nil
+	}
+	if v.htmlDoc == nil {
+		respMap, ok := v.respObjMeta.(map[string]interface{})
+		if !ok {
+			log.Error().Interface("resp", v.respObjMeta).Msg("convert respObjMeta to map failed")
+			return nil
+		}
+		bodyStr, ok := respMap["body"].(string)
+		if !ok {
+			log.Error().Interface("resp", respMap).Msg("convert body to string failed")
+			return nil
+		}
+		if v.htmlDoc, err = html.Parse(strings.NewReader(bodyStr)); err != nil {
+			log.Error().Err(err).Msg("parse html body failed")
+			return nil
+		}
+	}
+	return extractor.extract(v.htmlDoc)
+}
+
+// checkOutput extracts variables from output of non-HTTP steps and validates it, the same as response of request step,
+// output can be received message of MQTT/Kafka steps, or query result of database steps.
+func (r *SessionRunner) checkOutput(step *TStep, output map[string]interface{},
+	stepVariables map[string]interface{}, stepResult *StepResult) error {
+
+	msgObj, err := newResponseObjectWithMeta(r.hrpRunner.t, r.parser, output)
+	if err != nil {
+		return err
+	}
+	msgObj.jwtVerifier = r.jwtVerifier
+	stepResult.ExportVars, err = msgObj.Extract(step.Extract)
+	if err != nil {
+		return err
+	}
+	stepVariables = mergeVariables(stepVariables, stepResult.ExportVars)
+	err = msgObj.Validate(step.Validators, stepVariables)
+	if err != nil {
+		r.hrpRunner.reporter.printValidationFailures(msgObj.validationResults)
+	}
+	stepResult.Data = map[string]interface{}{
+		"output":     builtin.FormatResponse(msgObj.respObjMeta),
+		"validators": msgObj.validationResults,
+	}
+	return err
+}
@@ -3,8 +3,10 @@ package hrp
 import (
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -33,3 +35,236 @@ func TestSearchRegexp(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateJSONEquals(t *testing.T) {
+	resp := http.Response{}
+	resp.Body = io.NopCloser(strings.NewReader(`{"id": 123, "name": "hrp", "created_at": "2022-04-01", "tags": ["a", "b"]}`))
+	respObj, err := newResponseObject(t, newParser(), &resp)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+
+	validators := []interface{}{
+		Validator{
+			Check:  "body",
+			Assert: "json_equals",
+			Expect: `{"id": 1, "name": "hrp", "tags": ["a", "b"]}`,
+			Ignore: []string{"id", "created_at"},
+		},
+	}
+	if !assert.Nil(t, respObj.Validate(validators, map[string]interface{}{})) {
+		t.Fatal()
+	}
+
+	// validation failure is expected, use a new testing.T to avoid failing current test
+	resp.Body = io.NopCloser(strings.NewReader(`{"id": 123, "name": "hrp", "created_at": "2022-04-01", "tags": ["a", "b"]}`))
+	respObj, err = newResponseObject(&testing.T{}, newParser(), &resp)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	validators = []interface{}{
+		Validator{
+			Check:  "body",
+			Assert: "json_equals",
+			Expect: map[string]interface{}{"name": "httprunner", "tags": []interface{}{"a"}},
+			Ignore: []string{"id", "created_at"},
+		},
+	}
+	err = respObj.Validate(validators, map[string]interface{}{})
+	if !assert.NotNil(t, err) {
+		t.Fatal()
+	}
+	diffs := respObj.validationResults[0].Diffs
+	if !assert.Len(t, diffs, 2) {
+		t.Fatal()
+	}
+	assert.Equal(t, "name", diffs[0].Path)
+	assert.Equal(t, "tags[1]", diffs[1].Path)
+}
+
+func TestValidateApproxEqual(t *testing.T) {
+	compat := []interface{}{
+		map[string]interface{}{"check": "body.price", "assert": "approx_equal", "expect": 0.3},
+		map[string]interface{}{"check": "body.lat", "assert": "approx_equal", "expect": 39.9, "epsilon": 0.01},
+	}
+	if !assert.Nil(t, convertCompatValidator(compat)) {
+		t.Fatal()
+	}
+	assert.Equal(t, 0.01, compat[1].(Validator).Epsilon)
+	validators := compat
+
+	resp := http.Response{}
+	resp.Body = io.NopCloser(strings.NewReader(`{"price": 0.30000000000000004, "lat": 39.9042}`))
+	respObj, err := newResponseObject(t, newParser(), &resp)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	assert.Nil(t, respObj.Validate(validators, map[string]interface{}{}))
+
+	// lat differs more than default epsilon
+	resp.Body = io.NopCloser(strings.NewReader(`{"lat": 39.9042}`))
+	respObj, err = newResponseObject(&testing.T{}, newParser(), &resp)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	err = respObj.Validate([]interface{}{
+		Validator{Check: "body.lat", Assert: "approx_equal", Expect: 39.9},
+	}, map[string]interface{}{})
+	assert.NotNil(t, err)
+}
+
+func TestValidateCrossField(t *testing.T) {
+	compat := []interface{}{
+		map[string]interface{}{"check": "body.total", "assert": "equals", "expect_path": "length(body.items)"},
+		map[string]interface{}{"check": "body.items[0].price", "assert": "less_than", "expect_path": "body.max-price"},
+	}
+	if !assert.Nil(t, convertCompatValidator(compat)) {
+		t.Fatal()
+	}
+	assert.Equal(t, `body."max-price"`, compat[1].(Validator).ExpectPath)
+
+	resp := http.Response{}
+	resp.Body = io.NopCloser(strings.NewReader(`{"total": 2, "max-price": 100, "items": [{"price": 9.9}, {"price": 19.9}]}`))
+	respObj, err := newResponseObject(t, newParser(), &resp)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	assert.Nil(t, respObj.Validate(compat, map[string]interface{}{}))
+	assert.Equal(t, float64(2), respObj.validationResults[0].Expect)
+
+	resp.Body = io.NopCloser(strings.NewReader(`{"total": 3, "items": [{"price": 9.9}]}`))
+	respObj, err = newResponseObject(&testing.T{}, newParser(), &resp)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	err = respObj.Validate([]interface{}{
+		Validator{Check: "body.total", Assert: "equals", ExpectPath: "length(body.items)"},
+	}, map[string]interface{}{})
+	assert.NotNil(t, err)
+}
+
+func TestValidateGroups(t *testing.T) {
+	compat := []interface{}{
+		map[string]interface{}{"any_of": []interface{}{
+			map[string]interface{}{"eq": []interface{}{"status_code", 200}},
+			map[string]interface{}{"eq": []interface{}{"status_code", 204}},
+		}, "msg": "check status code"},
+		map[string]interface{}{"not": map[string]interface{}{
+			"check": "body.error", "assert": "not_equal", "expect": nil,
+		}},
+		map[string]interface{}{"not": map[string]interface{}{"any_of": []interface{}{
+			map[string]interface{}{"contains": []interface{}{"body.message", "fail"}},
+			map[string]interface{}{"contains": []interface{}{"body.message", "error"}},
+		}}},
+	}
+	if !assert.Nil(t, convertCompatValidator(compat)) {
+		t.Fatal()
+	}
+	assert.Equal(t, "check status code", compat[0].(Validator).Message)
+	assert.Len(t, compat[2].(Validator).Not.AnyOf, 2)
+
+	resp := http.Response{StatusCode: 204}
+	resp.Body = io.NopCloser(strings.NewReader(`{"message": "success"}`))
+	respObj, err := newResponseObject(t, newParser(), &resp)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	if !assert.Nil(t, respObj.Validate(compat, map[string]interface{}{})) {
+		t.Fatal()
+	}
+	assert.Equal(t, "status_code", respObj.validationResults[0].Check)
+	assert.Equal(t, []interface{}{200, 204}, respObj.validationResults[0].Expect)
+	assert.Equal(t, "not not_equal", respObj.validationResults[1].Assert)
+
+	resp = http.Response{StatusCode: 500}
+	for _, validator := range []Validator{
+		{AnyOf: []Validator{
+			{Check: "status_code", Assert: "equals", Expect: 200},
+			{Check: "status_code", Assert: "equals", Expect: 204},
+		}},
+		{Not: &Validator{Check: "body.error", Assert: "equals", Expect: "timeout"}},
+	} {
+		resp.Body = io.NopCloser(strings.NewReader(`{"message": "internal error", "error": "timeout"}`))
+		respObj, err = newResponseObject(&testing.T{}, newParser(), &resp)
+		if !assert.Nil(t, err) {
+			t.Fatal()
+		}
+		assert.NotNil(t, respObj.Validate([]interface{}{validator}, map[string]interface{}{}))
+	}
+}
+
+func TestValidateExists(t *testing.T) {
+	compat := []interface{}{
+		map[string]interface{}{"check": "body.token", "assert": "exists"},
+		map[string]interface{}{"exists": []interface{}{"body.items[1]"}},
+		map[string]interface{}{"not_exists": []interface{}{"body.error"}},
+		map[string]interface{}{"check": "body.items[2]", "assert": "not_exists", "expect": true},
+		map[string]interface{}{"check": "headers.\"Content-Type\"", "assert": "exists", "expect": true},
+	}
+	if !assert.Nil(t, convertCompatValidator(compat)) {
+		t.Fatal()
+	}
+
+	newResp := func(t *testing.T) *responseObject {
+		resp := http.Response{Header: http.Header{"Content-Type": []string{"application/json"}}}
+		resp.Body = io.NopCloser(strings.NewReader(`{"token": null, "items": [1, null]}`))
+		respObj, err := newResponseObject(t, newParser(), &resp)
+		if !assert.Nil(t, err) {
+			t.Fatal()
+		}
+		return respObj
+	}
+	assert.Nil(t, newResp(t).Validate(compat, map[string]interface{}{}))
+
+	for _, validator := range []Validator{
+		{Check: "body.error", Assert: "exists", Expect: true},
+		{Check: "body.token", Assert: "not_exists", Expect: true},
+		{Check: "body.items[2]", Assert: "exists"},
+	} {
+		assert.NotNil(t, newResp(&testing.T{}).Validate([]interface{}{validator}, map[string]interface{}{}))
+	}
+}
+
+func TestLastResponse(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc"})
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Add("X-Trace", "a")
+		w.Header().Add("X-Trace", "b")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"user": {"id": 1, "name": "foo"}}`))
+	}))
+	defer ts.Close()
+
+	testcase := &TestCase{
+		Config: NewConfig("last response").SetBaseURL(ts.URL),
+		TestSteps: []IStep{
+			NewStep("create user").POST("/users"),
+		},
+	}
+	sessionRunner := NewRunner(t).NewSessionRunner(testcase)
+	assert.Nil(t, sessionRunner.LastResponse())
+	if !assert.Nil(t, sessionRunner.Start()) {
+		t.Fatal()
+	}
+
+	resp := sessionRunner.LastResponse()
+	if !assert.NotNil(t, resp) {
+		t.Fatal()
+	}
+	assert.Same(t, resp, sessionRunner.summary.Records[0].Response)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, []string{"a", "b"}, resp.Header.Values("X-Trace"))
+	if cookie := resp.Cookie("session"); assert.NotNil(t, cookie) {
+		assert.Equal(t, "abc", cookie.Value)
+	}
+	assert.Nil(t, resp.Cookie("unknown"))
+	user := resp.Body.(map[string]interface{})["user"].(map[string]interface{})
+	assert.Equal(t, "foo", user["name"])
+
+	// new connection is dialed with tls handshake
+	assert.Greater(t, resp.Timings.TCPConnection, time.Duration(0))
+	assert.Greater(t, resp.Timings.TLSHandshake, time.Duration(0))
+	assert.Greater(t, resp.Timings.ServerProcessing, time.Duration(0))
+	assert.GreaterOrEqual(t, resp.Timings.Total, resp.Timings.ServerProcessing)
+}
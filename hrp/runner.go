@@ -1,15 +1,17 @@
 package hrp
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
-	"net/url"
-	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 
 	"github.com/httprunner/httprunner/hrp/internal/builtin"
@@ -31,6 +33,9 @@ func NewRunner(t *testing.T) *HRPRunner {
 		t:             t,
 		failfast:      true, // default to failfast
 		genHTMLReport: false,
+		maxCallDepth:  defaultMaxCallDepth,
+		reporter:      newConsoleReporter(),
+		exprCache:     newExpressionCache(),
 		client: &http.Client{
 			Transport: &http.Transport{
 				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
@@ -48,22 +53,99 @@ type HRPRunner struct {
 	saveTests     bool
 	genHTMLReport bool
 	client        *http.Client
+	doer          Doer        // sends requests instead of client if set by SetHTTPClient
+	dialer        *net.Dialer // dialer of client transport, which is the base of dialers tuned by testcases and steps
+	// snapshot settings
+	snapshotDir     string
+	updateSnapshots bool
+	// think time setting, override testcase config if set
+	thinkTimeSetting *ThinkTimeConfig
+	// max depth of nested testcase calls
+	maxCallDepth int
+	// replay settings, only run specified steps with seeded session variables
+	replaySteps     []string
+	replayVariables map[string]interface{}
+	// session state settings, save state on failure and resume from failed step
+	saveSession   bool
+	resumeSession bool
+	// console reporter prints requests, responses and step results
+	reporter *consoleReporter
+	// HAR output path, all requests & responses of run are recorded if set
+	harPath     string
+	harRecorder *harRecorder
+	// .http files output dir, each executed request step is written into .http file if set
+	httpFileDir string
+	httpFileSeq int64
+	// json response body larger than threshold bytes is streamed, only fields referenced by step are decoded
+	jsonStreamThreshold int64
+	// compiled jmespath and regexp expressions shared by all session runners
+	exprCache *expressionCache
+	// http clients with transport tuned by testcase config
+	tunedClients map[TransportConfig]*http.Client
+	// http clients with transport routed by egress of steps
+	egressClients map[egressClientKey]*http.Client
+	clientsMutex  sync.Mutex
+	// notifiers send summary after run completes
+	notifiers []Notifier
+	// listeners receive events of testcases and steps
+	listeners []RunListener
+	// running steps are reported in runtime status of debug server if set
+	debugServer DebugServer
+	// summary of the latest run
+	summary *Summary
+	// history store records results of each run
+	historyStore *HistoryStore
+	// rerun failed testcases up to rerunFailed times, flaky testcases are written into quarantine file if set
+	rerunFailed    int
+	quarantinePath string
+	// variables of global and cli scopes, see VariableScope for precedence
+	globalVariables map[string]interface{}
+	cliVariables    map[string]interface{}
 }
 
 // SetClientTransport configures transport of http client for high concurrency load testing
 func (r *HRPRunner) SetClientTransport(maxConns int, disableKeepAlive bool, disableCompression bool) *HRPRunner {
 	log.Info().Int("maxConns", maxConns).Msg("[init] SetClientTransport")
+	r.dialer = &net.Dialer{}
 	r.client.Transport = &http.Transport{
 		TLSClientConfig:     &tls.Config{InsecureSkipVerify: true},
-		DialContext:         (&net.Dialer{}).DialContext,
+		DialContext:         r.dialer.DialContext,
 		MaxIdleConns:        0,
 		MaxIdleConnsPerHost: maxConns,
 		DisableKeepAlives:   disableKeepAlive,
 		DisableCompression:  disableCompression,
 	}
+	r.resetClients() // tuned clients are rebuilt based on new transport
 	return r
 }
 
+// SetHTTPClient configures client to send requests of steps, e.g. instrumented clients, recorders
+// or in-memory handlers for unit-testing testcases without sockets.
+// If client is *http.Client, it replaces the default client and its *http.Transport is still tuned by testcase config,
+// otherwise transport config, egress and faults of testcases are not applied.
+func (r *HRPRunner) SetHTTPClient(client Doer) *HRPRunner {
+	log.Info().Str("client", fmt.Sprintf("%T", client)).Msg("[init] SetHTTPClient")
+	if c, ok := client.(*http.Client); ok {
+		r.client = c
+		r.doer = nil
+		r.resetClients() // tuned clients are rebuilt based on new client
+		return r
+	}
+	r.doer = client
+	return r
+}
+
+// SetHandler routes requests of steps directly to handler in process without network and ports,
+// thus Go services can run testcases in their own unit tests, host of request urls is kept in Host header.
+func (r *HRPRunner) SetHandler(handler http.Handler) *HRPRunner {
+	log.Info().Msg("[init] SetHandler")
+	return r.SetHTTPClient(&http.Client{
+		Transport: &handlerTransport{handler: handler},
+		Jar:       r.client.Jar,
+		Timeout:   r.client.Timeout,
+	})
+}
+
 // SetFailfast configures whether to stop running when one step fails.
 func (r *HRPRunner) SetFailfast(failfast bool) *HRPRunner {
 	log.Info().Bool("failfast", failfast).Msg("[init] SetFailfast")
@@ -71,6 +153,13 @@ func (r *HRPRunner) SetFailfast(failfast bool) *HRPRunner {
 	return r
 }
 
+// SetFakerSeed seeds random source of builtin fake_* functions, thus fake data are reproducible.
+func (r *HRPRunner) SetFakerSeed(seed int64) *HRPRunner {
+	log.Info().Int64("seed", seed).Msg("[init] SetFakerSeed")
+	builtin.SetFakerSeed(seed)
+	return r
+}
+
 // SetRequestsLogOn turns on request & response details logging.
 func (r *HRPRunner) SetRequestsLogOn() *HRPRunner {
 	log.Info().Msg("[init] SetRequestsLogOn")
@@ -87,17 +176,7 @@ func (r *HRPRunner) SetPluginLogOn() *HRPRunner {
 
 // SetProxyUrl configures the proxy URL, which is usually used to capture HTTP packets for debugging.
 func (r *HRPRunner) SetProxyUrl(proxyUrl string) *HRPRunner {
-	log.Info().Str("proxyUrl", proxyUrl).Msg("[init] SetProxyUrl")
-	p, err := url.Parse(proxyUrl)
-	if err != nil {
-		log.Error().Err(err).Str("proxyUrl", proxyUrl).Msg("[init] invalid proxyUrl")
-		return r
-	}
-	r.client.Transport = &http.Transport{
-		Proxy:           http.ProxyURL(p),
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-	}
-	return r
+	return r.SetProxy(&ProxyConfig{URL: proxyUrl})
 }
 
 // SetSaveTests configures whether to save summary of tests.
@@ -114,8 +193,163 @@ func (r *HRPRunner) GenHTMLReport() *HRPRunner {
 	return r
 }
 
+// SetSnapshotDir configures the folder to store snapshot golden files.
+// Default to snapshots folder beside testcase file.
+func (r *HRPRunner) SetSnapshotDir(dir string) *HRPRunner {
+	log.Info().Str("snapshotDir", dir).Msg("[init] SetSnapshotDir")
+	r.snapshotDir = dir
+	return r
+}
+
+// SetUpdateSnapshots configures whether to overwrite snapshot golden files with current responses.
+func (r *HRPRunner) SetUpdateSnapshots(update bool) *HRPRunner {
+	log.Info().Bool("updateSnapshots", update).Msg("[init] SetUpdateSnapshots")
+	r.updateSnapshots = update
+	return r
+}
+
+// SetThinkTime configures think time setting for all testcases, which overrides think time config of testcase.
+// e.g. ignore think time in CI functional runs, and keep realistic pacing in load testing.
+func (r *HRPRunner) SetThinkTime(setting *ThinkTimeConfig) *HRPRunner {
+	log.Info().Interface("thinkTime", setting).Msg("[init] SetThinkTime")
+	setting.checkThinkTime()
+	r.thinkTimeSetting = setting
+	return r
+}
+
+// SetMaxCallDepth configures max depth of nested referenced testcase calls, default to 10.
+func (r *HRPRunner) SetMaxCallDepth(depth int) *HRPRunner {
+	log.Info().Int("maxCallDepth", depth).Msg("[init] SetMaxCallDepth")
+	r.maxCallDepth = depth
+	return r
+}
+
+// SetReplaySteps configures to only run the specified steps plus their declared dependencies,
+// steps are specified by name or index (starting from 1), which is used to debug long testcases.
+func (r *HRPRunner) SetReplaySteps(steps ...string) *HRPRunner {
+	log.Info().Strs("steps", steps).Msg("[init] SetReplaySteps")
+	r.replaySteps = steps
+	return r
+}
+
+// SetReplayVariables configures variables to seed session when replaying steps,
+// e.g. variables extracted by previous steps loaded with LoadSessionVariables.
+func (r *HRPRunner) SetReplayVariables(variables map[string]interface{}) *HRPRunner {
+	log.Info().Interface("variables", variables).Msg("[init] SetReplayVariables")
+	r.replayVariables = variables
+	return r
+}
+
+// SetSaveSession configures whether to save session variables, cookies and completed steps
+// to <testcase>.session.json when testcase fails, which can be resumed with SetResume.
+func (r *HRPRunner) SetSaveSession(save bool) *HRPRunner {
+	log.Info().Bool("saveSession", save).Msg("[init] SetSaveSession")
+	r.saveSession = save
+	r.enableCookieJar()
+	return r
+}
+
+// SetResume configures whether to restore saved session state and continue from the failed step,
+// steps completed in previous run are skipped.
+func (r *HRPRunner) SetResume(resume bool) *HRPRunner {
+	log.Info().Bool("resume", resume).Msg("[init] SetResume")
+	r.resumeSession = resume
+	r.saveSession = r.saveSession || resume
+	r.enableCookieJar()
+	return r
+}
+
+// SetOutput configures writer of console output, default to stdout,
+// which is usually used to embed hrp in other tools.
+func (r *HRPRunner) SetOutput(out io.Writer) *HRPRunner {
+	log.Info().Msg("[init] SetOutput")
+	r.reporter.out = NewSecretMaskWriter(out)
+	return r
+}
+
+// SetOutputLevel configures console output level, OutputQuiet only prints pass/fail line of each step,
+// OutputVerbose prints full request & response dumps.
+func (r *HRPRunner) SetOutputLevel(level OutputLevel) *HRPRunner {
+	log.Info().Int("level", int(level)).Msg("[init] SetOutputLevel")
+	r.reporter.level = level
+	return r
+}
+
+// SetColorOutput configures whether to highlight statuses and failed asserts with colors.
+func (r *HRPRunner) SetColorOutput(color bool) *HRPRunner {
+	log.Info().Bool("color", color).Msg("[init] SetColorOutput")
+	r.reporter.color = color
+	return r
+}
+
+// SetLogBodyLimit configures max bytes of pretty printed request & response bodies,
+// longer bodies are truncated with a note.
+func (r *HRPRunner) SetLogBodyLimit(limit int) *HRPRunner {
+	log.Info().Int("limit", limit).Msg("[init] SetLogBodyLimit")
+	r.reporter.bodyLimit = limit
+	return r
+}
+
+// SetPrintableContentTypes configures prefixes of content types whose request & response bodies
+// are printed as text, "*" prints bodies of all content types, default to DefaultPrintableContentTypes.
+func (r *HRPRunner) SetPrintableContentTypes(contentTypes ...string) *HRPRunner {
+	log.Info().Strs("contentTypes", contentTypes).Msg("[init] SetPrintableContentTypes")
+	r.reporter.printableContentTypes = contentTypes
+	return r
+}
+
+// SetLogHexdump configures to print first limit bytes of binary request & response bodies
+// as hexdump, e.g. protobuf or msgpack, instead of omitting them.
+func (r *HRPRunner) SetLogHexdump(limit int) *HRPRunner {
+	log.Info().Int("limit", limit).Msg("[init] SetLogHexdump")
+	r.reporter.hexdumpLimit = limit
+	return r
+}
+
+// SetHAROutput configures to write all executed requests & responses of run into HAR file with timings,
+// which can be loaded into browser devtools for analysis.
+func (r *HRPRunner) SetHAROutput(path string) *HRPRunner {
+	log.Info().Str("path", path).Msg("[init] SetHAROutput")
+	r.harPath = path
+	return r
+}
+
+// SetJSONStreamThreshold configures size threshold in bytes of streaming json response body,
+// only fields referenced by simple jmespath of extractors and validators are decoded to reduce peak memory,
+// the whole body is decoded if step uses complex jmespath, regexp extractor, snapshot or hrp_step_response.
+func (r *HRPRunner) SetJSONStreamThreshold(threshold int64) *HRPRunner {
+	log.Info().Int64("threshold", threshold).Msg("[init] SetJSONStreamThreshold")
+	r.jsonStreamThreshold = threshold
+	return r
+}
+
 // Run starts to execute one or multiple testcases.
 func (r *HRPRunner) Run(testcases ...ITestCase) error {
+	return r.RunWithContext(context.Background(), testcases...)
+}
+
+// RunWithContext executes testcases until context is done, e.g. canceled by SIGINT.
+// When context is done, in-flight requests are canceled, teardown hooks of started steps are run,
+// no more steps are run, and partial summary of executed steps is still saved and reported.
+func (r *HRPRunner) RunWithContext(ctx context.Context, testcases ...ITestCase) error {
+	_, err := r.runAndNotify(ctx, testcases...)
+	return err
+}
+
+// runAndNotify executes testcases, then saves summary into history store and sends it to notifiers.
+func (r *HRPRunner) runAndNotify(ctx context.Context, testcases ...ITestCase) (*Summary, error) {
+	summary, err := r.run(ctx, testcases...)
+	if r.historyStore != nil {
+		if err := r.historyStore.Save(summary); err != nil {
+			log.Error().Err(err).Msg("save run results into history store failed")
+		}
+	}
+	r.notify(summary, err)
+	return summary, err
+}
+
+// run executes testcases and returns summary of executed testcases.
+func (r *HRPRunner) run(ctx context.Context, testcases ...ITestCase) (*Summary, error) {
 	event := sdk.EventTracking{
 		Category: "RunAPITests",
 		Action:   "hrp run",
@@ -126,21 +360,34 @@ func (r *HRPRunner) Run(testcases ...ITestCase) error {
 	defer sdk.SendEvent(event.StartTiming("execution"))
 	// record execution data to summary
 	s := newOutSummary()
+	r.summary = s
+
+	// record requests & responses into HAR file, failed runs are also recorded
+	if r.harPath != "" {
+		r.harRecorder = newHARRecorder()
+		defer func() {
+			if err := r.harRecorder.dump(r.harPath); err != nil {
+				log.Error().Err(err).Str("path", r.harPath).Msg("write HAR file failed")
+			}
+		}()
+	}
 
 	// load all testcases
 	testCases, err := loadTestCases(testcases...)
 	if err != nil {
-		return err
+		return s, err
 	}
 
-	// run testcase one by one
+	// run testcase one by one, partial summary is kept if run is canceled
+	var canceledErr error
+runTestCases:
 	for _, testcase := range testCases {
 		cfg := testcase.Config
 		// parse config parameters
 		err := initParameterIterator(cfg, "runner")
 		if err != nil {
 			log.Error().Interface("parameters", cfg.Parameters).Err(err).Msg("parse config parameters failed")
-			return err
+			return s, err
 		}
 		// 在runner模式下，指定整体策略，cfg.ParametersSetting.Iterators仅包含一个CartesianProduct的迭代器
 		for it := cfg.ParametersSetting.Iterators[0]; it.HasNext(); {
@@ -150,27 +397,39 @@ func (r *HRPRunner) Run(testcases ...ITestCase) error {
 					cfg.Variables = mergeVariables(it.Next(), cfg.Variables)
 				}
 			}
-			sessionRunner := r.NewSessionRunner(testcase)
-			if err = sessionRunner.Start(); err != nil {
+			if canceledErr = canceled(ctx); canceledErr != nil {
+				break runTestCases
+			}
+			caseSummary, err := r.runTestCase(ctx, testcase)
+			if err != nil && canceled(ctx) != nil {
+				log.Warn().Err(err).Msg("[Run] run canceled, save partial summary")
+				s.appendCaseSummary(caseSummary)
+				canceledErr = err
+				break runTestCases
+			}
+			if err != nil {
 				log.Error().Err(err).Msg("[Run] run testcase failed")
-				return err
+				return s, err
 			}
-			caseSummary := sessionRunner.GetSummary()
 			s.appendCaseSummary(caseSummary)
 		}
 	}
+	if canceledErr != nil {
+		s.Success = false
+	}
 	s.Time.Duration = time.Since(s.Time.StartAt).Seconds()
 
+	// write quarantine list of flaky testcases
+	if r.quarantinePath != "" {
+		if err := s.dumpQuarantine(r.quarantinePath); err != nil {
+			return s, err
+		}
+	}
+
 	// save summary
 	if r.saveTests {
-		dir, _ := filepath.Split(summaryPath)
-		err := builtin.EnsureFolderExists(dir)
-		if err != nil {
-			return err
-		}
-		err = builtin.Dump2JSON(s, fmt.Sprintf(summaryPath, s.Time.StartAt.Unix()))
-		if err != nil {
-			return err
+		if err := s.dump(fmt.Sprintf(summaryPath, s.Time.StartAt.Unix())); err != nil {
+			return s, err
 		}
 	}
 
@@ -178,18 +437,47 @@ func (r *HRPRunner) Run(testcases ...ITestCase) error {
 	if r.genHTMLReport {
 		err := s.genHTMLReport()
 		if err != nil {
-			return err
+			return s, err
 		}
 	}
+	return s, canceledErr
+}
+
+// canceled returns error if context of run is done.
+func canceled(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return errors.Wrap(err, "run canceled")
+	}
 	return nil
 }
 
+// DumpSummary writes summary of the latest run into json file, which conforms to versioned json schema
+// schemas/summary.schema.json, thus can be consumed by external dashboards and result-diff tools.
+func (r *HRPRunner) DumpSummary(path string) error {
+	if r.summary == nil {
+		return errors.New("summary not found, run testcases first")
+	}
+	return r.summary.dump(path)
+}
+
 func (r *HRPRunner) NewSessionRunner(testcase *TestCase) *SessionRunner {
+	return r.newSessionRunner(context.Background(), testcase)
+}
+
+// newSessionRunner creates session runner of testcase canceled by ctx of run,
+// thus concurrent runs of the same runner are canceled separately.
+func (r *HRPRunner) newSessionRunner(ctx context.Context, testcase *TestCase) *SessionRunner {
+	parser := newParser()
+	// share compiled expressions among iterations of testcases
+	parser.exprCache = r.exprCache
 	sessionRunner := &SessionRunner{
 		testCase:  testcase,
 		hrpRunner: r,
-		parser:    newParser(),
+		parser:    parser,
 		summary:   newSummary(),
+		callChain: []*TestCase{testcase},
+		runCtx:    ctx,
+		ctx:       ctx,
 	}
 	sessionRunner.init()
 	return sessionRunner
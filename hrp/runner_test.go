@@ -1,11 +1,18 @@
 package hrp
 
 import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"os/exec"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/httprunner/httprunner/hrp/internal/builtin"
 	"github.com/httprunner/httprunner/hrp/internal/scaffold"
 	"github.com/rs/zerolog/log"
 	"github.com/stretchr/testify/assert"
@@ -155,6 +162,42 @@ func TestRunCaseWithThinkTime(t *testing.T) {
 	}
 }
 
+func TestRunCaseWithThinkTimeOverride(t *testing.T) {
+	settings := []string{"ignore", "multiply:0.2", "limit:300ms", "random_percentage:0.1-0.2,limit:0.15"}
+	expectedMinValue := []float64{0, 0.2, 0.3, 0.1}
+	expectedMaxValue := []float64{0.1, 0.4, 0.5, 0.25}
+	for idx, setting := range settings {
+		thinkTimeSetting, err := ParseThinkTimeConfig(setting)
+		if !assert.Nil(t, err) {
+			t.Fatal()
+		}
+		testcase := &TestCase{
+			Config: NewConfig("TestCase").
+				SetThinkTime(thinkTimeMultiply, 2, 0),
+			TestSteps: []IStep{
+				NewStep("thinkTime").SetThinkTime(1),
+			},
+		}
+		r := NewRunner(t).SetThinkTime(thinkTimeSetting)
+		startTime := time.Now()
+		if err := r.Run(testcase); err != nil {
+			t.Fatalf("run testcase error: %v", err)
+		}
+		duration := time.Since(startTime)
+		minValue := time.Duration(expectedMinValue[idx]*1000) * time.Millisecond
+		maxValue := time.Duration(expectedMaxValue[idx]*1000) * time.Millisecond
+		if duration < minValue || duration > maxValue {
+			t.Fatalf("failed to test think time %s, expect value: [%v, %v], actual value: %v",
+				setting, minValue, maxValue, duration)
+		}
+	}
+
+	for _, setting := range []string{"multiply:-1", "random_percentage:2-1", "limit:abc", "unknown"} {
+		_, err := ParseThinkTimeConfig(setting)
+		assert.NotNil(t, err, setting)
+	}
+}
+
 func TestRunCaseWithPluginJSON(t *testing.T) {
 	buildHashicorpGoPlugin()
 	defer removeHashicorpGoPlugin()
@@ -242,3 +285,287 @@ func TestLoadTestCases(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestRunWithContextCanceled(t *testing.T) {
+	started := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			close(started)
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	var teardownSteps []string
+	builtin.Functions["record_teardown"] = func(name string) string {
+		teardownSteps = append(teardownSteps, name)
+		return name
+	}
+	defer delete(builtin.Functions, "record_teardown")
+
+	testcase := &TestCase{
+		Config: NewConfig("cancel").SetBaseURL(ts.URL),
+		TestSteps: []IStep{
+			NewStep("fast").GET("/fast"),
+			NewStep("slow").GET("/slow").TeardownHook("${record_teardown($hrp_step_name)}"),
+			NewStep("not started").GET("/fast"),
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+	runner := NewRunner(t)
+	start := time.Now()
+	err := runner.RunWithContext(ctx, testcase)
+	assert.Less(t, time.Since(start), 5*time.Second)
+	if !assert.True(t, errors.Is(err, context.Canceled)) {
+		t.Fatal()
+	}
+	assert.Equal(t, []string{"slow"}, teardownSteps)
+
+	// partial summary of executed steps is kept
+	summary := runner.summary
+	assert.False(t, summary.Success)
+	if assert.Len(t, summary.Details, 1) && assert.Len(t, summary.Details[0].Records, 2) {
+		assert.True(t, summary.Details[0].Records[0].Success)
+		assert.False(t, summary.Details[0].Records[1].Success)
+	}
+
+	// think time is interrupted when context is done
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	err = runner.RunWithContext(ctx, &TestCase{
+		Config:    NewConfig("think time"),
+		TestSteps: []IStep{NewStep("think").SetThinkTime(10)},
+	})
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	// runner can be reused after cancellation
+	assert.Nil(t, runner.Run(&TestCase{
+		Config:    NewConfig("reuse").SetBaseURL(ts.URL),
+		TestSteps: []IStep{NewStep("fast").GET("/fast")},
+	}))
+}
+
+func TestRunWithContextConcurrently(t *testing.T) {
+	started := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			close(started)
+			<-r.Context().Done()
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	runner := NewRunner(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	canceledErr := make(chan error)
+	go func() {
+		canceledErr <- runner.RunWithContext(ctx, &TestCase{
+			Config:    NewConfig("canceled").SetBaseURL(ts.URL),
+			TestSteps: []IStep{NewStep("slow").GET("/slow")},
+		})
+	}()
+	<-started
+
+	// run with another context is not canceled, nor does it override context of the canceled run
+	done := make(chan error)
+	go func() {
+		done <- runner.RunWithContext(context.Background(), &TestCase{
+			Config: NewConfig("not canceled").SetBaseURL(ts.URL),
+			TestSteps: []IStep{
+				NewStep("think").SetThinkTime(0.1),
+				NewStep("fast").GET("/fast"),
+			},
+		})
+	}()
+	cancel()
+	select {
+	case err := <-canceledErr:
+		assert.True(t, errors.Is(err, context.Canceled))
+	case <-time.After(5 * time.Second):
+		t.Fatal("run should be canceled")
+	}
+	select {
+	case err := <-done:
+		assert.Nil(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("run should be finished")
+	}
+}
+
+func TestRunCaseWithDeadline(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	var remaining []int64
+	builtin.Functions["record_remaining"] = func(ms int64) int64 {
+		remaining = append(remaining, ms)
+		return ms
+	}
+	defer delete(builtin.Functions, "record_remaining")
+
+	testcase := &TestCase{
+		Config: NewConfig("deadline").SetBaseURL(ts.URL).SetDeadline(0.3),
+		TestSteps: []IStep{
+			NewStep("fast").SetupHook("${record_remaining($hrp_remaining_ms)}").GET("/fast"),
+			NewStep("slow").GET("/slow").SetTimeout(10),
+			NewStep("not started").GET("/fast"),
+		},
+	}
+	sessionRunner := NewRunner(t).SetFailfast(false).NewSessionRunner(testcase)
+	start := time.Now()
+	err := sessionRunner.Start()
+	assert.Less(t, time.Since(start), 5*time.Second)
+	if !assert.NotNil(t, err) {
+		t.Fatal()
+	}
+	if assert.Len(t, remaining, 1) {
+		assert.Greater(t, remaining[0], int64(0))
+		assert.LessOrEqual(t, remaining[0], int64(300))
+	}
+	records := sessionRunner.summary.Records
+	if assert.Len(t, records, 2) {
+		assert.True(t, records[0].Success)
+		assert.False(t, records[1].Success)
+		assert.Equal(t, FailureTimeout, records[1].FailureCode)
+	}
+
+	// step timeout is applied without deadline
+	testcase = &TestCase{
+		Config:    NewConfig("step timeout").SetBaseURL(ts.URL),
+		TestSteps: []IStep{NewStep("slow").GET("/slow").SetTimeout(0.1)},
+	}
+	sessionRunner = NewRunner(t).SetFailfast(false).NewSessionRunner(testcase)
+	start = time.Now()
+	assert.Nil(t, sessionRunner.Start())
+	assert.Less(t, time.Since(start), 5*time.Second)
+	if records := sessionRunner.summary.Records; assert.Len(t, records, 1) {
+		assert.Equal(t, FailureTimeout, records[0].FailureCode)
+	}
+}
+
+type doerFunc func(req *http.Request) (*http.Response, error)
+
+func (f doerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestRunCaseWithHTTPClient(t *testing.T) {
+	// serve requests in memory without sockets
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"path": "` + r.URL.Path + `"}`))
+	})
+	var paths []string
+	doer := doerFunc(func(req *http.Request) (*http.Response, error) {
+		paths = append(paths, req.URL.Path)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder.Result(), nil
+	})
+
+	testcase := &TestCase{
+		Config: NewConfig("http client").SetBaseURL("http://example.invalid"),
+		TestSteps: []IStep{
+			NewStep("get user").
+				GET("/user").
+				Extract().
+				WithJmesPath("body.path", "path").
+				Validate().
+				AssertEqual("status_code", 200, "check status code"),
+			NewStep("get order").
+				GET("/order").
+				WithParams(map[string]interface{}{"from": "$path"}).
+				Validate().
+				AssertEqual("body.path", "/order", "check path"),
+		},
+	}
+	runner := NewRunner(t).SetHTTPClient(doer)
+	if !assert.Nil(t, runner.Run(testcase)) {
+		t.Fatal()
+	}
+	assert.Equal(t, []string{"/user", "/order"}, paths)
+
+	// *http.Client replaces default client
+	client := &http.Client{Timeout: time.Second}
+	runner.SetHTTPClient(client)
+	assert.Nil(t, runner.doer)
+	assert.Same(t, client, runner.client)
+}
+
+type testCloser struct {
+	closed bool
+}
+
+func (c *testCloser) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestSessionClientConnectWithoutLock(t *testing.T) {
+	sessionRunner := NewRunner(t).NewSessionRunner(&TestCase{Config: NewConfig("clients")})
+	connecting := make(chan struct{})
+	release := make(chan struct{})
+	slow := &testCloser{}
+	var slowClient io.Closer
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		var err error
+		slowClient, err = sessionRunner.client("slow", func() (io.Closer, error) {
+			close(connecting)
+			<-release
+			return slow, nil
+		})
+		assert.Nil(t, err)
+	}()
+
+	// clients of other keys and session variables are not blocked by dialing
+	<-connecting
+	fast := &testCloser{}
+	client, err := sessionRunner.client("fast", func() (io.Closer, error) {
+		return fast, nil
+	})
+	assert.Nil(t, err)
+	assert.Same(t, fast, client)
+	sessionRunner.updateSessionVariables(map[string]interface{}{"a": 1})
+
+	// client of the same key connected first is kept, the later one is closed
+	first := &testCloser{}
+	client, err = sessionRunner.client("slow", func() (io.Closer, error) {
+		return first, nil
+	})
+	assert.Nil(t, err)
+	assert.Same(t, first, client)
+	close(release)
+	wg.Wait()
+	assert.Same(t, first, slowClient)
+	assert.True(t, slow.closed)
+
+	sessionRunner.closeClients()
+	assert.True(t, first.closed)
+	assert.True(t, fast.closed)
+}
@@ -0,0 +1,61 @@
+package hrp
+
+import (
+	builtinJSON "encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/httprunner/httprunner/hrp/internal/builtin"
+	"github.com/httprunner/httprunner/hrp/internal/json"
+)
+
+// RegisterSecrets registers secret values to be masked in console output, logs written by
+// NewSecretMaskWriter, summary, html report, HAR and notifications. Values resolved by builtin
+// vault, aws_sm and decrypt_secret functions are registered automatically, this is used to mask
+// secrets resolved by plugin functions. Values shorter than 4 characters are not masked.
+func RegisterSecrets(values ...string) {
+	builtin.AddSecrets(values...)
+}
+
+// NewSecretMaskWriter wraps writer to mask registered secrets, e.g. writer of logger.
+func NewSecretMaskWriter(w io.Writer) io.Writer {
+	if mw, ok := w.(*secretMaskWriter); ok {
+		return mw
+	}
+	return &secretMaskWriter{w: w}
+}
+
+type secretMaskWriter struct {
+	w io.Writer
+}
+
+func (mw *secretMaskWriter) Write(p []byte) (int, error) {
+	var masked []byte
+	if builtinJSON.Valid(p) {
+		// json logs are masked by tokens to keep them valid
+		masked = builtin.MaskSecretsInJSON(p)
+	} else {
+		masked = []byte(builtin.MaskSecrets(string(p)))
+	}
+	if _, err := mw.w.Write(masked); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// dumpMaskedJSON writes data into json file with registered secrets masked.
+func dumpMaskedJSON(data interface{}, path string) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	log.Info().Str("path", path).Msg("dump data to json")
+	content, err := json.MarshalIndent(data, "", "    ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, builtin.MaskSecretsInJSON(content), 0o644)
+}
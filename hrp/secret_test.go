@@ -0,0 +1,69 @@
+package hrp
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/httprunner/httprunner/hrp/internal/builtin"
+)
+
+func TestMaskSecretsInOutputs(t *testing.T) {
+	ts := newQueryEchoServer()
+	defer ts.Close()
+
+	key, _ := builtin.GenSecretKey()
+	os.Setenv(builtin.SecretKeyEnv, key)
+	defer os.Unsetenv(builtin.SecretKeyEnv)
+	encrypted, err := builtin.EncryptSecret("masked-password")
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+
+	testcase := &TestCase{
+		Config: NewConfig("mask secrets").
+			SetBaseURL(ts.URL).
+			WithVariables(map[string]interface{}{"password": "${decrypt_secret(" + encrypted + ")}"}),
+		TestSteps: []IStep{
+			NewStep("login").
+				GET("/login").
+				WithParams(map[string]interface{}{"password": "$password"}).
+				Validate().
+				AssertEqual("body.password", "masked-password", "check password decrypted"),
+		},
+	}
+	var out bytes.Buffer
+	runner := NewRunner(t).SetOutput(&out).SetOutputLevel(OutputVerbose)
+	if !assert.Nil(t, runner.Run(testcase)) {
+		t.Fatal()
+	}
+	assert.Contains(t, out.String(), "password=******")
+	assert.NotContains(t, out.String(), "masked-password")
+
+	summaryPath := filepath.Join(t.TempDir(), "summary.json")
+	if !assert.Nil(t, runner.DumpSummary(summaryPath)) {
+		t.Fatal()
+	}
+	content, _ := os.ReadFile(summaryPath)
+	assert.True(t, strings.Contains(string(content), "******"))
+	assert.NotContains(t, string(content), "masked-password")
+
+	// secrets registered by plugins are masked in logs
+	RegisterSecrets("plugin-token")
+	var logs bytes.Buffer
+	_, _ = NewSecretMaskWriter(&logs).Write([]byte(`{"token": "plugin-token"}`))
+	assert.Equal(t, `{"token": "******"}`, logs.String())
+
+	// json logs are kept valid with numeric secrets
+	RegisterSecrets("13572468")
+	logs.Reset()
+	_, _ = NewSecretMaskWriter(&logs).Write([]byte(`{"pin":13572468,"msg":"pin 13572468"}`))
+	assert.Equal(t, `{"pin":"******","msg":"pin ******"}`, logs.String())
+	logs.Reset()
+	_, _ = NewSecretMaskWriter(&logs).Write([]byte("pin 13572468\n"))
+	assert.Equal(t, "pin ******\n", logs.String())
+}
@@ -1,7 +1,11 @@
 package hrp
 
 import (
+	"context"
 	_ "embed"
+	"io"
+	"net/http"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
@@ -15,21 +19,56 @@ type SessionRunner struct {
 	hrpRunner        *HRPRunner
 	parser           *Parser
 	sessionVariables map[string]interface{}
+	// parsed variables of global and cli scopes, see VariableScope for precedence
+	globalVariables map[string]interface{}
+	cliVariables    map[string]interface{}
+	lazyVariables   map[string]interface{} // variable name => *lazyValue of testcase config
+	jwtVerifier     *jwtVerifier           // verifies jwt of responses with keys of testcase config
 	// transactions stores transaction timing info.
 	// key is transaction name, value is map of transaction type and time, e.g. start time and end time.
 	transactions map[string]map[transactionType]time.Time
 	startTime    time.Time        // record start time of the testcase
 	summary      *TestCaseSummary // record test case summary
+	// callChain records referenced testcases from root testcase to current testcase
+	callChain []*TestCase
+	// completedSteps records index of successful steps in testcase, which is saved in session state
+	completedSteps map[int]bool
+	// visitedURLs records scheme and host of requests, which is used to save cookies
+	visitedURLs map[string]bool
+	// lastResponse records response of the latest request step, which is inspected in shell mode
+	lastResponse interface{}
+	// response records typed response of the latest request step, see LastResponse
+	response *Response
+	// jar keeps cookies of virtual user in load testing, which overrides cookie jar of http client if set
+	jar http.CookieJar
+	// clients stores broker and database clients of steps, e.g. MQTT, Kafka, which are closed when session ends
+	clients map[string]io.Closer
+	// mutex protects session variables and transactions when steps run concurrently,
+	// summary is protected by its own mutex
+	mutex sync.RWMutex
+	// runCtx is done when run is canceled, ctx is also done when deadline of testcase is exceeded,
+	// both are inherited by referenced testcases
+	runCtx context.Context
+	ctx    context.Context
 }
 
 func (r *SessionRunner) init() {
 	log.Info().Msg("init session runner")
 	r.sessionVariables = make(map[string]interface{})
 	r.transactions = make(map[string]map[transactionType]time.Time)
+	r.completedSteps = make(map[int]bool)
+	r.visitedURLs = make(map[string]bool)
+	r.clients = make(map[string]io.Closer)
 	r.startTime = time.Now()
 	r.summary.Name = r.testCase.Config.Name
 }
 
+func (r *SessionRunner) quitPlugin() {
+	if r.parser.plugin != nil {
+		r.parser.plugin.Quit()
+	}
+}
+
 func (r *SessionRunner) GetParser() *Parser {
 	return r.parser
 }
@@ -39,11 +78,21 @@ func (r *SessionRunner) GetConfig() *TConfig {
 }
 
 func (r *SessionRunner) LogOn() bool {
-	return r.hrpRunner.requestsLogOn
+	return r.hrpRunner.reporter.showDumps(r.hrpRunner.requestsLogOn)
 }
 
-// Start runs the test steps in sequential order.
+// Start runs the test steps in sequential order,
+// or in DAG order if any step declares depends_on.
 func (r *SessionRunner) Start() error {
+	r.hrpRunner.onCaseStart(r.testCase)
+	err := r.start()
+	if len(r.hrpRunner.listeners) > 0 {
+		r.hrpRunner.onCaseEnd(r.testCase, r.GetSummary(), err)
+	}
+	return err
+}
+
+func (r *SessionRunner) start() error {
 	config := r.testCase.Config
 	log.Info().Str("testcase", config.Name).Msg("run testcase start")
 
@@ -55,24 +104,94 @@ func (r *SessionRunner) Start() error {
 	if r.parser.plugin, err = initPlugin(config.Path, r.hrpRunner.pluginLogOn); err != nil {
 		return err
 	}
-	defer func() {
-		if r.parser.plugin != nil {
-			r.parser.plugin.Quit()
-		}
-	}()
+	defer r.quitPlugin()
+	defer r.closeClients()
 
 	// parse config
 	if err := r.parseConfig(config); err != nil {
 		return err
 	}
 
+	// generate correlation id shared by requests of testcase
+	if cfg := config.CorrelationID; cfg != nil && !cfg.perStep() {
+		id, err := cfg.generate(r.parser, config.Variables)
+		if err != nil {
+			return err
+		}
+		r.updateSessionVariables(map[string]interface{}{cfg.variable(): id})
+		r.summary.CorrelationID = id
+		log.Info().Str("testcase", config.Name).Str("correlationID", id).Msg("generate correlation id")
+	}
+
+	steps := r.testCase.TestSteps
+	// indexes of steps in testcase, which identify completed steps in session state
+	indexes := make([]int, len(steps))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	isRoot := len(r.callChain) == 1
+	// only replay specified steps in root testcase for debugging
+	if len(r.hrpRunner.replaySteps) > 0 && isRoot {
+		steps, indexes, err = selectReplaySteps(steps, r.hrpRunner.replaySteps)
+		if err != nil {
+			return err
+		}
+		r.updateSessionVariables(r.hrpRunner.replayVariables)
+	}
+
+	// restore session state saved by previous failed run
+	if isRoot && r.hrpRunner.resumeSession {
+		if err := r.restoreSessionState(); err != nil {
+			return err
+		}
+	}
+
 	r.startTime = time.Now()
-	// run step in sequential order
-	for _, step := range r.testCase.TestSteps {
+	if config.Deadline > 0 {
+		baseCtx := r.ctx
+		var cancel context.CancelFunc
+		r.ctx, cancel = context.WithDeadline(baseCtx, r.startTime.Add(time.Duration(config.Deadline*1000)*time.Millisecond))
+		defer func() {
+			cancel()
+			r.ctx = baseCtx
+		}()
+	}
+	if hasStepDependencies(steps) {
+		err = r.runStepsInDAG(steps, indexes)
+	} else {
+		err = r.runStepsInOrder(steps, indexes)
+	}
+	if isRoot && r.hrpRunner.saveSession {
+		r.saveSessionState(err == nil && r.summary.isSuccess())
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Info().Str("testcase", config.Name).Str("correlationID", r.summary.CorrelationID).Msg("run testcase end")
+	return nil
+}
+
+// runStepsInOrder runs steps in sequential order, indexes are positions of steps in testcase.
+func (r *SessionRunner) runStepsInOrder(steps []IStep, indexes []int) error {
+	for index, step := range steps {
+		if err := r.interrupted(); err != nil {
+			return err
+		}
+		if r.completedSteps[indexes[index]] {
+			log.Info().Str("step", step.Name()).Msg("skip step completed in previous run")
+			continue
+		}
 		log.Info().Str("step", step.Name()).
 			Str("type", string(step.Type())).Msg("run step start")
 
-		stepResult, err := step.Run(r)
+		stepResult, err := r.runStep(step)
+		r.hrpRunner.reporter.printStepResult(stepResult, err)
+		if err != nil && r.interrupted() != nil {
+			// keep result of step interrupted by cancellation in partial summary
+			r.updateSummary(stepResult)
+			return err
+		}
 		if err != nil && r.hrpRunner.failfast {
 			log.Error().
 				Str("step", stepResult.Name).
@@ -83,11 +202,12 @@ func (r *SessionRunner) Start() error {
 		}
 
 		// update extracted variables
-		for k, v := range stepResult.ExportVars {
-			r.sessionVariables[k] = v
-		}
+		r.updateSessionVariables(stepResult.ExportVars)
 		// update testcase summary
 		r.updateSummary(stepResult)
+		if stepResult.Success {
+			r.completedSteps[indexes[index]] = true
+		}
 
 		log.Info().
 			Str("step", stepResult.Name).
@@ -96,31 +216,134 @@ func (r *SessionRunner) Start() error {
 			Interface("exportVars", stepResult.ExportVars).
 			Msg("run step end")
 	}
+	return nil
+}
+
+// runStepGroup runs nested steps of loop or branch in sequential order,
+// step results are recorded in summary and exported variables are merged into session variables.
+// If failfast is not set, all steps are run and the last error is returned.
+func (r *SessionRunner) runStepGroup(steps []IStep) (stepErr error) {
+	for _, step := range steps {
+		if err := r.interrupted(); err != nil {
+			return err
+		}
+		log.Info().Str("step", step.Name()).
+			Str("type", string(step.Type())).Msg("run step start")
+		stepResult, err := r.runStep(step)
+		if stepResult != nil {
+			r.updateSessionVariables(stepResult.ExportVars)
+			r.updateSummary(stepResult)
+			r.hrpRunner.reporter.printStepResult(stepResult, err)
+		}
+		if err != nil {
+			if r.hrpRunner.failfast || r.interrupted() != nil {
+				return err
+			}
+			log.Warn().Err(err).Str("step", step.Name()).Msg("run step failed, continue next step")
+			stepErr = err
+		}
+	}
+	return stepErr
+}
 
-	log.Info().Str("testcase", config.Name).Msg("run testcase end")
+// interrupted returns error if run is canceled or deadline of testcase is exceeded.
+func (r *SessionRunner) interrupted() error {
+	if err := canceled(r.runCtx); err != nil {
+		return err
+	}
+	if r.ctx.Err() != nil {
+		return newStepError(FailureTimeout, errors.New("testcase deadline exceeded"))
+	}
 	return nil
 }
 
+// remainingMilliseconds returns remaining time budget of testcase deadline in milliseconds,
+// false is returned if testcase has no deadline.
+func (r *SessionRunner) remainingMilliseconds() (int64, bool) {
+	deadline, ok := r.ctx.Deadline()
+	if !ok {
+		return 0, false
+	}
+	remaining := time.Until(deadline).Milliseconds()
+	if remaining < 0 {
+		remaining = 0
+	}
+	return remaining, true
+}
+
+// sleep pauses current step for duration, error is returned if run is canceled
+// or deadline of testcase is exceeded while sleeping.
+func (r *SessionRunner) sleep(d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-r.ctx.Done():
+		return r.interrupted()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// runStep runs step and records failure code of step error in step result,
+// step fails if its exported variables override readonly config variables.
+func (r *SessionRunner) runStep(step IStep) (stepResult *StepResult, err error) {
+	r.hrpRunner.onStepStart(r.testCase, step)
+	defer r.hrpRunner.debugStepStarted(r.testCase, step)()
+	defer func() {
+		r.hrpRunner.onStepEnd(r.testCase, stepResult, err)
+	}()
+
+	stepResult, err = step.Run(r)
+	if err == nil && stepResult != nil {
+		if err = r.checkReadonlyVariables(stepResult.ExportVars, VariableScopeExtracted); err != nil {
+			stepResult.Success = false
+			stepResult.ExportVars = nil
+			stepResult.Attachment = err.Error()
+			err = newStepError(FailureExtraction, err)
+		}
+	}
+	setFailureCode(stepResult, err)
+	return stepResult, err
+}
+
+// updateSessionVariables merges exported variables into session variables
+func (r *SessionRunner) updateSessionVariables(exportVars map[string]interface{}) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for k, v := range exportVars {
+		r.sessionVariables[k] = v
+	}
+}
+
 // updateSummary appends step result to summary
 func (r *SessionRunner) updateSummary(stepResult *StepResult) {
-	r.summary.Records = append(r.summary.Records, stepResult)
-	r.summary.Stat.Total += 1
-	if stepResult.Success {
-		r.summary.Stat.Successes += 1
-	} else {
-		r.summary.Stat.Failures += 1
-		// update summary result to failed
-		r.summary.Success = false
-	}
+	r.summary.addRecord(stepResult)
 }
 
-// MergeStepVariables merges step variables with config variables and session variables
+// MergeStepVariables merges step variables with variables of other scopes,
+// precedence from high to low: cli > step > extracted > testcase > global.
+// Error is returned if step variables override readonly config variables.
 func (r *SessionRunner) MergeStepVariables(vars map[string]interface{}) (map[string]interface{}, error) {
+	if err := r.checkReadonlyVariables(vars, VariableScopeStep); err != nil {
+		return nil, err
+	}
+	// copy session variables to avoid data race when steps run concurrently
+	r.mutex.RLock()
+	sessionVariables := make(map[string]interface{}, len(r.sessionVariables))
+	for k, v := range r.sessionVariables {
+		sessionVariables[k] = v
+	}
+	r.mutex.RUnlock()
+
 	// override variables
 	// step variables > session variables (extracted variables from previous steps)
-	overrideVars := mergeVariables(vars, r.sessionVariables)
-	// step variables > testcase config variables
+	overrideVars := mergeVariables(vars, sessionVariables)
+	// step variables > testcase config variables > global variables
 	overrideVars = mergeVariables(overrideVars, r.testCase.Config.Variables)
+	overrideVars = mergeVariables(overrideVars, r.lazyVariables)
+	overrideVars = mergeVariables(overrideVars, r.globalVariables)
+	// cli variables override all
+	overrideVars = mergeVariables(r.cliVariables, overrideVars)
 
 	// parse step variables
 	parsedVariables, err := r.parser.ParseVariables(overrideVars)
@@ -133,28 +356,32 @@ func (r *SessionRunner) MergeStepVariables(vars map[string]interface{}) (map[str
 }
 
 func (r *SessionRunner) parseConfig(cfg *TConfig) error {
-	// parse config variables
-	parsedVariables, err := r.parser.ParseVariables(cfg.Variables)
+	// parse config variables with global and cli variables
+	parsedVariables, err := r.parseScopedVariables(cfg)
 	if err != nil {
 		log.Error().Interface("variables", cfg.Variables).Err(err).Msg("parse config variables failed")
 		return err
 	}
-	cfg.Variables = parsedVariables
 
 	// parse config name
-	parsedName, err := r.parser.ParseString(cfg.Name, cfg.Variables)
+	parsedName, err := r.parser.ParseString(cfg.Name, parsedVariables)
 	if err != nil {
 		return err
 	}
 	cfg.Name = convertString(parsedName)
 
 	// parse config base url
-	parsedBaseURL, err := r.parser.ParseString(cfg.BaseURL, cfg.Variables)
+	parsedBaseURL, err := r.parser.ParseString(cfg.BaseURL, parsedVariables)
 	if err != nil {
 		return err
 	}
 	cfg.BaseURL = convertString(parsedBaseURL)
 
+	// parse jwt keys
+	if r.jwtVerifier, err = newJWTVerifier(cfg.JWT, r.parser, parsedVariables); err != nil {
+		return err
+	}
+
 	// ensure correction of think time config
 	cfg.ThinkTimeSetting.checkThinkTime()
 
@@ -162,14 +389,64 @@ func (r *SessionRunner) parseConfig(cfg *TConfig) error {
 }
 
 func (r *SessionRunner) GetSummary() *TestCaseSummary {
-	caseSummary := r.summary
-	caseSummary.Time.StartAt = r.startTime
-	caseSummary.Time.Duration = time.Since(r.startTime).Seconds()
 	exportVars := make(map[string]interface{})
+	r.mutex.RLock()
 	for _, value := range r.testCase.Config.Export {
 		exportVars[value] = r.sessionVariables[value]
 	}
+	r.mutex.RUnlock()
+
+	caseSummary := r.summary
+	caseSummary.mutex.Lock()
+	defer caseSummary.mutex.Unlock()
+	caseSummary.Time.StartAt = r.startTime
+	caseSummary.Time.Duration = time.Since(r.startTime).Seconds()
 	caseSummary.InOut.ExportVars = exportVars
 	caseSummary.InOut.ConfigVars = r.testCase.Config.Variables
 	return caseSummary
 }
+
+// LastResponse returns typed response of the latest request step, nil is returned if no request has been sent.
+// It is used by Go embedders to write custom assertions after steps run.
+func (r *SessionRunner) LastResponse() *Response {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.response
+}
+
+// client returns client connected to broker or database, which is shared by steps in the same session.
+// connect is called without holding session lock, thus concurrent steps are not blocked by dialing,
+// client connected by the loser of concurrent steps with the same key is closed.
+func (r *SessionRunner) client(key string, connect func() (io.Closer, error)) (io.Closer, error) {
+	r.mutex.RLock()
+	client, ok := r.clients[key]
+	r.mutex.RUnlock()
+	if ok {
+		return client, nil
+	}
+
+	client, err := connect()
+	if err != nil {
+		return nil, err
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if existing, ok := r.clients[key]; ok {
+		client.Close()
+		return existing, nil
+	}
+	r.clients[key] = client
+	return client, nil
+}
+
+// closeClients disconnects all broker and database clients of session.
+func (r *SessionRunner) closeClients() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for key, client := range r.clients {
+		if err := client.Close(); err != nil {
+			log.Warn().Err(err).Str("client", key).Msg("close client failed")
+		}
+		delete(r.clients, key)
+	}
+}
@@ -0,0 +1,231 @@
+package hrp
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/httprunner/httprunner/hrp/internal/builtin"
+)
+
+const sessionStateFileSuffix = ".session.json"
+
+// SessionState represents state of failed testcase run, which is saved to .session.json file
+// and restored with --resume to continue from the failed step.
+type SessionState struct {
+	TestCase       string                 `json:"testcase"`
+	Variables      map[string]interface{} `json:"variables"`
+	Cookies        []*SessionCookie       `json:"cookies,omitempty"`
+	CompletedSteps []int                  `json:"completed_steps"` // index of completed steps, starting from 0
+}
+
+// SessionCookie represents cookie saved in session state with its attributes,
+// which is restored by setting it again for the url it was set by.
+type SessionCookie struct {
+	URL      string        `json:"url"` // url of response setting cookie, without query
+	Name     string        `json:"name"`
+	Value    string        `json:"value"`
+	Domain   string        `json:"domain,omitempty"`
+	Path     string        `json:"path,omitempty"`
+	Expires  *time.Time    `json:"expires,omitempty"` // session cookie if not set
+	Secure   bool          `json:"secure,omitempty"`
+	HttpOnly bool          `json:"http_only,omitempty"`
+	SameSite http.SameSite `json:"same_site,omitempty"`
+}
+
+func newSessionCookie(u *url.URL, cookie *http.Cookie) *SessionCookie {
+	c := &SessionCookie{
+		URL:      (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}).String(),
+		Name:     cookie.Name,
+		Value:    cookie.Value,
+		Domain:   cookie.Domain,
+		Path:     cookie.Path,
+		Secure:   cookie.Secure,
+		HttpOnly: cookie.HttpOnly,
+		SameSite: cookie.SameSite,
+	}
+	// max age takes precedence over expires, which is converted to expires thus it is still valid after restored
+	if cookie.MaxAge > 0 {
+		expires := time.Now().Add(time.Duration(cookie.MaxAge) * time.Second)
+		c.Expires = &expires
+	} else if !cookie.Expires.IsZero() {
+		expires := cookie.Expires
+		c.Expires = &expires
+	}
+	return c
+}
+
+func (c *SessionCookie) toHTTPCookie() *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     c.Name,
+		Value:    c.Value,
+		Domain:   c.Domain,
+		Path:     c.Path,
+		Secure:   c.Secure,
+		HttpOnly: c.HttpOnly,
+		SameSite: c.SameSite,
+	}
+	if c.Expires != nil {
+		cookie.Expires = *c.Expires
+	}
+	return cookie
+}
+
+// sessionCookieJar records cookies with attributes set by responses, which are not returned by cookie jar.
+type sessionCookieJar struct {
+	http.CookieJar
+	mutex   sync.Mutex
+	keys    []string                  // keys of cookies in order of being set
+	cookies map[string]*SessionCookie // nil if cookie is deleted
+}
+
+func (j *sessionCookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.CookieJar.SetCookies(u, cookies)
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	for _, cookie := range cookies {
+		key := strings.Join([]string{u.Host, cookie.Domain, cookie.Path, cookie.Name}, "\x00")
+		if _, ok := j.cookies[key]; !ok {
+			j.keys = append(j.keys, key)
+		}
+		if cookie.MaxAge < 0 {
+			// cookie is deleted
+			j.cookies[key] = nil
+			continue
+		}
+		j.cookies[key] = newSessionCookie(u, cookie)
+	}
+}
+
+// sessionCookies returns recorded cookies set by urls of visited scheme and host.
+func (j *sessionCookieJar) sessionCookies(visitedURLs map[string]bool) []*SessionCookie {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	var cookies []*SessionCookie
+	for _, key := range j.keys {
+		cookie := j.cookies[key]
+		if cookie == nil {
+			continue
+		}
+		u, err := url.Parse(cookie.URL)
+		if err != nil || !visitedURLs[u.Scheme+"://"+u.Host] {
+			continue
+		}
+		cookies = append(cookies, cookie)
+	}
+	return cookies
+}
+
+// enableCookieJar sets cookie jar for http client, thus cookies can be saved in session state.
+func (r *HRPRunner) enableCookieJar() {
+	if _, ok := r.client.Jar.(*sessionCookieJar); ok {
+		return
+	}
+	jar := r.client.Jar
+	if jar == nil {
+		jar, _ = cookiejar.New(nil)
+	}
+	r.client.Jar = &sessionCookieJar{
+		CookieJar: jar,
+		cookies:   make(map[string]*SessionCookie),
+	}
+}
+
+// sessionStatePath returns session state file path, which is located beside testcase file,
+// e.g. testcases/demo.json => testcases/demo.session.json
+func (r *SessionRunner) sessionStatePath() string {
+	if casePath := r.testCase.Config.Path; casePath != "" && casePath != stdinTestCasePath {
+		return strings.TrimSuffix(casePath, filepath.Ext(casePath)) + sessionStateFileSuffix
+	}
+	fileName := regexSnapshotFileName.ReplaceAllString(strings.TrimSpace(r.testCase.Config.Name), "_")
+	return fileName + sessionStateFileSuffix
+}
+
+// recordVisitedURL records scheme and host of request url, cookies of visited urls are saved in session state.
+func (r *SessionRunner) recordVisitedURL(u *url.URL) {
+	if u == nil || u.Host == "" {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.visitedURLs[u.Scheme+"://"+u.Host] = true
+}
+
+// saveSessionState saves session state to file if testcase failed,
+// and removes stale session state file if testcase succeeded.
+func (r *SessionRunner) saveSessionState(success bool) {
+	path := r.sessionStatePath()
+	if success {
+		if builtin.IsFilePathExists(path) {
+			if err := os.Remove(path); err != nil {
+				log.Warn().Err(err).Str("path", path).Msg("remove session state file failed")
+			}
+		}
+		return
+	}
+
+	r.mutex.RLock()
+	state := &SessionState{
+		TestCase:  r.testCase.Config.Name,
+		Variables: make(map[string]interface{}, len(r.sessionVariables)),
+	}
+	for k, v := range r.sessionVariables {
+		state.Variables[k] = v
+	}
+	if jar, ok := r.hrpRunner.client.Jar.(*sessionCookieJar); ok {
+		state.Cookies = jar.sessionCookies(r.visitedURLs)
+	}
+	r.mutex.RUnlock()
+
+	for index, completed := range r.completedSteps {
+		if completed {
+			state.CompletedSteps = append(state.CompletedSteps, index)
+		}
+	}
+	sort.Ints(state.CompletedSteps)
+
+	if err := builtin.Dump2JSON(state, path); err != nil {
+		log.Error().Err(err).Str("path", path).Msg("save session state failed")
+		return
+	}
+	log.Info().Str("path", path).Msg("session state saved, rerun with --resume to continue from failed step")
+}
+
+// restoreSessionState restores session variables, cookies and completed steps from session state file.
+func (r *SessionRunner) restoreSessionState() error {
+	path := r.sessionStatePath()
+	if !builtin.IsFilePathExists(path) {
+		log.Warn().Str("path", path).Msg("session state file not found, run from the beginning")
+		return nil
+	}
+	state := &SessionState{}
+	if err := builtin.LoadFile(path, state); err != nil {
+		return errors.Wrap(err, "load session state failed")
+	}
+
+	r.updateSessionVariables(state.Variables)
+	if jar := r.hrpRunner.client.Jar; jar != nil {
+		for _, cookie := range state.Cookies {
+			u, err := url.Parse(cookie.URL)
+			if err != nil {
+				return errors.Wrap(err, "parse session state cookie url failed")
+			}
+			jar.SetCookies(u, []*http.Cookie{cookie.toHTTPCookie()})
+			r.visitedURLs[u.Scheme+"://"+u.Host] = true
+		}
+	}
+	for _, index := range state.CompletedSteps {
+		r.completedSteps[index] = true
+	}
+	log.Info().Str("path", path).Ints("completedSteps", state.CompletedSteps).Msg("session state restored")
+	return nil
+}
@@ -0,0 +1,138 @@
+package hrp
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/httprunner/httprunner/hrp/internal/builtin"
+)
+
+func TestRunCaseWithResume(t *testing.T) {
+	setupCount := 0
+	checkFailed := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/setup":
+			setupCount++
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc"})
+			// path scoped cookie is only sent to /check
+			http.SetCookie(w, &http.Cookie{Name: "scoped", Value: "s1", Path: "/check", MaxAge: 3600, HttpOnly: true})
+			fmt.Fprint(w, `{"token": "t123"}`)
+		case "/check":
+			cookie, err := r.Cookie("session")
+			scoped, scopedErr := r.Cookie("scoped")
+			if !checkFailed || err != nil || scopedErr != nil {
+				// fail at the first time
+				checkFailed = true
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			fmt.Fprintf(w, `{"cookie": "%s", "scoped": "%s", "token": "%s"}`,
+				cookie.Value, scoped.Value, r.URL.Query().Get("token"))
+		}
+	}))
+	defer ts.Close()
+
+	casePath := filepath.Join(t.TempDir(), "resume.json")
+	newTestCase := func() *TestCase {
+		config := NewConfig("resume").SetBaseURL(ts.URL)
+		config.Path = casePath
+		return &TestCase{
+			Config: config,
+			TestSteps: []IStep{
+				NewStep("expensive setup").
+					GET("/setup").
+					Extract().
+					WithJmesPath("body.token", "token"),
+				NewStep("check").
+					GET("/check").
+					WithParams(map[string]interface{}{"token": "$token"}).
+					Validate().
+					AssertEqual("status_code", 200, "check status code").
+					AssertEqual("body.cookie", "abc", "check cookie").
+					AssertEqual("body.scoped", "s1", "check path scoped cookie").
+					AssertEqual("body.token", "t123", "check token"),
+			},
+		}
+	}
+	statePath := filepath.Join(filepath.Dir(casePath), "resume.session.json")
+
+	// first run fails at check step, session state is saved
+	err := NewRunner(nil).SetSaveSession(true).Run(newTestCase())
+	if !assert.NotNil(t, err) {
+		t.Fatal()
+	}
+	state := &SessionState{}
+	if !assert.Nil(t, builtin.LoadFile(statePath, state)) {
+		t.Fatal()
+	}
+	assert.Equal(t, []int{0}, state.CompletedSteps)
+	assert.Equal(t, "t123", state.Variables["token"])
+	if assert.Len(t, state.Cookies, 2) {
+		assert.Equal(t, ts.URL+"/setup", state.Cookies[0].URL)
+		assert.Equal(t, "session", state.Cookies[0].Name)
+		assert.Equal(t, "abc", state.Cookies[0].Value)
+		assert.Nil(t, state.Cookies[0].Expires)
+		scoped := state.Cookies[1]
+		assert.Equal(t, "scoped", scoped.Name)
+		assert.Equal(t, "/check", scoped.Path)
+		assert.True(t, scoped.HttpOnly)
+		assert.NotNil(t, scoped.Expires)
+	}
+
+	// resume with a new runner, setup step is skipped
+	if !assert.Nil(t, NewRunner(t).SetResume(true).Run(newTestCase())) {
+		t.Fatal()
+	}
+	assert.Equal(t, 1, setupCount)
+	assert.False(t, builtin.IsFilePathExists(statePath))
+}
+
+func TestRunCaseWithResumeReplaySteps(t *testing.T) {
+	counts := make(map[string]int)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		counts[r.URL.Path]++
+		if r.URL.Path == "/third" && counts[r.URL.Path] == 1 {
+			// fail at the first time
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer ts.Close()
+
+	casePath := filepath.Join(t.TempDir(), "resume.json")
+	newTestCase := func() *TestCase {
+		config := NewConfig("resume replay").SetBaseURL(ts.URL)
+		config.Path = casePath
+		var steps []IStep
+		for _, name := range []string{"first", "second", "third"} {
+			steps = append(steps, NewStep(name).GET("/"+name).
+				Validate().
+				AssertEqual("status_code", 200, "check status code"))
+		}
+		return &TestCase{Config: config, TestSteps: steps}
+	}
+	statePath := filepath.Join(filepath.Dir(casePath), "resume.session.json")
+
+	// replay the last two steps, completed steps are recorded by index in testcase
+	err := NewRunner(nil).SetReplaySteps("second", "third").SetSaveSession(true).Run(newTestCase())
+	if !assert.NotNil(t, err) {
+		t.Fatal()
+	}
+	state := &SessionState{}
+	if !assert.Nil(t, builtin.LoadFile(statePath, state)) {
+		t.Fatal()
+	}
+	assert.Equal(t, []int{1}, state.CompletedSteps)
+
+	// resume all steps, only the second step is skipped
+	if !assert.Nil(t, NewRunner(t).SetResume(true).Run(newTestCase())) {
+		t.Fatal()
+	}
+	assert.Equal(t, map[string]int{"/first": 1, "/second": 1, "/third": 2}, counts)
+}
@@ -0,0 +1,235 @@
+package hrp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jmespath/go-jmespath"
+	"github.com/pkg/errors"
+
+	"github.com/httprunner/httprunner/hrp/internal/json"
+)
+
+const shellHelp = `commands:
+  n, next            run next step
+  s, skip            skip next step
+  c, continue        run all remaining steps
+  l, list            list steps, > marks next step
+  resp               print response of last request step (hrp_step_response)
+  j, jmespath EXPR   evaluate jmespath expression against last response, e.g. j body.data
+  vars               print session variables
+  set NAME=VALUE     set session variable before next step, value can be expression, e.g. set id=${max(1,2)}
+  h, help            print this help
+  q, quit            exit shell`
+
+// Shell is an interactive debugger, which loads a testcase and runs its steps one at a time,
+// response of last step can be inspected and session variables can be edited before next step.
+type Shell struct {
+	sessionRunner *SessionRunner
+	steps         []IStep
+	cursor        int // index of next step to run
+	in            *bufio.Scanner
+	out           io.Writer
+}
+
+// NewShell loads testcase and prepares session runner for interactive debugging,
+// commands are read from in and results are written to out.
+func (r *HRPRunner) NewShell(testcase ITestCase, in io.Reader, out io.Writer) (*Shell, error) {
+	testCases, err := loadTestCases(testcase)
+	if err != nil {
+		return nil, err
+	}
+	if len(testCases) != 1 {
+		return nil, fmt.Errorf("shell mode requires exactly one testcase, got %d", len(testCases))
+	}
+	tc := testCases[0]
+
+	// use the first group of parameters
+	cfg := tc.Config
+	if err := initParameterIterator(cfg, "runner"); err != nil {
+		return nil, errors.Wrap(err, "parse config parameters failed")
+	}
+	for _, it := range cfg.ParametersSetting.Iterators {
+		if it.HasNext() {
+			cfg.Variables = mergeVariables(it.Next(), cfg.Variables)
+		}
+	}
+
+	sessionRunner := r.NewSessionRunner(tc)
+	if sessionRunner.parser.plugin, err = initPlugin(cfg.Path, r.pluginLogOn); err != nil {
+		return nil, err
+	}
+	if err := sessionRunner.parseConfig(cfg); err != nil {
+		sessionRunner.quitPlugin()
+		return nil, err
+	}
+	return &Shell{
+		sessionRunner: sessionRunner,
+		steps:         tc.TestSteps,
+		in:            bufio.NewScanner(in),
+		out:           out,
+	}, nil
+}
+
+// Run reads and executes commands until quit or end of input.
+func (s *Shell) Run() error {
+	defer s.sessionRunner.quitPlugin()
+
+	fmt.Fprintf(s.out, "testcase: %s, %d steps, type help for commands\n",
+		s.sessionRunner.testCase.Config.Name, len(s.steps))
+	for {
+		fmt.Fprint(s.out, "hrp> ")
+		if !s.in.Scan() {
+			fmt.Fprintln(s.out)
+			return s.in.Err()
+		}
+		quit, err := s.Execute(s.in.Text())
+		if err != nil {
+			fmt.Fprintf(s.out, "error: %v\n", err)
+		}
+		if quit {
+			return nil
+		}
+	}
+}
+
+// Execute executes one shell command line, quit is true if shell should exit.
+func (s *Shell) Execute(line string) (quit bool, err error) {
+	command, arg := line, ""
+	if i := strings.IndexAny(line, " \t"); i >= 0 {
+		command, arg = line[:i], strings.TrimSpace(line[i+1:])
+	}
+
+	switch strings.TrimSpace(command) {
+	case "":
+		return false, nil
+	case "n", "next":
+		return false, s.next()
+	case "s", "skip":
+		if s.cursor >= len(s.steps) {
+			return false, errors.New("no more steps")
+		}
+		fmt.Fprintf(s.out, "skip step %d: %s\n", s.cursor+1, s.steps[s.cursor].Name())
+		s.cursor++
+	case "c", "continue":
+		for s.cursor < len(s.steps) {
+			if err := s.next(); err != nil {
+				return false, err
+			}
+		}
+	case "l", "list":
+		for i, step := range s.steps {
+			marker := " "
+			if i == s.cursor {
+				marker = ">"
+			}
+			fmt.Fprintf(s.out, "%s %d. %s (%s)\n", marker, i+1, step.Name(), step.Type())
+		}
+	case "resp":
+		resp, err := s.lastResponse()
+		if err != nil {
+			return false, err
+		}
+		return false, s.print(resp)
+	case "j", "jmespath":
+		if arg == "" {
+			return false, errors.New("missing jmespath expression")
+		}
+		resp, err := s.lastResponse()
+		if err != nil {
+			return false, err
+		}
+		value, err := jmespath.Search(arg, resp)
+		if err != nil {
+			return false, errors.Wrap(err, "search jmespath failed")
+		}
+		return false, s.print(value)
+	case "vars":
+		s.sessionRunner.mutex.RLock()
+		defer s.sessionRunner.mutex.RUnlock()
+		return false, s.print(s.sessionRunner.sessionVariables)
+	case "set":
+		return false, s.setVariable(arg)
+	case "h", "help":
+		fmt.Fprintln(s.out, shellHelp)
+	case "q", "quit", "exit":
+		return true, nil
+	default:
+		return false, fmt.Errorf("unknown command %s, type help for commands", command)
+	}
+	return false, nil
+}
+
+// next runs the next step and updates session variables and summary.
+func (s *Shell) next() error {
+	if s.cursor >= len(s.steps) {
+		return errors.New("no more steps")
+	}
+	step := s.steps[s.cursor]
+	s.cursor++
+	fmt.Fprintf(s.out, "run step %d: %s\n", s.cursor, step.Name())
+
+	r := s.sessionRunner
+	stepResult, err := r.runStep(step)
+	if stepResult != nil {
+		r.updateSessionVariables(stepResult.ExportVars)
+		r.updateSummary(stepResult)
+		fmt.Fprintf(s.out, "success: %v, elapsed: %dms\n", stepResult.Success, stepResult.Elapsed)
+		if len(stepResult.ExportVars) > 0 {
+			fmt.Fprint(s.out, "export vars: ")
+			if err := s.print(stepResult.ExportVars); err != nil {
+				return err
+			}
+		}
+	}
+	if err != nil {
+		return errors.Wrap(err, "run step failed")
+	}
+	if s.cursor == len(s.steps) {
+		fmt.Fprintln(s.out, "all steps finished")
+	}
+	return nil
+}
+
+func (s *Shell) lastResponse() (interface{}, error) {
+	s.sessionRunner.mutex.RLock()
+	defer s.sessionRunner.mutex.RUnlock()
+	if s.sessionRunner.lastResponse == nil {
+		return nil, errors.New("no response yet, run a request step first")
+	}
+	return s.sessionRunner.lastResponse, nil
+}
+
+// setVariable sets session variable with NAME=VALUE, value is parsed with current session variables.
+func (s *Shell) setVariable(arg string) error {
+	items := strings.SplitN(arg, "=", 2)
+	name := strings.TrimSpace(items[0])
+	if len(items) != 2 || name == "" {
+		return errors.New("invalid variable, expect NAME=VALUE")
+	}
+	r := s.sessionRunner
+	variables, err := r.MergeStepVariables(nil)
+	if err != nil {
+		return err
+	}
+	value, err := r.parser.Parse(strings.TrimSpace(items[1]), variables)
+	if err != nil {
+		return errors.Wrap(err, "parse variable value failed")
+	}
+	// convert literal numbers and booleans
+	value = convertLiteral(value)
+	r.updateSessionVariables(map[string]interface{}{name: value})
+	fmt.Fprintf(s.out, "%s = %v\n", name, value)
+	return nil
+}
+
+func (s *Shell) print(value interface{}) error {
+	content, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(s.out, string(content))
+	return nil
+}
@@ -0,0 +1,73 @@
+package hrp
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShell(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"path": "%s", "user": "%s"}`, r.URL.Path, r.URL.Query().Get("user"))
+	}))
+	defer ts.Close()
+
+	testcase := &TestCase{
+		Config: NewConfig("shell").SetBaseURL(ts.URL),
+		TestSteps: []IStep{
+			NewStep("login").
+				GET("/login").
+				Extract().
+				WithJmesPath("body.path", "login_path"),
+			NewStep("skipped").
+				GET("/skipped"),
+			NewStep("profile").
+				GET("/profile").
+				WithParams(map[string]interface{}{"user": "$user"}),
+		},
+	}
+
+	commands := []string{
+		"resp",
+		"next",
+		"j body.path",
+		"list",
+		"skip",
+		"set user=${max(1,2)}",
+		"vars",
+		"n",
+		"j body.user",
+		"unknown",
+		"quit",
+		"next",
+	}
+	out := &bytes.Buffer{}
+	shell, err := NewRunner(t).NewShell(testcase, strings.NewReader(strings.Join(commands, "\n")), out)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	if !assert.Nil(t, shell.Run()) {
+		t.Fatal()
+	}
+
+	output := out.String()
+	assert.Contains(t, output, "error: no response yet, run a request step first")
+	assert.Contains(t, output, "run step 1: login")
+	assert.Contains(t, output, "\"/login\"")
+	assert.Contains(t, output, "> 2. skipped (request-GET)")
+	assert.Contains(t, output, "skip step 2: skipped")
+	assert.Contains(t, output, "user = 2")
+	assert.Contains(t, output, `"login_path": "/login"`)
+	assert.Contains(t, output, "run step 3: profile")
+	assert.Contains(t, output, "all steps finished")
+	assert.Contains(t, output, "\"2\"")
+	assert.Contains(t, output, "error: unknown command unknown")
+	assert.NotContains(t, output, "no more steps")
+	assert.Len(t, shell.sessionRunner.summary.Records, 2)
+}
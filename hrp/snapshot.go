@@ -0,0 +1,118 @@
+package hrp
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/httprunner/httprunner/hrp/internal/builtin"
+	"github.com/httprunner/httprunner/hrp/internal/json"
+)
+
+const defaultSnapshotDir = "snapshots"
+
+// Snapshot represents golden file snapshot setting for request step.
+// The first run writes normalized response to golden file,
+// subsequent runs compare response with the golden file.
+type Snapshot struct {
+	Name   string   `json:"name,omitempty" yaml:"name,omitempty"`     // golden file name, default to step name
+	Ignore []string `json:"ignore,omitempty" yaml:"ignore,omitempty"` // jmespath of fields to ignore, e.g. body.data.timestamp
+}
+
+var regexSnapshotFileName = regexp.MustCompile(`[^\w\-.]+`)
+
+// snapshotPath returns golden file path for the specified snapshot name.
+// golden files are located in snapshots folder beside testcase file by default.
+func (r *SessionRunner) snapshotPath(name string) string {
+	dir := r.hrpRunner.snapshotDir
+	if dir == "" {
+		dir = defaultSnapshotDir
+		if casePath := r.testCase.Config.Path; casePath != "" {
+			dir = filepath.Join(filepath.Dir(casePath), defaultSnapshotDir)
+		}
+	}
+	fileName := regexSnapshotFileName.ReplaceAllString(strings.TrimSpace(name), "_")
+	return filepath.Join(dir, fileName+".json")
+}
+
+// normalizeSnapshot converts response to snapshot content stored in golden file,
+// only status code and body are kept and ignored fields are removed.
+func normalizeSnapshot(respObjMeta interface{}, ignore []string) (map[string]interface{}, error) {
+	// deep copy response to avoid modifying original response
+	respBytes, err := json.Marshal(respObjMeta)
+	if err != nil {
+		return nil, err
+	}
+	var data map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(respBytes))
+	decoder.UseNumber()
+	if err := decoder.Decode(&data); err != nil {
+		return nil, err
+	}
+	content := map[string]interface{}{
+		"status_code": data["status_code"],
+		"body":        data["body"],
+	}
+	if err := builtin.RemoveJSONPaths(content, ignore...); err != nil {
+		return nil, errors.Wrap(err, "remove snapshot ignore paths failed")
+	}
+	return content, nil
+}
+
+// matchSnapshot compares response with golden file, golden file will be created
+// if not exists or update is set.
+func (v *responseObject) matchSnapshot(snapshot *Snapshot, path string, update bool) error {
+	actual, err := normalizeSnapshot(v.respObjMeta, snapshot.Ignore)
+	if err != nil {
+		return err
+	}
+
+	if update || !builtin.IsFilePathExists(path) {
+		if err := builtin.EnsureFolderExists(filepath.Dir(path)); err != nil {
+			return err
+		}
+		log.Info().Str("path", path).Msg("write snapshot golden file")
+		return builtin.Dump2JSON(actual, path)
+	}
+
+	var expected map[string]interface{}
+	if err := builtin.LoadFile(path, &expected); err != nil {
+		return errors.Wrap(err, "load snapshot golden file failed")
+	}
+	// ignored fields may be recorded in golden file before ignore rules changed
+	expectedSnapshot, err := normalizeSnapshot(expected, snapshot.Ignore)
+	if err != nil {
+		return err
+	}
+
+	diffs := builtin.DiffJSON(expectedSnapshot, actual)
+	validResult := &ValidationResult{
+		Validator: Validator{
+			Check:  "snapshot",
+			Assert: "snapshot_match",
+			Expect: path,
+		},
+		CheckResult: "pass",
+	}
+	v.validationResults = append(v.validationResults, validResult)
+	if len(diffs) == 0 {
+		log.Info().Str("path", path).Msg("snapshot matched")
+		return nil
+	}
+
+	var diffLines []string
+	for _, diff := range diffs {
+		diffLines = append(diffLines, diff.String())
+	}
+	validResult.Diffs = diffs
+	validResult.Diff = renderDiffs(diffs)
+	validResult.CheckResult = "fail"
+	v.t.Fail()
+	log.Error().Str("path", path).Strs("diffs", diffLines).Msg("snapshot mismatched")
+	return fmt.Errorf("response mismatched with snapshot %s:\n%s", path, strings.Join(diffLines, "\n"))
+}
@@ -0,0 +1,65 @@
+package hrp
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunRequestWithSnapshot(t *testing.T) {
+	var name string
+	var counter int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		counter++
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"name": "%s", "request_id": %d}`, name, counter)
+	}))
+	defer ts.Close()
+
+	snapshotDir := t.TempDir()
+	newTestCase := func() *TestCase {
+		return &TestCase{
+			Config: NewConfig("snapshot").SetBaseURL(ts.URL),
+			TestSteps: []IStep{
+				NewStep("get user").
+					GET("/user").
+					Validate().
+					AssertEqual("status_code", 200, "check status code").
+					AssertSnapshot("", "body.request_id"),
+			},
+		}
+	}
+	runner := NewRunner(nil).SetSnapshotDir(snapshotDir)
+
+	// first run writes golden file
+	name = "debugtalk"
+	if !assert.Nil(t, runner.Run(newTestCase())) {
+		t.Fatal()
+	}
+	assert.FileExists(t, filepath.Join(snapshotDir, "get_user.json"))
+
+	// request_id changed but ignored
+	if !assert.Nil(t, runner.Run(newTestCase())) {
+		t.Fatal()
+	}
+
+	// contract drifts
+	name = "httprunner"
+	err := runner.Run(newTestCase())
+	if !assert.NotNil(t, err) {
+		t.Fatal()
+	}
+	assert.Contains(t, err.Error(), `body.name: expected "debugtalk", got "httprunner"`)
+
+	// update golden file
+	if !assert.Nil(t, runner.SetUpdateSnapshots(true).Run(newTestCase())) {
+		t.Fatal()
+	}
+	if !assert.Nil(t, runner.SetUpdateSnapshots(false).Run(newTestCase())) {
+		t.Fatal()
+	}
+}
@@ -9,41 +9,73 @@ const (
 	stepTypeTransaction StepType = "transaction"
 	stepTypeRendezvous  StepType = "rendezvous"
 	stepTypeThinkTime   StepType = "thinktime"
+	stepTypeFuzz        StepType = "fuzz"
+	stepTypeLoop        StepType = "loop"
+	stepTypeBranch      StepType = "branch"
+	stepTypeMQTT        StepType = "mqtt"
+	stepTypeKafka       StepType = "kafka"
+	stepTypeDB          StepType = "db"
+	stepTypeRedis       StepType = "redis"
+	stepTypeShell       StepType = "shell"
+	stepTypeFile        StepType = "file"
+	stepTypeEmail       StepType = "email"
 )
 
 type StepResult struct {
-	Name        string                 `json:"name" yaml:"name"`                                   // step name
-	StepType    StepType               `json:"step_type" yaml:"step_type"`                         // step type, testcase/request/transaction/rendezvous
-	Success     bool                   `json:"success" yaml:"success"`                             // step execution result
-	Elapsed     int64                  `json:"elapsed_ms" yaml:"elapsed_ms"`                       // step execution time in millisecond(ms)
-	Data        interface{}            `json:"data,omitempty" yaml:"data,omitempty"`               // session data or slice of step data
-	ContentSize int64                  `json:"content_size" yaml:"content_size"`                   // response body length
-	ExportVars  map[string]interface{} `json:"export_vars,omitempty" yaml:"export_vars,omitempty"` // extract variables
-	Attachment  string                 `json:"attachment,omitempty" yaml:"attachment,omitempty"`   // step error information
+	Name          string                 `json:"name" yaml:"name"`                                         // step name
+	StepType      StepType               `json:"step_type" yaml:"step_type"`                               // step type, testcase/request/transaction/rendezvous/fuzz
+	Success       bool                   `json:"success" yaml:"success"`                                   // step execution result
+	Elapsed       int64                  `json:"elapsed_ms" yaml:"elapsed_ms"`                             // step execution time in millisecond(ms)
+	Data          interface{}            `json:"data,omitempty" yaml:"data,omitempty"`                     // session data or slice of step data
+	ContentSize   int64                  `json:"content_size" yaml:"content_size"`                         // response body length
+	ExportVars    map[string]interface{} `json:"export_vars,omitempty" yaml:"export_vars,omitempty"`       // extract variables
+	Attachment    string                 `json:"attachment,omitempty" yaml:"attachment,omitempty"`         // step error information
+	CorrelationID string                 `json:"correlation_id,omitempty" yaml:"correlation_id,omitempty"` // correlation id attached to request
+	FailureCode   FailureCode            `json:"failure_code,omitempty" yaml:"failure_code,omitempty"`     // failure category, e.g. transport_error, timeout
+	Response      *Response              `json:"-" yaml:"-"`                                               // typed response of request step for custom assertions in Go
 }
 
 // TStep represents teststep data structure.
 // Each step maybe three different types: make one request or reference another api/testcase.
 type TStep struct {
-	Name          string                 `json:"name" yaml:"name"` // required
-	Request       *Request               `json:"request,omitempty" yaml:"request,omitempty"`
-	API           interface{}            `json:"api,omitempty" yaml:"api,omitempty"`           // *APIPath or *API
-	TestCase      interface{}            `json:"testcase,omitempty" yaml:"testcase,omitempty"` // *TestCasePath or *TestCase
-	Transaction   *Transaction           `json:"transaction,omitempty" yaml:"transaction,omitempty"`
-	Rendezvous    *Rendezvous            `json:"rendezvous,omitempty" yaml:"rendezvous,omitempty"`
-	ThinkTime     *ThinkTime             `json:"think_time,omitempty" yaml:"think_time,omitempty"`
-	Variables     map[string]interface{} `json:"variables,omitempty" yaml:"variables,omitempty"`
-	SetupHooks    []string               `json:"setup_hooks,omitempty" yaml:"setup_hooks,omitempty"`
-	TeardownHooks []string               `json:"teardown_hooks,omitempty" yaml:"teardown_hooks,omitempty"`
-	Extract       map[string]string      `json:"extract,omitempty" yaml:"extract,omitempty"`
-	Validators    []interface{}          `json:"validate,omitempty" yaml:"validate,omitempty"`
-	Export        []string               `json:"export,omitempty" yaml:"export,omitempty"`
+	Name              string                 `json:"name" yaml:"name"` // required
+	Request           *Request               `json:"request,omitempty" yaml:"request,omitempty"`
+	API               interface{}            `json:"api,omitempty" yaml:"api,omitempty"`           // *APIPath or *API
+	TestCase          interface{}            `json:"testcase,omitempty" yaml:"testcase,omitempty"` // *TestCasePath or *TestCase
+	Transaction       *Transaction           `json:"transaction,omitempty" yaml:"transaction,omitempty"`
+	Rendezvous        *Rendezvous            `json:"rendezvous,omitempty" yaml:"rendezvous,omitempty"`
+	ThinkTime         *ThinkTime             `json:"think_time,omitempty" yaml:"think_time,omitempty"`
+	Variables         map[string]interface{} `json:"variables,omitempty" yaml:"variables,omitempty"`
+	SetupHooks        []string               `json:"setup_hooks,omitempty" yaml:"setup_hooks,omitempty"`
+	TeardownHooks     []string               `json:"teardown_hooks,omitempty" yaml:"teardown_hooks,omitempty"`
+	Extract           map[string]string      `json:"extract,omitempty" yaml:"extract,omitempty"`
+	Validators        []interface{}          `json:"validate,omitempty" yaml:"validate,omitempty"`
+	Export            []string               `json:"export,omitempty" yaml:"export,omitempty"`
+	Snapshot          *Snapshot              `json:"snapshot,omitempty" yaml:"snapshot,omitempty"`
+	Fuzz              *Fuzz                  `json:"fuzz,omitempty" yaml:"fuzz,omitempty"`
+	Fault             *Fault                 `json:"fault,omitempty" yaml:"fault,omitempty"`
+	Egress            *Egress                `json:"egress,omitempty" yaml:"egress,omitempty"`
+	RequestTransform  *RequestTransform      `json:"request_transform,omitempty" yaml:"request_transform,omitempty"`   // transform of request body after it is built
+	ResponseTransform *ResponseTransform     `json:"response_transform,omitempty" yaml:"response_transform,omitempty"` // transform of response body before extraction
+	DependsOn         []string               `json:"depends_on,omitempty" yaml:"depends_on,omitempty"`
+	Loop              *Loop                  `json:"loop,omitempty" yaml:"loop,omitempty"`
+	WaitUntil         *WaitUntil             `json:"wait_until,omitempty" yaml:"wait_until,omitempty"`
+	Branch            *Branch                `json:"branch,omitempty" yaml:"branch,omitempty"`
+	APIOverride       *APIOverride           `json:"api_override,omitempty" yaml:"api_override,omitempty"`
+	MQTT              *MQTT                  `json:"mqtt,omitempty" yaml:"mqtt,omitempty"`
+	Kafka             *Kafka                 `json:"kafka,omitempty" yaml:"kafka,omitempty"`
+	DB                *DBQuery               `json:"db,omitempty" yaml:"db,omitempty"`
+	Redis             *RedisCommand          `json:"redis,omitempty" yaml:"redis,omitempty"`
+	Shell             *ShellCommand          `json:"shell,omitempty" yaml:"shell,omitempty"`
+	File              *FileTransfer          `json:"file,omitempty" yaml:"file,omitempty"`
+	Email             *Email                 `json:"email,omitempty" yaml:"email,omitempty"`
+	Include           string                 `json:"include,omitempty" yaml:"include,omitempty"` // fragment file path, steps are inlined when loading
 }
 
 // IStep represents interface for all types for teststeps, includes:
 // StepRequest, StepRequestWithOptionalArgs, StepRequestValidation, StepRequestExtraction,
 // StepTestCaseWithOptionalArgs,
-// StepTransaction, StepRendezvous.
+// StepTransaction, StepRendezvous, StepFuzz, StepLoop, StepBranch, StepMQTT, StepKafka, StepDB, StepRedis, StepShell, StepFile, StepEmail.
 type IStep interface {
 	Name() string
 	Type() StepType
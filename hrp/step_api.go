@@ -51,11 +51,52 @@ func (path *APIPath) ToAPI() (*API, error) {
 	return api, err
 }
 
+// APIOverride represents per-call request overrides for referenced api,
+// which makes a shared api definition reusable with different payload shapes.
+type APIOverride struct {
+	Params  map[string]interface{} `json:"params,omitempty" yaml:"params,omitempty"`
+	Headers map[string]string      `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Cookies map[string]string      `json:"cookies,omitempty" yaml:"cookies,omitempty"`
+	Body    interface{}            `json:"body,omitempty" yaml:"body,omitempty"` // deep merged into api body if both are objects
+}
+
 // StepAPIWithOptionalArgs implements IStep interface.
 type StepAPIWithOptionalArgs struct {
 	step *TStep
 }
 
+func (s *StepAPIWithOptionalArgs) ensureOverride() *APIOverride {
+	if s.step.APIOverride == nil {
+		s.step.APIOverride = &APIOverride{}
+	}
+	return s.step.APIOverride
+}
+
+// WithParams overrides HTTP request params of referenced api for current step.
+func (s *StepAPIWithOptionalArgs) WithParams(params map[string]interface{}) *StepAPIWithOptionalArgs {
+	s.ensureOverride().Params = params
+	return s
+}
+
+// WithHeaders overrides HTTP request headers of referenced api for current step.
+func (s *StepAPIWithOptionalArgs) WithHeaders(headers map[string]string) *StepAPIWithOptionalArgs {
+	s.ensureOverride().Headers = headers
+	return s
+}
+
+// WithCookies overrides HTTP request cookies of referenced api for current step.
+func (s *StepAPIWithOptionalArgs) WithCookies(cookies map[string]string) *StepAPIWithOptionalArgs {
+	s.ensureOverride().Cookies = cookies
+	return s
+}
+
+// WithBody overrides HTTP request body of referenced api for current step,
+// body fragments are deep merged into api body if both are objects.
+func (s *StepAPIWithOptionalArgs) WithBody(body interface{}) *StepAPIWithOptionalArgs {
+	s.ensureOverride().Body = body
+	return s
+}
+
 // TeardownHook adds a teardown hook for current teststep.
 func (s *StepAPIWithOptionalArgs) TeardownHook(hook string) *StepAPIWithOptionalArgs {
 	s.step.TeardownHooks = append(s.step.TeardownHooks, hook)
@@ -97,7 +138,7 @@ func (s *StepAPIWithOptionalArgs) Run(r *SessionRunner) (*StepResult, error) {
 
 	stepResult, err := runStepRequest(r, s.step)
 	if err != nil {
-		r.summary.Success = false
+		r.summary.fail()
 		return nil, err
 	}
 	stepResult.StepType = stepTypeAPI
@@ -111,7 +152,7 @@ func extendWithAPI(testStep *TStep, overriddenStep *API) {
 		testStep.Name = overriddenStep.Name
 	}
 	// merge & override request
-	testStep.Request = overriddenStep.Request
+	testStep.Request = overrideAPIRequest(overriddenStep.Request, testStep.APIOverride)
 	// merge & override variables
 	testStep.Variables = mergeVariables(testStep.Variables, overriddenStep.Variables)
 	// merge & override extractors
@@ -123,3 +164,54 @@ func extendWithAPI(testStep *TStep, overriddenStep *API) {
 	// merge & override teardownHooks
 	testStep.TeardownHooks = mergeSlices(testStep.TeardownHooks, overriddenStep.TeardownHooks)
 }
+
+// overrideAPIRequest returns a copy of api request merged with per-call overrides,
+// referenced api request is not modified.
+func overrideAPIRequest(request *Request, override *APIOverride) *Request {
+	if request == nil || override == nil {
+		return request
+	}
+	req := *request
+	req.compiled = nil // overridden request is parsed in each execution
+	if override.Params != nil {
+		params, _ := deepMergeValue(request.Params, override.Params).(map[string]interface{})
+		req.Params = params
+	}
+	if override.Headers != nil {
+		req.Headers = mergeMap(override.Headers, request.Headers)
+	}
+	if override.Cookies != nil {
+		req.Cookies = mergeMap(override.Cookies, request.Cookies)
+	}
+	if override.Body != nil {
+		body := request.Body
+		if body == nil {
+			// request body may be specified by json or data in HttpRunner format
+			if request.Json != nil {
+				body = request.Json
+			} else {
+				body = request.Data
+			}
+		}
+		req.Body = deepMergeValue(body, override.Body)
+	}
+	return &req
+}
+
+// deepMergeValue merges override into value recursively if both are objects,
+// otherwise override is returned. value is not modified.
+func deepMergeValue(value, override interface{}) interface{} {
+	valueMap, ok1 := value.(map[string]interface{})
+	overrideMap, ok2 := override.(map[string]interface{})
+	if !ok1 || !ok2 {
+		return override
+	}
+	merged := make(map[string]interface{}, len(valueMap)+len(overrideMap))
+	for k, v := range valueMap {
+		merged[k] = v
+	}
+	for k, v := range overrideMap {
+		merged[k] = deepMergeValue(valueMap[k], v)
+	}
+	return merged
+}
@@ -0,0 +1,72 @@
+package hrp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunRefAPIWithOverride(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"success": true}`))
+	}))
+	defer ts.Close()
+
+	api := &API{
+		Name: "create user",
+		Request: &Request{
+			Method:  httpPOST,
+			URL:     ts.URL + "/users",
+			Params:  map[string]interface{}{"version": "v1"},
+			Headers: map[string]string{"Token": "abc", "Lang": "en"},
+			Body: map[string]interface{}{
+				"name":    "debugtalk",
+				"profile": map[string]interface{}{"age": 18, "city": "Shenzhen"},
+			},
+		},
+	}
+
+	testcase := &TestCase{
+		Config: NewConfig("api override"),
+		TestSteps: []IStep{
+			NewStep("create user with override").
+				CallRefAPI(api).
+				WithParams(map[string]interface{}{"dry_run": true}).
+				WithHeaders(map[string]string{"Lang": "zh"}).
+				WithBody(map[string]interface{}{
+					"profile": map[string]interface{}{"city": "Beijing"},
+				}),
+			NewStep("create user without override").
+				CallRefAPI(api),
+		},
+	}
+	caseRunner := NewRunner(t).NewSessionRunner(testcase)
+	if !assert.Nil(t, caseRunner.Start()) {
+		t.Fatal()
+	}
+	records := caseRunner.GetSummary().Records
+
+	request := records[0].Data.(*SessionData).ReqResps.Request.(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"version": "v1", "dry_run": true}, request["params"])
+	assert.Equal(t, map[string]string{"Token": "abc", "Lang": "zh"}, request["headers"])
+	assert.EqualValues(t, map[string]interface{}{
+		"name":    "debugtalk",
+		"profile": map[string]interface{}{"age": 18, "city": "Beijing"},
+	}, request["body"])
+
+	// referenced api is not modified
+	request = records[1].Data.(*SessionData).ReqResps.Request.(map[string]interface{})
+	assert.Equal(t, map[string]string{"Token": "abc", "Lang": "en"}, request["headers"])
+	assert.Equal(t, "Shenzhen", api.Request.Body.(map[string]interface{})["profile"].(map[string]interface{})["city"])
+}
+
+func TestDeepMergeValue(t *testing.T) {
+	value := map[string]interface{}{"a": 1, "b": map[string]interface{}{"c": 2, "d": 3}}
+	merged := deepMergeValue(value, map[string]interface{}{"b": map[string]interface{}{"d": 4}, "e": 5})
+	assert.Equal(t, map[string]interface{}{"a": 1, "b": map[string]interface{}{"c": 2, "d": 4}, "e": 5}, merged)
+	assert.Equal(t, 3, value["b"].(map[string]interface{})["d"])
+	assert.Equal(t, []int{1}, deepMergeValue(value, []int{1}))
+}
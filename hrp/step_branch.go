@@ -0,0 +1,99 @@
+package hrp
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// Branch represents conditional branching between two step groups,
+// then steps are run if condition is true, otherwise else steps are run.
+type Branch struct {
+	If   string   `json:"if" yaml:"if"` // condition expression, e.g. $user_exists, $status_code == 404
+	Then []*TStep `json:"then" yaml:"then"`
+	Else []*TStep `json:"else,omitempty" yaml:"else,omitempty"`
+}
+
+// If creates a new branch step, which selects step group to run by condition.
+func (s *StepRequest) If(condition string) *StepBranch {
+	s.step.Branch = &Branch{
+		If: condition,
+	}
+	return &StepBranch{
+		step: s.step,
+	}
+}
+
+// StepBranch implements IStep interface.
+type StepBranch struct {
+	step      *TStep
+	thenSteps []IStep
+	elseSteps []IStep
+}
+
+// Then appends steps to be run if condition is true.
+func (s *StepBranch) Then(steps ...IStep) *StepBranch {
+	for _, step := range steps {
+		s.thenSteps = append(s.thenSteps, step)
+		s.step.Branch.Then = append(s.step.Branch.Then, step.Struct())
+	}
+	return s
+}
+
+// Else appends steps to be run if condition is false.
+func (s *StepBranch) Else(steps ...IStep) *StepBranch {
+	for _, step := range steps {
+		s.elseSteps = append(s.elseSteps, step)
+		s.step.Branch.Else = append(s.step.Branch.Else, step.Struct())
+	}
+	return s
+}
+
+func (s *StepBranch) Name() string {
+	if s.step.Name != "" {
+		return s.step.Name
+	}
+	return fmt.Sprintf("if %s", s.step.Branch.If)
+}
+
+func (s *StepBranch) Type() StepType {
+	return stepTypeBranch
+}
+
+func (s *StepBranch) Struct() *TStep {
+	return s.step
+}
+
+func (s *StepBranch) Run(r *SessionRunner) (*StepResult, error) {
+	branch := s.step.Branch
+	stepResult := &StepResult{
+		Name:     s.Name(),
+		StepType: stepTypeBranch,
+		Success:  false,
+	}
+
+	stepVariables, err := r.MergeStepVariables(s.step.Variables)
+	if err != nil {
+		return stepResult, err
+	}
+	ok, err := evalCondition(r.parser, branch.If, stepVariables)
+	if err != nil {
+		stepResult.Attachment = err.Error()
+		return stepResult, errors.Wrap(err, "eval branch condition failed")
+	}
+
+	steps := s.elseSteps
+	if ok {
+		steps = s.thenSteps
+	}
+	log.Info().Str("if", branch.If).Bool("condition", ok).
+		Int("steps", len(steps)).Msg("run branch")
+
+	if err := r.runStepGroup(steps); err != nil {
+		stepResult.Attachment = err.Error()
+		return stepResult, errors.Wrap(err, "run branch step failed")
+	}
+	stepResult.Success = true
+	return stepResult, nil
+}
@@ -0,0 +1,137 @@
+package hrp
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newUserServer() (*httptest.Server, map[string]int) {
+	var mutex sync.Mutex
+	calls := make(map[string]int)
+	registered := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		calls[r.URL.Path]++
+		switch r.URL.Path {
+		case "/register":
+			registered = true
+		case "/user", "/login":
+			if !registered {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"path": "%s"}`, r.URL.Path)
+	}))
+	return ts, calls
+}
+
+func TestRunCaseWithBranch(t *testing.T) {
+	ts, calls := newUserServer()
+	defer ts.Close()
+
+	newTestCase := func() *TestCase {
+		return &TestCase{
+			Config: NewConfig("branch").SetBaseURL(ts.URL),
+			TestSteps: []IStep{
+				NewStep("get user").
+					GET("/user").
+					Extract().
+					WithJmesPath("status_code", "user_status"),
+				NewStep("login or register").
+					If("$user_status == 200").
+					Then(
+						NewStep("login").
+							GET("/login").
+							Validate().
+							AssertEqual("status_code", 200, "check login"),
+					).
+					Else(
+						NewStep("register").POST("/register"),
+						NewStep("login").
+							GET("/login").
+							Validate().
+							AssertEqual("status_code", 200, "check login"),
+					),
+			},
+		}
+	}
+
+	// user not exists, register then login
+	if !assert.Nil(t, NewRunner(t).Run(newTestCase())) {
+		t.Fatal()
+	}
+	assert.Equal(t, map[string]int{"/user": 1, "/register": 1, "/login": 1}, calls)
+
+	// user exists, login directly
+	if !assert.Nil(t, NewRunner(t).Run(newTestCase())) {
+		t.Fatal()
+	}
+	assert.Equal(t, map[string]int{"/user": 2, "/register": 1, "/login": 2}, calls)
+}
+
+func TestLoadCaseWithBranch(t *testing.T) {
+	ts, calls := newUserServer()
+	defer ts.Close()
+
+	content := fmt.Sprintf(`
+config:
+  name: branch
+  base_url: %s
+teststeps:
+  - name: get user
+    request:
+      method: GET
+      url: /user
+    extract:
+      user_status: status_code
+  - name: login or register
+    branch:
+      if: $user_status == 200
+      then:
+        - name: login
+          request:
+            method: GET
+            url: /login
+      else:
+        - name: register
+          request:
+            method: POST
+            url: /register
+        - name: login
+          request:
+            method: GET
+            url: /login
+          validate:
+            - eq: ["status_code", 200]
+`, ts.URL)
+	casePath := filepath.Join(t.TempDir(), "branch.yaml")
+	if err := os.WriteFile(casePath, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tc := TestCasePath(casePath)
+	testcase, err := tc.ToTestCase()
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	stepBranch, ok := testcase.TestSteps[1].(*StepBranch)
+	if !assert.True(t, ok) {
+		t.Fatal()
+	}
+	assert.Len(t, stepBranch.thenSteps, 1)
+	assert.Len(t, stepBranch.elseSteps, 2)
+
+	if !assert.Nil(t, NewRunner(t).Run(testcase)) {
+		t.Fatal()
+	}
+	assert.Equal(t, 1, calls["/register"])
+}
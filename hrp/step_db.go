@@ -0,0 +1,243 @@
+package hrp
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql" // register mysql driver
+	_ "github.com/lib/pq"              // register postgres driver
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+const defaultDatabase = "default"
+
+// DBConfig represents database connection configured in testcase config, which is queried by database steps.
+// supported drivers are mysql, postgres and sqlite3.
+type DBConfig struct {
+	Driver string `json:"driver" yaml:"driver"` // required, mysql/postgres/sqlite3
+	DSN    string `json:"dsn" yaml:"dsn"`       // required, variables and functions are supported, e.g. user:$password@tcp(127.0.0.1:3306)/db
+}
+
+// SetDatabase configures database connection with name for database steps, e.g. ("default", "mysql", dsn).
+func (c *TConfig) SetDatabase(name, driver, dsn string) *TConfig {
+	if c.Databases == nil {
+		c.Databases = make(map[string]*DBConfig)
+	}
+	c.Databases[name] = &DBConfig{
+		Driver: driver,
+		DSN:    dsn,
+	}
+	return c
+}
+
+// DBQuery represents database step, which runs parameterized SQL query against database configured in testcase config,
+// result rows are exposed as list of maps, e.g. rows[0].status, and validated the same as response of request step.
+type DBQuery struct {
+	Database string        `json:"database,omitempty" yaml:"database,omitempty"` // database name in config, default to "default"
+	SQL      string        `json:"sql" yaml:"sql"`                               // required, placeholder is ? for mysql/sqlite3 and $1 for postgres
+	Args     []interface{} `json:"args,omitempty" yaml:"args,omitempty"`         // query arguments, variables and functions are supported
+	Timeout  float64       `json:"timeout,omitempty" yaml:"timeout,omitempty"`   // seconds, default to 10
+}
+
+// DB creates a new database step, which queries database configured with name in testcase config.
+func (s *StepRequest) DB(database string) *StepDB {
+	s.step.DB = &DBQuery{
+		Database: database,
+	}
+	return &StepDB{
+		step: s.step,
+	}
+}
+
+// StepDB implements IStep interface.
+type StepDB struct {
+	step *TStep
+}
+
+// Query sets parameterized SQL query and its arguments.
+func (s *StepDB) Query(sql string, args ...interface{}) *StepDB {
+	s.step.DB.SQL = sql
+	s.step.DB.Args = args
+	return s
+}
+
+// WithTimeout sets timeout seconds of query.
+func (s *StepDB) WithTimeout(timeout float64) *StepDB {
+	s.step.DB.Timeout = timeout
+	return s
+}
+
+// Extract extracts variable from query result with jmesPath, e.g. rows[0].id
+func (s *StepDB) Extract(jmesPath string, varName string) *StepDB {
+	if s.step.Extract == nil {
+		s.step.Extract = make(map[string]string)
+	}
+	s.step.Extract[varName] = jmesPath
+	return s
+}
+
+// AssertEqual asserts value extracted from query result with jmesPath equals to expected.
+func (s *StepDB) AssertEqual(jmesPath string, expected interface{}, msg string) *StepDB {
+	s.step.Validators = append(s.step.Validators, Validator{
+		Check:   jmesPath,
+		Assert:  "equals",
+		Expect:  expected,
+		Message: msg,
+	})
+	return s
+}
+
+// AssertLengthEqual asserts length of value extracted from query result with jmesPath, e.g. rows
+func (s *StepDB) AssertLengthEqual(jmesPath string, expected interface{}, msg string) *StepDB {
+	s.step.Validators = append(s.step.Validators, Validator{
+		Check:   jmesPath,
+		Assert:  "length_equals",
+		Expect:  expected,
+		Message: msg,
+	})
+	return s
+}
+
+func (s *StepDB) Name() string {
+	if s.step.Name != "" {
+		return s.step.Name
+	}
+	return fmt.Sprintf("db %s", s.step.DB.SQL)
+}
+
+func (s *StepDB) Type() StepType {
+	return stepTypeDB
+}
+
+func (s *StepDB) Struct() *TStep {
+	return s.step
+}
+
+func (s *StepDB) Run(r *SessionRunner) (*StepResult, error) {
+	stepResult := &StepResult{
+		Name:     s.Name(),
+		StepType: stepTypeDB,
+		Success:  false,
+	}
+	start := time.Now()
+	err := s.run(r, stepResult)
+	stepResult.Elapsed = time.Since(start).Milliseconds()
+	if err != nil {
+		stepResult.Attachment = err.Error()
+		return stepResult, err
+	}
+	stepResult.Success = true
+	return stepResult, nil
+}
+
+func (s *StepDB) run(r *SessionRunner, stepResult *StepResult) error {
+	stepVariables, err := r.MergeStepVariables(s.step.Variables)
+	if err != nil {
+		return err
+	}
+	query := s.step.DB
+	name := query.Database
+	if name == "" {
+		name = defaultDatabase
+	}
+	dbConfig, ok := r.testCase.Config.Databases[name]
+	if !ok {
+		return fmt.Errorf("database %s is not configured in testcase config", name)
+	}
+	dsn, err := r.parser.ParseString(dbConfig.DSN, stepVariables)
+	if err != nil {
+		return err
+	}
+	args, err := r.parser.Parse(query.Args, stepVariables)
+	if err != nil {
+		return err
+	}
+
+	key := strings.Join([]string{"db", dbConfig.Driver, convertString(dsn)}, "|")
+	closer, err := r.client(key, func() (io.Closer, error) {
+		db, err := sql.Open(sqlDriverName(dbConfig.Driver), convertString(dsn))
+		if err != nil {
+			return nil, errors.Wrapf(err, "open database %s failed", name)
+		}
+		return db, nil
+	})
+	if err != nil {
+		return err
+	}
+	db := closer.(*sql.DB)
+
+	timeout := query.Timeout
+	if timeout <= 0 {
+		timeout = defaultMessageTimeout
+	}
+	ctx, cancel := context.WithTimeout(r.ctx, time.Duration(timeout*1000)*time.Millisecond)
+	defer cancel()
+
+	var queryArgs []interface{}
+	if args != nil {
+		queryArgs = args.([]interface{})
+	}
+	rows, err := queryRows(ctx, db, query.SQL, queryArgs...)
+	if err != nil {
+		return errors.Wrapf(err, "query database %s failed", name)
+	}
+	log.Info().Str("database", name).Str("sql", query.SQL).
+		Interface("args", queryArgs).Int("rows", len(rows)).Msg("query database")
+
+	result := map[string]interface{}{
+		"sql":       query.SQL,
+		"args":      queryArgs,
+		"rows":      rows,
+		"row_count": len(rows),
+	}
+	return r.checkOutput(s.step, result, stepVariables, stepResult)
+}
+
+// queryRows runs query and converts result rows to list of maps, column name => value.
+func queryRows(ctx context.Context, db *sql.DB, query string, args ...interface{}) ([]interface{}, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]interface{}, 0)
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+		row := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			row[column] = convertDBValue(values[i])
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// convertDBValue converts scanned column value to json compatible value,
+// e.g. mysql returns []byte for text and decimal columns.
+func convertDBValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return v
+	}
+}
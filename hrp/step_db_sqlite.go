@@ -0,0 +1,15 @@
+package hrp
+
+import (
+	_ "modernc.org/sqlite" // register pure go sqlite driver without cgo
+)
+
+// sqlDriverName returns name of registered database driver of driver in testcases,
+// sqlite3 refers to built-in pure go sqlite driver, which is registered as sqlite.
+// sqlite3 driver is not registered by hrp, thus it is still available for other drivers, e.g. mattn/go-sqlite3.
+func sqlDriverName(driver string) string {
+	if driver == "sqlite3" {
+		return "sqlite"
+	}
+	return driver
+}
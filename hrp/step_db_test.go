@@ -0,0 +1,98 @@
+package hrp
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"modernc.org/sqlite"
+)
+
+func TestRunCaseWithDB(t *testing.T) {
+	db, mock, err := sqlmock.NewWithDSN("hrp_orders_db")
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	defer db.Close()
+	created := time.Date(2022, 3, 1, 8, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT id, status, created_at FROM orders WHERE user = ?").
+		WithArgs("debugtalk").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status", "created_at"}).
+			AddRow(1, []byte("paid"), created).
+			AddRow(2, []byte("created"), created))
+	mock.ExpectQuery("SELECT id FROM orders WHERE status = ?").
+		WithArgs("canceled").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	testcase := &TestCase{
+		Config: NewConfig("db assertion").
+			SetDatabase("orders", "sqlmock", "$dsn").
+			WithVariables(map[string]interface{}{"dsn": "hrp_orders_db", "user": "debugtalk"}),
+		TestSteps: []IStep{
+			NewStep("query orders of user").DB("orders").
+				Query("SELECT id, status, created_at FROM orders WHERE user = ?", "$user").
+				Extract("rows[0].id", "order_id").
+				AssertLengthEqual("rows", 2, "check rows count").
+				AssertEqual("rows[0].status", "paid", "check []byte column").
+				AssertEqual("rows[1].created_at", "2022-03-01T08:00:00Z", "check time column"),
+			NewStep("no canceled orders").DB("orders").
+				Query("SELECT id FROM orders WHERE status = ?", "canceled").
+				AssertEqual("row_count", 0, "check empty result"),
+		},
+	}
+	sessionRunner := NewRunner(t).NewSessionRunner(testcase)
+	if !assert.Nil(t, sessionRunner.Start()) {
+		t.Fatal()
+	}
+	assert.EqualValues(t, 1, sessionRunner.sessionVariables["order_id"])
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestRunCaseWithSQLite(t *testing.T) {
+	// sqlite3 driver may be registered by other packages, e.g. mattn/go-sqlite3, which does not conflict with hrp
+	registered := false
+	for _, driver := range sql.Drivers() {
+		registered = registered || driver == "sqlite3"
+	}
+	if !registered {
+		sql.Register("sqlite3", &sqlite.Driver{})
+	}
+
+	dsn := filepath.Join(t.TempDir(), "orders.db")
+	db, err := sql.Open("sqlite", dsn)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	defer db.Close()
+	_, err = db.Exec(`CREATE TABLE orders (id INTEGER PRIMARY KEY, user TEXT, status TEXT);
+		INSERT INTO orders (user, status) VALUES ('debugtalk', 'paid'), ('debugtalk', 'created'), ('leo', 'paid')`)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+
+	testcase := &TestCase{
+		Config: NewConfig("sqlite assertion").
+			SetDatabase("orders", "sqlite3", dsn),
+		TestSteps: []IStep{
+			NewStep("query orders of user").DB("orders").
+				Query("SELECT id, status FROM orders WHERE user = ? ORDER BY id", "debugtalk").
+				AssertLengthEqual("rows", 2, "check rows count").
+				AssertEqual("rows[0].status", "paid", "check text column").
+				AssertEqual("rows[1].id", 2, "check integer column"),
+		},
+	}
+	assert.Nil(t, NewRunner(t).Run(testcase))
+}
+
+func TestRunCaseWithDBNotConfigured(t *testing.T) {
+	testcase := &TestCase{
+		Config: NewConfig("db not configured"),
+		TestSteps: []IStep{
+			NewStep("query").DB("").Query("SELECT 1"),
+		},
+	}
+	assert.NotNil(t, NewRunner(nil).Run(testcase))
+}
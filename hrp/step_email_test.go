@@ -0,0 +1,90 @@
+package hrp
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-imap/backend/memory"
+	"github.com/emersion/go-imap/client"
+	"github.com/emersion/go-imap/server"
+	"github.com/stretchr/testify/assert"
+)
+
+// startIMAPServer starts in-memory IMAP server, which accepts user username with password password.
+func startIMAPServer(t *testing.T) string {
+	s := server.New(memory.New())
+	s.AllowInsecureAuth = true
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve(listener)
+	t.Cleanup(func() { s.Close() })
+	return listener.Addr().String()
+}
+
+func deliverEmail(t *testing.T, addr, to, subject, body string) {
+	c, err := client.Dial(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Logout()
+	if err := c.Login("username", "password"); err != nil {
+		t.Fatal(err)
+	}
+	raw := fmt.Sprintf("From: Service <noreply@example.com>\r\nTo: %s\r\nSubject: %s\r\n"+
+		"Content-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\n%s", to, subject, body)
+	if err := c.Append("INBOX", nil, time.Now(), bytes.NewBufferString(raw)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunCaseWithEmail(t *testing.T) {
+	addr := startIMAPServer(t)
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		deliverEmail(t, addr, "bob@example.com", "Reset your password", "Hi bob, your code: 654321\r\n"+
+			"Reset via https://example.com/reset?token=3D=\r\nabc.")
+		deliverEmail(t, addr, "alice@example.com", "Reset your password", "Hi alice, your code: 123456\r\n"+
+			"Reset via https://example.com/reset?token=3Dxyz.")
+	}()
+
+	testcase := &TestCase{
+		Config: NewConfig("email verification").
+			WithVariables(map[string]interface{}{
+				"server": fmt.Sprintf("imap://username:password@%s", addr),
+				"user":   "alice",
+			}),
+		TestSteps: []IStep{
+			NewStep("receive reset email").Email("$server").
+				WithSubject("Reset your password").
+				WithRecipient("$user@example.com").
+				WithInterval(0.1).WithTimeout(5).
+				Extract("code: (.*)", "code").
+				Extract("links[0]", "link").
+				AssertEqual("to[0]", "alice@example.com", "check recipient").
+				AssertEqual("from", "noreply@example.com", "check sender"),
+		},
+	}
+	sessionRunner := NewRunner(t).NewSessionRunner(testcase)
+	if !assert.Nil(t, sessionRunner.Start()) {
+		t.Fatal()
+	}
+	assert.Equal(t, "123456", sessionRunner.sessionVariables["code"])
+	assert.Equal(t, "https://example.com/reset?token=xyz", sessionRunner.sessionVariables["link"])
+}
+
+func TestRunCaseWithEmailTimeout(t *testing.T) {
+	addr := startIMAPServer(t)
+	testcase := &TestCase{
+		Config: NewConfig("email not received"),
+		TestSteps: []IStep{
+			NewStep("receive welcome email").Email(fmt.Sprintf("imap://username:password@%s", addr)).
+				WithSubject("Welcome").WithInterval(0.1).WithTimeout(0.3),
+		},
+	}
+	assert.NotNil(t, NewRunner(nil).Run(testcase))
+}
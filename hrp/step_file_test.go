@@ -0,0 +1,321 @@
+package hrp
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+// startSFTPServer starts SFTP server serving local file system, which accepts user debugtalk with password 123456.
+func startSFTPServer(t *testing.T) string {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(c ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if c.User() == "debugtalk" && string(password) == "123456" {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("password rejected for %s", c.User())
+		},
+	}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSFTP(conn, config)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func serveSFTP(conn net.Conn, config *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for newChannel := range chans {
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+		go func(in <-chan *ssh.Request) {
+			for req := range in {
+				req.Reply(req.Type == "subsystem" && string(req.Payload[4:]) == "sftp", nil)
+			}
+		}(requests)
+		server, err := sftp.NewServer(channel)
+		if err != nil {
+			return
+		}
+		go func() {
+			server.Serve()
+			server.Close()
+		}()
+	}
+}
+
+func TestRunCaseWithSFTP(t *testing.T) {
+	addr := startSFTPServer(t)
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "report.csv"), []byte("id,status\n1,paid"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	testcase := &TestCase{
+		Config: NewConfig("sftp file operations").
+			WithVariables(map[string]interface{}{
+				"server": fmt.Sprintf("sftp://debugtalk:123456@%s", addr),
+				"dir":    dir,
+			}),
+		TestSteps: []IStep{
+			NewStep("report dropped").File("$server").
+				Exists("$dir/report.csv").
+				AssertEqual("exists", true, "check file exists").
+				AssertEqual("size", 16, "check file size"),
+			NewStep("download report").File("$server").
+				Download("$dir/report.csv", "$dir/local.csv").
+				AssertEqual("content", "id,status\n1,paid", "check file content"),
+			NewStep("upload order").File("$server").
+				UploadContent(map[string]interface{}{"order_id": 1}, "$dir/order.json").
+				AssertEqual("size", 14, "check uploaded size"),
+			NewStep("download order").File("$server").
+				Download("$dir/order.json", "").
+				Extract("content.order_id", "order_id"),
+			NewStep("missing file").File("$server").
+				Exists("$dir/missing.csv").
+				AssertEqual("exists", false, "check file not exists"),
+		},
+	}
+	sessionRunner := NewRunner(t).NewSessionRunner(testcase)
+	if !assert.Nil(t, sessionRunner.Start()) {
+		t.Fatal()
+	}
+	assert.EqualValues(t, 1, sessionRunner.sessionVariables["order_id"])
+	local, err := os.ReadFile(filepath.Join(dir, "local.csv"))
+	if assert.Nil(t, err) {
+		assert.Equal(t, "id,status\n1,paid", string(local))
+	}
+}
+
+// startFTPServer starts FTP server serving files in memory, which accepts user debugtalk with password 123456,
+// only commands used by ftp client are implemented with extended passive mode.
+func startFTPServer(t *testing.T, files map[string][]byte) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	var mutex sync.Mutex
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveFTP(conn, files, &mutex)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func serveFTP(conn net.Conn, files map[string][]byte, mutex *sync.Mutex) {
+	defer conn.Close()
+	reply := func(format string, args ...interface{}) {
+		fmt.Fprintf(conn, format+"\r\n", args...)
+	}
+	reply("220 ready")
+	var user string
+	var data net.Listener
+	accept := func() (net.Conn, error) {
+		if data == nil {
+			return nil, fmt.Errorf("no data connection")
+		}
+		defer func() { data.Close(); data = nil }()
+		data.(*net.TCPListener).SetDeadline(time.Now().Add(time.Second))
+		return data.Accept()
+	}
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.SplitN(strings.TrimSpace(line), " ", 2)
+		command, arg := strings.ToUpper(fields[0]), ""
+		if len(fields) == 2 {
+			arg = fields[1]
+		}
+		switch command {
+		case "USER":
+			user = arg
+			reply("331 password required")
+		case "PASS":
+			if user != "debugtalk" || arg != "123456" {
+				reply("530 login incorrect")
+				continue
+			}
+			reply("230 logged in")
+		case "TYPE":
+			reply("200 type set")
+		case "EPSV":
+			if data, err = net.Listen("tcp", "127.0.0.1:0"); err != nil {
+				reply("425 can not open data connection")
+				continue
+			}
+			reply("229 Entering Extended Passive Mode (|||%d|)", data.Addr().(*net.TCPAddr).Port)
+		case "SIZE":
+			mutex.Lock()
+			content, ok := files[arg]
+			mutex.Unlock()
+			if !ok {
+				reply("550 file not found")
+				continue
+			}
+			reply("213 %d", len(content))
+		case "RETR":
+			mutex.Lock()
+			content, ok := files[arg]
+			mutex.Unlock()
+			dataConn, err := accept()
+			if err != nil {
+				reply("425 can not open data connection")
+				continue
+			}
+			if !ok {
+				dataConn.Close()
+				reply("550 file not found")
+				continue
+			}
+			reply("150 opening data connection")
+			dataConn.Write(content)
+			dataConn.Close()
+			reply("226 transfer complete")
+		case "STOR":
+			dataConn, err := accept()
+			if err != nil {
+				reply("425 can not open data connection")
+				continue
+			}
+			reply("150 opening data connection")
+			content, _ := io.ReadAll(dataConn)
+			dataConn.Close()
+			mutex.Lock()
+			files[arg] = content
+			mutex.Unlock()
+			reply("226 transfer complete")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 command not implemented")
+		}
+	}
+}
+
+func TestRunCaseWithFTP(t *testing.T) {
+	addr := startFTPServer(t, map[string][]byte{"/report.csv": []byte("id,status\n1,paid")})
+	testcase := &TestCase{
+		Config: NewConfig("ftp file operations").
+			WithVariables(map[string]interface{}{"server": fmt.Sprintf("ftp://debugtalk:123456@%s", addr)}),
+		TestSteps: []IStep{
+			NewStep("report dropped").File("$server").
+				Exists("/report.csv").
+				AssertEqual("exists", true, "check file exists").
+				AssertEqual("size", 16, "check file size"),
+			NewStep("download report").File("$server").
+				Download("/report.csv", "").
+				AssertEqual("content", "id,status\n1,paid", "check file content"),
+			NewStep("upload order").File("$server").
+				UploadContent(map[string]interface{}{"order_id": 1}, "/order.json").
+				AssertEqual("size", 14, "check uploaded size"),
+			NewStep("download order").File("$server").
+				Download("/order.json", "").
+				Extract("content.order_id", "order_id"),
+			NewStep("missing file").File("$server").
+				Exists("/missing.csv").
+				AssertEqual("exists", false, "check file not exists"),
+		},
+	}
+	sessionRunner := NewRunner(t).NewSessionRunner(testcase)
+	if !assert.Nil(t, sessionRunner.Start()) {
+		t.Fatal()
+	}
+	assert.EqualValues(t, 1, sessionRunner.sessionVariables["order_id"])
+}
+
+func TestFTPClientConcurrently(t *testing.T) {
+	files := map[string][]byte{}
+	addr := startFTPServer(t, files)
+	client, err := connectFTP(addr, "debugtalk", "123456", time.Second)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	defer client.Close()
+
+	// steps in DAG share the same control connection
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			remote := fmt.Sprintf("/file-%d.txt", i)
+			content := strings.Repeat(fmt.Sprint(i), 100)
+			if !assert.Nil(t, client.upload(remote, strings.NewReader(content))) {
+				return
+			}
+			size, exists, err := client.stat(remote)
+			assert.Nil(t, err)
+			assert.True(t, exists)
+			assert.EqualValues(t, len(content), size)
+			var buf bytes.Buffer
+			assert.Nil(t, client.download(remote, &buf))
+			assert.Equal(t, content, buf.String())
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestRunCaseWithFileServerRejected(t *testing.T) {
+	addr := startSFTPServer(t)
+	testcase := &TestCase{
+		Config: NewConfig("sftp login failed"),
+		TestSteps: []IStep{
+			NewStep("wrong password").File(fmt.Sprintf("sftp://debugtalk:wrong@%s", addr)).
+				WithTimeout(1).Exists("/tmp"),
+		},
+	}
+	assert.NotNil(t, NewRunner(nil).Run(testcase))
+
+	_, err := connectFileServer("smb://127.0.0.1", "", time.Second)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,283 @@
+package hrp
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/httprunner/httprunner/hrp/internal/json"
+)
+
+type fuzzType string
+
+const (
+	fuzzInt    fuzzType = "int"
+	fuzzFloat  fuzzType = "float"
+	fuzzString fuzzType = "string"
+	fuzzBool   fuzzType = "bool"
+)
+
+// fuzzPayloads stores mutated values for each parameter type hint,
+// including boundary numbers, long strings, injection payloads and type confusion values.
+var fuzzPayloads = map[fuzzType][]interface{}{
+	fuzzInt: {
+		0, -1, 1, math.MaxInt32, math.MinInt32, int64(math.MaxInt64), int64(math.MinInt64),
+		"1a", "", nil,
+	},
+	fuzzFloat: {
+		0.0, -1.5, 1e-308, math.MaxFloat64, -math.MaxFloat64, "NaN", "Infinity", "", nil,
+	},
+	fuzzString: {
+		"", " ", strings.Repeat("A", 10240), "' OR '1'='1", "\"; DROP TABLE users; --",
+		"<script>alert(1)</script>", "../../../../etc/passwd", "${jndi:ldap://127.0.0.1/a}",
+		"%s%s%s%n", "\u0000", "测试😀", 0, nil,
+	},
+	fuzzBool: {
+		true, false, "true", "yes", 0, 1, "", nil,
+	},
+}
+
+const defaultFuzzServerErrorCode int64 = 500
+
+// Fuzz represents fuzzing setting for request step.
+// The step request is used as template, fields specified with type hints or derived from OpenAPI schema will be
+// mutated in each iteration, and the service is asserted to never return 5xx or exceed latency bound.
+type Fuzz struct {
+	Iterations int               `json:"iterations,omitempty" yaml:"iterations,omitempty"`   // default to max payloads count of all fields
+	Fields     map[string]string `json:"fields" yaml:"fields"`                               // field path and type hint, e.g. {"params.page": "int", "body.name": "string"}
+	Schema     string            `json:"schema,omitempty" yaml:"schema,omitempty"`           // OpenAPI 3 document path, fields of operation matching request are mutated
+	MaxLatency int64             `json:"max_latency,omitempty" yaml:"max_latency,omitempty"` // milliseconds, ignore if value <= 0
+
+	schemaPath string // schema path resolved against project root dir
+}
+
+// Fuzz switches to fuzzing step with current request as template.
+func (s *StepRequestWithOptionalArgs) Fuzz(iterations int) *StepFuzz {
+	s.step.Fuzz = &Fuzz{
+		Iterations: iterations,
+		Fields:     make(map[string]string),
+	}
+	return &StepFuzz{
+		step: s.step,
+	}
+}
+
+// StepFuzz implements IStep interface.
+type StepFuzz struct {
+	step *TStep
+}
+
+// WithField specifies request field to mutate with type hint, e.g. ("params.page", "int").
+// type hint should be one of int, float, string and bool.
+func (s *StepFuzz) WithField(path string, typeHint string) *StepFuzz {
+	s.step.Fuzz.Fields[path] = typeHint
+	return s
+}
+
+// WithSchema derives fields to mutate from OpenAPI 3 document in json or yaml format,
+// fields specified by WithField override derived type hints.
+func (s *StepFuzz) WithSchema(path string) *StepFuzz {
+	s.step.Fuzz.Schema = path
+	return s
+}
+
+// WithMaxLatency sets the latency bound in milliseconds for each request.
+func (s *StepFuzz) WithMaxLatency(maxLatency int64) *StepFuzz {
+	s.step.Fuzz.MaxLatency = maxLatency
+	return s
+}
+
+func (s *StepFuzz) Name() string {
+	if s.step.Name != "" {
+		return s.step.Name
+	}
+	return fmt.Sprintf("fuzz %v %s", s.step.Request.Method, s.step.Request.URL)
+}
+
+func (s *StepFuzz) Type() StepType {
+	return stepTypeFuzz
+}
+
+func (s *StepFuzz) Struct() *TStep {
+	return s.step
+}
+
+func (s *StepFuzz) Run(r *SessionRunner) (*StepResult, error) {
+	fuzz := s.step.Fuzz
+	stepResult := &StepResult{
+		Name:     s.Name(),
+		StepType: stepTypeFuzz,
+		Success:  false,
+	}
+
+	fields, err := s.fields()
+	if err != nil {
+		stepResult.Attachment = err.Error()
+		return stepResult, err
+	}
+
+	// sort field paths to make mutations reproducible
+	var paths []string
+	for path, typeHint := range fields {
+		if _, ok := fuzzPayloads[fuzzType(typeHint)]; !ok {
+			err := fmt.Errorf("unsupported fuzz type hint %s for field %s", typeHint, path)
+			stepResult.Attachment = err.Error()
+			return stepResult, err
+		}
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	iterations := fuzz.Iterations
+	if iterations <= 0 {
+		for _, typeHint := range fields {
+			if n := len(fuzzPayloads[fuzzType(typeHint)]); n > iterations {
+				iterations = n
+			}
+		}
+	}
+
+	var failures []string
+	start := time.Now()
+	for i := 0; i < iterations; i++ {
+		mutations := make(map[string]interface{})
+		for fieldIndex, path := range paths {
+			payloads := fuzzPayloads[fuzzType(fields[path])]
+			mutations[path] = payloads[(i+fieldIndex)%len(payloads)]
+		}
+
+		request, err := mutateRequest(s.step.Request, mutations)
+		if err != nil {
+			stepResult.Attachment = err.Error()
+			return stepResult, errors.Wrap(err, "mutate fuzz request failed")
+		}
+		iterStep := &TStep{
+			Name:          fmt.Sprintf("%s #%d", stepResult.Name, i+1),
+			Request:       request,
+			Variables:     s.step.Variables,
+			SetupHooks:    s.step.SetupHooks,
+			TeardownHooks: s.step.TeardownHooks,
+			Validators: []interface{}{
+				Validator{
+					Check:   "status_code",
+					Assert:  "less_than",
+					Expect:  defaultFuzzServerErrorCode,
+					Message: "fuzz: service should never return 5xx",
+				},
+			},
+		}
+
+		iterResult, err := runStepRequest(r, iterStep)
+		if err == nil && fuzz.MaxLatency > 0 && iterResult.Elapsed > fuzz.MaxLatency {
+			err = fmt.Errorf("fuzz: latency %dms exceeds bound %dms", iterResult.Elapsed, fuzz.MaxLatency)
+			iterResult.Success = false
+			iterResult.Attachment = err.Error()
+		}
+		if err != nil {
+			mutationsJSON, _ := json.Marshal(mutations)
+			log.Error().Err(err).Str("step", iterStep.Name).
+				RawJSON("mutations", mutationsJSON).Msg("fuzz iteration failed")
+			failures = append(failures, fmt.Sprintf("%s with %s: %v", iterStep.Name, mutationsJSON, err))
+		}
+		r.updateSummary(iterResult)
+	}
+	stepResult.Elapsed = time.Since(start).Milliseconds()
+
+	if len(failures) > 0 {
+		err := fmt.Errorf("fuzz failed in %d/%d iterations:\n%s",
+			len(failures), iterations, strings.Join(failures, "\n"))
+		stepResult.Attachment = err.Error()
+		return stepResult, err
+	}
+	stepResult.Success = true
+	return stepResult, nil
+}
+
+// fields returns field paths and type hints to mutate, derived from OpenAPI schema and overridden by specified fields.
+func (s *StepFuzz) fields() (map[string]string, error) {
+	fuzz := s.step.Fuzz
+	if fuzz.Schema == "" {
+		return fuzz.Fields, nil
+	}
+	path := fuzz.schemaPath
+	if path == "" {
+		path = fuzz.Schema
+	}
+	fields, err := loadOpenAPIFields(path, s.step.Request)
+	if err != nil {
+		return nil, err
+	}
+	for path, typeHint := range fuzz.Fields {
+		fields[path] = typeHint
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no fuzz field derived from openapi schema %s", fuzz.Schema)
+	}
+	return fields, nil
+}
+
+// mutateRequest returns a copy of request template with fields replaced by mutations,
+// field path is in format of params.xxx, headers.xxx, cookies.xxx or body.xxx.yyy
+func mutateRequest(template *Request, mutations map[string]interface{}) (*Request, error) {
+	requestBytes, err := json.Marshal(template)
+	if err != nil {
+		return nil, err
+	}
+	var requestMap map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(requestBytes))
+	decoder.UseNumber()
+	if err := decoder.Decode(&requestMap); err != nil {
+		return nil, err
+	}
+
+	for path, value := range mutations {
+		keys := strings.Split(path, ".")
+		if len(keys) < 2 {
+			return nil, fmt.Errorf("invalid fuzz field path: %s", path)
+		}
+		switch keys[0] {
+		case "headers", "cookies":
+			// header and cookie values should be string
+			if value == nil {
+				value = ""
+			}
+			value = fmt.Sprint(value)
+		case "params", "body":
+		default:
+			return nil, fmt.Errorf("unsupported fuzz field path: %s", path)
+		}
+		// escape $ to avoid payloads being parsed as variables or functions
+		if str, ok := value.(string); ok {
+			value = strings.ReplaceAll(str, "$", "$$")
+		}
+
+		m := requestMap
+		for _, key := range keys[:len(keys)-1] {
+			sub, ok := m[key].(map[string]interface{})
+			if !ok {
+				sub = make(map[string]interface{})
+				m[key] = sub
+			}
+			m = sub
+		}
+		m[keys[len(keys)-1]] = value
+	}
+
+	requestBytes, err = json.Marshal(requestMap)
+	if err != nil {
+		return nil, err
+	}
+	request := &Request{}
+	decoder = json.NewDecoder(bytes.NewReader(requestBytes))
+	decoder.UseNumber()
+	if err := decoder.Decode(request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
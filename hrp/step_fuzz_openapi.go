@@ -0,0 +1,212 @@
+package hrp
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/httprunner/httprunner/hrp/internal/builtin"
+)
+
+// maxOpenAPISchemaDepth limits nesting of body schemas, in case schemas reference themselves.
+const maxOpenAPISchemaDepth = 8
+
+// openAPIDocument is subset of OpenAPI 3 document used to derive fuzz fields.
+type openAPIDocument struct {
+	Paths      map[string]*openAPIPathItem `json:"paths" yaml:"paths"`
+	Components struct {
+		Schemas    map[string]*openAPISchema    `json:"schemas" yaml:"schemas"`
+		Parameters map[string]*openAPIParameter `json:"parameters" yaml:"parameters"`
+	} `json:"components" yaml:"components"`
+}
+
+type openAPIPathItem struct {
+	Parameters []*openAPIParameter `json:"parameters" yaml:"parameters"`
+	Get        *openAPIOperation   `json:"get" yaml:"get"`
+	Put        *openAPIOperation   `json:"put" yaml:"put"`
+	Post       *openAPIOperation   `json:"post" yaml:"post"`
+	Delete     *openAPIOperation   `json:"delete" yaml:"delete"`
+	Patch      *openAPIOperation   `json:"patch" yaml:"patch"`
+	Head       *openAPIOperation   `json:"head" yaml:"head"`
+	Options    *openAPIOperation   `json:"options" yaml:"options"`
+}
+
+func (p *openAPIPathItem) operation(method HTTPMethod) *openAPIOperation {
+	switch HTTPMethod(strings.ToUpper(string(method))) {
+	case httpGET:
+		return p.Get
+	case httpPUT:
+		return p.Put
+	case httpPOST:
+		return p.Post
+	case httpDELETE:
+		return p.Delete
+	case httpPATCH:
+		return p.Patch
+	case httpHEAD:
+		return p.Head
+	case httpOPTIONS:
+		return p.Options
+	}
+	return nil
+}
+
+type openAPIOperation struct {
+	Parameters  []*openAPIParameter `json:"parameters" yaml:"parameters"`
+	RequestBody *struct {
+		Content map[string]struct {
+			Schema *openAPISchema `json:"schema" yaml:"schema"`
+		} `json:"content" yaml:"content"`
+	} `json:"requestBody" yaml:"requestBody"`
+}
+
+type openAPIParameter struct {
+	Ref    string         `json:"$ref" yaml:"$ref"`
+	Name   string         `json:"name" yaml:"name"`
+	In     string         `json:"in" yaml:"in"` // query, header, cookie or path
+	Schema *openAPISchema `json:"schema" yaml:"schema"`
+}
+
+type openAPISchema struct {
+	Ref        string                    `json:"$ref" yaml:"$ref"`
+	Type       string                    `json:"type" yaml:"type"`
+	Properties map[string]*openAPISchema `json:"properties" yaml:"properties"`
+}
+
+// fuzzTypeOfSchema converts OpenAPI schema type to fuzz type hint, empty if type is not fuzzed, e.g. array.
+func fuzzTypeOfSchema(schemaType string) fuzzType {
+	switch schemaType {
+	case "integer":
+		return fuzzInt
+	case "number":
+		return fuzzFloat
+	case "string":
+		return fuzzString
+	case "boolean":
+		return fuzzBool
+	}
+	return ""
+}
+
+// resolveSchemaPath resolves relative schema path of fuzz against project root dir at testcase load time.
+func (f *Fuzz) resolveSchemaPath(projectRootDir string) {
+	if f.Schema != "" && !filepath.IsAbs(f.Schema) {
+		f.schemaPath = filepath.Join(projectRootDir, f.Schema)
+	}
+}
+
+// loadOpenAPIFields loads OpenAPI document and derives fuzz fields from operation matching method and url of request,
+// query, header and cookie parameters and properties of json request body are mutated with their schema types.
+func loadOpenAPIFields(path string, request *Request) (map[string]string, error) {
+	doc := &openAPIDocument{}
+	if err := builtin.LoadFile(path, doc); err != nil {
+		return nil, errors.Wrap(err, "load openapi schema failed")
+	}
+	return doc.fuzzFields(request)
+}
+
+func (doc *openAPIDocument) fuzzFields(request *Request) (map[string]string, error) {
+	requestPath := request.URL
+	if u, err := url.Parse(request.URL); err == nil {
+		requestPath = u.Path
+	}
+	pathItem := doc.matchPath(requestPath)
+	if pathItem == nil {
+		return nil, fmt.Errorf("path %s not found in openapi schema", requestPath)
+	}
+	operation := pathItem.operation(request.Method)
+	if operation == nil {
+		return nil, fmt.Errorf("operation %s %s not found in openapi schema", request.Method, requestPath)
+	}
+
+	fields := make(map[string]string)
+	// parameters of operation override those of path item with the same name and location
+	for _, parameters := range [][]*openAPIParameter{pathItem.Parameters, operation.Parameters} {
+		for _, parameter := range parameters {
+			if parameter.Ref != "" {
+				parameter = doc.Components.Parameters[strings.TrimPrefix(parameter.Ref, "#/components/parameters/")]
+				if parameter == nil {
+					continue
+				}
+			}
+			var prefix string
+			switch parameter.In {
+			case "query":
+				prefix = "params"
+			case "header":
+				prefix = "headers"
+			case "cookie":
+				prefix = "cookies"
+			default:
+				// path parameters are part of url, which is not mutated
+				continue
+			}
+			if typeHint := fuzzTypeOfSchema(doc.resolveSchema(parameter.Schema).Type); typeHint != "" {
+				fields[prefix+"."+parameter.Name] = string(typeHint)
+			}
+		}
+	}
+	if operation.RequestBody != nil {
+		for contentType, content := range operation.RequestBody.Content {
+			if strings.Contains(contentType, "json") {
+				doc.bodyFields(fields, "body", content.Schema, 0)
+				break
+			}
+		}
+	}
+	return fields, nil
+}
+
+// matchPath returns path item of OpenAPI document matching request path, path templates like {id} match any segment.
+func (doc *openAPIDocument) matchPath(requestPath string) *openAPIPathItem {
+	if item, ok := doc.Paths[requestPath]; ok {
+		return item
+	}
+	segments := strings.Split(strings.Trim(requestPath, "/"), "/")
+	for path, item := range doc.Paths {
+		templates := strings.Split(strings.Trim(path, "/"), "/")
+		if len(templates) != len(segments) {
+			continue
+		}
+		matched := true
+		for i, template := range templates {
+			if template != segments[i] && !(strings.HasPrefix(template, "{") && strings.HasSuffix(template, "}")) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return item
+		}
+	}
+	return nil
+}
+
+// resolveSchema resolves $ref of schema to components schemas, empty schema is returned if not found.
+func (doc *openAPIDocument) resolveSchema(schema *openAPISchema) *openAPISchema {
+	for depth := 0; schema != nil && schema.Ref != "" && depth < maxOpenAPISchemaDepth; depth++ {
+		schema = doc.Components.Schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+	}
+	if schema == nil || schema.Ref != "" {
+		return &openAPISchema{}
+	}
+	return schema
+}
+
+func (doc *openAPIDocument) bodyFields(fields map[string]string, prefix string, schema *openAPISchema, depth int) {
+	if depth >= maxOpenAPISchemaDepth {
+		return
+	}
+	schema = doc.resolveSchema(schema)
+	// body itself is not mutated, only its properties
+	if typeHint := fuzzTypeOfSchema(schema.Type); typeHint != "" && depth > 0 {
+		fields[prefix] = string(typeHint)
+		return
+	}
+	for name, property := range schema.Properties {
+		doc.bodyFields(fields, prefix+"."+name, property, depth+1)
+	}
+}
@@ -0,0 +1,170 @@
+package hrp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMutateRequest(t *testing.T) {
+	template := &Request{
+		Method:  httpPOST,
+		URL:     "/users",
+		Params:  map[string]interface{}{"page": 1},
+		Headers: map[string]string{"Content-Type": "application/json"},
+		Body:    map[string]interface{}{"user": map[string]interface{}{"name": "hrp", "age": 3}},
+	}
+	request, err := mutateRequest(template, map[string]interface{}{
+		"params.page":     "1a",
+		"headers.X-Token": 123,
+		"body.user.name":  "${jndi:ldap://127.0.0.1/a}",
+	})
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	assert.Equal(t, "1a", request.Params["page"])
+	assert.Equal(t, "123", request.Headers["X-Token"])
+	user := request.Body.(map[string]interface{})["user"].(map[string]interface{})
+	assert.Equal(t, "$${jndi:ldap://127.0.0.1/a}", user["name"])
+	// template should not be modified
+	assert.Equal(t, "hrp", template.Body.(map[string]interface{})["user"].(map[string]interface{})["name"])
+
+	_, err = mutateRequest(template, map[string]interface{}{"url": "/"})
+	assert.NotNil(t, err)
+}
+
+func TestRunStepFuzz(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, err := strconv.Atoi(r.URL.Query().Get("page"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if page < 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	step := NewStep("fuzz users").
+		GET(ts.URL+"/users").
+		WithParams(map[string]interface{}{"page": 1}).
+		Fuzz(0).
+		WithField("params.page", "int").
+		WithMaxLatency(1000)
+	testcase := &TestCase{
+		Config:    NewConfig("fuzz"),
+		TestSteps: []IStep{step},
+	}
+	sessionRunner := NewRunner(&testing.T{}).NewSessionRunner(testcase)
+	stepResult, err := step.Run(sessionRunner)
+	if !assert.NotNil(t, err) {
+		t.Fatal()
+	}
+	assert.False(t, stepResult.Success)
+	assert.Contains(t, err.Error(), "fuzz failed in 3/10 iterations")
+	assert.Equal(t, 10, sessionRunner.summary.Stat.Total)
+	assert.Equal(t, 3, sessionRunner.summary.Stat.Failures)
+}
+
+const testFuzzOpenAPI = `openapi: 3.0.0
+paths:
+  /users/{id}:
+    parameters:
+      - $ref: '#/components/parameters/Token'
+    get:
+      parameters:
+        - {name: id, in: path, schema: {type: integer}}
+        - {name: verbose, in: query, schema: {type: boolean}}
+    put:
+      parameters:
+        - {name: session, in: cookie, schema: {type: string}}
+        - {name: tags, in: query, schema: {type: array}}
+      requestBody:
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/User'
+components:
+  parameters:
+    Token:
+      {name: X-Token, in: header, schema: {type: string}}
+  schemas:
+    User:
+      type: object
+      properties:
+        name: {type: string}
+        age: {type: integer}
+        score: {type: number}
+        friend: {$ref: '#/components/schemas/User'}
+`
+
+func TestOpenAPIFuzzFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "openapi.yaml")
+	if !assert.Nil(t, os.WriteFile(path, []byte(testFuzzOpenAPI), 0o644)) {
+		t.Fatal()
+	}
+
+	fields, err := loadOpenAPIFields(path, &Request{Method: httpGET, URL: "http://127.0.0.1/users/1?verbose=1"})
+	if assert.Nil(t, err) {
+		assert.Equal(t, map[string]string{
+			"headers.X-Token": "string",
+			"params.verbose":  "bool",
+		}, fields)
+	}
+
+	fields, err = loadOpenAPIFields(path, &Request{Method: httpPUT, URL: "/users/1"})
+	if assert.Nil(t, err) {
+		assert.Equal(t, "string", fields["cookies.session"])
+		assert.Equal(t, "string", fields["body.name"])
+		assert.Equal(t, "int", fields["body.age"])
+		assert.Equal(t, "float", fields["body.score"])
+		assert.Equal(t, "int", fields["body.friend.friend.age"])
+		assert.NotContains(t, fields, "params.tags")
+	}
+
+	_, err = loadOpenAPIFields(path, &Request{Method: httpPOST, URL: "/users/1"})
+	assert.NotNil(t, err)
+	_, err = loadOpenAPIFields(path, &Request{Method: httpGET, URL: "/orders/1"})
+	assert.NotNil(t, err)
+}
+
+func TestRunStepFuzzWithSchema(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := strconv.ParseBool(r.URL.Query().Get("verbose")); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	path := filepath.Join(t.TempDir(), "openapi.yaml")
+	if !assert.Nil(t, os.WriteFile(path, []byte(testFuzzOpenAPI), 0o644)) {
+		t.Fatal()
+	}
+	step := NewStep("fuzz user").
+		GET(ts.URL+"/users/1").
+		Fuzz(0).
+		WithSchema(path).
+		WithField("headers.X-Token", "int")
+	testcase := &TestCase{
+		Config:    NewConfig("fuzz"),
+		TestSteps: []IStep{step},
+	}
+	sessionRunner := NewRunner(&testing.T{}).NewSessionRunner(testcase)
+	_, err := step.Run(sessionRunner)
+	if !assert.NotNil(t, err) {
+		t.Fatal()
+	}
+	// verbose is mutated as bool, "yes" and empty values are rejected with 5xx
+	assert.Contains(t, err.Error(), `"params.verbose":"yes"`)
+	assert.Equal(t, 10, sessionRunner.summary.Stat.Total)
+}
@@ -0,0 +1,341 @@
+package hrp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/segmentio/kafka-go"
+)
+
+type kafkaAction string
+
+const (
+	kafkaProduce kafkaAction = "produce"
+	kafkaConsume kafkaAction = "consume" // wait for message matching filter
+)
+
+// Kafka represents Kafka step, which produces message to topic, or consumes topic and waits for message matching filter.
+// messages produced since testcase start are consumed from all partitions, thus event emitted by former request steps
+// can be awaited, and each message is consumed only once in one session.
+type Kafka struct {
+	Brokers []string          `json:"brokers" yaml:"brokers"`                     // required, e.g. ["127.0.0.1:9092"]
+	Action  kafkaAction       `json:"action" yaml:"action"`                       // required, produce/consume
+	Topic   string            `json:"topic" yaml:"topic"`                         // required
+	Key     string            `json:"key,omitempty" yaml:"key,omitempty"`         // produce message key
+	Value   interface{}       `json:"value,omitempty" yaml:"value,omitempty"`     // produce message value, map and slice are encoded as json
+	Headers map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"` // produce message headers
+	Filter  *MessageFilter    `json:"filter,omitempty" yaml:"filter,omitempty"`   // consume the first message matching filter, e.g. value.order_id
+	Timeout float64           `json:"timeout,omitempty" yaml:"timeout,omitempty"` // seconds, default to 10
+}
+
+// Kafka creates a new Kafka step connected to brokers.
+func (s *StepRequest) Kafka(brokers ...string) *StepKafka {
+	s.step.Kafka = &Kafka{
+		Brokers: brokers,
+	}
+	return &StepKafka{
+		step: s.step,
+	}
+}
+
+// StepKafka implements IStep interface.
+type StepKafka struct {
+	step *TStep
+}
+
+// Produce produces message with key and value to topic.
+func (s *StepKafka) Produce(topic string, key string, value interface{}) *StepKafka {
+	s.step.Kafka.Action = kafkaProduce
+	s.step.Kafka.Topic = topic
+	s.step.Kafka.Key = key
+	s.step.Kafka.Value = value
+	return s
+}
+
+// WithHeaders sets headers of produced message.
+func (s *StepKafka) WithHeaders(headers map[string]string) *StepKafka {
+	s.step.Kafka.Headers = headers
+	return s
+}
+
+// Consume waits for message from topic, message is extracted and validated the same as response of request step.
+func (s *StepKafka) Consume(topic string) *StepKafka {
+	s.step.Kafka.Action = kafkaConsume
+	s.step.Kafka.Topic = topic
+	return s
+}
+
+// WithFilter consumes the first message whose value extracted by jmesPath equals to expected.
+func (s *StepKafka) WithFilter(jmesPath string, expected interface{}) *StepKafka {
+	s.step.Kafka.Filter = &MessageFilter{
+		JmesPath: jmesPath,
+		Expect:   expected,
+	}
+	return s
+}
+
+// WithTimeout sets timeout seconds of producing and consuming.
+func (s *StepKafka) WithTimeout(timeout float64) *StepKafka {
+	s.step.Kafka.Timeout = timeout
+	return s
+}
+
+// Extract extracts variable from consumed message with jmesPath, e.g. value.order_id
+func (s *StepKafka) Extract(jmesPath string, varName string) *StepKafka {
+	if s.step.Extract == nil {
+		s.step.Extract = make(map[string]string)
+	}
+	s.step.Extract[varName] = jmesPath
+	return s
+}
+
+// AssertEqual asserts value extracted from consumed message with jmesPath equals to expected.
+func (s *StepKafka) AssertEqual(jmesPath string, expected interface{}, msg string) *StepKafka {
+	s.step.Validators = append(s.step.Validators, Validator{
+		Check:   jmesPath,
+		Assert:  "equals",
+		Expect:  expected,
+		Message: msg,
+	})
+	return s
+}
+
+func (s *StepKafka) Name() string {
+	if s.step.Name != "" {
+		return s.step.Name
+	}
+	return fmt.Sprintf("kafka %s %s", s.step.Kafka.Action, s.step.Kafka.Topic)
+}
+
+func (s *StepKafka) Type() StepType {
+	return stepTypeKafka
+}
+
+func (s *StepKafka) Struct() *TStep {
+	return s.step
+}
+
+func (s *StepKafka) Run(r *SessionRunner) (*StepResult, error) {
+	stepResult := &StepResult{
+		Name:     s.Name(),
+		StepType: stepTypeKafka,
+		Success:  false,
+	}
+	start := time.Now()
+	err := s.run(r, stepResult)
+	stepResult.Elapsed = time.Since(start).Milliseconds()
+	if err != nil {
+		stepResult.Attachment = err.Error()
+		return stepResult, err
+	}
+	stepResult.Success = true
+	return stepResult, nil
+}
+
+func (s *StepKafka) run(r *SessionRunner, stepResult *StepResult) error {
+	stepVariables, err := r.MergeStepVariables(s.step.Variables)
+	if err != nil {
+		return err
+	}
+	cfg := s.step.Kafka
+	brokers := make([]string, 0, len(cfg.Brokers))
+	for _, broker := range cfg.Brokers {
+		parsed, err := r.parser.ParseString(broker, stepVariables)
+		if err != nil {
+			return err
+		}
+		brokers = append(brokers, convertString(parsed))
+	}
+	parsedTopic, err := r.parser.ParseString(cfg.Topic, stepVariables)
+	if err != nil {
+		return err
+	}
+	topic := convertString(parsedTopic)
+	if len(brokers) == 0 || topic == "" {
+		return errors.New("kafka brokers and topic should not be empty")
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultMessageTimeout
+	}
+	ctx, cancel := context.WithTimeout(r.ctx, time.Duration(timeout*1000)*time.Millisecond)
+	defer cancel()
+
+	key := "kafka|" + strings.Join(brokers, ",")
+	closer, err := r.client(key, func() (io.Closer, error) {
+		return newKafkaClient(brokers, r.startTime), nil
+	})
+	if err != nil {
+		return err
+	}
+	client := closer.(*kafkaClient)
+
+	switch cfg.Action {
+	case kafkaProduce:
+		msgKey, err := r.parser.ParseString(cfg.Key, stepVariables)
+		if err != nil {
+			return err
+		}
+		value, err := r.parser.Parse(cfg.Value, stepVariables)
+		if err != nil {
+			return err
+		}
+		headers, err := r.parser.ParseHeaders(cfg.Headers, stepVariables)
+		if err != nil {
+			return err
+		}
+		content, err := encodeMessagePayload(value)
+		if err != nil {
+			return err
+		}
+		msg := kafka.Message{
+			Topic: topic,
+			Key:   []byte(convertString(msgKey)),
+			Value: content,
+		}
+		for name, value := range headers {
+			msg.Headers = append(msg.Headers, kafka.Header{Key: name, Value: []byte(value)})
+		}
+		if err := client.writer.WriteMessages(ctx, msg); err != nil {
+			return errors.Wrapf(err, "produce to kafka topic %s failed", topic)
+		}
+		log.Info().Str("topic", topic).Int("size", len(content)).Msg("produce kafka message")
+		stepResult.ContentSize = int64(len(content))
+		stepResult.Data = map[string]interface{}{"topic": topic, "key": msgKey, "value": value}
+		return nil
+	case kafkaConsume:
+		if err := client.consume(ctx, topic); err != nil {
+			return err
+		}
+		matchFilter, err := r.messageMatcher(cfg.Filter, stepVariables)
+		if err != nil {
+			return err
+		}
+		message, err := client.buffer.wait(ctx, func(message map[string]interface{}) bool {
+			return message["topic"] == topic && matchFilter(message)
+		})
+		if err != nil {
+			return errors.Wrapf(err, "consume from kafka topic %s failed", topic)
+		}
+		log.Info().Str("topic", topic).Interface("message", message).Msg("consume kafka message")
+		return r.checkOutput(s.step, message, stepVariables, stepResult)
+	default:
+		return fmt.Errorf("unexpected kafka action %s, should be produce or consume", cfg.Action)
+	}
+}
+
+// kafkaClient is Kafka client of session, messages of consumed topics are read in background and buffered.
+type kafkaClient struct {
+	brokers []string
+	since   time.Time // messages are consumed since this time
+	writer  *kafka.Writer
+	buffer  *messageBuffer
+	ctx     context.Context
+	cancel  context.CancelFunc
+	mutex   sync.Mutex
+	readers map[string][]*kafka.Reader // topic => partition readers
+}
+
+func newKafkaClient(brokers []string, since time.Time) *kafkaClient {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &kafkaClient{
+		brokers: brokers,
+		since:   since,
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Balancer:     &kafka.Hash{},
+			BatchTimeout: 10 * time.Millisecond,
+		},
+		buffer:  newMessageBuffer(),
+		ctx:     ctx,
+		cancel:  cancel,
+		readers: make(map[string][]*kafka.Reader),
+	}
+}
+
+// consume starts reading all partitions of topic in background if not started yet.
+func (c *kafkaClient) consume(ctx context.Context, topic string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if _, ok := c.readers[topic]; ok {
+		return nil
+	}
+
+	conn, err := kafka.DialContext(ctx, "tcp", c.brokers[0])
+	if err != nil {
+		return errors.Wrapf(err, "connect to kafka broker %s failed", c.brokers[0])
+	}
+	partitions, err := conn.ReadPartitions(topic)
+	conn.Close()
+	if err != nil {
+		return errors.Wrapf(err, "read partitions of kafka topic %s failed", topic)
+	}
+
+	var readers []*kafka.Reader
+	for _, partition := range partitions {
+		reader := kafka.NewReader(kafka.ReaderConfig{
+			Brokers:   c.brokers,
+			Topic:     topic,
+			Partition: partition.ID,
+			MaxWait:   100 * time.Millisecond,
+		})
+		if err := reader.SetOffsetAt(ctx, c.since); err != nil {
+			reader.Close()
+			for _, r := range readers {
+				r.Close()
+			}
+			return errors.Wrapf(err, "seek kafka topic %s partition %d failed", topic, partition.ID)
+		}
+		readers = append(readers, reader)
+		go c.read(reader)
+	}
+	c.readers[topic] = readers
+	log.Info().Str("topic", topic).Int("partitions", len(readers)).
+		Time("since", c.since).Msg("consume kafka topic")
+	return nil
+}
+
+func (c *kafkaClient) read(reader *kafka.Reader) {
+	for {
+		msg, err := reader.ReadMessage(c.ctx)
+		if err != nil {
+			return
+		}
+		c.buffer.push(newKafkaMessage(msg))
+	}
+}
+
+func (c *kafkaClient) Close() error {
+	c.cancel()
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, readers := range c.readers {
+		for _, reader := range readers {
+			reader.Close()
+		}
+	}
+	return c.writer.Close()
+}
+
+// newKafkaMessage converts consumed message to message object, which is filtered, extracted and validated with jmespath.
+func newKafkaMessage(msg kafka.Message) map[string]interface{} {
+	headers := make(map[string]interface{}, len(msg.Headers))
+	for _, header := range msg.Headers {
+		headers[header.Key] = string(header.Value)
+	}
+	return map[string]interface{}{
+		"topic":     msg.Topic,
+		"partition": msg.Partition,
+		"offset":    msg.Offset,
+		"key":       string(msg.Key),
+		"value":     decodeMessagePayload(msg.Value),
+		"headers":   headers,
+		"timestamp": msg.Time.UnixNano() / int64(time.Millisecond),
+	}
+}
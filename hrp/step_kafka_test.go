@@ -0,0 +1,71 @@
+package hrp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/httprunner/httprunner/hrp/internal/json"
+)
+
+func TestNewKafkaMessage(t *testing.T) {
+	msg := kafka.Message{
+		Topic:     "orders",
+		Partition: 1,
+		Offset:    42,
+		Key:       []byte("order-1"),
+		Value:     []byte(`{"order_id": "order-1", "state": "created"}`),
+		Headers:   []kafka.Header{{Key: "trace_id", Value: []byte("abc")}},
+		Time:      time.Unix(1600000000, 0),
+	}
+	message := newKafkaMessage(msg)
+	assert.Equal(t, "orders", message["topic"])
+	assert.Equal(t, "order-1", message["key"])
+	assert.Equal(t, map[string]interface{}{"order_id": "order-1", "state": "created"}, message["value"])
+	assert.Equal(t, map[string]interface{}{"trace_id": "abc"}, message["headers"])
+	assert.Equal(t, int64(1600000000000), message["timestamp"])
+
+	// consumed messages are filtered with jmespath
+	sessionRunner := NewRunner(t).NewSessionRunner(&TestCase{Config: NewConfig("kafka")})
+	match, err := sessionRunner.messageMatcher(&MessageFilter{JmesPath: "value.order_id", Expect: "$order_id"},
+		map[string]interface{}{"order_id": "order-1"})
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	assert.True(t, match(message))
+	message["value"] = "not json"
+	assert.False(t, match(message))
+}
+
+func TestLoadKafkaStep(t *testing.T) {
+	tc := &TCase{}
+	err := json.Unmarshal([]byte(`{"config": {"name": "kafka"}, "teststeps": [
+		{"name": "await order created", "kafka": {"brokers": ["127.0.0.1:9092"], "action": "consume",
+		"topic": "orders", "filter": {"jmespath": "value.order_id", "expect": "$order_id"}, "timeout": 5},
+		"validate": [{"check": "value.state", "assert": "equals", "expect": "created"}]}]}`), tc)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	step, err := convertTStep(tc.TestSteps[0], "", nil)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	assert.Equal(t, stepTypeKafka, step.Type())
+	assert.Equal(t, kafkaConsume, step.Struct().Kafka.Action)
+	assert.Equal(t, "value.order_id", step.Struct().Kafka.Filter.JmesPath)
+}
+
+func TestRunKafkaStepUnreachableBroker(t *testing.T) {
+	testcase := &TestCase{
+		Config: NewConfig("kafka unreachable"),
+		TestSteps: []IStep{
+			NewStep("produce order").Kafka("127.0.0.1:1").WithTimeout(0.5).
+				Produce("orders", "order-1", map[string]interface{}{"order_id": "order-1"}),
+		},
+	}
+	start := time.Now()
+	assert.NotNil(t, NewRunner(nil).Run(testcase))
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
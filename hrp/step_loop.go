@@ -0,0 +1,135 @@
+package hrp
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+const defaultLoopMaxIterations = 100
+
+// Loop represents a group of steps repeated while condition is true,
+// which is usually used in polling patterns, e.g. poll job status until state == done.
+type Loop struct {
+	While         string   `json:"while" yaml:"while"`                                       // condition expression, e.g. $state != done
+	MaxIterations int      `json:"max_iterations,omitempty" yaml:"max_iterations,omitempty"` // default to 100, fail if exceeded
+	Interval      float64  `json:"interval,omitempty" yaml:"interval,omitempty"`             // sleep seconds between iterations
+	Steps         []*TStep `json:"steps" yaml:"steps"`
+}
+
+// Loop creates a new loop step, which repeats steps while condition is true.
+func (s *StepRequest) Loop(condition string) *StepLoop {
+	s.step.Loop = &Loop{
+		While: condition,
+	}
+	return &StepLoop{
+		step: s.step,
+	}
+}
+
+// StepLoop implements IStep interface.
+type StepLoop struct {
+	step  *TStep
+	steps []IStep
+}
+
+// WithMaxIterations sets max iterations of loop, loop fails if condition is still true after max iterations.
+func (s *StepLoop) WithMaxIterations(maxIterations int) *StepLoop {
+	s.step.Loop.MaxIterations = maxIterations
+	return s
+}
+
+// WithInterval sets sleep seconds between iterations.
+func (s *StepLoop) WithInterval(interval float64) *StepLoop {
+	s.step.Loop.Interval = interval
+	return s
+}
+
+// WithSteps appends steps to be repeated in loop.
+func (s *StepLoop) WithSteps(steps ...IStep) *StepLoop {
+	for _, step := range steps {
+		s.steps = append(s.steps, step)
+		s.step.Loop.Steps = append(s.step.Loop.Steps, step.Struct())
+	}
+	return s
+}
+
+func (s *StepLoop) Name() string {
+	if s.step.Name != "" {
+		return s.step.Name
+	}
+	return fmt.Sprintf("loop while %s", s.step.Loop.While)
+}
+
+func (s *StepLoop) Type() StepType {
+	return stepTypeLoop
+}
+
+func (s *StepLoop) Struct() *TStep {
+	return s.step
+}
+
+func (s *StepLoop) Run(r *SessionRunner) (*StepResult, error) {
+	loop := s.step.Loop
+	stepResult := &StepResult{
+		Name:     s.Name(),
+		StepType: stepTypeLoop,
+		Success:  false,
+	}
+
+	maxIterations := loop.MaxIterations
+	if maxIterations <= 0 {
+		maxIterations = defaultLoopMaxIterations
+	}
+
+	start := time.Now()
+	defer func() {
+		stepResult.Elapsed = time.Since(start).Milliseconds()
+	}()
+
+	var stepErr error
+	for i := 0; ; i++ {
+		// evaluate condition with latest session variables
+		stepVariables, err := r.MergeStepVariables(s.step.Variables)
+		if err != nil {
+			return stepResult, err
+		}
+		ok, err := evalCondition(r.parser, loop.While, stepVariables)
+		if err != nil {
+			return stepResult, errors.Wrap(err, "eval loop condition failed")
+		}
+		if !ok {
+			log.Info().Str("while", loop.While).Int("iterations", i).Msg("loop condition is false, exit loop")
+			break
+		}
+		if i >= maxIterations {
+			err := fmt.Errorf("loop condition %s is still true after %d iterations", loop.While, maxIterations)
+			stepResult.Attachment = err.Error()
+			return stepResult, err
+		}
+		if i > 0 && loop.Interval > 0 {
+			if err := r.sleep(time.Duration(loop.Interval*1000) * time.Millisecond); err != nil {
+				stepResult.Attachment = err.Error()
+				return stepResult, err
+			}
+		}
+
+		log.Info().Str("while", loop.While).Int("iteration", i+1).Msg("run loop iteration")
+		if err := r.runStepGroup(s.steps); err != nil {
+			if r.hrpRunner.failfast {
+				stepResult.Attachment = err.Error()
+				return stepResult, errors.Wrap(err, "run loop step failed")
+			}
+			stepErr = err
+		}
+	}
+
+	if stepErr != nil {
+		stepResult.Attachment = stepErr.Error()
+		return stepResult, stepErr
+	}
+	stepResult.Success = true
+	return stepResult, nil
+}
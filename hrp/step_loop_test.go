@@ -0,0 +1,129 @@
+package hrp
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newPollingServer(doneAfter int32) *httptest.Server {
+	var counter int32
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		state := "running"
+		if atomic.AddInt32(&counter, 1) >= doneAfter {
+			state = "done"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"state": "%s", "count": %d}`, state, atomic.LoadInt32(&counter))
+	}))
+}
+
+func TestRunCaseWithLoop(t *testing.T) {
+	ts := newPollingServer(3)
+	defer ts.Close()
+
+	testcase := &TestCase{
+		Config: NewConfig("loop").
+			SetBaseURL(ts.URL).
+			WithVariables(map[string]interface{}{"state": "pending"}),
+		TestSteps: []IStep{
+			NewStep("poll job").
+				Loop("$state != done").
+				WithMaxIterations(5).
+				WithInterval(0.01).
+				WithSteps(
+					NewStep("get job status").
+						GET("/job").
+						Extract().
+						WithJmesPath("body.state", "state").
+						WithJmesPath("body.count", "count"),
+				),
+			NewStep("check job").
+				GET("/job").
+				WithParams(map[string]interface{}{"count": "$count"}).
+				Validate().
+				AssertEqual("body.state", "done", "check job state"),
+		},
+	}
+	runner := NewRunner(t).NewSessionRunner(testcase)
+	if !assert.Nil(t, runner.Start()) {
+		t.Fatal()
+	}
+	summary := runner.GetSummary()
+	// 3 polling requests, loop step and check step
+	assert.Equal(t, 5, summary.Stat.Total)
+	assert.Equal(t, int64(3), runner.sessionVariables["count"])
+}
+
+func TestRunCaseWithLoopExceeded(t *testing.T) {
+	ts := newPollingServer(10)
+	defer ts.Close()
+
+	testcase := &TestCase{
+		Config: NewConfig("loop").SetBaseURL(ts.URL),
+		TestSteps: []IStep{
+			NewStep("poll job").
+				WithVariables(map[string]interface{}{"state": "pending"}).
+				Loop("$state != done").
+				WithMaxIterations(2).
+				WithSteps(
+					NewStep("get job status").
+						GET("/job").
+						Extract().
+						WithJmesPath("body.state", "state"),
+				),
+		},
+	}
+	err := NewRunner(t).Run(testcase)
+	if !assert.NotNil(t, err) {
+		t.Fatal()
+	}
+	assert.Contains(t, err.Error(), "still true after 2 iterations")
+}
+
+func TestLoadCaseWithLoop(t *testing.T) {
+	ts := newPollingServer(2)
+	defer ts.Close()
+
+	content := fmt.Sprintf(`{
+	"config": {"name": "loop", "base_url": "%s", "variables": {"state": "pending"}},
+	"teststeps": [
+		{
+			"name": "poll job",
+			"loop": {
+				"while": "$state != done",
+				"max_iterations": 3,
+				"steps": [
+					{
+						"name": "get job status",
+						"request": {"method": "GET", "url": "/job"},
+						"extract": {"state": "body.state"},
+						"validate": [{"eq": ["status_code", 200]}]
+					}
+				]
+			}
+		}
+	]
+}`, ts.URL)
+	casePath := filepath.Join(t.TempDir(), "loop.json")
+	if err := os.WriteFile(casePath, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tc := TestCasePath(casePath)
+	testcase, err := tc.ToTestCase()
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	stepLoop, ok := testcase.TestSteps[0].(*StepLoop)
+	if !assert.True(t, ok) || !assert.Len(t, stepLoop.steps, 1) {
+		t.Fatal()
+	}
+	assert.Nil(t, NewRunner(t).Run(testcase))
+}
@@ -0,0 +1,330 @@
+package hrp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+type mqttAction string
+
+const (
+	mqttPublish   mqttAction = "publish"
+	mqttSubscribe mqttAction = "subscribe" // subscribe topic in advance, messages are buffered until received
+	mqttReceive   mqttAction = "receive"   // wait for message matching filter, topic is subscribed if not yet
+)
+
+// MQTT represents MQTT step, which publishes payload to topic, or subscribes topic and waits for message.
+// client is shared by MQTT steps with the same broker, client id and username in one session.
+type MQTT struct {
+	Broker   string         `json:"broker" yaml:"broker"` // required, e.g. tcp://127.0.0.1:1883, ssl://host:8883, ws://host:8083/mqtt
+	ClientID string         `json:"client_id,omitempty" yaml:"client_id,omitempty"`
+	Username string         `json:"username,omitempty" yaml:"username,omitempty"`
+	Password string         `json:"password,omitempty" yaml:"password,omitempty"`
+	Action   mqttAction     `json:"action" yaml:"action"` // required, publish/subscribe/receive
+	Topic    string         `json:"topic" yaml:"topic"`   // required, wildcards + and # are allowed for subscribe and receive
+	QoS      byte           `json:"qos,omitempty" yaml:"qos,omitempty"`
+	Retained bool           `json:"retained,omitempty" yaml:"retained,omitempty"` // publish retained message
+	Payload  interface{}    `json:"payload,omitempty" yaml:"payload,omitempty"`   // publish payload, map and slice are encoded as json
+	Filter   *MessageFilter `json:"filter,omitempty" yaml:"filter,omitempty"`     // receive the first message matching filter
+	Timeout  float64        `json:"timeout,omitempty" yaml:"timeout,omitempty"`   // seconds, default to 10
+}
+
+// MQTT creates a new MQTT step connected to broker.
+func (s *StepRequest) MQTT(broker string) *StepMQTT {
+	s.step.MQTT = &MQTT{
+		Broker: broker,
+	}
+	return &StepMQTT{
+		step: s.step,
+	}
+}
+
+// StepMQTT implements IStep interface.
+type StepMQTT struct {
+	step *TStep
+}
+
+// WithClientID sets MQTT client id, default to random id.
+func (s *StepMQTT) WithClientID(clientID string) *StepMQTT {
+	s.step.MQTT.ClientID = clientID
+	return s
+}
+
+// WithAuth sets username and password to connect to broker.
+func (s *StepMQTT) WithAuth(username, password string) *StepMQTT {
+	s.step.MQTT.Username = username
+	s.step.MQTT.Password = password
+	return s
+}
+
+// WithQoS sets QoS of publishing or subscribing, should be 0, 1 or 2.
+func (s *StepMQTT) WithQoS(qos byte) *StepMQTT {
+	s.step.MQTT.QoS = qos
+	return s
+}
+
+// WithTimeout sets timeout seconds of connecting, publishing and receiving.
+func (s *StepMQTT) WithTimeout(timeout float64) *StepMQTT {
+	s.step.MQTT.Timeout = timeout
+	return s
+}
+
+// Publish publishes payload to topic.
+func (s *StepMQTT) Publish(topic string, payload interface{}) *StepMQTT {
+	s.step.MQTT.Action = mqttPublish
+	s.step.MQTT.Topic = topic
+	s.step.MQTT.Payload = payload
+	return s
+}
+
+// WithRetained publishes payload as retained message.
+func (s *StepMQTT) WithRetained() *StepMQTT {
+	s.step.MQTT.Retained = true
+	return s
+}
+
+// Subscribe subscribes topic in advance, thus messages emitted by the following steps can be received.
+func (s *StepMQTT) Subscribe(topic string) *StepMQTT {
+	s.step.MQTT.Action = mqttSubscribe
+	s.step.MQTT.Topic = topic
+	return s
+}
+
+// Receive waits for message from topic, message is extracted and validated the same as response of request step.
+func (s *StepMQTT) Receive(topic string) *StepMQTT {
+	s.step.MQTT.Action = mqttReceive
+	s.step.MQTT.Topic = topic
+	return s
+}
+
+// WithFilter receives the first message whose value extracted by jmesPath equals to expected.
+func (s *StepMQTT) WithFilter(jmesPath string, expected interface{}) *StepMQTT {
+	s.step.MQTT.Filter = &MessageFilter{
+		JmesPath: jmesPath,
+		Expect:   expected,
+	}
+	return s
+}
+
+// Extract extracts variable from received message with jmesPath, e.g. payload.order_id
+func (s *StepMQTT) Extract(jmesPath string, varName string) *StepMQTT {
+	if s.step.Extract == nil {
+		s.step.Extract = make(map[string]string)
+	}
+	s.step.Extract[varName] = jmesPath
+	return s
+}
+
+// AssertEqual asserts value extracted from received message with jmesPath equals to expected.
+func (s *StepMQTT) AssertEqual(jmesPath string, expected interface{}, msg string) *StepMQTT {
+	s.step.Validators = append(s.step.Validators, Validator{
+		Check:   jmesPath,
+		Assert:  "equals",
+		Expect:  expected,
+		Message: msg,
+	})
+	return s
+}
+
+func (s *StepMQTT) Name() string {
+	if s.step.Name != "" {
+		return s.step.Name
+	}
+	return fmt.Sprintf("mqtt %s %s", s.step.MQTT.Action, s.step.MQTT.Topic)
+}
+
+func (s *StepMQTT) Type() StepType {
+	return stepTypeMQTT
+}
+
+func (s *StepMQTT) Struct() *TStep {
+	return s.step
+}
+
+func (s *StepMQTT) Run(r *SessionRunner) (*StepResult, error) {
+	stepResult := &StepResult{
+		Name:     s.Name(),
+		StepType: stepTypeMQTT,
+		Success:  false,
+	}
+	start := time.Now()
+	err := s.run(r, stepResult)
+	stepResult.Elapsed = time.Since(start).Milliseconds()
+	if err != nil {
+		stepResult.Attachment = err.Error()
+		return stepResult, err
+	}
+	stepResult.Success = true
+	return stepResult, nil
+}
+
+func (s *StepMQTT) run(r *SessionRunner, stepResult *StepResult) error {
+	stepVariables, err := r.MergeStepVariables(s.step.Variables)
+	if err != nil {
+		return err
+	}
+	cfg := s.step.MQTT
+	var fields [5]string
+	for i, raw := range []string{cfg.Broker, cfg.ClientID, cfg.Username, cfg.Password, cfg.Topic} {
+		parsed, err := r.parser.ParseString(raw, stepVariables)
+		if err != nil {
+			return err
+		}
+		fields[i] = convertString(parsed)
+	}
+	broker, clientID, username, password, topic := fields[0], fields[1], fields[2], fields[3], fields[4]
+	if broker == "" || topic == "" {
+		return errors.New("mqtt broker and topic should not be empty")
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultMessageTimeout
+	}
+	timeoutDuration := time.Duration(timeout*1000) * time.Millisecond
+	ctx, cancel := context.WithTimeout(r.ctx, timeoutDuration)
+	defer cancel()
+
+	key := strings.Join([]string{"mqtt", broker, clientID, username}, "|")
+	closer, err := r.client(key, func() (io.Closer, error) {
+		return connectMQTT(ctx, broker, clientID, username, password, timeoutDuration)
+	})
+	if err != nil {
+		return err
+	}
+	client := closer.(*mqttClient)
+
+	switch cfg.Action {
+	case mqttPublish:
+		payload, err := r.parser.Parse(cfg.Payload, stepVariables)
+		if err != nil {
+			return err
+		}
+		content, err := encodeMessagePayload(payload)
+		if err != nil {
+			return err
+		}
+		token := client.client.Publish(topic, cfg.QoS, cfg.Retained, content)
+		if err := waitMQTTToken(ctx, token); err != nil {
+			return errors.Wrapf(err, "publish to mqtt topic %s failed", topic)
+		}
+		log.Info().Str("topic", topic).Int("size", len(content)).Msg("publish mqtt message")
+		stepResult.ContentSize = int64(len(content))
+		stepResult.Data = map[string]interface{}{"topic": topic, "payload": payload}
+		return nil
+	case mqttSubscribe:
+		return client.subscribe(ctx, topic, cfg.QoS)
+	case mqttReceive:
+		if err := client.subscribe(ctx, topic, cfg.QoS); err != nil {
+			return err
+		}
+		matchFilter, err := r.messageMatcher(cfg.Filter, stepVariables)
+		if err != nil {
+			return err
+		}
+		message, err := client.buffer.wait(ctx, func(message map[string]interface{}) bool {
+			return mqttTopicMatch(topic, message["topic"].(string)) && matchFilter(message)
+		})
+		if err != nil {
+			return errors.Wrapf(err, "receive from mqtt topic %s failed", topic)
+		}
+		log.Info().Str("topic", topic).Interface("message", message).Msg("receive mqtt message")
+		return r.checkOutput(s.step, message, stepVariables, stepResult)
+	default:
+		return fmt.Errorf("unexpected mqtt action %s, should be publish, subscribe or receive", cfg.Action)
+	}
+}
+
+// mqttClient is MQTT client of session, received messages of subscribed topics are buffered.
+type mqttClient struct {
+	client     mqtt.Client
+	buffer     *messageBuffer
+	mutex      sync.Mutex
+	subscribed map[string]bool
+}
+
+func connectMQTT(ctx context.Context, broker, clientID, username, password string, timeout time.Duration) (*mqttClient, error) {
+	if clientID == "" {
+		clientID = "hrp-" + uuid.NewString()
+	}
+	opts := mqtt.NewClientOptions().
+		AddBroker(broker).
+		SetClientID(clientID).
+		SetUsername(username).
+		SetPassword(password).
+		SetConnectTimeout(timeout).
+		SetAutoReconnect(false)
+	client := mqtt.NewClient(opts)
+	if err := waitMQTTToken(ctx, client.Connect()); err != nil {
+		return nil, errors.Wrapf(err, "connect to mqtt broker %s failed", broker)
+	}
+	log.Info().Str("broker", broker).Str("clientID", clientID).Msg("connect to mqtt broker")
+	return &mqttClient{
+		client:     client,
+		buffer:     newMessageBuffer(),
+		subscribed: make(map[string]bool),
+	}, nil
+}
+
+func (c *mqttClient) subscribe(ctx context.Context, topic string, qos byte) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.subscribed[topic] {
+		return nil
+	}
+	token := c.client.Subscribe(topic, qos, func(_ mqtt.Client, msg mqtt.Message) {
+		c.buffer.push(map[string]interface{}{
+			"topic":    msg.Topic(),
+			"payload":  decodeMessagePayload(msg.Payload()),
+			"qos":      msg.Qos(),
+			"retained": msg.Retained(),
+		})
+	})
+	if err := waitMQTTToken(ctx, token); err != nil {
+		return errors.Wrapf(err, "subscribe mqtt topic %s failed", topic)
+	}
+	c.subscribed[topic] = true
+	log.Info().Str("topic", topic).Msg("subscribe mqtt topic")
+	return nil
+}
+
+func (c *mqttClient) Close() error {
+	c.client.Disconnect(250)
+	return nil
+}
+
+// waitMQTTToken waits for token to complete, or ctx is done when step times out or run is canceled.
+func waitMQTTToken(ctx context.Context, token mqtt.Token) error {
+	select {
+	case <-token.Done():
+		return token.Error()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// mqttTopicMatch checks if topic matches topic filter with wildcards, + matches one level and # matches the rest.
+func mqttTopicMatch(filter, topic string) bool {
+	filterLevels := strings.Split(filter, "/")
+	topicLevels := strings.Split(topic, "/")
+	for i, level := range filterLevels {
+		if level == "#" {
+			return true
+		}
+		if i >= len(topicLevels) {
+			return false
+		}
+		if level != "+" && level != topicLevels[i] {
+			return false
+		}
+	}
+	return len(filterLevels) == len(topicLevels)
+}
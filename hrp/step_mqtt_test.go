@@ -0,0 +1,140 @@
+package hrp
+
+import (
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/eclipse/paho.mqtt.golang/packets"
+	"github.com/stretchr/testify/assert"
+)
+
+// startMQTTBroker starts a minimal MQTT broker for testing, messages are forwarded to subscribers with QoS 0.
+func startMQTTBroker(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	var mutex sync.Mutex
+	subscriptions := make(map[net.Conn][]string)
+	write := func(conn net.Conn, packet packets.ControlPacket) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		_ = packet.Write(conn)
+	}
+	handle := func(conn net.Conn) {
+		defer func() {
+			mutex.Lock()
+			delete(subscriptions, conn)
+			mutex.Unlock()
+			conn.Close()
+		}()
+		for {
+			packet, err := packets.ReadPacket(conn)
+			if err != nil {
+				return
+			}
+			switch p := packet.(type) {
+			case *packets.ConnectPacket:
+				write(conn, packets.NewControlPacket(packets.Connack))
+			case *packets.SubscribePacket:
+				mutex.Lock()
+				subscriptions[conn] = append(subscriptions[conn], p.Topics...)
+				mutex.Unlock()
+				suback := packets.NewControlPacket(packets.Suback).(*packets.SubackPacket)
+				suback.MessageID = p.MessageID
+				suback.ReturnCodes = make([]byte, len(p.Topics))
+				write(conn, suback)
+			case *packets.PublishPacket:
+				if p.Qos > 0 {
+					puback := packets.NewControlPacket(packets.Puback).(*packets.PubackPacket)
+					puback.MessageID = p.MessageID
+					write(conn, puback)
+				}
+				forward := p.Copy()
+				forward.Qos = 0
+				mutex.Lock()
+				var subscribers []net.Conn
+				for subscriber, topics := range subscriptions {
+					for _, topic := range topics {
+						if mqttTopicMatch(topic, p.TopicName) {
+							subscribers = append(subscribers, subscriber)
+							break
+						}
+					}
+				}
+				mutex.Unlock()
+				for _, subscriber := range subscribers {
+					write(subscriber, forward)
+				}
+			case *packets.PingreqPacket:
+				write(conn, packets.NewControlPacket(packets.Pingresp))
+			case *packets.DisconnectPacket:
+				return
+			}
+		}
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go handle(conn)
+		}
+	}()
+	return "tcp://" + listener.Addr().String()
+}
+
+func TestRunCaseWithMQTT(t *testing.T) {
+	broker := startMQTTBroker(t)
+	testcase := &TestCase{
+		Config: NewConfig("mqtt pipeline").
+			WithVariables(map[string]interface{}{"broker": broker, "device": "sensor-1"}),
+		TestSteps: []IStep{
+			NewStep("subscribe events").MQTT("$broker").Subscribe("events/+"),
+			NewStep("publish first").MQTT("$broker").WithQoS(1).
+				Publish("events/$device", map[string]interface{}{"seq": 1, "device": "$device"}),
+			NewStep("publish second").MQTT("$broker").
+				Publish("events/$device", map[string]interface{}{"seq": 2, "device": "$device"}),
+			NewStep("receive second").MQTT("$broker").
+				Receive("events/+").
+				WithFilter("payload.seq", 2).
+				Extract("payload.device", "received_device").
+				AssertEqual("topic", "events/sensor-1", "check topic").
+				AssertEqual("payload.seq", 2, "check seq"),
+			NewStep("receive first").MQTT("$broker").
+				Receive("events/#").
+				AssertEqual("payload.seq", 1, "check buffered message"),
+		},
+	}
+	sessionRunner := NewRunner(t).NewSessionRunner(testcase)
+	if !assert.Nil(t, sessionRunner.Start()) {
+		t.Fatal()
+	}
+	assert.Equal(t, "sensor-1", sessionRunner.sessionVariables["received_device"])
+	assert.Empty(t, sessionRunner.clients) // closed after session ends
+}
+
+func TestRunCaseWithMQTTReceiveTimeout(t *testing.T) {
+	broker := startMQTTBroker(t)
+	testcase := &TestCase{
+		Config: NewConfig("mqtt timeout"),
+		TestSteps: []IStep{
+			NewStep("receive nothing").MQTT(broker).WithTimeout(0.2).Receive("events/none"),
+		},
+	}
+	assert.NotNil(t, NewRunner(nil).Run(testcase))
+}
+
+func TestMQTTTopicMatch(t *testing.T) {
+	assert.True(t, mqttTopicMatch("a/b", "a/b"))
+	assert.True(t, mqttTopicMatch("a/+", "a/b"))
+	assert.True(t, mqttTopicMatch("a/#", "a/b/c"))
+	assert.True(t, mqttTopicMatch("#", "a"))
+	assert.False(t, mqttTopicMatch("a/+", "a/b/c"))
+	assert.False(t, mqttTopicMatch("a/b/c", "a/b"))
+	assert.False(t, mqttTopicMatch("a/b", "a/c"))
+}
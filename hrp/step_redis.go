@@ -0,0 +1,216 @@
+package hrp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+const defaultRedisInstance = "default"
+
+// RedisConfig represents redis instance configured in testcase config, which is used by redis steps.
+type RedisConfig struct {
+	Addr     string `json:"addr" yaml:"addr"`                             // required, variables and functions are supported, e.g. 127.0.0.1:6379
+	Password string `json:"password,omitempty" yaml:"password,omitempty"` // variables and functions are supported
+	DB       int    `json:"db,omitempty" yaml:"db,omitempty"`
+}
+
+// SetRedis configures redis instance with name for redis steps, e.g. ("default", "127.0.0.1:6379", "", 0).
+func (c *TConfig) SetRedis(name, addr, password string, db int) *TConfig {
+	if c.Redis == nil {
+		c.Redis = make(map[string]*RedisConfig)
+	}
+	c.Redis[name] = &RedisConfig{
+		Addr:     addr,
+		Password: password,
+		DB:       db,
+	}
+	return c
+}
+
+// RedisCommand represents redis step, which executes command against redis instance configured in testcase config,
+// e.g. ["GET", "user:$uid"], result is exposed as result and validated the same as response of request step.
+type RedisCommand struct {
+	Instance string        `json:"instance,omitempty" yaml:"instance,omitempty"` // redis instance name in config, default to "default"
+	Command  []interface{} `json:"command" yaml:"command"`                       // required, command name and arguments, variables and functions are supported
+	Timeout  float64       `json:"timeout,omitempty" yaml:"timeout,omitempty"`   // seconds, default to 10
+}
+
+// Redis creates a new redis step, which executes command against redis instance configured with name in testcase config.
+func (s *StepRequest) Redis(instance string) *StepRedis {
+	s.step.Redis = &RedisCommand{
+		Instance: instance,
+	}
+	return &StepRedis{
+		step: s.step,
+	}
+}
+
+// StepRedis implements IStep interface.
+type StepRedis struct {
+	step *TStep
+}
+
+// Command sets redis command name and arguments, e.g. ("HGETALL", "user:1").
+func (s *StepRedis) Command(args ...interface{}) *StepRedis {
+	s.step.Redis.Command = args
+	return s
+}
+
+// WithTimeout sets timeout seconds of command.
+func (s *StepRedis) WithTimeout(timeout float64) *StepRedis {
+	s.step.Redis.Timeout = timeout
+	return s
+}
+
+// Extract extracts variable from command result with jmesPath, e.g. result.name
+func (s *StepRedis) Extract(jmesPath string, varName string) *StepRedis {
+	if s.step.Extract == nil {
+		s.step.Extract = make(map[string]string)
+	}
+	s.step.Extract[varName] = jmesPath
+	return s
+}
+
+// AssertEqual asserts value extracted from command result with jmesPath equals to expected.
+func (s *StepRedis) AssertEqual(jmesPath string, expected interface{}, msg string) *StepRedis {
+	s.step.Validators = append(s.step.Validators, Validator{
+		Check:   jmesPath,
+		Assert:  "equals",
+		Expect:  expected,
+		Message: msg,
+	})
+	return s
+}
+
+func (s *StepRedis) Name() string {
+	if s.step.Name != "" {
+		return s.step.Name
+	}
+	return fmt.Sprintf("redis %v", s.step.Redis.Command)
+}
+
+func (s *StepRedis) Type() StepType {
+	return stepTypeRedis
+}
+
+func (s *StepRedis) Struct() *TStep {
+	return s.step
+}
+
+func (s *StepRedis) Run(r *SessionRunner) (*StepResult, error) {
+	stepResult := &StepResult{
+		Name:     s.Name(),
+		StepType: stepTypeRedis,
+		Success:  false,
+	}
+	start := time.Now()
+	err := s.run(r, stepResult)
+	stepResult.Elapsed = time.Since(start).Milliseconds()
+	if err != nil {
+		stepResult.Attachment = err.Error()
+		return stepResult, err
+	}
+	stepResult.Success = true
+	return stepResult, nil
+}
+
+func (s *StepRedis) run(r *SessionRunner, stepResult *StepResult) error {
+	stepVariables, err := r.MergeStepVariables(s.step.Variables)
+	if err != nil {
+		return err
+	}
+	cmd := s.step.Redis
+	if len(cmd.Command) == 0 {
+		return errors.New("redis command should not be empty")
+	}
+	name := cmd.Instance
+	if name == "" {
+		name = defaultRedisInstance
+	}
+	redisConfig, ok := r.testCase.Config.Redis[name]
+	if !ok {
+		return fmt.Errorf("redis instance %s is not configured in testcase config", name)
+	}
+	addr, err := r.parser.ParseString(redisConfig.Addr, stepVariables)
+	if err != nil {
+		return err
+	}
+	password, err := r.parser.ParseString(redisConfig.Password, stepVariables)
+	if err != nil {
+		return err
+	}
+	parsedArgs, err := r.parser.Parse(cmd.Command, stepVariables)
+	if err != nil {
+		return err
+	}
+	args := parsedArgs.([]interface{})
+
+	key := strings.Join([]string{"redis", convertString(addr), strconv.Itoa(redisConfig.DB)}, "|")
+	closer, err := r.client(key, func() (io.Closer, error) {
+		return redis.NewClient(&redis.Options{
+			Addr:     convertString(addr),
+			Password: convertString(password),
+			DB:       redisConfig.DB,
+		}), nil
+	})
+	if err != nil {
+		return err
+	}
+	client := closer.(*redis.Client)
+
+	timeout := cmd.Timeout
+	if timeout <= 0 {
+		timeout = defaultMessageTimeout
+	}
+	ctx, cancel := context.WithTimeout(r.ctx, time.Duration(timeout*1000)*time.Millisecond)
+	defer cancel()
+
+	result, err := client.Do(ctx, args...).Result()
+	if err == redis.Nil {
+		result, err = nil, nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "execute redis command %v failed", args)
+	}
+	result = convertRedisResult(args[0], result)
+	log.Info().Str("instance", name).Interface("command", args).
+		Interface("result", result).Msg("execute redis command")
+
+	output := map[string]interface{}{
+		"command": args,
+		"result":  result,
+	}
+	return r.checkOutput(s.step, output, stepVariables, stepResult)
+}
+
+// convertRedisResult converts command reply to json compatible value,
+// flat field-value reply of HGETALL and CONFIG GET is converted to map.
+func convertRedisResult(command interface{}, result interface{}) interface{} {
+	values, ok := result.([]interface{})
+	if !ok {
+		return result
+	}
+	for i, value := range values {
+		values[i] = convertRedisResult(nil, value)
+	}
+	switch strings.ToUpper(fmt.Sprint(command)) {
+	case "HGETALL", "CONFIG":
+		if len(values)%2 != 0 {
+			return values
+		}
+		fields := make(map[string]interface{}, len(values)/2)
+		for i := 0; i < len(values); i += 2 {
+			fields[fmt.Sprint(values[i])] = values[i+1]
+		}
+		return fields
+	}
+	return values
+}
@@ -0,0 +1,80 @@
+package hrp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunCaseWithRedis(t *testing.T) {
+	s, err := miniredis.Run()
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	defer s.Close()
+	s.Set("user:1:token", "abc")
+	s.SetTTL("user:1:token", 60*time.Second)
+	s.HSet("user:1", "name", "debugtalk", "level", "3")
+
+	testcase := &TestCase{
+		Config: NewConfig("redis assertion").
+			SetRedis("cache", "$redis_addr", "", 0).
+			WithVariables(map[string]interface{}{"redis_addr": s.Addr(), "uid": 1}),
+		TestSteps: []IStep{
+			NewStep("get token").Redis("cache").
+				Command("GET", "user:${uid}:token").
+				Extract("result", "token").
+				AssertEqual("result", "abc", "check cached value"),
+			NewStep("get ttl").Redis("cache").
+				Command("TTL", "user:${uid}:token").
+				AssertEqual("result", 60, "check ttl"),
+			NewStep("get user").Redis("cache").
+				Command("HGETALL", "user:$uid").
+				AssertEqual("result.name", "debugtalk", "check hash field"),
+			NewStep("missing key").Redis("cache").
+				Command("GET", "user:2:token").
+				AssertEqual("result", nil, "check invalidated key"),
+		},
+	}
+	sessionRunner := NewRunner(t).NewSessionRunner(testcase)
+	if !assert.Nil(t, sessionRunner.Start()) {
+		t.Fatal()
+	}
+	assert.Equal(t, "abc", sessionRunner.sessionVariables["token"])
+}
+
+func TestRunCaseWithRedisNotConfigured(t *testing.T) {
+	testcase := &TestCase{
+		Config: NewConfig("redis not configured"),
+		TestSteps: []IStep{
+			NewStep("get").Redis("").Command("GET", "key"),
+		},
+	}
+	assert.NotNil(t, NewRunner(nil).Run(testcase))
+}
+
+func TestRunCaseWithRedisCanceled(t *testing.T) {
+	s, err := miniredis.Run()
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	defer s.Close()
+
+	testcase := &TestCase{
+		Config: NewConfig("redis canceled").SetRedis("cache", s.Addr(), "", 0),
+		TestSteps: []IStep{
+			// blocks until timeout of step if not canceled
+			NewStep("wait task").Redis("cache").
+				Command("BLPOP", "tasks", 0).
+				WithTimeout(10),
+		},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	assert.NotNil(t, NewRunner(t).RunWithContext(ctx, testcase))
+	assert.Less(t, int64(time.Since(start)), int64(5*time.Second))
+}
@@ -2,26 +2,33 @@ package hrp
 
 import (
 	"bytes"
-	"compress/gzip"
-	"compress/zlib"
+	"context"
+	"crypto/tls"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
-	"net/http/httputil"
+	"net/http/httptrace"
 	"net/url"
 	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/andybalholm/brotli"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
+	"golang.org/x/text/encoding/htmlindex"
 
 	"github.com/httprunner/httprunner/hrp/internal/builtin"
 	"github.com/httprunner/httprunner/hrp/internal/json"
 )
 
+// HTTPMethod is method of HTTP request, methods other than the predefined ones are also allowed,
+// e.g. WebDAV methods PROPFIND, MKCOL, or vendor methods like PURGE.
 type HTTPMethod string
 
 const (
@@ -34,28 +41,38 @@ const (
 	httpPATCH   HTTPMethod = "PATCH"
 )
 
+// HeaderField is one header line in ordered header list, name is sent as is without canonicalization.
+type HeaderField struct {
+	Name  string `json:"name" yaml:"name"`
+	Value string `json:"value" yaml:"value"`
+}
+
 // Request represents HTTP request data structure.
 // This is used for teststep.
 type Request struct {
 	Method         HTTPMethod             `json:"method" yaml:"method"` // required
 	URL            string                 `json:"url" yaml:"url"`       // required
 	Params         map[string]interface{} `json:"params,omitempty" yaml:"params,omitempty"`
+	ParamsOrder    []string               `json:"params_order,omitempty" yaml:"params_order,omitempty"`     // keys of params encoded first in order, the rest are sorted
+	ParamsEncoded  bool                   `json:"params_encoded,omitempty" yaml:"params_encoded,omitempty"` // params are pre-encoded and appended as is
+	RawURL         bool                   `json:"raw_url,omitempty" yaml:"raw_url,omitempty"`               // send url as is without normalization or re-encoding
 	Headers        map[string]string      `json:"headers,omitempty" yaml:"headers,omitempty"`
+	HeaderList     []HeaderField          `json:"header_list,omitempty" yaml:"header_list,omitempty"` // ordered headers, duplicate names allowed
 	Cookies        map[string]string      `json:"cookies,omitempty" yaml:"cookies,omitempty"`
 	Body           interface{}            `json:"body,omitempty" yaml:"body,omitempty"`
+	BodyFile       string                 `json:"body_file,omitempty" yaml:"body_file,omitempty"` // template file rendered as body at run time
+	BodyProtobuf   *ProtobufBody          `json:"body_protobuf,omitempty" yaml:"body_protobuf,omitempty"`
 	Json           interface{}            `json:"json,omitempty" yaml:"json,omitempty"`
 	Data           interface{}            `json:"data,omitempty" yaml:"data,omitempty"`
 	Timeout        float32                `json:"timeout,omitempty" yaml:"timeout,omitempty"`
 	AllowRedirects bool                   `json:"allow_redirects,omitempty" yaml:"allow_redirects,omitempty"`
 	Verify         bool                   `json:"verify,omitempty" yaml:"verify,omitempty"`
+
+	compiled     *compiledRequest // constant fields resolved at testcase load time
+	bodyFilePath string           // body file path resolved against project root dir
 }
 
 func newRequestBuilder(parser *Parser, config *TConfig, stepRequest *Request) *requestBuilder {
-	// convert request struct to map
-	jsonRequest, _ := json.Marshal(stepRequest)
-	var requestMap map[string]interface{}
-	_ = json.Unmarshal(jsonRequest, &requestMap)
-
 	return &requestBuilder{
 		stepRequest: stepRequest,
 		req: &http.Request{
@@ -66,8 +83,63 @@ func newRequestBuilder(parser *Parser, config *TConfig, stepRequest *Request) *r
 		},
 		config:     config,
 		parser:     parser,
-		requestMap: requestMap,
+		requestMap: newRequestMap(stepRequest),
+	}
+}
+
+// newRequestMap converts request struct to map recorded in session data, which is the same as json tags of Request.
+// static fields are referenced directly instead of marshaling/unmarshaling the whole request every execution,
+// params, headers and body are replaced with parsed values when request is prepared.
+func newRequestMap(stepRequest *Request) map[string]interface{} {
+	requestMap := make(map[string]interface{}, 8)
+	requestMap["method"] = string(stepRequest.Method)
+	requestMap["url"] = stepRequest.URL
+	if len(stepRequest.Params) > 0 {
+		requestMap["params"] = stepRequest.Params
+	}
+	if len(stepRequest.ParamsOrder) > 0 {
+		requestMap["params_order"] = stepRequest.ParamsOrder
+	}
+	if stepRequest.ParamsEncoded {
+		requestMap["params_encoded"] = true
+	}
+	if stepRequest.RawURL {
+		requestMap["raw_url"] = true
+	}
+	if len(stepRequest.Headers) > 0 {
+		requestMap["headers"] = stepRequest.Headers
+	}
+	if len(stepRequest.HeaderList) > 0 {
+		requestMap["header_list"] = stepRequest.HeaderList
+	}
+	if len(stepRequest.Cookies) > 0 {
+		requestMap["cookies"] = stepRequest.Cookies
+	}
+	if stepRequest.Body != nil {
+		requestMap["body"] = stepRequest.Body
+	}
+	if stepRequest.BodyFile != "" {
+		requestMap["body_file"] = stepRequest.BodyFile
+	}
+	if stepRequest.BodyProtobuf != nil {
+		requestMap["body_protobuf"] = stepRequest.BodyProtobuf
+	}
+	if stepRequest.Json != nil {
+		requestMap["json"] = stepRequest.Json
+	}
+	if stepRequest.Data != nil {
+		requestMap["data"] = stepRequest.Data
+	}
+	if stepRequest.Timeout != 0 {
+		requestMap["timeout"] = stepRequest.Timeout
+	}
+	if stepRequest.AllowRedirects {
+		requestMap["allow_redirects"] = true
 	}
+	if stepRequest.Verify {
+		requestMap["verify"] = true
+	}
+	return requestMap
 }
 
 type requestBuilder struct {
@@ -80,17 +152,34 @@ type requestBuilder struct {
 
 func (r *requestBuilder) prepareHeaders(stepVariables map[string]interface{}) error {
 	// prepare request headers
-	stepHeaders := r.stepRequest.Headers
-	if r.config.Headers != nil {
-		// override headers
-		stepHeaders = mergeMap(stepHeaders, r.config.Headers)
+	compiled := r.stepRequest.compiled
+	var headers map[string]string
+	if compiled != nil && compiled.headers != nil {
+		headers = compiled.headers
+		if r.config.Headers != nil {
+			// override headers
+			configHeaders, err := r.parser.ParseHeaders(r.config.Headers, stepVariables)
+			if err != nil {
+				return errors.Wrap(err, "parse headers failed")
+			}
+			headers = mergeMap(headers, configHeaders)
+		}
+	} else {
+		stepHeaders := r.stepRequest.Headers
+		if r.config.Headers != nil {
+			// override headers
+			stepHeaders = mergeMap(stepHeaders, r.config.Headers)
+		}
+		if len(stepHeaders) > 0 {
+			var err error
+			headers, err = r.parser.ParseHeaders(stepHeaders, stepVariables)
+			if err != nil {
+				return errors.Wrap(err, "parse headers failed")
+			}
+		}
 	}
 
-	if len(stepHeaders) > 0 {
-		headers, err := r.parser.ParseHeaders(stepHeaders, stepVariables)
-		if err != nil {
-			return errors.Wrap(err, "parse headers failed")
-		}
+	if len(headers) > 0 {
 		for key, value := range headers {
 			// omit pseudo header names for HTTP/1, e.g. :authority, :method, :path, :scheme
 			if strings.HasPrefix(key, ":") {
@@ -107,7 +196,85 @@ func (r *requestBuilder) prepareHeaders(stepVariables map[string]interface{}) er
 		}
 	}
 
+	// prepare ordered header list
+	if err := r.prepareHeaderList(stepVariables); err != nil {
+		return err
+	}
+
+	// inject generated idempotency key if key header is not specified
+	if cfg := r.config.IdempotencyKey; cfg != nil && cfg.applies(r.config, r.stepRequest) {
+		if key, ok := stepVariables[cfg.variable()]; ok {
+			r.req.Header.Set(cfg.header(), convertString(key))
+		}
+	}
+
+	// attach correlation id if id header is not specified
+	if cfg := r.config.CorrelationID; cfg != nil && !hasExplicitHeader(r.config, r.stepRequest, cfg.header()) {
+		if id, ok := stepVariables[cfg.variable()]; ok {
+			r.req.Header.Set(cfg.header(), convertString(id))
+		}
+	}
+
+	// rotate User-Agent profiles if User-Agent is not specified
+	if r.config.UserAgent != nil && !hasHeader(r.req.Header, "User-Agent") {
+		if userAgent := r.config.UserAgent.next(); userAgent != "" {
+			r.req.Header.Set("User-Agent", userAgent)
+		}
+	}
+
 	// prepare request cookies
+	if compiled != nil && compiled.cookies != nil {
+		for cookieName, cookieValue := range compiled.cookies {
+			r.req.AddCookie(&http.Cookie{
+				Name:  cookieName,
+				Value: cookieValue,
+			})
+		}
+	} else if err := r.prepareCookies(stepVariables); err != nil {
+		return err
+	}
+
+	// update header
+	headers = make(map[string]string)
+	for key, value := range r.req.Header {
+		headers[key] = value[0]
+	}
+	r.requestMap["headers"] = headers
+	return nil
+}
+
+// hasHeader checks if header is set, including names not canonicalized in ordered header list.
+func hasHeader(header http.Header, name string) bool {
+	for key := range header {
+		if strings.EqualFold(key, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// prepareHeaderList appends headers in declared order, duplicate names are sent as separate header lines.
+// names are kept as is without canonicalization, values of the same name are sent in declared order,
+// while net/http writes different names sorted.
+func (r *requestBuilder) prepareHeaderList(stepVariables map[string]interface{}) error {
+	if len(r.stepRequest.HeaderList) == 0 {
+		return nil
+	}
+	headerList := make([]HeaderField, 0, len(r.stepRequest.HeaderList))
+	for _, field := range r.stepRequest.HeaderList {
+		value, err := r.parser.Parse(field.Value, stepVariables)
+		if err != nil {
+			return errors.Wrap(err, "parse header list failed")
+		}
+		parsedField := HeaderField{Name: field.Name, Value: convertString(value)}
+		headerList = append(headerList, parsedField)
+		r.req.Header[parsedField.Name] = append(r.req.Header[parsedField.Name], parsedField.Value)
+	}
+	r.requestMap["header_list"] = headerList
+	return nil
+}
+
+func (r *requestBuilder) prepareCookies(stepVariables map[string]interface{}) error {
 	for cookieName, cookieValue := range r.stepRequest.Cookies {
 		value, err := r.parser.Parse(cookieValue, stepVariables)
 		if err != nil {
@@ -118,44 +285,49 @@ func (r *requestBuilder) prepareHeaders(stepVariables map[string]interface{}) er
 			Value: fmt.Sprintf("%v", value),
 		})
 	}
-
-	// update header
-	headers := make(map[string]string)
-	for key, value := range r.req.Header {
-		headers[key] = value[0]
-	}
-	r.requestMap["headers"] = headers
 	return nil
 }
 
 func (r *requestBuilder) prepareUrlParams(stepVariables map[string]interface{}) error {
+	compiled := r.stepRequest.compiled
+
 	// parse step request url
-	requestUrl, err := r.parser.ParseString(r.stepRequest.URL, stepVariables)
-	if err != nil {
-		log.Error().Err(err).Msg("parse request url failed")
-		return err
+	var stepUrl string
+	if compiled != nil && compiled.urlConstant {
+		stepUrl = compiled.url
+	} else {
+		requestUrl, err := r.parser.ParseString(r.stepRequest.URL, stepVariables)
+		if err != nil {
+			log.Error().Err(err).Msg("parse request url failed")
+			return err
+		}
+		stepUrl = convertString(requestUrl)
+	}
+	var rawUrl string
+	if r.stepRequest.RawURL {
+		rawUrl = joinRawURL(r.config.BaseURL, stepUrl)
+	} else {
+		rawUrl = buildURL(r.config.BaseURL, stepUrl)
 	}
-	rawUrl := buildURL(r.config.BaseURL, convertString(requestUrl))
 
 	// prepare request params
-	var queryParams url.Values
+	var paramStr string
 	if len(r.stepRequest.Params) > 0 {
-		params, err := r.parser.Parse(r.stepRequest.Params, stepVariables)
-		if err != nil {
-			return errors.Wrap(err, "parse request params failed")
-		}
-		parsedParams := params.(map[string]interface{})
-		r.requestMap["params"] = parsedParams
-		if len(parsedParams) > 0 {
-			queryParams = make(url.Values)
-			for k, v := range parsedParams {
-				queryParams.Add(k, fmt.Sprint(v))
+		var parsedParams map[string]interface{}
+		if compiled != nil && compiled.params != nil {
+			parsedParams = compiled.params
+		} else {
+			params, err := r.parser.Parse(r.stepRequest.Params, stepVariables)
+			if err != nil {
+				return errors.Wrap(err, "parse request params failed")
 			}
+			parsedParams = params.(map[string]interface{})
 		}
+		r.requestMap["params"] = parsedParams
+		paramStr = encodeParams(parsedParams, r.stepRequest.ParamsOrder, r.stepRequest.ParamsEncoded)
 	}
-	if queryParams != nil {
+	if paramStr != "" {
 		// append params to url
-		paramStr := queryParams.Encode()
 		if strings.IndexByte(rawUrl, '?') == -1 {
 			rawUrl = rawUrl + "?" + paramStr
 		} else {
@@ -164,7 +336,13 @@ func (r *requestBuilder) prepareUrlParams(stepVariables map[string]interface{})
 	}
 
 	// prepare url
-	u, err := url.Parse(rawUrl)
+	var u *url.URL
+	var err error
+	if r.stepRequest.RawURL {
+		u, err = parseRawURL(rawUrl)
+	} else {
+		u, err = url.Parse(rawUrl)
+	}
 	if err != nil {
 		return errors.Wrap(err, "parse url failed")
 	}
@@ -174,15 +352,140 @@ func (r *requestBuilder) prepareUrlParams(stepVariables map[string]interface{})
 	return nil
 }
 
+// joinRawURL joins base url and step url without cleaning path, e.g. .. and double slashes are kept.
+func joinRawURL(baseURL, stepURL string) string {
+	if strings.Contains(stepURL, "://") || baseURL == "" {
+		return stepURL
+	}
+	if strings.HasSuffix(baseURL, "/") && strings.HasPrefix(stepURL, "/") {
+		baseURL = baseURL[:len(baseURL)-1]
+	}
+	return baseURL + stepURL
+}
+
+// parseRawURL parses scheme and host of url, and keeps path and query in opaque form,
+// thus they are sent as is without normalization or re-encoding, e.g. %2F, .., double slashes.
+func parseRawURL(rawURL string) (*url.URL, error) {
+	i := strings.Index(rawURL, "://")
+	if i <= 0 {
+		return nil, fmt.Errorf("raw url should be absolute: %s", rawURL)
+	}
+	u := &url.URL{Scheme: rawURL[:i]}
+	rest := rawURL[i+3:]
+	end := strings.IndexAny(rest, "/?")
+	if end == -1 {
+		end = len(rest)
+	}
+	u.Host, rest = rest[:end], rest[end:]
+	if u.Host == "" {
+		return nil, fmt.Errorf("missing host in raw url: %s", rawURL)
+	}
+	if q := strings.IndexByte(rest, '?'); q >= 0 {
+		rest, u.RawQuery = rest[:q], rest[q+1:]
+	}
+	if rest == "" {
+		rest = "/"
+	}
+	if strings.HasPrefix(rest, "//") {
+		// opaque starting with // is sent in absolute form, e.g. GET http://host//path
+		u.Opaque = "//" + u.Host + rest
+	} else {
+		u.Opaque = rest
+	}
+	return u, nil
+}
+
+// displayURL returns full url for logging and recording, which is also valid for raw url in opaque form.
+func displayURL(u *url.URL) string {
+	if strings.HasPrefix(u.Opaque, "/") && !strings.HasPrefix(u.Opaque, "//") {
+		displayed := u.Scheme + "://" + u.Host + u.Opaque
+		if u.RawQuery != "" {
+			displayed += "?" + u.RawQuery
+		}
+		return displayed
+	}
+	return u.String()
+}
+
+// encodeParams encodes params into query string, slice values produce repeated keys, e.g. id=1&id=2.
+// keys listed in order are encoded first in the specified order, and the rest are sorted by key.
+// pre-encoded keys and values are appended as is to avoid double-encoding.
+func encodeParams(params map[string]interface{}, order []string, encoded bool) string {
+	keys := make([]string, 0, len(params))
+	ordered := make(map[string]bool, len(order))
+	for _, key := range order {
+		if _, ok := params[key]; ok && !ordered[key] {
+			keys = append(keys, key)
+			ordered[key] = true
+		}
+	}
+	rest := make([]string, 0, len(params)-len(keys))
+	for key := range params {
+		if !ordered[key] {
+			rest = append(rest, key)
+		}
+	}
+	sort.Strings(rest)
+	keys = append(keys, rest...)
+
+	escape := url.QueryEscape
+	if encoded {
+		escape = func(s string) string { return s }
+	}
+	var buf strings.Builder
+	for _, key := range keys {
+		for _, value := range paramValues(params[key]) {
+			if buf.Len() > 0 {
+				buf.WriteByte('&')
+			}
+			buf.WriteString(escape(key))
+			buf.WriteByte('=')
+			buf.WriteString(escape(value))
+		}
+	}
+	return buf.String()
+}
+
+// paramValues converts param value to strings, each element of slice is a repeated value.
+func paramValues(value interface{}) []string {
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return []string{fmt.Sprint(value)}
+	}
+	if _, ok := value.([]byte); ok {
+		return []string{string(value.([]byte))}
+	}
+	values := make([]string, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		values[i] = fmt.Sprint(rv.Index(i).Interface())
+	}
+	return values
+}
+
 func (r *requestBuilder) prepareBody(stepVariables map[string]interface{}) error {
 	// prepare request body
-	if r.stepRequest.Body == nil {
+	if r.stepRequest.BodyProtobuf != nil {
+		return r.prepareProtobufBody(stepVariables)
+	}
+	if r.stepRequest.Body == nil && r.stepRequest.BodyFile == "" {
 		return nil
 	}
 
-	data, err := r.parser.Parse(r.stepRequest.Body, stepVariables)
-	if err != nil {
-		return err
+	var data interface{}
+	if r.stepRequest.Body == nil {
+		var err error
+		data, err = r.renderBodyFile(stepVariables)
+		if err != nil {
+			return err
+		}
+	} else if compiled := r.stepRequest.compiled; compiled != nil && compiled.bodyConstant {
+		data = compiled.body
+	} else {
+		var err error
+		data, err = r.parser.Parse(r.stepRequest.Body, stepVariables)
+		if err != nil {
+			return err
+		}
 	}
 	// check request body format if Content-Type specified as application/json
 	if strings.HasPrefix(r.req.Header.Get("Content-Type"), "application/json") {
@@ -195,7 +498,19 @@ func (r *requestBuilder) prepareBody(stepVariables map[string]interface{}) error
 		}
 	}
 	r.requestMap["body"] = data
-	var dataBytes []byte
+	// encode map or slice body in binary format specified by Content-Type, e.g. msgpack, cbor
+	if codec := lookupBodyCodec(r.req.Header.Get("Content-Type")); codec != nil {
+		switch data.(type) {
+		case map[string]interface{}, []interface{}:
+			content, err := codec.marshal(data)
+			if err != nil {
+				return errors.Wrapf(err, "encode request body in %s failed", codec.name)
+			}
+			r.req.Body = io.NopCloser(bytes.NewReader(content))
+			r.req.ContentLength = int64(len(content))
+			return nil
+		}
+	}
 	switch vv := data.(type) {
 	case map[string]interface{}:
 		contentType := r.req.Header.Get("Content-Type")
@@ -205,11 +520,10 @@ func (r *requestBuilder) prepareBody(stepVariables map[string]interface{}) error
 			for k, v := range vv {
 				formData.Add(k, fmt.Sprint(v))
 			}
-			dataBytes = []byte(formData.Encode())
+			r.setStringBody(formData.Encode())
 		} else {
 			// post json
-			dataBytes, err = json.Marshal(vv)
-			if err != nil {
+			if err := r.setJSONBody(vv); err != nil {
 				return err
 			}
 			if contentType == "" {
@@ -219,26 +533,100 @@ func (r *requestBuilder) prepareBody(stepVariables map[string]interface{}) error
 	case []interface{}:
 		contentType := r.req.Header.Get("Content-Type")
 		// post json
-		dataBytes, err = json.Marshal(vv)
-		if err != nil {
+		if err := r.setJSONBody(vv); err != nil {
 			return err
 		}
 		if contentType == "" {
 			r.req.Header.Set("Content-Type", "application/json; charset=utf-8")
 		}
 	case string:
-		dataBytes = []byte(vv)
+		r.setStringBody(vv)
 	case []byte:
-		dataBytes = vv
+		r.req.Body = io.NopCloser(bytes.NewReader(vv))
+		r.req.ContentLength = int64(len(vv))
 	case bytes.Buffer:
-		dataBytes = vv.Bytes()
+		r.req.Body = io.NopCloser(bytes.NewReader(vv.Bytes()))
+		r.req.ContentLength = int64(vv.Len())
 	default: // unexpected body type
 		return errors.New("unexpected request body type")
 	}
 
-	r.req.Body = io.NopCloser(bytes.NewReader(dataBytes))
-	r.req.ContentLength = int64(len(dataBytes))
+	return nil
+}
 
+// resolveFilePaths resolves relative file paths of request against project root dir at testcase load time.
+func (r *Request) resolveFilePaths(projectRootDir string) {
+	if r.BodyFile != "" && !filepath.IsAbs(r.BodyFile) {
+		r.bodyFilePath = filepath.Join(projectRootDir, r.BodyFile)
+	}
+	if r.BodyProtobuf != nil && r.BodyProtobuf.Descriptor != "" && !filepath.IsAbs(r.BodyProtobuf.Descriptor) {
+		r.BodyProtobuf.descriptorPath = filepath.Join(projectRootDir, r.BodyProtobuf.Descriptor)
+	}
+}
+
+// prepareProtobufBody encodes parsed data of body_protobuf into protobuf binary message.
+func (r *requestBuilder) prepareProtobufBody(stepVariables map[string]interface{}) error {
+	body := r.stepRequest.BodyProtobuf
+	data, err := r.parser.Parse(body.Data, stepVariables)
+	if err != nil {
+		return err
+	}
+	content, err := encodeProtobuf(body.path(), body.Message, data)
+	if err != nil {
+		return err
+	}
+	r.requestMap["body"] = data
+	r.req.Body = io.NopCloser(bytes.NewReader(content))
+	r.req.ContentLength = int64(len(content))
+	if r.req.Header.Get("Content-Type") == "" {
+		r.req.Header.Set("Content-Type", contentTypeProtobuf)
+	}
+	return nil
+}
+
+// renderBodyFile loads body template file and renders it with step variables and functions,
+// rendered content of json file (*.json or *.json.tmpl) is decoded and sent as json body.
+func (r *requestBuilder) renderBodyFile(stepVariables map[string]interface{}) (interface{}, error) {
+	path := r.stepRequest.bodyFilePath
+	if path == "" {
+		path = r.stepRequest.BodyFile
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "read body file failed")
+	}
+	data, err := r.parser.ParseString(string(content), stepVariables)
+	if err != nil {
+		return nil, errors.Wrapf(err, "render body file %s failed", path)
+	}
+	text, ok := data.(string)
+	if !ok || filepath.Ext(strings.TrimSuffix(path, ".tmpl")) != ".json" {
+		return data, nil
+	}
+	var body interface{}
+	if err := json.Unmarshal([]byte(text), &body); err != nil {
+		return nil, errors.Wrapf(err, "rendered body file %s is not valid json", path)
+	}
+	return body, nil
+}
+
+// setStringBody sets request body with string reader, avoiding copying string to bytes.
+func (r *requestBuilder) setStringBody(body string) {
+	r.req.Body = io.NopCloser(strings.NewReader(body))
+	r.req.ContentLength = int64(len(body))
+}
+
+// setJSONBody encodes request body into pooled buffer, which is put back when body is closed by transport.
+func (r *requestBuilder) setJSONBody(data interface{}) error {
+	buf := getBuffer()
+	if err := json.NewEncoder(buf).Encode(data); err != nil {
+		putBuffer(buf)
+		return err
+	}
+	// remove trailing newline appended by encoder, keep the same as json.Marshal
+	buf.Truncate(buf.Len() - 1)
+	r.req.Body = newPooledBody(buf)
+	r.req.ContentLength = int64(buf.Len())
 	return nil
 }
 
@@ -267,89 +655,220 @@ func runStepRequest(r *SessionRunner, step *TStep) (stepResult *StepResult, err
 	parser := r.GetParser()
 	config := r.GetConfig()
 
-	rb := newRequestBuilder(parser, config, step.Request)
-	rb.req.Method = string(step.Request.Method)
-
-	err = rb.prepareUrlParams(stepVariables)
-	if err != nil {
-		return
+	// generate idempotency key, which is injected as request header and exposed as variable
+	idempotencyKey := config.IdempotencyKey
+	if idempotencyKey != nil && !idempotencyKey.applies(config, step.Request) {
+		idempotencyKey = nil
+	}
+	if idempotencyKey != nil {
+		if err = setIdempotencyKey(idempotencyKey, parser, stepVariables); err != nil {
+			return
+		}
 	}
 
-	err = rb.prepareHeaders(stepVariables)
-	if err != nil {
-		return
+	// attach correlation id, which is generated for each step or shared by testcase
+	if correlationID := config.CorrelationID; correlationID != nil && !hasExplicitHeader(config, step.Request, correlationID.header()) {
+		name := correlationID.variable()
+		if correlationID.perStep() {
+			id, err := correlationID.generate(parser, stepVariables)
+			if err != nil {
+				return stepResult, err
+			}
+			stepVariables[name] = id
+		}
+		if id, ok := stepVariables[name]; ok {
+			stepResult.CorrelationID = convertString(id)
+			log.Info().Str("step", step.Name).Str("correlationID", stepResult.CorrelationID).
+				Msg("attach correlation id")
+		}
 	}
 
-	err = rb.prepareBody(stepVariables)
+	reqTransform := requestTransform(config, step)
+	rb, err := buildTransformedRequest(parser, config, step.Request, reqTransform, stepVariables)
 	if err != nil {
 		return
 	}
 
 	// add request object to step variables, could be used in setup hooks
 	stepVariables["hrp_step_name"] = step.Name
+	if remaining, ok := r.remainingMilliseconds(); ok {
+		stepVariables["hrp_remaining_ms"] = remaining
+	}
 	stepVariables["hrp_step_request"] = rb.requestMap
 
 	// deal with setup hooks
 	for _, setupHook := range step.SetupHooks {
 		_, err = parser.Parse(setupHook, stepVariables)
 		if err != nil {
-			return stepResult, errors.Wrap(err, "run setup hooks failed")
+			return stepResult, newStepError(FailureHook, errors.Wrap(err, "run setup hooks failed"))
 		}
 	}
 
-	// log & print request
-	if r.LogOn() {
-		if err := printRequest(rb.req); err != nil {
+	client := r.hrpRunner.getClient(config.Transport)
+	if step.Egress != nil {
+		egress, err := parseEgress(parser, step.Egress, stepVariables)
+		if err != nil {
+			return stepResult, err
+		}
+		client, err = r.hrpRunner.getEgressClient(config.Transport, egress)
+		if err != nil {
 			return stepResult, err
 		}
 	}
-
-	// do request action
-	start := time.Now()
-	resp, err := r.hrpRunner.client.Do(rb.req)
-	stepResult.Elapsed = time.Since(start).Milliseconds()
-	if err != nil {
-		return stepResult, errors.Wrap(err, "do request failed")
+	if step.Fault != nil {
+		// inject faults with a shallow copy of client, to avoid affecting other steps
+		faultClient := *client
+		faultClient.Transport = newFaultTransport(client.Transport, step.Fault)
+		client = &faultClient
+	}
+	if r.jar != nil {
+		// keep cookies of virtual user with a shallow copy of client
+		userClient := *client
+		userClient.Jar = r.jar
+		client = &userClient
+	}
+	var doer Doer = client
+	if r.hrpRunner.doer != nil {
+		doer = r.hrpRunner.doer
+		client = nil
 	}
-	defer resp.Body.Close()
 
-	// decode response body in br/gzip/deflate formats
-	err = decodeResponseBody(resp)
-	if err != nil {
-		return stepResult, errors.Wrap(err, "decode response body failed")
+	var waitExpected interface{}
+	if step.WaitUntil != nil {
+		waitExpected, err = parser.Parse(step.WaitUntil.Expect, stepVariables)
+		if err != nil {
+			return stepResult, errors.Wrap(err, "parse wait until expected value failed")
+		}
 	}
 
-	// log & print response
-	if r.LogOn() {
-		if err := printResponse(resp); err != nil {
-			return stepResult, err
+	// stream large json response body and only decode fields referenced by step
+	var bodyTrie *jsonPathNode
+	if r.hrpRunner.jsonStreamThreshold > 0 {
+		if trie, ok := bodyPathTrie(step); ok {
+			bodyTrie = trie
 		}
 	}
 
-	// new response object
-	respObj, err := newResponseObject(r.hrpRunner.t, parser, resp)
-	if err != nil {
-		err = errors.Wrap(err, "init ResponseObject error")
-		return
+	var resp *http.Response
+	var respObj *responseObject
+	httpFile := r.httpFilePath(step.Name)
+	waitStart := time.Now()
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 {
+			if idempotencyKey != nil && idempotencyKey.Scope == idempotencyKeyPerRequest {
+				if err = setIdempotencyKey(idempotencyKey, parser, stepVariables); err != nil {
+					return
+				}
+			}
+			// rebuild request for each attempt since request body has been consumed
+			rb, err = buildTransformedRequest(parser, config, step.Request, reqTransform, stepVariables)
+			if err != nil {
+				return
+			}
+		}
+		if httpFile != "" {
+			// request of the last attempt is kept
+			if err := dumpHTTPFile(httpFile, step.Name, client, rb.req); err != nil {
+				log.Error().Err(err).Str("path", httpFile).Msg("write .http file failed")
+			}
+		}
+		resp, respObj, stepResult.Elapsed, err = r.doRequest(doer, rb, bodyTrie)
+		if err != nil {
+			if r.interrupted() != nil {
+				runCanceledTeardownHooks(parser, step, stepVariables)
+			}
+			return
+		}
+		if transform := responseTransform(config, step); transform != nil {
+			if err = respObj.transform(transform, stepVariables); err != nil {
+				return stepResult, newStepError(FailureHook, errors.Wrap(err, "transform response failed"))
+			}
+		}
+		if sessionData.Connection == nil {
+			sessionData.Connection = &ConnectionStats{}
+		}
+		if respObj.connReused {
+			sessionData.Connection.Reused++
+		} else {
+			sessionData.Connection.New++
+		}
+		sessionData.Address = respObj.address
+		if step.WaitUntil == nil {
+			break
+		}
+
+		// re-issue request until condition matches or times out
+		actual := respObj.searchJmespath(step.WaitUntil.JmesPath)
+		matched := len(builtin.DiffJSON(waitExpected, actual)) == 0
+		sessionData.Attempts = append(sessionData.Attempts, &WaitAttempt{
+			Attempt:  attempt,
+			Elapsed:  stepResult.Elapsed,
+			Actual:   actual,
+			Matched:  matched,
+			Response: builtin.FormatResponse(respObj.respObjMeta),
+		})
+		if matched {
+			log.Info().Str("jmesPath", step.WaitUntil.JmesPath).
+				Int("attempts", attempt).Msg("wait until condition matched")
+			break
+		}
+		interval := time.Duration(step.WaitUntil.Interval*1000) * time.Millisecond
+		timeout := time.Duration(step.WaitUntil.Timeout*1000) * time.Millisecond
+		if time.Since(waitStart)+interval > timeout {
+			stepResult.Data = sessionData
+			err = newStepError(FailureTimeout, fmt.Errorf("wait until %s == %v timed out after %d attempts, last value: %v",
+				step.WaitUntil.JmesPath, waitExpected, attempt, actual))
+			return
+		}
+		log.Info().Str("jmesPath", step.WaitUntil.JmesPath).Interface("actual", actual).
+			Int("attempt", attempt).Msg("wait until condition not matched, retry later")
+		if err = r.sleep(interval); err != nil {
+			stepResult.Data = sessionData
+			runCanceledTeardownHooks(parser, step, stepVariables)
+			return
+		}
 	}
 
 	// add response object to step variables, could be used in teardown hooks
 	stepVariables["hrp_step_response"] = respObj.respObjMeta
+	if remaining, ok := r.remainingMilliseconds(); ok {
+		stepVariables["hrp_remaining_ms"] = remaining
+	}
+	stepResult.Response = respObj.response
+	r.mutex.Lock()
+	r.lastResponse = respObj.respObjMeta
+	r.response = respObj.response
+	r.mutex.Unlock()
 
 	// deal with teardown hooks
 	for _, teardownHook := range step.TeardownHooks {
 		_, err = parser.Parse(teardownHook, stepVariables)
 		if err != nil {
-			return stepResult, errors.Wrap(err, "run teardown hooks failed")
+			return stepResult, newStepError(FailureHook, errors.Wrap(err, "run teardown hooks failed"))
 		}
 	}
 
 	sessionData.ReqResps.Request = rb.requestMap
 	sessionData.ReqResps.Response = builtin.FormatResponse(respObj.respObjMeta)
+	sessionData.Truncated = respObj.truncated
 
 	// extract variables from response
 	extractors := step.Extract
-	extractMapping := respObj.Extract(extractors)
+	extractMapping, err := respObj.Extract(extractors)
+	if err != nil {
+		stepResult.Data = sessionData
+		return
+	}
+	if idempotencyKey != nil {
+		// expose generated key to later steps
+		if extractMapping == nil {
+			extractMapping = make(map[string]interface{})
+		}
+		name := idempotencyKey.variable()
+		if _, ok := extractMapping[name]; !ok {
+			extractMapping[name] = stepVariables[name]
+		}
+	}
 	stepResult.ExportVars = extractMapping
 
 	// override step variables with extracted variables
@@ -357,7 +876,19 @@ func runStepRequest(r *SessionRunner, step *TStep) (stepResult *StepResult, err
 
 	// validate response
 	err = respObj.Validate(step.Validators, stepVariables)
+	// compare response with snapshot golden file
+	if err == nil && step.Snapshot != nil {
+		name := step.Snapshot.Name
+		if name == "" {
+			name = step.Name
+		}
+		err = newStepError(FailureValidation,
+			respObj.matchSnapshot(step.Snapshot, r.snapshotPath(name), r.hrpRunner.updateSnapshots))
+	}
 	sessionData.Validators = respObj.validationResults
+	if err != nil {
+		r.hrpRunner.reporter.printValidationFailures(respObj.validationResults)
+	}
 	if err == nil {
 		sessionData.Success = true
 		stepResult.Success = true
@@ -365,78 +896,331 @@ func runStepRequest(r *SessionRunner, step *TStep) (stepResult *StepResult, err
 	stepResult.ContentSize = resp.ContentLength
 	stepResult.Data = sessionData
 
-	return stepResult, err
+	return stepResult, err
+}
+
+// buildStepRequest builds http request of step with variables mapping.
+func buildStepRequest(parser *Parser, config *TConfig, stepRequest *Request,
+	stepVariables map[string]interface{}) (*requestBuilder, error) {
+
+	rb := newRequestBuilder(parser, config, stepRequest)
+	rb.req.Method = string(stepRequest.Method)
+
+	if err := rb.prepareUrlParams(stepVariables); err != nil {
+		return nil, err
+	}
+	if err := rb.prepareHeaders(stepVariables); err != nil {
+		return nil, err
+	}
+	if err := rb.prepareBody(stepVariables); err != nil {
+		return nil, err
+	}
+	return rb, nil
+}
+
+// buildTransformedRequest builds http request of step and transforms it, e.g. encrypts body and signs it.
+func buildTransformedRequest(parser *Parser, config *TConfig, stepRequest *Request, transform *RequestTransform,
+	stepVariables map[string]interface{}) (*requestBuilder, error) {
+
+	rb, err := buildStepRequest(parser, config, stepRequest, stepVariables)
+	if err != nil || transform == nil {
+		return rb, err
+	}
+	if err := rb.transform(transform, stepVariables); err != nil {
+		return nil, newStepError(FailureHook, errors.Wrap(err, "transform request failed"))
+	}
+	return rb, nil
+}
+
+// runCanceledTeardownHooks runs teardown hooks of step interrupted by cancellation to release resources,
+// hrp_step_response is nil since response is not received, and errors are only logged.
+func runCanceledTeardownHooks(parser *Parser, step *TStep, stepVariables map[string]interface{}) {
+	stepVariables["hrp_step_response"] = nil
+	for _, teardownHook := range step.TeardownHooks {
+		if _, err := parser.Parse(teardownHook, stepVariables); err != nil {
+			log.Error().Err(err).Str("step", step.Name).Msg("run teardown hooks of canceled step failed")
+		}
+	}
+}
+
+// doRequest sends http request and returns response object with request elapsed time in milliseconds,
+// json response body larger than stream threshold is decoded partially with bodyTrie if it is not nil.
+func (r *SessionRunner) doRequest(client Doer, rb *requestBuilder, bodyTrie *jsonPathNode) (
+	resp *http.Response, respObj *responseObject, elapsed int64, err error) {
+
+	// log & print request
+	if r.LogOn() {
+		if err = r.hrpRunner.reporter.printRequest(rb.req); err != nil {
+			return
+		}
+	}
+
+	// request is canceled when run is canceled or deadline of testcase is exceeded,
+	// and times out after step timeout if it is set
+	ctx := r.ctx
+	if timeout := rb.stepRequest.Timeout; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(timeout*1000)*time.Millisecond)
+		defer cancel()
+	}
+
+	// trace connection and time points of request phases for response timings
+	trace := &requestTrace{}
+	rb.req = rb.req.WithContext(httptrace.WithClientTrace(ctx, trace.clientTrace()))
+
+	// capture request & response into HAR
+	var harCapture *harCapture
+	if r.hrpRunner.harRecorder != nil {
+		harCapture, rb.req, err = newHARCapture(rb.req)
+		if err != nil {
+			err = errors.Wrap(err, "capture request for HAR failed")
+			return
+		}
+	}
+
+	// do request action
+	r.recordVisitedURL(rb.req.URL)
+	start := time.Now()
+	resp, err = client.Do(rb.req)
+	total := time.Since(start)
+	elapsed = total.Milliseconds()
+	if err != nil {
+		err = errors.Wrap(err, "do request failed")
+		if harCapture != nil {
+			entry, _ := harCapture.finish(nil, err)
+			r.recordHAR(entry)
+		}
+		return
+	}
+	defer resp.Body.Close()
+
+	// count response body size on wire for printing
+	var wire *countingReader
+	if r.LogOn() {
+		wire = &countingReader{ReadCloser: resp.Body}
+		resp.Body = wire
+	}
+
+	// decode response body in br/gzip/deflate/zstd formats and non-UTF-8 charsets
+	err = decodeResponseBody(resp)
+	if err != nil {
+		err = errors.Wrap(err, "decode response body failed")
+		return
+	}
+	defer resp.Body.Close()
+
+	// read response body up to max body size
+	var truncated bool
+	if maxBodySize := r.testCase.Config.MaxBodySize; maxBodySize > 0 {
+		truncated, err = limitResponseBody(resp, maxBodySize)
+		if err != nil {
+			err = errors.Wrap(err, "read response body failed")
+			return
+		}
+		if truncated {
+			log.Warn().Str("url", displayURL(rb.req.URL)).Int64("maxBodySize", maxBodySize).
+				Msg("response body exceeds max body size, truncated")
+		}
+	}
+
+	if harCapture != nil {
+		var entry *harEntry
+		entry, err = harCapture.finish(resp, nil)
+		if err != nil {
+			err = errors.Wrap(err, "capture response for HAR failed")
+			return
+		}
+		r.recordHAR(entry)
+	}
+
+	// log & print response
+	if r.LogOn() {
+		if err = r.hrpRunner.reporter.printResponse(resp, wire); err != nil {
+			return
+		}
+	}
+
+	// new response object
+	var body interface{}
+	if pb := rb.stepRequest.BodyProtobuf; pb != nil && pb.Response != "" &&
+		isProtobufContentType(resp.Header.Get("Content-Type")) {
+		body, err = readProtobufResponseBody(resp.Body, pb.path(), pb.Response)
+	} else if codec := lookupBodyCodec(resp.Header.Get("Content-Type")); codec != nil {
+		body, err = readCodecResponseBody(resp.Body, codec)
+	} else {
+		threshold := r.hrpRunner.jsonStreamThreshold
+		if truncated {
+			// truncated json can not be decoded from stream, body is read as raw string instead
+			threshold = 0
+		}
+		body, err = readResponseBody(resp.Body, threshold, bodyTrie)
+	}
+	if err != nil {
+		err = errors.Wrap(err, "read response body failed")
+		return
+	}
+	connReused, address := trace.conn()
+	respObj, err = newResponseObjectWithBody(r.hrpRunner.t, r.parser, resp, body, address)
+	if err != nil {
+		err = errors.Wrap(err, "init ResponseObject error")
+		return
+	}
+	respObj.truncated = truncated
+	respObj.connReused = connReused
+	respObj.address = address
+	respObj.jwtVerifier = r.jwtVerifier
+	respObj.response = &Response{
+		StatusCode: resp.StatusCode,
+		Proto:      resp.Proto,
+		Header:     resp.Header,
+		Cookies:    resp.Cookies(),
+		Body:       body,
+		Truncated:  truncated,
+		Timings:    trace.timings(total),
+	}
+	return
+}
+
+// requestTrace records whether connection is reused from pool, addresses of connection and time points
+// of request phases, mutex protects them since callbacks may be called in dialing goroutines of transport,
+// e.g. racing dials of dual-stack hosts, even after request is done.
+type requestTrace struct {
+	mutex                     sync.Mutex
+	connReused                bool
+	address                   *Address
+	dnsStart, dnsDone         time.Time
+	connectStart, connectDone time.Time
+	tlsStart, tlsDone         time.Time
+	wroteRequest, firstByte   time.Time
+}
+
+func (t *requestTrace) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			address := newAddress(info.Conn.LocalAddr(), info.Conn.RemoteAddr())
+			t.mutex.Lock()
+			defer t.mutex.Unlock()
+			t.connReused = info.Reused
+			t.address = address
+		},
+		DNSStart:             func(httptrace.DNSStartInfo) { t.record(&t.dnsStart) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { t.record(&t.dnsDone) },
+		ConnectStart:         func(string, string) { t.record(&t.connectStart) },
+		ConnectDone:          func(string, string, error) { t.record(&t.connectDone) },
+		TLSHandshakeStart:    func() { t.record(&t.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { t.record(&t.tlsDone) },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { t.record(&t.wroteRequest) },
+		GotFirstResponseByte: func() { t.record(&t.firstByte) },
+	}
+}
+
+// record records time of request phase.
+func (t *requestTrace) record(p *time.Time) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	*p = time.Now()
+}
+
+// conn returns whether connection is reused and addresses of connection.
+func (t *requestTrace) conn() (bool, *Address) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.connReused, t.address
 }
 
-func printRequest(req *http.Request) error {
-	reqContentType := req.Header.Get("Content-Type")
-	printBody := shouldPrintBody(reqContentType)
-	reqDump, err := httputil.DumpRequest(req, printBody)
-	if err != nil {
-		return errors.Wrap(err, "dump request failed")
+func (t *requestTrace) timings(total time.Duration) ResponseTimings {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return ResponseTimings{
+		DNSLookup:        between(t.dnsStart, t.dnsDone),
+		TCPConnection:    between(t.connectStart, t.connectDone),
+		TLSHandshake:     between(t.tlsStart, t.tlsDone),
+		ServerProcessing: between(t.wroteRequest, t.firstByte),
+		Total:            total,
 	}
-	fmt.Println("-------------------- request --------------------")
-	reqContent := string(reqDump)
-	if req.Body != nil && !printBody {
-		reqContent += fmt.Sprintf("(request body omitted for Content-Type: %v)", reqContentType)
+}
+
+// between returns duration from start to end, zero is returned if either time point is not traced.
+func between(start, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() {
+		return 0
 	}
-	fmt.Println(reqContent)
-	return nil
+	return end.Sub(start)
 }
 
-func printResponse(resp *http.Response) error {
-	fmt.Println("==================== response ===================")
-	respContentType := resp.Header.Get("Content-Type")
-	printBody := shouldPrintBody(respContentType)
-	respDump, err := httputil.DumpResponse(resp, printBody)
+// limitResponseBody reads response body up to maxSize bytes, the rest is discarded without being buffered.
+func limitResponseBody(resp *http.Response, maxSize int64) (truncated bool, err error) {
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
 	if err != nil {
-		return errors.Wrap(err, "dump response failed")
+		return false, err
 	}
-	respContent := string(respDump)
-	if !printBody {
-		respContent += fmt.Sprintf("(response body omitted for Content-Type: %v)", respContentType)
+	if int64(len(body)) > maxSize {
+		truncated = true
+		body = body[:maxSize]
 	}
-	fmt.Println(respContent)
-	fmt.Println("--------------------------------------------------")
-	return nil
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	return truncated, nil
 }
 
+// decodeResponseBody replaces response body with pooled decompression reader,
+// which should be closed to put reader back to pool after body is read.
 func decodeResponseBody(resp *http.Response) (err error) {
 	switch resp.Header.Get("Content-Encoding") {
 	case "br":
-		resp.Body = io.NopCloser(brotli.NewReader(resp.Body))
+		resp.Body, err = newBrotliReader(resp.Body)
+		if err != nil {
+			return err
+		}
 	case "gzip":
-		resp.Body, err = gzip.NewReader(resp.Body)
+		resp.Body, err = newGzipReader(resp.Body)
 		if err != nil {
 			return err
 		}
 		resp.ContentLength = -1 // set to unknown to avoid Content-Length mismatched
 	case "deflate":
-		resp.Body, err = zlib.NewReader(resp.Body)
+		resp.Body, err = newZlibReader(resp.Body)
+		if err != nil {
+			return err
+		}
+		resp.ContentLength = -1 // set to unknown to avoid Content-Length mismatched
+	case "zstd":
+		resp.Body, err = newZstdReader(resp.Body)
 		if err != nil {
 			return err
 		}
 		resp.ContentLength = -1 // set to unknown to avoid Content-Length mismatched
 	}
+	decodeResponseCharset(resp)
 	return nil
 }
 
-// shouldPrintBody return true if the Content-Type is printable
-// including text/*, application/json, application/xml, application/www-form-urlencoded
-func shouldPrintBody(contentType string) bool {
-	if strings.HasPrefix(contentType, "text/") {
-		return true
+// decodeResponseCharset transcodes response body in non-UTF-8 charset declared in Content-Type into UTF-8,
+// e.g. GBK, Shift_JIS, ISO-8859-1, thus validators and extractors compare correct strings.
+// body is kept as is if charset is not declared or not supported.
+func decodeResponseCharset(resp *http.Response) {
+	_, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		return
 	}
-	if strings.HasPrefix(contentType, "application/json") {
-		return true
+	name := strings.ToLower(strings.TrimSpace(params["charset"]))
+	switch name {
+	case "", "utf-8", "utf8", "us-ascii", "ascii":
+		return
 	}
-	if strings.HasPrefix(contentType, "application/xml") {
-		return true
+	encoding, err := htmlindex.Get(name)
+	if err != nil {
+		log.Warn().Str("charset", name).Msg("unsupported response charset, keep body as is")
+		return
 	}
-	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
-		return true
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{
+		Reader: encoding.NewDecoder().Reader(resp.Body),
+		Closer: resp.Body,
 	}
-	return false
+	resp.ContentLength = -1 // set to unknown to avoid Content-Length mismatched
 }
 
 // NewStep returns a new constructed teststep with specified step name.
@@ -542,6 +1326,18 @@ func (s *StepRequest) PATCH(url string) *StepRequestWithOptionalArgs {
 	}
 }
 
+// CustomMethod makes a HTTP request with arbitrary method, e.g. WebDAV methods PROPFIND, MKCOL, or vendor methods like PURGE.
+// method is sent as is, since HTTP methods are case-sensitive.
+func (s *StepRequest) CustomMethod(method, url string) *StepRequestWithOptionalArgs {
+	s.step.Request = &Request{
+		Method: HTTPMethod(method),
+		URL:    url,
+	}
+	return &StepRequestWithOptionalArgs{
+		step: s.step,
+	}
+}
+
 // CallRefCase calls a referenced testcase.
 func (s *StepRequest) CallRefCase(tc ITestCase) *StepTestCaseWithOptionalArgs {
 	var err error
@@ -641,12 +1437,38 @@ func (s *StepRequestWithOptionalArgs) WithParams(params map[string]interface{})
 	return s
 }
 
+// WithParamsOrder sets order of params keys in query string, keys not listed are sorted after them.
+func (s *StepRequestWithOptionalArgs) WithParamsOrder(keys ...string) *StepRequestWithOptionalArgs {
+	s.step.Request.ParamsOrder = keys
+	return s
+}
+
+// WithEncodedParams marks params as pre-encoded, which are appended to url as is without double-encoding.
+func (s *StepRequestWithOptionalArgs) WithEncodedParams() *StepRequestWithOptionalArgs {
+	s.step.Request.ParamsEncoded = true
+	return s
+}
+
+// WithRawURL sends url as is without normalization or re-encoding, e.g. %2F, .., double slashes,
+// which is useful for security and routing tests.
+func (s *StepRequestWithOptionalArgs) WithRawURL() *StepRequestWithOptionalArgs {
+	s.step.Request.RawURL = true
+	return s
+}
+
 // WithHeaders sets HTTP request headers for current step.
 func (s *StepRequestWithOptionalArgs) WithHeaders(headers map[string]string) *StepRequestWithOptionalArgs {
 	s.step.Request.Headers = headers
 	return s
 }
 
+// AddHeader appends header to ordered header list, duplicate names are allowed and name is sent as is,
+// which is useful for WAF or fingerprinting tests.
+func (s *StepRequestWithOptionalArgs) AddHeader(name, value string) *StepRequestWithOptionalArgs {
+	s.step.Request.HeaderList = append(s.step.Request.HeaderList, HeaderField{Name: name, Value: value})
+	return s
+}
+
 // WithCookies sets HTTP request cookies for current step.
 func (s *StepRequestWithOptionalArgs) WithCookies(cookies map[string]string) *StepRequestWithOptionalArgs {
 	s.step.Request.Cookies = cookies
@@ -659,6 +1481,19 @@ func (s *StepRequestWithOptionalArgs) WithBody(body interface{}) *StepRequestWit
 	return s
 }
 
+// WithProtobufBody sets HTTP request body encoded in protobuf binary format for current step.
+func (s *StepRequestWithOptionalArgs) WithProtobufBody(body *ProtobufBody) *StepRequestWithOptionalArgs {
+	s.step.Request.BodyProtobuf = body
+	return s
+}
+
+// WithBodyFile sets template file of HTTP request body for current step,
+// the file is rendered with variables and functions at run time, relative path is resolved against current working directory.
+func (s *StepRequestWithOptionalArgs) WithBodyFile(path string) *StepRequestWithOptionalArgs {
+	s.step.Request.BodyFile = path
+	return s
+}
+
 // TeardownHook adds a teardown hook for current teststep.
 func (s *StepRequestWithOptionalArgs) TeardownHook(hook string) *StepRequestWithOptionalArgs {
 	s.step.TeardownHooks = append(s.step.TeardownHooks, hook)
@@ -710,6 +1545,23 @@ func (s *StepRequestExtraction) WithJmesPath(jmesPath string, varName string) *S
 	return s
 }
 
+// WithCSSSelector sets the css selector to extract from html response, target is one of text, html,
+// attr:<name> and count, e.g. WithCSSSelector("form input[name=csrf]", "attr:value", "token").
+func (s *StepRequestExtraction) WithCSSSelector(selector, target, varName string) *StepRequestExtraction {
+	expr := cssExtractorPrefix + selector
+	if target != "" {
+		expr += "|" + target
+	}
+	s.step.Extract[varName] = expr
+	return s
+}
+
+// WithJWTClaim decodes jwt located by jmespath and extracts its claim, e.g. WithJWTClaim("body.token", "sub", "user_id").
+func (s *StepRequestExtraction) WithJWTClaim(tokenPath, claim, varName string) *StepRequestExtraction {
+	s.step.Extract[varName] = jwtClaimCheckExpr(tokenPath, claim)
+	return s
+}
+
 // Validate switches to step validation.
 func (s *StepRequestExtraction) Validate() *StepRequestValidation {
 	return &StepRequestValidation{
@@ -922,6 +1774,315 @@ func (s *StepRequestValidation) AssertStringEqual(jmesPath string, expected inte
 	return s
 }
 
+// AssertEqualFold asserts string extracted with jmesPath equals to expected ignoring case.
+func (s *StepRequestValidation) AssertEqualFold(jmesPath string, expected interface{}, msg string) *StepRequestValidation {
+	v := Validator{
+		Check:   jmesPath,
+		Assert:  "equal_fold",
+		Expect:  expected,
+		Message: msg,
+	}
+	s.step.Validators = append(s.step.Validators, v)
+	return s
+}
+
+// AssertContainsFold asserts string extracted with jmesPath contains expected ignoring case,
+// or list of strings extracted with jmesPath contains expected element ignoring case.
+func (s *StepRequestValidation) AssertContainsFold(jmesPath string, expected interface{}, msg string) *StepRequestValidation {
+	v := Validator{
+		Check:   jmesPath,
+		Assert:  "contains_fold",
+		Expect:  expected,
+		Message: msg,
+	}
+	s.step.Validators = append(s.step.Validators, v)
+	return s
+}
+
+// AssertApproxEqual asserts number extracted with jmesPath differs from expected by no more than epsilon,
+// e.g. prices, coordinates and scores which can not be compared exactly due to floating point precision.
+func (s *StepRequestValidation) AssertApproxEqual(jmesPath string, expected interface{}, epsilon float64) *StepRequestValidation {
+	v := Validator{
+		Check:   jmesPath,
+		Assert:  "approx_equal",
+		Expect:  expected,
+		Epsilon: epsilon,
+	}
+	s.step.Validators = append(s.step.Validators, v)
+	return s
+}
+
+// AssertSetEquals asserts list extracted with jmesPath contains the same items as expected regardless of order.
+func (s *StepRequestValidation) AssertSetEquals(jmesPath string, expected interface{}, msg string) *StepRequestValidation {
+	v := Validator{
+		Check:   jmesPath,
+		Assert:  "set_equals",
+		Expect:  expected,
+		Message: msg,
+	}
+	s.step.Validators = append(s.step.Validators, v)
+	return s
+}
+
+// AssertSortedAsc asserts list of numbers or strings extracted with jmesPath is sorted in ascending order.
+func (s *StepRequestValidation) AssertSortedAsc(jmesPath string, msg string) *StepRequestValidation {
+	v := Validator{
+		Check:   jmesPath,
+		Assert:  "is_sorted_asc",
+		Expect:  true,
+		Message: msg,
+	}
+	s.step.Validators = append(s.step.Validators, v)
+	return s
+}
+
+// AssertSortedDesc asserts list of numbers or strings extracted with jmesPath is sorted in descending order.
+func (s *StepRequestValidation) AssertSortedDesc(jmesPath string, msg string) *StepRequestValidation {
+	v := Validator{
+		Check:   jmesPath,
+		Assert:  "is_sorted_desc",
+		Expect:  true,
+		Message: msg,
+	}
+	s.step.Validators = append(s.step.Validators, v)
+	return s
+}
+
+// AssertUniqueItems asserts items of list extracted with jmesPath are unique.
+func (s *StepRequestValidation) AssertUniqueItems(jmesPath string, msg string) *StepRequestValidation {
+	v := Validator{
+		Check:   jmesPath,
+		Assert:  "unique_items",
+		Expect:  true,
+		Message: msg,
+	}
+	s.step.Validators = append(s.step.Validators, v)
+	return s
+}
+
+// AssertRFC3339 asserts value extracted with jmesPath is RFC 3339 datetime string, e.g. 2022-04-01T08:00:00Z
+func (s *StepRequestValidation) AssertRFC3339(jmesPath string, msg string) *StepRequestValidation {
+	v := Validator{
+		Check:   jmesPath,
+		Assert:  "is_rfc3339",
+		Expect:  true,
+		Message: msg,
+	}
+	s.step.Validators = append(s.step.Validators, v)
+	return s
+}
+
+// AssertUnixTimestamp asserts value extracted with jmesPath is unix timestamp in seconds or milliseconds.
+func (s *StepRequestValidation) AssertUnixTimestamp(jmesPath string, msg string) *StepRequestValidation {
+	v := Validator{
+		Check:   jmesPath,
+		Assert:  "is_unix_timestamp",
+		Expect:  true,
+		Message: msg,
+	}
+	s.step.Validators = append(s.step.Validators, v)
+	return s
+}
+
+// AssertTimeWithin asserts datetime or unix timestamp extracted with jmesPath is within duration from now,
+// e.g. AssertTimeWithin("body.created_at", 5*time.Minute, "")
+func (s *StepRequestValidation) AssertTimeWithin(jmesPath string, duration time.Duration, msg string) *StepRequestValidation {
+	v := Validator{
+		Check:   jmesPath,
+		Assert:  "time_within",
+		Expect:  duration.String(),
+		Message: msg,
+	}
+	s.step.Validators = append(s.step.Validators, v)
+	return s
+}
+
+// AssertField asserts value extracted with jmesPath against value extracted with expectJmesPath from the same response,
+// e.g. AssertField("body.total", "equals", "length(body.items)", "") for internal consistency checks.
+func (s *StepRequestValidation) AssertField(jmesPath string, assertMethod string, expectJmesPath string, msg string) *StepRequestValidation {
+	v := Validator{
+		Check:      jmesPath,
+		Assert:     assertMethod,
+		ExpectPath: expectJmesPath,
+		Message:    msg,
+	}
+	s.step.Validators = append(s.step.Validators, v)
+	return s
+}
+
+// AssertFieldEqual asserts value extracted with jmesPath equals to value extracted with expectJmesPath from the same response.
+func (s *StepRequestValidation) AssertFieldEqual(jmesPath string, expectJmesPath string, msg string) *StepRequestValidation {
+	return s.AssertField(jmesPath, "equals", expectJmesPath, msg)
+}
+
+// AssertExists asserts field located by jmesPath exists, even if its value is null.
+func (s *StepRequestValidation) AssertExists(jmesPath string, msg string) *StepRequestValidation {
+	v := Validator{
+		Check:   jmesPath,
+		Assert:  "exists",
+		Expect:  true,
+		Message: msg,
+	}
+	s.step.Validators = append(s.step.Validators, v)
+	return s
+}
+
+// AssertNotExists asserts field located by jmesPath is missing, field with null value is regarded as existing.
+func (s *StepRequestValidation) AssertNotExists(jmesPath string, msg string) *StepRequestValidation {
+	v := Validator{
+		Check:   jmesPath,
+		Assert:  "not_exists",
+		Expect:  true,
+		Message: msg,
+	}
+	s.step.Validators = append(s.step.Validators, v)
+	return s
+}
+
+// AssertStatusOK asserts status code equals to 200.
+func (s *StepRequestValidation) AssertStatusOK(msg string) *StepRequestValidation {
+	return s.AssertEqual("status_code", 200, msg)
+}
+
+// AssertStatus2xx asserts status code is 2xx.
+func (s *StepRequestValidation) AssertStatus2xx(msg string) *StepRequestValidation {
+	return s.assertStatusIn("2xx", msg)
+}
+
+// AssertStatus3xx asserts status code is 3xx.
+func (s *StepRequestValidation) AssertStatus3xx(msg string) *StepRequestValidation {
+	return s.assertStatusIn("3xx", msg)
+}
+
+// AssertStatus4xx asserts status code is 4xx.
+func (s *StepRequestValidation) AssertStatus4xx(msg string) *StepRequestValidation {
+	return s.assertStatusIn("4xx", msg)
+}
+
+// AssertStatus5xx asserts status code is 5xx.
+func (s *StepRequestValidation) AssertStatus5xx(msg string) *StepRequestValidation {
+	return s.assertStatusIn("5xx", msg)
+}
+
+// AssertStatusIn asserts status code is one of codes, e.g. []int{200, 204}
+func (s *StepRequestValidation) AssertStatusIn(codes []int, msg string) *StepRequestValidation {
+	return s.assertStatusIn(codes, msg)
+}
+
+func (s *StepRequestValidation) assertStatusIn(expected interface{}, msg string) *StepRequestValidation {
+	v := Validator{
+		Check:   "status_code",
+		Assert:  "status_in",
+		Expect:  expected,
+		Message: msg,
+	}
+	s.step.Validators = append(s.step.Validators, v)
+	return s
+}
+
+// AssertHeaderEqual asserts response header equals to expected, header name is case-insensitive.
+func (s *StepRequestValidation) AssertHeaderEqual(name string, expected string, msg string) *StepRequestValidation {
+	v := Validator{
+		Check:   headerCheckExpr(name),
+		Assert:  "equals",
+		Expect:  expected,
+		Message: msg,
+	}
+	s.step.Validators = append(s.step.Validators, v)
+	return s
+}
+
+// AssertHeaderContains asserts response header contains expected substring, header name is case-insensitive.
+func (s *StepRequestValidation) AssertHeaderContains(name string, expected string, msg string) *StepRequestValidation {
+	v := Validator{
+		Check:   headerCheckExpr(name),
+		Assert:  "contains",
+		Expect:  expected,
+		Message: msg,
+	}
+	s.step.Validators = append(s.step.Validators, v)
+	return s
+}
+
+// AssertHeaderExists asserts response header exists, header name is case-insensitive.
+func (s *StepRequestValidation) AssertHeaderExists(name string, msg string) *StepRequestValidation {
+	v := Validator{
+		Check:   headerCheckExpr(name),
+		Assert:  "exists",
+		Expect:  true,
+		Message: msg,
+	}
+	s.step.Validators = append(s.step.Validators, v)
+	return s
+}
+
+// AssertCertValidFor asserts server certificate expires no earlier than days from now.
+func (s *StepRequestValidation) AssertCertValidFor(days int, msg string) *StepRequestValidation {
+	v := Validator{
+		Check:   "conn.tls.cert_days_left",
+		Assert:  "greater_or_equals",
+		Expect:  days,
+		Message: msg,
+	}
+	s.step.Validators = append(s.step.Validators, v)
+	return s
+}
+
+// AssertJWTValid asserts jwt located by jmespath is signed by key of testcase config and is not expired,
+// token can be prefixed with Bearer, e.g. AssertJWTValid("headers.Authorization", "check token").
+func (s *StepRequestValidation) AssertJWTValid(tokenPath, msg string) *StepRequestValidation {
+	v := Validator{
+		Check:   jwtExtractorPrefix + tokenPath + "|valid",
+		Assert:  "equals",
+		Expect:  true,
+		Message: msg,
+	}
+	s.step.Validators = append(s.step.Validators, v)
+	return s
+}
+
+// AssertJWTClaim asserts claim of jwt located by jmespath equals expected value,
+// e.g. AssertJWTClaim("body.access_token", "aud", "api", "check audience").
+func (s *StepRequestValidation) AssertJWTClaim(tokenPath, claim string, expected interface{}, msg string) *StepRequestValidation {
+	v := Validator{
+		Check:   jwtClaimCheckExpr(tokenPath, claim),
+		Assert:  "equals",
+		Expect:  expected,
+		Message: msg,
+	}
+	s.step.Validators = append(s.step.Validators, v)
+	return s
+}
+
+// jwtClaimCheckExpr returns jwt extractor of claim, claim name is quoted since it may be url, e.g. https://example.com/roles
+func jwtClaimCheckExpr(tokenPath, claim string) string {
+	return fmt.Sprintf(`%s%s|claims."%s"`, jwtExtractorPrefix, tokenPath, claim)
+}
+
+// headerCheckExpr returns jmespath of response header, e.g. content-type => headers."Content-Type",
+// since header names of response are canonicalized.
+func headerCheckExpr(name string) string {
+	return fmt.Sprintf(`headers."%s"`, http.CanonicalHeaderKey(name))
+}
+
+// AssertAnyOf asserts at least one of the validators passes, e.g. status code is 200 or 204.
+func (s *StepRequestValidation) AssertAnyOf(validators ...Validator) *StepRequestValidation {
+	v := Validator{
+		AnyOf: validators,
+	}
+	s.step.Validators = append(s.step.Validators, v)
+	return s
+}
+
+// AssertNot asserts the validator fails, e.g. body.error is not equal to "timeout".
+func (s *StepRequestValidation) AssertNot(validator Validator) *StepRequestValidation {
+	v := Validator{
+		Not: &validator,
+	}
+	s.step.Validators = append(s.step.Validators, v)
+	return s
+}
+
 func (s *StepRequestValidation) AssertLengthLessOrEquals(jmesPath string, expected interface{}, msg string) *StepRequestValidation {
 	v := Validator{
 		Check:   jmesPath,
@@ -955,10 +2116,38 @@ func (s *StepRequestValidation) AssertLengthGreaterOrEquals(jmesPath string, exp
 	return s
 }
 
+// AssertJSONEquals deep compares json value extracted by jmesPath with expected,
+// fields located by ignorePaths (relative to the checked value) are excluded.
+func (s *StepRequestValidation) AssertJSONEquals(jmesPath string, expected interface{}, ignorePaths ...string) *StepRequestValidation {
+	v := Validator{
+		Check:  jmesPath,
+		Assert: "json_equals",
+		Expect: expected,
+		Ignore: ignorePaths,
+	}
+	s.step.Validators = append(s.step.Validators, v)
+	return s
+}
+
+// AssertSnapshot compares response with snapshot golden file named by name,
+// the golden file will be created on first run. Fields located by ignorePaths are excluded.
+func (s *StepRequestValidation) AssertSnapshot(name string, ignorePaths ...string) *StepRequestValidation {
+	s.step.Snapshot = &Snapshot{
+		Name:   name,
+		Ignore: ignorePaths,
+	}
+	return s
+}
+
 // Validator represents validator for one HTTP response.
 type Validator struct {
-	Check   string      `json:"check" yaml:"check"` // get value with jmespath
-	Assert  string      `json:"assert" yaml:"assert"`
-	Expect  interface{} `json:"expect" yaml:"expect"`
-	Message string      `json:"msg,omitempty" yaml:"msg,omitempty"` // optional
+	Check      string      `json:"check" yaml:"check"` // get value with jmespath
+	Assert     string      `json:"assert" yaml:"assert"`
+	Expect     interface{} `json:"expect" yaml:"expect"`
+	Message    string      `json:"msg,omitempty" yaml:"msg,omitempty"`                 // optional
+	Ignore     []string    `json:"ignore,omitempty" yaml:"ignore,omitempty"`           // optional, jmespath of fields to ignore in json comparison
+	Epsilon    float64     `json:"epsilon,omitempty" yaml:"epsilon,omitempty"`         // optional, max allowed difference of approx_equal, default to 1e-6
+	ExpectPath string      `json:"expect_path,omitempty" yaml:"expect_path,omitempty"` // optional, jmespath of expected value in the same response instead of expect, e.g. length(body.items)
+	Not        *Validator  `json:"not,omitempty" yaml:"not,omitempty"`                 // optional, validator group passes if the nested validator fails
+	AnyOf      []Validator `json:"any_of,omitempty" yaml:"any_of,omitempty"`           // optional, validator group passes if any of the nested validators passes
 }
@@ -1,7 +1,20 @@
 package hrp
 
 import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/httprunner/httprunner/hrp/internal/json"
 )
 
 var (
@@ -88,3 +101,453 @@ func TestRunRequestRun(t *testing.T) {
 		t.Fatalf("stepPOSTData.Run() error: %v", err)
 	}
 }
+
+func TestRunRequestWithMaxBodySize(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte(strings.Repeat("a", 1<<20)))
+	}))
+	defer ts.Close()
+
+	testcase := &TestCase{
+		Config: NewConfig("max body size").SetBaseURL(ts.URL).SetMaxBodySize(10),
+		TestSteps: []IStep{
+			NewStep("giant response").
+				GET("/").
+				Validate().
+				AssertEqual("body", "aaaaaaaaaa", "check truncated body"),
+		},
+	}
+	sessionRunner := NewRunner(t).NewSessionRunner(testcase)
+	if !assert.Nil(t, sessionRunner.Start()) {
+		t.Fatal()
+	}
+	sessionData := sessionRunner.GetSummary().Records[0].Data.(*SessionData)
+	assert.True(t, sessionData.Truncated)
+
+	// truncated json body is not streamed
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data": "` + strings.Repeat("a", 1<<20) + `"}`))
+	}))
+	defer ts.Close()
+	testcase = &TestCase{
+		Config: NewConfig("max body size").SetBaseURL(ts.URL).SetMaxBodySize(20),
+		TestSteps: []IStep{
+			NewStep("giant json response").
+				GET("/").
+				Validate().
+				AssertEqual("status_code", 200, "check status code").
+				AssertEqual("body.data", nil, "check truncated body"),
+		},
+	}
+	sessionRunner = NewRunner(t).SetJSONStreamThreshold(10).NewSessionRunner(testcase)
+	if !assert.Nil(t, sessionRunner.Start()) {
+		t.Fatal()
+	}
+	sessionData = sessionRunner.GetSummary().Records[0].Data.(*SessionData)
+	assert.True(t, sessionData.Truncated)
+}
+
+func TestNewRequestMap(t *testing.T) {
+	requestMap := newRequestMap(stepPOSTData.step.Request)
+	assert.Equal(t, "POST", requestMap["method"])
+	assert.Equal(t, "/post", requestMap["url"])
+	assert.Equal(t, "a=1&b=2", requestMap["body"])
+	assert.Contains(t, requestMap, "params")
+	assert.Contains(t, requestMap, "headers")
+	assert.Contains(t, requestMap, "cookies")
+	// omit empty fields, the same as json tags of Request
+	for _, key := range []string{"json", "data", "timeout", "allow_redirects", "verify"} {
+		assert.NotContains(t, requestMap, key)
+	}
+}
+
+func BenchmarkBuildStepRequest(b *testing.B) {
+	parser := newParser()
+	config := NewConfig("benchmark").SetBaseURL("https://postman-echo.com").
+		WithVariables(map[string]interface{}{"foo": "bar"})
+	stepRequest := NewStep("post json").
+		POST("/post").
+		WithParams(map[string]interface{}{"foo1": "$foo", "foo2": "bar2"}).
+		WithHeaders(map[string]string{"User-Agent": "HttpRunnerPlus"}).
+		WithBody(map[string]interface{}{"foo": "$foo", "list": []int{1, 2, 3}}).
+		step.Request
+	stepVariables := map[string]interface{}{"foo": "bar"}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := buildStepRequest(parser, config, stepRequest, stepVariables); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestDecodeResponseCharset(t *testing.T) {
+	testData := []struct {
+		contentType string
+		body        []byte
+		expected    string
+	}{
+		{"text/plain; charset=GBK", []byte{0xc4, 0xe3, 0xba, 0xc3}, "你好"},
+		{"text/html; charset=Shift_JIS", []byte{0x82, 0xb1, 0x82, 0xf1}, "こん"},
+		{"text/plain; charset=ISO-8859-1", []byte{0x63, 0x61, 0x66, 0xe9}, "café"},
+		{"application/json; charset=utf-8", []byte(`"你好"`), `"你好"`},
+		{"text/plain", []byte("hello"), "hello"},
+		{"text/plain; charset=unknown", []byte("hello"), "hello"},
+	}
+	for _, data := range testData {
+		resp := &http.Response{
+			Header: http.Header{"Content-Type": []string{data.contentType}},
+			Body:   io.NopCloser(bytes.NewReader(data.body)),
+		}
+		if !assert.Nil(t, decodeResponseBody(resp)) {
+			t.Fatal()
+		}
+		body, err := io.ReadAll(resp.Body)
+		assert.Nil(t, err)
+		assert.Nil(t, resp.Body.Close())
+		assert.Equal(t, data.expected, string(body), data.contentType)
+	}
+}
+
+func TestRunRequestWithCustomMethod(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte(r.Method))
+	}))
+	defer ts.Close()
+
+	testcase := &TestCase{
+		Config: NewConfig("custom method").SetBaseURL(ts.URL),
+		TestSteps: []IStep{
+			NewStep("purge cache").
+				CustomMethod("PURGE", "/cache").
+				Validate().
+				AssertEqual("body", "PURGE", "check method"),
+			NewStep("webdav propfind").
+				CustomMethod("PROPFIND", "/dav").
+				WithHeaders(map[string]string{"Depth": "1"}).
+				Validate().
+				AssertEqual("body", "PROPFIND", "check method"),
+		},
+	}
+	if !assert.Nil(t, NewRunner(t).Run(testcase)) {
+		t.Fatal()
+	}
+
+	// method in testcase file is not validated against predefined methods
+	tc := &TCase{}
+	err := json.Unmarshal([]byte(`{"config": {"name": "webdav"}, "teststeps": [
+		{"name": "mkcol", "request": {"method": "MKCOL", "url": "/dav/new"}}]}`), tc)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	assert.Equal(t, HTTPMethod("MKCOL"), tc.TestSteps[0].Request.Method)
+}
+
+func TestEncodeParams(t *testing.T) {
+	params := map[string]interface{}{
+		"id":   []interface{}{1, 2},
+		"name": "a b",
+		"foo":  "bar",
+		"q":    "%2F",
+	}
+	assert.Equal(t, "foo=bar&id=1&id=2&name=a+b&q=%252F", encodeParams(params, nil, false))
+	assert.Equal(t, "q=%252F&name=a+b&foo=bar&id=1&id=2", encodeParams(params, []string{"q", "name", "missing", "q"}, false))
+	// pre-encoded params are not double-encoded
+	assert.Equal(t, "q=%2F&foo=bar&id=1&id=2&name=a b", encodeParams(params, []string{"q"}, true))
+}
+
+func TestRunRequestWithParamsArray(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte(r.URL.RawQuery))
+	}))
+	defer ts.Close()
+
+	testcase := &TestCase{
+		Config: NewConfig("params array").SetBaseURL(ts.URL).
+			WithVariables(map[string]interface{}{"ids": []interface{}{3, 4}}),
+		TestSteps: []IStep{
+			NewStep("repeated keys").
+				GET("/get?a=0").
+				WithParams(map[string]interface{}{"id": "$ids", "b": "x"}).
+				WithParamsOrder("id").
+				Validate().
+				AssertEqual("body", "a=0&id=3&id=4&b=x", "check query"),
+			NewStep("pre-encoded").
+				GET("/get").
+				WithParams(map[string]interface{}{"path": "a%2Fb"}).
+				WithEncodedParams().
+				Validate().
+				AssertEqual("body", "path=a%2Fb", "check query"),
+		},
+	}
+	assert.Nil(t, NewRunner(t).Run(testcase))
+}
+
+func TestParseRawURL(t *testing.T) {
+	u, err := parseRawURL("https://example.com/a%2Fb/../c//d?x=%zz")
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	assert.Equal(t, "example.com", u.Host)
+	assert.Equal(t, "/a%2Fb/../c//d?x=%zz", u.RequestURI())
+	assert.Equal(t, "https://example.com/a%2Fb/../c//d?x=%zz", displayURL(u))
+
+	u, err = parseRawURL("http://example.com//admin")
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	assert.Equal(t, "http://example.com//admin", u.RequestURI())
+	assert.Equal(t, "http://example.com//admin", displayURL(u))
+
+	_, err = parseRawURL("/relative")
+	assert.NotNil(t, err)
+
+	assert.Equal(t, "http://example.com/api/../x", joinRawURL("http://example.com/api/", "/../x"))
+	assert.Equal(t, "https://other.com/x", joinRawURL("http://example.com", "https://other.com/x"))
+}
+
+func TestRunRequestWithRawURL(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte(r.RequestURI))
+	}))
+	defer ts.Close()
+
+	testcase := &TestCase{
+		Config: NewConfig("raw url").SetBaseURL(ts.URL),
+		TestSteps: []IStep{
+			NewStep("raw url").
+				GET("/files/..%2F..%2Fetc/passwd//x").
+				WithParams(map[string]interface{}{"a": "b"}).
+				WithRawURL().
+				Validate().
+				AssertEqual("body", "/files/..%2F..%2Fetc/passwd//x?a=b", "check request uri"),
+			NewStep("normalized url").
+				GET("/files/../x").
+				Validate().
+				AssertEqual("body", "/x", "check request uri"),
+		},
+	}
+	assert.Nil(t, NewRunner(t).Run(testcase))
+}
+
+func TestRunRequestWithHeaderList(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte(strings.Join(r.Header["X-Dup"], ",")))
+	}))
+	defer ts.Close()
+
+	step := NewStep("duplicate headers").
+		GET("/get").
+		WithHeaders(map[string]string{"X-Dup": "first"}).
+		AddHeader("X-Dup", "second").
+		AddHeader("X-Dup", "$third").
+		AddHeader("x-lower-case", "1")
+	rb, err := buildStepRequest(newParser(), NewConfig("header list"), step.step.Request,
+		map[string]interface{}{"third": "third"})
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	assert.Equal(t, []string{"first", "second", "third"}, rb.req.Header["X-Dup"])
+	assert.Equal(t, []string{"1"}, rb.req.Header["x-lower-case"]) // name is not canonicalized
+
+	testcase := &TestCase{
+		Config: NewConfig("header list").SetBaseURL(ts.URL).
+			WithVariables(map[string]interface{}{"third": "third"}),
+		TestSteps: []IStep{
+			step.Validate().AssertEqual("body", "first,second,third", "check duplicate headers"),
+		},
+	}
+	assert.Nil(t, NewRunner(t).Run(testcase))
+}
+
+func TestRunRequestWithHeaderAssertions(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("x-request-id", "")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	testcase := &TestCase{
+		Config: NewConfig("header assertions").SetBaseURL(ts.URL),
+		TestSteps: []IStep{
+			NewStep("get").
+				GET("/get").
+				Validate().
+				AssertHeaderEqual("content-type", "application/json; charset=utf-8", "check content type").
+				AssertHeaderContains("CONTENT-TYPE", "json", "check content type").
+				AssertHeaderExists("X-Request-ID", "check request id"),
+		},
+	}
+	assert.Nil(t, NewRunner(t).Run(testcase))
+
+	testcase = &TestCase{
+		Config: NewConfig("header missing").SetBaseURL(ts.URL),
+		TestSteps: []IStep{
+			NewStep("get").GET("/get").Validate().AssertHeaderExists("x-trace-id", "check trace id"),
+		},
+	}
+	assert.NotNil(t, NewRunner(nil).Run(testcase))
+}
+
+func TestRunRequestWithStatusAssertions(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/created":
+			w.WriteHeader(http.StatusCreated)
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	testcase := &TestCase{
+		Config: NewConfig("status assertions").SetBaseURL(ts.URL),
+		TestSteps: []IStep{
+			NewStep("ok").GET("/").Validate().AssertStatusOK("check status code"),
+			NewStep("created").GET("/created").Validate().
+				AssertStatus2xx("check status class").
+				AssertStatusIn([]int{200, 201}, "check status code"),
+			NewStep("missing").GET("/missing").Validate().AssertStatus4xx("check status class"),
+		},
+	}
+	assert.Nil(t, NewRunner(t).Run(testcase))
+
+	compat := []interface{}{
+		map[string]interface{}{"status_in": []interface{}{"status_code", []interface{}{"2xx", 404}}},
+	}
+	if !assert.Nil(t, convertCompatValidator(compat)) {
+		t.Fatal()
+	}
+	step := NewStep("missing").GET("/missing")
+	step.step.Validators = compat
+	testcase = &TestCase{
+		Config:    NewConfig("status in").SetBaseURL(ts.URL),
+		TestSteps: []IStep{step},
+	}
+	assert.Nil(t, NewRunner(t).Run(testcase))
+
+	testcase = &TestCase{
+		Config: NewConfig("status class mismatch").SetBaseURL(ts.URL),
+		TestSteps: []IStep{
+			NewStep("missing").GET("/missing").Validate().AssertStatus5xx("check status class"),
+		},
+	}
+	assert.NotNil(t, NewRunner(nil).Run(testcase))
+}
+
+func TestRunRequestWithUserAgentRotation(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte(r.UserAgent()))
+	}))
+	defer ts.Close()
+
+	testcase := &TestCase{
+		Config: NewConfig("user agent rotation").SetBaseURL(ts.URL).
+			SetUserAgents(userAgentRoundRobin, "ua1", "ua2"),
+		TestSteps: []IStep{
+			NewStep("first").GET("/").
+				Validate().AssertEqual("body", "ua1", "check user agent"),
+			NewStep("second").GET("/").
+				Validate().AssertEqual("body", "ua2", "check user agent"),
+			NewStep("explicit user agent").GET("/").
+				AddHeader("User-Agent", "explicit").
+				Validate().AssertEqual("body", "explicit", "check user agent"),
+			NewStep("third").GET("/").
+				Validate().AssertEqual("body", "ua1", "check user agent"),
+		},
+	}
+	assert.Nil(t, NewRunner(t).Run(testcase))
+
+	fixed := &UserAgentConfig{Profiles: []string{"ua1", "ua2"}}
+	assert.Equal(t, "ua1", fixed.next())
+	assert.Equal(t, "ua1", fixed.next())
+	random := &UserAgentConfig{Strategy: userAgentRandom, Profiles: []string{"ua1", "ua2"}}
+	assert.Contains(t, []string{"ua1", "ua2"}, random.next())
+	assert.Equal(t, "", (&UserAgentConfig{}).next())
+}
+
+func TestRunRequestWithBodyFile(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+		_, _ = io.Copy(w, r.Body)
+	}))
+	defer ts.Close()
+
+	dir := t.TempDir()
+	jsonTmpl := filepath.Join(dir, "create_order.json.tmpl")
+	err := os.WriteFile(jsonTmpl, []byte(`{"user": "$user", "amount": ${max(1, 3)}, "items": ["a", "b"]}`), 0o644)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	textTmpl := filepath.Join(dir, "message.txt.tmpl")
+	if !assert.Nil(t, os.WriteFile(textTmpl, []byte("hello $user"), 0o644)) {
+		t.Fatal()
+	}
+
+	rb, err := buildStepRequest(newParser(), NewConfig("body file"),
+		NewStep("json").POST("/post").WithBodyFile(jsonTmpl).step.Request,
+		map[string]interface{}{"user": "debugtalk"})
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	assert.Equal(t, "application/json; charset=utf-8", rb.req.Header.Get("Content-Type"))
+	assert.Equal(t, map[string]interface{}{
+		"user": "debugtalk", "amount": float64(3), "items": []interface{}{"a", "b"},
+	}, rb.requestMap["body"])
+
+	testcase := &TestCase{
+		Config: NewConfig("body file").SetBaseURL(ts.URL).
+			WithVariables(map[string]interface{}{"user": "debugtalk"}),
+		TestSteps: []IStep{
+			NewStep("json body file").POST("/post").WithBodyFile(jsonTmpl).
+				Validate().
+				AssertEqual("body.user", "debugtalk", "check rendered variable").
+				AssertEqual("body.amount", 3, "check rendered function"),
+			NewStep("text body file").POST("/post").WithBodyFile(textTmpl).
+				Validate().
+				AssertEqual("body", "hello debugtalk", "check rendered text"),
+		},
+	}
+	assert.Nil(t, NewRunner(t).Run(testcase))
+
+	// missing body file
+	_, err = buildStepRequest(newParser(), NewConfig("body file"),
+		NewStep("missing").POST("/post").WithBodyFile(filepath.Join(dir, "missing.json")).step.Request, nil)
+	assert.NotNil(t, err)
+
+	// relative body file is resolved against project root dir
+	request := &Request{BodyFile: "templates/create_order.json.tmpl"}
+	request.resolveFilePaths(dir)
+	assert.Equal(t, filepath.Join(dir, "templates", "create_order.json.tmpl"), request.bodyFilePath)
+}
+
+func TestRequestTraceRace(t *testing.T) {
+	trace := &requestTrace{}
+	clientTrace := trace.clientTrace()
+
+	// trace callbacks may be called by dialing goroutines of transport after request is done
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		clientTrace.DNSStart(httptrace.DNSStartInfo{})
+		clientTrace.DNSDone(httptrace.DNSDoneInfo{})
+		clientTrace.ConnectStart("tcp", "127.0.0.1:80")
+		clientTrace.ConnectDone("tcp", "127.0.0.1:80", nil)
+	}()
+	timings := trace.timings(time.Second)
+	<-done
+	assert.Equal(t, time.Second, timings.Total)
+	assert.GreaterOrEqual(t, trace.timings(time.Second).TCPConnection, time.Duration(0))
+	reused, address := trace.conn()
+	assert.False(t, reused)
+	assert.Nil(t, address)
+}
@@ -0,0 +1,213 @@
+package hrp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"reflect"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// ShellCommand represents shell step, which runs local command, e.g. seeding script or CLI, in the middle of testcase.
+// scalar step variables are injected as environment variables, stdout, stderr without trailing newlines and exit_code
+// are captured and validated the same as response of request step, non-zero exit code does not fail the step itself.
+type ShellCommand struct {
+	Command string            `json:"command" yaml:"command"`                     // required, run with sh -c, or cmd /C on windows, as is without rendering, $var is expanded by shell from environment
+	Env     map[string]string `json:"env,omitempty" yaml:"env,omitempty"`         // extra environment variables, variables and functions are supported
+	Dir     string            `json:"dir,omitempty" yaml:"dir,omitempty"`         // working directory, default to current directory
+	Timeout float64           `json:"timeout,omitempty" yaml:"timeout,omitempty"` // seconds, default to 10
+}
+
+// Shell creates a new shell step, which runs command with sh -c.
+func (s *StepRequest) Shell(command string) *StepShell {
+	s.step.Shell = &ShellCommand{
+		Command: command,
+	}
+	return &StepShell{
+		step: s.step,
+	}
+}
+
+// StepShell implements IStep interface.
+type StepShell struct {
+	step *TStep
+}
+
+// WithEnv sets extra environment variables of command.
+func (s *StepShell) WithEnv(env map[string]string) *StepShell {
+	s.step.Shell.Env = env
+	return s
+}
+
+// WithDir sets working directory of command.
+func (s *StepShell) WithDir(dir string) *StepShell {
+	s.step.Shell.Dir = dir
+	return s
+}
+
+// WithTimeout sets timeout seconds of command, command is killed when timed out.
+func (s *StepShell) WithTimeout(timeout float64) *StepShell {
+	s.step.Shell.Timeout = timeout
+	return s
+}
+
+// Extract extracts variable from command output with jmesPath, e.g. stdout
+func (s *StepShell) Extract(jmesPath string, varName string) *StepShell {
+	if s.step.Extract == nil {
+		s.step.Extract = make(map[string]string)
+	}
+	s.step.Extract[varName] = jmesPath
+	return s
+}
+
+// AssertEqual asserts value extracted from command output with jmesPath equals to expected.
+func (s *StepShell) AssertEqual(jmesPath string, expected interface{}, msg string) *StepShell {
+	s.step.Validators = append(s.step.Validators, Validator{
+		Check:   jmesPath,
+		Assert:  "equals",
+		Expect:  expected,
+		Message: msg,
+	})
+	return s
+}
+
+// AssertContains asserts value extracted from command output with jmesPath contains expected, e.g. stderr
+func (s *StepShell) AssertContains(jmesPath string, expected interface{}, msg string) *StepShell {
+	s.step.Validators = append(s.step.Validators, Validator{
+		Check:   jmesPath,
+		Assert:  "contains",
+		Expect:  expected,
+		Message: msg,
+	})
+	return s
+}
+
+func (s *StepShell) Name() string {
+	if s.step.Name != "" {
+		return s.step.Name
+	}
+	return fmt.Sprintf("shell %s", s.step.Shell.Command)
+}
+
+func (s *StepShell) Type() StepType {
+	return stepTypeShell
+}
+
+func (s *StepShell) Struct() *TStep {
+	return s.step
+}
+
+func (s *StepShell) Run(r *SessionRunner) (*StepResult, error) {
+	stepResult := &StepResult{
+		Name:     s.Name(),
+		StepType: stepTypeShell,
+		Success:  false,
+	}
+	start := time.Now()
+	err := s.run(r, stepResult)
+	stepResult.Elapsed = time.Since(start).Milliseconds()
+	if err != nil {
+		stepResult.Attachment = err.Error()
+		return stepResult, err
+	}
+	stepResult.Success = true
+	return stepResult, nil
+}
+
+func (s *StepShell) run(r *SessionRunner, stepResult *StepResult) error {
+	stepVariables, err := r.MergeStepVariables(s.step.Variables)
+	if err != nil {
+		return err
+	}
+	cfg := s.step.Shell
+	// command is not rendered, otherwise values of variables, e.g. extracted from response, are spliced into shell syntax,
+	// variables are passed as environment variables instead
+	command := cfg.Command
+	if strings.TrimSpace(command) == "" {
+		return errors.New("shell command should not be empty")
+	}
+	dir, err := r.parser.ParseString(cfg.Dir, stepVariables)
+	if err != nil {
+		return err
+	}
+	env, err := r.parser.ParseHeaders(cfg.Env, stepVariables)
+	if err != nil {
+		return err
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultMessageTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout*1000)*time.Millisecond)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	cmd.Dir = convertString(dir)
+	cmd.Env = shellEnv(stepVariables, env)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	exitCode := 0
+	if err := cmd.Start(); err != nil {
+		return errors.Wrap(err, "run shell command failed")
+	}
+	// output of orphan child processes is not waited after command is killed
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		_ = cmd.Process.Kill()
+		return fmt.Errorf("shell command timeout after %vs", timeout)
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		exitCode = exitErr.ExitCode()
+	} else if err != nil {
+		return errors.Wrap(err, "run shell command failed")
+	}
+	log.Info().Str("command", command).Int("exitCode", exitCode).Msg("run shell command")
+
+	stepResult.ContentSize = int64(stdout.Len())
+	output := map[string]interface{}{
+		"command":   command,
+		"stdout":    strings.TrimRight(stdout.String(), "\r\n"),
+		"stderr":    strings.TrimRight(stderr.String(), "\r\n"),
+		"exit_code": exitCode,
+	}
+	return r.checkOutput(s.step, output, stepVariables, stepResult)
+}
+
+// shellEnv returns environment of shell command, which inherits current process environment,
+// scalar step variables are injected with the same names and extra env overrides them.
+func shellEnv(stepVariables map[string]interface{}, env map[string]string) []string {
+	environ := os.Environ()
+	for name, value := range stepVariables {
+		if value == nil {
+			continue
+		}
+		switch reflect.ValueOf(value).Kind() {
+		case reflect.String, reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+			environ = append(environ, fmt.Sprintf("%s=%v", name, value))
+		}
+	}
+	for name, value := range env {
+		environ = append(environ, fmt.Sprintf("%s=%s", name, value))
+	}
+	return environ
+}
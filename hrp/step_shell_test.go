@@ -0,0 +1,62 @@
+package hrp
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunCaseWithShell(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell syntax of sh is used")
+	}
+	testcase := &TestCase{
+		Config: NewConfig("shell step").
+			WithVariables(map[string]interface{}{"user": "debugtalk", "count": 3}),
+		TestSteps: []IStep{
+			NewStep("seed data").Shell("echo $user; printenv count; echo warning >&2").
+				WithEnv(map[string]string{"count": "${max(1, 4)}"}).
+				Extract("stdout", "seeded").
+				AssertEqual("stdout", "debugtalk\n4", "check env injected").
+				AssertContains("stderr", "warning", "check stderr").
+				AssertEqual("exit_code", 0, "check exit code"),
+			NewStep("failed command").Shell("exit 3").
+				AssertEqual("exit_code", 3, "check non-zero exit code"),
+		},
+	}
+	sessionRunner := NewRunner(t).NewSessionRunner(testcase)
+	if !assert.Nil(t, sessionRunner.Start()) {
+		t.Fatal()
+	}
+	assert.Equal(t, "debugtalk\n4", sessionRunner.sessionVariables["seeded"])
+}
+
+func TestRunCaseWithShellInjection(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell syntax of sh is used")
+	}
+	testcase := &TestCase{
+		Config: NewConfig("shell injection").
+			WithVariables(map[string]interface{}{"name": "x; echo injected"}),
+		TestSteps: []IStep{
+			NewStep("variables are passed as environment").Shell(`echo "$name"; echo "$HOME"`).
+				WithEnv(map[string]string{"HOME": "/home/hrp"}).
+				AssertEqual("stdout", "x; echo injected\n/home/hrp", "check variables are not spliced into command"),
+		},
+	}
+	assert.Nil(t, NewRunner(t).Run(testcase))
+}
+
+func TestRunCaseWithShellTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell syntax of sh is used")
+	}
+	testcase := &TestCase{
+		Config: NewConfig("shell timeout"),
+		TestSteps: []IStep{
+			NewStep("sleep").Shell("sleep 3").WithTimeout(0.2),
+		},
+	}
+	assert.NotNil(t, NewRunner(nil).Run(testcase))
+}
@@ -1,12 +1,16 @@
 package hrp
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/jinzhu/copier"
 	"github.com/rs/zerolog/log"
 )
 
+const defaultMaxCallDepth = 10
+
 // StepTestCaseWithOptionalArgs implements IStep interface.
 type StepTestCaseWithOptionalArgs struct {
 	step *TStep
@@ -50,6 +54,14 @@ func (s *StepTestCaseWithOptionalArgs) Run(r *SessionRunner) (*StepResult, error
 		Success:  false,
 	}
 
+	// check nested testcase calls before running
+	refTestCase, _ := s.step.TestCase.(*TestCase)
+	callChain := append(r.callChain[:len(r.callChain):len(r.callChain)], refTestCase)
+	if err := checkCallChain(callChain, r.hrpRunner.maxCallDepth); err != nil {
+		stepResult.Attachment = err.Error()
+		return stepResult, err
+	}
+
 	stepVariables, err := r.MergeStepVariables(s.step.Variables)
 	if err != nil {
 		return stepResult, err
@@ -69,13 +81,17 @@ func (s *StepTestCaseWithOptionalArgs) Run(r *SessionRunner) (*StepResult, error
 	extendWithTestCase(s.step, copiedTestCase)
 
 	sessionRunner := r.hrpRunner.NewSessionRunner(copiedTestCase)
+	sessionRunner.callChain = callChain
+	sessionRunner.runCtx = r.runCtx
+	sessionRunner.ctx = r.ctx
+	sessionRunner.jar = r.jar
 
 	start := time.Now()
 	err = sessionRunner.Start()
 	stepResult.Elapsed = time.Since(start).Milliseconds()
 	if err != nil {
 		stepResult.Attachment = err.Error()
-		r.summary.Success = false
+		r.summary.fail()
 		return stepResult, err
 	}
 	summary := sessionRunner.GetSummary()
@@ -85,15 +101,10 @@ func (s *StepTestCaseWithOptionalArgs) Run(r *SessionRunner) (*StepResult, error
 	stepResult.Success = true
 
 	// update extracted variables
-	for k, v := range stepResult.ExportVars {
-		r.sessionVariables[k] = v
-	}
+	r.updateSessionVariables(stepResult.ExportVars)
 
 	// merge testcase summary
-	r.summary.Records = append(r.summary.Records, summary.Records...)
-	r.summary.Stat.Total += summary.Stat.Total
-	r.summary.Stat.Successes += summary.Stat.Successes
-	r.summary.Stat.Failures += summary.Stat.Failures
+	r.summary.merge(summary)
 
 	return stepResult, nil
 }
@@ -109,3 +120,29 @@ func extendWithTestCase(testStep *TStep, overriddenTestCase *TestCase) {
 	// merge & override extractors
 	overriddenTestCase.Config.Export = mergeSlices(testStep.Export, overriddenTestCase.Config.Export)
 }
+
+// checkCallChain checks cyclic references and max depth of nested testcase calls,
+// error contains the reference chain.
+func checkCallChain(callChain []*TestCase, maxDepth int) error {
+	var names []string
+	for _, tc := range callChain {
+		name := tc.Config.Path
+		if name == "" {
+			name = tc.Config.Name
+		}
+		names = append(names, name)
+	}
+
+	current := callChain[len(callChain)-1]
+	for _, tc := range callChain[:len(callChain)-1] {
+		if tc == current || (tc.Config.Path != "" && tc.Config.Path == current.Config.Path) {
+			return fmt.Errorf("cyclic testcase call found: %s", strings.Join(names, " -> "))
+		}
+	}
+
+	// root testcase is not counted as a nested call
+	if maxDepth > 0 && len(callChain)-1 > maxDepth {
+		return fmt.Errorf("testcase call depth exceeds max depth %d: %s", maxDepth, strings.Join(names, " -> "))
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package hrp
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadCaseWithCyclicReference(t *testing.T) {
+	cwd, _ := os.Getwd()
+	dir := t.TempDir()
+	pathA := filepath.Join(dir, "a.yaml")
+	pathB := filepath.Join(dir, "b.yaml")
+	relPathA, _ := filepath.Rel(cwd, pathA)
+	relPathB, _ := filepath.Rel(cwd, pathB)
+
+	content := `
+config:
+  name: %s
+teststeps:
+  - name: call %s
+    testcase: %s
+`
+	if err := os.WriteFile(pathA, []byte(fmt.Sprintf(content, "a", "b", relPathB)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(pathB, []byte(fmt.Sprintf(content, "b", "a", relPathA)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tc := TestCasePath(pathA)
+	_, err := tc.ToTestCase()
+	if !assert.NotNil(t, err) {
+		t.Fatal()
+	}
+	assert.Contains(t, err.Error(), fmt.Sprintf("cyclic testcase reference found: %s -> %s -> %s", pathA, pathB, pathA))
+}
+
+func TestRunCaseWithCallDepth(t *testing.T) {
+	// testcase3 -> testcase2 -> testcase1
+	newTestCases := func() (testcase1, testcase2, testcase3 *TestCase) {
+		testcase1 = &TestCase{
+			Config: NewConfig("testcase1"),
+			TestSteps: []IStep{
+				NewStep("transaction").StartTransaction("tx"),
+			},
+		}
+		testcase2 = &TestCase{
+			Config: NewConfig("testcase2"),
+			TestSteps: []IStep{
+				NewStep("").CallRefCase(testcase1),
+			},
+		}
+		testcase3 = &TestCase{
+			Config: NewConfig("testcase3"),
+			TestSteps: []IStep{
+				NewStep("").CallRefCase(testcase2),
+			},
+		}
+		return
+	}
+
+	_, _, testcase3 := newTestCases()
+	if !assert.Nil(t, NewRunner(t).SetMaxCallDepth(2).Run(testcase3)) {
+		t.Fatal()
+	}
+
+	_, _, testcase3 = newTestCases()
+	err := NewRunner(t).SetMaxCallDepth(1).Run(testcase3)
+	if !assert.NotNil(t, err) {
+		t.Fatal()
+	}
+	assert.Contains(t, err.Error(), "testcase call depth exceeds max depth 1: testcase3 -> testcase2 -> testcase1")
+
+	// testcase1 -> testcase2 -> testcase1
+	testcase1, testcase2, _ := newTestCases()
+	testcase1.TestSteps = append(testcase1.TestSteps, NewStep("").CallRefCase(testcase2))
+	err = NewRunner(t).Run(testcase1)
+	if !assert.NotNil(t, err) {
+		t.Fatal()
+	}
+	assert.Contains(t, err.Error(), "cyclic testcase call found: testcase1 -> testcase2 -> testcase1")
+}
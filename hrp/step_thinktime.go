@@ -38,7 +38,11 @@ func (s *StepThinkTime) Run(r *SessionRunner) (*StepResult, error) {
 		Success:  true,
 	}
 
-	cfg := r.testCase.Config.ThinkTimeSetting
+	// think time setting of runner overrides testcase config
+	cfg := r.hrpRunner.thinkTimeSetting
+	if cfg == nil {
+		cfg = r.testCase.Config.ThinkTimeSetting
+	}
 	if cfg == nil {
 		cfg = &ThinkTimeConfig{thinkTimeDefault, nil, 0}
 	}
@@ -74,6 +78,8 @@ func (s *StepThinkTime) Run(r *SessionRunner) (*StepResult, error) {
 		}
 	}
 
-	time.Sleep(tt)
+	if err := r.sleep(tt); err != nil {
+		return stepResult, err
+	}
 	return stepResult, nil
 }
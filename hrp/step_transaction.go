@@ -54,6 +54,9 @@ func (s *StepTransaction) Run(r *SessionRunner) (*StepResult, error) {
 		ContentSize: 0, // TODO: record transaction total response length
 	}
 
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
 	// create transaction if not exists
 	if _, ok := r.transactions[transaction.Name]; !ok {
 		r.transactions[transaction.Name] = make(map[transactionType]time.Time)
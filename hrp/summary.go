@@ -1,13 +1,16 @@
 package hrp
 
 import (
-	"bufio"
+	"bytes"
 	_ "embed"
 	"fmt"
 	"html/template"
+	"io"
+	"net"
 	"os"
 	"path/filepath"
 	"runtime"
+	"sync"
 	"time"
 
 	"github.com/httprunner/httprunner/hrp/internal/builtin"
@@ -15,6 +18,10 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+// SummarySchemaVersion is version of json schema of dumped summary, which is defined in schemas/summary.schema.json.
+// Minor version is bumped when fields are added, and major version is bumped when fields are removed or changed.
+const SummarySchemaVersion = "1.1"
+
 func newOutSummary() *Summary {
 	platForm := &Platform{
 		HttprunnerVersion: version.VERSION,
@@ -22,8 +29,9 @@ func newOutSummary() *Summary {
 		Platform:          fmt.Sprintf("%v-%v", runtime.GOOS, runtime.GOARCH),
 	}
 	return &Summary{
-		Success: true,
-		Stat:    &Stat{},
+		SchemaVersion: SummarySchemaVersion,
+		Success:       true,
+		Stat:          &Stat{},
 		Time: &TestCaseTime{
 			StartAt: time.Now(),
 		},
@@ -33,19 +41,28 @@ func newOutSummary() *Summary {
 
 // Summary stores tests summary for current task execution, maybe include one or multiple testcases
 type Summary struct {
-	Success  bool               `json:"success" yaml:"success"`
-	Stat     *Stat              `json:"stat" yaml:"stat"`
-	Time     *TestCaseTime      `json:"time" yaml:"time"`
-	Platform *Platform          `json:"platform" yaml:"platform"`
-	Details  []*TestCaseSummary `json:"details" yaml:"details"`
+	SchemaVersion string             `json:"schema_version" yaml:"schema_version"`
+	Success       bool               `json:"success" yaml:"success"`
+	Stat          *Stat              `json:"stat" yaml:"stat"`
+	Time          *TestCaseTime      `json:"time" yaml:"time"`
+	Platform      *Platform          `json:"platform" yaml:"platform"`
+	Details       []*TestCaseSummary `json:"details" yaml:"details"`
+	mutex         sync.Mutex         // protects summary when testcases are appended concurrently
 }
 
 func (s *Summary) appendCaseSummary(caseSummary *TestCaseSummary) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	caseSummary.mutex.Lock()
+	defer caseSummary.mutex.Unlock()
 	s.Success = s.Success && caseSummary.Success
 	s.Stat.TestCases.Total += 1
 	s.Stat.TestSteps.Total += len(caseSummary.Records)
 	if caseSummary.Success {
 		s.Stat.TestCases.Success += 1
+		if caseSummary.Flaky {
+			s.Stat.TestCases.Flaky += 1
+		}
 	} else {
 		s.Stat.TestCases.Fail += 1
 	}
@@ -55,6 +72,17 @@ func (s *Summary) appendCaseSummary(caseSummary *TestCaseSummary) {
 	s.Success = s.Success && caseSummary.Success
 }
 
+// dump writes summary into json file, parent folders are created if not exist.
+func (s *Summary) dump(path string) error {
+	dir, _ := filepath.Split(path)
+	if dir != "" {
+		if err := builtin.EnsureFolderExists(dir); err != nil {
+			return err
+		}
+	}
+	return dumpMaskedJSON(s, path)
+}
+
 func (s *Summary) genHTMLReport() error {
 	dir, _ := filepath.Split(reportPath)
 	err := builtin.EnsureFolderExists(dir)
@@ -67,14 +95,15 @@ func (s *Summary) genHTMLReport() error {
 		return err
 	}
 	defer file.Close()
-	writer := bufio.NewWriter(file)
+	// render whole report before masking secrets, which may be split in chunks written by template
+	var buf bytes.Buffer
 	tmpl := template.Must(template.New("report").Parse(reportTemplate))
-	err = tmpl.Execute(writer, s)
+	err = tmpl.Execute(&buf, s)
 	if err != nil {
 		log.Error().Err(err).Msg("execute applies a parsed template to the specified data object failed")
 		return err
 	}
-	err = writer.Flush()
+	_, err = io.WriteString(file, builtin.MaskSecrets(buf.String()))
 	return err
 }
 
@@ -95,6 +124,7 @@ type TestCaseStat struct {
 	Total   int `json:"total" yaml:"total"`
 	Success int `json:"success" yaml:"success"`
 	Fail    int `json:"fail" yaml:"fail"`
+	Flaky   int `json:"flaky,omitempty" yaml:"flaky,omitempty"` // testcases passed in rerun, also counted in success
 }
 
 type TestStepStat struct {
@@ -116,14 +146,65 @@ type Platform struct {
 
 // TestCaseSummary stores tests summary for one testcase
 type TestCaseSummary struct {
-	Name    string         `json:"name" yaml:"name"`
-	Success bool           `json:"success" yaml:"success"`
-	CaseId  string         `json:"case_id,omitempty" yaml:"case_id,omitempty"` // TODO
-	Stat    *TestStepStat  `json:"stat" yaml:"stat"`
-	Time    *TestCaseTime  `json:"time" yaml:"time"`
-	InOut   *TestCaseInOut `json:"in_out" yaml:"in_out"`
-	Log     string         `json:"log,omitempty" yaml:"log,omitempty"` // TODO
-	Records []*StepResult  `json:"records" yaml:"records"`
+	Name          string         `json:"name" yaml:"name"`
+	Success       bool           `json:"success" yaml:"success"`
+	CaseId        string         `json:"case_id,omitempty" yaml:"case_id,omitempty"`               // TODO
+	CorrelationID string         `json:"correlation_id,omitempty" yaml:"correlation_id,omitempty"` // correlation id shared by requests of testcase
+	Reruns        int            `json:"reruns,omitempty" yaml:"reruns,omitempty"`                 // times of rerun after testcase failed
+	Flaky         bool           `json:"flaky,omitempty" yaml:"flaky,omitempty"`                   // testcase failed at first but passed in rerun
+	Stat          *TestStepStat  `json:"stat" yaml:"stat"`
+	Time          *TestCaseTime  `json:"time" yaml:"time"`
+	InOut         *TestCaseInOut `json:"in_out" yaml:"in_out"`
+	Log           string         `json:"log,omitempty" yaml:"log,omitempty"` // TODO
+	Records       []*StepResult  `json:"records" yaml:"records"`
+	// mutex protects records and stat, which are updated concurrently by steps run as DAG
+	// and referenced testcases, use addRecord, merge and fail instead of updating fields directly.
+	mutex sync.Mutex
+}
+
+// addRecord appends step result to testcase summary and updates step stat.
+func (s *TestCaseSummary) addRecord(stepResult *StepResult) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.Records = append(s.Records, stepResult)
+	s.Stat.Total += 1
+	if stepResult.Success {
+		s.Stat.Successes += 1
+	} else {
+		s.Stat.Failures += 1
+		// update summary result to failed
+		s.Success = false
+	}
+}
+
+// merge appends records and stat of referenced testcase summary.
+func (s *TestCaseSummary) merge(other *TestCaseSummary) {
+	other.mutex.Lock()
+	records := make([]*StepResult, len(other.Records))
+	copy(records, other.Records)
+	stat := *other.Stat
+	other.mutex.Unlock()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.Records = append(s.Records, records...)
+	s.Stat.Total += stat.Total
+	s.Stat.Successes += stat.Successes
+	s.Stat.Failures += stat.Failures
+}
+
+// fail marks testcase summary as failed.
+func (s *TestCaseSummary) fail() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.Success = false
+}
+
+// isSuccess reports whether all steps of testcase succeeded.
+func (s *TestCaseSummary) isSuccess() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.Success
 }
 
 type TestCaseInOut struct {
@@ -131,18 +212,19 @@ type TestCaseInOut struct {
 	ExportVars map[string]interface{} `json:"export_vars" yaml:"export_vars"`
 }
 
+// newSessionData gets session data from pool, which is put back by releaseSessionData in load testing.
 func newSessionData() *SessionData {
-	return &SessionData{
-		Success:  false,
-		ReqResps: &ReqResps{},
-	}
+	return sessionDataPool.Get().(*SessionData)
 }
 
 type SessionData struct {
 	Success    bool                `json:"success" yaml:"success"`
 	ReqResps   *ReqResps           `json:"req_resps" yaml:"req_resps"`
-	Address    *Address            `json:"address,omitempty" yaml:"address,omitempty"` // TODO
+	Address    *Address            `json:"address,omitempty" yaml:"address,omitempty"` // addresses of connection, server is proxy if request is proxied
 	Validators []*ValidationResult `json:"validators,omitempty" yaml:"validators,omitempty"`
+	Attempts   []*WaitAttempt      `json:"attempts,omitempty" yaml:"attempts,omitempty"`     // polling attempts of wait until
+	Truncated  bool                `json:"truncated,omitempty" yaml:"truncated,omitempty"`   // response body exceeds max body size and is truncated
+	Connection *ConnectionStats    `json:"connection,omitempty" yaml:"connection,omitempty"` // connections got from pool
 }
 
 type ReqResps struct {
@@ -155,12 +237,34 @@ type Address struct {
 	ClientPort string `json:"client_port,omitempty" yaml:"client_port,omitempty"`
 	ServerIP   string `json:"server_ip,omitempty" yaml:"server_ip,omitempty"`
 	ServerPort string `json:"server_port,omitempty" yaml:"server_port,omitempty"`
+	IPFamily   string `json:"ip_family,omitempty" yaml:"ip_family,omitempty"` // ipv4 or ipv6
+}
+
+// newAddress creates address of connection from local and remote addresses.
+func newAddress(local, remote net.Addr) *Address {
+	address := &Address{}
+	if local != nil {
+		address.ClientIP, address.ClientPort, _ = net.SplitHostPort(local.String())
+	}
+	if remote != nil {
+		address.ServerIP, address.ServerPort, _ = net.SplitHostPort(remote.String())
+	}
+	if ip := net.ParseIP(address.ServerIP); ip != nil {
+		if ip.To4() != nil {
+			address.IPFamily = "ipv4"
+		} else {
+			address.IPFamily = "ipv6"
+		}
+	}
+	return address
 }
 
 type ValidationResult struct {
 	Validator
-	CheckValue  interface{} `json:"check_value" yaml:"check_value"`
-	CheckResult string      `json:"check_result" yaml:"check_result"`
+	CheckValue  interface{}        `json:"check_value" yaml:"check_value"`
+	CheckResult string             `json:"check_result" yaml:"check_result"`
+	Diffs       []builtin.JSONDiff `json:"diffs,omitempty" yaml:"diffs,omitempty"` // mismatched fields in json comparison
+	Diff        string             `json:"diff,omitempty" yaml:"diff,omitempty"`   // rendered diffs for maps/slices
 }
 
 func newSummary() *TestCaseSummary {
@@ -1,6 +1,21 @@
 package hrp
 
-import "testing"
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/httprunner/httprunner/hrp/internal/builtin"
+	"github.com/httprunner/httprunner/hrp/internal/json"
+)
 
 func TestGenHTMLReport(t *testing.T) {
 	summary := newOutSummary()
@@ -8,17 +23,239 @@ func TestGenHTMLReport(t *testing.T) {
 	caseSummary2 := newSummary()
 	stepResult1 := &StepResult{}
 	stepResult2 := &StepResult{
-		Name:        "Test",
-		StepType:    stepTypeRequest,
-		Success:     false,
-		ContentSize: 0,
-		Attachment:  "err",
+		Name:          "Test",
+		StepType:      stepTypeRequest,
+		Success:       false,
+		ContentSize:   0,
+		Attachment:    "err",
+		CorrelationID: "step-correlation-id",
 	}
 	caseSummary1.Records = []*StepResult{stepResult1, stepResult2, nil}
+	caseSummary1.CorrelationID = "case-correlation-id"
 	summary.appendCaseSummary(caseSummary1)
 	summary.appendCaseSummary(caseSummary2)
 	err := summary.genHTMLReport()
 	if err != nil {
 		t.Error(err)
 	}
+	report, err := os.ReadFile(fmt.Sprintf(reportPath, summary.Time.StartAt.Unix()))
+	if assert.Nil(t, err) {
+		assert.Contains(t, string(report), "Correlation ID: case-correlation-id")
+		assert.Contains(t, string(report), "Correlation ID: step-correlation-id")
+	}
+}
+
+func TestDumpSummary(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"id": 1}`))
+	}))
+	defer ts.Close()
+
+	runner := NewRunner(t)
+	path := filepath.Join(t.TempDir(), "reports", "summary.json")
+	assert.NotNil(t, runner.DumpSummary(path))
+	testcase := &TestCase{
+		Config: NewConfig("dump summary").SetBaseURL(ts.URL),
+		TestSteps: []IStep{
+			NewStep("get").GET("/get").
+				WaitUntil("body.id", 1, 0.01, 1).
+				Extract().
+				WithJmesPath("body.id", "id").
+				Validate().
+				AssertEqual("body.id", 1, "check id"),
+			NewStep("call").CallRefCase(&TestCase{
+				Config: NewConfig("referenced").SetBaseURL(ts.URL),
+				TestSteps: []IStep{
+					NewStep("post").POST("/post"),
+				},
+			}),
+		},
+	}
+	if !assert.Nil(t, runner.Run(testcase)) {
+		t.Fatal()
+	}
+	if !assert.Nil(t, runner.DumpSummary(path)) {
+		t.Fatal()
+	}
+
+	var schema, summary map[string]interface{}
+	readJSON := func(path string, v interface{}) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(data, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	readJSON("schemas/summary.schema.json", &schema)
+	readJSON(path, &summary)
+	assert.Equal(t, SummarySchemaVersion, summary["schema_version"])
+	checkSchema(t, schema, schema, summary, "$")
+
+	// schema covers all fields of summary structs
+	definitions := schema["definitions"].(map[string]interface{})
+	structs := map[string]interface{}{
+		"":                  Summary{},
+		"platform":          Platform{},
+		"stat":              Stat{},
+		"testcase_stat":     TestCaseStat{},
+		"teststep_stat":     TestStepStat{},
+		"time":              TestCaseTime{},
+		"testcase_summary":  TestCaseSummary{},
+		"in_out":            TestCaseInOut{},
+		"step_result":       StepResult{},
+		"session_data":      SessionData{},
+		"req_resps":         ReqResps{},
+		"address":           Address{},
+		"connection_stats":  ConnectionStats{},
+		"validation_result": ValidationResult{},
+		"json_diff":         builtin.JSONDiff{},
+		"wait_attempt":      WaitAttempt{},
+	}
+	for name, value := range structs {
+		definition := schema
+		if name != "" {
+			definition = definitions[name].(map[string]interface{})
+		}
+		var fields []string
+		for key := range definition["properties"].(map[string]interface{}) {
+			fields = append(fields, key)
+		}
+		assert.ElementsMatch(t, jsonFields(reflect.TypeOf(value)), fields, name)
+	}
+}
+
+// jsonFields returns json field names of struct, including fields of embedded structs.
+func jsonFields(typ reflect.Type) []string {
+	var fields []string
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.Anonymous {
+			fields = append(fields, jsonFields(field.Type)...)
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
+// checkSchema checks json value against subset of json schema keywords used by summary schema,
+// including $ref, type, required, properties, items and anyOf.
+func checkSchema(t *testing.T, root, schema map[string]interface{}, value interface{}, path string) bool {
+	if ref, ok := schema["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/definitions/")
+		return checkSchema(t, root, root["definitions"].(map[string]interface{})[name].(map[string]interface{}), value, path)
+	}
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok {
+		for _, s := range anyOf {
+			if checkSchema(&testing.T{}, root, s.(map[string]interface{}), value, path) {
+				return true
+			}
+		}
+		return assert.Fail(t, "no schema of anyOf matched", path)
+	}
+	if typ, ok := schema["type"]; ok {
+		var types []interface{}
+		if list, ok := typ.([]interface{}); ok {
+			types = list
+		} else {
+			types = []interface{}{typ}
+		}
+		if !assert.Contains(t, types, schemaType(value), path) {
+			return false
+		}
+	}
+	success := true
+	switch v := value.(type) {
+	case map[string]interface{}:
+		required, _ := schema["required"].([]interface{})
+		for _, key := range required {
+			if _, ok := v[key.(string)]; !ok {
+				success = assert.Fail(t, "missing required field", "%s.%s", path, key)
+			}
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		for key, field := range v {
+			s, ok := properties[key].(map[string]interface{})
+			if !ok {
+				if properties != nil {
+					success = assert.Fail(t, "unexpected field", "%s.%s", path, key)
+				}
+				continue
+			}
+			success = checkSchema(t, root, s, field, path+"."+key) && success
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				success = checkSchema(t, root, items, item, fmt.Sprintf("%s[%d]", path, i)) && success
+			}
+		}
+	}
+	return success
+}
+
+func schemaType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if v == float64(int64(v)) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	}
+	return "object"
+}
+
+func TestCaseSummaryConcurrentUpdate(t *testing.T) {
+	caseSummary := newSummary()
+	refSummary := newSummary()
+	refSummary.addRecord(&StepResult{Name: "ref step", Success: true})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(3)
+		go func(i int) {
+			defer wg.Done()
+			caseSummary.addRecord(&StepResult{Name: fmt.Sprintf("step %d", i), Success: i%10 != 0})
+		}(i)
+		go func() {
+			defer wg.Done()
+			caseSummary.merge(refSummary)
+		}()
+		go func() {
+			defer wg.Done()
+			_ = caseSummary.isSuccess()
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, caseSummary.Records, 100)
+	assert.Equal(t, 100, caseSummary.Stat.Total)
+	assert.Equal(t, 95, caseSummary.Stat.Successes)
+	assert.Equal(t, 5, caseSummary.Stat.Failures)
+	assert.False(t, caseSummary.Success)
+
+	summary := newOutSummary()
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			summary.appendCaseSummary(caseSummary)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 10, summary.Stat.TestCases.Total)
+	assert.Equal(t, 1000, summary.Stat.TestSteps.Total)
 }
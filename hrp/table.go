@@ -0,0 +1,344 @@
+package hrp
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	mimeXLSX = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	mimeXLS  = "application/vnd.ms-excel"
+)
+
+// tableMeta is tabular response body, e.g. report exported in csv or excel, whose first row is header.
+// Rows are located by jmespath like csv.rows[0].amount, and columns like csv.columns.amount.
+type tableMeta struct {
+	Header  []string                 `json:"header"`
+	Rows    []map[string]interface{} `json:"rows"`
+	Columns map[string][]interface{} `json:"columns"`
+	Records [][]interface{}          `json:"records"` // rows except header, located by position like csv.records[0][1]
+}
+
+// excelMeta is excel workbook in response body, the first sheet is located as csv, e.g. excel.rows[0].amount,
+// and other sheets are located by name, e.g. excel.sheets.Sheet2.rows[0].amount.
+type excelMeta struct {
+	*tableMeta
+	SheetNames []string              `json:"sheet_names"`
+	Sheets     map[string]*tableMeta `json:"sheets"`
+}
+
+// newTableMeta creates table from records, whose first record is header.
+// Empty header names are named by column position, e.g. column_3, and duplicated ones are suffixed, e.g. amount_2.
+func newTableMeta(records [][]interface{}) *tableMeta {
+	table := &tableMeta{
+		Header:  []string{},
+		Rows:    []map[string]interface{}{},
+		Columns: make(map[string][]interface{}),
+		Records: [][]interface{}{},
+	}
+	if len(records) == 0 {
+		return table
+	}
+	seen := make(map[string]int)
+	for i, cell := range records[0] {
+		name := strings.TrimSpace(fmt.Sprint(cell))
+		if cell == nil || name == "" {
+			name = fmt.Sprintf("column_%d", i+1)
+		}
+		seen[name]++
+		if seen[name] > 1 {
+			name = fmt.Sprintf("%s_%d", name, seen[name])
+		}
+		table.Header = append(table.Header, name)
+		table.Columns[name] = []interface{}{}
+	}
+	for _, record := range records[1:] {
+		row := make(map[string]interface{}, len(table.Header))
+		for i, name := range table.Header {
+			var value interface{}
+			if i < len(record) {
+				value = record[i]
+			}
+			row[name] = value
+			table.Columns[name] = append(table.Columns[name], value)
+		}
+		table.Rows = append(table.Rows, row)
+		table.Records = append(table.Records, record)
+	}
+	return table
+}
+
+// isCSVContentType checks if content type is csv or tsv, returns delimiter of fields.
+func isCSVContentType(mediaType string) (rune, bool) {
+	switch mediaType {
+	case "text/csv", "application/csv", "text/comma-separated-values":
+		return ',', true
+	case "text/tab-separated-values":
+		return '\t', true
+	}
+	return 0, false
+}
+
+// parseCSVTable parses csv body into table, utf-8 bom written by excel is skipped and values are kept as strings.
+func parseCSVTable(body []byte, delimiter rune) (*tableMeta, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(body, []byte("\xef\xbb\xbf"))))
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, errors.Wrap(err, "parse csv failed")
+	}
+	rows := make([][]interface{}, len(records))
+	for i, record := range records {
+		rows[i] = make([]interface{}, len(record))
+		for j, field := range record {
+			rows[i][j] = field
+		}
+	}
+	return newTableMeta(rows), nil
+}
+
+// setTableMeta parses tabular body of csv or excel content type into meta of response.
+// Excel file with legacy content type of xls is parsed as csv if it is not xlsx, which is common for exported reports.
+func (m *respObjMeta) setTableMeta(contentType string, body interface{}) {
+	raw, ok := body.(string)
+	if !ok || contentType == "" {
+		return
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return
+	}
+	isXLSX := strings.HasPrefix(raw, "PK\x03\x04")
+	if delimiter, ok := isCSVContentType(mediaType); ok || mediaType == mimeXLS && !isXLSX {
+		if !ok {
+			delimiter = ','
+		}
+		if m.CSV, err = parseCSVTable([]byte(raw), delimiter); err != nil {
+			log.Warn().Err(err).Str("contentType", contentType).Msg("parse csv response body failed")
+		}
+	} else if mediaType == mimeXLSX || mediaType == mimeXLS {
+		if m.Excel, err = parseXLSX([]byte(raw)); err != nil {
+			log.Warn().Err(err).Str("contentType", contentType).Msg("parse excel response body failed")
+		}
+	}
+}
+
+// parseXLSX parses sheets of xlsx workbook, numeric cells are parsed as numbers and others as strings.
+func parseXLSX(body []byte) (*excelMeta, error) {
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		return nil, errors.Wrap(err, "open xlsx failed")
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	var workbook struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := decodeXLSXPart(files, "xl/workbook.xml", &workbook); err != nil {
+		return nil, err
+	}
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decodeXLSXPart(files, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	targets := make(map[string]string, len(rels.Relationships))
+	for _, rel := range rels.Relationships {
+		if strings.HasPrefix(rel.Target, "/") {
+			targets[rel.ID] = strings.TrimPrefix(rel.Target, "/")
+		} else {
+			targets[rel.ID] = path.Join("xl", rel.Target)
+		}
+	}
+	var sharedStrings []string
+	if _, ok := files["xl/sharedStrings.xml"]; ok {
+		var sst struct {
+			Items []xlsxRichText `xml:"si"`
+		}
+		if err := decodeXLSXPart(files, "xl/sharedStrings.xml", &sst); err != nil {
+			return nil, err
+		}
+		for _, item := range sst.Items {
+			sharedStrings = append(sharedStrings, item.String())
+		}
+	}
+
+	excel := &excelMeta{
+		SheetNames: []string{},
+		Sheets:     make(map[string]*tableMeta),
+	}
+	for _, sheet := range workbook.Sheets {
+		records, err := parseXLSXSheet(files, targets[sheet.RID], sharedStrings)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse sheet %s failed", sheet.Name)
+		}
+		table := newTableMeta(records)
+		excel.SheetNames = append(excel.SheetNames, sheet.Name)
+		excel.Sheets[sheet.Name] = table
+		if excel.tableMeta == nil {
+			excel.tableMeta = table
+		}
+	}
+	if excel.tableMeta == nil {
+		excel.tableMeta = newTableMeta(nil)
+	}
+	return excel, nil
+}
+
+// xlsxRichText is text of shared string or inline string, which may be split in runs.
+type xlsxRichText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxRichText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+	var b strings.Builder
+	for _, run := range t.Runs {
+		b.WriteString(run.Text)
+	}
+	return b.String()
+}
+
+func parseXLSXSheet(files map[string]*zip.File, name string, sharedStrings []string) ([][]interface{}, error) {
+	var worksheet struct {
+		Rows []struct {
+			Index int `xml:"r,attr"`
+			Cells []struct {
+				Ref    string       `xml:"r,attr"`
+				Type   string       `xml:"t,attr"`
+				Value  string       `xml:"v"`
+				Inline xlsxRichText `xml:"is"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := decodeXLSXPart(files, name, &worksheet); err != nil {
+		return nil, err
+	}
+
+	var records [][]interface{}
+	for i, row := range worksheet.Rows {
+		// rows and cells may be omitted if empty
+		rowIndex := row.Index - 1
+		if row.Index == 0 {
+			rowIndex = i
+		}
+		if rowIndex < 0 || rowIndex >= xlsxMaxRows {
+			return nil, fmt.Errorf("invalid row index %d", row.Index)
+		}
+		for len(records) <= rowIndex {
+			records = append(records, []interface{}{})
+		}
+		var record []interface{}
+		for j, cell := range row.Cells {
+			colIndex := j
+			if cell.Ref != "" {
+				var err error
+				if colIndex, err = xlsxColumnIndex(cell.Ref); err != nil {
+					return nil, err
+				}
+			}
+			if colIndex >= xlsxMaxColumns {
+				return nil, fmt.Errorf("too many cells in row %d", rowIndex+1)
+			}
+			for len(record) <= colIndex {
+				record = append(record, nil)
+			}
+			var value interface{}
+			switch cell.Type {
+			case "s":
+				index, err := strconv.Atoi(cell.Value)
+				if err != nil || index < 0 || index >= len(sharedStrings) {
+					return nil, fmt.Errorf("invalid shared string index %s of cell %s", cell.Value, cell.Ref)
+				}
+				value = sharedStrings[index]
+			case "inlineStr":
+				value = cell.Inline.String()
+			case "b":
+				value = cell.Value == "1"
+			case "str", "e":
+				value = cell.Value
+			default:
+				if cell.Value == "" {
+					break
+				}
+				number, err := strconv.ParseFloat(cell.Value, 64)
+				if err != nil {
+					value = cell.Value
+				} else {
+					value = number
+				}
+			}
+			record[colIndex] = value
+		}
+		records[rowIndex] = record
+	}
+	return records, nil
+}
+
+// limits of worksheet in xlsx
+const (
+	xlsxMaxRows    = 1048576
+	xlsxMaxColumns = 16384
+)
+
+// xlsxColumnIndex returns zero-based column index of cell reference, e.g. A1 => 0, AB12 => 27.
+func xlsxColumnIndex(ref string) (int, error) {
+	index := 0
+	for _, c := range ref {
+		if c < 'A' || c > 'Z' {
+			break
+		}
+		index = index*26 + int(c-'A'+1)
+		if index > xlsxMaxColumns {
+			break
+		}
+	}
+	if index < 1 || index > xlsxMaxColumns {
+		return 0, fmt.Errorf("invalid cell reference %s", ref)
+	}
+	return index - 1, nil
+}
+
+func decodeXLSXPart(files map[string]*zip.File, name string, v interface{}) error {
+	f, ok := files[name]
+	if !ok {
+		return fmt.Errorf("%s not found in xlsx", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if err := xml.NewDecoder(io.LimitReader(rc, 512<<20)).Decode(v); err != nil {
+		return errors.Wrapf(err, "decode %s failed", name)
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package hrp
+
+import (
+	"archive/zip"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testXLSXOrders = `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>4</v></c><c r="C1" t="s"><v>1</v></c><c r="D1" t="s"><v>2</v></c></row>
+<row r="2"><c r="A2"><v>1001</v></c><c r="B2" t="s"><v>3</v></c><c r="C2"><v>99.5</v></c><c r="D2" t="b"><v>1</v></c></row>
+<row r="4"><c r="A4"><v>1002</v></c><c r="B4" t="inlineStr"><is><t>bob</t></is></c><c r="D4" t="b"><v>0</v></c></row>
+</sheetData></worksheet>`
+
+// newTestXLSX creates xlsx workbook with shared strings, inline strings, numbers and booleans.
+func newTestXLSX(t *testing.T) []byte {
+	return newTestXLSXWithOrders(t, testXLSXOrders)
+}
+
+// newTestXLSXWithOrders creates xlsx workbook with content of the first worksheet Orders.
+func newTestXLSXWithOrders(t *testing.T, orders string) []byte {
+	parts := map[string]string{
+		"xl/workbook.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Orders" sheetId="1" r:id="rId1"/><sheet name="Summary" sheetId="2" r:id="rId2"/></sheets>
+</workbook>`,
+		"xl/_rels/workbook.xml.rels": `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="/xl/worksheets/sheet2.xml"/>
+</Relationships>`,
+		"xl/sharedStrings.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<si><t>id</t></si><si><t>amount</t></si><si><t>paid</t></si><si><r><t>ali</t></r><r><t>ce</t></r></si><si><t>name</t></si>
+</sst>`,
+		"xl/worksheets/sheet1.xml": orders,
+		"xl/worksheets/sheet2.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+<row r="1"><c r="A1" t="inlineStr"><is><t>total</t></is></c></row>
+<row r="2"><c r="A2" t="str"><v>99.5</v></c></row>
+</sheetData></worksheet>`,
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range parts {
+		w, err := zw.Create(name)
+		if !assert.Nil(t, err) {
+			t.Fatal()
+		}
+		_, _ = w.Write([]byte(content))
+	}
+	if !assert.Nil(t, zw.Close()) {
+		t.Fatal()
+	}
+	return buf.Bytes()
+}
+
+func TestParseXLSX(t *testing.T) {
+	excel, err := parseXLSX(newTestXLSX(t))
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	assert.Equal(t, []string{"Orders", "Summary"}, excel.SheetNames)
+	assert.Equal(t, []string{"id", "name", "amount", "paid"}, excel.Header)
+	if assert.Len(t, excel.Rows, 3) {
+		assert.Equal(t, map[string]interface{}{"id": 1001.0, "name": "alice", "amount": 99.5, "paid": true}, excel.Rows[0])
+		// empty row is kept
+		assert.Equal(t, map[string]interface{}{"id": nil, "name": nil, "amount": nil, "paid": nil}, excel.Rows[1])
+		assert.Equal(t, map[string]interface{}{"id": 1002.0, "name": "bob", "amount": nil, "paid": false}, excel.Rows[2])
+	}
+	assert.Equal(t, []interface{}{"99.5"}, excel.Sheets["Summary"].Columns["total"])
+	index, err := xlsxColumnIndex("AB12")
+	assert.Nil(t, err)
+	assert.Equal(t, 27, index)
+
+	_, err = parseXLSX([]byte("not xlsx"))
+	assert.NotNil(t, err)
+}
+
+func TestParseXLSXInvalidIndex(t *testing.T) {
+	for _, ref := range []string{"1", "a1", "XFE1", "ZZZZZZZZZZZZZZZZ1"} {
+		_, err := xlsxColumnIndex(ref)
+		assert.NotNil(t, err, ref)
+	}
+	index, err := xlsxColumnIndex("XFD1")
+	assert.Nil(t, err)
+	assert.Equal(t, xlsxMaxColumns-1, index)
+
+	// malformed worksheets of response are reported as errors instead of panics
+	for _, row := range []string{
+		`<row r="1"><c r="1"><v>1</v></c></row>`,
+		`<row r="1"><c r="a1"><v>1</v></c></row>`,
+		`<row r="-3"><c r="A1"><v>1</v></c></row>`,
+		`<row r="2000000"><c r="A1"><v>1</v></c></row>`,
+	} {
+		orders := `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` + row + `</sheetData></worksheet>`
+		_, err := parseXLSX(newTestXLSXWithOrders(t, orders))
+		assert.NotNil(t, err, row)
+	}
+}
+
+func TestTabularResponse(t *testing.T) {
+	xlsx := newTestXLSX(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/report.csv":
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			w.Write([]byte("\xef\xbb\xbfid,name,amount,\n1001,\"Smith, John\",99.5,x\n1002,bob\n"))
+		case "/report.tsv":
+			w.Header().Set("Content-Type", "text/tab-separated-values")
+			w.Write([]byte("id\tid\n1\t2\n"))
+		case "/report.xls":
+			// csv exported with legacy excel content type
+			w.Header().Set("Content-Type", "application/vnd.ms-excel")
+			w.Write([]byte("id,amount\n1001,10\n"))
+		case "/report.xlsx":
+			w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+			w.Write(xlsx)
+		}
+	}))
+	defer ts.Close()
+
+	testcase := &TestCase{
+		Config: NewConfig("tabular response").SetBaseURL(ts.URL),
+		TestSteps: []IStep{
+			NewStep("csv").
+				GET("/report.csv").
+				Extract().
+				WithJmesPath("csv.rows[0].name", "name").
+				Validate().
+				AssertEqual("csv.header", []interface{}{"id", "name", "amount", "column_4"}, "check header").
+				AssertEqual("csv.rows[0].amount", "99.5", "check row by column name").
+				AssertEqual("csv.rows[1].amount", nil, "check missing cell").
+				AssertEqual("csv.records[0][1]", "Smith, John", "check row by position").
+				AssertEqual("csv.columns.id", []interface{}{"1001", "1002"}, "check column").
+				AssertLengthEqual("csv.rows", 2, "check rows count"),
+			NewStep("tsv").
+				GET("/report.tsv").
+				Validate().
+				AssertEqual("csv.rows[0]", map[string]interface{}{"id": "1", "id_2": "2"}, "check duplicated header"),
+			NewStep("xls").
+				GET("/report.xls").
+				Validate().
+				AssertEqual("csv.rows[0].amount", "10", "check csv of legacy excel content type"),
+			NewStep("xlsx").
+				GET("/report.xlsx").
+				Validate().
+				AssertEqual("excel.rows[0].name", "alice", "check first sheet").
+				AssertEqual("excel.rows[0].amount", 99.5, "check number cell").
+				AssertEqual("excel.sheets.Summary.rows[0].total", "99.5", "check sheet by name").
+				AssertEqual("excel.sheet_names", []interface{}{"Orders", "Summary"}, "check sheet names"),
+		},
+	}
+	sessionRunner := NewRunner(t).NewSessionRunner(testcase)
+	if assert.Nil(t, sessionRunner.Start()) {
+		assert.Equal(t, "Smith, John", sessionRunner.sessionVariables["name"])
+	}
+}
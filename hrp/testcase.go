@@ -2,8 +2,6 @@ package hrp
 
 import (
 	"fmt"
-	"io/fs"
-	"os"
 	"path/filepath"
 	"strings"
 
@@ -53,21 +51,42 @@ func (path *TestCasePath) GetPath() string {
 
 // ToTestCase loads testcase path and convert to *TestCase
 func (path *TestCasePath) ToTestCase() (*TestCase, error) {
-	tc := &TCase{}
-	casePath := path.GetPath()
-	err := builtin.LoadFile(casePath, tc)
+	return path.toTestCase(nil)
+}
+
+// toTestCase loads testcase path with reference chain of testcase paths,
+// error is returned if testcase references are cyclic or file contains multiple testcases.
+func (path *TestCasePath) toTestCase(refChain []string) (*TestCase, error) {
+	testCases, err := path.toTestCases(refChain)
 	if err != nil {
 		return nil, err
 	}
+	if len(testCases) > 1 {
+		return nil, fmt.Errorf("%d testcases found in %s, only single testcase can be referenced",
+			len(testCases), path.GetPath())
+	}
+	return testCases[0], nil
+}
 
-	err = tc.makeCompat()
+// toTestCases loads all testcases in testcase path, yaml file may contain multiple testcases
+// separated by ---, which are returned in the order they appear in file.
+func (path *TestCasePath) toTestCases(refChain []string) ([]*TestCase, error) {
+	casePath := path.GetPath()
+	absPath, err := filepath.Abs(casePath)
 	if err != nil {
 		return nil, err
 	}
-	tc.Config.Path = casePath
+	for _, refPath := range refChain {
+		if refPath == absPath {
+			return nil, fmt.Errorf("cyclic testcase reference found: %s",
+				strings.Join(append(refChain, absPath), " -> "))
+		}
+	}
+	refChain = append(refChain[:len(refChain):len(refChain)], absPath)
 
-	testCase := &TestCase{
-		Config: tc.Config,
+	tCases, err := loadTCases(casePath)
+	if err != nil {
+		return nil, err
 	}
 
 	// locate project root dir by plugin path
@@ -76,67 +95,174 @@ func (path *TestCasePath) ToTestCase() (*TestCase, error) {
 		return nil, errors.Wrap(err, "failed to get project root dir")
 	}
 
-	for _, step := range tc.TestSteps {
-		if step.API != nil {
-			apiPath, ok := step.API.(string)
-			if !ok {
-				return nil, fmt.Errorf("referenced api path should be string, got %v", step.API)
-			}
-			path := filepath.Join(projectRootDir, apiPath)
-			if !builtin.IsFilePathExists(path) {
-				return nil, errors.New("referenced api file not found: " + path)
-			}
-
-			refAPI := APIPath(path)
-			apiContent, err := refAPI.ToAPI()
-			if err != nil {
-				return nil, err
-			}
-			step.API = apiContent
+	var testCases []*TestCase
+	for _, tc := range tCases {
+		err = tc.makeCompat()
+		if err != nil {
+			return nil, err
+		}
+		tc.Config.Path = casePath
 
-			testCase.TestSteps = append(testCase.TestSteps, &StepAPIWithOptionalArgs{
-				step: step,
-			})
-		} else if step.TestCase != nil {
-			casePath, ok := step.TestCase.(string)
-			if !ok {
-				return nil, fmt.Errorf("referenced testcase path should be string, got %v", step.TestCase)
-			}
-			path := filepath.Join(projectRootDir, casePath)
-			if !builtin.IsFilePathExists(path) {
-				return nil, errors.New("referenced testcase file not found: " + path)
-			}
+		testCase := &TestCase{
+			Config: tc.Config,
+		}
+		testCase.TestSteps, err = convertTSteps(tc.TestSteps, projectRootDir, refChain)
+		if err != nil {
+			return nil, err
+		}
+		testCases = append(testCases, testCase)
+	}
+	return testCases, nil
+}
 
-			refTestCase := TestCasePath(path)
-			tc, err := refTestCase.ToTestCase()
+// convertTSteps converts TStep list to IStep list, included fragments are inlined
+// and unexpected steps are ignored.
+func convertTSteps(steps []*TStep, projectRootDir string, refChain []string) ([]IStep, error) {
+	var iSteps []IStep
+	for _, step := range steps {
+		// inline steps of included fragment
+		if step.Include != "" {
+			fragmentSteps, err := loadFragment(step, projectRootDir, refChain)
 			if err != nil {
 				return nil, err
 			}
-			step.TestCase = tc
-			testCase.TestSteps = append(testCase.TestSteps, &StepTestCaseWithOptionalArgs{
-				step: step,
-			})
-		} else if step.ThinkTime != nil {
-			testCase.TestSteps = append(testCase.TestSteps, &StepThinkTime{
-				step: step,
-			})
-		} else if step.Request != nil {
-			testCase.TestSteps = append(testCase.TestSteps, &StepRequestWithOptionalArgs{
-				step: step,
-			})
-		} else if step.Transaction != nil {
-			testCase.TestSteps = append(testCase.TestSteps, &StepTransaction{
-				step: step,
-			})
-		} else if step.Rendezvous != nil {
-			testCase.TestSteps = append(testCase.TestSteps, &StepRendezvous{
-				step: step,
-			})
-		} else {
+			iSteps = append(iSteps, fragmentSteps...)
+			continue
+		}
+
+		iStep, err := convertTStep(step, projectRootDir, refChain)
+		if err != nil {
+			return nil, err
+		}
+		if iStep == nil {
 			log.Warn().Interface("step", step).Msg("[convertTestCase] unexpected step")
+			continue
+		}
+		iSteps = append(iSteps, iStep)
+	}
+	return iSteps, nil
+}
+
+// convertTStep converts TStep to IStep according to step content,
+// nil is returned if step type is unexpected.
+// refChain is the reference chain of testcase paths, which is used to detect cyclic references.
+func convertTStep(step *TStep, projectRootDir string, refChain []string) (IStep, error) {
+	if step.API != nil {
+		apiPath, ok := step.API.(string)
+		if !ok {
+			return nil, fmt.Errorf("referenced api path should be string, got %v", step.API)
+		}
+		path := filepath.Join(projectRootDir, apiPath)
+		if !builtin.IsFilePathExists(path) {
+			return nil, errors.New("referenced api file not found: " + path)
+		}
+
+		refAPI := APIPath(path)
+		apiContent, err := refAPI.ToAPI()
+		if err != nil {
+			return nil, err
 		}
+		if apiContent.Request != nil {
+			apiContent.Request.resolveFilePaths(projectRootDir)
+		}
+		step.API = apiContent
+		return &StepAPIWithOptionalArgs{
+			step: step,
+		}, nil
+	} else if step.TestCase != nil {
+		casePath, ok := step.TestCase.(string)
+		if !ok {
+			return nil, fmt.Errorf("referenced testcase path should be string, got %v", step.TestCase)
+		}
+		path := filepath.Join(projectRootDir, casePath)
+		if !builtin.IsFilePathExists(path) {
+			return nil, errors.New("referenced testcase file not found: " + path)
+		}
+
+		refTestCase := TestCasePath(path)
+		tc, err := refTestCase.toTestCase(refChain)
+		if err != nil {
+			return nil, err
+		}
+		step.TestCase = tc
+		return &StepTestCaseWithOptionalArgs{
+			step: step,
+		}, nil
+	} else if step.ThinkTime != nil {
+		return &StepThinkTime{
+			step: step,
+		}, nil
+	} else if step.Loop != nil {
+		steps, err := convertTSteps(step.Loop.Steps, projectRootDir, refChain)
+		if err != nil {
+			return nil, err
+		}
+		return &StepLoop{
+			step:  step,
+			steps: steps,
+		}, nil
+	} else if step.Branch != nil {
+		thenSteps, err := convertTSteps(step.Branch.Then, projectRootDir, refChain)
+		if err != nil {
+			return nil, err
+		}
+		elseSteps, err := convertTSteps(step.Branch.Else, projectRootDir, refChain)
+		if err != nil {
+			return nil, err
+		}
+		return &StepBranch{
+			step:      step,
+			thenSteps: thenSteps,
+			elseSteps: elseSteps,
+		}, nil
+	} else if step.Fuzz != nil {
+		step.Fuzz.resolveSchemaPath(projectRootDir)
+		return &StepFuzz{
+			step: step,
+		}, nil
+	} else if step.Request != nil {
+		step.Request.resolveFilePaths(projectRootDir)
+		return &StepRequestWithOptionalArgs{
+			step: step,
+		}, nil
+	} else if step.Transaction != nil {
+		return &StepTransaction{
+			step: step,
+		}, nil
+	} else if step.Rendezvous != nil {
+		return &StepRendezvous{
+			step: step,
+		}, nil
+	} else if step.MQTT != nil {
+		return &StepMQTT{
+			step: step,
+		}, nil
+	} else if step.Kafka != nil {
+		return &StepKafka{
+			step: step,
+		}, nil
+	} else if step.DB != nil {
+		return &StepDB{
+			step: step,
+		}, nil
+	} else if step.Redis != nil {
+		return &StepRedis{
+			step: step,
+		}, nil
+	} else if step.Shell != nil {
+		return &StepShell{
+			step: step,
+		}, nil
+	} else if step.File != nil {
+		return &StepFile{
+			step: step,
+		}, nil
+	} else if step.Email != nil {
+		return &StepEmail{
+			step: step,
+		}, nil
 	}
-	return testCase, nil
+	return nil, nil
 }
 
 // TCase represents testcase data structure.
@@ -147,27 +273,51 @@ type TCase struct {
 }
 
 // makeCompat converts TCase to compatible testcase
-func (tc *TCase) makeCompat() error {
-	var err error
+func (tc *TCase) makeCompat() (err error) {
 	defer func() {
 		if p := recover(); p != nil {
 			err = fmt.Errorf("convert compat testcase error: %v", p)
 		}
 	}()
 	for _, step := range tc.TestSteps {
-		// 1. deal with request body compatible with HttpRunner
-		if step.Request != nil && step.Request.Body == nil {
-			if step.Request.Json != nil {
-				step.Request.Headers["Content-Type"] = "application/json; charset=utf-8"
-				step.Request.Body = step.Request.Json
-			} else if step.Request.Data != nil {
-				step.Request.Body = step.Request.Data
+		err = makeCompatStep(step)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func makeCompatStep(step *TStep) error {
+	// 1. deal with request body compatible with HttpRunner
+	if step.Request != nil && step.Request.Body == nil {
+		if step.Request.Json != nil {
+			if step.Request.Headers == nil {
+				step.Request.Headers = make(map[string]string)
 			}
+			step.Request.Headers["Content-Type"] = "application/json; charset=utf-8"
+			step.Request.Body = step.Request.Json
+		} else if step.Request.Data != nil {
+			step.Request.Body = step.Request.Data
 		}
+	}
 
-		// 2. deal with validators compatible with HttpRunner
-		err = convertCompatValidator(step.Validators)
-		if err != nil {
+	// 2. deal with validators compatible with HttpRunner
+	if err := convertCompatValidator(step.Validators); err != nil {
+		return err
+	}
+
+	// 3. deal with nested steps in loop and branch
+	var nestedSteps []*TStep
+	if step.Loop != nil {
+		nestedSteps = append(nestedSteps, step.Loop.Steps...)
+	}
+	if step.Branch != nil {
+		nestedSteps = append(nestedSteps, step.Branch.Then...)
+		nestedSteps = append(nestedSteps, step.Branch.Else...)
+	}
+	for _, nestedStep := range nestedSteps {
+		if err := makeCompatStep(nestedStep); err != nil {
 			return err
 		}
 	}
@@ -177,39 +327,132 @@ func (tc *TCase) makeCompat() error {
 func convertCompatValidator(Validators []interface{}) (err error) {
 	for i, iValidator := range Validators {
 		validatorMap := iValidator.(map[string]interface{})
-		validator := Validator{}
-		_, checkExisted := validatorMap["check"]
-		_, assertExisted := validatorMap["assert"]
-		_, expectExisted := validatorMap["expect"]
-		// check priority: HRP > HttpRunner
-		if checkExisted && assertExisted && expectExisted {
-			// HRP validator format
-			validator.Check = validatorMap["check"].(string)
-			validator.Assert = validatorMap["assert"].(string)
-			validator.Expect = validatorMap["expect"]
-			if msg, existed := validatorMap["msg"]; existed {
-				validator.Message = msg.(string)
+		Validators[i], err = convertValidatorMap(validatorMap)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func convertValidatorMap(validatorMap map[string]interface{}) (validator Validator, err error) {
+	var ok bool
+	errFormat := fmt.Errorf("unexpected validator format: %v", validatorMap)
+	if msg, existed := validatorMap["msg"]; existed {
+		if validator.Message, ok = msg.(string); !ok {
+			return validator, errFormat
+		}
+	}
+	// validator group, e.g. {"any_of": [{"eq": ["status_code", 200]}, {"eq": ["status_code", 204]}]}
+	if iNested, existed := validatorMap["not"]; existed {
+		nestedMap, ok := iNested.(map[string]interface{})
+		if !ok {
+			return validator, fmt.Errorf("unexpected not validator format: %v", validatorMap)
+		}
+		nested, err := convertValidatorMap(nestedMap)
+		if err != nil {
+			return validator, err
+		}
+		validator.Not = &nested
+		return validator, nil
+	}
+	if iNested, existed := validatorMap["any_of"]; existed {
+		nestedList, ok := iNested.([]interface{})
+		if !ok || len(nestedList) == 0 {
+			return validator, fmt.Errorf("unexpected any_of validator format: %v", validatorMap)
+		}
+		for _, iNestedValidator := range nestedList {
+			nestedMap, ok := iNestedValidator.(map[string]interface{})
+			if !ok {
+				return validator, fmt.Errorf("unexpected any_of validator format: %v", validatorMap)
 			}
-			validator.Check = convertCheckExpr(validator.Check)
-			Validators[i] = validator
-		} else if len(validatorMap) == 1 {
-			// HttpRunner validator format
-			for assertMethod, iValidatorContent := range validatorMap {
-				checkAndExpect := iValidatorContent.([]interface{})
-				if len(checkAndExpect) != 2 {
-					return fmt.Errorf("unexpected validator format: %v", validatorMap)
+			nested, err := convertValidatorMap(nestedMap)
+			if err != nil {
+				return validator, err
+			}
+			validator.AnyOf = append(validator.AnyOf, nested)
+		}
+		return validator, nil
+	}
+
+	_, checkExisted := validatorMap["check"]
+	_, assertExisted := validatorMap["assert"]
+	_, expectExisted := validatorMap["expect"]
+	expectPath, expectPathExisted := validatorMap["expect_path"]
+	// expect is optional for exists and not_exists, which defaults to true
+	expectOptional := validatorMap["assert"] == "exists" || validatorMap["assert"] == "not_exists"
+	// check priority: HRP > HttpRunner
+	if checkExisted && assertExisted && (expectExisted || expectPathExisted || expectOptional) {
+		// HRP validator format
+		if validator.Check, ok = validatorMap["check"].(string); !ok {
+			return validator, errFormat
+		}
+		if validator.Assert, ok = validatorMap["assert"].(string); !ok {
+			return validator, errFormat
+		}
+		validator.Expect = validatorMap["expect"]
+		if expectPathExisted {
+			path, ok := expectPath.(string)
+			if !ok {
+				return validator, errFormat
+			}
+			validator.ExpectPath = convertCheckExpr(path)
+		}
+		if ignore, existed := validatorMap["ignore"]; existed {
+			paths, ok := ignore.([]interface{})
+			if !ok {
+				return validator, fmt.Errorf("ignore of validator should be list of paths: %v", validatorMap)
+			}
+			for _, iPath := range paths {
+				path, ok := iPath.(string)
+				if !ok {
+					return validator, fmt.Errorf("ignore of validator should be list of paths: %v", validatorMap)
 				}
-				validator.Check = checkAndExpect[0].(string)
-				validator.Assert = assertMethod
-				validator.Expect = checkAndExpect[1]
+				validator.Ignore = append(validator.Ignore, path)
+			}
+		}
+		if epsilon, existed := validatorMap["epsilon"]; existed {
+			validator.Epsilon, err = convertEpsilon(epsilon)
+			if err != nil {
+				return validator, err
 			}
-			validator.Check = convertCheckExpr(validator.Check)
-			Validators[i] = validator
-		} else {
-			return fmt.Errorf("unexpected validator format: %v", validatorMap)
 		}
+		validator.Check = convertCheckExpr(validator.Check)
+	} else if len(validatorMap) == 1 {
+		// HttpRunner validator format
+		for assertMethod, iValidatorContent := range validatorMap {
+			checkAndExpect, ok := iValidatorContent.([]interface{})
+			if ok && len(checkAndExpect) == 1 && (assertMethod == "exists" || assertMethod == "not_exists") {
+				// e.g. {"exists": ["body.token"]}
+				checkAndExpect = append(checkAndExpect, nil)
+			}
+			if !ok || len(checkAndExpect) != 2 {
+				return validator, errFormat
+			}
+			if validator.Check, ok = checkAndExpect[0].(string); !ok {
+				return validator, errFormat
+			}
+			validator.Assert = assertMethod
+			validator.Expect = checkAndExpect[1]
+		}
+		validator.Check = convertCheckExpr(validator.Check)
+	} else {
+		return validator, errFormat
+	}
+	return validator, nil
+}
+
+func convertEpsilon(epsilon interface{}) (float64, error) {
+	switch v := epsilon.(type) {
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case interface{ Float64() (float64, error) }: // json.Number
+		return v.Float64()
+	default:
+		return 0, fmt.Errorf("epsilon should be number, got %v(%T)", epsilon, epsilon)
 	}
-	return nil
 }
 
 // convertCheckExpr deals with check expression including hyphen
@@ -246,40 +489,26 @@ func loadTestCases(iTestCases ...ITestCase) ([]*TestCase, error) {
 			return nil, errors.New("invalid iTestCase type")
 		}
 
-		casePath := tcPath.GetPath()
-		err := fs.WalkDir(os.DirFS(casePath), ".", func(path string, dir fs.DirEntry, e error) error {
-			if dir == nil {
-				// casePath is a file other than a dir
-				path = casePath
-			} else if dir.IsDir() && path != "." && strings.HasPrefix(path, ".") {
-				// skip hidden folders
-				return fs.SkipDir
-			} else {
-				// casePath is a dir
-				path = filepath.Join(casePath, path)
-			}
-
-			// ignore non-testcase files
-			ext := filepath.Ext(path)
-			if ext != ".yml" && ext != ".yaml" && ext != ".json" {
-				return nil
-			}
-
-			// filtered testcases
+		casePaths, err := expandTestCasePath(tcPath.GetPath())
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range casePaths {
 			testCasePath := TestCasePath(path)
-			tc, err := testCasePath.ToTestCase()
+			tcs, err := testCasePath.toTestCases(nil)
 			if err != nil {
 				log.Error().Err(err).Str("path", path).Msg("load testcase failed")
-				return errors.Wrap(err, "load testcase failed")
+				return nil, errors.Wrap(err, "load testcase failed")
 			}
-			testCases = append(testCases, tc)
-			return nil
-		})
-		if err != nil {
-			return nil, errors.Wrap(err, "read dir failed")
+			testCases = append(testCases, tcs...)
 		}
 	}
 
+	// resolve constant request fields once, skip parsing them in each execution
+	for _, testcase := range testCases {
+		compileTestCase(testcase)
+	}
+
 	log.Info().Int("count", len(testCases)).Msg("load testcases successfully")
 	return testCases, nil
 }
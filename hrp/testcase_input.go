@@ -0,0 +1,189 @@
+package hrp
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/httprunner/httprunner/hrp/internal/json"
+)
+
+// stdinTestCasePath is testcase path to read json/yaml testcase document from stdin, e.g. hrp run -
+// plugin, includes and referenced testcases of stdin testcase are located from current working directory.
+const stdinTestCasePath = "-"
+
+// stdin is reader of stdin testcase, replaced in tests.
+var stdin io.Reader = os.Stdin
+
+// loadStdinTCases loads testcases from stdin, json document is detected by leading brace,
+// otherwise content is loaded as yaml which may contain multiple documents.
+func loadStdinTCases() ([]*TCase, error) {
+	log.Info().Msg("load testcase from stdin")
+	content, err := io.ReadAll(stdin)
+	if err != nil {
+		return nil, errors.Wrap(err, "read stdin failed")
+	}
+	content = bytes.TrimSpace(content)
+	if len(content) == 0 {
+		return nil, errors.New("testcase not found in stdin")
+	}
+	if content[0] != '{' {
+		return loadYAMLTCases(content, stdinTestCasePath)
+	}
+	tc := &TCase{}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	if err := decoder.Decode(tc); err != nil {
+		return nil, errors.Wrap(err, "decode json testcase from stdin failed")
+	}
+	return []*TCase{tc}, nil
+}
+
+// expandTestCasePath returns testcase files of path in deterministic order, path may be stdin,
+// file, folder which is discovered recursively, or glob pattern supporting ** to match any folders.
+func expandTestCasePath(casePath string) ([]string, error) {
+	if casePath == stdinTestCasePath {
+		return []string{stdinTestCasePath}, nil
+	}
+	if _, err := os.Stat(casePath); err == nil || !hasGlobMeta(casePath) {
+		return testCaseFiles(casePath)
+	}
+
+	matches, err := globPaths(casePath)
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		return nil, errors.Errorf("no testcase matched pattern %s", casePath)
+	}
+	var files []string
+	visited := make(map[string]bool)
+	for _, match := range matches {
+		matchedFiles, err := testCaseFiles(match)
+		if err != nil {
+			return nil, err
+		}
+		// files may be matched by pattern and also found in matched folder
+		for _, file := range matchedFiles {
+			if !visited[file] {
+				visited[file] = true
+				files = append(files, file)
+			}
+		}
+	}
+	return files, nil
+}
+
+// testCaseFiles returns json/yaml files of path, folders are walked in lexical order and hidden folders are skipped.
+func testCaseFiles(casePath string) ([]string, error) {
+	var files []string
+	err := fs.WalkDir(os.DirFS(casePath), ".", func(path string, dir fs.DirEntry, e error) error {
+		if dir == nil {
+			// casePath is a file other than a dir
+			path = casePath
+		} else if dir.IsDir() && path != "." && strings.HasPrefix(dir.Name(), ".") {
+			// skip hidden folders
+			return fs.SkipDir
+		} else {
+			// casePath is a dir
+			path = filepath.Join(casePath, path)
+		}
+
+		// ignore non-testcase files
+		ext := filepath.Ext(path)
+		if ext != ".yml" && ext != ".yaml" && ext != ".json" {
+			return nil
+		}
+		files = append(files, path)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "read dir failed")
+	}
+	return files, nil
+}
+
+func hasGlobMeta(pattern string) bool {
+	return strings.ContainsAny(pattern, `*?[`)
+}
+
+// globPaths returns sorted paths matching pattern, ** matches zero or more folders.
+func globPaths(pattern string) ([]string, error) {
+	if !strings.Contains(pattern, "**") {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid glob pattern")
+		}
+		sort.Strings(matches)
+		return matches, nil
+	}
+
+	// walk from base folder without glob meta
+	segments := strings.Split(filepath.ToSlash(pattern), "/")
+	i := 0
+	for i < len(segments) && !hasGlobMeta(segments[i]) {
+		i++
+	}
+	base := strings.Join(segments[:i], "/")
+	if base == "" && i > 0 {
+		base = "/"
+	} else if base == "" {
+		base = "."
+	}
+	patternSegments := segments[i:]
+	for _, segment := range patternSegments {
+		if _, err := path.Match(segment, ""); err != nil {
+			return nil, errors.Wrap(err, "invalid glob pattern")
+		}
+	}
+
+	var matches []string
+	err := filepath.WalkDir(filepath.FromSlash(base), func(p string, dir fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(filepath.FromSlash(base), p)
+		if rel == "." {
+			return nil
+		}
+		if dir.IsDir() && strings.HasPrefix(dir.Name(), ".") {
+			return fs.SkipDir
+		}
+		if matchGlobSegments(patternSegments, strings.Split(filepath.ToSlash(rel), "/")) {
+			matches = append(matches, p)
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrap(err, "walk glob pattern failed")
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+func matchGlobSegments(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(name); i++ {
+			if matchGlobSegments(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(name) == 0 {
+		return false
+	}
+	matched, _ := path.Match(pattern[0], name[0])
+	return matched && matchGlobSegments(pattern[1:], name[1:])
+}
@@ -0,0 +1,96 @@
+package hrp
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandTestCasePath(t *testing.T) {
+	dir := t.TempDir()
+	for _, file := range []string{"a/1.yaml", "a/b/2.json", "a/b/notes.txt", "a/.hidden/3.yml", "d/4.yml", "d/e/5.yaml"} {
+		path := filepath.Join(dir, file)
+		if !assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0o755)) {
+			t.Fatal()
+		}
+		if !assert.Nil(t, os.WriteFile(path, nil, 0o644)) {
+			t.Fatal()
+		}
+	}
+	join := func(files ...string) []string {
+		var paths []string
+		for _, file := range files {
+			paths = append(paths, filepath.Join(dir, file))
+		}
+		return paths
+	}
+
+	testData := []struct {
+		path  string
+		files []string
+	}{
+		// folders are discovered recursively in lexical order
+		{dir, join("a/1.yaml", "a/b/2.json", "d/4.yml", "d/e/5.yaml")},
+		{filepath.Join(dir, "a", "1.yaml"), join("a/1.yaml")},
+		{filepath.Join(dir, "*", "*.yml"), join("d/4.yml")},
+		{filepath.Join(dir, "**", "*.yaml"), join("a/1.yaml", "d/e/5.yaml")},
+		// matched folders are expanded, files are not duplicated
+		{filepath.Join(dir, "**"), join("a/1.yaml", "a/b/2.json", "d/4.yml", "d/e/5.yaml")},
+		{filepath.Join(dir, "d", "**", "*"), join("d/4.yml", "d/e/5.yaml")},
+		{"-", []string{"-"}},
+	}
+	for _, data := range testData {
+		files, err := expandTestCasePath(data.path)
+		if !assert.Nil(t, err, data.path) {
+			t.Fatal()
+		}
+		assert.Equal(t, data.files, files, data.path)
+	}
+
+	_, err := expandTestCasePath(filepath.Join(dir, "**", "*.har"))
+	assert.EqualError(t, err, "no testcase matched pattern "+filepath.Join(dir, "**", "*.har"))
+}
+
+func TestLoadTestCasesFromStdin(t *testing.T) {
+	defer func() { stdin = os.Stdin }()
+
+	path := TestCasePath(stdinTestCasePath)
+	stdin = strings.NewReader(`
+config:
+    name: first
+teststeps:
+    - name: get
+      request:
+        method: GET
+        url: /get
+---
+config:
+    name: second
+`)
+	testCases, err := loadTestCases(&path)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	if assert.Len(t, testCases, 2) {
+		assert.Equal(t, "first", testCases[0].Config.Name)
+		assert.Equal(t, stdinTestCasePath, testCases[0].Config.Path)
+		assert.Len(t, testCases[0].TestSteps, 1)
+		assert.Equal(t, "second", testCases[1].Config.Name)
+	}
+
+	stdin = strings.NewReader(`{"config": {"name": "json", "variables": {"n": 1}}, "teststeps": []}`)
+	testCases, err = loadTestCases(&path)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	if assert.Len(t, testCases, 1) {
+		assert.Equal(t, "json", testCases[0].Config.Name)
+	}
+
+	stdin = strings.NewReader("  \n")
+	_, err = loadTestCases(&path)
+	assert.NotNil(t, err)
+}
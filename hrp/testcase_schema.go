@@ -0,0 +1,250 @@
+package hrp
+
+import (
+	"bytes"
+	builtinJSON "encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/httprunner/httprunner/hrp/internal/builtin"
+)
+
+// TestCaseSchemaID is id of json schema of testcase files, which is generated by GenTestCaseSchema
+// and published in schemas/testcase.schema.json.
+const TestCaseSchemaID = "https://httprunner.com/schemas/testcase/v1.json"
+
+// enums of string types in testcase
+var schemaEnums = map[reflect.Type][]string{
+	reflect.TypeOf(transactionType("")): {
+		string(transactionStart), string(transactionEnd),
+	},
+	reflect.TypeOf(thinkTimeStrategy("")): {
+		string(thinkTimeDefault), string(thinkTimeRandomPercentage), string(thinkTimeMultiply), string(thinkTimeIgnore),
+	},
+	reflect.TypeOf(userAgentStrategy("")): {
+		string(userAgentFixed), string(userAgentRoundRobin), string(userAgentRandom),
+	},
+	reflect.TypeOf(idempotencyKeyScope("")): {
+		string(idempotencyKeyPerStep), string(idempotencyKeyPerRequest),
+	},
+	reflect.TypeOf(correlationIDScope("")): {
+		string(correlationIDPerTestCase), string(correlationIDPerStep),
+	},
+	reflect.TypeOf(lazyEvalMode("")): {
+		string(lazyEvalOnce), string(lazyEvalAlways),
+	},
+	reflect.TypeOf(mqttAction("")): {
+		string(mqttPublish), string(mqttSubscribe), string(mqttReceive),
+	},
+	reflect.TypeOf(kafkaAction("")): {
+		string(kafkaProduce), string(kafkaConsume),
+	},
+	reflect.TypeOf(fileAction("")): {
+		string(fileUpload), string(fileDownload), string(fileExists),
+	},
+}
+
+// schemaFieldOverrides overrides schema of fields which can not be derived from field types,
+// keyed by struct type name and json field name.
+var schemaFieldOverrides = map[string]map[string]interface{}{
+	"TParamsConfig.parameterIterator": {
+		"type":        "array",
+		"description": "iterators generated at run time, which are kept in dumped testcases",
+	},
+	"Request.method": {
+		"type":        "string",
+		"description": "http method, custom methods are sent as is",
+		"examples": []string{
+			string(httpGET), string(httpHEAD), string(httpPOST), string(httpPUT),
+			string(httpDELETE), string(httpOPTIONS), string(httpPATCH),
+		},
+	},
+	"TStep.api": {
+		"type":        []string{"string", "object"},
+		"description": "referenced api file path relative to project root dir",
+	},
+	"TStep.testcase": {
+		"type":        []string{"string", "object"},
+		"description": "referenced testcase file path relative to project root dir",
+	},
+	"TStep.include": {
+		"type":        "string",
+		"description": "included fragment file path relative to project root dir",
+	},
+	"TStep.validate": {
+		"type":  "array",
+		"items": map[string]interface{}{"$ref": "#/definitions/validator"},
+	},
+}
+
+// GenTestCaseSchema generates json schema of json/yaml testcase files from TCase and its nested structs,
+// which can be used by editors for autocompletion and validation of testcases.
+func GenTestCaseSchema() ([]byte, error) {
+	g := &testCaseSchemaGenerator{
+		definitions: make(map[string]interface{}),
+	}
+	config := g.typeSchema(reflect.TypeOf(TConfig{}))
+	step := g.typeSchema(reflect.TypeOf(TStep{}))
+	g.genValidatorSchema()
+
+	schema := map[string]interface{}{
+		"$schema":     "http://json-schema.org/draft-07/schema#",
+		"$id":         TestCaseSchemaID,
+		"title":       "hrp testcase",
+		"description": "json/yaml testcase file of hrp, generated by hrp schema.",
+		"type":        "object",
+		"properties": map[string]interface{}{
+			"config": config,
+			"teststeps": map[string]interface{}{
+				"type":  "array",
+				"items": step,
+			},
+			yamlIncludesKey: map[string]interface{}{
+				"type":        "array",
+				"description": "yaml files whose anchors are shared with testcase, paths are relative to project root dir",
+				"items":       map[string]interface{}{"type": "string"},
+			},
+		},
+		"required":    []string{"config", "teststeps"},
+		"definitions": g.definitions,
+	}
+
+	var buf bytes.Buffer
+	encoder := builtinJSON.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(schema); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type testCaseSchemaGenerator struct {
+	definitions map[string]interface{} // definition name => schema of struct
+}
+
+// typeSchema returns schema of go type, structs are added to definitions and referenced.
+func (g *testCaseSchemaGenerator) typeSchema(rt reflect.Type) map[string]interface{} {
+	if enum, ok := schemaEnums[rt]; ok {
+		return map[string]interface{}{"type": "string", "enum": enum}
+	}
+	switch rt.Kind() {
+	case reflect.Ptr:
+		return g.typeSchema(rt.Elem())
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": g.typeSchema(rt.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.typeSchema(rt.Elem())}
+	case reflect.Struct:
+		name := schemaDefName(rt.Name())
+		if _, ok := g.definitions[name]; !ok {
+			g.definitions[name] = nil // placeholder for recursive structs
+			g.definitions[name] = g.structSchema(rt)
+		}
+		return map[string]interface{}{"$ref": "#/definitions/" + name}
+	}
+	// interface{} accepts any value
+	return map[string]interface{}{}
+}
+
+// structSchema returns schema of struct with properties of json fields, unknown fields are not allowed.
+// Fields without omitempty are required, except fields of interface type which accept any value.
+func (g *testCaseSchemaGenerator) structSchema(rt reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	var required []string
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		tags := strings.Split(field.Tag.Get("json"), ",")
+		name := tags[0]
+		if field.PkgPath != "" || name == "" || name == "-" {
+			continue
+		}
+		if override, ok := schemaFieldOverrides[rt.Name()+"."+name]; ok {
+			properties[name] = override
+		} else {
+			properties[name] = g.typeSchema(field.Type)
+		}
+		if !builtin.Contains(tags[1:], "omitempty") && field.Type.Kind() != reflect.Interface {
+			required = append(required, name)
+		}
+	}
+	schema := map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	return schema
+}
+
+// genValidatorSchema adds validator definition, which is either Validator or compatible format
+// of HttpRunner, e.g. {"eq": ["status_code", 200], "msg": "check status code"}.
+func (g *testCaseSchemaGenerator) genValidatorSchema() {
+	validator := g.structSchema(reflect.TypeOf(Validator{}))
+	// validator group of not or any_of has no check and assert
+	delete(validator, "required")
+	validator["anyOf"] = []interface{}{
+		map[string]interface{}{"required": []string{"check", "assert"}},
+		map[string]interface{}{"required": []string{"not"}},
+		map[string]interface{}{"required": []string{"any_of"}},
+	}
+	// nested validators of not and any_of are in the same format
+	properties := validator["properties"].(map[string]interface{})
+	properties["not"] = map[string]interface{}{"$ref": "#/definitions/validator"}
+	properties["any_of"] = map[string]interface{}{
+		"type":  "array",
+		"items": map[string]interface{}{"$ref": "#/definitions/validator"},
+	}
+	properties["assert"] = map[string]interface{}{
+		"type":     "string",
+		"examples": sortedKeys(builtin.Assertions),
+	}
+
+	compat := map[string]interface{}{
+		"type":          "object",
+		"minProperties": 1,
+		"properties": map[string]interface{}{
+			"msg": map[string]interface{}{"type": "string"},
+		},
+		"additionalProperties": map[string]interface{}{
+			"type":        "array",
+			"description": "[check, expect] of assert method",
+			"minItems":    1,
+			"maxItems":    2,
+		},
+	}
+	g.definitions["validator"] = map[string]interface{}{
+		"anyOf": []interface{}{validator, compat},
+	}
+}
+
+// schemaDefName converts struct name into definition name, e.g. TConfig => config, APIOverride => api_override.
+func schemaDefName(typeName string) string {
+	runes := []rune(typeName)
+	if len(runes) > 1 && runes[0] == 'T' && unicode.IsUpper(runes[1]) && len(runes) > 2 && unicode.IsLower(runes[2]) {
+		runes = runes[1:] // TConfig, TStep
+	}
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) &&
+			(unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
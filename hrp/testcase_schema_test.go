@@ -0,0 +1,186 @@
+package hrp
+
+import (
+	builtinJSON "encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestGenTestCaseSchema(t *testing.T) {
+	content, err := GenTestCaseSchema()
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	// published schema should be regenerated with hrp schema -o schemas/testcase.schema.json
+	published, err := os.ReadFile("schemas/testcase.schema.json")
+	if assert.Nil(t, err) {
+		assert.Equal(t, string(published), string(content))
+	}
+
+	var schema map[string]interface{}
+	if !assert.Nil(t, builtinJSON.Unmarshal(content, &schema)) {
+		t.Fatal()
+	}
+	definitions := schema["definitions"].(map[string]interface{})
+	step := definitions["step"].(map[string]interface{})
+	assert.Equal(t, []interface{}{"name"}, step["required"])
+	assert.Contains(t, step["properties"], "request")
+	assert.Contains(t, definitions, "api_override")
+
+	// testcases of hrp are valid against schema
+	paths, _ := filepath.Glob("internal/scaffold/templates/testcases/*")
+	examples, _ := filepath.Glob("../examples/hrp/*")
+	for _, path := range append(paths, examples...) {
+		if ext := filepath.Ext(path); ext != ".json" && ext != ".yml" && ext != ".yaml" {
+			continue
+		}
+		var tc interface{}
+		data, err := os.ReadFile(path)
+		if !assert.Nil(t, err) {
+			continue
+		}
+		if !assert.Nil(t, yaml.Unmarshal(data, &tc), path) {
+			continue
+		}
+		assert.Nil(t, validateSchema(schema, schema, tc, ""), path)
+	}
+
+	// unknown fields and invalid enums are reported
+	invalid := map[string]interface{}{
+		"config": map[string]interface{}{"name": "demo", "base_uri": "https://example.com"},
+		"teststeps": []interface{}{
+			map[string]interface{}{
+				"name":        "start",
+				"transaction": map[string]interface{}{"name": "t", "type": "begin"},
+			},
+		},
+	}
+	err = validateSchema(schema, schema, invalid, "")
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "config: unknown field base_uri")
+	}
+	delete(invalid["config"].(map[string]interface{}), "base_uri")
+	err = validateSchema(schema, schema, invalid, "")
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "teststeps[0].transaction.type: begin not in enum")
+	}
+}
+
+// validateSchema validates value against subset of json schema used by testcase schema.
+func validateSchema(root, schema map[string]interface{}, value interface{}, path string) error {
+	if ref, ok := schema["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/definitions/")
+		return validateSchema(root, root["definitions"].(map[string]interface{})[name].(map[string]interface{}), value, path)
+	}
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok {
+		var errs []string
+		for _, sub := range anyOf {
+			err := validateSchema(root, sub.(map[string]interface{}), value, path)
+			if err == nil {
+				errs = nil
+				break
+			}
+			errs = append(errs, err.Error())
+		}
+		if len(errs) > 0 {
+			return fmt.Errorf("%s: none of anyOf matched: %s", path, strings.Join(errs, "; "))
+		}
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		var found bool
+		for _, e := range enum {
+			found = found || e == value
+		}
+		if !found {
+			return fmt.Errorf("%s: %v not in enum", path, value)
+		}
+	}
+	if required, ok := schema["required"].([]interface{}); ok {
+		m, _ := value.(map[string]interface{})
+		for _, name := range required {
+			if _, ok := m[name.(string)]; !ok {
+				return fmt.Errorf("%s: required field %s not found", path, name)
+			}
+		}
+	}
+
+	var types []string
+	switch schemaType := schema["type"].(type) {
+	case string:
+		types = []string{schemaType}
+	case []interface{}:
+		for _, st := range schemaType {
+			types = append(types, st.(string))
+		}
+	}
+	if len(types) == 0 {
+		return nil
+	}
+	var actual string
+	switch value.(type) {
+	case string:
+		actual = "string"
+	case bool:
+		actual = "boolean"
+	case int:
+		actual = "integer"
+	case float64:
+		actual = "number"
+	case []interface{}:
+		actual = "array"
+	case map[string]interface{}:
+		actual = "object"
+	}
+	for _, st := range types {
+		if st == actual || st == "number" && actual == "integer" {
+			break
+		} else if st == types[len(types)-1] {
+			return fmt.Errorf("%s: expect %s, got %v", path, strings.Join(types, " or "), value)
+		}
+	}
+
+	switch v := value.(type) {
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				if err := validateSchema(root, items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			sub, ok := properties[key].(map[string]interface{})
+			if !ok {
+				switch additional := schema["additionalProperties"].(type) {
+				case bool:
+					if !additional {
+						return fmt.Errorf("%s: unknown field %s", path, key)
+					}
+					continue
+				case map[string]interface{}:
+					sub = additional
+				default:
+					continue
+				}
+			}
+			if err := validateSchema(root, sub, v[key], strings.TrimPrefix(path+"."+key, ".")); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -226,3 +226,24 @@ func TestConvertCheckExpr(t *testing.T) {
 		}
 	}
 }
+
+func TestConvertValidatorMapInvalid(t *testing.T) {
+	for _, validatorMap := range []map[string]interface{}{
+		{"check": "body", "assert": "equals", "expect": 1, "ignore": "body.id"},
+		{"check": "body", "assert": "equals", "expect": 1, "ignore": []interface{}{1}},
+		{"check": "body", "assert": "equals", "expect_path": 1},
+		{"check": "body", "assert": 1, "expect": 1},
+		{"eq": []interface{}{1, 200}},
+		{"eq": []interface{}{"status_code", 200}, "msg": 1},
+	} {
+		_, err := convertValidatorMap(validatorMap)
+		assert.NotNil(t, err, validatorMap)
+	}
+	validator, err := convertValidatorMap(map[string]interface{}{
+		"check": "body", "assert": "equals", "expect": 1, "ignore": []interface{}{"body.id"}, "msg": "check body",
+	})
+	if assert.Nil(t, err) {
+		assert.Equal(t, []string{"body.id"}, validator.Ignore)
+		assert.Equal(t, "check body", validator.Message)
+	}
+}
@@ -0,0 +1,194 @@
+package hrp
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/httprunner/httprunner/hrp/internal/builtin"
+)
+
+// TestCaseIssue is one invalid field found by Validate of TestCase and TStep.
+type TestCaseIssue struct {
+	Path    string // json path of invalid field, e.g. teststeps[1].request.url
+	Message string
+}
+
+func (i *TestCaseIssue) String() string {
+	if i.Path == "" {
+		return i.Message
+	}
+	return i.Path + ": " + i.Message
+}
+
+// InvalidTestCaseError is returned by Validate of TestCase and TStep, which collects all invalid fields.
+type InvalidTestCaseError struct {
+	Issues []*TestCaseIssue
+}
+
+func (e *InvalidTestCaseError) Error() string {
+	issues := make([]string, 0, len(e.Issues))
+	for _, issue := range e.Issues {
+		issues = append(issues, issue.String())
+	}
+	return "invalid testcase: " + strings.Join(issues, "; ")
+}
+
+// testCaseValidator collects issues of testcase fields.
+type testCaseValidator struct {
+	issues []*TestCaseIssue
+}
+
+func (v *testCaseValidator) addIssue(path, format string, args ...interface{}) {
+	v.issues = append(v.issues, &TestCaseIssue{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *testCaseValidator) err() error {
+	if len(v.issues) == 0 {
+		return nil
+	}
+	return &InvalidTestCaseError{Issues: v.issues}
+}
+
+// Validate checks required fields, conflicting request body fields and unknown assert names
+// of testcase built programmatically, *InvalidTestCaseError is returned with all issues found.
+func (tc *TestCase) Validate() error {
+	v := &testCaseValidator{}
+	if tc.Config == nil {
+		v.addIssue("config", "required")
+	} else if tc.Config.Name == "" {
+		v.addIssue("config.name", "required")
+	}
+	for i, step := range tc.TestSteps {
+		path := fmt.Sprintf("teststeps[%d]", i)
+		if step == nil || step.Struct() == nil {
+			v.addIssue(path, "required")
+			continue
+		}
+		v.validateStep(path, step.Struct())
+	}
+	return v.err()
+}
+
+// Validate checks required fields, conflicting request body fields and unknown assert names of step,
+// nested steps of loop and branch are also checked, *InvalidTestCaseError is returned with all issues found.
+func (s *TStep) Validate() error {
+	v := &testCaseValidator{}
+	v.validateStep("", s)
+	return v.err()
+}
+
+func joinFieldPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+func (v *testCaseValidator) validateStep(path string, step *TStep) {
+	if step.Name == "" {
+		v.addIssue(joinFieldPath(path, "name"), "required")
+	}
+	if !hasStepType(step) {
+		v.addIssue(path, "step type is missing, e.g. request, api, testcase, loop")
+	}
+	if step.Request != nil {
+		v.validateRequest(joinFieldPath(path, "request"), step.Request)
+	}
+	for i, iValidator := range step.Validators {
+		validatorPath := joinFieldPath(path, fmt.Sprintf("validate[%d]", i))
+		switch validator := iValidator.(type) {
+		case Validator:
+			v.validateValidator(validatorPath, &validator)
+		case *Validator:
+			v.validateValidator(validatorPath, validator)
+		case map[string]interface{}:
+			converted, err := convertValidatorMap(validator)
+			if err != nil {
+				v.addIssue(validatorPath, err.Error())
+				continue
+			}
+			v.validateValidator(validatorPath, &converted)
+		default:
+			v.addIssue(validatorPath, "unexpected validator type %T", iValidator)
+		}
+	}
+	if step.Loop != nil {
+		for i, nested := range step.Loop.Steps {
+			v.validateStep(joinFieldPath(path, fmt.Sprintf("loop.steps[%d]", i)), nested)
+		}
+	}
+	if step.Branch != nil {
+		for i, nested := range step.Branch.Then {
+			v.validateStep(joinFieldPath(path, fmt.Sprintf("branch.then[%d]", i)), nested)
+		}
+		for i, nested := range step.Branch.Else {
+			v.validateStep(joinFieldPath(path, fmt.Sprintf("branch.else[%d]", i)), nested)
+		}
+	}
+}
+
+func hasStepType(step *TStep) bool {
+	return step.Request != nil || step.API != nil || step.TestCase != nil || step.Transaction != nil ||
+		step.Rendezvous != nil || step.ThinkTime != nil || step.Loop != nil || step.Branch != nil ||
+		step.Fuzz != nil || step.MQTT != nil || step.Kafka != nil || step.DB != nil || step.Redis != nil ||
+		step.Shell != nil || step.File != nil || step.Email != nil || step.Include != ""
+}
+
+func (v *testCaseValidator) validateRequest(path string, request *Request) {
+	if request.Method == "" {
+		v.addIssue(joinFieldPath(path, "method"), "required")
+	}
+	if request.URL == "" {
+		v.addIssue(joinFieldPath(path, "url"), "required")
+	}
+
+	// json and data are converted to body of compatible testcase, thus equal values are not conflicting
+	var bodyFields []string
+	if request.Body != nil {
+		bodyFields = append(bodyFields, "body")
+	}
+	if request.Json != nil && !reflect.DeepEqual(request.Json, request.Body) {
+		bodyFields = append(bodyFields, "json")
+	}
+	if request.Data != nil && !reflect.DeepEqual(request.Data, request.Body) {
+		bodyFields = append(bodyFields, "data")
+	}
+	if request.BodyFile != "" {
+		bodyFields = append(bodyFields, "body_file")
+	}
+	if request.BodyProtobuf != nil {
+		bodyFields = append(bodyFields, "body_protobuf")
+		if request.BodyProtobuf.Message == "" {
+			v.addIssue(joinFieldPath(path, "body_protobuf.message"), "required")
+		}
+		if request.BodyProtobuf.Descriptor == "" {
+			v.addIssue(joinFieldPath(path, "body_protobuf.descriptor"), "required")
+		}
+	}
+	if len(bodyFields) > 1 {
+		v.addIssue(path, "conflicting body fields: %s", strings.Join(bodyFields, ", "))
+	}
+}
+
+func (v *testCaseValidator) validateValidator(path string, validator *Validator) {
+	// validator groups only contain nested validators
+	if validator.Not != nil {
+		v.validateValidator(joinFieldPath(path, "not"), validator.Not)
+		return
+	}
+	if len(validator.AnyOf) > 0 {
+		for i := range validator.AnyOf {
+			v.validateValidator(joinFieldPath(path, fmt.Sprintf("any_of[%d]", i)), &validator.AnyOf[i])
+		}
+		return
+	}
+	if validator.Check == "" {
+		v.addIssue(joinFieldPath(path, "check"), "required")
+	}
+	if validator.Assert == "" {
+		v.addIssue(joinFieldPath(path, "assert"), "required")
+	} else if _, ok := builtin.Assertions[validator.Assert]; !ok {
+		v.addIssue(joinFieldPath(path, "assert"), "unknown assert %s", validator.Assert)
+	}
+}
@@ -0,0 +1,78 @@
+package hrp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTestCaseValidate(t *testing.T) {
+	valid := &TestCase{
+		Config: NewConfig("valid"),
+		TestSteps: []IStep{
+			NewStep("get").
+				GET("/get").
+				Validate().
+				AssertEqual("status_code", 200, "check status code").
+				AssertAnyOf(
+					Validator{Check: "body.code", Assert: "equal", Expect: 0},
+					Validator{Check: "body.code", Assert: "equal", Expect: 1},
+				).
+				AssertNot(Validator{Check: "body.error", Assert: "equal", Expect: "timeout"}),
+		},
+	}
+	assert.Nil(t, valid.Validate())
+
+	invalid := &TestCase{
+		Config: NewConfig(""),
+		TestSteps: []IStep{
+			NewStep("").GET(""),
+			NewStep("post").
+				POST("/post").
+				WithBody(map[string]interface{}{"a": 1}).
+				Validate().
+				AssertEqual("status_code", 200, "check status code"),
+			NewStep("loop").Loop("$state != done").WithSteps(&StepTransaction{step: &TStep{Name: "nested"}}),
+		},
+	}
+	post := invalid.TestSteps[1].Struct()
+	post.Request.Data = "a=1"
+	post.Validators = append(post.Validators,
+		Validator{Check: "body", Assert: "equalz", Expect: 1},
+		map[string]interface{}{"lenght_equal": []interface{}{"body.items", 1}},
+		map[string]interface{}{"eq": "invalid"},
+		map[string]interface{}{"check": "body", "assert": "equals", "expect": 1, "ignore": "body.id"},
+		map[string]interface{}{"eq": []interface{}{1, 200}},
+		map[string]interface{}{"check": 1, "assert": "equals", "expect": 1},
+	)
+
+	err := invalid.Validate()
+	if !assert.IsType(t, &InvalidTestCaseError{}, err) {
+		t.Fatal()
+	}
+	var issues []string
+	for _, issue := range err.(*InvalidTestCaseError).Issues {
+		issues = append(issues, issue.String())
+	}
+	assert.Equal(t, []string{
+		"config.name: required",
+		"teststeps[0].name: required",
+		"teststeps[0].request.url: required",
+		"teststeps[1].request: conflicting body fields: body, data",
+		"teststeps[1].validate[1].assert: unknown assert equalz",
+		"teststeps[1].validate[2].assert: unknown assert lenght_equal",
+		"teststeps[1].validate[3]: unexpected validator format: map[eq:invalid]",
+		"teststeps[1].validate[4]: ignore of validator should be list of paths: map[assert:equals check:body expect:1 ignore:body.id]",
+		"teststeps[1].validate[5]: unexpected validator format: map[eq:[1 200]]",
+		"teststeps[1].validate[6]: unexpected validator format: map[assert:equals check:1 expect:1]",
+		"teststeps[2].loop.steps[0]: step type is missing, e.g. request, api, testcase, loop",
+	}, issues)
+	assert.Contains(t, err.Error(), "invalid testcase: config.name: required; teststeps[0].name: required")
+
+	// json converted to body of compatible testcase is not conflicting
+	step := &TStep{Name: "compat", Request: &Request{Method: httpPOST, URL: "/post", Json: map[string]interface{}{"a": 1}}}
+	assert.Nil(t, makeCompatStep(step))
+	assert.Nil(t, step.Validate())
+	step.Request.BodyFile = "body.json"
+	assert.EqualError(t, step.Validate(), "invalid testcase: request: conflicting body fields: body, body_file")
+}
@@ -0,0 +1,193 @@
+package hrp
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/httprunner/httprunner/hrp/internal/json"
+)
+
+// ResponseTransform transforms response body before extraction and validation,
+// e.g. decrypts encrypted payload with builtin or plugin functions.
+type ResponseTransform struct {
+	Extract map[string]string `json:"extract,omitempty" yaml:"extract,omitempty"` // variable name => jmespath of raw response, e.g. data: body.data
+	Body    string            `json:"body" yaml:"body"`                           // expression of transformed body, e.g. ${aes_gcm_decrypt($data, $key)}
+}
+
+// RequestTransform transforms request body after it is built, e.g. encrypts body with builtin or plugin functions,
+// and computes headers from the final body, e.g. signature of ciphertext.
+type RequestTransform struct {
+	Body    string            `json:"body,omitempty" yaml:"body,omitempty"`       // expression of transformed body, e.g. ${aes_gcm_encrypt($hrp_request_body, $key)}
+	Headers map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"` // headers computed from transformed body, e.g. X-Signature: ${hmac_sha256($hrp_request_body, $key)}
+}
+
+// SetRequestTransform sets transform of request body for request steps of current testcase,
+// which is overridden by request transform of step.
+func (c *TConfig) SetRequestTransform(transform *RequestTransform) *TConfig {
+	c.RequestTransform = transform
+	return c
+}
+
+// WithRequestTransform sets transform of request body after it is built.
+func (s *StepRequestWithOptionalArgs) WithRequestTransform(transform *RequestTransform) *StepRequestWithOptionalArgs {
+	s.step.RequestTransform = transform
+	return s
+}
+
+// requestTransform returns request transform of step, or of testcase config if step does not set.
+func requestTransform(config *TConfig, step *TStep) *RequestTransform {
+	if step.RequestTransform != nil {
+		return step.RequestTransform
+	}
+	return config.RequestTransform
+}
+
+// transform replaces request body with result of transform body expression, and then sets headers of transform,
+// expressions are evaluated with step variables, built request as hrp_step_request,
+// and body in string as hrp_request_body, which is the transformed body when evaluating headers.
+func (r *requestBuilder) transform(transform *RequestTransform, variables map[string]interface{}) error {
+	var body []byte
+	if r.req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(r.req.Body); err != nil {
+			return errors.Wrap(err, "read request body failed")
+		}
+		r.req.Body.Close()
+	}
+	transformVariables := make(map[string]interface{}, len(variables)+2)
+	for k, value := range variables {
+		transformVariables[k] = value
+	}
+	transformVariables["hrp_step_request"] = r.requestMap
+	transformVariables["hrp_request_body"] = string(body)
+
+	if transform.Body != "" {
+		result, err := r.parser.Parse(transform.Body, transformVariables)
+		if err != nil {
+			return err
+		}
+		switch v := result.(type) {
+		case string:
+			body = []byte(v)
+		case []byte:
+			body = v
+		case map[string]interface{}, []interface{}:
+			if body, err = json.Marshal(v); err != nil {
+				return errors.Wrap(err, "marshal transformed body failed")
+			}
+		default:
+			body = []byte(fmt.Sprint(v))
+		}
+		log.Info().Str("expr", transform.Body).Msg("transform request body")
+		r.requestMap["body"] = result
+		transformVariables["hrp_request_body"] = string(body)
+	}
+	if body != nil {
+		r.req.Body = io.NopCloser(bytes.NewReader(body))
+		r.req.ContentLength = int64(len(body))
+	}
+
+	if len(transform.Headers) == 0 {
+		return nil
+	}
+	headers, _ := r.requestMap["headers"].(map[string]string)
+	if headers == nil {
+		headers = make(map[string]string)
+		r.requestMap["headers"] = headers
+	}
+	for name, expr := range transform.Headers {
+		value, err := r.parser.ParseString(expr, transformVariables)
+		if err != nil {
+			return errors.Wrapf(err, "parse header %s failed", name)
+		}
+		r.req.Header.Set(name, convertString(value))
+		headers[http.CanonicalHeaderKey(name)] = convertString(value)
+	}
+	return nil
+}
+
+// SetResponseTransform sets transform of response body for request steps of current testcase,
+// which is overridden by response transform of step.
+func (c *TConfig) SetResponseTransform(transform *ResponseTransform) *TConfig {
+	c.ResponseTransform = transform
+	return c
+}
+
+// WithResponseTransform sets transform of response body before extraction and validation.
+func (s *StepRequestWithOptionalArgs) WithResponseTransform(transform *ResponseTransform) *StepRequestWithOptionalArgs {
+	s.step.ResponseTransform = transform
+	return s
+}
+
+// responseTransform returns response transform of step, or of testcase config if step does not set.
+func responseTransform(config *TConfig, step *TStep) *ResponseTransform {
+	if step.ResponseTransform != nil {
+		return step.ResponseTransform
+	}
+	return config.ResponseTransform
+}
+
+// transform replaces response body with result of transform expression, which is evaluated with step variables,
+// variables extracted from raw response, raw body as hrp_response_body and raw response as hrp_step_response.
+// Transformed body in string is parsed as json if possible.
+func (v *responseObject) transform(transform *ResponseTransform, variables map[string]interface{}) error {
+	respMap, ok := v.respObjMeta.(map[string]interface{})
+	if !ok {
+		return errors.New("response is not a map")
+	}
+	transformVariables := make(map[string]interface{}, len(variables)+len(transform.Extract)+2)
+	for k, value := range variables {
+		transformVariables[k] = value
+	}
+	transformVariables["hrp_step_response"] = respMap
+	transformVariables["hrp_response_body"] = respMap["body"]
+	for name, expr := range transform.Extract {
+		if _, err := v.parser.compileJmespath(expr); err != nil {
+			return errors.Wrapf(err, "invalid extractor %s for variable %s", expr, name)
+		}
+		transformVariables[name] = v.searchJmespath(expr)
+	}
+
+	result, err := v.parser.Parse(transform.Body, transformVariables)
+	if err != nil {
+		return err
+	}
+	body, err := normalizeTransformedBody(result)
+	if err != nil {
+		return err
+	}
+	log.Info().Str("expr", transform.Body).Msg("transform response body")
+	respMap["body"] = body
+	v.htmlDoc = nil
+	return nil
+}
+
+// normalizeTransformedBody decodes transformed body in the same way as raw response body,
+// json string is parsed and numbers are kept as json.Number.
+func normalizeTransformedBody(result interface{}) (interface{}, error) {
+	var data []byte
+	switch r := result.(type) {
+	case string:
+		data = []byte(r)
+	case []byte:
+		data = r
+	default:
+		var err error
+		if data, err = json.Marshal(r); err != nil {
+			return nil, errors.Wrap(err, "marshal transformed body failed")
+		}
+	}
+	var body interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&body); err != nil || decoder.More() {
+		// transformed body is not json, use raw string
+		return string(data), nil
+	}
+	return body, nil
+}
@@ -0,0 +1,148 @@
+package hrp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseTransform(t *testing.T) {
+	key := "0123456789abcdef0123456789abcdef"
+	block, _ := aes.NewCipher([]byte(key))
+	gcm, _ := cipher.NewGCM(block)
+	encrypt := func(plaintext string) string {
+		nonce := make([]byte, gcm.NonceSize())
+		rand.Read(nonce)
+		return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(plaintext), nil))
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/user":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"code": 0, "data": "%s"}`, encrypt(`{"user": "alice", "age": 18}`))
+		case "/wrapped":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte(base64.StdEncoding.EncodeToString([]byte("pong"))))
+		}
+	}))
+	defer ts.Close()
+
+	testcase := &TestCase{
+		Config: NewConfig("response transform").
+			SetBaseURL(ts.URL).
+			WithVariables(map[string]interface{}{"key": key}).
+			SetResponseTransform(&ResponseTransform{
+				Extract: map[string]string{"data": "body.data"},
+				Body:    "${aes_gcm_decrypt($data, $key)}",
+			}),
+		TestSteps: []IStep{
+			NewStep("decrypt body").
+				GET("/user").
+				Extract().
+				WithJmesPath("body.user", "user").
+				Validate().
+				AssertEqual("body.user", "alice", "check decrypted body").
+				AssertEqual("body.age", 18, "check decrypted number"),
+			NewStep("override transform").
+				GET("/wrapped").
+				WithResponseTransform(&ResponseTransform{Body: "${base64_decode($hrp_response_body)}"}).
+				Validate().
+				AssertEqual("body", "pong", "check unwrapped body"),
+		},
+	}
+	sessionRunner := NewRunner(t).NewSessionRunner(testcase)
+	if assert.Nil(t, sessionRunner.Start()) {
+		assert.Equal(t, "alice", sessionRunner.sessionVariables["user"])
+	}
+
+	// transform failure
+	testcase = &TestCase{
+		Config: NewConfig("response transform failure").SetBaseURL(ts.URL),
+		TestSteps: []IStep{
+			NewStep("wrong key").
+				GET("/user").
+				WithResponseTransform(&ResponseTransform{
+					Extract: map[string]string{"data": "body.data"},
+					Body:    "${aes_gcm_decrypt($data, fedcba9876543210)}",
+				}).
+				Validate().
+				AssertEqual("status_code", 200, "check status code"),
+		},
+	}
+	sessionRunner = NewRunner(t).SetFailfast(false).NewSessionRunner(testcase)
+	if assert.Nil(t, sessionRunner.Start()) && assert.Len(t, sessionRunner.summary.Records, 1) {
+		assert.False(t, sessionRunner.summary.Records[0].Success)
+		assert.Equal(t, FailureHook, sessionRunner.summary.Records[0].FailureCode)
+	}
+}
+
+func TestRequestTransform(t *testing.T) {
+	key := "0123456789abcdef0123456789abcdef"
+	secret := "s3cret"
+	block, _ := aes.NewCipher([]byte(key))
+	gcm, _ := cipher.NewGCM(block)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		if r.Header.Get("X-Signature") != hex.EncodeToString(mac.Sum(nil)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		sealed, err := base64.StdEncoding.DecodeString(string(body))
+		if err != nil || len(sealed) < gcm.NonceSize() {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(plaintext)
+	}))
+	defer ts.Close()
+
+	testcase := &TestCase{
+		Config: NewConfig("request transform").
+			SetBaseURL(ts.URL).
+			WithVariables(map[string]interface{}{"key": key, "secret": secret}).
+			SetRequestTransform(&RequestTransform{
+				Body:    "${aes_gcm_encrypt($hrp_request_body, $key)}",
+				Headers: map[string]string{"X-Signature": "${hmac_sha256($hrp_request_body, $secret)}"},
+			}),
+		TestSteps: []IStep{
+			NewStep("encrypt and sign").
+				POST("/echo").
+				WithBody(map[string]interface{}{"user": "alice"}).
+				Validate().
+				AssertEqual("status_code", 200, "check signature and decryption").
+				AssertEqual("body.user", "alice", "check echoed plaintext"),
+			NewStep("sign only").
+				POST("/echo").
+				WithBody(map[string]interface{}{"user": "bob"}).
+				WithRequestTransform(&RequestTransform{
+					Headers: map[string]string{"X-Signature": "${hmac_sha256($hrp_request_body, $secret)}"},
+				}).
+				Validate().
+				AssertEqual("status_code", 400, "check plaintext is not decrypted"),
+		},
+	}
+	sessionRunner := NewRunner(t).NewSessionRunner(testcase)
+	if assert.Nil(t, sessionRunner.Start()) && assert.Len(t, sessionRunner.summary.Records, 2) {
+		request := sessionRunner.summary.Records[0].Data.(*SessionData).ReqResps.Request.(map[string]interface{})
+		assert.NotEmpty(t, request["headers"].(map[string]string)["X-Signature"])
+	}
+}
@@ -0,0 +1,163 @@
+package hrp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"time"
+)
+
+// TransportConfig represents http transport tuning of testcase,
+// default connection pooling may saturate under high-concurrency load testing.
+type TransportConfig struct {
+	MaxIdleConnsPerHost int     `json:"max_idle_conns_per_host,omitempty" yaml:"max_idle_conns_per_host,omitempty"`
+	MaxConnsPerHost     int     `json:"max_conns_per_host,omitempty" yaml:"max_conns_per_host,omitempty"`
+	IdleConnTimeout     float64 `json:"idle_conn_timeout,omitempty" yaml:"idle_conn_timeout,omitempty"` // seconds
+	DisableCompression  bool    `json:"disable_compression,omitempty" yaml:"disable_compression,omitempty"`
+	IPFamily            string  `json:"ip_family,omitempty" yaml:"ip_family,omitempty"`           // ipv4, ipv6 or dual (default), e.g. ipv6 to validate services during IPv6 rollouts
+	FallbackDelay       float64 `json:"fallback_delay,omitempty" yaml:"fallback_delay,omitempty"` // seconds before falling back to the other family in dual-stack dialing (happy eyeballs), default to 0.3, negative to disable
+}
+
+// Doer sends http request and returns http response, which is implemented by *http.Client.
+// It can be set by HRPRunner.SetHTTPClient, e.g. instrumented clients, recorders or in-memory handlers.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// handlerTransport serves requests by handler in process without network.
+type handlerTransport struct {
+	handler http.Handler
+}
+
+func (t *handlerTransport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+	// convert client request to incoming request as received by http server
+	serverReq := req.Clone(req.Context())
+	serverReq.RequestURI = req.URL.RequestURI()
+	serverReq.RemoteAddr = "192.0.2.1:1234"
+	serverReq.Proto, serverReq.ProtoMajor, serverReq.ProtoMinor = "HTTP/1.1", 1, 1
+	if serverReq.Host == "" {
+		serverReq.Host = req.URL.Host
+	}
+	if serverReq.Body == nil {
+		serverReq.Body = http.NoBody
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panic: %v", r)
+		}
+	}()
+
+	recorder := httptest.NewRecorder()
+	t.handler.ServeHTTP(recorder, serverReq)
+	resp = recorder.Result()
+	resp.Request = req
+	return resp, nil
+}
+
+// tunable checks whether transport can be tuned by testcase config and egress,
+// custom transports, e.g. handler transport, are used as is.
+func tunable(transport http.RoundTripper) bool {
+	if transport == nil {
+		return true
+	}
+	_, ok := transport.(*http.Transport)
+	return ok
+}
+
+// ConnectionStats records connections got from pool for requests of step.
+type ConnectionStats struct {
+	Reused int `json:"reused" yaml:"reused"` // reused idle connections
+	New    int `json:"new" yaml:"new"`       // newly dialed connections
+}
+
+// resetClients drops cached tuned and egress clients, which are rebuilt from the current client.
+func (r *HRPRunner) resetClients() {
+	r.clientsMutex.Lock()
+	defer r.clientsMutex.Unlock()
+	r.tunedClients = nil
+	r.egressClients = nil
+}
+
+// getClient returns http client with transport tuned by config, clients are cached by config
+// thus connection pool is shared among session runners of testcases with the same transport config.
+func (r *HRPRunner) getClient(cfg *TransportConfig) *http.Client {
+	if cfg == nil || !tunable(r.client.Transport) {
+		return r.client
+	}
+
+	r.clientsMutex.Lock()
+	defer r.clientsMutex.Unlock()
+	if client, ok := r.tunedClients[*cfg]; ok {
+		return client
+	}
+
+	var transport *http.Transport
+	if t, ok := r.client.Transport.(*http.Transport); ok {
+		transport = t.Clone()
+	} else {
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	}
+	if cfg.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	}
+	if cfg.MaxConnsPerHost > 0 {
+		transport.MaxConnsPerHost = cfg.MaxConnsPerHost
+	}
+	if cfg.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = time.Duration(cfg.IdleConnTimeout * float64(time.Second))
+	}
+	if cfg.DisableCompression {
+		transport.DisableCompression = true
+	}
+	if cfg.IPFamily != "" || cfg.FallbackDelay != 0 {
+		transport.DialContext = r.dialContext(cfg, nil)
+	}
+
+	client := *r.client
+	client.Transport = transport
+	if r.tunedClients == nil {
+		r.tunedClients = make(map[TransportConfig]*http.Client)
+	}
+	r.tunedClients[*cfg] = &client
+	return &client
+}
+
+// dialContext returns dial function with local ip, address family and happy eyeballs settings,
+// based on dialer of runner, e.g. with proxy connect timeout.
+func (r *HRPRunner) dialContext(cfg *TransportConfig, localIP net.IP) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{}
+	if r.dialer != nil {
+		*dialer = *r.dialer
+	}
+	if localIP != nil {
+		dialer.LocalAddr = &net.TCPAddr{IP: localIP}
+	}
+	var family string
+	if cfg != nil {
+		family = cfg.IPFamily
+		if cfg.FallbackDelay != 0 {
+			dialer.FallbackDelay = time.Duration(cfg.FallbackDelay * float64(time.Second))
+		}
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		switch family {
+		case "", "dual":
+		case "ipv4":
+			if network == "tcp" {
+				network = "tcp4"
+			}
+		case "ipv6":
+			if network == "tcp" {
+				network = "tcp6"
+			}
+		default:
+			return nil, fmt.Errorf("unexpected ip family %s, should be ipv4, ipv6 or dual", family)
+		}
+		return dialer.DialContext(ctx, network, addr)
+	}
+}
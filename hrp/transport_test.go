@@ -0,0 +1,186 @@
+package hrp
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetClientWithTransportConfig(t *testing.T) {
+	runner := NewRunner(t)
+	assert.Same(t, runner.client, runner.getClient(nil))
+
+	cfg := &TransportConfig{
+		MaxIdleConnsPerHost: 100,
+		MaxConnsPerHost:     200,
+		IdleConnTimeout:     1.5,
+		DisableCompression:  true,
+	}
+	client := runner.getClient(cfg)
+	transport := client.Transport.(*http.Transport)
+	assert.Equal(t, 100, transport.MaxIdleConnsPerHost)
+	assert.Equal(t, 200, transport.MaxConnsPerHost)
+	assert.Equal(t, 1500*time.Millisecond, transport.IdleConnTimeout)
+	assert.True(t, transport.DisableCompression)
+	// keep settings of runner transport
+	assert.True(t, transport.TLSClientConfig.InsecureSkipVerify)
+
+	// clients are cached by config
+	assert.Same(t, client, runner.getClient(&TransportConfig{
+		MaxIdleConnsPerHost: 100,
+		MaxConnsPerHost:     200,
+		IdleConnTimeout:     1.5,
+		DisableCompression:  true,
+	}))
+	assert.NotSame(t, client, runner.getClient(&TransportConfig{MaxConnsPerHost: 10}))
+}
+
+func TestResetClientsRace(t *testing.T) {
+	runner := NewRunner(t)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			runner.resetClients()
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		_, err := runner.getEgressClient(&TransportConfig{MaxConnsPerHost: 10}, Egress{LocalAddr: "127.0.0.1"})
+		assert.Nil(t, err)
+	}
+	<-done
+}
+
+func TestRunCaseWithConnectionStats(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	testcase := &TestCase{
+		Config: NewConfig("connection stats").
+			SetBaseURL(ts.URL).
+			SetTransport(&TransportConfig{MaxIdleConnsPerHost: 10}),
+		TestSteps: []IStep{
+			NewStep("first request").GET("/"),
+			NewStep("second request").GET("/"),
+		},
+	}
+	sessionRunner := NewRunner(t).NewSessionRunner(testcase)
+	if !assert.Nil(t, sessionRunner.Start()) {
+		t.Fatal()
+	}
+	records := sessionRunner.GetSummary().Records
+	assert.Equal(t, &ConnectionStats{New: 1}, records[0].Data.(*SessionData).Connection)
+	assert.Equal(t, &ConnectionStats{Reused: 1}, records[1].Data.(*SessionData).Connection)
+}
+
+func TestRunCaseWithIPFamily(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	testcase := &TestCase{
+		Config: NewConfig("ipv4 only").
+			SetBaseURL(ts.URL).
+			SetTransport(&TransportConfig{IPFamily: "ipv4", FallbackDelay: -1}),
+		TestSteps: []IStep{
+			NewStep("request").GET("/"),
+		},
+	}
+	sessionRunner := NewRunner(t).NewSessionRunner(testcase)
+	if !assert.Nil(t, sessionRunner.Start()) {
+		t.Fatal()
+	}
+	address := sessionRunner.GetSummary().Records[0].Data.(*SessionData).Address
+	assert.Equal(t, "ipv4", address.IPFamily)
+	assert.Equal(t, "127.0.0.1", address.ServerIP)
+	assert.Equal(t, "127.0.0.1", address.ClientIP)
+
+	// server listens on ipv4 address only
+	testcase = &TestCase{
+		Config: NewConfig("ipv6 only").
+			SetBaseURL(ts.URL).
+			SetTransport(&TransportConfig{IPFamily: "ipv6"}),
+		TestSteps: []IStep{
+			NewStep("request").GET("/"),
+		},
+	}
+	assert.NotNil(t, NewRunner(nil).Run(testcase))
+
+	listener, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skip("ipv6 is not available")
+	}
+	ts6 := httptest.NewUnstartedServer(ts.Config.Handler)
+	ts6.Listener.Close()
+	ts6.Listener = listener
+	ts6.Start()
+	defer ts6.Close()
+	testcase = &TestCase{
+		Config: NewConfig("ipv6 only").
+			SetBaseURL(ts6.URL).
+			SetTransport(&TransportConfig{IPFamily: "ipv6"}),
+		TestSteps: []IStep{
+			NewStep("request").GET("/"),
+		},
+	}
+	sessionRunner = NewRunner(t).NewSessionRunner(testcase)
+	if !assert.Nil(t, sessionRunner.Start()) {
+		t.Fatal()
+	}
+	address = sessionRunner.GetSummary().Records[0].Data.(*SessionData).Address
+	assert.Equal(t, "ipv6", address.IPFamily)
+	assert.Equal(t, "::1", address.ServerIP)
+}
+
+func TestRunCaseWithHandler(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"uri": "%s", "host": "%s", "body": %s}`, r.RequestURI, r.Host, body)
+	})
+	mux.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	testcase := &TestCase{
+		// transport config is not applied to handler
+		Config: NewConfig("handler").SetBaseURL("http://api.example.invalid").
+			SetTransport(&TransportConfig{MaxConnsPerHost: 1}),
+		TestSteps: []IStep{
+			NewStep("login").
+				POST("/login").
+				WithParams(map[string]interface{}{"from": "test"}).
+				WithBody(map[string]interface{}{"user": "foo"}).
+				Validate().
+				AssertEqual("status_code", 200, "check status code").
+				AssertEqual("body.uri", "/login?from=test", "check request uri").
+				AssertEqual("body.host", "api.example.invalid", "check host").
+				AssertEqual("body.body.user", "foo", "check request body"),
+		},
+	}
+	runner := NewRunner(t).SetHandler(mux)
+	if !assert.Nil(t, runner.Run(testcase)) {
+		t.Fatal()
+	}
+
+	testcase = &TestCase{
+		Config: NewConfig("handler panic").SetBaseURL("http://api.example.invalid"),
+		TestSteps: []IStep{
+			NewStep("panic").GET("/panic"),
+		},
+	}
+	err := runner.Run(testcase)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "handler panic: boom")
+	}
+}
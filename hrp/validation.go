@@ -0,0 +1,114 @@
+package hrp
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/httprunner/httprunner/hrp/internal/builtin"
+	"github.com/httprunner/httprunner/hrp/internal/json"
+)
+
+// equality assertions which compare structures, diff is rendered for maps/slices when they fail
+var equalityAssertions = map[string]bool{
+	"eq":          true,
+	"equals":      true,
+	"equal":       true,
+	"json_equals": true,
+	"json_eq":     true,
+}
+
+// ValidationError is returned when response validation fails,
+// failed validation results carry check value, expected value, assert name and rendered diff.
+type ValidationError struct {
+	Results []*ValidationResult
+}
+
+func (e *ValidationError) Error() string {
+	var failures []string
+	for _, result := range e.Results {
+		failures = append(failures, fmt.Sprintf("%s %s %s, got %s",
+			result.Check, result.Assert, formatValidationValue(result.Expect), formatValidationValue(result.CheckValue)))
+	}
+	return "step validation failed: " + strings.Join(failures, "; ")
+}
+
+// isComposite checks if value is map or slice.
+func isComposite(value interface{}) bool {
+	if value == nil {
+		return false
+	}
+	switch reflect.TypeOf(value).Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array:
+		return true
+	}
+	return false
+}
+
+// diffValidationResult computes path-by-path diffs and rendered diff of failed equality assertion on maps/slices.
+func diffValidationResult(result *ValidationResult) {
+	if !equalityAssertions[result.Assert] {
+		return
+	}
+	if !isComposite(result.Expect) && !isComposite(result.CheckValue) {
+		return
+	}
+	result.Diffs = diffJSONValues(result.Expect, result.CheckValue)
+	result.Diff = renderDiffs(result.Diffs)
+}
+
+// renderDiffs renders diffs line by line, e.g.
+//   - data.name: expected "a", got "b"
+func renderDiffs(diffs []builtin.JSONDiff) string {
+	lines := make([]string, 0, len(diffs))
+	for _, diff := range diffs {
+		lines = append(lines, "- "+diff.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func formatValidationValue(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	content, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(content)
+}
+
+const maxTableValueLength = 60
+
+// truncateValue truncates long value in failure table by characters, full diff is printed separately.
+func truncateValue(value string) string {
+	value = strings.ReplaceAll(value, "\n", " ")
+	runes := []rune(value)
+	if len(runes) <= maxTableValueLength {
+		return value
+	}
+	return string(runes[:maxTableValueLength-3]) + "..."
+}
+
+// alignNumbers converts both values to float64 if they are numbers of different types, e.g. int64 extracted
+// from response body and float64 returned by jmespath functions, thus they can be compared by ordering assertions.
+func alignNumbers(a, b interface{}) (interface{}, interface{}) {
+	if a == nil || b == nil || reflect.TypeOf(a) == reflect.TypeOf(b) {
+		return a, b
+	}
+	if _, ok := a.(string); ok {
+		return a, b
+	}
+	if _, ok := b.(string); ok {
+		return a, b
+	}
+	fa, err := builtin.Interface2Float64(a)
+	if err != nil {
+		return a, b
+	}
+	fb, err := builtin.Interface2Float64(b)
+	if err != nil {
+		return a, b
+	}
+	return fa, fb
+}
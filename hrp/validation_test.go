@@ -0,0 +1,71 @@
+package hrp
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateWithStructuredFailure(t *testing.T) {
+	resp := http.Response{
+		StatusCode: 200,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(`{"user": {"name": "hrp", "roles": ["admin"]}}`)),
+	}
+	// validation failure is expected, use a new testing.T to avoid failing current test
+	respObj, err := newResponseObject(&testing.T{}, newParser(), &resp)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	validators := []interface{}{
+		Validator{Check: "status_code", Assert: "equals", Expect: 200},
+		Validator{
+			Check:   "body.user",
+			Assert:  "equals",
+			Expect:  map[string]interface{}{"name": "httprunner", "roles": []interface{}{"admin"}},
+			Message: "check user",
+		},
+	}
+	err = respObj.Validate(validators, map[string]interface{}{})
+	var validationErr *ValidationError
+	if !assert.True(t, errors.As(err, &validationErr)) {
+		t.Fatal()
+	}
+	assert.Equal(t, `step validation failed: body.user equals {"name":"httprunner","roles":["admin"]}, got {"name":"hrp","roles":["admin"]}`, err.Error())
+
+	result := validationErr.Results[0]
+	assert.Equal(t, "fail", result.CheckResult)
+	assert.Equal(t, "equals", result.Assert)
+	assert.Equal(t, `- name: expected "httprunner", got "hrp"`, result.Diff)
+	assert.Nil(t, respObj.validationResults[0].Diffs)
+
+	out := &bytes.Buffer{}
+	reporter := newConsoleReporter()
+	reporter.out = out
+	reporter.printValidationFailures(respObj.validationResults)
+	lines := strings.Split(out.String(), "\n")
+	assert.Equal(t, "CHECK      ASSERT  EXPECT                                   ACTUAL                            MESSAGE", lines[1])
+	assert.Equal(t, `body.user  equals  {"name":"httprunner","roles":["admin"]}  {"name":"hrp","roles":["admin"]}  check user`, lines[2])
+	assert.Equal(t, "diff of body.user:", lines[3])
+	assert.NotContains(t, out.String(), "status_code")
+}
+
+func TestTruncateValue(t *testing.T) {
+	assert.Equal(t, "short value", truncateValue("short\nvalue"))
+
+	long := strings.Repeat("a", 100)
+	assert.Equal(t, strings.Repeat("a", maxTableValueLength-3)+"...", truncateValue(long))
+
+	// multi-byte characters are not split
+	chinese := strings.Repeat("中文", 50)
+	truncated := truncateValue(chinese)
+	assert.True(t, utf8.ValidString(truncated))
+	assert.Equal(t, maxTableValueLength, utf8.RuneCountInString(truncated))
+	assert.Equal(t, strings.Repeat("中文", 30), truncateValue(strings.Repeat("中文", 30)))
+}
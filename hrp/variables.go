@@ -0,0 +1,321 @@
+package hrp
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/httprunner/httprunner/hrp/internal/json"
+)
+
+// VariableScope is the level where variable is defined, variables of scope with higher precedence
+// override variables with the same name, precedence from high to low: cli > step > extracted > testcase > global.
+type VariableScope string
+
+const (
+	VariableScopeCLI       VariableScope = "cli"       // set by --var k=v in command line or HRPRunner.SetCLIVariables
+	VariableScopeStep      VariableScope = "step"      // variables of current step
+	VariableScopeExtracted VariableScope = "extracted" // extracted or exported by previous steps
+	VariableScopeTestCase  VariableScope = "testcase"  // variables and parameters of testcase config
+	VariableScopeGlobal    VariableScope = "global"    // set by HRPRunner.SetGlobalVariables, shared by all testcases
+)
+
+// EffectiveVariable is effective value of variable with the scope it comes from.
+type EffectiveVariable struct {
+	Name     string        `json:"name"`
+	Value    interface{}   `json:"value"`
+	Scope    VariableScope `json:"scope"`
+	Readonly bool          `json:"readonly,omitempty"` // readonly in testcase config
+}
+
+// SetGlobalVariables configures variables shared by all testcases, which have the lowest precedence
+// and are overridden by variables of testcase, extracted variables and step variables.
+func (r *HRPRunner) SetGlobalVariables(variables map[string]interface{}) *HRPRunner {
+	log.Info().Interface("variables", variables).Msg("[init] SetGlobalVariables")
+	r.globalVariables = variables
+	return r
+}
+
+// SetCLIVariables configures variables overriding variables of all scopes in all testcases,
+// e.g. set by --var k=v in command line, readonly config variables are also overridden.
+func (r *HRPRunner) SetCLIVariables(variables map[string]interface{}) *HRPRunner {
+	log.Info().Interface("variables", variables).Msg("[init] SetCLIVariables")
+	r.cliVariables = variables
+	return r
+}
+
+// ParseVariableArgs parses variables in format of NAME=VALUE, e.g. args of --var,
+// literal numbers and booleans are converted.
+func ParseVariableArgs(args []string) (map[string]interface{}, error) {
+	variables := make(map[string]interface{}, len(args))
+	for _, arg := range args {
+		items := strings.SplitN(arg, "=", 2)
+		name := strings.TrimSpace(items[0])
+		if len(items) != 2 || name == "" {
+			return nil, fmt.Errorf("invalid variable %s, expect NAME=VALUE", arg)
+		}
+		variables[name] = convertLiteral(items[1])
+	}
+	return variables, nil
+}
+
+// convertLiteral converts literal numbers and booleans in string.
+func convertLiteral(value interface{}) interface{} {
+	str, ok := value.(string)
+	if !ok {
+		return value
+	}
+	if number, err := strconv.ParseFloat(str, 64); err == nil {
+		return number
+	} else if b, err := strconv.ParseBool(str); err == nil {
+		return b
+	}
+	return str
+}
+
+// parseScopedVariables parses testcase config variables together with global and cli variables,
+// so that they can reference each other. Parsed global and cli variables are kept in session runner,
+// and testcase config variables are updated with effective values.
+func (r *SessionRunner) parseScopedVariables(cfg *TConfig) (map[string]interface{}, error) {
+	globalVariables := r.hrpRunner.globalVariables
+	cliVariables := r.hrpRunner.cliVariables
+	lazyVariables, err := newLazyValues(cfg)
+	if err != nil {
+		return nil, err
+	}
+	r.lazyVariables = lazyVariables
+	// lazy variables are in testcase scope, which are passed through unevaluated
+	testCaseVariables := mergeVariables(cfg.Variables, lazyVariables)
+	variables := mergeVariables(cliVariables, mergeVariables(testCaseVariables, globalVariables))
+	parsedVariables, err := r.parser.ParseVariables(variables)
+	if err != nil {
+		return nil, err
+	}
+
+	r.globalVariables = make(map[string]interface{})
+	for name := range globalVariables {
+		if _, ok := testCaseVariables[name]; !ok {
+			r.globalVariables[name] = parsedVariables[name]
+		}
+	}
+	r.cliVariables = make(map[string]interface{})
+	for name := range cliVariables {
+		r.cliVariables[name] = parsedVariables[name]
+	}
+	configVariables := make(map[string]interface{}, len(cfg.Variables))
+	for name := range cfg.Variables {
+		configVariables[name] = parsedVariables[name]
+	}
+	for _, name := range cfg.Readonly {
+		if _, ok := testCaseVariables[name]; !ok {
+			return nil, fmt.Errorf("readonly variable %s not found in config variables", name)
+		}
+	}
+	cfg.Variables = configVariables
+	return parsedVariables, nil
+}
+
+// isReadonlyVariable returns true if variable is readonly in testcase config.
+func (r *SessionRunner) isReadonlyVariable(name string) bool {
+	for _, readonly := range r.testCase.Config.Readonly {
+		if readonly == name {
+			return true
+		}
+	}
+	return false
+}
+
+// checkReadonlyVariables returns error if variables of scope override readonly config variables,
+// variables referencing themselves, e.g. {"token": "$token"}, do not override.
+func (r *SessionRunner) checkReadonlyVariables(variables map[string]interface{}, scope VariableScope) error {
+	var names []string
+	for name, value := range variables {
+		if value == "$"+name || value == "${"+name+"}" {
+			continue
+		}
+		if r.isReadonlyVariable(name) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+	return errors.Errorf("%s variables %s override readonly config variables",
+		scope, strings.Join(names, ", "))
+}
+
+// LookupVariable returns effective value of variable in current session with step variables,
+// and the scope it comes from. Values of step variables are not parsed, and expressions of lazy variables
+// are returned if not evaluated yet.
+func (r *SessionRunner) LookupVariable(name string, stepVariables map[string]interface{}) (*EffectiveVariable, bool) {
+	r.mutex.RLock()
+	extractedValue, extracted := r.sessionVariables[name]
+	r.mutex.RUnlock()
+
+	variable := &EffectiveVariable{Name: name, Readonly: r.isReadonlyVariable(name)}
+	isSelfRef := func(value interface{}) bool {
+		return value == "$"+name || value == "${"+name+"}"
+	}
+	if value, ok := r.cliVariables[name]; ok {
+		variable.Value, variable.Scope = value, VariableScopeCLI
+	} else if value, ok := stepVariables[name]; ok && !isSelfRef(value) {
+		variable.Value, variable.Scope = value, VariableScopeStep
+	} else if extracted {
+		variable.Value, variable.Scope = extractedValue, VariableScopeExtracted
+	} else if value, ok := r.testCase.Config.Variables[name]; ok {
+		variable.Value, variable.Scope = value, VariableScopeTestCase
+	} else if value, ok := r.lazyVariables[name]; ok {
+		variable.Value, variable.Scope = value, VariableScopeTestCase
+	} else if value, ok := r.globalVariables[name]; ok {
+		variable.Value, variable.Scope = value, VariableScopeGlobal
+	} else {
+		return nil, false
+	}
+	if lazy, ok := variable.Value.(*lazyValue); ok {
+		variable.Value = lazy.current()
+	}
+	return variable, true
+}
+
+// EffectiveVariables returns effective variables of all scopes in current session with step variables,
+// sorted by variable name.
+func (r *SessionRunner) EffectiveVariables(stepVariables map[string]interface{}) []*EffectiveVariable {
+	names := make(map[string]bool)
+	r.mutex.RLock()
+	for _, variables := range []map[string]interface{}{
+		r.cliVariables, stepVariables, r.sessionVariables, r.testCase.Config.Variables, r.lazyVariables, r.globalVariables,
+	} {
+		for name := range variables {
+			names[name] = true
+		}
+	}
+	r.mutex.RUnlock()
+
+	var effective []*EffectiveVariable
+	for _, name := range sortedKeys(names) {
+		if variable, ok := r.LookupVariable(name, stepVariables); ok {
+			effective = append(effective, variable)
+		}
+	}
+	return effective
+}
+
+// lazyEvalMode is when expression of lazy variable is evaluated.
+type lazyEvalMode string
+
+const (
+	lazyEvalOnce   lazyEvalMode = "once"   // evaluated on first reference and reused in testcase, default
+	lazyEvalAlways lazyEvalMode = "always" // evaluated on each reference
+)
+
+// LazyVariable is testcase variable whose value expression is not evaluated until it is referenced,
+// e.g. {"value": "${gen_order_id()}", "eval": "once"} generates one order id shared by steps.
+type LazyVariable struct {
+	Value interface{}  `json:"value" yaml:"value"`
+	Eval  lazyEvalMode `json:"eval,omitempty" yaml:"eval,omitempty"` // once (default) or always
+}
+
+// lazyValue is state of lazy variable in session, which is kept in variables mapping
+// and evaluated by parser when referenced.
+type lazyValue struct {
+	name      string
+	expr      interface{}
+	mode      lazyEvalMode
+	mutex     sync.Mutex
+	evaluated bool
+	value     interface{}
+}
+
+// evaluate parses expression of lazy variable with variables mapping where it is referenced,
+// value evaluated in once mode is cached.
+func (v *lazyValue) evaluate(p *Parser, variablesMapping map[string]interface{}) (interface{}, error) {
+	if v.mode == lazyEvalAlways {
+		return p.Parse(v.expr, variablesMapping)
+	}
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	if v.evaluated {
+		return v.value, nil
+	}
+	value, err := p.Parse(v.expr, variablesMapping)
+	if err != nil {
+		return nil, errors.Wrapf(err, "evaluate lazy variable %s failed", v.name)
+	}
+	log.Info().Str("variable", v.name).Interface("value", value).Msg("evaluate lazy variable")
+	v.value, v.evaluated = value, true
+	return value, nil
+}
+
+// current returns evaluated value in once mode, or expression if not evaluated yet.
+func (v *lazyValue) current() interface{} {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	if v.evaluated {
+		return v.value
+	}
+	return v.expr
+}
+
+func (v *lazyValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.current())
+}
+
+// newLazyValues creates lazy values of testcase config in new session,
+// lazy variables should not be defined in variables or reference each other circularly.
+func newLazyValues(cfg *TConfig) (map[string]interface{}, error) {
+	values := make(map[string]interface{}, len(cfg.LazyVariables))
+	for _, name := range sortedKeys(cfg.LazyVariables) {
+		variable := cfg.LazyVariables[name]
+		if variable == nil {
+			return nil, fmt.Errorf("lazy variable %s has no value", name)
+		}
+		if _, ok := cfg.Variables[name]; ok {
+			return nil, fmt.Errorf("lazy variable %s is also defined in variables", name)
+		}
+		mode := variable.Eval
+		switch mode {
+		case "":
+			mode = lazyEvalOnce
+		case lazyEvalOnce, lazyEvalAlways:
+		default:
+			return nil, fmt.Errorf("invalid eval %s of lazy variable %s, expect once or always", mode, name)
+		}
+		values[name] = &lazyValue{name: name, expr: variable.Value, mode: mode}
+	}
+
+	// detect circular references among lazy variables, which are evaluated recursively
+	visiting := make(map[string]bool)
+	visited := make(map[string]bool)
+	var visit func(name string) error
+	visit = func(name string) error {
+		if visiting[name] {
+			return fmt.Errorf("lazy variable %s references itself circularly", name)
+		}
+		if visited[name] {
+			return nil
+		}
+		visiting[name] = true
+		for ref := range extractVariables(cfg.LazyVariables[name].Value) {
+			if _, ok := cfg.LazyVariables[ref]; ok {
+				if err := visit(ref); err != nil {
+					return err
+				}
+			}
+		}
+		visiting[name] = false
+		visited[name] = true
+		return nil
+	}
+	for _, name := range sortedKeys(cfg.LazyVariables) {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,238 @@
+package hrp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newQueryEchoServer returns server responding with query args in json object.
+func newQueryEchoServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := make(map[string]string)
+		for key := range r.URL.Query() {
+			query[key] = r.URL.Query().Get(key)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(query)
+	}))
+}
+
+func TestVariableScopes(t *testing.T) {
+	ts := newQueryEchoServer()
+	defer ts.Close()
+
+	stepVariables := map[string]interface{}{"d": "step", "e": "step"}
+	testcase := &TestCase{
+		Config: NewConfig("variable scopes").
+			SetBaseURL(ts.URL).
+			WithVariables(map[string]interface{}{"b": "testcase", "c": "testcase", "d": "testcase", "e": "testcase"}),
+		TestSteps: []IStep{
+			NewStep("extract").
+				GET("/echo").
+				WithParams(map[string]interface{}{"c": "extracted"}).
+				Extract().
+				WithJmesPath("body.c", "c"),
+			NewStep("check").
+				WithVariables(stepVariables).
+				GET("/echo").
+				WithParams(map[string]interface{}{"a": "$a", "b": "$b", "c": "$c", "d": "$d", "e": "$e"}).
+				Validate().
+				AssertEqual("body.a", "global", "check global variable").
+				AssertEqual("body.b", "testcase", "check testcase variable").
+				AssertEqual("body.c", "extracted", "check extracted variable").
+				AssertEqual("body.d", "step", "check step variable").
+				AssertEqual("body.e", "cli", "check cli variable"),
+		},
+	}
+	sessionRunner := NewRunner(t).
+		SetGlobalVariables(map[string]interface{}{"a": "global", "b": "global", "prefix": "${a}-"}).
+		SetCLIVariables(map[string]interface{}{"e": "cli"}).
+		NewSessionRunner(testcase)
+	if !assert.Nil(t, sessionRunner.Start()) {
+		t.Fatal()
+	}
+
+	expected := map[string]VariableScope{
+		"a": VariableScopeGlobal, "b": VariableScopeTestCase, "c": VariableScopeExtracted,
+		"d": VariableScopeStep, "e": VariableScopeCLI, "prefix": VariableScopeGlobal,
+	}
+	for name, scope := range expected {
+		variable, ok := sessionRunner.LookupVariable(name, stepVariables)
+		if assert.True(t, ok, name) {
+			assert.Equal(t, scope, variable.Scope, name)
+		}
+	}
+	variable, _ := sessionRunner.LookupVariable("prefix", nil)
+	assert.Equal(t, "global-", variable.Value)
+	_, ok := sessionRunner.LookupVariable("unknown", stepVariables)
+	assert.False(t, ok)
+
+	effective := sessionRunner.EffectiveVariables(nil)
+	if assert.Len(t, effective, 6) {
+		assert.Equal(t, "a", effective[0].Name)
+		assert.Equal(t, &EffectiveVariable{Name: "d", Value: "testcase", Scope: VariableScopeTestCase}, effective[3])
+	}
+}
+
+func TestReadonlyVariables(t *testing.T) {
+	ts := newQueryEchoServer()
+	defer ts.Close()
+
+	newConfig := func() *TConfig {
+		return NewConfig("readonly variables").
+			SetBaseURL(ts.URL).
+			WithVariables(map[string]interface{}{"env": "staging", "user": "alice"}).
+			SetReadonly("env")
+	}
+
+	// self referenced step variables do not override readonly variables
+	testcase := &TestCase{
+		Config: newConfig(),
+		TestSteps: []IStep{
+			NewStep("read").
+				WithVariables(map[string]interface{}{"env": "$env", "user": "bob"}).
+				GET("/echo").
+				WithParams(map[string]interface{}{"env": "$env", "user": "$user"}).
+				Validate().
+				AssertEqual("body.env", "staging", "check env").
+				AssertEqual("body.user", "bob", "check user"),
+		},
+	}
+	assert.Nil(t, NewRunner(t).Run(testcase))
+
+	// override by step variables
+	testcase = &TestCase{
+		Config: newConfig(),
+		TestSteps: []IStep{
+			NewStep("override").
+				WithVariables(map[string]interface{}{"env": "production"}).
+				GET("/echo"),
+		},
+	}
+	err := NewRunner(t).Run(testcase)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "step variables env override readonly config variables")
+	}
+
+	// override by extraction
+	testcase = &TestCase{
+		Config: newConfig(),
+		TestSteps: []IStep{
+			NewStep("extract").
+				GET("/echo").
+				WithParams(map[string]interface{}{"env": "production"}).
+				Extract().
+				WithJmesPath("body.env", "env"),
+		},
+	}
+	sessionRunner := NewRunner(t).SetFailfast(false).NewSessionRunner(testcase)
+	assert.Nil(t, sessionRunner.Start())
+	summary := sessionRunner.GetSummary()
+	if assert.Len(t, summary.Records, 1) {
+		assert.False(t, summary.Records[0].Success)
+		assert.Contains(t, summary.Records[0].Attachment, "extracted variables env override readonly config variables")
+		assert.Equal(t, FailureExtraction, summary.Records[0].FailureCode)
+	}
+	variable, _ := sessionRunner.LookupVariable("env", nil)
+	assert.Equal(t, &EffectiveVariable{Name: "env", Value: "staging", Scope: VariableScopeTestCase, Readonly: true}, variable)
+
+	// cli variables override readonly variables
+	testcase = &TestCase{
+		Config: newConfig(),
+		TestSteps: []IStep{
+			NewStep("cli").
+				GET("/echo").
+				WithParams(map[string]interface{}{"env": "$env"}).
+				Validate().
+				AssertEqual("body.env", "local", "check env"),
+		},
+	}
+	assert.Nil(t, NewRunner(t).SetCLIVariables(map[string]interface{}{"env": "local"}).Run(testcase))
+
+	// readonly variables should be defined
+	testcase = &TestCase{
+		Config:    newConfig().SetReadonly("env", "token"),
+		TestSteps: []IStep{NewStep("get").GET("/echo")},
+	}
+	err = NewRunner(t).Run(testcase)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "readonly variable token not found in config variables")
+	}
+}
+
+func TestParseVariableArgs(t *testing.T) {
+	variables, err := ParseVariableArgs([]string{"base_url=http://localhost:8080/?a=1", "retry=3", "debug=true", "empty="})
+	if assert.Nil(t, err) {
+		assert.Equal(t, map[string]interface{}{
+			"base_url": "http://localhost:8080/?a=1",
+			"retry":    3.0,
+			"debug":    true,
+			"empty":    "",
+		}, variables)
+	}
+	_, err = ParseVariableArgs([]string{"retry"})
+	assert.NotNil(t, err)
+	_, err = ParseVariableArgs([]string{"=3"})
+	assert.NotNil(t, err)
+}
+
+func TestLazyVariables(t *testing.T) {
+	ts := newQueryEchoServer()
+	defer ts.Close()
+
+	testcase := &TestCase{
+		Config: NewConfig("lazy variables").
+			SetBaseURL(ts.URL).
+			WithVariables(map[string]interface{}{"prefix": "order"}).
+			WithLazyVariable("order_id", "${prefix}-${gen_random_string(16)}", "once").
+			WithLazyVariable("nonce", "${gen_random_string(16)}", "always").
+			WithLazyVariable("unused", "${undefined_function()}", ""),
+		TestSteps: []IStep{
+			NewStep("create").
+				GET("/orders").
+				WithParams(map[string]interface{}{"id": "$order_id", "nonce": "$nonce"}).
+				Extract().
+				WithJmesPath("body.id", "created_id").
+				WithJmesPath("body.nonce", "created_nonce").
+				Validate().
+				AssertStartsWith("body.id", "order-", "check order id"),
+			NewStep("query").
+				WithVariables(map[string]interface{}{"order": "$order_id"}).
+				GET("/orders").
+				WithParams(map[string]interface{}{"id": "$order", "nonce": "$nonce"}).
+				Validate().
+				AssertEqual("body.id", "$created_id", "check order id reused").
+				AssertNotEqual("body.nonce", "$created_nonce", "check nonce evaluated again"),
+		},
+	}
+	sessionRunner := NewRunner(t).NewSessionRunner(testcase)
+	if !assert.Nil(t, sessionRunner.Start()) {
+		t.Fatal()
+	}
+	variable, ok := sessionRunner.LookupVariable("order_id", nil)
+	if assert.True(t, ok) {
+		assert.Equal(t, VariableScopeTestCase, variable.Scope)
+		assert.Equal(t, sessionRunner.sessionVariables["created_id"], variable.Value)
+	}
+	variable, _ = sessionRunner.LookupVariable("unused", nil)
+	assert.Equal(t, "${undefined_function()}", variable.Value)
+
+	// invalid lazy variables
+	for _, config := range []*TConfig{
+		NewConfig("circular").
+			WithLazyVariable("a", "${b}", "once").
+			WithLazyVariable("b", "$a", "always"),
+		NewConfig("duplicated").
+			WithVariables(map[string]interface{}{"a": 1}).
+			WithLazyVariable("a", "${get_timestamp()}", "once"),
+		NewConfig("invalid eval").
+			WithLazyVariable("a", "${get_timestamp()}", "never"),
+	} {
+		testcase := &TestCase{Config: config, TestSteps: []IStep{NewStep("get").GET(ts.URL)}}
+		assert.NotNil(t, NewRunner(t).Run(testcase), config.Name)
+	}
+}
@@ -0,0 +1,31 @@
+package hrp
+
+// WaitUntil represents polling setting for request step,
+// the same request is re-issued until value extracted by jmespath equals to expected value or times out.
+type WaitUntil struct {
+	JmesPath string      `json:"jmespath" yaml:"jmespath"`                     // e.g. body.state
+	Expect   interface{} `json:"expect" yaml:"expect"`                         // expected value, variables and functions are supported
+	Interval float64     `json:"interval,omitempty" yaml:"interval,omitempty"` // sleep seconds between attempts
+	Timeout  float64     `json:"timeout,omitempty" yaml:"timeout,omitempty"`   // max seconds to wait
+}
+
+// WaitAttempt represents one polling attempt of request step with wait until setting.
+type WaitAttempt struct {
+	Attempt  int         `json:"attempt" yaml:"attempt"`
+	Elapsed  int64       `json:"elapsed_ms" yaml:"elapsed_ms"`
+	Actual   interface{} `json:"actual" yaml:"actual"`
+	Matched  bool        `json:"matched" yaml:"matched"`
+	Response interface{} `json:"response" yaml:"response"`
+}
+
+// WaitUntil re-issues current HTTP request every interval seconds until value extracted by jmesPath
+// equals to expected, step fails if condition does not match in timeout seconds.
+func (s *StepRequestWithOptionalArgs) WaitUntil(jmesPath string, expected interface{}, interval, timeout float64) *StepRequestWithOptionalArgs {
+	s.step.WaitUntil = &WaitUntil{
+		JmesPath: jmesPath,
+		Expect:   expected,
+		Interval: interval,
+		Timeout:  timeout,
+	}
+	return s
+}
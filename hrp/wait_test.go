@@ -0,0 +1,45 @@
+package hrp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunRequestWaitUntil(t *testing.T) {
+	ts := newPollingServer(3)
+	defer ts.Close()
+
+	stepResult, err := NewStep("wait job done").
+		GET(ts.URL+"/job").
+		WaitUntil("body.state", "done", 0.05, 2).
+		Validate().
+		AssertEqual("body.count", 3, "check polling count").
+		Run(NewRunner(t).NewSessionRunner(&TestCase{Config: NewConfig("wait")}))
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	sessionData := stepResult.Data.(*SessionData)
+	if !assert.Len(t, sessionData.Attempts, 3) {
+		t.Fatal()
+	}
+	assert.False(t, sessionData.Attempts[0].Matched)
+	assert.Equal(t, "running", sessionData.Attempts[0].Actual)
+	assert.True(t, sessionData.Attempts[2].Matched)
+}
+
+func TestRunRequestWaitUntilTimeout(t *testing.T) {
+	ts := newPollingServer(100)
+	defer ts.Close()
+
+	stepResult, err := NewStep("wait job done").
+		GET(ts.URL+"/job").
+		WaitUntil("body.state", "done", 0.05, 0.2).
+		Run(NewRunner(t).NewSessionRunner(&TestCase{Config: NewConfig("wait")}))
+	if !assert.NotNil(t, err) {
+		t.Fatal()
+	}
+	assert.Contains(t, err.Error(), "wait until body.state == done timed out")
+	assert.False(t, stepResult.Success)
+	assert.GreaterOrEqual(t, len(stepResult.Data.(*SessionData).Attempts), 3)
+}
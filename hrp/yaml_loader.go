@@ -0,0 +1,228 @@
+package hrp
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+
+	"github.com/httprunner/httprunner/hrp/internal/builtin"
+)
+
+// yamlIncludesKey is top level key of yaml testcase listing files whose anchors are shared with the testcase,
+// included file paths are relative to project root dir, e.g.
+//
+//	includes:
+//	    - common/anchors.yml
+//	config:
+//	    name: demo
+//	    variables:
+//	        <<: *common_variables
+const yamlIncludesKey = "includes"
+
+var yamlErrLineRegexp = regexp.MustCompile(`line (\d+)`)
+
+// yamlDocument is one document of yaml file separated by ---.
+type yamlDocument struct {
+	content string
+	line    int // line number of the first line in file
+}
+
+// loadTCases loads all testcases in json/yaml file, yaml file may contain multiple documents separated by ---,
+// which are loaded in the order they appear in file.
+// Anchors defined in files listed in top level includes of yaml document can be referenced by aliases in the document.
+func loadTCases(path string) ([]*TCase, error) {
+	if path == stdinTestCasePath {
+		return loadStdinTCases()
+	}
+	ext := filepath.Ext(path)
+	if ext != ".yaml" && ext != ".yml" {
+		tc := &TCase{}
+		if err := builtin.LoadFile(path, tc); err != nil {
+			return nil, err
+		}
+		return []*TCase{tc}, nil
+	}
+
+	log.Info().Str("path", path).Msg("load file")
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "read file failed")
+	}
+	return loadYAMLTCases(content, path)
+}
+
+// loadYAMLTCases loads all testcases in yaml content of path, includes are located by path.
+func loadYAMLTCases(content []byte, path string) ([]*TCase, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	projectRootDir, err := getProjectRootDirPath(absPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get project root dir")
+	}
+
+	var tCases []*TCase
+	for _, doc := range splitYAMLDocuments(string(content)) {
+		expanded, prefixLines, err := expandYAMLIncludes(doc.content, projectRootDir, []string{absPath})
+		if err != nil {
+			return nil, errors.Wrapf(err, "document at line %d", doc.line)
+		}
+		tc := &TCase{}
+		if err := yaml.Unmarshal([]byte(expanded), tc); err != nil {
+			return nil, fixYAMLErrLine(err, prefixLines, doc.line)
+		}
+		tCases = append(tCases, tc)
+	}
+	if len(tCases) == 0 {
+		return nil, errors.New("testcase not found in file")
+	}
+	return tCases, nil
+}
+
+// loadTCase loads testcase in json/yaml file, error is returned if yaml file contains multiple testcases.
+func loadTCase(path string) (*TCase, error) {
+	tCases, err := loadTCases(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(tCases) > 1 {
+		return nil, fmt.Errorf("%d testcases found in %s, only single testcase is supported", len(tCases), path)
+	}
+	return tCases[0], nil
+}
+
+// splitYAMLDocuments splits yaml content by document separator lines, documents without content are skipped.
+func splitYAMLDocuments(content string) []yamlDocument {
+	var docs []yamlDocument
+	var b strings.Builder
+	start := 1
+	flush := func() {
+		if hasYAMLContent(b.String()) {
+			docs = append(docs, yamlDocument{content: b.String(), line: start})
+		}
+		b.Reset()
+	}
+	for i, line := range strings.SplitAfter(content, "\n") {
+		trimmed := strings.TrimRight(line, "\r\n")
+		if trimmed == "---" || strings.HasPrefix(trimmed, "--- ") || trimmed == "..." {
+			flush()
+			start = i + 1
+			if strings.HasPrefix(trimmed, "--- ") {
+				// keep content after separator, e.g. --- # comment
+				b.WriteString("   " + line[3:])
+			} else {
+				b.WriteString("\n")
+			}
+			continue
+		}
+		b.WriteString(line)
+	}
+	flush()
+	return docs
+}
+
+func hasYAMLContent(content string) bool {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return true
+		}
+	}
+	return false
+}
+
+// expandYAMLIncludes prepends contents of included files to yaml document as hidden top level keys,
+// so that anchors defined in included files can be referenced by aliases in document.
+// Included files may include other files, chain of file paths is used to detect cyclic includes.
+// Count of prepended lines is returned to correct line numbers in errors.
+func expandYAMLIncludes(content, projectRootDir string, chain []string) (string, int, error) {
+	includes, err := parseYAMLIncludes(content)
+	if err != nil || len(includes) == 0 {
+		return content, 0, err
+	}
+
+	var b strings.Builder
+	for i, include := range includes {
+		path, err := filepath.Abs(filepath.Join(projectRootDir, include))
+		if err != nil {
+			return "", 0, err
+		}
+		for _, refPath := range chain {
+			if refPath == path {
+				return "", 0, fmt.Errorf("cyclic yaml include found: %s",
+					strings.Join(append(chain, path), " -> "))
+			}
+		}
+		included, err := os.ReadFile(path)
+		if err != nil {
+			return "", 0, errors.Wrap(err, "read included file failed")
+		}
+		docs := splitYAMLDocuments(string(included))
+		if len(docs) != 1 {
+			return "", 0, fmt.Errorf("included file %s should contain exactly one yaml document", include)
+		}
+		expanded, _, err := expandYAMLIncludes(docs[0].content,
+			projectRootDir, append(chain[:len(chain):len(chain)], path))
+		if err != nil {
+			return "", 0, errors.Wrapf(err, "include %s", include)
+		}
+
+		fmt.Fprintf(&b, "__hrp_include_%d__:\n", i+1)
+		for _, line := range strings.SplitAfter(strings.TrimRight(expanded, "\n")+"\n", "\n") {
+			if strings.TrimSpace(line) != "" {
+				b.WriteString("    ")
+			}
+			b.WriteString(line)
+		}
+	}
+	prefix := b.String()
+	return prefix + content, strings.Count(prefix, "\n"), nil
+}
+
+// parseYAMLIncludes parses top level includes of yaml document, which is extracted from content before parsing
+// the whole document since aliases to included anchors can not be resolved yet.
+func parseYAMLIncludes(content string) ([]string, error) {
+	var block strings.Builder
+	inBlock := false
+	for _, line := range strings.SplitAfter(content, "\n") {
+		if strings.HasPrefix(line, yamlIncludesKey+":") {
+			inBlock = true
+		} else if inBlock && line != "" && !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "-") &&
+			!strings.HasPrefix(line, "#") && strings.TrimSpace(line) != "" {
+			break // next top level key
+		}
+		if inBlock {
+			block.WriteString(line)
+		}
+	}
+	if block.Len() == 0 {
+		return nil, nil
+	}
+	var includes struct {
+		Includes []string `yaml:"includes"`
+	}
+	if err := yaml.Unmarshal([]byte(block.String()), &includes); err != nil {
+		return nil, errors.Wrap(err, "parse yaml includes failed, includes should be list of file paths")
+	}
+	return includes.Includes, nil
+}
+
+// fixYAMLErrLine corrects line numbers in yaml error of expanded document to line numbers in original file.
+func fixYAMLErrLine(err error, prefixLines, docLine int) error {
+	msg := yamlErrLineRegexp.ReplaceAllStringFunc(err.Error(), func(s string) string {
+		line, _ := strconv.Atoi(strings.TrimPrefix(s, "line "))
+		if line <= prefixLines {
+			return fmt.Sprintf("line %d of included files", line)
+		}
+		return fmt.Sprintf("line %d", line-prefixLines+docLine-1)
+	})
+	return errors.New(msg)
+}
@@ -0,0 +1,124 @@
+package hrp
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const yamlAnchors = `# shared blocks referenced by testcases
+headers: &common_headers
+    User-Agent: hrp
+    X-Source: anchors
+variables: &common_variables
+    user: admin
+    retry: 3
+`
+
+func TestLoadTestCasesWithYAMLIncludes(t *testing.T) {
+	cwd, _ := os.Getwd()
+	dir := t.TempDir()
+	anchorsPath := filepath.Join(dir, "anchors.yml")
+	relAnchorsPath, _ := filepath.Rel(cwd, anchorsPath)
+	assert.Nil(t, os.WriteFile(anchorsPath, []byte(yamlAnchors), 0o644))
+
+	content := fmt.Sprintf(`includes:
+    - %s
+config:
+    name: first
+    variables:
+        <<: *common_variables
+        retry: 5
+teststeps:
+    - name: get
+      request:
+          method: GET
+          url: /get
+          headers: *common_headers
+---
+# second testcase in the same file
+includes: [%s]
+config:
+    name: second
+    variables: *common_variables
+teststeps:
+    - name: post
+      request:
+          method: POST
+          url: /post
+          headers:
+              <<: *common_headers
+              X-Source: second
+`, relAnchorsPath, relAnchorsPath)
+	casePath := filepath.Join(dir, "multi.yml")
+	assert.Nil(t, os.WriteFile(casePath, []byte(content), 0o644))
+
+	path := TestCasePath(casePath)
+	testCases, err := loadTestCases(&path)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	if !assert.Len(t, testCases, 2) {
+		t.Fatal()
+	}
+	// testcases are loaded in the order they appear in file
+	first, second := testCases[0], testCases[1]
+	assert.Equal(t, "first", first.Config.Name)
+	assert.Equal(t, map[string]interface{}{"user": "admin", "retry": 5}, first.Config.Variables)
+	assert.Equal(t, map[string]string{"User-Agent": "hrp", "X-Source": "anchors"},
+		first.TestSteps[0].Struct().Request.Headers)
+	assert.Equal(t, "second", second.Config.Name)
+	assert.Equal(t, map[string]interface{}{"user": "admin", "retry": 3}, second.Config.Variables)
+	assert.Equal(t, map[string]string{"User-Agent": "hrp", "X-Source": "second"},
+		second.TestSteps[0].Struct().Request.Headers)
+
+	// file with multiple testcases can not be referenced
+	_, err = path.ToTestCase()
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "2 testcases found")
+	}
+}
+
+func TestLoadTCasesErrors(t *testing.T) {
+	cwd, _ := os.Getwd()
+	dir := t.TempDir()
+	casePath := filepath.Join(dir, "demo.yml")
+	relCasePath, _ := filepath.Rel(cwd, casePath)
+
+	// line numbers in errors are lines in original file
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "anchors.yml"), []byte(yamlAnchors), 0o644))
+	relAnchorsPath, _ := filepath.Rel(cwd, filepath.Join(dir, "anchors.yml"))
+	assert.Nil(t, os.WriteFile(casePath, []byte(fmt.Sprintf(`config:
+    name: first
+---
+includes:
+    - %s
+config:
+    name: [second
+`, relAnchorsPath)), 0o644))
+	_, err := loadTCases(casePath)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "line 6")
+	}
+
+	// cyclic includes are reported
+	assert.Nil(t, os.WriteFile(casePath, []byte(fmt.Sprintf(`includes:
+    - %s
+config:
+    name: cyclic
+`, relCasePath)), 0o644))
+	_, err = loadTCases(casePath)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "cyclic yaml include found")
+	}
+
+	// documents without content are skipped
+	assert.Nil(t, os.WriteFile(casePath, []byte("---\n# comment only\n---\nconfig:\n    name: single\n...\n"), 0o644))
+	tc, err := loadTCase(casePath)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "single", tc.Config.Name)
+	}
+}